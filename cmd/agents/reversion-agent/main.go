@@ -24,6 +24,7 @@ import (
 	"github.com/ajitpratap0/cryptofunk/internal/agents"
 	"github.com/ajitpratap0/cryptofunk/internal/config"
 	"github.com/ajitpratap0/cryptofunk/internal/llm"
+	"github.com/ajitpratap0/cryptofunk/internal/regime"
 )
 
 // ============================================================================
@@ -118,6 +119,9 @@ type ReversionAgent struct {
 	natsTopic string
 	heartbeat *agents.HeartbeatPublisher
 
+	// Latest HMM regime estimates from the regime service (nil when unavailable)
+	regimes *regime.Tracker
+
 	// LLM client for AI-powered analysis
 	llmClient     llm.LLMClient // Interface supports both Client and FallbackClient
 	promptBuilder *llm.PromptBuilder
@@ -162,10 +166,12 @@ type BollingerIndicators struct {
 
 // MarketRegime represents the current market state
 type MarketRegime struct {
-	Type       string    `json:"type"`       // "ranging", "trending", "volatile"
-	ADX        float64   `json:"adx"`        // ADX value for trend strength
-	Confidence float64   `json:"confidence"` // Confidence in regime assessment
-	Timestamp  time.Time `json:"timestamp"`
+	Type          string             `json:"type"`                    // "ranging", "trending", "volatile"
+	ADX           float64            `json:"adx"`                     // ADX value for trend strength
+	Confidence    float64            `json:"confidence"`              // Confidence in regime assessment
+	Source        string             `json:"source,omitempty"`        // "adx" or "hmm" (regime service)
+	Probabilities map[string]float64 `json:"probabilities,omitempty"` // Regime service probabilities by type
+	Timestamp     time.Time          `json:"timestamp"`
 }
 
 // ReversionSignal represents a mean reversion trading signal
@@ -213,6 +219,9 @@ func NewReversionAgent(config *agents.AgentConfig, log zerolog.Logger, metricsPo
 
 	log.Info().Msg("Successfully connected to NATS")
 
+	// Track regime estimates from the regime service; ADX is the fallback
+	regimes := newRegimeTracker(nc, viper.GetString("communication.nats.topics.market_regime"))
+
 	// Get heartbeat topic for agent registration with orchestrator
 	heartbeatTopic := viper.GetString("strategy_agents.mean_reversion.heartbeat_topic")
 	if heartbeatTopic == "" {
@@ -323,6 +332,7 @@ func NewReversionAgent(config *agents.AgentConfig, log zerolog.Logger, metricsPo
 		natsConn:             nc,
 		natsTopic:            natsTopic,
 		heartbeat:            heartbeatPublisher,
+		regimes:              regimes,
 		llmClient:            llmClient,
 		promptBuilder:        promptBuilder,
 		useLLM:               useLLM,
@@ -447,8 +457,11 @@ func (a *ReversionAgent) Step(ctx context.Context) error {
 		Float64("adx", adx).
 		Msg("ADX calculated for regime detection")
 
-	// Detect market regime (ranging vs trending vs volatile)
+	// Detect market regime (ranging vs trending vs volatile), preferring the regime service's HMM estimate
 	regime := a.detectMarketRegime(adx)
+	if hmm := a.hmmRegime(symbol, adx); hmm != nil {
+		regime = hmm
+	}
 
 	log.Info().
 		Str("regime", regime.Type).
@@ -1126,6 +1139,7 @@ func (a *ReversionAgent) detectMarketRegime(adx float64) *MarketRegime {
 		Type:       regimeType,
 		ADX:        adx,
 		Confidence: confidence,
+		Source:     "adx",
 		Timestamp:  time.Now(),
 	}
 
@@ -1172,12 +1186,16 @@ func (a *ReversionAgent) filterSignalByRegime(signal string, confidence float64,
 
 // updateRegimeBeliefs updates the agent's belief base with market regime data
 func (a *ReversionAgent) updateRegimeBeliefs(regime *MarketRegime) {
-	a.beliefs.UpdateBelief("market_regime", regime.Type, regime.Confidence, "adx_indicator")
+	source := "adx_indicator"
+	if regime.Source == "hmm" {
+		source = "regime_service"
+	}
+	a.beliefs.UpdateBelief("market_regime", regime.Type, regime.Confidence, source)
 	a.beliefs.UpdateBelief("adx_value", regime.ADX, 0.9, "adx_indicator")
 
 	// Determine if regime is favorable for mean reversion
 	isFavorable := regime.Type == "ranging"
-	a.beliefs.UpdateBelief("regime_favorable", isFavorable, regime.Confidence, "adx_indicator")
+	a.beliefs.UpdateBelief("regime_favorable", isFavorable, regime.Confidence, source)
 
 	log.Debug().
 		Str("regime", regime.Type).
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/regime"
+)
+
+// regimeMaxAge is how old a regime service estimate may be before ADX is used instead
+const regimeMaxAge = time.Hour
+
+// newRegimeTracker subscribes to the regime service's estimates. Without a
+// subscription the agent falls back to ADX.
+func newRegimeTracker(nc *nats.Conn, subject string) *regime.Tracker {
+	tracker := regime.NewTracker(regimeMaxAge)
+	if _, err := tracker.Subscribe(nc, subject); err != nil {
+		log.Warn().Err(err).Msg("Failed to subscribe to market regimes, using ADX only")
+		return nil
+	}
+	return tracker
+}
+
+// hmmRegime maps the regime service's latest estimate for a symbol onto the
+// agent's regime types: sideways is ranging, bullish and bearish are
+// trending. It returns nil when there is no recent estimate.
+func (a *ReversionAgent) hmmRegime(symbol string, adx float64) *MarketRegime {
+	if a.regimes == nil {
+		return nil
+	}
+	estimate, ok := a.regimes.Get(symbol)
+	if !ok {
+		return nil
+	}
+
+	probabilities := map[string]float64{
+		"ranging":  estimate.Probability(regime.RegimeSideways),
+		"trending": estimate.Probability(regime.RegimeBullish) + estimate.Probability(regime.RegimeBearish),
+		"volatile": estimate.Probability(regime.RegimeVolatile),
+	}
+	result := &MarketRegime{
+		ADX:           adx,
+		Source:        "hmm",
+		Probabilities: probabilities,
+		Timestamp:     estimate.DetectedAt,
+	}
+	for _, regimeType := range []string{"ranging", "trending", "volatile"} {
+		if p := probabilities[regimeType]; p > result.Confidence {
+			result.Type, result.Confidence = regimeType, p
+		}
+	}
+	if result.Type == "" {
+		return nil
+	}
+
+	log.Debug().
+		Str("regime", result.Type).
+		Str("hmm_regime", string(estimate.Regime)).
+		Float64("confidence", result.Confidence).
+		Msg("Market regime from regime service")
+	return result
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/regime"
+)
+
+func TestHMMRegime(t *testing.T) {
+	agent := &ReversionAgent{}
+	assert.Nil(t, agent.hmmRegime("BTC/USDT", 30), "no tracker")
+
+	agent.regimes = regime.NewTracker(time.Hour)
+	assert.Nil(t, agent.hmmRegime("BTC/USDT", 30), "no estimate")
+
+	agent.regimes.Update(&regime.Estimate{
+		Symbol: "BTC/USDT",
+		Regime: regime.RegimeBullish,
+		Probabilities: map[regime.Regime]float64{
+			regime.RegimeBullish:  0.4,
+			regime.RegimeBearish:  0.2,
+			regime.RegimeSideways: 0.3,
+			regime.RegimeVolatile: 0.1,
+		},
+		DetectedAt: time.Now(),
+	})
+
+	result := agent.hmmRegime("BTCUSDT", 30)
+	require.NotNil(t, result)
+	assert.Equal(t, "trending", result.Type)
+	assert.InDelta(t, 0.6, result.Confidence, 1e-9)
+	assert.Equal(t, "hmm", result.Source)
+	assert.Equal(t, 30.0, result.ADX)
+
+	// A trending HMM regime suppresses mean reversion like a high ADX does
+	signal, _, _ := agent.filterSignalByRegime("BUY", 0.8, "oversold", result)
+	assert.Equal(t, "HOLD", signal)
+}
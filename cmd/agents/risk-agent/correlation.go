@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// Correlations are rolling correlations of hourly returns over this lookback
+const (
+	correlationInterval     = "1h"
+	correlationLookbackDays = 30
+)
+
+// checkCorrelatedExposure reports whether adding size (quote currency, negative
+// to sell) to symbol keeps exposure to symbols correlated with it within the
+// limit, along with the
+// projected correlated exposure and the limit. Trades are not blocked when
+// correlations cannot be loaded. The positions are copied and the beliefs lock
+// is released before correlations are loaded; callers must not hold it.
+func (a *RiskAgent) checkCorrelatedExposure(ctx context.Context, symbol string, size float64) (bool, float64, float64) {
+	a.beliefs.mu.RLock()
+	threshold := a.beliefs.maxCorrelation
+	positions := make([]risk.Position, 0, len(a.beliefs.currentPositions)+1)
+	symbols := []string{symbol}
+	for _, position := range a.beliefs.currentPositions {
+		positions = append(positions, risk.Position{Symbol: position.Symbol, Size: position.signedSize()})
+		if !strings.EqualFold(position.Symbol, symbol) {
+			symbols = append(symbols, position.Symbol)
+		}
+	}
+	a.beliefs.mu.RUnlock()
+
+	// Net the proposal against the symbol's position, so a SELL that flips a
+	// long counts only the resulting short
+	added := false
+	for i := range positions {
+		if strings.EqualFold(positions[i].Symbol, symbol) {
+			positions[i].Size += size
+			added = true
+			break
+		}
+	}
+	if !added {
+		positions = append(positions, risk.Position{Symbol: symbol, Size: size})
+	}
+
+	limit := a.config.MaxTotalExposure * a.config.MaxCorrelatedExposure
+	if threshold <= 0 || limit <= 0 {
+		return true, 0, limit
+	}
+
+	// A lone symbol is only correlated with itself
+	var matrix *risk.CorrelationMatrix
+	if len(symbols) > 1 {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		var err error
+		matrix, err = a.correlations.Matrix(ctx, symbols, correlationInterval, correlationLookbackDays, risk.CorrelationOptions{})
+		if err != nil {
+			log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to load correlations, skipping correlated exposure limit")
+			return true, 0, limit
+		}
+	}
+
+	exposure := risk.CorrelatedExposure(matrix, positions, symbol, threshold)
+	return exposure <= limit, exposure, limit
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// expectHourlyCandles mocks the hourly closes of a symbol
+func expectHourlyCandles(mock pgxmock.PgxPoolIface, symbol string, closes ...float64) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := pgxmock.NewRows([]string{"close", "open_time"})
+	for i, price := range closes {
+		rows.AddRow(price, start.Add(time.Duration(i)*time.Hour))
+	}
+	mock.ExpectQuery("SELECT close, open_time FROM candlesticks").
+		WithArgs(symbol, correlationInterval, correlationLookbackDays).
+		WillReturnRows(rows)
+}
+
+func TestEvaluateProposal_VetoOnCorrelatedExposure(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	agent := createTestRiskAgent()
+	agent.calculator = risk.NewCalculator(mock)
+	agent.correlations = risk.NewCorrelationService(agent.calculator)
+	agent.config.MaxCorrelatedExposure = 0.4 // $20,000 of $50,000
+	agent.beliefs.maxCorrelation = 0.7
+	agent.beliefs.currentPositions = []Position{
+		{Symbol: "BTC/USDT", Size: 9000, EntryPrice: 100},
+		{Symbol: "ETH/USDT", Size: 9000, EntryPrice: 10},
+	}
+	agent.beliefs.totalExposure = 18000
+	agent.beliefs.openPositionCount = 2
+
+	// SOL is high-beta to BTC and ETH
+	expectHourlyCandles(mock, "SOL/USDT", 20, 22, 19, 23, 21, 24)
+	expectHourlyCandles(mock, "BTC/USDT", 100, 104, 98, 105, 101, 106)
+	expectHourlyCandles(mock, "ETH/USDT", 10, 10.6, 9.7, 10.8, 10.2, 10.9)
+
+	intentions := agent.evaluateProposal(context.Background(), "SOL/USDT", "BUY", 5000.0, 0.8)
+
+	assert.True(t, intentions.shouldVeto)
+	assert.Contains(t, intentions.vetoReason, "correlated")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckCorrelatedExposure(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	agent := createTestRiskAgent()
+	agent.calculator = risk.NewCalculator(mock)
+	agent.correlations = risk.NewCorrelationService(agent.calculator)
+	agent.config.MaxCorrelatedExposure = 0.4
+
+	// No correlation limit
+	withinLimit, _, _ := agent.checkCorrelatedExposure(context.Background(), "BTC/USDT", 5000)
+	assert.True(t, withinLimit)
+
+	// Uncorrelated holdings do not count toward the trade's exposure
+	agent.beliefs.maxCorrelation = 0.7
+	agent.beliefs.currentPositions = []Position{{Symbol: "PAXG/USDT", Size: 18000}}
+	expectHourlyCandles(mock, "BTC/USDT", 100, 104, 98, 105, 101, 106)
+	expectHourlyCandles(mock, "PAXG/USDT", 2000, 1990, 2010, 1995, 2015, 2000)
+
+	withinLimit, exposure, limit := agent.checkCorrelatedExposure(context.Background(), "BTC/USDT", 5000)
+	assert.True(t, withinLimit)
+	assert.Equal(t, 5000.0, exposure)
+	assert.Equal(t, 20000.0, limit)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// Correlations that cannot be loaded do not block trades
+	agent.correlations = risk.NewCorrelationService(risk.NewCalculator(nil))
+	withinLimit, _, _ = agent.checkCorrelatedExposure(context.Background(), "ETH/USDT", 5000)
+	assert.True(t, withinLimit)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+	"github.com/ajitpratap0/cryptofunk/internal/strategy"
+)
+
+// deRiskTimeout covers a de-risking step, which may chase limit orders
+const deRiskTimeout = 3 * time.Minute
+
+// deRiskPolicy converts a strategy's de-risking settings. Invalid settings
+// disable de-risking rather than act on a half-understood policy.
+func deRiskPolicy(settings strategy.DeRiskingSettings) risk.DeRiskPolicy {
+	policy := risk.DeRiskPolicy{
+		Enabled: settings.Enabled,
+		Ranking: risk.DeRiskRanking(settings.Ranking),
+	}
+	if settings.Cooldown != "" {
+		cooldown, err := time.ParseDuration(settings.Cooldown)
+		if err != nil {
+			log.Warn().Err(err).Str("cooldown", settings.Cooldown).Msg("Invalid de-risking cooldown, de-risking disabled")
+			return risk.DeRiskPolicy{}
+		}
+		policy.Cooldown = cooldown
+	}
+	for _, p := range settings.Policies {
+		policy.Rules = append(policy.Rules, risk.DeRiskRule{
+			Name:           p.Name,
+			Metric:         risk.DeRiskMetric(p.Metric),
+			Threshold:      p.Threshold,
+			Action:         risk.DeRiskAction(p.Action),
+			ReduceFraction: p.ReduceFraction,
+		})
+	}
+	if err := policy.Validate(); err != nil {
+		log.Warn().Err(err).Msg("Invalid de-risking policy, de-risking disabled")
+		return risk.DeRiskPolicy{}
+	}
+	return policy
+}
+
+// checkDeRisking cuts open positions when the active strategy's de-risking
+// policies are breached
+func (a *RiskAgent) checkDeRisking(ctx context.Context) {
+	if a.deRisker == nil || a.db == nil || !a.deRisker.Policy().Enabled {
+		return
+	}
+
+	positions, err := a.db.GetAllOpenPositions(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load open positions for de-risking")
+		return
+	}
+	a.applyDeRisking(ctx, a.deRiskPositions(ctx, positions), time.Now())
+}
+
+// deRiskPositions prices open positions and attaches their lot rules and, for
+// the riskiest ranking, their volatility
+func (a *RiskAgent) deRiskPositions(ctx context.Context, positions []*db.Position) []risk.DeRiskPosition {
+	riskiest := a.deRisker.Policy().Ranking == risk.DeRiskRankRiskiest
+	prices := make(map[string]float64)
+	volatilities := make(map[string]float64)
+
+	result := make([]risk.DeRiskPosition, 0, len(positions))
+	for _, position := range positions {
+		price, ok := prices[position.Symbol]
+		if !ok {
+			price = a.getCurrentPrice(ctx, position.Symbol)
+			prices[position.Symbol] = price
+		}
+
+		candidate := risk.DeRiskPosition{
+			ID:       position.ID.String(),
+			Symbol:   position.Symbol,
+			Side:     string(position.Side),
+			Quantity: position.Quantity,
+			Price:    price,
+		}
+		if a.instruments != nil {
+			if inst, ok := a.instruments.Get(position.Symbol); ok {
+				candidate.StepSize = inst.StepSize
+				candidate.MinQty = inst.MinQty
+				candidate.MinNotional = inst.MinNotional
+			}
+		}
+		if riskiest {
+			volatility, ok := volatilities[position.Symbol]
+			if !ok {
+				volCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+				var err error
+				if volatility, err = a.calculator.LoadAnnualizedVolatility(volCtx, position.Symbol, sizingVolatilityInterval, sizingLookbackDays); err != nil {
+					log.Debug().Err(err).Str("symbol", position.Symbol).Msg("No volatility for de-risking ranking")
+				}
+				cancel()
+				volatilities[position.Symbol] = volatility
+			}
+			candidate.Volatility = volatility
+		}
+		result = append(result, candidate)
+	}
+	return result
+}
+
+// applyDeRisking plans the next de-risking step for positions and sends it to
+// the API server. Every step is logged with its before and after metrics.
+func (a *RiskAgent) applyDeRisking(ctx context.Context, positions []risk.DeRiskPosition, now time.Time) *risk.DeRiskPlan {
+	exposure, largest := 0.0, 0.0
+	for _, position := range positions {
+		exposure += position.Notional()
+		if position.Notional() > largest {
+			largest = position.Notional()
+		}
+	}
+
+	a.beliefs.mu.RLock()
+	state := risk.DeRiskMetrics{
+		Drawdown:        a.beliefs.currentDrawdown / 100,
+		Exposure:        exposure,
+		ExposureLimit:   a.config.MaxTotalExposure,
+		VaR95:           a.beliefs.portfolioVaR95,
+		LargestPosition: largest,
+		Positions:       len(positions),
+	}
+	a.beliefs.mu.RUnlock()
+	if state.ExposureLimit > 0 {
+		state.ExposureUtilization = state.Exposure / state.ExposureLimit
+	}
+
+	plan := a.deRisker.Plan(state, positions, now)
+	if plan == nil {
+		return nil
+	}
+
+	event := log.Warn().
+		Str("rule", plan.Rule.Name).
+		Str("metric", string(plan.Rule.Metric)).
+		Str("action", string(plan.Rule.Action)).
+		Float64("value", plan.Value).
+		Float64("threshold", plan.Rule.Threshold).
+		Interface("before", plan.Before).
+		Interface("after", plan.After).
+		Interface("orders", plan.Orders)
+	if len(plan.Skipped) > 0 {
+		event = event.Str("skipped", strings.Join(plan.Skipped, ","))
+	}
+	event.Msg("De-risking policy breached")
+
+	if len(plan.Orders) == 0 {
+		log.Warn().Str("rule", plan.Rule.Name).Msg("No position can be cut within exchange minimums")
+		return plan
+	}
+	if a.config.KillSwitchURL == "" {
+		log.Error().Str("rule", plan.Rule.Name).Msg("No kill_switch_url configured, de-risking step not executed")
+		return plan
+	}
+
+	if err := a.executeDeRisking(ctx, plan); err != nil {
+		log.Error().Err(err).Str("rule", plan.Rule.Name).Msg("Failed to execute de-risking step")
+	}
+	return plan
+}
+
+// executeDeRisking asks the API server to cut the plan's positions
+func (a *RiskAgent) executeDeRisking(ctx context.Context, plan *risk.DeRiskPlan) error {
+	ctx, cancel := context.WithTimeout(ctx, deRiskTimeout)
+	defer cancel()
+
+	reductions := make([]map[string]interface{}, len(plan.Orders))
+	for i, order := range plan.Orders {
+		reductions[i] = map[string]interface{}{
+			"position_id": order.PositionID,
+			"quantity":    order.Quantity,
+		}
+	}
+
+	var result struct {
+		Message string `json:"message"`
+	}
+	if err := a.postKillSwitch(ctx, "/api/v1/trade/derisk", map[string]interface{}{
+		"source":       "risk_agent",
+		"requested_by": a.config.AgentName,
+		"reason":       plan.Reason(),
+		"rule":         plan.Rule.Name,
+		"reductions":   reductions,
+		"before":       plan.Before,
+		"after":        plan.After,
+	}, &result); err != nil {
+		return err
+	}
+
+	log.Warn().Str("rule", plan.Rule.Name).Str("result", result.Message).Msg("De-risking step executed")
+	return nil
+}
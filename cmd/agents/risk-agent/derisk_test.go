@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+	"github.com/ajitpratap0/cryptofunk/internal/strategy"
+)
+
+func TestDeRiskPolicy_FromStrategySettings(t *testing.T) {
+	settings := strategy.NewDefaultStrategy("test").Risk.DeRisking
+	settings.Enabled = true
+
+	policy := deRiskPolicy(settings)
+	assert.True(t, policy.Enabled)
+	assert.Equal(t, risk.DeRiskRankLargest, policy.Ranking)
+	assert.Equal(t, 15*time.Minute, policy.Cooldown)
+	require.Len(t, policy.Rules, 2)
+	assert.Equal(t, risk.DeRiskActionFlatten, policy.Rules[1].Action)
+
+	// A policy that cannot be understood is not acted on
+	settings.Cooldown = "soon"
+	assert.False(t, deRiskPolicy(settings).Enabled)
+}
+
+func TestApplyDeRisking_SendsStepToAPIServer(t *testing.T) {
+	var calls int
+	var body struct {
+		Source     string                   `json:"source"`
+		Rule       string                   `json:"rule"`
+		Reason     string                   `json:"reason"`
+		Reductions []map[string]interface{} `json:"reductions"`
+		Before     map[string]interface{}   `json:"before"`
+		After      map[string]interface{}   `json:"after"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Equal(t, "/api/v1/trade/derisk", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": "Positions reduced"})
+	}))
+	defer server.Close()
+
+	agent := createTestRiskAgent()
+	agent.config.MaxTotalExposure = 100000
+	agent.config.KillSwitchURL = server.URL
+	agent.config.KillSwitchAPIKey = "secret"
+	agent.deRisker = risk.NewDeRisker(risk.DeRiskPolicy{
+		Enabled:  true,
+		Cooldown: 10 * time.Minute,
+		Rules: []risk.DeRiskRule{
+			{Name: "soft_drawdown", Metric: risk.DeRiskMetricDrawdown, Threshold: 0.05, Action: risk.DeRiskActionReduce, ReduceFraction: 0.5},
+		},
+	})
+	positions := []risk.DeRiskPosition{
+		{ID: "p1", Symbol: "BTCUSDT", Side: "LONG", Quantity: 1, Price: 50000, StepSize: 0.001},
+	}
+	ctx := context.Background()
+	now := time.Now()
+
+	// Within limits nothing is sent
+	agent.beliefs.currentDrawdown = 3
+	assert.Nil(t, agent.applyDeRisking(ctx, positions, now))
+	assert.Zero(t, calls)
+
+	agent.beliefs.currentDrawdown = 8
+	plan := agent.applyDeRisking(ctx, positions, now)
+	require.NotNil(t, plan)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, "risk_agent", body.Source)
+	assert.Equal(t, "soft_drawdown", body.Rule)
+	assert.Contains(t, body.Reason, "soft_drawdown")
+	require.Len(t, body.Reductions, 1)
+	assert.Equal(t, "p1", body.Reductions[0]["position_id"])
+	assert.InDelta(t, 0.5, body.Reductions[0]["quantity"], 1e-9)
+	assert.InDelta(t, 50000, body.Before["exposure"], 1e-6)
+	assert.InDelta(t, 25000, body.After["exposure"], 1e-6)
+
+	// The next step waits out the cooldown
+	assert.Nil(t, agent.applyDeRisking(ctx, positions, now.Add(time.Minute)))
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// killSwitchTimeout covers arming plus a full flatten, which may chase limit orders
+const killSwitchTimeout = 3 * time.Minute
+
+// checkKillSwitch trips the global kill switch once when the current drawdown
+// breaches the configured maximum. The latch resets after drawdown recovers.
+func (a *RiskAgent) checkKillSwitch(ctx context.Context) {
+	if !a.config.FlattenOnDrawdown || a.config.MaxDrawdownPercent <= 0 {
+		return
+	}
+
+	a.beliefs.mu.RLock()
+	drawdown := a.beliefs.currentDrawdown
+	a.beliefs.mu.RUnlock()
+
+	a.mu.Lock()
+	if drawdown <= a.config.MaxDrawdownPercent {
+		a.flattenTripped = false
+		a.mu.Unlock()
+		return
+	}
+	if a.flattenTripped {
+		a.mu.Unlock()
+		return
+	}
+	a.flattenTripped = true
+	a.mu.Unlock()
+
+	reason := fmt.Sprintf("Drawdown %.1f%% exceeds maximum %.1f%%", drawdown, a.config.MaxDrawdownPercent)
+	log.Warn().Str("reason", reason).Msg("Drawdown circuit breaker breached, tripping kill switch")
+
+	if err := a.tripKillSwitch(ctx, reason); err != nil {
+		// Allow a retry on the next belief update
+		a.mu.Lock()
+		a.flattenTripped = false
+		a.mu.Unlock()
+		log.Error().Err(err).Msg("Failed to trip kill switch")
+	}
+}
+
+// tripKillSwitch arms the API kill switch and immediately confirms it
+func (a *RiskAgent) tripKillSwitch(ctx context.Context, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, killSwitchTimeout)
+	defer cancel()
+
+	var ticket struct {
+		Token string `json:"token"`
+	}
+	if err := a.postKillSwitch(ctx, "/api/v1/trade/flatten", map[string]string{
+		"source":       "risk_agent",
+		"requested_by": a.config.AgentName,
+		"reason":       reason,
+	}, &ticket); err != nil {
+		return fmt.Errorf("failed to arm kill switch: %w", err)
+	}
+
+	var result struct {
+		Message string `json:"message"`
+	}
+	if err := a.postKillSwitch(ctx, "/api/v1/trade/flatten/confirm", map[string]string{
+		"token": ticket.Token,
+	}, &result); err != nil {
+		return fmt.Errorf("failed to confirm kill switch: %w", err)
+	}
+
+	log.Warn().Str("result", result.Message).Msg("Kill switch executed by risk agent")
+	return nil
+}
+
+// postKillSwitch sends a JSON request to the kill switch API and decodes the response
+func (a *RiskAgent) postKillSwitch(ctx context.Context, path string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.KillSwitchURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.config.KillSwitchAPIKey != "" {
+		req.Header.Set("X-API-Key", a.config.KillSwitchAPIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 207 means the kill switch ran but some steps failed; the API logs and audits the details
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("kill switch API returned status %d: %s", resp.StatusCode, respBody)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
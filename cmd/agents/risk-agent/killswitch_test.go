@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckKillSwitch_TripsOnceOnDrawdownBreach(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	var armBody map[string]string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+
+		switch r.URL.Path {
+		case "/api/v1/trade/flatten":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&armBody))
+			_ = json.NewEncoder(w).Encode(map[string]string{"token": "abc123"})
+		case "/api/v1/trade/flatten/confirm":
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "abc123", body["token"])
+			_ = json.NewEncoder(w).Encode(map[string]string{"message": "flattened"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	agent := createTestRiskAgent()
+	agent.config.FlattenOnDrawdown = true
+	agent.config.KillSwitchURL = server.URL
+	agent.config.KillSwitchAPIKey = "secret"
+	ctx := context.Background()
+
+	// Below the limit nothing happens
+	agent.beliefs.currentDrawdown = 15
+	agent.checkKillSwitch(ctx)
+	assert.Empty(t, calls)
+
+	// Breach trips the kill switch exactly once
+	agent.beliefs.currentDrawdown = 25
+	agent.checkKillSwitch(ctx)
+	agent.checkKillSwitch(ctx)
+	assert.Equal(t, []string{"/api/v1/trade/flatten", "/api/v1/trade/flatten/confirm"}, calls)
+	assert.Equal(t, "risk_agent", armBody["source"])
+	assert.Contains(t, armBody["reason"], "25.0%")
+
+	// Recovery re-arms the latch
+	agent.beliefs.currentDrawdown = 10
+	agent.checkKillSwitch(ctx)
+	agent.beliefs.currentDrawdown = 22
+	agent.checkKillSwitch(ctx)
+	assert.Len(t, calls, 4)
+}
+
+func TestCheckKillSwitch_RetriesAfterFailure(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	agent := createTestRiskAgent()
+	agent.config.FlattenOnDrawdown = true
+	agent.config.KillSwitchURL = server.URL
+	agent.beliefs.currentDrawdown = 30
+
+	agent.checkKillSwitch(context.Background())
+	assert.False(t, agent.flattenTripped, "failed trip must not latch")
+	agent.checkKillSwitch(context.Background())
+	assert.Equal(t, 2, attempts)
+}
+
+func TestCheckKillSwitch_Disabled(t *testing.T) {
+	agent := createTestRiskAgent()
+	agent.config.KillSwitchURL = "http://127.0.0.1:0"
+	agent.beliefs.currentDrawdown = 50
+
+	agent.checkKillSwitch(context.Background())
+	assert.False(t, agent.flattenTripped)
+}
@@ -8,8 +8,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -22,6 +24,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/exchange"
 	"github.com/ajitpratap0/cryptofunk/internal/llm"
 	"github.com/ajitpratap0/cryptofunk/internal/metrics"
 	"github.com/ajitpratap0/cryptofunk/internal/orchestrator"
@@ -52,6 +55,22 @@ type RiskAgentConfig struct {
 	KellyFraction      float64 `mapstructure:"kelly_fraction"`
 	StopLossMultiplier float64 `mapstructure:"stop_loss_multiplier"`
 	RiskFreeRate       float64 `mapstructure:"risk_free_rate"`
+	Exchange           string  `mapstructure:"exchange"`            // Exchange whose instrument rules and balances drive sizing
+	QuoteAsset         string  `mapstructure:"quote_asset"`         // Asset that funds new positions (e.g., USDT)
+	FlattenOnDrawdown  bool    `mapstructure:"flatten_on_drawdown"` // Trip the global kill switch when max drawdown is breached
+	KillSwitchURL      string  `mapstructure:"kill_switch_url"`     // API server that hosts the kill switch
+	KillSwitchAPIKey   string  `mapstructure:"kill_switch_api_key"` // API key for trading control endpoints (optional)
+	MaxVaR95           float64 `mapstructure:"max_var_95"`          // Max 95% VaR as a fraction of exposure; the active strategy's limit takes precedence (0 = none)
+
+	MaxCorrelation        float64 `mapstructure:"max_correlation"`         // Symbols correlated at or above this count as one exposure; the active strategy's limit takes precedence (0 = none)
+	MaxCorrelatedExposure float64 `mapstructure:"max_correlated_exposure"` // Max exposure to correlated symbols as a fraction of max_total_exposure
+
+	SizingMethod              string  `mapstructure:"sizing_method"`               // kelly, vol_target or atr
+	TargetVolatility          float64 `mapstructure:"target_volatility"`           // Annualized volatility each vol_target position is sized to
+	PortfolioTargetVolatility float64 `mapstructure:"portfolio_target_volatility"` // Cap on annualized portfolio volatility in vol_target mode (0 = none)
+	RiskPerTrade              float64 `mapstructure:"risk_per_trade"`              // Share of max_total_exposure lost when an ATR stop is hit
+	ATRPeriod                 int     `mapstructure:"atr_period"`                  // Candles in the ATR
+	ATRMultiplier             float64 `mapstructure:"atr_multiplier"`              // ATR stop distance in ATRs
 }
 
 // ============================================================================
@@ -64,10 +83,14 @@ type RiskAgent struct {
 	config *RiskAgentConfig
 
 	// Services
-	db          *db.DB
-	riskService *risk.Service
-	calculator  *risk.Calculator // Database-backed risk calculator
-	natsConn    *nats.Conn
+	db           *db.DB
+	riskService  *risk.Service
+	calculator   *risk.Calculator         // Database-backed risk calculator
+	correlations *risk.CorrelationService // Candlestick return correlations, cached per interval
+	natsConn     *nats.Conn
+
+	// Exchange trading rules (lot size, min notional) so recommended sizes are placeable
+	instruments *exchange.InstrumentRegistry
 
 	// LLM client for AI-powered risk analysis
 	llmClient     llm.LLMClient // Interface supports both Client and FallbackClient
@@ -89,6 +112,12 @@ type RiskAgent struct {
 	approvalCount  int64
 	totalDecisions int64
 
+	// Kill switch latch: set once the drawdown breach has flattened the book
+	flattenTripped bool
+
+	// Plans de-risking steps from the active strategy's de-risking policies
+	deRisker *risk.DeRisker
+
 	// Metrics
 	metricsServer *metrics.Server
 	riskMetrics   *RiskMetrics
@@ -105,6 +134,7 @@ type RiskMetrics struct {
 	SharpeRatio       prometheus.Gauge
 	OpenPositions     prometheus.Gauge
 	LimitsUtilization prometheus.Gauge
+	PortfolioVaR95    prometheus.Gauge
 	AgentStatus       prometheus.Gauge
 }
 
@@ -117,6 +147,11 @@ type RiskBeliefs struct {
 	totalExposure     float64
 	openPositionCount int
 
+	// Account funds (from exchange balances)
+	balancesKnown bool
+	cashBalance   float64 // Free quote-asset balance available for new positions
+	accountEquity float64 // Cash plus open position exposure
+
 	// Performance metrics
 	equityCurve     []float64
 	returns         []float64
@@ -127,12 +162,19 @@ type RiskBeliefs struct {
 
 	// Market conditions
 	volatility   float64
-	marketRegime string // "bullish", "bearish", "sideways"
+	marketRegime string // "bullish", "bearish", "sideways", "volatile"
 	lastUpdate   time.Time
 
 	// Risk limits status
 	limitsUtilization float64 // 0.0 to 1.0
 	nearLimitSymbols  []string
+
+	// Value at Risk (fractions of gross exposure)
+	portfolioVaR95 float64
+	maxVaR95       float64 // 0 = no limit
+
+	// Correlation threshold for correlated exposure (0 = no limit)
+	maxCorrelation float64
 }
 
 // RiskDesires represents the agent's goals
@@ -168,12 +210,45 @@ type RiskIntentions struct {
 // Position represents a trading position (matches internal/risk)
 type Position struct {
 	Symbol       string  `json:"symbol"`
-	Size         float64 `json:"size"`
+	Side         string  `json:"side"` // LONG or SHORT; empty means LONG
+	Size         float64 `json:"size"` // Gross notional, positive for either side
 	EntryPrice   float64 `json:"entry_price"`
 	CurrentPrice float64 `json:"current_price"`
 	UnrealizedPL float64 `json:"unrealized_pl"`
 }
 
+// signedSize returns the position's notional, negative for shorts
+func (p Position) signedSize() float64 {
+	if p.Side == "SHORT" {
+		return -p.Size
+	}
+	return p.Size
+}
+
+// exposureChange returns the signed notional a proposal adds to symbol's
+// position, negative for SELL, and whether it reduces the position's exposure
+// (a SELL against a long or a BUY against a short)
+func (a *RiskAgent) exposureChange(symbol, action string, size float64) (float64, bool) {
+	change := 0.0
+	switch action {
+	case "BUY":
+		change = size
+	case "SELL":
+		change = -size
+	}
+
+	a.beliefs.mu.RLock()
+	current := 0.0
+	for _, position := range a.beliefs.currentPositions {
+		if strings.EqualFold(position.Symbol, symbol) {
+			current += position.signedSize()
+		}
+	}
+	a.beliefs.mu.RUnlock()
+
+	return change, math.Abs(current+change) <= math.Abs(current)
+}
+
 // ============================================================================
 // INITIALIZATION
 // ============================================================================
@@ -211,6 +286,14 @@ func main() {
 	viper.SetDefault("risk_agent.kelly_fraction", 0.25)
 	viper.SetDefault("risk_agent.stop_loss_multiplier", 2.0)
 	viper.SetDefault("risk_agent.risk_free_rate", 0.03)
+	viper.SetDefault("risk_agent.exchange", "mock")
+	viper.SetDefault("risk_agent.quote_asset", "USDT")
+	viper.SetDefault("risk_agent.max_correlated_exposure", 0.5)
+	viper.SetDefault("risk_agent.sizing_method", "kelly")
+	viper.SetDefault("risk_agent.target_volatility", 0.20)
+	viper.SetDefault("risk_agent.risk_per_trade", 0.01)
+	viper.SetDefault("risk_agent.atr_period", risk.DefaultATRPeriod)
+	viper.SetDefault("risk_agent.atr_multiplier", risk.DefaultATRMultiplier)
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Warn().Err(err).Msg("No config file found, using defaults")
@@ -277,6 +360,53 @@ func main() {
 	if config.RiskFreeRate == 0 {
 		config.RiskFreeRate = viper.GetFloat64("risk_agent.risk_free_rate")
 	}
+	if config.Exchange == "" {
+		config.Exchange = viper.GetString("risk_agent.exchange")
+	}
+	if config.QuoteAsset == "" {
+		config.QuoteAsset = viper.GetString("risk_agent.quote_asset")
+	}
+	if !config.FlattenOnDrawdown {
+		config.FlattenOnDrawdown = viper.GetBool("risk_agent.flatten_on_drawdown")
+	}
+	if config.KillSwitchURL == "" {
+		config.KillSwitchURL = viper.GetString("risk_agent.kill_switch_url")
+	}
+	if config.KillSwitchAPIKey == "" {
+		config.KillSwitchAPIKey = os.Getenv("RISK_AGENT_API_KEY")
+	}
+	if config.MaxVaR95 == 0 {
+		config.MaxVaR95 = viper.GetFloat64("risk_agent.max_var_95")
+	}
+	if config.MaxCorrelation == 0 {
+		config.MaxCorrelation = viper.GetFloat64("risk_agent.max_correlation")
+	}
+	if config.MaxCorrelatedExposure == 0 {
+		config.MaxCorrelatedExposure = viper.GetFloat64("risk_agent.max_correlated_exposure")
+	}
+	if config.SizingMethod == "" {
+		config.SizingMethod = viper.GetString("risk_agent.sizing_method")
+	}
+	if config.TargetVolatility == 0 {
+		config.TargetVolatility = viper.GetFloat64("risk_agent.target_volatility")
+	}
+	if config.PortfolioTargetVolatility == 0 {
+		config.PortfolioTargetVolatility = viper.GetFloat64("risk_agent.portfolio_target_volatility")
+	}
+	if config.RiskPerTrade == 0 {
+		config.RiskPerTrade = viper.GetFloat64("risk_agent.risk_per_trade")
+	}
+	if config.ATRPeriod == 0 {
+		config.ATRPeriod = viper.GetInt("risk_agent.atr_period")
+	}
+	if config.ATRMultiplier == 0 {
+		config.ATRMultiplier = viper.GetFloat64("risk_agent.atr_multiplier")
+	}
+	sizingMethod, err := risk.ParseSizingMethod(config.SizingMethod)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid sizing_method")
+	}
+	config.SizingMethod = string(sizingMethod)
 
 	log.Info().
 		Str("agent_name", config.AgentName).
@@ -285,6 +415,7 @@ func main() {
 		Float64("max_position_size", config.MaxPositionSize).
 		Float64("max_total_exposure", config.MaxTotalExposure).
 		Float64("max_drawdown", config.MaxDrawdownPercent).
+		Str("sizing_method", config.SizingMethod).
 		Msg("Configuration loaded")
 
 	// Create context
@@ -465,6 +596,10 @@ func NewRiskAgent(config *RiskAgentConfig, database *db.DB, riskService *risk.Se
 			Name: "risk_agent_limits_utilization",
 			Help: "Portfolio limits utilization (0.0 to 1.0)",
 		}),
+		PortfolioVaR95: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "risk_agent_portfolio_var_95",
+			Help: "Historical 95% Value at Risk of open positions as a fraction of exposure",
+		}),
 		AgentStatus: promauto.NewGauge(prometheus.GaugeOpts{
 			Name: "risk_agent_status",
 			Help: "Risk agent status (1=running, 0=stopped)",
@@ -479,6 +614,9 @@ func NewRiskAgent(config *RiskAgentConfig, database *db.DB, riskService *risk.Se
 		db:            database,
 		riskService:   riskService,
 		calculator:    calculator,
+		correlations:  risk.NewCorrelationService(calculator),
+		instruments:   exchange.NewInstrumentRegistry(exchange.DefaultInstruments()...),
+		deRisker:      risk.NewDeRisker(risk.DeRiskPolicy{}),
 		llmClient:     llmClient,
 		promptBuilder: promptBuilder,
 		useLLM:        useLLM,
@@ -534,6 +672,11 @@ func (a *RiskAgent) Initialize(ctx context.Context) error {
 		log.Warn().Err(err).Msg("Failed to load initial portfolio state")
 	}
 
+	// Load exchange instrument rules used to keep recommended sizes placeable
+	if err := a.loadInstruments(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to load instrument rules, using defaults")
+	}
+
 	// Start metrics server
 	if a.metricsServer != nil {
 		if err := a.metricsServer.Start(); err != nil {
@@ -739,8 +882,48 @@ func (a *RiskAgent) getSymbolExposure(symbol string) float64 {
 // T121: KELLY CRITERION POSITION SIZING
 // ============================================================================
 
-// calculateOptimalSize calculates optimal position size using Kelly Criterion
+// calculateOptimalSize calculates the optimal position size with the configured
+// sizing method. Volatility-target and ATR sizing fall back to Kelly when the
+// symbol's candles cannot be loaded.
 func (a *RiskAgent) calculateOptimalSize(ctx context.Context, symbol string, confidence float64) float64 {
+	var optimalSize float64
+	var err error
+	switch risk.SizingMethod(a.config.SizingMethod) {
+	case risk.SizingMethodVolTarget:
+		optimalSize, err = a.volTargetSize(ctx, symbol)
+	case risk.SizingMethodATR:
+		optimalSize, err = a.atrSize(ctx, symbol)
+	default:
+		optimalSize = a.kellySize(ctx, symbol, confidence)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("symbol", symbol).Str("sizing_method", a.config.SizingMethod).
+			Msg("Sizing method unavailable, falling back to Kelly")
+		optimalSize = a.kellySize(ctx, symbol, confidence)
+	}
+
+	// Cap at max position size
+	if optimalSize > a.config.MaxPositionSize {
+		optimalSize = a.config.MaxPositionSize
+	}
+
+	// Never recommend more than the funds actually available
+	a.beliefs.mu.RLock()
+	if a.beliefs.balancesKnown && optimalSize > a.beliefs.cashBalance {
+		optimalSize = math.Max(a.beliefs.cashBalance, 0)
+	}
+	a.beliefs.mu.RUnlock()
+
+	// Snap to the exchange lot size so the recommendation can actually be placed
+	if price, err := a.calculator.GetCurrentPrice(ctx, symbol, "1h"); err == nil {
+		optimalSize = a.placeableSize(symbol, optimalSize, price)
+	}
+
+	return optimalSize
+}
+
+// kellySize sizes a position with the Kelly Criterion from historical performance
+func (a *RiskAgent) kellySize(ctx context.Context, symbol string, confidence float64) float64 {
 	// Get historical performance for this symbol or overall portfolio
 	winRate := a.getHistoricalWinRate(ctx, symbol)
 	avgWin := a.getHistoricalAvgWin(ctx, symbol)
@@ -774,12 +957,58 @@ func (a *RiskAgent) calculateOptimalSize(ctx context.Context, symbol string, con
 		optimalSize = maxSize
 	}
 
-	// Cap at max position size
-	if optimalSize > a.config.MaxPositionSize {
-		optimalSize = a.config.MaxPositionSize
+	return optimalSize
+}
+
+// placeableSize rounds a USD position size down to a whole number of lot steps at the
+// given price. Sizes below the exchange minimum quantity or notional become zero.
+// Symbols without known instrument rules are returned unchanged.
+func (a *RiskAgent) placeableSize(symbol string, size float64, price float64) float64 {
+	if a.instruments == nil || price <= 0 {
+		return size
 	}
 
-	return optimalSize
+	inst, ok := a.instruments.Get(symbol)
+	if !ok {
+		return size
+	}
+
+	qty := inst.QuantityForNotional(size, price)
+	if qty == 0 && size > 0 {
+		log.Debug().
+			Str("symbol", symbol).
+			Float64("size", size).
+			Float64("min_notional", inst.MinNotional).
+			Msg("Recommended size is below exchange minimums")
+	}
+
+	return qty * price
+}
+
+// loadInstruments refreshes instrument rules published by the order executor
+func (a *RiskAgent) loadInstruments(ctx context.Context) error {
+	if a.db == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := a.db.GetInstruments(ctx, a.config.Exchange)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		a.instruments.Set(exchange.InstrumentFromDB(row))
+	}
+
+	log.Info().
+		Str("exchange", a.config.Exchange).
+		Int("instruments", len(rows)).
+		Msg("Instrument rules loaded")
+
+	return nil
 }
 
 // getHistoricalWinRate returns historical win rate for symbol (or overall)
@@ -877,9 +1106,19 @@ func (a *RiskAgent) updateBeliefs(ctx context.Context) error {
 	// Calculate performance metrics
 	a.calculatePerformanceMetrics(ctx)
 
+	// Flatten everything if the drawdown circuit breaker is breached
+	a.checkKillSwitch(ctx)
+
 	// Assess market conditions
 	a.assessMarketConditions(ctx)
 
+	// Refresh the active strategy's VaR and correlation limits and the VaR of open positions
+	a.loadStrategyLimits(ctx)
+	a.updatePortfolioVaR(ctx)
+
+	// Cut positions step by step when a de-risking policy is breached
+	a.checkDeRisking(ctx)
+
 	// Update limits utilization
 	a.beliefs.mu.Lock()
 	if a.config.MaxTotalExposure > 0 {
@@ -897,6 +1136,7 @@ func (a *RiskAgent) updateBeliefs(ctx context.Context) error {
 		a.riskMetrics.SharpeRatio.Set(a.beliefs.sharpeRatio)
 		a.riskMetrics.OpenPositions.Set(float64(a.beliefs.openPositionCount))
 		a.riskMetrics.LimitsUtilization.Set(a.beliefs.limitsUtilization)
+		a.riskMetrics.PortfolioVaR95.Set(a.beliefs.portfolioVaR95)
 		a.beliefs.mu.RUnlock()
 	}
 
@@ -924,11 +1164,12 @@ func (a *RiskAgent) loadPortfolioState(ctx context.Context) error {
 	query := `
 		SELECT symbol,
 		       SUM(CASE WHEN side = 'LONG' THEN quantity ELSE -quantity END) as net_quantity,
-		       AVG(CASE WHEN side = 'LONG' THEN entry_price ELSE NULL END) as avg_entry_price
+		       AVG(CASE WHEN side = 'LONG' THEN entry_price ELSE NULL END) as avg_long_entry_price,
+		       AVG(CASE WHEN side = 'SHORT' THEN entry_price ELSE NULL END) as avg_short_entry_price
 		FROM positions
 		WHERE exit_time IS NULL
 		GROUP BY symbol
-		HAVING SUM(CASE WHEN side = 'LONG' THEN quantity ELSE -quantity END) > 0
+		HAVING SUM(CASE WHEN side = 'LONG' THEN quantity ELSE -quantity END) <> 0
 	`
 
 	rows, err := a.db.Pool().Query(ctx, query)
@@ -942,31 +1183,102 @@ func (a *RiskAgent) loadPortfolioState(ctx context.Context) error {
 
 	for rows.Next() {
 		var symbol string
-		var quantity, entryPrice float64
+		var quantity float64
+		var longEntry, shortEntry *float64
 
-		if err := rows.Scan(&symbol, &quantity, &entryPrice); err != nil {
+		if err := rows.Scan(&symbol, &quantity, &longEntry, &shortEntry); err != nil {
 			return fmt.Errorf("failed to scan position: %w", err)
 		}
 
-		size := quantity * entryPrice
+		// A net short is valued at the short entries
+		side, entryPrice := "LONG", longEntry
+		if quantity < 0 {
+			side, entryPrice, quantity = "SHORT", shortEntry, -quantity
+		}
+		if entryPrice == nil {
+			continue
+		}
+
+		size := quantity * *entryPrice
 		positions = append(positions, Position{
 			Symbol:     symbol,
+			Side:       side,
 			Size:       size,
-			EntryPrice: entryPrice,
+			EntryPrice: *entryPrice,
 		})
 
 		totalExposure += size
 	}
 
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating positions: %w", err)
+	}
+
+	// Load actual account funds so sizing is based on real balances
+	balances, err := a.db.GetAccountBalances(ctx, a.config.Exchange)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load account balances")
+	}
+
 	a.beliefs.mu.Lock()
 	a.beliefs.currentPositions = positions
 	a.beliefs.totalExposure = totalExposure
 	a.beliefs.openPositionCount = len(positions)
+	if err == nil && len(balances) > 0 {
+		a.applyBalances(balances)
+	}
 	a.beliefs.mu.Unlock()
 
 	return nil
 }
 
+// loadStrategyLimits refreshes the max 95% VaR and max correlation beliefs and
+// the de-risking policy. The active strategy's risk settings take precedence
+// over the agent's configuration.
+func (a *RiskAgent) loadStrategyLimits(ctx context.Context) {
+	maxVaR95 := a.config.MaxVaR95
+	maxCorrelation := a.config.MaxCorrelation
+
+	if a.db != nil {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		active, err := db.NewStrategyRepository(a.db).GetActive(ctx)
+		if err != nil {
+			log.Debug().Err(err).Msg("No active strategy, using configured risk limits")
+		} else {
+			if active.Risk.MaxVaR95 > 0 {
+				maxVaR95 = active.Risk.MaxVaR95
+			}
+			if active.Risk.MaxCorrelation > 0 {
+				maxCorrelation = active.Risk.MaxCorrelation
+			}
+			if a.deRisker != nil {
+				a.deRisker.SetPolicy(deRiskPolicy(active.Risk.DeRisking))
+			}
+		}
+	}
+
+	a.beliefs.mu.Lock()
+	a.beliefs.maxVaR95 = maxVaR95
+	a.beliefs.maxCorrelation = maxCorrelation
+	a.beliefs.mu.Unlock()
+}
+
+// applyBalances updates cash and equity beliefs from exchange balances (caller must hold beliefs lock)
+func (a *RiskAgent) applyBalances(balances []*db.AccountBalance) {
+	cash := 0.0
+	for _, b := range balances {
+		if b.Asset == a.config.QuoteAsset {
+			cash = b.Free
+		}
+	}
+
+	a.beliefs.balancesKnown = true
+	a.beliefs.cashBalance = cash
+	a.beliefs.accountEquity = cash + a.beliefs.totalExposure
+}
+
 // calculatePerformanceMetrics calculates Sharpe, drawdown, etc.
 func (a *RiskAgent) calculatePerformanceMetrics(ctx context.Context) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
@@ -1030,13 +1342,19 @@ func (a *RiskAgent) assessMarketConditions(ctx context.Context) {
 		return
 	}
 
+	// The regime service's HMM estimate takes precedence over the moving-average heuristic
+	marketRegime := regimeData.Regime
+	if estimate := a.latestRegime(ctx, "BTC/USDT"); estimate != nil {
+		marketRegime = string(estimate.Regime)
+	}
+
 	a.beliefs.mu.Lock()
-	a.beliefs.marketRegime = regimeData.Regime
+	a.beliefs.marketRegime = marketRegime
 	a.beliefs.volatility = regimeData.Volatility
 	a.beliefs.mu.Unlock()
 
 	log.Debug().
-		Str("regime", regimeData.Regime).
+		Str("regime", marketRegime).
 		Float64("volatility", regimeData.Volatility).
 		Float64("short_ma", regimeData.ShortMA).
 		Float64("long_ma", regimeData.LongMA).
@@ -1086,7 +1404,9 @@ func (a *RiskAgent) evaluateProposalWithLLM(ctx context.Context, symbol string,
 
 	a.beliefs.mu.RLock()
 	portfolioValue := a.config.MaxPositionSize * 10.0 // Estimate portfolio as 10x max position
-	if a.beliefs.totalExposure > 0 {
+	if a.beliefs.balancesKnown && a.beliefs.accountEquity > 0 {
+		portfolioValue = a.beliefs.accountEquity // Actual funds from exchange balances
+	} else if a.beliefs.totalExposure > 0 {
 		portfolioValue = a.beliefs.totalExposure / 0.8 // Assume 80% utilization
 	}
 	a.beliefs.mu.RUnlock()
@@ -1179,6 +1499,16 @@ func (a *RiskAgent) evaluateProposalWithLLM(ctx context.Context, symbol string,
 
 // evaluateProposalRuleBased performs rule-based risk assessment
 func (a *RiskAgent) evaluateProposalRuleBased(ctx context.Context, symbol string, action string, size float64, confidence float64) *RiskIntentions {
+	// VaR and correlations query the database, so they are projected before
+	// the beliefs lock is taken for the checks. A SELL can open or grow a
+	// short, so only proposals that reduce the position skip them.
+	varWithinLimit, projectedVaR := true, 0.0
+	correlatedWithinLimit, correlatedExposure, correlatedLimit := true, 0.0, 0.0
+	if change, reduces := a.exposureChange(symbol, action, size); !reduces {
+		varWithinLimit, projectedVaR = a.checkVaRLimit(ctx, symbol, change)
+		correlatedWithinLimit, correlatedExposure, correlatedLimit = a.checkCorrelatedExposure(ctx, symbol, change)
+	}
+
 	a.beliefs.mu.RLock()
 	defer a.beliefs.mu.RUnlock()
 
@@ -1211,7 +1541,27 @@ func (a *RiskAgent) evaluateProposalRuleBased(ctx context.Context, symbol string
 		return intentions
 	}
 
-	// Check 3: Approaching drawdown limit (80% of limit)
+	// Check 3: Projected portfolio VaR (unless the proposal reduces the position)
+	if !varWithinLimit {
+		intentions.shouldVeto = true
+		intentions.vetoReason = fmt.Sprintf(
+			"Projected 95%% VaR %.2f%% exceeds maximum %.2f%%",
+			projectedVaR*100, a.beliefs.maxVaR95*100)
+		intentions.confidenceScore = 0.90
+		return intentions
+	}
+
+	// Check 4: Correlated exposure (unless the proposal reduces the position)
+	if !correlatedWithinLimit {
+		intentions.shouldVeto = true
+		intentions.vetoReason = fmt.Sprintf(
+			"Exposure correlated above %.2f with %s $%.2f would exceed maximum $%.2f",
+			a.beliefs.maxCorrelation, symbol, correlatedExposure, correlatedLimit)
+		intentions.confidenceScore = 0.90
+		return intentions
+	}
+
+	// Check 5: Approaching drawdown limit (80% of limit)
 	drawdownWarningLevel := a.config.MaxDrawdownPercent * 0.80
 	if a.beliefs.currentDrawdown > drawdownWarningLevel && action == "BUY" {
 		intentions.shouldVeto = true
@@ -1222,7 +1572,7 @@ func (a *RiskAgent) evaluateProposalRuleBased(ctx context.Context, symbol string
 		return intentions
 	}
 
-	// Check 4: High volatility + high utilization
+	// Check 6: High volatility + high utilization
 	highVolatilityThreshold := 0.04  // 4%
 	highUtilizationThreshold := 0.85 // 85%
 	if a.beliefs.volatility > highVolatilityThreshold &&
@@ -1236,19 +1586,19 @@ func (a *RiskAgent) evaluateProposalRuleBased(ctx context.Context, symbol string
 		return intentions
 	}
 
-	// Check 5: Position sizing recommendation
+	// Check 7: Position sizing recommendation
 	optimalSize := a.calculateOptimalSize(ctx, symbol, confidence)
 	if size > optimalSize*1.5 { // Allow 50% over optimal
 		intentions.shouldVeto = false // Don't veto, but recommend smaller size
 		intentions.recommendedSize = optimalSize
 		intentions.vetoReason = fmt.Sprintf(
-			"Recommended size $%.2f (requested $%.2f) - Kelly Criterion suggests smaller position",
-			optimalSize, size)
+			"Recommended size $%.2f (requested $%.2f) - %s sizing suggests smaller position",
+			optimalSize, size, sizingMethodName(a.config.SizingMethod))
 		intentions.confidenceScore = 0.70
 		return intentions
 	}
 
-	// Check 6: Concentration risk
+	// Check 8: Concentration risk
 	symbolExposure := a.getSymbolExposure(symbol)
 	if a.beliefs.totalExposure > 0 {
 		currentConcentration := symbolExposure / a.beliefs.totalExposure
@@ -1269,7 +1619,7 @@ func (a *RiskAgent) evaluateProposalRuleBased(ctx context.Context, symbol string
 
 	// Calculate stop loss using current market price
 	currentPrice := a.getCurrentPrice(ctx, symbol)
-	intentions.stopLossLevel = a.calculateStopLoss(currentPrice, action)
+	intentions.stopLossLevel = a.stopLossFor(ctx, symbol, currentPrice, action)
 
 	return intentions
 }
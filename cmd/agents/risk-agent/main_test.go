@@ -9,6 +9,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/exchange"
 	"github.com/ajitpratap0/cryptofunk/internal/risk"
 )
 
@@ -229,6 +231,52 @@ func TestCalculateOptimalSize_CappedAtMaxPositionSize(t *testing.T) {
 	assert.LessOrEqual(t, optimalSize, agent.config.MaxPositionSize)
 }
 
+func TestPlaceableSize_RoundsToLotSize(t *testing.T) {
+	agent := createTestRiskAgent()
+	agent.instruments = exchange.NewInstrumentRegistry(exchange.Instrument{
+		Symbol:      "BTCUSDT",
+		TickSize:    0.01,
+		StepSize:    0.001,
+		MinQty:      0.001,
+		MinNotional: 10,
+	})
+
+	// $1234 at $50,000 = 0.02468 BTC -> rounded down to 0.024 BTC = $1200
+	assert.InDelta(t, 1200.0, agent.placeableSize("BTC/USDT", 1234, 50000), 1e-9)
+
+	// Below one lot step the size is not placeable
+	assert.Zero(t, agent.placeableSize("BTC/USDT", 40, 50000))
+
+	// Unknown symbols and missing prices are left unchanged
+	assert.Equal(t, 1234.0, agent.placeableSize("DOGE/USDT", 1234, 0.1))
+	assert.Equal(t, 1234.0, agent.placeableSize("BTC/USDT", 1234, 0))
+}
+
+func TestCalculateOptimalSize_CappedAtCashBalance(t *testing.T) {
+	agent := createTestRiskAgent()
+	agent.config.QuoteAsset = "USDT"
+	ctx := context.Background()
+
+	uncapped := agent.calculateOptimalSize(ctx, "BTC/USDT", 0.9)
+	require.Greater(t, uncapped, 100.0)
+
+	agent.beliefs.totalExposure = 2000
+	agent.applyBalances([]*db.AccountBalance{
+		{Exchange: "mock", Asset: "USDT", Free: 100, Locked: 50},
+		{Exchange: "mock", Asset: "BTC", Free: 0.04},
+	})
+	assert.True(t, agent.beliefs.balancesKnown)
+	assert.Equal(t, 100.0, agent.beliefs.cashBalance)
+	assert.Equal(t, 2100.0, agent.beliefs.accountEquity)
+
+	assert.Equal(t, 100.0, agent.calculateOptimalSize(ctx, "BTC/USDT", 0.9))
+}
+
+func TestPlaceableSize_NoRegistry(t *testing.T) {
+	agent := createTestRiskAgent()
+	assert.Equal(t, 500.0, agent.placeableSize("BTC/USDT", 500, 50000))
+}
+
 // ============================================================================
 // T122: STOP-LOSS CALCULATION TESTS
 // ============================================================================
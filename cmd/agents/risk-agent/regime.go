@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/regime"
+)
+
+// regimeMaxAge is how old a stored regime estimate may be before it is ignored
+const regimeMaxAge = 2 * time.Hour
+
+// latestRegime returns the regime service's latest estimate for a symbol, or
+// nil when there is no recent one
+func (a *RiskAgent) latestRegime(ctx context.Context, symbol string) *regime.Estimate {
+	if a.db == nil {
+		return nil
+	}
+	record, err := a.db.GetLatestMarketRegime(ctx, symbol)
+	if err != nil {
+		log.Debug().Err(err).Str("symbol", symbol).Msg("No market regime from the regime service")
+		return nil
+	}
+	if record == nil || time.Since(record.DetectedAt) > regimeMaxAge {
+		return nil
+	}
+	estimate, err := regime.FromRecord(record)
+	if err != nil {
+		log.Warn().Err(err).Str("symbol", symbol).Msg("Invalid stored market regime")
+		return nil
+	}
+	return estimate
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// Volatility targets use daily returns; ATR uses hourly candles
+const (
+	sizingVolatilityInterval = "1d"
+	sizingLookbackDays       = 30
+	portfolioVolLookbackDays = 90
+	atrInterval              = "1h"
+)
+
+// volTargetSize sizes symbol so its annualized volatility is the target share of
+// max_total_exposure, then caps it to the portfolio volatility target
+func (a *RiskAgent) volTargetSize(ctx context.Context, symbol string) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	volatility, err := a.calculator.LoadAnnualizedVolatility(ctx, symbol, sizingVolatilityInterval, sizingLookbackDays)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load volatility: %w", err)
+	}
+	sizing, err := risk.VolTargetSize(a.config.MaxTotalExposure, a.config.TargetVolatility, volatility, 1)
+	if err != nil {
+		return 0, err
+	}
+	return a.capToPortfolioVolTarget(ctx, symbol, sizing.Notional), nil
+}
+
+// capToPortfolioVolTarget limits size so that adding it keeps the portfolio's
+// annualized volatility within portfolio_target_volatility. Sizes are not
+// limited when the portfolio's returns cannot be loaded.
+func (a *RiskAgent) capToPortfolioVolTarget(ctx context.Context, symbol string, size float64) float64 {
+	target := a.config.PortfolioTargetVolatility
+	if target <= 0 {
+		return size
+	}
+
+	a.beliefs.mu.RLock()
+	positions := portfolioPositions(a.beliefs.currentPositions, symbol, 0)
+	a.beliefs.mu.RUnlock()
+
+	portfolio, err := a.calculator.LoadPortfolioReturns(ctx, positions, sizingVolatilityInterval, portfolioVolLookbackDays)
+	if err != nil {
+		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to load portfolio returns, skipping portfolio volatility target")
+		return size
+	}
+	capped, err := risk.MarginalVolTargetSize(portfolio, symbol, size, a.config.MaxTotalExposure, target,
+		risk.PeriodsPerYear(sizingVolatilityInterval))
+	if err != nil {
+		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to apply portfolio volatility target")
+		return size
+	}
+	return capped
+}
+
+// atrSize sizes symbol so that a stop atr_multiplier ATRs away loses
+// risk_per_trade of max_total_exposure
+func (a *RiskAgent) atrSize(ctx context.Context, symbol string) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	price, err := a.calculator.GetCurrentPrice(ctx, symbol, atrInterval)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load price: %w", err)
+	}
+	atr, err := a.calculator.LoadATR(ctx, symbol, atrInterval, a.config.ATRPeriod)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load ATR: %w", err)
+	}
+	sizing, err := risk.ATRSize(a.config.MaxTotalExposure, a.config.RiskPerTrade, price, atr, a.config.ATRMultiplier, "BUY", 1)
+	if err != nil {
+		return 0, err
+	}
+	return sizing.Notional, nil
+}
+
+// stopLossFor places the stop atr_multiplier ATRs from price in ATR mode, and
+// falls back to the volatility-based stop otherwise
+func (a *RiskAgent) stopLossFor(ctx context.Context, symbol string, price float64, side string) float64 {
+	if risk.SizingMethod(a.config.SizingMethod) == risk.SizingMethodATR && price > 0 {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		atr, err := a.calculator.LoadATR(ctx, symbol, atrInterval, a.config.ATRPeriod)
+		if err == nil {
+			return risk.ATRStopLoss(price, atr, a.config.ATRMultiplier, side)
+		}
+		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to load ATR, using volatility stop-loss")
+	}
+	return a.calculateStopLoss(price, side)
+}
+
+// sizingMethodName describes a sizing method in veto reasons
+func sizingMethodName(method string) string {
+	switch risk.SizingMethod(method) {
+	case risk.SizingMethodVolTarget:
+		return "Volatility-target"
+	case risk.SizingMethodATR:
+		return "ATR"
+	default:
+		return "Kelly Criterion"
+	}
+}
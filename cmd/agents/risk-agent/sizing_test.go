@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// expectATRCandles mocks the current price and hourly candles of a symbol with
+// a constant 10-point range
+func expectATRCandles(mock pgxmock.PgxPoolIface, symbol string, price float64) {
+	mock.ExpectQuery("SELECT close FROM candlesticks").
+		WithArgs(symbol, atrInterval).
+		WillReturnRows(pgxmock.NewRows([]string{"close"}).AddRow(price))
+
+	rows := pgxmock.NewRows([]string{"high", "low", "close"})
+	for i := 0; i < 4*risk.DefaultATRPeriod+1; i++ {
+		rows.AddRow(price+5, price-5, price)
+	}
+	mock.ExpectQuery("SELECT high, low, close FROM candlesticks").
+		WithArgs(symbol, atrInterval, 4*risk.DefaultATRPeriod+1).
+		WillReturnRows(rows)
+}
+
+func TestCalculateOptimalSize_VolTarget(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	agent := createTestRiskAgent()
+	agent.calculator = risk.NewCalculator(mock)
+	agent.config.SizingMethod = string(risk.SizingMethodVolTarget)
+	agent.config.TargetVolatility = 0.20
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	closes := []float64{100, 105, 100, 105, 100, 105}
+	rows := pgxmock.NewRows([]string{"close", "open_time"})
+	returns := make([]float64, 0, len(closes)-1)
+	for i, price := range closes {
+		rows.AddRow(price, start.AddDate(0, 0, i))
+		if i > 0 {
+			returns = append(returns, (price-closes[i-1])/closes[i-1])
+		}
+	}
+	mock.ExpectQuery("SELECT close, open_time FROM candlesticks").
+		WithArgs("ETH/USDT", sizingVolatilityInterval, sizingLookbackDays).
+		WillReturnRows(rows)
+
+	size := agent.calculateOptimalSize(context.Background(), "ETH/USDT", 0.8)
+
+	volatility := risk.AnnualizedVolatility(returns, risk.CryptoDaysPerYear)
+	assert.InDelta(t, 50000*0.20/volatility, size, 1e-6)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCalculateOptimalSize_ATR(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	agent := createTestRiskAgent()
+	agent.calculator = risk.NewCalculator(mock)
+	agent.config.SizingMethod = string(risk.SizingMethodATR)
+	agent.config.RiskPerTrade = 0.01
+	agent.config.ATRPeriod = risk.DefaultATRPeriod
+	agent.config.ATRMultiplier = 2
+
+	expectATRCandles(mock, "BTC/USDT", 105)
+
+	// $500 at risk over a 2×10 stop: 25 units at $105
+	size := agent.calculateOptimalSize(context.Background(), "BTC/USDT", 0.8)
+	assert.InDelta(t, 2625, size, 1e-6)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCalculateOptimalSize_FallsBackToKelly(t *testing.T) {
+	agent := createTestRiskAgent()
+	kelly := agent.calculateOptimalSize(context.Background(), "BTC/USDT", 0.8)
+
+	// Without candles volatility targeting cannot size the trade
+	agent.config.SizingMethod = string(risk.SizingMethodVolTarget)
+	agent.config.TargetVolatility = 0.20
+	assert.Equal(t, kelly, agent.calculateOptimalSize(context.Background(), "BTC/USDT", 0.8))
+}
+
+func TestCapToPortfolioVolTarget(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	agent := createTestRiskAgent()
+	agent.calculator = risk.NewCalculator(mock)
+
+	// No portfolio target: sizes pass through without loading returns
+	assert.Equal(t, 8000.0, agent.capToPortfolioVolTarget(context.Background(), "ETH/USDT", 8000))
+
+	agent.config.PortfolioTargetVolatility = 0.10
+	agent.beliefs.currentPositions = []Position{{Symbol: "BTC/USDT", Size: 2000}}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, symbol := range []string{"BTC/USDT", "ETH/USDT"} {
+		rows := pgxmock.NewRows([]string{"close", "open_time"})
+		for i, price := range []float64{100, 105, 100, 105, 100, 105} {
+			rows.AddRow(price, start.AddDate(0, 0, i))
+		}
+		mock.ExpectQuery("SELECT close, open_time FROM candlesticks").
+			WithArgs(symbol, sizingVolatilityInterval, portfolioVolLookbackDays).
+			WillReturnRows(rows)
+	}
+
+	// Both move in step, so the BTC holding uses up part of the ETH budget
+	size := agent.capToPortfolioVolTarget(context.Background(), "ETH/USDT", 1e6)
+	returns := []float64{0.05, -5.0 / 105, 0.05, -5.0 / 105, 0.05}
+	budget := 50000 * 0.10 / risk.AnnualizedVolatility(returns, risk.CryptoDaysPerYear)
+	assert.InDelta(t, budget-2000, size, 1e-6)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStopLossFor(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	agent := createTestRiskAgent()
+	agent.calculator = risk.NewCalculator(mock)
+
+	// Kelly mode keeps the volatility stop: 2% × 2 from entry
+	assert.InDelta(t, 96.0, agent.stopLossFor(context.Background(), "BTC/USDT", 100, "BUY"), 1e-9)
+
+	agent.config.SizingMethod = string(risk.SizingMethodATR)
+	agent.config.ATRPeriod = risk.DefaultATRPeriod
+	agent.config.ATRMultiplier = 3
+	rows := pgxmock.NewRows([]string{"high", "low", "close"})
+	for i := 0; i < 4*risk.DefaultATRPeriod+1; i++ {
+		rows.AddRow(105.0, 95.0, 100.0)
+	}
+	mock.ExpectQuery("SELECT high, low, close FROM candlesticks").
+		WithArgs("BTC/USDT", atrInterval, 4*risk.DefaultATRPeriod+1).
+		WillReturnRows(rows)
+
+	assert.InDelta(t, 130.0, agent.stopLossFor(context.Background(), "BTC/USDT", 100, "SELL"), 1e-9)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSizingMethodName(t *testing.T) {
+	assert.Equal(t, "Kelly Criterion", sizingMethodName(""))
+	assert.Equal(t, "Volatility-target", sizingMethodName("vol_target"))
+	assert.Equal(t, "ATR", sizingMethodName("atr"))
+}
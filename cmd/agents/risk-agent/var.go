@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// VaR is estimated at 95% confidence from daily returns over this lookback
+const (
+	varConfidenceLevel = 0.95
+	varInterval        = "1d"
+	varLookbackDays    = 90
+)
+
+// updatePortfolioVaR refreshes the 95% VaR belief of the open positions
+func (a *RiskAgent) updatePortfolioVaR(ctx context.Context) {
+	a.beliefs.mu.RLock()
+	positions := portfolioPositions(a.beliefs.currentPositions, "", 0)
+	a.beliefs.mu.RUnlock()
+
+	portfolioVaR := 0.0
+	if len(positions) > 0 {
+		var err error
+		if portfolioVaR, err = a.portfolioVaR(ctx, positions); err != nil {
+			log.Warn().Err(err).Msg("Failed to calculate portfolio VaR")
+			return
+		}
+	}
+
+	a.beliefs.mu.Lock()
+	a.beliefs.portfolioVaR95 = portfolioVaR
+	a.beliefs.mu.Unlock()
+}
+
+// checkVaRLimit reports whether adding size (quote currency, negative to sell)
+// to symbol keeps the portfolio's 95% VaR within the limit, along with the
+// projected VaR. Trades
+// are not blocked when VaR cannot be estimated. The positions are copied and
+// the beliefs lock is released before the estimate queries the database;
+// callers must not hold it.
+func (a *RiskAgent) checkVaRLimit(ctx context.Context, symbol string, size float64) (bool, float64) {
+	a.beliefs.mu.RLock()
+	limit := a.beliefs.maxVaR95
+	positions := portfolioPositions(a.beliefs.currentPositions, symbol, size)
+	a.beliefs.mu.RUnlock()
+
+	if limit <= 0 {
+		return true, 0
+	}
+
+	projected, err := a.portfolioVaR(ctx, positions)
+	if err != nil {
+		log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to project portfolio VaR, skipping VaR limit")
+		return true, 0
+	}
+	return projected <= limit, projected
+}
+
+// portfolioVaR estimates historical 95% VaR as a fraction of gross exposure
+func (a *RiskAgent) portfolioVaR(ctx context.Context, positions []risk.PortfolioPosition) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	estimate, err := a.calculator.CalculatePortfolioVaR(ctx, positions, varInterval, varLookbackDays,
+		risk.VaRMethodHistorical, risk.VaROptions{ConfidenceLevel: varConfidenceLevel})
+	if err != nil {
+		return 0, fmt.Errorf("failed to calculate portfolio VaR: %w", err)
+	}
+	return estimate.VaR, nil
+}
+
+// portfolioPositions converts positions to VaR engine positions, valuing shorts
+// negatively, and adds size to symbol's position when symbol is set
+func portfolioPositions(positions []Position, symbol string, size float64) []risk.PortfolioPosition {
+	result := make([]risk.PortfolioPosition, 0, len(positions)+1)
+	added := symbol == ""
+	for _, position := range positions {
+		value := position.signedSize()
+		if !added && strings.EqualFold(position.Symbol, symbol) {
+			value += size
+			added = true
+		}
+		result = append(result, risk.PortfolioPosition{Symbol: position.Symbol, Value: value})
+	}
+	if !added {
+		result = append(result, risk.PortfolioPosition{Symbol: symbol, Value: size})
+	}
+	return result
+}
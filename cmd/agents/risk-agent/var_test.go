@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// expectCandles mocks the daily closes of a symbol
+func expectCandles(mock pgxmock.PgxPoolIface, symbol string, closes ...float64) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := pgxmock.NewRows([]string{"close", "open_time"})
+	for i, price := range closes {
+		rows.AddRow(price, start.Add(time.Duration(i)*24*time.Hour))
+	}
+	mock.ExpectQuery("SELECT close, open_time FROM candlesticks").
+		WithArgs(symbol, varInterval, varLookbackDays).
+		WillReturnRows(rows)
+}
+
+func TestEvaluateProposal_VetoOnProjectedVaR(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	agent := createTestRiskAgent()
+	agent.calculator = risk.NewCalculator(mock)
+	agent.beliefs.maxVaR95 = 0.05
+	agent.beliefs.currentPositions = []Position{{Symbol: "BTC/USDT", Size: 5000, EntryPrice: 100}}
+	agent.beliefs.totalExposure = 5000
+	agent.beliefs.openPositionCount = 1
+
+	// Both assets swing 10-20% a day, in step
+	expectCandles(mock, "BTC/USDT", 100, 110, 90, 105, 85, 100, 120, 95)
+	expectCandles(mock, "ETH/USDT", 10, 11, 9, 10.5, 8.5, 10, 12, 9.5)
+
+	intentions := agent.evaluateProposal(context.Background(), "ETH/USDT", "BUY", 3000.0, 0.8)
+
+	assert.True(t, intentions.shouldVeto)
+	assert.Contains(t, intentions.vetoReason, "VaR")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEvaluateProposal_VetoOnProjectedVaRForShortSell(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	agent := createTestRiskAgent()
+	agent.calculator = risk.NewCalculator(mock)
+	agent.beliefs.maxVaR95 = 0.05
+
+	// Opening a short on an asset that swings 10-20% a day
+	expectCandles(mock, "ETH/USDT", 10, 11, 9, 10.5, 8.5, 10, 12, 9.5)
+
+	intentions := agent.evaluateProposal(context.Background(), "ETH/USDT", "SELL", 3000.0, 0.8)
+
+	assert.True(t, intentions.shouldVeto)
+	assert.Contains(t, intentions.vetoReason, "VaR")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExposureChange(t *testing.T) {
+	agent := createTestRiskAgent()
+	agent.beliefs.currentPositions = []Position{
+		{Symbol: "BTC/USDT", Side: "LONG", Size: 5000},
+		{Symbol: "ETH/USDT", Side: "SHORT", Size: 2000},
+	}
+
+	tests := []struct {
+		name    string
+		symbol  string
+		action  string
+		size    float64
+		change  float64
+		reduces bool
+	}{
+		{"buy adds to long", "BTC/USDT", "BUY", 1000, 1000, false},
+		{"sell closes part of long", "BTC/USDT", "SELL", 3000, -3000, true},
+		{"sell flips long to larger short", "BTC/USDT", "SELL", 12000, -12000, false},
+		{"sell opens short", "SOL/USDT", "SELL", 1000, -1000, false},
+		{"sell adds to short", "ETH/USDT", "SELL", 1000, -1000, false},
+		{"buy covers short", "ETH/USDT", "BUY", 1500, 1500, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, reduces := agent.exposureChange(tt.symbol, tt.action, tt.size)
+			assert.Equal(t, tt.change, change)
+			assert.Equal(t, tt.reduces, reduces)
+		})
+	}
+}
+
+func TestCheckVaRLimit(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	agent := createTestRiskAgent()
+	agent.calculator = risk.NewCalculator(mock)
+
+	// No limit means no VaR estimate is needed
+	withinLimit, _ := agent.checkVaRLimit(context.Background(), "BTC/USDT", 1000)
+	assert.True(t, withinLimit)
+
+	agent.beliefs.maxVaR95 = 0.05
+	expectCandles(mock, "BTC/USDT", 100, 101, 100.5, 101.5, 101, 102)
+
+	withinLimit, projected := agent.checkVaRLimit(context.Background(), "BTC/USDT", 1000)
+	assert.True(t, withinLimit)
+	assert.InDelta(t, 0.005, projected, 0.001)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// Estimation failures do not block trades
+	agent.calculator = risk.NewCalculator(nil)
+	withinLimit, _ = agent.checkVaRLimit(context.Background(), "BTC/USDT", 1000)
+	assert.True(t, withinLimit)
+}
+
+func TestPortfolioPositions_AddsProposal(t *testing.T) {
+	positions := []Position{
+		{Symbol: "BTC/USDT", Size: 5000},
+		{Symbol: "ETH/USDT", Size: 2000},
+	}
+
+	assert.Equal(t, []risk.PortfolioPosition{
+		{Symbol: "BTC/USDT", Value: 5000},
+		{Symbol: "ETH/USDT", Value: 3000},
+	}, portfolioPositions(positions, "ETH/USDT", 1000))
+
+	assert.Equal(t, []risk.PortfolioPosition{
+		{Symbol: "BTC/USDT", Value: 5000},
+		{Symbol: "ETH/USDT", Value: 2000},
+		{Symbol: "SOL/USDT", Value: 1000},
+	}, portfolioPositions(positions, "SOL/USDT", 1000))
+
+	assert.Len(t, portfolioPositions(positions, "", 0), 2)
+}
+
+func TestPortfolioPositions_ValuesShortsNegatively(t *testing.T) {
+	positions := []Position{
+		{Symbol: "BTC/USDT", Side: "LONG", Size: 5000},
+		{Symbol: "ETH/USDT", Side: "SHORT", Size: 2000},
+	}
+
+	assert.Equal(t, []risk.PortfolioPosition{
+		{Symbol: "BTC/USDT", Value: 5000},
+		{Symbol: "ETH/USDT", Value: -1000},
+	}, portfolioPositions(positions, "ETH/USDT", 1000))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// handleGetAgentWeights returns the orchestrator's learned agent voting
+// weights, with the audit trail of ?agent=<name>
+func (s *APIServer) handleGetAgentWeights(c *gin.Context) {
+	target := s.getOrchestratorURL() + "/api/v1/agent-weights"
+	if agent := c.Query("agent"); agent != "" {
+		target += "?agent=" + url.QueryEscape(agent)
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, target, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to build orchestrator request",
+			"details": err.Error(),
+		})
+		return
+	}
+	s.proxyAgentWeights(c, req)
+}
+
+// handleAdjustAgentWeight freezes, unfreezes, overrides or clears the
+// override of an agent's voting weight
+func (s *APIServer) handleAdjustAgentWeight(c *gin.Context) {
+	var body struct {
+		Agent  string  `json:"agent" binding:"required"`
+		Action string  `json:"action" binding:"required,oneof=freeze unfreeze override clear_override"`
+		Weight float64 `json:"weight"`
+		Actor  string  `json:"actor"`
+		Note   string  `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Authenticated callers are always recorded as themselves
+	if userID, exists := c.Get("user_id"); exists && userID != nil {
+		body.Actor = fmt.Sprintf("%v", userID)
+	}
+	if body.Actor == "" {
+		body.Actor = "api"
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to encode orchestrator request",
+			"details": err.Error(),
+		})
+		return
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, s.getOrchestratorURL()+"/api/v1/agent-weights", bytes.NewReader(payload))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to build orchestrator request",
+			"details": err.Error(),
+		})
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.proxyAgentWeights(c, req)
+}
+
+// proxyAgentWeights relays an agent weights request to the orchestrator.
+// Rejected adjustments keep their status so callers see why.
+func (s *APIServer) proxyAgentWeights(c *gin.Context, req *http.Request) {
+	resp, err := s.orchestratorClient.Do(req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to call orchestrator agent weights endpoint")
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "orchestrator unavailable",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			log.Error().Err(cerr).Msg("Failed to close response body")
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusServiceUnavailable:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		c.JSON(resp.StatusCode, gin.H{
+			"error": strings.TrimSpace(string(message)),
+		})
+		return
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":  "orchestrator failed to handle agent weights",
+			"status": resp.StatusCode,
+		})
+		return
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "invalid orchestrator response",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
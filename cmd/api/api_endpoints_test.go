@@ -14,6 +14,7 @@ import (
 	"github.com/ajitpratap0/cryptofunk/internal/config"
 	"github.com/ajitpratap0/cryptofunk/internal/db"
 	"github.com/ajitpratap0/cryptofunk/internal/db/testhelpers"
+	"github.com/ajitpratap0/cryptofunk/internal/exchange"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -201,6 +202,32 @@ func TestListPositions_Empty(t *testing.T) {
 	assert.Contains(t, response, "positions")
 }
 
+// TestGetAccount tests the account balances endpoint
+func TestGetAccount(t *testing.T) {
+	server, tc := setupTestAPIServer(t)
+
+	err := tc.DB.UpsertAccountBalances(context.Background(), []*db.AccountBalance{
+		{Exchange: "mock", Asset: "USDT", Free: 9500, Locked: 500},
+		{Exchange: "mock", Asset: "BTC", Free: 0.01},
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/api/v1/account?exchange=mock", nil)
+	w := httptest.NewRecorder()
+
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err)
+
+	assert.Equal(t, "mock", response["exchange"])
+	assert.Equal(t, float64(2), response["count"])
+	assert.Contains(t, response, "updated_at")
+}
+
 // TestListOrders_Empty tests list orders endpoint with empty database
 func TestListOrders_Empty(t *testing.T) {
 	server, tc := setupTestAPIServer(t)
@@ -256,6 +283,82 @@ func TestPlaceOrder_MissingFields(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+// TestPlaceOrder_PreTradeRejection tests that manual orders go through the pre-trade checks
+func TestPlaceOrder_PreTradeRejection(t *testing.T) {
+	server, tc := setupTestAPIServer(t)
+	server.preTrade = exchange.NewPreTradeRisk(exchange.PreTradeConfig{
+		RestrictedSymbols: []string{"LUNAUSDT"},
+		MaxOrderNotional:  10000,
+	}, server.auditLogger)
+	server.preTrade.SetPriceSource(tc.DB.GetLatestClosePrice)
+
+	placeOrder := func(reqBody map[string]interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(reqBody)
+		req := httptest.NewRequest("POST", "/api/v1/orders", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := placeOrder(map[string]interface{}{
+		"symbol":   "LUNAUSDT",
+		"side":     "buy",
+		"type":     "market",
+		"quantity": 1.0,
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	rejection := response["rejection"].(map[string]interface{})
+	assert.Equal(t, exchange.CheckRestrictedSymbol, rejection["check"])
+
+	w = placeOrder(map[string]interface{}{
+		"symbol":   "BTCUSDT",
+		"side":     "buy",
+		"type":     "limit",
+		"quantity": 1.0,
+		"price":    50000.0,
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	rejection = response["rejection"].(map[string]interface{})
+	assert.Equal(t, exchange.CheckMaxNotional, rejection["check"])
+
+	w = placeOrder(map[string]interface{}{
+		"symbol":   "BTCUSDT",
+		"side":     "buy",
+		"type":     "limit",
+		"quantity": 0.1,
+		"price":    50000.0,
+	})
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	// Amendments pass the same checks
+	var created struct {
+		Order struct {
+			ID string `json:"id"`
+		} `json:"order"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	amend := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/v1/orders/"+created.Order.ID, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w = amend(`{"quantity": 1.0}`)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	rejection = response["rejection"].(map[string]interface{})
+	assert.Equal(t, exchange.CheckMaxNotional, rejection["check"])
+
+	assert.Equal(t, http.StatusOK, amend(`{"quantity": 0.15}`).Code)
+}
+
 // TestStartTrading_InvalidRequest tests start trading with invalid request
 func TestStartTrading_InvalidRequest(t *testing.T) {
 	server, tc := setupTestAPIServer(t)
@@ -306,6 +409,34 @@ func TestCancelOrder_InvalidOrderID(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+// TestAmendOrder_InvalidRequest tests amend order with an invalid ID or body
+func TestAmendOrder_InvalidRequest(t *testing.T) {
+	server, tc := setupTestAPIServer(t)
+	_ = tc // testcontainers handles cleanup automatically
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"invalid order ID", "/api/v1/orders/invalid-id", `{"price": 41000}`},
+		{"no changes", "/api/v1/orders/" + uuid.New().String(), `{}`},
+		{"negative price", "/api/v1/orders/" + uuid.New().String(), `{"price": -1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PATCH", tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			server.router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
 // TestGetOrchestratorURL tests orchestrator URL retrieval
 func TestGetOrchestratorURL(t *testing.T) {
 	server, tc := setupTestAPIServer(t)
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// handleGetAgentCalibration returns an agent's confidence calibration from the
+// orchestrator: the fitted mapping, reliability diagrams and Brier scores
+// before and after calibration
+func (s *APIServer) handleGetAgentCalibration(c *gin.Context) {
+	name := c.Param("name")
+	target := s.getOrchestratorURL() + "/api/v1/agents/" + url.PathEscape(name) + "/calibration"
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, target, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to build orchestrator request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	resp, err := s.orchestratorClient.Do(req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to call orchestrator calibration endpoint")
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "orchestrator unavailable",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			log.Error().Err(cerr).Msg("Failed to close response body")
+		}
+	}()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": strings.TrimSpace(string(message)),
+			"name":  name,
+		})
+		return
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":  "orchestrator failed to report calibration",
+			"status": resp.StatusCode,
+		})
+		return
+	}
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "invalid orchestrator response",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/exchange"
+)
+
+// handleDeRisk reduces or closes the listed positions for a de-risking rule
+func (s *APIServer) handleDeRisk(c *gin.Context) {
+	if s.killSwitch == nil || s.killSwitch.HaltOnly() {
+		s.respondKillSwitchUnavailable(c)
+		return
+	}
+
+	var req struct {
+		Source      string                       `json:"source"`
+		RequestedBy string                       `json:"requested_by"`
+		Reason      string                       `json:"reason"`
+		Rule        string                       `json:"rule"`
+		Reductions  []exchange.PositionReduction `json:"reductions" binding:"required,min=1"`
+		Before      map[string]interface{}       `json:"before"`
+		After       map[string]interface{}       `json:"after"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if req.Source == "" {
+		req.Source = "api"
+	}
+	if !flattenSources[req.Source] {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "source must be one of: api, telegram, risk_agent",
+		})
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "Manual de-risking"
+	}
+
+	// Authenticated callers are always recorded as themselves
+	requestedBy := req.RequestedBy
+	if userID, exists := c.Get("user_id"); exists && userID != nil {
+		requestedBy = fmt.Sprintf("%v", userID)
+	}
+
+	metadata := make(map[string]interface{})
+	if req.Before != nil {
+		metadata["before"] = req.Before
+	}
+	if req.After != nil {
+		metadata["after"] = req.After
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flattenTimeout)
+	defer cancel()
+
+	report, err := s.killSwitch.Reduce(ctx, exchange.ReduceRequest{
+		Trigger: exchange.FlattenTrigger{
+			Source:      req.Source,
+			RequestedBy: requestedBy,
+			IPAddress:   c.ClientIP(),
+			Reason:      req.Reason,
+			Rule:        req.Rule,
+		},
+		Reductions: req.Reductions,
+		Metadata:   metadata,
+	})
+	switch {
+	case errors.Is(err, exchange.ErrFlattenInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, exchange.ErrNoReductions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Error().Err(err).Msg("De-risking failed")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "de-risking failed",
+		})
+		return
+	}
+
+	status := http.StatusOK
+	message := "Positions reduced"
+	if !report.Success {
+		status = http.StatusMultiStatus
+		message = "De-risking completed with errors"
+	}
+
+	c.JSON(status, gin.H{
+		"message": message,
+		"report":  report,
+	})
+}
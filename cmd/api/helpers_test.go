@@ -1,12 +1,15 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ajitpratap0/cryptofunk/internal/config"
 )
@@ -252,3 +255,43 @@ func TestRateLimiter_ConcurrentAccess(t *testing.T) {
 	assert.Equal(t, 10, allowedCount, "Should allow exactly maxRequests")
 	assert.Equal(t, 10, deniedCount, "Should deny the rest")
 }
+
+// TestKillSwitch_HaltOnlyInPaperMode tests that paper mode creates a halt-only
+// kill switch, refuses de-risking with the reason and reports itself degraded
+func TestKillSwitch_HaltOnlyInPaperMode(t *testing.T) {
+	t.Setenv("TRADING_MODE", "")
+	gin.SetMode(gin.TestMode)
+
+	killSwitch, err := newKillSwitch(&config.Config{Trading: config.TradingConfig{Mode: "paper"}}, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, killSwitch.HaltOnly())
+
+	server := &APIServer{killSwitch: killSwitch, killSwitchErr: errFlattenPaperMode}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/trade/derisk", nil)
+	server.handleDeRisk(c)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "not available in paper mode")
+
+	assert.Equal(t, "degraded", server.killSwitchStatus())
+	details := server.killSwitchDetails()
+	assert.Equal(t, true, details["halt_only"])
+	assert.Equal(t, false, details["flatten"])
+	assert.Contains(t, details["details"], "only halts trading")
+
+	// Without a kill switch every trigger is refused
+	unavailable := &APIServer{killSwitchErr: errors.New("no credentials")}
+	assert.Equal(t, "unavailable", unavailable.killSwitchStatus())
+	for name, handler := range map[string]gin.HandlerFunc{
+		"flatten": unavailable.handleArmFlatten,
+		"confirm": unavailable.handleConfirmFlatten,
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/trade/"+name, nil)
+		handler(c)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, name)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
+	"github.com/ajitpratap0/cryptofunk/internal/config"
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/exchange"
+)
+
+// flattenTimeout bounds a confirmed flatten independently of the HTTP client,
+// so a disconnect cannot abandon the kill switch halfway through
+const flattenTimeout = 2 * time.Minute
+
+// Trigger sources accepted from API callers
+var flattenSources = map[string]bool{
+	"api":        true,
+	"telegram":   true,
+	"risk_agent": true,
+}
+
+// errFlattenPaperMode explains why the kill switch only halts trading in paper
+// mode. Paper orders and positions live in the order executor's simulated
+// account, which the API cannot reach, so closing them here would only rewrite
+// the database behind the executor's back.
+var errFlattenPaperMode = errors.New("flatten and de-risking are not available in paper mode: paper orders and positions are held by the order executor, the kill switch only halts trading")
+
+// newKillSwitch creates the global kill switch on the live exchange. In paper
+// mode it creates a halt-only kill switch that pauses trading without flattening.
+func newKillSwitch(cfg *config.Config, database *db.DB, auditLogger *audit.Logger) (*exchange.KillSwitch, error) {
+	// Same venues, credentials and market as the order executor
+	serviceConfig, err := exchange.ServiceConfigFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ksCfg := cfg.Risk.KillSwitch
+	if serviceConfig.Mode != exchange.TradingModeLive {
+		return exchange.NewKillSwitch(nil, database, auditLogger, exchange.KillSwitchConfig{
+			TokenTTL: ksCfg.GetTokenTTL(),
+		}), nil
+	}
+	ex, _, err := exchange.NewExchange(database, serviceConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	killSwitch := exchange.NewKillSwitch(ex, database, auditLogger, exchange.KillSwitchConfig{
+		CloseMethod:    exchange.ParseCloseMethod(ksCfg.CloseMethod),
+		ChaseAttempts:  ksCfg.ChaseAttempts,
+		ChaseInterval:  ksCfg.GetChaseInterval(),
+		ChaseOffsetBps: ksCfg.ChaseOffsetBps,
+		TokenTTL:       ksCfg.GetTokenTTL(),
+		FeeRate:        serviceConfig.Fees.Taker,
+	})
+	killSwitch.SetPriceSource(database.GetLatestClosePrice)
+
+	return killSwitch, nil
+}
+
+// respondKillSwitchUnavailable answers flatten and de-risking requests when
+// the kill switch could not be created, and de-risking requests when it only
+// halts trading, with the reason
+func (s *APIServer) respondKillSwitchUnavailable(c *gin.Context) {
+	body := gin.H{"error": "kill switch is not available"}
+	if s.killSwitchErr != nil {
+		body["details"] = s.killSwitchErr.Error()
+	}
+	c.JSON(http.StatusServiceUnavailable, body)
+}
+
+// killSwitchStatus reports the kill switch as healthy when it can flatten,
+// degraded when it only halts trading and unavailable when it was not created
+func (s *APIServer) killSwitchStatus() string {
+	switch {
+	case s.killSwitch == nil:
+		return "unavailable"
+	case s.killSwitch.HaltOnly():
+		return "degraded"
+	default:
+		return "healthy"
+	}
+}
+
+// killSwitchDetails describes what the kill switch does when confirmed
+func (s *APIServer) killSwitchDetails() gin.H {
+	details := gin.H{
+		"status":    s.killSwitchStatus(),
+		"flatten":   s.killSwitch != nil && !s.killSwitch.HaltOnly(),
+		"halt_only": s.killSwitch != nil && s.killSwitch.HaltOnly(),
+	}
+	if s.killSwitchErr != nil {
+		details["details"] = s.killSwitchErr.Error()
+	}
+	return details
+}
+
+// handleArmFlatten arms the kill switch and returns a confirmation token
+func (s *APIServer) handleArmFlatten(c *gin.Context) {
+	if s.killSwitch == nil {
+		s.respondKillSwitchUnavailable(c)
+		return
+	}
+
+	var req struct {
+		Reason      string `json:"reason"`
+		Source      string `json:"source"`
+		RequestedBy string `json:"requested_by"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	if req.Source == "" {
+		req.Source = "api"
+	}
+	if !flattenSources[req.Source] {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "source must be one of: api, telegram, risk_agent",
+		})
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "Manual flatten"
+	}
+
+	// Authenticated callers are always recorded as themselves
+	requestedBy := req.RequestedBy
+	if userID, exists := c.Get("user_id"); exists && userID != nil {
+		requestedBy = fmt.Sprintf("%v", userID)
+	}
+
+	ticket, err := s.killSwitch.Arm(c.Request.Context(), exchange.FlattenTrigger{
+		Source:      req.Source,
+		RequestedBy: requestedBy,
+		IPAddress:   c.ClientIP(),
+		Reason:      req.Reason,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to arm kill switch")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to arm kill switch",
+		})
+		return
+	}
+
+	message := "Kill switch armed. Confirm with POST /api/v1/trade/flatten/confirm before the token expires."
+	if ticket.HaltOnly {
+		message = "Kill switch armed in halt-only mode. Confirming pauses trading; paper orders and positions are not flattened."
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":        message,
+		"token":          ticket.Token,
+		"expires_at":     ticket.ExpiresAt,
+		"open_orders":    ticket.OpenOrders,
+		"open_positions": ticket.OpenPositions,
+		"halt_only":      ticket.HaltOnly,
+	})
+}
+
+// handleConfirmFlatten executes the kill switch for a previously issued token
+func (s *APIServer) handleConfirmFlatten(c *gin.Context) {
+	if s.killSwitch == nil {
+		s.respondKillSwitchUnavailable(c)
+		return
+	}
+
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flattenTimeout)
+	defer cancel()
+
+	report, err := s.killSwitch.Execute(ctx, req.Token)
+	switch {
+	case errors.Is(err, exchange.ErrInvalidFlattenToken):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, exchange.ErrFlattenTokenExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, exchange.ErrFlattenInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Error().Err(err).Msg("Kill switch failed")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "kill switch failed",
+		})
+		return
+	}
+
+	if s.hub != nil {
+		if err := s.hub.Broadcast(MessageTypeKillSwitch, report); err != nil {
+			log.Error().Err(err).Msg("Failed to broadcast kill switch report")
+		}
+	}
+
+	status := http.StatusOK
+	message := "All orders cancelled and positions flattened"
+	if report.HaltOnly {
+		message = "Trading halted; paper orders and positions were not flattened"
+	}
+	if !report.Success {
+		status = http.StatusMultiStatus
+		message = "Kill switch completed with errors"
+	}
+
+	c.JSON(status, gin.H{
+		"message": message,
+		"report":  report,
+	})
+}
+
+// pauseForFlatten stops the orchestrator from generating new orders while
+// flattening. A failure is reported with the flatten, which carries on.
+func (s *APIServer) pauseForFlatten(ctx context.Context, trigger exchange.FlattenTrigger) error {
+	if s.db != nil {
+		if paused, err := s.db.IsTradingPaused(ctx); err == nil && paused {
+			log.Warn().Str("source", trigger.Source).Msg("Orchestrator already paused, kill switch continues")
+			return nil
+		}
+	}
+
+	resp, err := s.callOrchestratorWithRetry(s.getOrchestratorURL() + "/pause")
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to pause orchestrator before flatten, continuing")
+		return fmt.Errorf("failed to pause orchestrator: %w", err)
+	}
+	if cerr := resp.Body.Close(); cerr != nil {
+		log.Error().Err(cerr).Msg("Failed to close response body")
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Error().Int("status", resp.StatusCode).Msg("Orchestrator refused to pause before flatten, continuing")
+		return fmt.Errorf("orchestrator refused to pause: status %d", resp.StatusCode)
+	}
+	log.Warn().Str("source", trigger.Source).Msg("Orchestrator paused by kill switch")
+	return nil
+}
+
+// haltForFlatten halts trading for the halt-only kill switch: it pauses every
+// active session, so the order executor rejects their new orders, and the
+// orchestrator
+func (s *APIServer) haltForFlatten(ctx context.Context, trigger exchange.FlattenTrigger) error {
+	var errs []error
+	sessions, err := s.db.ListActiveSessions(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, session := range sessions {
+		if session.Paused {
+			continue
+		}
+		if err := s.db.SetSessionPaused(ctx, session.ID, true); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Warn().
+			Str("session_id", session.ID.String()).
+			Str("source", trigger.Source).
+			Msg("Trading session paused by kill switch")
+	}
+
+	if err := s.pauseForFlatten(ctx, trigger); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
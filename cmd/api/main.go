@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -21,7 +22,9 @@ import (
 	"github.com/ajitpratap0/cryptofunk/internal/audit"
 	"github.com/ajitpratap0/cryptofunk/internal/config"
 	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/exchange"
 	"github.com/ajitpratap0/cryptofunk/internal/metrics"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
 )
 
 const (
@@ -37,6 +40,10 @@ type APIServer struct {
 	orchestratorClient *http.Client
 	rateLimiter        *RateLimiterMiddleware
 	apiKeyStore        *api.APIKeyStore
+	auditLogger        *audit.Logger
+	killSwitch         *exchange.KillSwitch
+	killSwitchErr      error                  // Why killSwitch is nil or only halts trading
+	preTrade           *exchange.PreTradeRisk // Checks manual orders; nil when disabled
 }
 
 // HTTP client for orchestrator communication with timeout and connection pooling
@@ -106,6 +113,25 @@ func main() {
 	// Setup middleware
 	server.setupMiddleware()
 
+	// Create the global kill switch (flatten all orders and positions)
+	killSwitch, err := newKillSwitch(cfg, database, server.auditLogger)
+	switch {
+	case err != nil:
+		log.Error().Err(err).Msg("Failed to create kill switch, flatten endpoints disabled")
+		server.killSwitchErr = err
+	case killSwitch.HaltOnly():
+		log.Warn().Msg("Kill switch only halts trading in paper mode, de-risking endpoint refuses requests")
+		killSwitch.OnTrigger(server.haltForFlatten)
+		server.killSwitch = killSwitch
+		server.killSwitchErr = errFlattenPaperMode
+	default:
+		killSwitch.OnTrigger(server.pauseForFlatten)
+		server.killSwitch = killSwitch
+	}
+
+	// Manual orders pass the same pre-trade checks as orders from the order executor
+	server.preTrade = newPreTradeRisk(cfg, database, server.auditLogger)
+
 	// Setup routes
 	server.setupRoutes()
 
@@ -168,8 +194,8 @@ func (s *APIServer) setupMiddleware() {
 	s.router.Use(metrics.GinMiddleware())
 
 	// Audit logging middleware (logs security-relevant events)
-	auditLogger := audit.NewLogger(s.db.Pool(), true)
-	s.router.Use(AuditLoggingMiddleware(auditLogger))
+	s.auditLogger = audit.NewLogger(s.db.Pool(), true)
+	s.router.Use(AuditLoggingMiddleware(s.auditLogger))
 
 	// Request logging middleware
 	s.router.Use(requestLogger())
@@ -233,6 +259,7 @@ func (s *APIServer) setupRoutes() {
 			agents.GET("", s.handleListAgents)
 			agents.GET("/:name", s.handleGetAgent)
 			agents.GET("/:name/status", s.handleGetAgentStatus)
+			agents.GET("/:name/calibration", s.handleGetAgentCalibration)
 		}
 
 		// Position routes (read-only, apply read rate limiter)
@@ -243,15 +270,34 @@ func (s *APIServer) setupRoutes() {
 			positions.GET("/:symbol", s.handleGetPosition)
 		}
 
+		// Trading session routes (read-only; sessions are controlled via /trade)
+		sessions := v1.Group("/sessions")
+		sessions.Use(s.rateLimiter.ReadMiddleware())
+		{
+			sessions.GET("", s.handleListSessions)
+			sessions.GET("/:id", s.handleGetSession)
+		}
+
+		// Account balances (read-only, published by the order executor)
+		v1.GET("/account", s.rateLimiter.ReadMiddleware(), s.handleGetAccount)
+
+		// Risk budget utilization per agent and strategy (reported by the orchestrator)
+		v1.GET("/risk/budgets", s.rateLimiter.ReadMiddleware(), s.handleGetRiskBudgets)
+
+		// Learned agent voting weights and their audit trail (reported by the orchestrator)
+		v1.GET("/agent-weights", s.rateLimiter.ReadMiddleware(), s.handleGetAgentWeights)
+
 		// Order routes (mixed read/write, apply appropriate limiters)
 		orders := v1.Group("/orders")
 		{
 			// Read operations (higher limit)
 			orders.GET("", s.rateLimiter.ReadMiddleware(), s.handleListOrders)
 			orders.GET("/:id", s.rateLimiter.ReadMiddleware(), s.handleGetOrder)
+			orders.GET("/:id/children", s.rateLimiter.ReadMiddleware(), s.handleGetChildOrders)
 
 			// Write operations (lower limit to prevent order spam)
 			orders.POST("", s.rateLimiter.OrderMiddleware(), s.handlePlaceOrder)
+			orders.PATCH("/:id", s.rateLimiter.OrderMiddleware(), s.handleAmendOrder)
 			orders.DELETE("/:id", s.rateLimiter.OrderMiddleware(), s.handleCancelOrder)
 		}
 
@@ -282,8 +328,23 @@ func (s *APIServer) setupRoutes() {
 			trade.POST("/stop", s.handleStopTrading)
 			trade.POST("/pause", s.handlePauseTrading)
 			trade.POST("/resume", s.handleResumeTrading)
+
+			// Kill switch: arm returns a confirmation token, confirm flattens everything
+			trade.POST("/flatten", s.handleArmFlatten)
+			trade.POST("/flatten/confirm", s.handleConfirmFlatten)
+
+			// De-risking: reduce or close selected positions without pausing trading
+			trade.POST("/derisk", s.handleDeRisk)
 		}
 
+		// Agent weight controls: freeze, unfreeze or override learned voting weights
+		agentWeights := v1.Group("/agent-weights")
+		agentWeights.Use(s.rateLimiter.ControlMiddleware())
+		if s.config.API.Auth.Enabled {
+			agentWeights.Use(api.AuthMiddleware(s.apiKeyStore, authConfig))
+		}
+		agentWeights.POST("", s.handleAdjustAgentWeight)
+
 		// Configuration routes (admin ops, apply control rate limiter)
 		config := v1.Group("/config")
 		{
@@ -314,6 +375,14 @@ func (s *APIServer) setupRoutes() {
 		// Backtesting operations can be computationally expensive, so we apply stricter rate limits
 		backtestHandler := api.NewBacktestHandler(s.db.Pool())
 		backtestHandler.RegisterRoutesWithRateLimiter(v1, s.rateLimiter.ReadMiddleware(), s.rateLimiter.OrderMiddleware())
+
+		// Report routes (realized gains from the tax-lot ledger)
+		reportsHandler := api.NewReportsHandler(s.db)
+		reportsHandler.RegisterRoutesWithRateLimiter(v1, s.rateLimiter.ReadMiddleware())
+
+		// Stress test routes (scenario P&L of the open positions)
+		stressHandler := api.NewStressHandler(s.db, risk.NewStressTester(risk.NewCalculatorWithPool(s.db.Pool())))
+		stressHandler.RegisterRoutesWithRateLimiter(v1, s.rateLimiter.ReadMiddleware(), s.rateLimiter.OrderMiddleware())
 	}
 
 	// Root endpoint
@@ -400,13 +469,15 @@ func (s *APIServer) handleStatus(c *gin.Context) {
 		"version": config.Version,
 		"uptime":  time.Since(startTime).String(),
 		"components": gin.H{
-			"database":  "healthy",
-			"api":       "healthy",
-			"websocket": "healthy",
+			"database":    "healthy",
+			"api":         "healthy",
+			"websocket":   "healthy",
+			"kill_switch": s.killSwitchStatus(),
 		},
 		"websocket": gin.H{
 			"connected_clients": s.hub.ClientCount(),
 		},
+		"kill_switch": s.killSwitchDetails(),
 	})
 }
 
@@ -511,6 +582,41 @@ func (s *APIServer) handleListPositions(c *gin.Context) {
 	})
 }
 
+func (s *APIServer) handleGetAccount(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Optional: filter by exchange (e.g., "binance" or "mock")
+	exchangeName := c.Query("exchange")
+
+	balances, err := s.db.GetAccountBalances(ctx, exchangeName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to retrieve account balances",
+		})
+		return
+	}
+
+	var updatedAt time.Time
+	for _, b := range balances {
+		if b.UpdatedAt.After(updatedAt) {
+			updatedAt = b.UpdatedAt
+		}
+	}
+
+	response := gin.H{
+		"balances": balances,
+		"count":    len(balances),
+	}
+	if exchangeName != "" {
+		response["exchange"] = exchangeName
+	}
+	if !updatedAt.IsZero() {
+		response["updated_at"] = updatedAt
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 func (s *APIServer) handleGetPosition(c *gin.Context) {
 	symbol := c.Param("symbol")
 	ctx := c.Request.Context()
@@ -548,6 +654,85 @@ func (s *APIServer) handleGetPosition(c *gin.Context) {
 	})
 }
 
+// Session handlers
+func (s *APIServer) handleListSessions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	sessions, err := s.db.ListActiveSessions(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to retrieve sessions",
+		})
+		return
+	}
+
+	// Optional: filter by symbol and mode
+	symbol := strings.ToUpper(c.Query("symbol"))
+	mode := strings.ToUpper(c.Query("mode"))
+
+	results := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		if symbol != "" && session.Symbol != symbol {
+			continue
+		}
+		if mode != "" && strings.ToUpper(string(session.Mode)) != mode {
+			continue
+		}
+		results = append(results, sessionResponse(session))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": results,
+		"count":    len(results),
+	})
+}
+
+func (s *APIServer) handleGetSession(c *gin.Context) {
+	sessionID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid session_id format",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := s.db.GetSession(ctx, sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":      "session not found",
+			"session_id": sessionID.String(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session": sessionResponse(session),
+	})
+}
+
+// sessionResponse formats a trading session for API responses
+func sessionResponse(session *db.TradingSession) gin.H {
+	return gin.H{
+		"session_id":      session.ID.String(),
+		"mode":            session.Mode,
+		"symbol":          session.Symbol,
+		"exchange":        session.Exchange,
+		"paused":          session.Paused,
+		"active":          session.StoppedAt == nil,
+		"started_at":      session.StartedAt,
+		"stopped_at":      session.StoppedAt,
+		"initial_capital": session.InitialCapital,
+		"final_capital":   session.FinalCapital,
+		"total_trades":    session.TotalTrades,
+		"winning_trades":  session.WinningTrades,
+		"losing_trades":   session.LosingTrades,
+		"total_pnl":       session.TotalPnL,
+		"max_drawdown":    session.MaxDrawdown,
+		"sharpe_ratio":    session.SharpeRatio,
+	}
+}
+
 // Order handlers
 func (s *APIServer) handleListOrders(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -617,6 +802,49 @@ func (s *APIServer) handleGetOrder(c *gin.Context) {
 	})
 }
 
+// handleGetChildOrders returns a parent algo order with its child orders and execution progress
+func (s *APIServer) handleGetChildOrders(c *gin.Context) {
+	orderIDStr := c.Param("id")
+	ctx := c.Request.Context()
+
+	orderID, err := parseUUID(orderIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid order_id format",
+		})
+		return
+	}
+
+	parent, err := s.db.GetOrderByID(ctx, orderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":    "order not found",
+			"order_id": orderIDStr,
+		})
+		return
+	}
+
+	children, err := s.db.GetChildOrders(ctx, orderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to retrieve child orders",
+		})
+		return
+	}
+
+	var progress float64
+	if parent.Quantity > 0 {
+		progress = parent.ExecutedQuantity / parent.Quantity
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order":    parent,
+		"children": children,
+		"count":    len(children),
+		"progress": progress,
+	})
+}
+
 func (s *APIServer) handlePlaceOrder(c *gin.Context) {
 	var req struct {
 		Symbol   string  `json:"symbol" binding:"required"`
@@ -648,6 +876,36 @@ func (s *APIServer) handlePlaceOrder(c *gin.Context) {
 		price = nil
 	}
 
+	ctx := c.Request.Context()
+
+	check := &exchange.PreTradeOrder{
+		Source:    "api",
+		Symbol:    req.Symbol,
+		Side:      exchange.OrderSide(strings.ToLower(req.Side)),
+		Type:      exchange.OrderType(strings.ToLower(req.Type)),
+		Quantity:  req.Quantity,
+		Price:     req.Price,
+		IPAddress: c.ClientIP(),
+	}
+	if userID, exists := c.Get("user_id"); exists && userID != nil {
+		check.RequestedBy = fmt.Sprintf("%v", userID)
+	}
+	if err := s.checkManualOrder(ctx, check); err != nil {
+		var rejection *exchange.PreTradeError
+		if errors.As(err, &rejection) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":     "order rejected by pre-trade checks",
+				"rejection": rejection.Rejection,
+			})
+			return
+		}
+		log.Error().Err(err).Str("symbol", req.Symbol).Msg("Failed to run pre-trade checks")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to run pre-trade checks",
+		})
+		return
+	}
+
 	order := &db.Order{
 		ID:        uuid.New(),
 		Symbol:    req.Symbol,
@@ -662,7 +920,6 @@ func (s *APIServer) handlePlaceOrder(c *gin.Context) {
 		UpdatedAt: time.Now(),
 	}
 
-	ctx := c.Request.Context()
 	if err := s.db.InsertOrder(ctx, order); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "failed to create order",
@@ -722,6 +979,20 @@ func (s *APIServer) handleCancelOrder(c *gin.Context) {
 		return
 	}
 
+	// Cancelling an algo (parent) order also cancels its open child orders.
+	// The order executor stops slicing once it sees the parent is cancelled.
+	if order.AlgoType != nil {
+		canceledChildren, err := s.db.CancelChildOrders(ctx, orderID, cancelledAt)
+		if err != nil {
+			log.Error().Err(err).Str("order_id", orderIDStr).Msg("Failed to cancel child orders")
+		} else {
+			log.Info().
+				Str("order_id", orderIDStr).
+				Int64("canceled_children", canceledChildren).
+				Msg("Algo order child orders cancelled")
+		}
+	}
+
 	// Get updated order
 	order, _ = s.db.GetOrderByID(ctx, orderID)
 
@@ -736,6 +1007,145 @@ func (s *APIServer) handleCancelOrder(c *gin.Context) {
 	})
 }
 
+// handleAmendOrder moves a resting limit order to a new price and/or total quantity,
+// appending the change to the order's amendment history
+func (s *APIServer) handleAmendOrder(c *gin.Context) {
+	orderIDStr := c.Param("id")
+	ctx := c.Request.Context()
+
+	orderID, err := parseUUID(orderIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid order_id format",
+		})
+		return
+	}
+
+	var req struct {
+		Price    *float64 `json:"price" binding:"omitempty,gt=0"`
+		Quantity *float64 `json:"quantity" binding:"omitempty,gt=0"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	if req.Price == nil && req.Quantity == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "price or quantity is required",
+		})
+		return
+	}
+
+	order, err := s.db.GetOrderByID(ctx, orderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":    "order not found",
+			"order_id": orderIDStr,
+		})
+		return
+	}
+
+	// Only resting limit orders can be amended
+	if order.Status != db.OrderStatusNew && order.Status != db.OrderStatusPartiallyFilled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "order cannot be amended",
+			"status": order.Status,
+		})
+		return
+	}
+	if order.Type != db.OrderTypeLimit || order.Price == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "only limit orders can be amended",
+			"type":  order.Type,
+		})
+		return
+	}
+
+	amendment := db.OrderAmendment{
+		PreviousPrice:    *order.Price,
+		PreviousQuantity: order.Quantity,
+		Price:            *order.Price,
+		Quantity:         order.Quantity,
+		AmendedAt:        time.Now(),
+	}
+	if order.ExchangeOrderID != nil {
+		amendment.PreviousExchangeOrderID = *order.ExchangeOrderID
+		amendment.ExchangeOrderID = *order.ExchangeOrderID
+	}
+	if req.Price != nil {
+		amendment.Price = *req.Price
+	}
+	if req.Quantity != nil {
+		amendment.Quantity = *req.Quantity
+	}
+
+	if amendment.Quantity <= order.ExecutedQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             "quantity must exceed the executed quantity",
+			"executed_quantity": order.ExecutedQuantity,
+		})
+		return
+	}
+	if amendment.Price == amendment.PreviousPrice && amendment.Quantity == amendment.PreviousQuantity {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "amendment does not change the order",
+		})
+		return
+	}
+
+	// The amended order passes the same pre-trade checks as a new one
+	check := &exchange.PreTradeOrder{
+		Source:    "api",
+		Symbol:    order.Symbol,
+		Side:      exchange.OrderSide(strings.ToLower(string(order.Side))),
+		Type:      exchange.OrderTypeLimit,
+		Quantity:  amendment.Quantity - order.ExecutedQuantity,
+		Price:     amendment.Price,
+		IPAddress: c.ClientIP(),
+	}
+	if userID, exists := c.Get("user_id"); exists && userID != nil {
+		check.RequestedBy = fmt.Sprintf("%v", userID)
+	}
+	if err := s.checkManualAmend(ctx, check); err != nil {
+		var rejection *exchange.PreTradeError
+		if errors.As(err, &rejection) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":     "amendment rejected by pre-trade checks",
+				"rejection": rejection.Rejection,
+			})
+			return
+		}
+		log.Error().Err(err).Str("symbol", order.Symbol).Msg("Failed to run pre-trade checks")
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to run pre-trade checks",
+		})
+		return
+	}
+
+	if err := s.db.AmendOrder(ctx, orderID, amendment); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to amend order",
+		})
+		return
+	}
+
+	// Get updated order
+	order, _ = s.db.GetOrderByID(ctx, orderID)
+
+	// Broadcast order update to WebSocket clients
+	if err := s.BroadcastOrderUpdate(order); err != nil {
+		log.Warn().Err(err).Msg("Failed to broadcast order amendment")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order":   order,
+		"message": "Order amended successfully",
+	})
+}
+
 // Trading control handlers
 func (s *APIServer) handleStartTrading(c *gin.Context) {
 	var req struct {
@@ -881,6 +1291,16 @@ func (s *APIServer) handlePauseTrading(c *gin.Context) {
 		return
 	}
 
+	// Flag the session so the order executor rejects (or accepts) its new orders
+	if err := s.db.SetSessionPaused(ctx, sessionID, true); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "failed to pause session",
+			"details":    err.Error(),
+			"session_id": req.SessionID,
+		})
+		return
+	}
+
 	// Call orchestrator to pause trading with retry
 	orchestratorURL := s.getOrchestratorURL()
 	resp, err := s.callOrchestratorWithRetry(orchestratorURL + "/pause")
@@ -910,6 +1330,7 @@ func (s *APIServer) handlePauseTrading(c *gin.Context) {
 		"message":    "Trading paused successfully",
 		"session_id": session.ID.String(),
 		"symbol":     session.Symbol,
+		"paused":     true,
 	})
 }
 
@@ -945,6 +1366,16 @@ func (s *APIServer) handleResumeTrading(c *gin.Context) {
 		return
 	}
 
+	// Flag the session so the order executor rejects (or accepts) its new orders
+	if err := s.db.SetSessionPaused(ctx, sessionID, false); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "failed to resume session",
+			"details":    err.Error(),
+			"session_id": req.SessionID,
+		})
+		return
+	}
+
 	// Call orchestrator to resume trading with retry
 	orchestratorURL := s.getOrchestratorURL()
 	resp, err := s.callOrchestratorWithRetry(orchestratorURL + "/resume")
@@ -974,6 +1405,7 @@ func (s *APIServer) handleResumeTrading(c *gin.Context) {
 		"message":    "Trading resumed successfully",
 		"session_id": session.ID.String(),
 		"symbol":     session.Symbol,
+		"paused":     false,
 	})
 }
 
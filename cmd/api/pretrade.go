@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
+	"github.com/ajitpratap0/cryptofunk/internal/config"
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/exchange"
+)
+
+// newPreTradeRisk creates the pre-trade checks manual orders must pass. Returns nil
+// when pre-trade checks are disabled.
+func newPreTradeRisk(cfg *config.Config, database *db.DB, auditLogger *audit.Logger) *exchange.PreTradeRisk {
+	preTrade := cfg.Risk.PreTrade
+	if !preTrade.Enabled {
+		return nil
+	}
+
+	risk := exchange.NewPreTradeRisk(exchange.PreTradeConfig{
+		MaxOrderNotional:    preTrade.MaxOrderNotional,
+		MaxPositionNotional: preTrade.MaxPositionNotional,
+		PriceBand:           preTrade.PriceBand,
+		MaxOpenOrders:       preTrade.MaxOpenOrders,
+		RestrictedSymbols:   preTrade.RestrictedSymbols,
+		MaxDailyLoss:        cfg.Risk.MaxDailyLoss,
+	}, auditLogger)
+	risk.SetPriceSource(database.GetLatestClosePrice)
+	return risk
+}
+
+// preTradeOrder fills in the account state of a manual order from the database.
+// Manual orders are not part of a session, so positions, open orders and P&L are
+// taken across all sessions.
+func (s *APIServer) preTradeOrder(ctx context.Context, order *exchange.PreTradeOrder) error {
+	order.LastPrice = s.preTrade.LastPrice(ctx, order.Symbol)
+	order.Capital = s.config.Trading.InitialCapital
+
+	positions, err := s.db.GetAllOpenPositions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, position := range positions {
+		if !strings.EqualFold(position.Symbol, order.Symbol) {
+			continue
+		}
+		if position.Side == db.PositionSideShort {
+			order.PositionQty -= position.Quantity
+		} else {
+			order.PositionQty += position.Quantity
+		}
+	}
+
+	for _, status := range []db.OrderStatus{db.OrderStatusNew, db.OrderStatusPartiallyFilled} {
+		orders, err := s.db.GetOrdersByStatus(ctx, status)
+		if err != nil {
+			return err
+		}
+		order.OpenOrders += len(orders)
+	}
+
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if order.DailyPnL, err = s.db.GetPnLSince(ctx, midnight); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkManualOrder runs the pre-trade checks on an order placed through the API
+func (s *APIServer) checkManualOrder(ctx context.Context, order *exchange.PreTradeOrder) error {
+	if s.preTrade == nil {
+		return nil
+	}
+	if err := s.preTradeOrder(ctx, order); err != nil {
+		return fmt.Errorf("failed to load account state: %w", err)
+	}
+	return s.preTrade.Evaluate(ctx, order)
+}
+
+// checkManualAmend runs the pre-trade checks on a resting order as amended
+// through the API. order carries the amended price and the quantity still to
+// be filled; the order already counts as one of the open orders.
+func (s *APIServer) checkManualAmend(ctx context.Context, order *exchange.PreTradeOrder) error {
+	if s.preTrade == nil {
+		return nil
+	}
+	if err := s.preTradeOrder(ctx, order); err != nil {
+		return fmt.Errorf("failed to load account state: %w", err)
+	}
+	if order.OpenOrders > 0 {
+		order.OpenOrders--
+	}
+	return s.preTrade.Evaluate(ctx, order)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// handleGetRiskBudgets returns the orchestrator's risk budget utilization per
+// agent and strategy
+func (s *APIServer) handleGetRiskBudgets(c *gin.Context) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, s.getOrchestratorURL()+"/api/v1/risk-budgets", nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to build orchestrator request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	resp, err := s.orchestratorClient.Do(req)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to call orchestrator risk budgets endpoint")
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "orchestrator unavailable",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			log.Error().Err(cerr).Msg("Failed to close response body")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":  "orchestrator failed to report risk budgets",
+			"status": resp.StatusCode,
+		})
+		return
+	}
+
+	var budgets map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&budgets); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "invalid orchestrator response",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, budgets)
+}
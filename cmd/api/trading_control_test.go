@@ -11,9 +11,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
 	"github.com/ajitpratap0/cryptofunk/internal/config"
 	"github.com/ajitpratap0/cryptofunk/internal/db"
 	"github.com/ajitpratap0/cryptofunk/internal/db/testhelpers"
+	"github.com/ajitpratap0/cryptofunk/internal/exchange"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -485,3 +487,214 @@ func TestOrchestratorRetry(t *testing.T) {
 	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 	assert.Equal(t, 1, attemptCount, "Should not retry on HTTP status errors")
 }
+
+// TestFlatten_RequiresConfirmationToken tests the kill switch arm/confirm flow
+func TestFlatten_RequiresConfirmationToken(t *testing.T) {
+	paused := false
+	mockOrchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pause", r.URL.Path)
+		paused = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockOrchestrator.Close()
+
+	server, tc := setupTestServer(t, mockOrchestrator)
+	_ = tc // testcontainers handles cleanup automatically
+	defer server.db.Close()
+
+	server.killSwitch = exchange.NewKillSwitch(exchange.NewMockExchange(nil), server.db,
+		audit.NewLogger(server.db.Pool(), true), exchange.DefaultKillSwitchConfig())
+	server.killSwitch.OnTrigger(server.pauseForFlatten)
+
+	// Arm
+	body, _ := json.Marshal(map[string]string{"reason": "integration test"})
+	req := httptest.NewRequest("POST", "/api/v1/trade/flatten", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var armed map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &armed))
+	token, ok := armed["token"].(string)
+	require.True(t, ok)
+	assert.NotEmpty(t, token)
+
+	// Wrong token is rejected without touching the orchestrator
+	body, _ = json.Marshal(map[string]string{"token": "wrong"})
+	req = httptest.NewRequest("POST", "/api/v1/trade/flatten/confirm", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, paused)
+
+	// Confirm
+	body, _ = json.Marshal(map[string]string{"token": token})
+	req = httptest.NewRequest("POST", "/api/v1/trade/flatten/confirm", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, paused)
+
+	var confirmed map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &confirmed))
+	report, ok := confirmed["report"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, true, report["success"])
+}
+
+// TestFlatten_HaltOnlyInPaperMode tests that the paper mode kill switch pauses
+// every session and the orchestrator without touching orders or positions
+func TestFlatten_HaltOnlyInPaperMode(t *testing.T) {
+	t.Setenv("TRADING_MODE", "")
+	paused := false
+	mockOrchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/pause", r.URL.Path)
+		paused = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockOrchestrator.Close()
+
+	server, tc := setupTestServer(t, mockOrchestrator)
+	_ = tc
+	defer server.db.Close()
+
+	killSwitch, err := newKillSwitch(&config.Config{Trading: config.TradingConfig{Mode: "paper"}}, server.db, nil)
+	require.NoError(t, err)
+	require.True(t, killSwitch.HaltOnly())
+	killSwitch.OnTrigger(server.haltForFlatten)
+	server.killSwitch = killSwitch
+	server.killSwitchErr = errFlattenPaperMode
+
+	ctx := context.Background()
+	session := &db.TradingSession{
+		ID:             uuid.New(),
+		Symbol:         "BTCUSDT",
+		Mode:           db.TradingModePaper,
+		Exchange:       "PAPER",
+		InitialCapital: 10000.0,
+		StartedAt:      time.Now(),
+	}
+	require.NoError(t, server.db.CreateSession(ctx, session))
+
+	body, _ := json.Marshal(map[string]string{"source": "risk_agent", "reason": "drawdown"})
+	req := httptest.NewRequest("POST", "/api/v1/trade/flatten", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var armed map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &armed))
+	assert.Equal(t, true, armed["halt_only"])
+
+	body, _ = json.Marshal(map[string]string{"token": armed["token"].(string)})
+	req = httptest.NewRequest("POST", "/api/v1/trade/flatten/confirm", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var confirmed map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &confirmed))
+	report, ok := confirmed["report"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, true, report["halt_only"])
+	assert.Equal(t, true, report["success"])
+	assert.True(t, paused)
+
+	sessionPaused, err := server.db.IsSessionPaused(ctx, session.ID)
+	require.NoError(t, err)
+	assert.True(t, sessionPaused)
+
+	// The status endpoint reports the degraded kill switch
+	req = httptest.NewRequest("GET", "/api/v1/status", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var status map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	components := status["components"].(map[string]interface{})
+	assert.Equal(t, "degraded", components["kill_switch"])
+}
+
+// TestSessions_ListAndPauseIndependently tests that concurrent sessions are listed and paused independently
+func TestSessions_ListAndPauseIndependently(t *testing.T) {
+	mockOrchestrator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer mockOrchestrator.Close()
+
+	server, tc := setupTestServer(t, mockOrchestrator)
+	_ = tc
+	defer server.db.Close()
+
+	ctx := context.Background()
+	paperSession := &db.TradingSession{
+		ID:             uuid.New(),
+		Symbol:         "BTCUSDT",
+		Mode:           db.TradingModePaper,
+		Exchange:       "PAPER",
+		InitialCapital: 10000.0,
+		StartedAt:      time.Now(),
+	}
+	liveSession := &db.TradingSession{
+		ID:             uuid.New(),
+		Symbol:         "ETHUSDT",
+		Mode:           db.TradingModeLive,
+		Exchange:       "BINANCE",
+		InitialCapital: 5000.0,
+		StartedAt:      time.Now(),
+	}
+	require.NoError(t, server.db.CreateSession(ctx, paperSession))
+	require.NoError(t, server.db.CreateSession(ctx, liveSession))
+
+	// Pause only the paper session
+	body, _ := json.Marshal(map[string]string{"session_id": paperSession.ID.String()})
+	req := httptest.NewRequest("POST", "/api/v1/trade/pause", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest("GET", "/api/v1/sessions", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var listed struct {
+		Sessions []map[string]interface{} `json:"sessions"`
+		Count    int                      `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	assert.Equal(t, 2, listed.Count)
+	for _, session := range listed.Sessions {
+		assert.Equal(t, session["session_id"] == paperSession.ID.String(), session["paused"])
+	}
+
+	// Filter by mode
+	req = httptest.NewRequest("GET", "/api/v1/sessions?mode=live", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Equal(t, 1, listed.Count)
+	assert.Equal(t, liveSession.ID.String(), listed.Sessions[0]["session_id"])
+
+	// Get a single session
+	req = httptest.NewRequest("GET", "/api/v1/sessions/"+paperSession.ID.String(), nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var single map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &single))
+	assert.Equal(t, true, single["session"]["paused"])
+
+	paused, err := server.db.IsSessionPaused(ctx, liveSession.ID)
+	require.NoError(t, err)
+	assert.False(t, paused)
+}
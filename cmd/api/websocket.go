@@ -31,6 +31,7 @@ const (
 	MessageTypeSystemStatus   MessageType = "system_status"
 	MessageTypeDecision       MessageType = "decision"       // LLM decision updates
 	MessageTypeDecisionStats  MessageType = "decision_stats" // Decision statistics updates
+	MessageTypeKillSwitch     MessageType = "kill_switch"    // Kill switch flatten reports
 	MessageTypeError          MessageType = "error"
 	MessageTypePing           MessageType = "ping"
 	MessageTypePong           MessageType = "pong"
@@ -39,10 +39,16 @@ var (
 	// Capital and risk
 	initialCapital = flag.Float64("capital", 10000.0, "Initial capital in USD")
 	commissionRate = flag.Float64("commission", 0.001, "Commission rate (0.001 = 0.1%)")
-	positionSizing = flag.String("sizing", "percent", "Position sizing method (fixed, percent, kelly)")
-	positionSize   = flag.Float64("size", 0.1, "Position size (depends on sizing method)")
+	positionSizing = flag.String("sizing", "percent", "Position sizing method (fixed, percent, kelly, vol_target, atr)")
+	positionSize   = flag.Float64("size", 0.1, "Position size (depends on sizing method; vol_target: annualized volatility, atr: risk per trade)")
 	maxPositions   = flag.Int("max-positions", 3, "Maximum concurrent positions")
 
+	// Volatility-target and ATR sizing
+	volLookback   = flag.Int("vol-lookback", 30, "Candles of returns used to measure volatility (vol_target)")
+	portfolioVol  = flag.Float64("portfolio-vol", 0, "Cap on annualized portfolio volatility (vol_target, 0 = none)")
+	atrPeriod     = flag.Int("atr-period", 14, "ATR period in candles (atr)")
+	atrMultiplier = flag.Float64("atr-multiplier", 2.0, "Stop distance in ATRs (atr)")
+
 	// Optimization
 	optimize = flag.Bool("optimize", false, "Run parameter optimization")
 	// TODO: Will be used in Phase 11 for advanced strategy optimization
@@ -159,6 +165,11 @@ func runBacktest(ctx context.Context, start, end time.Time, symbolList []string)
 		StartDate:      start,
 		EndDate:        end,
 		Symbols:        symbolList,
+
+		VolatilityLookback:        *volLookback,
+		PortfolioTargetVolatility: *portfolioVol,
+		ATRPeriod:                 *atrPeriod,
+		ATRMultiplier:             *atrMultiplier,
 	}
 
 	// Create backtest engine
@@ -0,0 +1,86 @@
+// Exchange cassette CLI: records venue traffic through a proxy, or replays a
+// recorded cassette so the order-executor can run offline.
+//
+// Point the order-executor at the proxy with BINANCE_BASE_URL=http://<addr> and
+// BINANCE_WS_BASE_URL=ws://<addr>/ws.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ajitpratap0/cryptofunk/internal/exchange/cassette"
+)
+
+func main() {
+	command := flag.String("command", "replay", "Command to run: record or replay")
+	path := flag.String("cassette", "", "Cassette file to write (record) or read (replay)")
+	addr := flag.String("addr", "127.0.0.1:8090", "Address to listen on")
+	restUpstream := flag.String("rest", "https://testnet.binance.vision", "REST upstream to record")
+	wsUpstream := flag.String("ws", "wss://stream.testnet.binance.vision", "WebSocket upstream to record")
+	realTime := flag.Bool("realtime", true, "Replay stream messages at their recorded timing")
+	flag.Parse()
+
+	if *path == "" {
+		fmt.Fprintf(os.Stderr, "ERROR: --cassette is required\n")
+		fmt.Fprintf(os.Stderr, "Usage: exchange-cassette -command=[record|replay] -cassette=path.json\n")
+		os.Exit(1)
+	}
+
+	var handler http.Handler
+	var recorder *cassette.Recorder
+	switch *command {
+	case "record":
+		recorder = cassette.NewRecorder(strings.TrimSuffix(filepath.Base(*path), filepath.Ext(*path)), *restUpstream, *wsUpstream)
+		handler = recorder
+		fmt.Printf("Recording %s and %s on %s\n", *restUpstream, *wsUpstream, *addr)
+	case "replay":
+		c, err := cassette.Load(*path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load cassette: %v\n", err)
+			os.Exit(1)
+		}
+		replayer := cassette.NewReplayer(c)
+		replayer.RealTime = *realTime
+		handler = replayer
+		fmt.Printf("Replaying %s (%d interactions, %d streams) on %s\n", c.Name, len(c.Interactions), len(c.Streams), *addr)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", *command)
+		fmt.Fprintf(os.Stderr, "Usage: exchange-cassette -command=[record|replay] -cassette=path.json\n")
+		os.Exit(1)
+	}
+
+	server := &http.Server{Addr: *addr, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Server failed: %v\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(ctx)
+
+	if recorder != nil {
+		c := recorder.Cassette()
+		if err := c.Save(*path); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save cassette: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Saved %d interactions and %d streams to %s\n", len(c.Interactions), len(c.Streams), *path)
+	}
+}
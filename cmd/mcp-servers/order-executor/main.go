@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
 	"github.com/ajitpratap0/cryptofunk/internal/config"
 	"github.com/ajitpratap0/cryptofunk/internal/db"
 	"github.com/ajitpratap0/cryptofunk/internal/exchange"
@@ -20,10 +22,20 @@ const (
 	toolPlaceMarketOrder = "place_market_order"
 	toolPlaceLimitOrder  = "place_limit_order"
 	toolCancelOrder      = "cancel_order"
+	toolAmendOrder       = "amend_order"
 	toolGetOrderStatus   = "get_order_status"
 	toolStartSession     = "start_session"
 	toolStopSession      = "stop_session"
 	toolGetSessionStats  = "get_session_stats"
+	toolPlaceAlgoOrder   = "place_algo_order"
+	toolGetAlgoOrder     = "get_algo_order_status"
+	toolCancelAlgoOrder  = "cancel_algo_order"
+	toolGetInstrument    = "get_instrument"
+	toolGetAccount       = "get_account"
+	toolListSessions     = "list_sessions"
+	toolPauseSession     = "pause_session"
+	toolResumeSession    = "resume_session"
+	toolGetMarginStatus  = "get_margin_status"
 )
 
 func main() {
@@ -38,36 +50,16 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to load configuration")
 	}
 
-	// Read trading mode from environment or config
-	tradingMode := os.Getenv("TRADING_MODE")
-	if tradingMode == "" {
-		tradingMode = cfg.Trading.Mode
-	}
-
-	// Get Binance configuration (from Vault or env vars via config)
-	var binanceAPIKey, binanceSecret string
-	var binanceTestnet bool
-
-	if binanceCfg, ok := cfg.Exchanges["binance"]; ok {
-		binanceAPIKey = binanceCfg.APIKey
-		binanceSecret = binanceCfg.SecretKey
-		binanceTestnet = binanceCfg.Testnet
-	}
-
-	// Allow environment variable override for development
-	if envKey := os.Getenv("BINANCE_API_KEY"); envKey != "" {
-		binanceAPIKey = envKey
-	}
-	if envSecret := os.Getenv("BINANCE_API_SECRET"); envSecret != "" {
-		binanceSecret = envSecret
-	}
-	if os.Getenv("BINANCE_TESTNET") == "true" {
-		binanceTestnet = true
+	// Build the exchange configuration (Vault secrets, then environment overrides)
+	exchangeConfig, err := exchange.ServiceConfigFromConfig(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid exchange configuration")
 	}
 
 	log.Info().
-		Str("mode", tradingMode).
-		Bool("testnet", binanceTestnet).
+		Str("mode", string(exchangeConfig.Mode)).
+		Bool("testnet", exchangeConfig.BinanceTestnet).
+		Str("market", exchangeConfig.BinanceMarket).
 		Bool("vault_enabled", config.GetVaultConfigFromEnv().Enabled).
 		Msg("Configuration loaded successfully")
 
@@ -82,17 +74,20 @@ func main() {
 	log.Info().Msg("Database connection established")
 
 	// Create exchange service with configuration
-	exchangeConfig := exchange.ServiceConfig{
-		Mode:           exchange.TradingMode(tradingMode),
-		BinanceAPIKey:  binanceAPIKey,
-		BinanceSecret:  binanceSecret,
-		BinanceTestnet: binanceTestnet,
+	if len(exchangeConfig.Venues) > 1 {
+		names := make([]string, 0, len(exchangeConfig.Venues))
+		for _, venue := range exchangeConfig.Venues {
+			names = append(names, venue.Name)
+		}
+		log.Info().Strs("venues", names).Msg("Smart order routing enabled")
 	}
 
 	exchangeService, err := exchange.NewService(database, exchangeConfig)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to create exchange service")
 	}
+	exchangeService.SetAuditLogger(audit.NewLogger(database.Pool(), true))
+	exchangeService.StartMarginGuard(ctx)
 
 	// Start MCP server with stdio transport
 	server := &MCPServer{
@@ -165,8 +160,9 @@ type MCPResponse struct {
 
 // MCPError represents an MCP error
 type MCPError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
 }
 
 // handleRequest routes the request to the appropriate handler
@@ -199,6 +195,11 @@ func (s *MCPServer) handleRequest(req *MCPRequest) *MCPResponse {
 				Code:    -32603,
 				Message: err.Error(),
 			}
+			// Pre-trade rejections carry the failing check and its limit
+			var rejection *exchange.PreTradeError
+			if errors.As(err, &rejection) {
+				resp.Error.Data = rejection
+			}
 		} else {
 			resp.Result = result
 		}
@@ -214,7 +215,7 @@ func (s *MCPServer) handleRequest(req *MCPRequest) *MCPResponse {
 
 // listTools returns the list of available tools
 func (s *MCPServer) listTools() interface{} {
-	return map[string]interface{}{
+	tools := map[string]interface{}{
 		"tools": []map[string]interface{}{
 			{
 				"name":        toolPlaceMarketOrder,
@@ -235,6 +236,20 @@ func (s *MCPServer) listTools() interface{} {
 							"type":        "number",
 							"description": "Order quantity",
 						},
+						"tax_lot_ids": map[string]interface{}{
+							"type":        "array",
+							"description": "Tax lots to dispose of first when the session uses specific_id matching",
+							"items":       map[string]interface{}{"type": "string"},
+						},
+						"reduce_only": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Futures only: the order may only reduce an open position",
+						},
+						"position_side": map[string]interface{}{
+							"type":        "string",
+							"description": "Futures only: BOTH in one-way mode, LONG or SHORT in hedge mode",
+							"enum":        []string{"BOTH", "LONG", "SHORT"},
+						},
 					},
 					"required": []string{"symbol", "side", "quantity"},
 				},
@@ -262,6 +277,20 @@ func (s *MCPServer) listTools() interface{} {
 							"type":        "number",
 							"description": "Limit price",
 						},
+						"tax_lot_ids": map[string]interface{}{
+							"type":        "array",
+							"description": "Tax lots to dispose of first when the session uses specific_id matching",
+							"items":       map[string]interface{}{"type": "string"},
+						},
+						"reduce_only": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Futures only: the order may only reduce an open position",
+						},
+						"position_side": map[string]interface{}{
+							"type":        "string",
+							"description": "Futures only: BOTH in one-way mode, LONG or SHORT in hedge mode",
+							"enum":        []string{"BOTH", "LONG", "SHORT"},
+						},
 					},
 					"required": []string{"symbol", "side", "quantity", "price"},
 				},
@@ -280,6 +309,28 @@ func (s *MCPServer) listTools() interface{} {
 					"required": []string{"order_id"},
 				},
 			},
+			{
+				"name":        toolAmendOrder,
+				"description": "Move a resting limit order: change its price and/or total quantity without losing its order ID",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"order_id": map[string]interface{}{
+							"type":        "string",
+							"description": "Order ID to amend",
+						},
+						"price": map[string]interface{}{
+							"type":        "number",
+							"description": "New limit price (omit to keep the current price)",
+						},
+						"quantity": map[string]interface{}{
+							"type":        "number",
+							"description": "New total order quantity including any filled amount (omit to keep the current quantity)",
+						},
+					},
+					"required": []string{"order_id"},
+				},
+			},
 			{
 				"name":        toolGetOrderStatus,
 				"description": "Get current status and details of an order",
@@ -296,7 +347,7 @@ func (s *MCPServer) listTools() interface{} {
 			},
 			{
 				"name":        toolStartSession,
-				"description": "Start a new trading session for paper trading",
+				"description": "Start a new trading session. Sessions run side by side with isolated orders, positions and P&L",
 				"inputSchema": map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
@@ -308,6 +359,21 @@ func (s *MCPServer) listTools() interface{} {
 							"type":        "number",
 							"description": "Initial capital for the trading session",
 						},
+						"mode": map[string]interface{}{
+							"type":        "string",
+							"description": "Trading mode of the session: 'paper' or 'live' (defaults to the server mode)",
+							"enum":        []string{"paper", "live"},
+						},
+						"tax_lot_method": map[string]interface{}{
+							"type":        "string",
+							"description": "Tax-lot matching for realized gain reports: 'fifo' (default), 'lifo', 'hifo' or 'specific_id'",
+							"enum":        []string{"fifo", "lifo", "hifo", "specific_id"},
+						},
+						"lot_method": map[string]interface{}{
+							"type":        "string",
+							"description": "How closing fills are matched against open lots: 'average_cost' (default) or 'fifo'",
+							"enum":        []string{"average_cost", "fifo"},
+						},
 						"config": map[string]interface{}{
 							"type":        "object",
 							"description": "Optional configuration parameters for the session",
@@ -318,7 +384,7 @@ func (s *MCPServer) listTools() interface{} {
 			},
 			{
 				"name":        toolStopSession,
-				"description": "Stop the current trading session and retrieve final statistics",
+				"description": "Stop a trading session and retrieve final statistics",
 				"inputSchema": map[string]interface{}{
 					"type": "object",
 					"properties": map[string]interface{}{
@@ -332,7 +398,147 @@ func (s *MCPServer) listTools() interface{} {
 			},
 			{
 				"name":        toolGetSessionStats,
-				"description": "Get current statistics for the active trading session",
+				"description": "Get current statistics for a trading session",
+				"inputSchema": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{},
+					"required":   []string{},
+				},
+			},
+			{
+				"name":        toolPlaceAlgoOrder,
+				"description": "Execute a parent order by slicing it into child orders with an execution algorithm (TWAP, VWAP, POV or iceberg)",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"symbol": map[string]interface{}{
+							"type":        "string",
+							"description": "Trading pair symbol (e.g., 'BTCUSDT')",
+						},
+						"side": map[string]interface{}{
+							"type":        "string",
+							"description": "Order side: 'buy' or 'sell'",
+							"enum":        []string{"buy", "sell"},
+						},
+						"quantity": map[string]interface{}{
+							"type":        "number",
+							"description": "Total (parent) order quantity",
+						},
+						"algo": map[string]interface{}{
+							"type":        "string",
+							"description": "Execution algorithm: 'twap' (equal time slices), 'vwap' (historical volume profile), 'pov' (percentage of market volume) or 'iceberg' (small visible slices)",
+							"enum":        []string{"twap", "vwap", "pov", "iceberg"},
+						},
+						"duration_seconds": map[string]interface{}{
+							"type":        "number",
+							"description": "Execution horizon in seconds (required for twap, vwap and pov)",
+						},
+						"slices": map[string]interface{}{
+							"type":        "number",
+							"description": "Number of child orders (twap, vwap) or volume checks (pov), default 10",
+						},
+						"limit_price": map[string]interface{}{
+							"type":        "number",
+							"description": "Optional limit price for child orders (market orders if omitted)",
+						},
+						"participation_rate": map[string]interface{}{
+							"type":        "number",
+							"description": "Fraction of market volume to trade, 0-1 (required for pov)",
+						},
+						"display_quantity": map[string]interface{}{
+							"type":        "number",
+							"description": "Visible quantity of each child order (required for iceberg)",
+						},
+					},
+					"required": []string{"symbol", "side", "quantity", "algo"},
+				},
+			},
+			{
+				"name":        toolGetAlgoOrder,
+				"description": "Get execution progress of an algo order including its child orders",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"algo_order_id": map[string]interface{}{
+							"type":        "string",
+							"description": "Algo (parent) order ID to query",
+						},
+					},
+					"required": []string{"algo_order_id"},
+				},
+			},
+			{
+				"name":        toolCancelAlgoOrder,
+				"description": "Stop an algo order and cancel its resting child orders",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"algo_order_id": map[string]interface{}{
+							"type":        "string",
+							"description": "Algo (parent) order ID to cancel",
+						},
+					},
+					"required": []string{"algo_order_id"},
+				},
+			},
+			{
+				"name":        toolGetInstrument,
+				"description": "Get exchange trading rules for a symbol (tick size, lot size, min notional, precision)",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"symbol": map[string]interface{}{
+							"type":        "string",
+							"description": "Trading pair symbol (e.g., BTCUSDT)",
+						},
+					},
+					"required": []string{"symbol"},
+				},
+			},
+			{
+				"name":        toolGetAccount,
+				"description": "Get exchange account balances (free and locked per asset)",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"asset": map[string]interface{}{
+							"type":        "string",
+							"description": "Only return the balance for this asset (e.g., USDT)",
+						},
+					},
+					"required": []string{},
+				},
+			},
+			{
+				"name":        toolGetMarginStatus,
+				"description": "Get margin ratio, liquidation price and liquidation distance of every futures position. Margin is account-wide: live sessions share one futures account",
+				"inputSchema": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{},
+					"required":   []string{},
+				},
+			},
+			{
+				"name":        toolListSessions,
+				"description": "List the active trading sessions with their mode, pause state and P&L",
+				"inputSchema": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{},
+					"required":   []string{},
+				},
+			},
+			{
+				"name":        toolPauseSession,
+				"description": "Pause a trading session so it rejects new orders (resting orders stay open)",
+				"inputSchema": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{},
+					"required":   []string{},
+				},
+			},
+			{
+				"name":        toolResumeSession,
+				"description": "Resume a paused trading session",
 				"inputSchema": map[string]interface{}{
 					"type":       "object",
 					"properties": map[string]interface{}{},
@@ -341,6 +547,24 @@ func (s *MCPServer) listTools() interface{} {
 			},
 		},
 	}
+
+	addSessionIDArgument(tools["tools"].([]map[string]interface{}))
+	return tools
+}
+
+// addSessionIDArgument lets every tool except start_session and list_sessions
+// target a session with the session_id argument
+func addSessionIDArgument(tools []map[string]interface{}) {
+	for _, tool := range tools {
+		if tool["name"] == toolStartSession || tool["name"] == toolListSessions {
+			continue
+		}
+		schema := tool["inputSchema"].(map[string]interface{})
+		schema["properties"].(map[string]interface{})["session_id"] = map[string]interface{}{
+			"type":        "string",
+			"description": "Trading session ID (optional when at most one session is active)",
+		}
+	}
 }
 
 // callTool executes the specified tool
@@ -357,6 +581,8 @@ func (s *MCPServer) callTool(name string, args map[string]interface{}) (interfac
 		return s.service.PlaceLimitOrder(ctx, args)
 	case toolCancelOrder:
 		return s.service.CancelOrder(ctx, args)
+	case toolAmendOrder:
+		return s.service.AmendOrder(ctx, args)
 	case toolGetOrderStatus:
 		return s.service.GetOrderStatus(ctx, args)
 	case toolStartSession:
@@ -365,6 +591,24 @@ func (s *MCPServer) callTool(name string, args map[string]interface{}) (interfac
 		return s.service.StopSession(ctx, args)
 	case toolGetSessionStats:
 		return s.service.GetSessionStats(ctx, args)
+	case toolPlaceAlgoOrder:
+		return s.service.PlaceAlgoOrder(ctx, args)
+	case toolGetAlgoOrder:
+		return s.service.GetAlgoOrderStatus(ctx, args)
+	case toolCancelAlgoOrder:
+		return s.service.CancelAlgoOrder(ctx, args)
+	case toolGetInstrument:
+		return s.service.GetInstrument(ctx, args)
+	case toolGetAccount:
+		return s.service.GetAccount(ctx, args)
+	case toolGetMarginStatus:
+		return s.service.GetMarginStatus(ctx, args)
+	case toolListSessions:
+		return s.service.ListSessions(ctx, args)
+	case toolPauseSession:
+		return s.service.PauseSession(ctx, args)
+	case toolResumeSession:
+		return s.service.ResumeSession(ctx, args)
 	default:
 		return nil, fmt.Errorf("unknown tool: %s", name)
 	}
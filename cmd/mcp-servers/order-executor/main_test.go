@@ -67,7 +67,7 @@ func TestOrderExecutorServer_ListTools(t *testing.T) {
 
 	tools, ok := result["tools"].([]map[string]interface{})
 	require.True(t, ok)
-	assert.Len(t, tools, 7) // 7 tools: place_market_order, place_limit_order, cancel_order, get_order_status, start_session, stop_session, get_session_stats
+	assert.Len(t, tools, 17) // 17 tools: place_market_order, place_limit_order, cancel_order, amend_order, get_order_status, start_session, stop_session, get_session_stats, place_algo_order, get_algo_order_status, cancel_algo_order, get_instrument, get_account, get_margin_status, list_sessions, pause_session, resume_session
 
 	// Verify tool names
 	toolNames := make([]string, len(tools))
@@ -77,10 +77,20 @@ func TestOrderExecutorServer_ListTools(t *testing.T) {
 	assert.Contains(t, toolNames, "place_market_order")
 	assert.Contains(t, toolNames, "place_limit_order")
 	assert.Contains(t, toolNames, "cancel_order")
+	assert.Contains(t, toolNames, "amend_order")
 	assert.Contains(t, toolNames, "get_order_status")
 	assert.Contains(t, toolNames, "start_session")
 	assert.Contains(t, toolNames, "stop_session")
 	assert.Contains(t, toolNames, "get_session_stats")
+	assert.Contains(t, toolNames, "place_algo_order")
+	assert.Contains(t, toolNames, "get_algo_order_status")
+	assert.Contains(t, toolNames, "cancel_algo_order")
+	assert.Contains(t, toolNames, "get_instrument")
+	assert.Contains(t, toolNames, "get_account")
+	assert.Contains(t, toolNames, "get_margin_status")
+	assert.Contains(t, toolNames, "list_sessions")
+	assert.Contains(t, toolNames, "pause_session")
+	assert.Contains(t, toolNames, "resume_session")
 }
 
 func TestStartSession_ValidInput(t *testing.T) {
@@ -104,6 +104,45 @@ func TestCallTool_CancelOrder(t *testing.T) {
 	assert.Equal(t, exchange.OrderStatusCancelled, order.Status)
 }
 
+// TestCallTool_AmendOrder tests calling amend_order tool
+func TestCallTool_AmendOrder(t *testing.T) {
+	service := exchange.NewServicePaper(nil)
+	server := &MCPServer{
+		service: service,
+	}
+
+	placeResult, err := server.callTool("place_limit_order", map[string]interface{}{
+		"symbol":   "BTCUSDT",
+		"side":     "buy",
+		"quantity": 0.1,
+		"price":    40000.0,
+	})
+	assert.NoError(t, err)
+	placedOrder := placeResult.(*exchange.Order)
+
+	// Move the price up and increase the size
+	result, err := server.callTool("amend_order", map[string]interface{}{
+		"order_id": placedOrder.ID,
+		"price":    41000.0,
+		"quantity": 0.2,
+	})
+
+	assert.NoError(t, err)
+	order, ok := result.(*exchange.Order)
+	assert.True(t, ok, "Result should be an *exchange.Order")
+	assert.Equal(t, placedOrder.ID, order.ID)
+	assert.Equal(t, 41000.0, order.Price)
+	assert.Equal(t, 0.2, order.Quantity)
+	assert.Len(t, order.Amendments, 1)
+	assert.Equal(t, 40000.0, order.Amendments[0].PreviousPrice)
+
+	// At least one of price or quantity is required
+	_, err = server.callTool("amend_order", map[string]interface{}{
+		"order_id": placedOrder.ID,
+	})
+	assert.Error(t, err)
+}
+
 // TestCallTool_GetOrderStatus tests calling get_order_status tool
 func TestCallTool_GetOrderStatus(t *testing.T) {
 	service := exchange.NewServicePaper(nil)
@@ -194,6 +233,74 @@ func TestCallTool_GetSessionStats(t *testing.T) {
 	assert.Nil(t, result)
 }
 
+// TestCallTool_AlgoOrderLifecycle tests place_algo_order, get_algo_order_status and cancel_algo_order
+func TestCallTool_AlgoOrderLifecycle(t *testing.T) {
+	service := exchange.NewServicePaper(nil)
+	server := &MCPServer{
+		service: service,
+	}
+
+	placeResult, err := server.callTool("place_algo_order", map[string]interface{}{
+		"symbol":           "BTCUSDT",
+		"side":             "buy",
+		"quantity":         1.0,
+		"algo":             "twap",
+		"duration_seconds": 3600.0,
+		"slices":           12.0,
+	})
+	assert.NoError(t, err)
+	placed, ok := placeResult.(map[string]interface{})
+	assert.True(t, ok)
+	algoOrderID := placed["algo_order_id"].(string)
+	assert.NotEmpty(t, algoOrderID)
+	assert.Equal(t, "running", placed["status"])
+
+	statusResult, err := server.callTool("get_algo_order_status", map[string]interface{}{
+		"algo_order_id": algoOrderID,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, algoOrderID, statusResult.(map[string]interface{})["algo_order_id"])
+
+	cancelResult, err := server.callTool("cancel_algo_order", map[string]interface{}{
+		"algo_order_id": algoOrderID,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "cancelled", cancelResult.(map[string]interface{})["status"])
+}
+
+// TestCallTool_GetAccount tests that get_account reflects paper fills
+func TestCallTool_GetAccount(t *testing.T) {
+	service := exchange.NewServicePaper(nil)
+	server := &MCPServer{
+		service: service,
+	}
+
+	result, err := server.callTool("get_account", map[string]interface{}{})
+	assert.NoError(t, err)
+	account, ok := result.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "mock", account["exchange"])
+	assert.NotEmpty(t, account["balances"])
+
+	_, err = server.callTool("place_market_order", map[string]interface{}{
+		"symbol":   "BTCUSDT",
+		"side":     "buy",
+		"quantity": 0.01,
+	})
+	assert.NoError(t, err)
+
+	result, err = server.callTool("get_account", map[string]interface{}{"asset": "btc"})
+	assert.NoError(t, err)
+	balance, ok := result.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "BTC", balance["asset"])
+	assert.InDelta(t, 0.01, balance["free"], 1e-9)
+
+	result, err = server.callTool("get_account", map[string]interface{}{"asset": "USDT"})
+	assert.NoError(t, err)
+	assert.Less(t, result.(map[string]interface{})["free"], 10000.0)
+}
+
 // TestCallTool_UnknownTool tests calling an unknown tool
 func TestCallTool_UnknownTool(t *testing.T) {
 	service := exchange.NewServicePaper(nil)
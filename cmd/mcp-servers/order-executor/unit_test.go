@@ -113,7 +113,7 @@ func TestListTools(t *testing.T) {
 
 	tools, ok := resultMap["tools"].([]map[string]interface{})
 	require.True(t, ok)
-	assert.Len(t, tools, 7)
+	assert.Len(t, tools, 17)
 
 	// Verify all expected tools are present
 	toolNames := make(map[string]bool)
@@ -131,10 +131,20 @@ func TestListTools(t *testing.T) {
 		toolPlaceMarketOrder,
 		toolPlaceLimitOrder,
 		toolCancelOrder,
+		toolAmendOrder,
 		toolGetOrderStatus,
 		toolStartSession,
 		toolStopSession,
 		toolGetSessionStats,
+		toolPlaceAlgoOrder,
+		toolGetAlgoOrder,
+		toolCancelAlgoOrder,
+		toolGetInstrument,
+		toolGetAccount,
+		toolGetMarginStatus,
+		toolListSessions,
+		toolPauseSession,
+		toolResumeSession,
 	}
 
 	for _, expected := range expectedTools {
@@ -142,6 +152,24 @@ func TestListTools(t *testing.T) {
 	}
 }
 
+// TestListTools_SessionID tests that session-scoped tools accept a session_id
+func TestListTools_SessionID(t *testing.T) {
+	server := &MCPServer{}
+
+	tools := server.listTools().(map[string]interface{})["tools"].([]map[string]interface{})
+	for _, tool := range tools {
+		properties := tool["inputSchema"].(map[string]interface{})["properties"].(map[string]interface{})
+		_, hasSessionID := properties["session_id"]
+
+		switch tool["name"] {
+		case toolStartSession, toolListSessions:
+			assert.False(t, hasSessionID, "%s should not take a session_id", tool["name"])
+		default:
+			assert.True(t, hasSessionID, "%s should take a session_id", tool["name"])
+		}
+	}
+}
+
 // TestHandleRequest_UnknownMethod tests handling of unknown methods
 func TestHandleRequest_UnknownMethod(t *testing.T) {
 	server := &MCPServer{
@@ -188,7 +216,7 @@ func TestHandleRequest_ListTools(t *testing.T) {
 
 	tools, ok := resultMap["tools"].([]map[string]interface{})
 	require.True(t, ok)
-	assert.Len(t, tools, 7)
+	assert.Len(t, tools, 17)
 }
 
 // TestMCPRequestStructure tests the MCP request structure
@@ -296,8 +324,8 @@ func TestToolCount(t *testing.T) {
 	resultMap := result.(map[string]interface{})
 	tools := resultMap["tools"].([]map[string]interface{})
 
-	// We expect exactly 7 tools
-	assert.Equal(t, 7, len(tools), "Should have exactly 7 tools defined")
+	// We expect exactly 17 tools
+	assert.Equal(t, 17, len(tools), "Should have exactly 17 tools defined")
 }
 
 // TestMCPErrorCodes tests standard MCP error codes
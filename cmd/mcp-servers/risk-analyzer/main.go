@@ -2,13 +2,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math"
 	"os"
+	"sort"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
 )
 
 func main() {
@@ -20,13 +26,35 @@ func main() {
 	// Start MCP server with stdio transport
 	server := &MCPServer{}
 
+	// Candlestick correlations need the database; explicit return series work without it
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	database, err := db.New(ctx)
+	cancel()
+	if err != nil {
+		log.Warn().Err(err).Msg("Database unavailable, correlation matrices, stress tests and volatility sizing require explicit inputs")
+	} else {
+		defer database.Close()
+		calculator := risk.NewCalculatorWithPool(database.Pool())
+		server.calculator = calculator
+		server.correlations = risk.NewCorrelationService(calculator)
+		server.stress = risk.NewStressTester(calculator)
+		server.openPositions = func(ctx context.Context) ([]risk.StressPosition, error) {
+			return openStressPositions(ctx, database)
+		}
+	}
+
 	if err := server.Run(); err != nil {
 		log.Fatal().Err(err).Msg("Server failed")
 	}
 }
 
 // MCPServer handles MCP protocol over stdio
-type MCPServer struct{}
+type MCPServer struct {
+	calculator    *risk.Calculator                                         // nil without a database
+	correlations  *risk.CorrelationService                                 // nil without a database
+	stress        *risk.StressTester                                       // nil without a database
+	openPositions func(ctx context.Context) ([]risk.StressPosition, error) // nil without a database
+}
 
 // Run starts the MCP server
 func (s *MCPServer) Run() error {
@@ -165,6 +193,127 @@ func (s *MCPServer) listTools() interface{} {
 					"required": []string{"win_rate", "avg_win", "avg_loss", "capital", "kelly_fraction"},
 				},
 			},
+			{
+				"name":        "calculate_vol_target_size",
+				"description": "Size a position, or scale the whole portfolio, to a target annualized volatility",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"capital": map[string]interface{}{
+							"type":        "number",
+							"description": "Total trading capital",
+						},
+						"target_volatility": map[string]interface{}{
+							"type":        "number",
+							"description": "Target annualized volatility as a fraction of capital (e.g., 0.2 for 20%)",
+						},
+						"volatility": map[string]interface{}{
+							"type":        "number",
+							"description": "Annualized volatility of the asset",
+						},
+						"returns": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "number"},
+							"description": "Period returns of the asset, used when volatility is omitted",
+						},
+						"periods_per_year": map[string]interface{}{
+							"type":        "number",
+							"description": "Return periods per year (default: 365 for daily)",
+						},
+						"symbol": map[string]interface{}{
+							"type":        "string",
+							"description": "Symbol whose volatility and price are loaded from candlesticks when not given",
+						},
+						"interval": map[string]interface{}{
+							"type":        "string",
+							"description": "Candle interval for loaded volatility (default: 1d)",
+						},
+						"days": map[string]interface{}{
+							"type":        "number",
+							"description": "Days of candles for loaded volatility (default: 30)",
+						},
+						"price": map[string]interface{}{
+							"type":        "number",
+							"description": "Current price, to convert notional to quantity",
+						},
+						"positions": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "object"},
+							"description": "Portfolio positions with symbol, value and returns; scales the whole portfolio to the target",
+						},
+						"max_leverage": map[string]interface{}{
+							"type":        "number",
+							"description": "Maximum notional as a multiple of capital (default: 1)",
+						},
+					},
+					"required": []string{"capital", "target_volatility"},
+				},
+			},
+			{
+				"name":        "calculate_atr_position_size",
+				"description": "Size a position so that a stop N×ATR away risks a fixed share of capital",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"capital": map[string]interface{}{
+							"type":        "number",
+							"description": "Total trading capital",
+						},
+						"risk_per_trade": map[string]interface{}{
+							"type":        "number",
+							"description": "Share of capital lost if the stop is hit (e.g., 0.01 for 1%)",
+						},
+						"price": map[string]interface{}{
+							"type":        "number",
+							"description": "Entry price; loaded from candlesticks when omitted and a symbol is given",
+						},
+						"side": map[string]interface{}{
+							"type":        "string",
+							"description": "BUY/LONG or SELL/SHORT (default: BUY)",
+						},
+						"atr": map[string]interface{}{
+							"type":        "number",
+							"description": "Average True Range in price units",
+						},
+						"atr_multiplier": map[string]interface{}{
+							"type":        "number",
+							"description": "Stop distance in ATRs (default: 2)",
+						},
+						"atr_period": map[string]interface{}{
+							"type":        "number",
+							"description": "ATR period in candles (default: 14)",
+						},
+						"highs": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "number"},
+							"description": "Candle highs, oldest first, used when atr is omitted",
+						},
+						"lows": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "number"},
+							"description": "Candle lows, oldest first",
+						},
+						"closes": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "number"},
+							"description": "Candle closes, oldest first",
+						},
+						"symbol": map[string]interface{}{
+							"type":        "string",
+							"description": "Symbol whose ATR and price are loaded from candlesticks when not given",
+						},
+						"interval": map[string]interface{}{
+							"type":        "string",
+							"description": "Candle interval for loaded ATR (default: 1h)",
+						},
+						"max_leverage": map[string]interface{}{
+							"type":        "number",
+							"description": "Maximum notional as a multiple of capital (default: 1)",
+						},
+					},
+					"required": []string{"capital", "risk_per_trade"},
+				},
+			},
 			{
 				"name":        "calculate_var",
 				"description": "Calculate Value at Risk (VaR) for a return series",
@@ -184,6 +333,105 @@ func (s *MCPServer) listTools() interface{} {
 					"required": []string{"returns", "confidence_level"},
 				},
 			},
+			{
+				"name":        "calculate_portfolio_var",
+				"description": "Calculate portfolio Value at Risk and expected shortfall (CVaR) from position values and return series",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"positions": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "object"},
+							"description": "Positions with symbol, value (negative for shorts) and returns (period returns, oldest first)",
+						},
+						"method": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"parametric", "historical", "filtered_historical", "monte_carlo", "all"},
+							"description": "VaR method (default: historical)",
+						},
+						"confidence_level": map[string]interface{}{
+							"type":        "number",
+							"description": "Confidence level (default: 0.95)",
+						},
+						"horizon_periods": map[string]interface{}{
+							"type":        "number",
+							"description": "Horizon in return periods, scaled by the square root of time (default: 1)",
+						},
+						"ewma_lambda": map[string]interface{}{
+							"type":        "number",
+							"description": "EWMA decay for filtered historical VaR (default: 0.94)",
+						},
+						"simulations": map[string]interface{}{
+							"type":        "number",
+							"description": "Monte Carlo scenarios (default: 10000)",
+						},
+					},
+					"required": []string{"positions"},
+				},
+			},
+			{
+				"name":        "calculate_expected_shortfall",
+				"description": "Calculate VaR and expected shortfall of a return series with every VaR method",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"returns": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "number"},
+							"description": "Array of historical returns",
+						},
+						"confidence_level": map[string]interface{}{
+							"type":        "number",
+							"description": "Confidence level (default: 0.95)",
+						},
+						"method": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"parametric", "historical", "filtered_historical", "monte_carlo", "all"},
+							"description": "VaR method (default: all)",
+						},
+					},
+					"required": []string{"returns"},
+				},
+			},
+			{
+				"name":        "calculate_correlation_matrix",
+				"description": "Calculate the return correlation matrix of symbols from candlesticks or explicit return series",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"symbols": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "string"},
+							"description": "Symbols whose candlestick returns are correlated (requires database)",
+						},
+						"returns": map[string]interface{}{
+							"type":        "object",
+							"description": "Return series by symbol, oldest first (used instead of candlesticks)",
+						},
+						"interval": map[string]interface{}{
+							"type":        "string",
+							"description": "Candle interval (default: 1h)",
+						},
+						"days": map[string]interface{}{
+							"type":        "number",
+							"description": "Days of candles to load (default: 30)",
+						},
+						"method": map[string]interface{}{
+							"type":        "string",
+							"enum":        []string{"rolling", "ewma"},
+							"description": "Rolling window or EWMA weighting (default: rolling)",
+						},
+						"window": map[string]interface{}{
+							"type":        "number",
+							"description": "Rolling window in periods (default: 30)",
+						},
+						"lambda": map[string]interface{}{
+							"type":        "number",
+							"description": "EWMA decay (default: 0.94)",
+						},
+					},
+				},
+			},
 			{
 				"name":        "check_portfolio_limits",
 				"description": "Check if a proposed trade violates portfolio risk limits",
@@ -201,12 +449,34 @@ func (s *MCPServer) listTools() interface{} {
 						},
 						"limits": map[string]interface{}{
 							"type":        "object",
-							"description": "Risk limits: max_exposure, max_concentration, max_drawdown",
+							"description": "Risk limits: max_exposure, max_concentration, max_drawdown, max_correlation, max_correlated_exposure",
+						},
+						"correlations": map[string]interface{}{
+							"type":        "object",
+							"description": "Correlation matrix (symbols, matrix) for max_correlated_exposure; loaded from candlesticks when omitted",
 						},
 					},
 					"required": []string{"current_positions", "new_trade", "limits"},
 				},
 			},
+			{
+				"name":        "run_stress_test",
+				"description": "Estimate the P&L impact of shock, historical replay and correlation-stressed scenarios on positions",
+				"inputSchema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"positions": map[string]interface{}{
+							"type":        "array",
+							"items":       map[string]string{"type": "object"},
+							"description": "Positions with symbol, side (LONG/SHORT), quantity, entry_price and optional price; open positions are loaded from the database when omitted",
+						},
+						"scenarios": map[string]interface{}{
+							"type":        "array",
+							"description": "Historical scenario names (covid_march_2020, may_2021_crash, ftx_collapse_2022) or scenario objects (default: all historical scenarios)",
+						},
+					},
+				},
+			},
 			{
 				"name":        "calculate_sharpe",
 				"description": "Calculate Sharpe ratio for a return series",
@@ -254,10 +524,22 @@ func (s *MCPServer) callTool(name string, args map[string]interface{}) (interfac
 	switch name {
 	case "calculate_position_size":
 		return s.calculatePositionSize(args)
+	case "calculate_vol_target_size":
+		return s.calculateVolTargetSize(args)
+	case "calculate_atr_position_size":
+		return s.calculateATRPositionSize(args)
 	case "calculate_var":
 		return s.calculateVaR(args)
+	case "calculate_portfolio_var":
+		return risk.NewService().CalculatePortfolioVaR(args)
+	case "calculate_expected_shortfall":
+		return s.calculateExpectedShortfall(args)
+	case "calculate_correlation_matrix":
+		return s.calculateCorrelationMatrix(args)
 	case "check_portfolio_limits":
 		return s.checkPortfolioLimits(args)
+	case "run_stress_test":
+		return s.runStressTest(args)
 	case "calculate_sharpe":
 		return s.calculateSharpe(args)
 	case "calculate_drawdown":
@@ -403,6 +685,95 @@ func (s *MCPServer) calculatePositionSize(args map[string]interface{}) (interfac
 	return result, nil
 }
 
+// calculateVolTargetSize sizes to a target volatility. A symbol's volatility
+// and price are loaded from candlesticks when they are not given.
+func (s *MCPServer) calculateVolTargetSize(args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("calculateVolTargetSize called")
+
+	symbol, _ := args["symbol"].(string)
+	_, hasVolatility := args["volatility"]
+	_, hasReturns := args["returns"]
+	_, hasPositions := args["positions"]
+	if symbol == "" || s.calculator == nil || hasPositions {
+		return risk.NewService().CalculateVolTargetSize(args)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sizingArgs := make(map[string]interface{}, len(args)+2)
+	for key, value := range args {
+		sizingArgs[key] = value
+	}
+	if !hasVolatility && !hasReturns {
+		interval, _ := args["interval"].(string)
+		if interval == "" {
+			interval = "1d"
+		}
+		days := 30
+		if v, ok := args["days"].(float64); ok && v > 0 {
+			days = int(v)
+		}
+		volatility, err := s.calculator.LoadAnnualizedVolatility(ctx, symbol, interval, days)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load volatility of %s: %w", symbol, err)
+		}
+		sizingArgs["volatility"] = volatility
+	}
+	if _, ok := args["price"]; !ok {
+		if price, err := s.calculator.GetCurrentPrice(ctx, symbol, "1h"); err == nil {
+			sizingArgs["price"] = price
+		}
+	}
+
+	return risk.NewService().CalculateVolTargetSize(sizingArgs)
+}
+
+// calculateATRPositionSize sizes a position and its stop from the ATR. A
+// symbol's ATR and price are loaded from candlesticks when they are not given.
+func (s *MCPServer) calculateATRPositionSize(args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("calculateATRPositionSize called")
+
+	symbol, _ := args["symbol"].(string)
+	if symbol == "" || s.calculator == nil {
+		return risk.NewService().CalculateATRSize(args)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	interval, _ := args["interval"].(string)
+	if interval == "" {
+		interval = "1h"
+	}
+	sizingArgs := make(map[string]interface{}, len(args)+2)
+	for key, value := range args {
+		sizingArgs[key] = value
+	}
+	_, hasATR := args["atr"]
+	_, hasCandles := args["highs"]
+	if !hasATR && !hasCandles {
+		period := risk.DefaultATRPeriod
+		if v, ok := args["atr_period"].(float64); ok && v > 0 {
+			period = int(v)
+		}
+		atr, err := s.calculator.LoadATR(ctx, symbol, interval, period)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load ATR of %s: %w", symbol, err)
+		}
+		sizingArgs["atr"] = atr
+	}
+	if _, ok := args["price"]; !ok {
+		price, err := s.calculator.GetCurrentPrice(ctx, symbol, interval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load price of %s: %w", symbol, err)
+		}
+		sizingArgs["price"] = price
+	}
+
+	return risk.NewService().CalculateATRSize(sizingArgs)
+}
+
 func (s *MCPServer) calculateVaR(args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("calculateVaR called")
 
@@ -517,6 +888,45 @@ func (s *MCPServer) calculateVaR(args map[string]interface{}) (interface{}, erro
 	return result, nil
 }
 
+// calculateExpectedShortfall estimates VaR and expected shortfall of a single
+// return series, comparing all VaR methods unless one is requested
+func (s *MCPServer) calculateExpectedShortfall(args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("calculateExpectedShortfall called")
+
+	returns, ok := args["returns"]
+	if !ok {
+		return nil, fmt.Errorf("returns is required")
+	}
+
+	portfolioArgs := map[string]interface{}{
+		"positions": []interface{}{
+			map[string]interface{}{"symbol": "series", "value": 1.0, "returns": returns},
+		},
+		"method": "all",
+	}
+	for _, key := range []string{"confidence_level", "method", "simulations", "ewma_lambda"} {
+		if v, ok := args[key]; ok {
+			portfolioArgs[key] = v
+		}
+	}
+
+	result, err := risk.NewService().CalculatePortfolioVaR(portfolioArgs)
+	if err != nil {
+		return nil, err
+	}
+	estimates := result.(*risk.PortfolioVaRResult).Estimates
+
+	log.Info().
+		Int("sample_size", estimates[0].Observations).
+		Int("methods", len(estimates)).
+		Msg("Expected shortfall calculated")
+
+	return map[string]interface{}{
+		"estimates":   estimates,
+		"sample_size": estimates[0].Observations,
+	}, nil
+}
+
 func (s *MCPServer) checkPortfolioLimits(args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("checkPortfolioLimits called")
 
@@ -651,6 +1061,25 @@ func (s *MCPServer) checkPortfolioLimits(args map[string]interface{}) (interface
 		}
 	}
 
+	// Correlated exposure: share of the portfolio in symbols correlated at or above max_correlation with the trade
+	maxCorrelation := 0.7
+	var maxCorrelatedExposure float64
+	_, hasMaxCorrelatedExposure := limits["max_correlated_exposure"]
+	if hasMaxCorrelatedExposure {
+		var err error
+		if maxCorrelatedExposure, err = extractFloat(limits, "max_correlated_exposure"); err != nil {
+			return nil, fmt.Errorf("limits.max_correlated_exposure must be a number")
+		}
+		if maxCorrelatedExposure <= 0 || maxCorrelatedExposure > 1 {
+			return nil, fmt.Errorf("limits.max_correlated_exposure must be between 0 and 1 (got %f)", maxCorrelatedExposure)
+		}
+		if _, ok := limits["max_correlation"]; ok {
+			if maxCorrelation, err = extractFloat(limits, "max_correlation"); err != nil {
+				return nil, fmt.Errorf("limits.max_correlation must be a number")
+			}
+		}
+	}
+
 	// Calculate current portfolio metrics
 	var totalPortfolioValue float64
 	positionsBySymbol := make(map[string]float64)
@@ -746,6 +1175,35 @@ func (s *MCPServer) checkPortfolioLimits(args map[string]interface{}) (interface
 		}
 	}
 
+	// Check correlated exposure
+	if hasMaxCorrelatedExposure && newTotalValue > 0 {
+		correlations, err := s.portfolioCorrelations(args, positionsBySymbol)
+		if err != nil {
+			return nil, err
+		}
+
+		positions := make([]risk.Position, 0, len(positionsBySymbol))
+		for sym, val := range positionsBySymbol {
+			positions = append(positions, risk.Position{Symbol: sym, Size: val})
+		}
+		correlatedExposure := risk.CorrelatedExposure(correlations, positions, tradeSymbol, maxCorrelation)
+		correlatedShare := correlatedExposure / newTotalValue
+		correlationViolation := correlatedShare > maxCorrelatedExposure
+
+		checks["correlation_check"] = map[string]interface{}{
+			"symbol":                  tradeSymbol,
+			"correlated_exposure":     correlatedExposure,
+			"correlated_share":        correlatedShare,
+			"max_correlation":         maxCorrelation,
+			"max_correlated_exposure": maxCorrelatedExposure,
+			"violated":                correlationViolation,
+		}
+
+		if correlationViolation {
+			violations = append(violations, fmt.Sprintf("Exposure correlated with %s %.2f%% exceeds maximum %.2f%%", tradeSymbol, correlatedShare*100, maxCorrelatedExposure*100))
+		}
+	}
+
 	// Check max drawdown (if applicable - requires historical equity data)
 	if hasMaxDrawdown {
 		checks["drawdown_check"] = map[string]interface{}{
@@ -786,6 +1244,193 @@ func (s *MCPServer) checkPortfolioLimits(args map[string]interface{}) (interface
 	return result, nil
 }
 
+// portfolioCorrelations returns the correlations argument, or loads the
+// correlations of the portfolio's symbols from candlesticks
+func (s *MCPServer) portfolioCorrelations(args map[string]interface{}, positionsBySymbol map[string]float64) (*risk.CorrelationMatrix, error) {
+	if raw, ok := args["correlations"].(map[string]interface{}); ok {
+		return risk.ParseCorrelationMatrix(raw)
+	}
+	if s.correlations == nil {
+		return nil, fmt.Errorf("correlations are required for max_correlated_exposure when no database is available")
+	}
+
+	symbols := make([]string, 0, len(positionsBySymbol))
+	for sym := range positionsBySymbol {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return s.correlations.Matrix(ctx, symbols, "1h", 30, risk.CorrelationOptions{})
+}
+
+// calculateCorrelationMatrix correlates explicit return series, or the
+// candlestick returns of symbols
+func (s *MCPServer) calculateCorrelationMatrix(args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("calculateCorrelationMatrix called")
+
+	method, _ := args["method"].(string)
+	options := risk.CorrelationOptions{}
+	var err error
+	if options.Method, err = risk.ParseCorrelationMethod(method); err != nil {
+		return nil, err
+	}
+	if _, ok := args["window"]; ok {
+		window, err := extractFloat(args, "window")
+		if err != nil {
+			return nil, err
+		}
+		options.Window = int(window)
+	}
+	if _, ok := args["lambda"]; ok {
+		if options.Lambda, err = extractFloat(args, "lambda"); err != nil {
+			return nil, err
+		}
+	}
+
+	var matrix *risk.CorrelationMatrix
+	if returnsRaw, ok := args["returns"].(map[string]interface{}); ok {
+		symbols := make([]string, 0, len(returnsRaw))
+		for symbol := range returnsRaw {
+			symbols = append(symbols, symbol)
+		}
+		sort.Strings(symbols)
+
+		returns := make([][]float64, len(symbols))
+		for i, symbol := range symbols {
+			series, ok := returnsRaw[symbol].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("returns.%s must be an array", symbol)
+			}
+			returns[i] = make([]float64, len(series))
+			for j, v := range series {
+				if returns[i][j], ok = v.(float64); !ok {
+					return nil, fmt.Errorf("returns.%s[%d] must be a number", symbol, j)
+				}
+			}
+		}
+		if matrix, err = risk.NewCorrelationMatrix(symbols, returns, options); err != nil {
+			return nil, err
+		}
+	} else {
+		symbolsRaw, ok := args["symbols"].([]interface{})
+		if !ok || len(symbolsRaw) == 0 {
+			return nil, fmt.Errorf("symbols or returns is required")
+		}
+		if s.correlations == nil {
+			return nil, fmt.Errorf("candlestick correlations require a database; pass returns instead")
+		}
+		symbols := make([]string, len(symbolsRaw))
+		for i, v := range symbolsRaw {
+			if symbols[i], ok = v.(string); !ok {
+				return nil, fmt.Errorf("symbols[%d] must be a string", i)
+			}
+		}
+
+		interval, _ := args["interval"].(string)
+		if interval == "" {
+			interval = "1h"
+		}
+		days := 30
+		if _, ok := args["days"]; ok {
+			d, err := extractFloat(args, "days")
+			if err != nil {
+				return nil, err
+			}
+			days = int(d)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if matrix, err = s.correlations.Matrix(ctx, symbols, interval, days, options); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info().
+		Int("symbols", len(matrix.Symbols)).
+		Str("method", string(matrix.Method)).
+		Int("observations", matrix.Observations).
+		Msg("Correlation matrix calculated")
+
+	return matrix, nil
+}
+
+// runStressTest stresses explicit positions, or the open positions in the
+// database when none are given
+func (s *MCPServer) runStressTest(args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("runStressTest called")
+
+	// Without a database historical scenarios use their built-in shocks
+	if s.stress == nil {
+		return risk.NewService().RunStressTest(args)
+	}
+
+	scenarios, err := risk.ParseStressScenarios(args["scenarios"])
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var positions []risk.StressPosition
+	if raw, ok := args["positions"]; ok {
+		positionsRaw, ok := raw.([]interface{})
+		if !ok || len(positionsRaw) == 0 {
+			return nil, fmt.Errorf("positions must be a non-empty array")
+		}
+		positions, err = risk.ParseStressPositions(positionsRaw)
+	} else {
+		positions, err = s.openPositions(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.stress.RunAll(ctx, positions, scenarios)
+	if err != nil {
+		return nil, err
+	}
+	result := risk.NewStressTestResult(results)
+
+	log.Info().
+		Int("positions", len(positions)).
+		Int("scenarios", len(results)).
+		Str("worst_scenario", result.WorstScenario).
+		Float64("worst_pnl", result.WorstPnL).
+		Msg("Stress test completed")
+
+	return result, nil
+}
+
+// openStressPositions marks the open positions to their latest close,
+// falling back to the entry price when no candlestick is available
+func openStressPositions(ctx context.Context, database *db.DB) ([]risk.StressPosition, error) {
+	open, err := database.GetAllOpenPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open positions: %w", err)
+	}
+
+	positions := make([]risk.StressPosition, 0, len(open))
+	for _, position := range open {
+		price, err := database.GetLatestClosePrice(ctx, position.Symbol)
+		if err != nil {
+			log.Debug().Err(err).Str("symbol", position.Symbol).Msg("No latest price, stressing at entry price")
+			price = 0
+		}
+		positions = append(positions, risk.StressPosition{
+			Symbol:     position.Symbol,
+			Side:       string(position.Side),
+			Quantity:   position.Quantity,
+			EntryPrice: position.EntryPrice,
+			Price:      price,
+		})
+	}
+	return positions, nil
+}
+
 func (s *MCPServer) calculateSharpe(args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("calculateSharpe called")
 
@@ -3,6 +3,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"math"
 	"strings"
@@ -10,6 +11,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
 )
 
 func TestRiskAnalyzerServer_Initialize(t *testing.T) {
@@ -58,7 +61,7 @@ func TestRiskAnalyzerServer_ListTools(t *testing.T) {
 
 	tools, ok := result["tools"].([]map[string]interface{})
 	require.True(t, ok)
-	assert.Len(t, tools, 5) // 5 tools: position_size, var, limits, sharpe, drawdown
+	assert.Len(t, tools, 11) // 11 tools: position_size, vol_target_size, atr_position_size, var, portfolio_var, expected_shortfall, correlation_matrix, limits, stress_test, sharpe, drawdown
 
 	// Verify tool names
 	toolNames := make([]string, len(tools))
@@ -66,8 +69,14 @@ func TestRiskAnalyzerServer_ListTools(t *testing.T) {
 		toolNames[i] = tool["name"].(string)
 	}
 	assert.Contains(t, toolNames, "calculate_position_size")
+	assert.Contains(t, toolNames, "calculate_vol_target_size")
+	assert.Contains(t, toolNames, "calculate_atr_position_size")
 	assert.Contains(t, toolNames, "calculate_var")
+	assert.Contains(t, toolNames, "calculate_portfolio_var")
+	assert.Contains(t, toolNames, "calculate_expected_shortfall")
+	assert.Contains(t, toolNames, "calculate_correlation_matrix")
 	assert.Contains(t, toolNames, "check_portfolio_limits")
+	assert.Contains(t, toolNames, "run_stress_test")
 	assert.Contains(t, toolNames, "calculate_sharpe")
 	assert.Contains(t, toolNames, "calculate_drawdown")
 }
@@ -218,6 +227,103 @@ func TestCalculateVaR_EmptyReturns(t *testing.T) {
 	assert.NotNil(t, resp.Error)
 }
 
+func TestCalculatePortfolioVaR_AllMethods(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      9,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "calculate_portfolio_var"
+	req.Params.Arguments = map[string]interface{}{
+		"positions": []interface{}{
+			map[string]interface{}{
+				"symbol":  "BTCUSDT",
+				"value":   6000.0,
+				"returns": []interface{}{0.01, -0.02, 0.03, -0.01, 0.02, -0.03, 0.01, -0.04, 0.02, 0.01},
+			},
+			map[string]interface{}{
+				"symbol":  "ETHUSDT",
+				"value":   4000.0,
+				"returns": []interface{}{0.02, -0.03, 0.02, -0.02, 0.03, -0.04, 0.02, -0.05, 0.01, 0.02},
+			},
+		},
+		"method":      "all",
+		"simulations": 2000.0,
+	}
+
+	resp := server.handleRequest(&req)
+	require.Nil(t, resp.Error)
+
+	data, err := json.Marshal(resp.Result)
+	require.NoError(t, err)
+	var result struct {
+		GrossValue float64            `json:"gross_value"`
+		Weights    map[string]float64 `json:"weights"`
+		Estimates  []struct {
+			Method            string  `json:"method"`
+			VaR               float64 `json:"var"`
+			ExpectedShortfall float64 `json:"expected_shortfall"`
+			VaRAmount         float64 `json:"var_amount"`
+		} `json:"estimates"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+
+	assert.Equal(t, 10000.0, result.GrossValue)
+	assert.InDelta(t, 0.6, result.Weights["BTCUSDT"], 1e-9)
+	require.Len(t, result.Estimates, 4)
+	for _, estimate := range result.Estimates {
+		assert.Greater(t, estimate.VaR, 0.0, estimate.Method)
+		assert.GreaterOrEqual(t, estimate.ExpectedShortfall, estimate.VaR, estimate.Method)
+		assert.InDelta(t, estimate.VaR*10000, estimate.VaRAmount, 1e-6, estimate.Method)
+	}
+}
+
+func TestCalculatePortfolioVaR_InvalidMethod(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      10,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "calculate_portfolio_var"
+	req.Params.Arguments = map[string]interface{}{
+		"positions": []interface{}{
+			map[string]interface{}{"symbol": "BTCUSDT", "value": 1000.0, "returns": []interface{}{0.01, -0.02, 0.03}},
+		},
+		"method": "garch",
+	}
+
+	resp := server.handleRequest(&req)
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Message, "invalid VaR method")
+}
+
+func TestCalculateExpectedShortfall_ValidInput(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      11,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "calculate_expected_shortfall"
+	req.Params.Arguments = map[string]interface{}{
+		"returns":          []interface{}{0.01, -0.02, 0.03, -0.01, 0.02, -0.03, 0.01},
+		"confidence_level": 0.9,
+	}
+
+	resp := server.handleRequest(&req)
+	require.Nil(t, resp.Error)
+
+	result, ok := resp.Result.(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, 7, result["sample_size"])
+	assert.Contains(t, result, "estimates")
+}
+
 func TestCheckPortfolioLimits_ValidInput(t *testing.T) {
 	server := &MCPServer{}
 
@@ -260,6 +366,279 @@ func TestCheckPortfolioLimits_ValidInput(t *testing.T) {
 	assert.Contains(t, result, "recommendation")
 }
 
+func TestCheckPortfolioLimits_CorrelatedExposure(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      12,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "check_portfolio_limits"
+	req.Params.Arguments = map[string]interface{}{
+		"current_positions": []interface{}{
+			map[string]interface{}{"symbol": "BTCUSDT", "value": 20000.0},
+			map[string]interface{}{"symbol": "SOLUSDT", "value": 10000.0},
+			map[string]interface{}{"symbol": "PAXGUSDT", "value": 20000.0},
+		},
+		"new_trade": map[string]interface{}{
+			"symbol":   "ETHUSDT",
+			"quantity": 5.0,
+			"price":    3000.0,
+			"side":     "BUY",
+		},
+		"limits": map[string]interface{}{
+			"max_correlation":         0.7,
+			"max_correlated_exposure": 0.5,
+		},
+		"correlations": map[string]interface{}{
+			"symbols": []interface{}{"BTCUSDT", "ETHUSDT", "SOLUSDT", "PAXGUSDT"},
+			"matrix": []interface{}{
+				[]interface{}{1.0, 0.85, 0.8, 0.1},
+				[]interface{}{0.85, 1.0, 0.75, 0.05},
+				[]interface{}{0.8, 0.75, 1.0, 0.0},
+				[]interface{}{0.1, 0.05, 0.0, 1.0},
+			},
+		},
+	}
+
+	resp := server.handleRequest(&req)
+	require.Nil(t, resp.Error)
+
+	result, ok := resp.Result.(map[string]interface{})
+	require.True(t, ok)
+	assert.False(t, result["approved"].(bool))
+
+	// ETH moves with BTC and SOL: 15000 + 20000 + 10000 of 65000
+	check := result["checks"].(map[string]interface{})["correlation_check"].(map[string]interface{})
+	assert.Equal(t, 45000.0, check["correlated_exposure"])
+	assert.True(t, check["violated"].(bool))
+}
+
+func TestRunStressTest_ExplicitPositions(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      14,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "run_stress_test"
+	req.Params.Arguments = map[string]interface{}{
+		"positions": []interface{}{
+			map[string]interface{}{"symbol": "BTCUSDT", "side": "LONG", "quantity": 1.0, "entry_price": 50000.0},
+			map[string]interface{}{"symbol": "SOLUSDT", "side": "LONG", "quantity": 100.0, "entry_price": 100.0},
+		},
+		"scenarios": []interface{}{
+			map[string]interface{}{
+				"name":          "btc_down_30_alts_down_45",
+				"shocks":        map[string]interface{}{"BTC": -0.30},
+				"default_shock": -0.45,
+			},
+		},
+	}
+
+	resp := server.handleRequest(&req)
+	require.Nil(t, resp.Error)
+
+	result, ok := resp.Result.(*risk.StressTestResult)
+	require.True(t, ok)
+	require.Len(t, result.Results, 1)
+	assert.InDelta(t, -19500.0, result.WorstPnL, 1e-9) // -15000 BTC, -4500 SOL
+	assert.Equal(t, "BTCUSDT", result.Results[0].WorstSymbol)
+
+	// Without a database positions are required
+	req.Params.Arguments = map[string]interface{}{}
+	resp = server.handleRequest(&req)
+	assert.NotNil(t, resp.Error)
+}
+
+func TestRunStressTest_OpenPositions(t *testing.T) {
+	server := &MCPServer{
+		stress: risk.NewStressTester(nil),
+		openPositions: func(ctx context.Context) ([]risk.StressPosition, error) {
+			return []risk.StressPosition{
+				{Symbol: "ETHUSDT", Side: "SHORT", Quantity: 10, EntryPrice: 3000, Price: 2500},
+			}, nil
+		},
+	}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      15,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "run_stress_test"
+	req.Params.Arguments = map[string]interface{}{
+		"scenarios": []interface{}{"ftx_collapse_2022"},
+	}
+
+	resp := server.handleRequest(&req)
+	require.Nil(t, resp.Error)
+
+	result, ok := resp.Result.(*risk.StressTestResult)
+	require.True(t, ok)
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, "ftx_collapse_2022", result.WorstScenario)
+	assert.InDelta(t, 8000.0, result.Results[0].PnL, 1e-9) // Short ETH gains 32% of $25,000
+}
+
+func TestCalculateVolTargetSize_ExplicitVolatility(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      16,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "calculate_vol_target_size"
+	req.Params.Arguments = map[string]interface{}{
+		"capital":           100000.0,
+		"target_volatility": 0.15,
+		"volatility":        0.6,
+		"price":             2500.0,
+	}
+
+	resp := server.handleRequest(&req)
+	require.Nil(t, resp.Error)
+
+	result, ok := resp.Result.(*risk.VolTargetSizing)
+	require.True(t, ok)
+	assert.InDelta(t, 25000.0, result.Notional, 1e-9)
+	assert.InDelta(t, 10.0, result.Quantity, 1e-9)
+}
+
+func TestCalculateVolTargetSize_SymbolWithoutDatabase(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      17,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "calculate_vol_target_size"
+	req.Params.Arguments = map[string]interface{}{
+		"capital":           100000.0,
+		"target_volatility": 0.15,
+		"symbol":            "BTCUSDT",
+	}
+
+	resp := server.handleRequest(&req)
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Message, "volatility or returns is required")
+}
+
+func TestCalculateATRPositionSize(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      18,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "calculate_atr_position_size"
+	req.Params.Arguments = map[string]interface{}{
+		"capital":        50000.0,
+		"risk_per_trade": 0.02,
+		"price":          3000.0,
+		"side":           "SHORT",
+		"atr":            50.0,
+		"atr_multiplier": 3.0,
+	}
+
+	resp := server.handleRequest(&req)
+	require.Nil(t, resp.Error)
+
+	result, ok := resp.Result.(*risk.ATRSizing)
+	require.True(t, ok)
+	assert.InDelta(t, 1000.0/150.0, result.Quantity, 1e-9)
+	assert.InDelta(t, 3150.0, result.StopLoss, 1e-9)
+	assert.InDelta(t, 1000.0, result.RiskAmount, 1e-9)
+}
+
+func TestCheckPortfolioLimits_CorrelatedExposureWithoutCorrelations(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      13,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "check_portfolio_limits"
+	req.Params.Arguments = map[string]interface{}{
+		"current_positions": []interface{}{
+			map[string]interface{}{"symbol": "BTCUSDT", "value": 20000.0},
+		},
+		"new_trade": map[string]interface{}{
+			"symbol":   "ETHUSDT",
+			"quantity": 1.0,
+			"price":    3000.0,
+			"side":     "BUY",
+		},
+		"limits": map[string]interface{}{
+			"max_correlated_exposure": 0.5,
+		},
+	}
+
+	resp := server.handleRequest(&req)
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Message, "correlations are required")
+}
+
+func TestCalculateCorrelationMatrix_FromReturns(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      14,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "calculate_correlation_matrix"
+	req.Params.Arguments = map[string]interface{}{
+		"returns": map[string]interface{}{
+			"BTCUSDT":  []interface{}{0.01, -0.02, 0.03, -0.01, 0.02},
+			"ETHUSDT":  []interface{}{0.02, -0.04, 0.06, -0.02, 0.04},
+			"USDCUSDT": []interface{}{-0.01, 0.02, -0.03, 0.01, -0.02},
+		},
+		"method": "ewma",
+	}
+
+	resp := server.handleRequest(&req)
+	require.Nil(t, resp.Error)
+
+	data, err := json.Marshal(resp.Result)
+	require.NoError(t, err)
+	var matrix struct {
+		Symbols []string    `json:"symbols"`
+		Matrix  [][]float64 `json:"matrix"`
+		Method  string      `json:"method"`
+	}
+	require.NoError(t, json.Unmarshal(data, &matrix))
+
+	assert.Equal(t, []string{"BTCUSDT", "ETHUSDT", "USDCUSDT"}, matrix.Symbols)
+	assert.Equal(t, "ewma", matrix.Method)
+	assert.InDelta(t, 1.0, matrix.Matrix[0][1], 1e-9)
+	assert.InDelta(t, -1.0, matrix.Matrix[0][2], 1e-9)
+}
+
+func TestCalculateCorrelationMatrix_SymbolsWithoutDatabase(t *testing.T) {
+	server := &MCPServer{}
+
+	req := MCPRequest{
+		JSONRPC: "2.0",
+		ID:      15,
+		Method:  "tools/call",
+	}
+	req.Params.Name = "calculate_correlation_matrix"
+	req.Params.Arguments = map[string]interface{}{
+		"symbols": []interface{}{"BTCUSDT", "ETHUSDT"},
+	}
+
+	resp := server.handleRequest(&req)
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Message, "require a database")
+}
+
 func TestCalculateSharpe_ValidInput(t *testing.T) {
 	server := &MCPServer{}
 
@@ -93,6 +93,15 @@ func (h *HTTPServer) Start() error {
 	mux.HandleFunc("/resume", h.orchestrator.HandleResumeRequest)
 	mux.HandleFunc("/status", h.orchestrator.HandleControlStatusRequest)
 
+	// Risk budget utilization per agent and strategy
+	mux.HandleFunc("/api/v1/risk-budgets", h.orchestrator.HandleRiskBudgetsRequest)
+
+	// Learned agent voting weights, their audit trail, and freeze/override controls
+	mux.HandleFunc("/api/v1/agent-weights", h.orchestrator.HandleAgentWeightsRequest)
+
+	// Per-agent confidence calibration: mapping, reliability diagrams and Brier scores
+	mux.HandleFunc("/api/v1/agents/{name}/calibration", h.orchestrator.HandleAgentCalibrationRequest)
+
 	// Prometheus metrics endpoint
 	mux.Handle("/metrics", promhttp.Handler())
 
@@ -16,6 +16,7 @@ import (
 	"github.com/ajitpratap0/cryptofunk/internal/config"
 	"github.com/ajitpratap0/cryptofunk/internal/db"
 	"github.com/ajitpratap0/cryptofunk/internal/orchestrator"
+	"github.com/ajitpratap0/cryptofunk/internal/regime"
 )
 
 func main() {
@@ -72,6 +73,42 @@ func main() {
 	viper.SetDefault("orchestrator.min_confidence", 0.5)
 	viper.SetDefault("orchestrator.max_signal_age", "5m")
 	viper.SetDefault("orchestrator.health_check_interval", "1m")
+	viper.SetDefault("orchestrator.circuit_breaker_interval", "1m")
+	viper.SetDefault("orchestrator.circuit_breaker_cooldown", "1h")
+	viper.SetDefault("orchestrator.risk_budgets.metric", "capital_at_risk")
+	viper.SetDefault("orchestrator.risk_budgets.period", "24h")
+	viper.SetDefault("orchestrator.risk_budgets.interval", "1m")
+	viper.SetDefault("orchestrator.risk_budgets.throttle_start", 0.8)
+	viper.SetDefault("orchestrator.regime.enabled", false)
+	viper.SetDefault("orchestrator.regime.symbols", []string{"BTC/USDT", "ETH/USDT"})
+	viper.SetDefault("orchestrator.regime.interval", "15m")
+	viper.SetDefault("orchestrator.regime.timeframe", regime.DefaultTimeframe)
+	viper.SetDefault("orchestrator.regime.lookback_days", regime.DefaultLookbackDays)
+	viper.SetDefault("orchestrator.regime.subject", regime.DefaultSubject)
+	viper.SetDefault("orchestrator.regime.model.states", regime.DefaultStates)
+	viper.SetDefault("orchestrator.regime.model.volatility_window", regime.DefaultVolatilityWindow)
+	viper.SetDefault("orchestrator.weight_learning.enabled", false)
+	viper.SetDefault("orchestrator.weight_learning.method", orchestrator.WeightLearningHedge)
+	viper.SetDefault("orchestrator.weight_learning.interval", "1h")
+	viper.SetDefault("orchestrator.weight_learning.horizon", "4h")
+	viper.SetDefault("orchestrator.weight_learning.price_interval", "1h")
+	viper.SetDefault("orchestrator.weight_learning.deadband", 0.001)
+	viper.SetDefault("orchestrator.weight_learning.half_life", 50)
+	viper.SetDefault("orchestrator.weight_learning.learning_rate", 1.0)
+	viper.SetDefault("orchestrator.weight_learning.min_samples", 20)
+	viper.SetDefault("orchestrator.weight_learning.min_weight", 0.05)
+	viper.SetDefault("orchestrator.weight_learning.max_weight", 0.6)
+	viper.SetDefault("orchestrator.weight_learning.lookback", "168h")
+	viper.SetDefault("orchestrator.weight_learning.exclude_types", []string{"risk"})
+	viper.SetDefault("orchestrator.calibration.enabled", false)
+	viper.SetDefault("orchestrator.calibration.method", "isotonic")
+	viper.SetDefault("orchestrator.calibration.interval", "6h")
+	viper.SetDefault("orchestrator.calibration.lookback", "720h")
+	viper.SetDefault("orchestrator.calibration.horizon", "4h")
+	viper.SetDefault("orchestrator.calibration.price_interval", "1h")
+	viper.SetDefault("orchestrator.calibration.deadband", 0.001)
+	viper.SetDefault("orchestrator.calibration.min_samples", 100)
+	viper.SetDefault("orchestrator.calibration.bins", 10)
 	viper.SetDefault("orchestrator.metrics_port", 8080)
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -145,6 +182,60 @@ func main() {
 		MinConfidence:       viper.GetFloat64("orchestrator.min_confidence"),
 		MaxSignalAge:        viper.GetDuration("orchestrator.max_signal_age"),
 		HealthCheckInterval: viper.GetDuration("orchestrator.health_check_interval"),
+
+		CircuitBreakerInterval: viper.GetDuration("orchestrator.circuit_breaker_interval"),
+		CircuitBreakerCooldown: viper.GetDuration("orchestrator.circuit_breaker_cooldown"),
+
+		RiskBudgets: orchestrator.RiskBudgetConfig{
+			Metric:        viper.GetString("orchestrator.risk_budgets.metric"),
+			Period:        viper.GetDuration("orchestrator.risk_budgets.period"),
+			Interval:      viper.GetDuration("orchestrator.risk_budgets.interval"),
+			ThrottleStart: viper.GetFloat64("orchestrator.risk_budgets.throttle_start"),
+			Agents:        budgetLimits("orchestrator.risk_budgets.agents"),
+			Strategies:    budgetLimits("orchestrator.risk_budgets.strategies"),
+		},
+
+		Regime: orchestrator.RegimeConfig{
+			Service: regime.ServiceConfig{
+				Enabled:      viper.GetBool("orchestrator.regime.enabled"),
+				Symbols:      viper.GetStringSlice("orchestrator.regime.symbols"),
+				Interval:     viper.GetDuration("orchestrator.regime.interval"),
+				Timeframe:    viper.GetString("orchestrator.regime.timeframe"),
+				LookbackDays: viper.GetInt("orchestrator.regime.lookback_days"),
+				Subject:      viper.GetString("orchestrator.regime.subject"),
+				Model: regime.Config{
+					States:           viper.GetInt("orchestrator.regime.model.states"),
+					VolatilityWindow: viper.GetInt("orchestrator.regime.model.volatility_window"),
+				},
+			},
+			Weights: regimeWeights("orchestrator.regime.weights"),
+		},
+		WeightLearning: orchestrator.WeightLearningConfig{
+			Enabled:       viper.GetBool("orchestrator.weight_learning.enabled"),
+			Method:        viper.GetString("orchestrator.weight_learning.method"),
+			Interval:      viper.GetDuration("orchestrator.weight_learning.interval"),
+			Horizon:       viper.GetDuration("orchestrator.weight_learning.horizon"),
+			PriceInterval: viper.GetString("orchestrator.weight_learning.price_interval"),
+			Deadband:      viper.GetFloat64("orchestrator.weight_learning.deadband"),
+			HalfLife:      viper.GetFloat64("orchestrator.weight_learning.half_life"),
+			LearningRate:  viper.GetFloat64("orchestrator.weight_learning.learning_rate"),
+			MinSamples:    viper.GetInt("orchestrator.weight_learning.min_samples"),
+			MinWeight:     viper.GetFloat64("orchestrator.weight_learning.min_weight"),
+			MaxWeight:     viper.GetFloat64("orchestrator.weight_learning.max_weight"),
+			Lookback:      viper.GetDuration("orchestrator.weight_learning.lookback"),
+			ExcludeTypes:  viper.GetStringSlice("orchestrator.weight_learning.exclude_types"),
+		},
+		Calibration: orchestrator.CalibrationConfig{
+			Enabled:       viper.GetBool("orchestrator.calibration.enabled"),
+			Method:        viper.GetString("orchestrator.calibration.method"),
+			Interval:      viper.GetDuration("orchestrator.calibration.interval"),
+			Lookback:      viper.GetDuration("orchestrator.calibration.lookback"),
+			Horizon:       viper.GetDuration("orchestrator.calibration.horizon"),
+			PriceInterval: viper.GetString("orchestrator.calibration.price_interval"),
+			Deadband:      viper.GetFloat64("orchestrator.calibration.deadband"),
+			MinSamples:    viper.GetInt("orchestrator.calibration.min_samples"),
+			Bins:          viper.GetInt("orchestrator.calibration.bins"),
+		},
 	}
 
 	// Get metrics port
@@ -161,6 +252,18 @@ func main() {
 		Float64("min_consensus", config.MinConsensus).
 		Float64("min_confidence", config.MinConfidence).
 		Dur("max_signal_age", config.MaxSignalAge).
+		Dur("circuit_breaker_interval", config.CircuitBreakerInterval).
+		Dur("circuit_breaker_cooldown", config.CircuitBreakerCooldown).
+		Str("risk_budget_metric", config.RiskBudgets.Metric).
+		Int("agent_risk_budgets", len(config.RiskBudgets.Agents)).
+		Int("strategy_risk_budgets", len(config.RiskBudgets.Strategies)).
+		Bool("regime_service", config.Regime.Service.Enabled).
+		Strs("regime_symbols", config.Regime.Service.Symbols).
+		Int("regime_weights", len(config.Regime.Weights)).
+		Bool("weight_learning", config.WeightLearning.Enabled).
+		Str("weight_learning_method", config.WeightLearning.Method).
+		Bool("calibration", config.Calibration.Enabled).
+		Str("calibration_method", config.Calibration.Method).
 		Int("metrics_port", metricsPort).
 		Msg("Orchestrator configuration loaded")
 
@@ -240,6 +343,32 @@ func main() {
 	log.Info().Msg("Orchestrator shutdown complete")
 }
 
+// budgetLimits reads a map of risk budget limits keyed by agent name or type
+func budgetLimits(key string) map[string]float64 {
+	entries := viper.GetStringMap(key)
+	if len(entries) == 0 {
+		return nil
+	}
+	limits := make(map[string]float64, len(entries))
+	for name := range entries {
+		limits[name] = viper.GetFloat64(key + "." + name)
+	}
+	return limits
+}
+
+// regimeWeights reads voting weight multipliers keyed by regime and agent type
+func regimeWeights(key string) map[string]map[string]float64 {
+	entries := viper.GetStringMap(key)
+	if len(entries) == 0 {
+		return nil
+	}
+	weights := make(map[string]map[string]float64, len(entries))
+	for name := range entries {
+		weights[name] = budgetLimits(key + "." + name)
+	}
+	return weights
+}
+
 // verifyAPIKeys verifies all configured API keys and secrets
 // Returns 0 if all keys are valid, 1 if any keys are invalid or missing
 func verifyAPIKeys() int {
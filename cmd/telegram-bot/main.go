@@ -56,6 +56,8 @@ func main() {
 		PollingTimeout:  config.PollingTimeout,
 		Debug:           config.Debug,
 		OrchestratorURL: config.OrchestratorURL,
+		APIURL:          config.APIURL,
+		APIKey:          config.APIKey,
 	}
 
 	// Create bot instance
@@ -102,6 +104,8 @@ type Config struct {
 	PollingTimeout  int
 	Debug           bool
 	OrchestratorURL string
+	APIURL          string
+	APIKey          string
 }
 
 // loadConfig loads configuration from file and environment variables
@@ -133,6 +137,8 @@ func loadConfig() (*Config, error) {
 		PollingTimeout:  viper.GetInt("telegram.polling_timeout"),
 		Debug:           viper.GetBool("telegram.debug"),
 		OrchestratorURL: viper.GetString("api.orchestrator_url"),
+		APIURL:          viper.GetString("telegram.api_url"),
+		APIKey:          viper.GetString("telegram.api_key"),
 	}
 
 	// Override with environment variables if set
@@ -140,6 +146,10 @@ func loadConfig() (*Config, error) {
 		config.BotToken = token
 	}
 
+	if apiKey := os.Getenv("TELEGRAM_API_KEY"); apiKey != "" {
+		config.APIKey = apiKey
+	}
+
 	if enabled := os.Getenv("TELEGRAM_ENABLED"); enabled != "" {
 		config.Enabled = enabled == "true" || enabled == "1"
 	}
@@ -153,6 +163,10 @@ func loadConfig() (*Config, error) {
 		config.OrchestratorURL = "http://localhost:8081"
 	}
 
+	if config.APIURL == "" {
+		config.APIURL = "http://localhost:8080"
+	}
+
 	return config, nil
 }
 
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// ReportPeriod groups realized gains into reporting buckets
+type ReportPeriod string
+
+const (
+	ReportPeriodDay     ReportPeriod = "day"
+	ReportPeriodMonth   ReportPeriod = "month"
+	ReportPeriodQuarter ReportPeriod = "quarter"
+	ReportPeriodYear    ReportPeriod = "year"
+)
+
+// longTermHolding is the holding period after which a gain is reported as long term
+const longTermHolding = 365 * 24 * time.Hour
+
+// realizedGainsCSVHeader lists the columns of the realized gains export
+var realizedGainsCSVHeader = []string{
+	"period", "session_id", "symbol", "side", "method", "quantity",
+	"acquired_at", "disposed_at", "holding_days", "term",
+	"cost_basis", "proceeds", "gain", "lot_id", "order_id",
+}
+
+// RealizedGainsRepository reads realized gains (*db.DB satisfies it)
+type RealizedGainsRepository interface {
+	GetRealizedGains(ctx context.Context, filter db.RealizedGainFilter) ([]*db.RealizedGain, error)
+}
+
+// RealizedGainsSummary totals realized gains for one period
+type RealizedGainsSummary struct {
+	Period        string  `json:"period"`
+	Disposals     int     `json:"disposals"`
+	Quantity      float64 `json:"quantity"`
+	CostBasis     float64 `json:"cost_basis"`
+	Proceeds      float64 `json:"proceeds"`
+	Gain          float64 `json:"gain"`
+	ShortTermGain float64 `json:"short_term_gain"`
+	LongTermGain  float64 `json:"long_term_gain"`
+}
+
+// ReportsHandler serves trading reports built from the tax-lot ledger
+type ReportsHandler struct {
+	repo RealizedGainsRepository
+}
+
+// NewReportsHandler creates a new reports handler
+func NewReportsHandler(repo RealizedGainsRepository) *ReportsHandler {
+	return &ReportsHandler{repo: repo}
+}
+
+// RegisterRoutesWithRateLimiter registers report routes; readMiddleware may be nil
+func (h *ReportsHandler) RegisterRoutesWithRateLimiter(router *gin.RouterGroup, readMiddleware gin.HandlerFunc) {
+	handlers := []gin.HandlerFunc{h.GetRealizedGains}
+	if readMiddleware != nil {
+		handlers = append([]gin.HandlerFunc{readMiddleware}, handlers...)
+	}
+
+	reports := router.Group("/reports")
+	reports.GET("/realized-gains", handlers...)
+}
+
+// GetRealizedGains handles GET /api/v1/reports/realized-gains
+// @Summary Realized gains by period
+// @Tags Reports
+// @Produce json,text/csv
+// @Param session_id query string false "Trading session ID (UUID)"
+// @Param symbol query string false "Trading pair symbol"
+// @Param from query string false "Start of the range, inclusive (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "End of the range, exclusive (RFC3339 or YYYY-MM-DD)"
+// @Param period query string false "day, month (default), quarter or year"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} map[string]interface{} "Disposals and per-period totals"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Router /reports/realized-gains [get]
+func (h *ReportsHandler) GetRealizedGains(c *gin.Context) {
+	filter := db.RealizedGainFilter{
+		Symbol: c.Query("symbol"),
+	}
+
+	if sessionIDStr := c.Query("session_id"); sessionIDStr != "" {
+		sessionID, err := uuid.Parse(sessionIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID format"})
+			return
+		}
+		filter.SessionID = &sessionID
+	}
+
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := parseReportTime(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s: use RFC3339 or YYYY-MM-DD", param.name)})
+			return
+		}
+		*param.target = &parsed
+	}
+
+	period := ReportPeriod(strings.ToLower(c.DefaultQuery("period", string(ReportPeriodMonth))))
+	switch period {
+	case ReportPeriodDay, ReportPeriodMonth, ReportPeriodQuarter, ReportPeriodYear:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be day, month, quarter or year"})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "json"))
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	gains, err := h.repo.GetRealizedGains(c.Request.Context(), filter)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get realized gains")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get realized gains"})
+		return
+	}
+
+	if format == "csv" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=realized-gains-%s.csv", period))
+		c.Header("Content-Type", "text/csv")
+		c.Status(http.StatusOK)
+		if err := WriteRealizedGainsCSV(c.Writer, gains, period); err != nil {
+			log.Error().Err(err).Msg("Failed to write realized gains CSV")
+		}
+		return
+	}
+
+	summaries := SummarizeRealizedGains(gains, period)
+	var total float64
+	for _, s := range summaries {
+		total += s.Gain
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"period":     period,
+		"summary":    summaries,
+		"disposals":  gains,
+		"count":      len(gains),
+		"total_gain": total,
+	})
+}
+
+// SummarizeRealizedGains totals gains per period in chronological order
+func SummarizeRealizedGains(gains []*db.RealizedGain, period ReportPeriod) []RealizedGainsSummary {
+	byPeriod := make(map[string]*RealizedGainsSummary)
+	for _, gain := range gains {
+		key := periodKey(gain.DisposedAt, period)
+		summary, ok := byPeriod[key]
+		if !ok {
+			summary = &RealizedGainsSummary{Period: key}
+			byPeriod[key] = summary
+		}
+
+		summary.Disposals++
+		summary.Quantity += gain.Quantity
+		summary.CostBasis += gain.CostBasis
+		summary.Proceeds += gain.Proceeds
+		summary.Gain += gain.Gain
+		if isLongTerm(gain) {
+			summary.LongTermGain += gain.Gain
+		} else {
+			summary.ShortTermGain += gain.Gain
+		}
+	}
+
+	summaries := make([]RealizedGainsSummary, 0, len(byPeriod))
+	for _, summary := range byPeriod {
+		summaries = append(summaries, *summary)
+	}
+	// Period keys sort chronologically (2024-01, 2024-Q1, 2024-01-31, 2024)
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Period < summaries[j].Period
+	})
+
+	return summaries
+}
+
+// WriteRealizedGainsCSV writes one row per lot disposal, tagged with its period
+func WriteRealizedGainsCSV(w io.Writer, gains []*db.RealizedGain, period ReportPeriod) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(realizedGainsCSVHeader); err != nil {
+		return err
+	}
+
+	formatFloat := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	for _, gain := range gains {
+		sessionID := ""
+		if gain.SessionID != nil {
+			sessionID = gain.SessionID.String()
+		}
+		orderID := ""
+		if gain.OrderID != nil {
+			orderID = *gain.OrderID
+		}
+		term := "short"
+		if isLongTerm(gain) {
+			term = "long"
+		}
+		holdingDays := int(gain.DisposedAt.Sub(gain.AcquiredAt).Hours() / 24)
+
+		if err := writer.Write([]string{
+			periodKey(gain.DisposedAt, period),
+			sessionID,
+			gain.Symbol,
+			string(gain.Side),
+			gain.Method,
+			formatFloat(gain.Quantity),
+			gain.AcquiredAt.UTC().Format(time.RFC3339),
+			gain.DisposedAt.UTC().Format(time.RFC3339),
+			strconv.Itoa(holdingDays),
+			term,
+			formatFloat(gain.CostBasis),
+			formatFloat(gain.Proceeds),
+			formatFloat(gain.Gain),
+			gain.LotID.String(),
+			orderID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// periodKey returns the reporting bucket of a timestamp in UTC
+func periodKey(t time.Time, period ReportPeriod) string {
+	t = t.UTC()
+	switch period {
+	case ReportPeriodDay:
+		return t.Format("2006-01-02")
+	case ReportPeriodQuarter:
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case ReportPeriodYear:
+		return strconv.Itoa(t.Year())
+	default:
+		return t.Format("2006-01")
+	}
+}
+
+// isLongTerm reports whether the disposed lot was held longer than a year
+func isLongTerm(gain *db.RealizedGain) bool {
+	return gain.DisposedAt.Sub(gain.AcquiredAt) > longTermHolding
+}
+
+// parseReportTime accepts RFC3339 timestamps or plain dates (UTC midnight)
+func parseReportTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// mockRealizedGainsRepository returns fixed gains and records the filter
+type mockRealizedGainsRepository struct {
+	gains  []*db.RealizedGain
+	filter db.RealizedGainFilter
+}
+
+func (m *mockRealizedGainsRepository) GetRealizedGains(ctx context.Context, filter db.RealizedGainFilter) ([]*db.RealizedGain, error) {
+	m.filter = filter
+	return m.gains, nil
+}
+
+func testRealizedGains(sessionID uuid.UUID) []*db.RealizedGain {
+	orderID := "order-1"
+	return []*db.RealizedGain{
+		{
+			ID: uuid.New(), SessionID: &sessionID, LotID: uuid.New(), Symbol: "BTCUSDT",
+			Side: db.PositionSideLong, Method: "fifo", Quantity: 1, CostBasis: 100, Proceeds: 150, Gain: 50,
+			OrderID:    &orderID,
+			AcquiredAt: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+			DisposedAt: time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			ID: uuid.New(), SessionID: &sessionID, LotID: uuid.New(), Symbol: "BTCUSDT",
+			Side: db.PositionSideShort, Method: "fifo", Quantity: 0.5, CostBasis: 60, Proceeds: 50, Gain: -10,
+			AcquiredAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			DisposedAt: time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			ID: uuid.New(), SessionID: &sessionID, LotID: uuid.New(), Symbol: "ETHUSDT",
+			Side: db.PositionSideLong, Method: "fifo", Quantity: 2, CostBasis: 4000, Proceeds: 5000, Gain: 1000,
+			AcquiredAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			DisposedAt: time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC),
+		},
+	}
+}
+
+func setupReportsRouter(repo RealizedGainsRepository) *gin.Engine {
+	router := gin.New()
+	NewReportsHandler(repo).RegisterRoutesWithRateLimiter(router.Group("/api/v1"), nil)
+	return router
+}
+
+func TestReportsHandler_RealizedGainsJSON(t *testing.T) {
+	sessionID := uuid.New()
+	repo := &mockRealizedGainsRepository{gains: testRealizedGains(sessionID)}
+	router := setupReportsRouter(repo)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/reports/realized-gains?session_id="+sessionID.String()+"&symbol=BTCUSDT&from=2024-01-01&to=2024-03-01T00:00:00Z", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	require.NotNil(t, repo.filter.SessionID)
+	assert.Equal(t, sessionID, *repo.filter.SessionID)
+	assert.Equal(t, "BTCUSDT", repo.filter.Symbol)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *repo.filter.From)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), *repo.filter.To)
+
+	var resp struct {
+		Period    string                 `json:"period"`
+		Summary   []RealizedGainsSummary `json:"summary"`
+		Count     int                    `json:"count"`
+		TotalGain float64                `json:"total_gain"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "month", resp.Period)
+	assert.Equal(t, 3, resp.Count)
+	assert.InDelta(t, 1040, resp.TotalGain, 1e-9)
+
+	require.Len(t, resp.Summary, 2)
+	assert.Equal(t, "2024-01", resp.Summary[0].Period)
+	assert.InDelta(t, 50, resp.Summary[0].ShortTermGain, 1e-9)
+	assert.Equal(t, "2024-02", resp.Summary[1].Period)
+	assert.Equal(t, 2, resp.Summary[1].Disposals)
+	assert.InDelta(t, -10, resp.Summary[1].ShortTermGain, 1e-9)
+	assert.InDelta(t, 1000, resp.Summary[1].LongTermGain, 1e-9)
+}
+
+func TestReportsHandler_RealizedGainsCSV(t *testing.T) {
+	sessionID := uuid.New()
+	router := setupReportsRouter(&mockRealizedGainsRepository{gains: testRealizedGains(sessionID)})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/reports/realized-gains?format=csv&period=quarter", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "realized-gains-quarter.csv")
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, realizedGainsCSVHeader, records[0])
+
+	first := records[1]
+	assert.Equal(t, "2024-Q1", first[0])
+	assert.Equal(t, sessionID.String(), first[1])
+	assert.Equal(t, "BTCUSDT", first[2])
+	assert.Equal(t, "LONG", first[3])
+	assert.Equal(t, "15", first[8])
+	assert.Equal(t, "short", first[9])
+	assert.Equal(t, "50", first[12])
+	assert.Equal(t, "order-1", first[14])
+	assert.Equal(t, "long", records[3][9])
+}
+
+func TestReportsHandler_InvalidParams(t *testing.T) {
+	router := setupReportsRouter(&mockRealizedGainsRepository{})
+
+	for _, query := range []string{
+		"session_id=not-a-uuid",
+		"from=yesterday",
+		"period=week",
+		"format=xml",
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/reports/realized-gains?"+query, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
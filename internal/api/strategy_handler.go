@@ -1097,7 +1097,7 @@ func (h *StrategyHandler) GetSchemaInfo(c *gin.Context) {
 			},
 			"orchestration": gin.H{
 				"required": []string{"voting_enabled", "voting_method", "min_votes", "quorum", "step_interval", "max_signal_age", "min_consensus", "min_confidence"},
-				"optional": []string{"llm_reasoning_enabled", "llm_temperature"},
+				"optional": []string{"veto_agents", "llm_reasoning_enabled", "llm_temperature"},
 			},
 			"indicators": gin.H{
 				"description": "Technical indicator configurations",
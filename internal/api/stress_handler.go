@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// OpenPositionsRepository reads open positions and their latest prices (*db.DB satisfies it)
+type OpenPositionsRepository interface {
+	GetAllOpenPositions(ctx context.Context) ([]*db.Position, error)
+	GetLatestClosePrice(ctx context.Context, symbol string) (float64, error)
+}
+
+// StressHandler runs stress scenarios against the open positions
+type StressHandler struct {
+	repo   OpenPositionsRepository
+	tester *risk.StressTester
+}
+
+// NewStressHandler creates a new stress test handler
+func NewStressHandler(repo OpenPositionsRepository, tester *risk.StressTester) *StressHandler {
+	return &StressHandler{repo: repo, tester: tester}
+}
+
+// RegisterRoutesWithRateLimiter registers stress test routes; middlewares may be nil
+func (h *StressHandler) RegisterRoutesWithRateLimiter(router *gin.RouterGroup, readMiddleware, runMiddleware gin.HandlerFunc) {
+	applyRead := func(handlers ...gin.HandlerFunc) []gin.HandlerFunc {
+		if readMiddleware != nil {
+			return append([]gin.HandlerFunc{readMiddleware}, handlers...)
+		}
+		return handlers
+	}
+	// Stress runs query candlesticks per position, so they share the stricter limiter
+	applyRun := func(handlers ...gin.HandlerFunc) []gin.HandlerFunc {
+		if runMiddleware != nil {
+			return append([]gin.HandlerFunc{runMiddleware}, handlers...)
+		}
+		return handlers
+	}
+
+	stress := router.Group("/risk/stress-test")
+	stress.GET("/scenarios", applyRead(h.ListScenarios)...)
+	stress.POST("", applyRun(h.RunStressTest)...)
+}
+
+// ListScenarios handles GET /api/v1/risk/stress-test/scenarios
+// @Summary Built-in historical stress scenarios
+// @Tags Risk
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Historical scenarios"
+// @Router /risk/stress-test/scenarios [get]
+func (h *StressHandler) ListScenarios(c *gin.Context) {
+	scenarios := risk.HistoricalScenarios()
+	c.JSON(http.StatusOK, gin.H{
+		"scenarios": scenarios,
+		"count":     len(scenarios),
+	})
+}
+
+// RunStressTest handles POST /api/v1/risk/stress-test
+// @Summary Stress test the open positions
+// @Description Applies shock, historical replay and correlation-stressed scenarios to the open positions. The body's "scenarios" lists historical scenario names or scenario objects; an empty body runs every historical scenario.
+// @Tags Risk
+// @Accept json
+// @Produce json
+// @Param request body map[string]interface{} false "Scenarios to run"
+// @Success 200 {object} risk.StressTestResult "P&L impact per scenario"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Router /risk/stress-test [post]
+func (h *StressHandler) RunStressTest(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	scenarios, err := risk.ParseStressScenarios(req["scenarios"])
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	open, err := h.repo.GetAllOpenPositions(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get open positions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get open positions"})
+		return
+	}
+
+	positions := make([]risk.StressPosition, 0, len(open))
+	for _, position := range open {
+		// Positions without a recent candle are stressed at their entry price
+		price, err := h.repo.GetLatestClosePrice(ctx, position.Symbol)
+		if err != nil {
+			log.Debug().Err(err).Str("symbol", position.Symbol).Msg("No latest price, stressing at entry price")
+			price = 0
+		}
+		positions = append(positions, risk.StressPosition{
+			Symbol:     position.Symbol,
+			Side:       string(position.Side),
+			Quantity:   position.Quantity,
+			EntryPrice: position.EntryPrice,
+			Price:      price,
+		})
+	}
+
+	results, err := h.tester.RunAll(ctx, positions, scenarios)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, risk.NewStressTestResult(results))
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// mockOpenPositionsRepository returns fixed positions and prices
+type mockOpenPositionsRepository struct {
+	positions []*db.Position
+	prices    map[string]float64
+	err       error
+}
+
+func (m *mockOpenPositionsRepository) GetAllOpenPositions(ctx context.Context) ([]*db.Position, error) {
+	return m.positions, m.err
+}
+
+func (m *mockOpenPositionsRepository) GetLatestClosePrice(ctx context.Context, symbol string) (float64, error) {
+	price, ok := m.prices[symbol]
+	if !ok {
+		return 0, errors.New("no candlesticks")
+	}
+	return price, nil
+}
+
+func setupStressRouter(repo OpenPositionsRepository) *gin.Engine {
+	router := gin.New()
+	NewStressHandler(repo, risk.NewStressTester(nil)).RegisterRoutesWithRateLimiter(router.Group("/api/v1"), nil, nil)
+	return router
+}
+
+func testOpenPositions() *mockOpenPositionsRepository {
+	return &mockOpenPositionsRepository{
+		positions: []*db.Position{
+			{ID: uuid.New(), Symbol: "BTCUSDT", Side: db.PositionSideLong, EntryPrice: 40000, Quantity: 1},
+			{ID: uuid.New(), Symbol: "SOLUSDT", Side: db.PositionSideShort, EntryPrice: 100, Quantity: 50},
+		},
+		prices: map[string]float64{"BTCUSDT": 50000}, // SOL has no candles and is stressed at entry
+	}
+}
+
+func TestStressHandler_ListScenarios(t *testing.T) {
+	router := setupStressRouter(testOpenPositions())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/risk/stress-test/scenarios", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Scenarios []risk.StressScenario `json:"scenarios"`
+		Count     int                   `json:"count"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 3, resp.Count)
+	assert.Equal(t, "covid_march_2020", resp.Scenarios[0].Name)
+}
+
+func TestStressHandler_RunStressTest(t *testing.T) {
+	router := setupStressRouter(testOpenPositions())
+
+	body := `{"scenarios": ["ftx_collapse_2022", {"name": "btc_down_30_alts_down_45", "shocks": {"BTC": -0.3}, "default_shock": -0.45}]}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/risk/stress-test", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp risk.StressTestResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Results, 2)
+
+	// FTX: BTC -26% of $50,000, short SOL +65% of $5,000
+	ftx := resp.Results[0]
+	assert.InDelta(t, -13000, ftx.Positions[0].PnL, 1e-9)
+	assert.InDelta(t, 3250, ftx.Positions[1].PnL, 1e-9)
+	assert.InDelta(t, 100, ftx.Positions[1].Price, 1e-9)
+
+	// BTC -30%, short SOL +45%
+	assert.InDelta(t, -12750, resp.Results[1].PnL, 1e-9)
+	assert.Equal(t, "btc_down_30_alts_down_45", resp.WorstScenario)
+}
+
+func TestStressHandler_RunStressTestDefaultsToHistorical(t *testing.T) {
+	router := setupStressRouter(testOpenPositions())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/risk/stress-test", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp risk.StressTestResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Results, 3)
+	assert.Equal(t, "covid_march_2020", resp.WorstScenario)
+}
+
+func TestStressHandler_RunStressTestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		repo *mockOpenPositionsRepository
+		body string
+		code int
+	}{
+		{"invalid body", testOpenPositions(), `{`, http.StatusBadRequest},
+		{"unknown scenario", testOpenPositions(), `{"scenarios": ["tulip_mania"]}`, http.StatusBadRequest},
+		{"correlation scenario without returns", testOpenPositions(), `{"scenarios": [{"name": "x", "kind": "correlation", "driver_shock": -0.2}]}`, http.StatusBadRequest},
+		{"repository failure", &mockOpenPositionsRepository{err: errors.New("db down")}, `{}`, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupStressRouter(tt.repo)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/risk/stress-test", strings.NewReader(tt.body)))
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+}
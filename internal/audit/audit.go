@@ -32,6 +32,7 @@ const (
 	EventTypeOrderPlaced   EventType = "ORDER_PLACED"
 	EventTypeOrderCanceled EventType = "ORDER_CANCELED"
 	EventTypeOrderFilled   EventType = "ORDER_FILLED"
+	EventTypeOrderRejected EventType = "ORDER_REJECTED"
 
 	// Configuration events
 	EventTypeConfigUpdated EventType = "CONFIG_UPDATED"
@@ -64,6 +65,16 @@ const (
 	EventTypeDecisionSearched        EventType = "DECISION_SEARCHED"
 	EventTypeDecisionStatsAccessed   EventType = "DECISION_STATS_ACCESSED"
 	EventTypeDecisionSimilarAccessed EventType = "DECISION_SIMILAR_ACCESSED"
+
+	// Kill switch events
+	EventTypeKillSwitchArmed     EventType = "KILL_SWITCH_ARMED"
+	EventTypeKillSwitchTriggered EventType = "KILL_SWITCH_TRIGGERED"
+	EventTypeKillSwitchCompleted EventType = "KILL_SWITCH_COMPLETED"
+	EventTypePositionClosed      EventType = "POSITION_CLOSED"
+
+	// De-risking events
+	EventTypeDeRiskExecuted  EventType = "DERISK_EXECUTED"
+	EventTypePositionReduced EventType = "POSITION_REDUCED"
 )
 
 // Severity represents the severity level of an audit event
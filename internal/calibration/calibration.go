@@ -0,0 +1,307 @@
+// Package calibration maps agents' stated confidences to the probabilities
+// their signals turned out to be right, by isotonic regression or Platt
+// scaling, and measures calibration with Brier scores and reliability
+// diagrams
+package calibration
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Method is a calibration mapping
+type Method string
+
+const (
+	MethodIsotonic Method = "isotonic" // Monotone step mapping fitted by pool-adjacent-violators
+	MethodPlatt    Method = "platt"    // Logistic mapping of the confidence's log-odds
+)
+
+// Defaults
+const (
+	DefaultBins = 10
+
+	// confidenceFloor keeps log-odds finite at confidences of 0 and 1
+	confidenceFloor = 1e-4
+	// plattIterations and plattTolerance bound the Newton fit
+	plattIterations = 100
+	plattTolerance  = 1e-10
+)
+
+// ErrNoSamples is returned when there is nothing to fit
+var ErrNoSamples = errors.New("no samples to calibrate")
+
+// ParseMethod parses a calibration method; empty means isotonic
+func ParseMethod(s string) (Method, error) {
+	switch Method(s) {
+	case "", MethodIsotonic:
+		return MethodIsotonic, nil
+	case MethodPlatt:
+		return MethodPlatt, nil
+	default:
+		return "", fmt.Errorf("unknown calibration method %q (want %s or %s)", s, MethodIsotonic, MethodPlatt)
+	}
+}
+
+// Sample is a stated confidence and whether the signal was right
+type Sample struct {
+	Confidence float64
+	Hit        bool
+}
+
+// Point is a knot of an isotonic mapping
+type Point struct {
+	Confidence float64 `json:"confidence"`
+	Calibrated float64 `json:"calibrated"`
+}
+
+// Bin is a bar of a reliability diagram: the signals whose confidence fell in
+// [Lower, Upper), their mean confidence and how often they were right. A
+// calibrated agent's bins lie on the diagonal.
+type Bin struct {
+	Lower          float64 `json:"lower"`
+	Upper          float64 `json:"upper"`
+	Count          int     `json:"count"`
+	MeanConfidence float64 `json:"mean_confidence"`
+	HitRate        float64 `json:"hit_rate"`
+}
+
+// Mapping is a fitted calibration mapping
+type Mapping struct {
+	Method Method  `json:"method"`
+	Points []Point `json:"points,omitempty"` // Isotonic knots, interpolated linearly
+	A      float64 `json:"a,omitempty"`      // Platt slope on the log-odds
+	B      float64 `json:"b,omitempty"`      // Platt intercept
+}
+
+// Apply returns the calibrated probability of a confidence
+func (m *Mapping) Apply(confidence float64) float64 {
+	if m == nil {
+		return confidence
+	}
+	switch m.Method {
+	case MethodPlatt:
+		return sigmoid(m.A*logit(confidence) + m.B)
+	default:
+		return interpolate(m.Points, confidence)
+	}
+}
+
+// Fit fits a mapping of the given method to the samples
+func Fit(method Method, samples []Sample) (*Mapping, error) {
+	if len(samples) == 0 {
+		return nil, ErrNoSamples
+	}
+	switch method {
+	case MethodIsotonic:
+		return &Mapping{Method: MethodIsotonic, Points: isotonic(samples)}, nil
+	case MethodPlatt:
+		a, b := platt(samples)
+		return &Mapping{Method: MethodPlatt, A: a, B: b}, nil
+	default:
+		return nil, fmt.Errorf("unknown calibration method %q", method)
+	}
+}
+
+// Brier returns the mean squared error of the confidences against the hits
+// (0 is perfect; always saying 0.5 scores 0.25)
+func Brier(samples []Sample) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, s := range samples {
+		d := s.Confidence - hit(s)
+		total += d * d
+	}
+	return total / float64(len(samples))
+}
+
+// Reliability bins the samples into equal-width confidence bins. Every bin
+// is listed, empty ones with a zero count.
+func Reliability(samples []Sample, bins int) []Bin {
+	if bins <= 0 {
+		bins = DefaultBins
+	}
+	diagram := make([]Bin, bins)
+	for i := range diagram {
+		diagram[i].Lower = float64(i) / float64(bins)
+		diagram[i].Upper = float64(i+1) / float64(bins)
+	}
+	for _, s := range samples {
+		i := int(s.Confidence * float64(bins))
+		i = max(0, min(bins-1, i))
+		diagram[i].Count++
+		diagram[i].MeanConfidence += s.Confidence
+		diagram[i].HitRate += hit(s)
+	}
+	for i := range diagram {
+		if n := float64(diagram[i].Count); n > 0 {
+			diagram[i].MeanConfidence /= n
+			diagram[i].HitRate /= n
+		}
+	}
+	return diagram
+}
+
+// Calibrated returns the samples with their confidences mapped
+func Calibrated(m *Mapping, samples []Sample) []Sample {
+	calibrated := make([]Sample, len(samples))
+	for i, s := range samples {
+		calibrated[i] = Sample{Confidence: m.Apply(s.Confidence), Hit: s.Hit}
+	}
+	return calibrated
+}
+
+// isotonic fits a non-decreasing step function by pool-adjacent-violators and
+// returns a knot at the mean confidence of each pooled block
+func isotonic(samples []Sample) []Point {
+	sorted := append([]Sample(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Confidence < sorted[j].Confidence })
+
+	type block struct {
+		confidence float64 // Sum of confidences
+		hits       float64
+		count      float64
+	}
+	var blocks []block
+	for _, s := range sorted {
+		b := block{confidence: s.Confidence, hits: hit(s), count: 1}
+		// Tied confidences share one block so the mapping stays a function
+		if n := len(blocks); n > 0 && blocks[n-1].confidence/blocks[n-1].count == s.Confidence {
+			blocks[n-1].confidence += b.confidence
+			blocks[n-1].hits += b.hits
+			blocks[n-1].count++
+		} else {
+			blocks = append(blocks, b)
+		}
+		for n := len(blocks); n > 1 && blocks[n-2].hits/blocks[n-2].count >= blocks[n-1].hits/blocks[n-1].count; n = len(blocks) {
+			blocks[n-2].confidence += blocks[n-1].confidence
+			blocks[n-2].hits += blocks[n-1].hits
+			blocks[n-2].count += blocks[n-1].count
+			blocks = blocks[:n-1]
+		}
+	}
+
+	points := make([]Point, len(blocks))
+	for i, b := range blocks {
+		points[i] = Point{Confidence: b.confidence / b.count, Calibrated: b.hits / b.count}
+	}
+	return points
+}
+
+// interpolate evaluates an isotonic mapping, flat beyond its end knots
+func interpolate(points []Point, confidence float64) float64 {
+	if len(points) == 0 {
+		return confidence
+	}
+	i := sort.Search(len(points), func(i int) bool { return points[i].Confidence >= confidence })
+	switch {
+	case i == 0:
+		return points[0].Calibrated
+	case i == len(points):
+		return points[len(points)-1].Calibrated
+	}
+	lo, hi := points[i-1], points[i]
+	t := (confidence - lo.Confidence) / (hi.Confidence - lo.Confidence)
+	return lo.Calibrated + t*(hi.Calibrated-lo.Calibrated)
+}
+
+// platt fits P(hit) = sigmoid(a·logit(confidence) + b) by Newton's method on
+// the log loss, with Platt's smoothed targets so separable data stays finite.
+// An already calibrated agent fits a = 1, b = 0.
+func platt(samples []Sample) (float64, float64) {
+	positives, negatives := 0.0, 0.0
+	for _, s := range samples {
+		if s.Hit {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+	hiTarget := (positives + 1) / (positives + 2)
+	loTarget := 1 / (negatives + 2)
+
+	x := make([]float64, len(samples))
+	y := make([]float64, len(samples))
+	for i, s := range samples {
+		x[i] = logit(s.Confidence)
+		y[i] = loTarget
+		if s.Hit {
+			y[i] = hiTarget
+		}
+	}
+
+	a, b := 1.0, 0.0
+	current := logLoss(x, y, a, b)
+	for iter := 0; iter < plattIterations; iter++ {
+		var ga, gb, haa, hab, hbb float64
+		for i := range x {
+			p := sigmoid(a*x[i] + b)
+			d := p - y[i]
+			w := p * (1 - p)
+			ga += d * x[i]
+			gb += d
+			haa += w * x[i] * x[i]
+			hab += w * x[i]
+			hbb += w
+		}
+		// A small ridge keeps the step defined when every confidence is equal
+		haa += 1e-9
+		hbb += 1e-9
+		det := haa*hbb - hab*hab
+		if det <= 0 {
+			break
+		}
+		da := (hbb*ga - hab*gb) / det
+		db := (haa*gb - hab*ga) / det
+
+		// Newton overshoots far from the optimum, so halve the step until the
+		// loss falls
+		step := 1.0
+		for ; step > 1e-8; step /= 2 {
+			if loss := logLoss(x, y, a-step*da, b-step*db); loss < current {
+				current = loss
+				break
+			}
+		}
+		if step <= 1e-8 {
+			break
+		}
+		a -= step * da
+		b -= step * db
+		if step*step*(da*da+db*db) < plattTolerance {
+			break
+		}
+	}
+	return a, b
+}
+
+// logLoss is the cross-entropy of sigmoid(a·x + b) against the targets
+func logLoss(x, y []float64, a, b float64) float64 {
+	loss := 0.0
+	for i := range x {
+		z := a*x[i] + b
+		// log(1 + e^z) - y·z, computed without overflow
+		loss += math.Max(z, 0) + math.Log1p(math.Exp(-math.Abs(z))) - y[i]*z
+	}
+	return loss
+}
+
+func hit(s Sample) float64 {
+	if s.Hit {
+		return 1
+	}
+	return 0
+}
+
+func logit(p float64) float64 {
+	p = math.Max(confidenceFloor, math.Min(1-confidenceFloor, p))
+	return math.Log(p / (1 - p))
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
@@ -0,0 +1,119 @@
+package calibration
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// overconfident draws n samples from an agent whose stated confidence c is
+// right with probability 0.5 + (c-0.5)/2
+func overconfident(seed int64, n int) []Sample {
+	rng := rand.New(rand.NewSource(seed))
+	samples := make([]Sample, n)
+	for i := range samples {
+		c := 0.5 + 0.5*rng.Float64()
+		samples[i] = Sample{Confidence: c, Hit: rng.Float64() < 0.5+(c-0.5)/2}
+	}
+	return samples
+}
+
+func TestParseMethod(t *testing.T) {
+	method, err := ParseMethod("")
+	require.NoError(t, err)
+	assert.Equal(t, MethodIsotonic, method)
+	method, err = ParseMethod("platt")
+	require.NoError(t, err)
+	assert.Equal(t, MethodPlatt, method)
+	_, err = ParseMethod("beta")
+	assert.Error(t, err)
+}
+
+func TestBrierAndReliability(t *testing.T) {
+	samples := []Sample{
+		{Confidence: 0.9, Hit: true},
+		{Confidence: 0.9, Hit: false},
+		{Confidence: 0.2, Hit: false},
+		{Confidence: 1.0, Hit: true},
+	}
+	assert.InDelta(t, (0.01+0.81+0.04+0)/4, Brier(samples), 1e-9)
+	assert.Zero(t, Brier(nil))
+
+	diagram := Reliability(samples, 5)
+	require.Len(t, diagram, 5)
+	assert.Equal(t, 1, diagram[1].Count)
+	assert.Zero(t, diagram[1].HitRate)
+	// A confidence of 1 falls in the top bin
+	assert.Equal(t, 3, diagram[4].Count)
+	assert.InDelta(t, 2.0/3, diagram[4].HitRate, 1e-9)
+	assert.InDelta(t, 2.8/3, diagram[4].MeanConfidence, 1e-9)
+	assert.Zero(t, diagram[2].Count)
+	assert.Equal(t, 0.6, diagram[2].Upper)
+}
+
+func TestFit_Isotonic(t *testing.T) {
+	samples := []Sample{
+		{Confidence: 0.1, Hit: false},
+		{Confidence: 0.3, Hit: true},
+		{Confidence: 0.5, Hit: false}, // Violates monotonicity with the one before
+		{Confidence: 0.7, Hit: true},
+		{Confidence: 0.9, Hit: true},
+	}
+	mapping, err := Fit(MethodIsotonic, samples)
+	require.NoError(t, err)
+	assert.Equal(t, []Point{
+		{Confidence: 0.1, Calibrated: 0},
+		{Confidence: 0.4, Calibrated: 0.5},
+		{Confidence: 0.8, Calibrated: 1},
+	}, mapping.Points)
+
+	assert.Equal(t, 0.0, mapping.Apply(0.05), "flat below the first knot")
+	assert.InDelta(t, 0.25, mapping.Apply(0.25), 1e-9)
+	assert.InDelta(t, 0.75, mapping.Apply(0.6), 1e-9)
+	assert.Equal(t, 1.0, mapping.Apply(0.95))
+
+	_, err = Fit(MethodIsotonic, nil)
+	assert.ErrorIs(t, err, ErrNoSamples)
+}
+
+func TestFit_ImprovesOverconfidentAgent(t *testing.T) {
+	train := overconfident(1, 4000)
+	test := overconfident(2, 4000)
+
+	for _, method := range []Method{MethodIsotonic, MethodPlatt} {
+		t.Run(string(method), func(t *testing.T) {
+			mapping, err := Fit(method, train)
+			require.NoError(t, err)
+			assert.Less(t, Brier(Calibrated(mapping, test)), Brier(test))
+
+			// 0.9 is right about 70% of the time
+			assert.InDelta(t, 0.7, mapping.Apply(0.9), 0.06)
+			assert.Less(t, mapping.Apply(0.6), mapping.Apply(0.9))
+		})
+	}
+}
+
+func TestFit_PlattKeepsCalibratedAgent(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	samples := make([]Sample, 20000)
+	for i := range samples {
+		c := 0.05 + 0.9*rng.Float64()
+		samples[i] = Sample{Confidence: c, Hit: rng.Float64() < c}
+	}
+	mapping, err := Fit(MethodPlatt, samples)
+	require.NoError(t, err)
+	assert.InDelta(t, 1, mapping.A, 0.1)
+	assert.InDelta(t, 0, mapping.B, 0.1)
+
+	// A single repeated confidence still fits
+	mapping, err = Fit(MethodPlatt, []Sample{{Confidence: 0.8, Hit: true}, {Confidence: 0.8, Hit: false}})
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, mapping.Apply(0.8), 1e-6)
+}
+
+func TestMapping_NilIsIdentity(t *testing.T) {
+	var mapping *Mapping
+	assert.Equal(t, 0.42, mapping.Apply(0.42))
+}
@@ -139,6 +139,73 @@ type RiskConfig struct {
 	LLMApprovalRequired bool                 `mapstructure:"llm_approval_required"` // true
 	MinConfidence       float64              `mapstructure:"min_confidence"`        // 0.7
 	CircuitBreaker      CircuitBreakerConfig `mapstructure:"circuit_breaker"`       // Circuit breaker thresholds
+	KillSwitch          KillSwitchConfig     `mapstructure:"kill_switch"`           // Global flatten settings
+	PreTrade            PreTradeConfig       `mapstructure:"pre_trade"`             // Checks every order must pass
+	Margin              MarginConfig         `mapstructure:"margin"`                // Liquidation monitoring of futures positions
+}
+
+// PreTradeConfig contains the limits checked inside the order path before an order
+// reaches the exchange. A zero limit disables its check; the daily loss check uses
+// risk.max_daily_loss.
+type PreTradeConfig struct {
+	Enabled             bool     `mapstructure:"enabled"`               // true
+	MaxOrderNotional    float64  `mapstructure:"max_order_notional"`    // Largest single order value in quote currency
+	MaxPositionNotional float64  `mapstructure:"max_position_notional"` // Largest position value per symbol
+	PriceBand           float64  `mapstructure:"price_band"`            // 0.05 (limit price within 5% of the last price)
+	MaxOpenOrders       int      `mapstructure:"max_open_orders"`       // Resting orders per session
+	RestrictedSymbols   []string `mapstructure:"restricted_symbols"`    // Symbols that may not be traded
+}
+
+// MarginConfig contains the thresholds of the futures margin guard. Margin ratio
+// is maintenance margin over margin balance (1 = liquidation); liquidation
+// distance is the adverse price move to liquidation as a fraction of the mark
+// price. Alerts are always raised; actions only run when Enabled is set.
+type MarginConfig struct {
+	Enabled        bool    `mapstructure:"enabled"`         // Take top-up and reduce actions
+	CheckInterval  string  `mapstructure:"check_interval"`  // How often positions are checked (duration string)
+	WarnRatio      float64 `mapstructure:"warn_ratio"`      // 0.5
+	ActionRatio    float64 `mapstructure:"action_ratio"`    // 0.8
+	TargetRatio    float64 `mapstructure:"target_ratio"`    // 0.4 (actions aim for this ratio)
+	WarnDistance   float64 `mapstructure:"warn_distance"`   // 0.10
+	ActionDistance float64 `mapstructure:"action_distance"` // 0.05
+	TopUp          bool    `mapstructure:"top_up"`          // Add margin to isolated positions
+	MaxTopUp       float64 `mapstructure:"max_top_up"`      // Largest single top-up in USDT (0 = available balance)
+	Reduce         bool    `mapstructure:"reduce"`          // Cut cross positions
+	ReduceFraction float64 `mapstructure:"reduce_fraction"` // Smallest share of each cross position cut
+	Cooldown       string  `mapstructure:"cooldown"`        // Wait before acting on a position again (duration string)
+}
+
+// GetCheckInterval returns the CheckInterval as time.Duration, returns zero on parse error
+func (m *MarginConfig) GetCheckInterval() time.Duration {
+	duration, _ := time.ParseDuration(m.CheckInterval)
+	return duration
+}
+
+// GetCooldown returns the Cooldown as time.Duration, returns zero on parse error
+func (m *MarginConfig) GetCooldown() time.Duration {
+	duration, _ := time.ParseDuration(m.Cooldown)
+	return duration
+}
+
+// KillSwitchConfig contains settings for the global kill switch that cancels all orders and flattens all positions
+type KillSwitchConfig struct {
+	CloseMethod    string  `mapstructure:"close_method"`     // "market" or "limit_chase"
+	ChaseAttempts  int     `mapstructure:"chase_attempts"`   // Limit re-prices before falling back to market
+	ChaseInterval  string  `mapstructure:"chase_interval"`   // Wait between limit re-prices (duration string)
+	ChaseOffsetBps float64 `mapstructure:"chase_offset_bps"` // Limit price offset through the reference price
+	TokenTTL       string  `mapstructure:"token_ttl"`        // How long a confirmation token stays valid (duration string)
+}
+
+// GetChaseInterval returns the ChaseInterval as time.Duration, returns zero on parse error
+func (k *KillSwitchConfig) GetChaseInterval() time.Duration {
+	duration, _ := time.ParseDuration(k.ChaseInterval)
+	return duration
+}
+
+// GetTokenTTL returns the TokenTTL as time.Duration, returns zero on parse error
+func (k *KillSwitchConfig) GetTokenTTL() time.Duration {
+	duration, _ := time.ParseDuration(k.TokenTTL)
+	return duration
 }
 
 // CircuitBreakerConfig contains circuit breaker settings for different service types
@@ -164,6 +231,27 @@ type ExchangeConfig struct {
 	Testnet     bool      `mapstructure:"testnet"`
 	RateLimitMS int       `mapstructure:"rate_limit_ms"`
 	Fees        FeeConfig `mapstructure:"fees"`
+
+	// Market selects spot or USD-M futures trading in live mode (default: spot)
+	Market string `mapstructure:"market"`
+
+	// MaxLeverage caps the leverage set on futures symbols (zero allows the exchange maximum)
+	MaxLeverage float64 `mapstructure:"max_leverage"`
+
+	// Instruments overrides trading rules per symbol for paper trading.
+	// Live trading loads them from the exchange.
+	Instruments map[string]InstrumentConfig `mapstructure:"instruments"`
+}
+
+// InstrumentConfig contains the trading rules for a single symbol
+type InstrumentConfig struct {
+	TickSize          float64 `mapstructure:"tick_size"`          // Minimum price increment (e.g., 0.01)
+	StepSize          float64 `mapstructure:"step_size"`          // Minimum quantity increment (e.g., 0.00001)
+	MinQty            float64 `mapstructure:"min_qty"`            // Minimum order quantity
+	MaxQty            float64 `mapstructure:"max_qty"`            // Maximum order quantity (0 = unlimited)
+	MinNotional       float64 `mapstructure:"min_notional"`       // Minimum order value in quote currency (e.g., 5.0)
+	PricePrecision    int     `mapstructure:"price_precision"`    // Decimal places for prices (derived from tick_size if 0)
+	QuantityPrecision int     `mapstructure:"quantity_precision"` // Decimal places for quantities (derived from step_size if 0)
 }
 
 // FeeConfig contains exchange fee structure
@@ -358,6 +446,30 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("risk.circuit_breaker.database.half_open_max_reqs", 5)
 	v.SetDefault("risk.circuit_breaker.database.count_interval", "10s")
 
+	// Kill switch defaults
+	v.SetDefault("risk.kill_switch.close_method", "market")
+	v.SetDefault("risk.kill_switch.chase_attempts", 3)
+	v.SetDefault("risk.kill_switch.chase_interval", "2s")
+	v.SetDefault("risk.kill_switch.chase_offset_bps", 5.0)
+	v.SetDefault("risk.kill_switch.token_ttl", "2m")
+	v.SetDefault("risk.pre_trade.enabled", true)
+	v.SetDefault("risk.pre_trade.max_order_notional", 0.0)
+	v.SetDefault("risk.pre_trade.max_position_notional", 0.0)
+	v.SetDefault("risk.pre_trade.price_band", 0.05)
+	v.SetDefault("risk.pre_trade.max_open_orders", 50)
+	v.SetDefault("risk.margin.enabled", false)
+	v.SetDefault("risk.margin.check_interval", "30s")
+	v.SetDefault("risk.margin.warn_ratio", 0.5)
+	v.SetDefault("risk.margin.action_ratio", 0.8)
+	v.SetDefault("risk.margin.target_ratio", 0.4)
+	v.SetDefault("risk.margin.warn_distance", 0.10)
+	v.SetDefault("risk.margin.action_distance", 0.05)
+	v.SetDefault("risk.margin.top_up", true)
+	v.SetDefault("risk.margin.max_top_up", 0.0)
+	v.SetDefault("risk.margin.reduce", true)
+	v.SetDefault("risk.margin.reduce_fraction", 0.25)
+	v.SetDefault("risk.margin.cooldown", "5m")
+
 	// API defaults
 	v.SetDefault("api.host", "0.0.0.0")
 	v.SetDefault("api.port", 8081)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 )
 
 // ValidationError represents a configuration validation error
@@ -353,6 +354,127 @@ func (c *Config) validateRisk() ValidationErrors {
 		})
 	}
 
+	switch c.Risk.KillSwitch.CloseMethod {
+	case "", "market", "limit_chase":
+	default:
+		errors = append(errors, ValidationError{
+			Field:   "risk.kill_switch.close_method",
+			Message: fmt.Sprintf("Invalid close_method '%s'. Must be 'market' or 'limit_chase'", c.Risk.KillSwitch.CloseMethod),
+		})
+	}
+
+	if c.Risk.KillSwitch.ChaseAttempts < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "risk.kill_switch.chase_attempts",
+			Message: "chase_attempts must be non-negative",
+		})
+	}
+
+	preTrade := c.Risk.PreTrade
+	if preTrade.MaxOrderNotional < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "risk.pre_trade.max_order_notional",
+			Message: "max_order_notional must be non-negative",
+		})
+	}
+
+	if preTrade.MaxPositionNotional < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "risk.pre_trade.max_position_notional",
+			Message: "max_position_notional must be non-negative",
+		})
+	}
+
+	if preTrade.PriceBand < 0 || preTrade.PriceBand >= 1 {
+		errors = append(errors, ValidationError{
+			Field:   "risk.pre_trade.price_band",
+			Message: fmt.Sprintf("Invalid price_band %.2f. Must be between 0-1", preTrade.PriceBand),
+		})
+	}
+
+	if preTrade.MaxOpenOrders < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "risk.pre_trade.max_open_orders",
+			Message: "max_open_orders must be non-negative",
+		})
+	}
+
+	errors = append(errors, c.validateMargin()...)
+
+	return errors
+}
+
+// validateMargin checks the margin guard thresholds
+func (c *Config) validateMargin() ValidationErrors {
+	var errors ValidationErrors
+	margin := c.Risk.Margin
+
+	fractions := []struct {
+		field string
+		value float64
+	}{
+		{"warn_ratio", margin.WarnRatio},
+		{"action_ratio", margin.ActionRatio},
+		{"target_ratio", margin.TargetRatio},
+		{"warn_distance", margin.WarnDistance},
+		{"action_distance", margin.ActionDistance},
+	}
+	for _, f := range fractions {
+		if f.value < 0 || f.value >= 1 {
+			errors = append(errors, ValidationError{
+				Field:   "risk.margin." + f.field,
+				Message: fmt.Sprintf("Invalid %s %.2f. Must be between 0-1", f.field, f.value),
+			})
+		}
+	}
+
+	if margin.WarnRatio > 0 && margin.ActionRatio > 0 && margin.WarnRatio > margin.ActionRatio {
+		errors = append(errors, ValidationError{
+			Field:   "risk.margin.warn_ratio",
+			Message: "warn_ratio must not exceed action_ratio",
+		})
+	}
+
+	if margin.WarnDistance > 0 && margin.ActionDistance > margin.WarnDistance {
+		errors = append(errors, ValidationError{
+			Field:   "risk.margin.action_distance",
+			Message: "action_distance must not exceed warn_distance",
+		})
+	}
+
+	if margin.ReduceFraction < 0 || margin.ReduceFraction > 1 {
+		errors = append(errors, ValidationError{
+			Field:   "risk.margin.reduce_fraction",
+			Message: fmt.Sprintf("Invalid reduce_fraction %.2f. Must be between 0-1", margin.ReduceFraction),
+		})
+	}
+
+	if margin.MaxTopUp < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "risk.margin.max_top_up",
+			Message: "max_top_up must be non-negative",
+		})
+	}
+
+	durations := []struct {
+		field string
+		value string
+	}{
+		{"check_interval", margin.CheckInterval},
+		{"cooldown", margin.Cooldown},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d.value); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "risk.margin." + d.field,
+				Message: fmt.Sprintf("Invalid duration '%s': %v", d.value, err),
+			})
+		}
+	}
+
 	return errors
 }
 
@@ -390,6 +512,22 @@ func (c *Config) validateExchanges() ValidationErrors {
 			})
 		}
 
+		// Validate market and leverage
+		switch strings.ToLower(exchangeConfig.Market) {
+		case "", "spot", "futures":
+		default:
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("exchanges.%s.market", exchangeName),
+				Message: "Market must be spot or futures",
+			})
+		}
+		if exchangeConfig.MaxLeverage < 0 || exchangeConfig.MaxLeverage > 125 {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("exchanges.%s.max_leverage", exchangeName),
+				Message: "Max leverage must be between 0 and 125",
+			})
+		}
+
 		// Validate fee configuration
 		errors = append(errors, c.validateFees(exchangeName, exchangeConfig.Fees)...)
 	}
@@ -499,6 +499,28 @@ func TestValidateRisk(t *testing.T) {
 			},
 			expectError: "Invalid min_confidence",
 		},
+		{
+			name: "invalid margin action_ratio",
+			modify: func(c *Config) {
+				c.Risk.Margin.ActionRatio = 1
+			},
+			expectError: "Invalid action_ratio",
+		},
+		{
+			name: "margin warn_ratio above action_ratio",
+			modify: func(c *Config) {
+				c.Risk.Margin.WarnRatio = 0.9
+				c.Risk.Margin.ActionRatio = 0.8
+			},
+			expectError: "warn_ratio must not exceed action_ratio",
+		},
+		{
+			name: "invalid margin cooldown",
+			modify: func(c *Config) {
+				c.Risk.Margin.Cooldown = "soon"
+			},
+			expectError: "risk.margin.cooldown",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AgentSignal is a signal an agent sent to the orchestrator
+type AgentSignal struct {
+	ID         uuid.UUID  `db:"id"`
+	SessionID  *uuid.UUID `db:"session_id"`
+	AgentName  string     `db:"agent_name"`
+	AgentType  string     `db:"agent_type"`
+	Symbol     string     `db:"symbol"`
+	Signal     string     `db:"signal"` // BUY, SELL, HOLD
+	Confidence float64    `db:"confidence"`
+	Reasoning  string     `db:"reasoning"`
+	CreatedAt  time.Time  `db:"created_at"`
+}
+
+// SignalOutcome is a signal with the symbol's close before it and at the end
+// of its return horizon
+type SignalOutcome struct {
+	AgentName  string
+	AgentType  string
+	Symbol     string
+	Signal     string
+	Confidence float64
+	CreatedAt  time.Time
+	EntryPrice float64
+	ExitPrice  float64
+}
+
+// InsertAgentSignal records a signal
+func (db *DB) InsertAgentSignal(ctx context.Context, signal *AgentSignal) error {
+	query := `
+		INSERT INTO agent_signals (id, session_id, agent_name, agent_type, symbol, signal, confidence, reasoning, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	if signal.ID == uuid.Nil {
+		signal.ID = uuid.New()
+	}
+	if signal.CreatedAt.IsZero() {
+		signal.CreatedAt = time.Now()
+	}
+
+	_, err := db.pool.Exec(ctx, query,
+		signal.ID,
+		signal.SessionID,
+		signal.AgentName,
+		signal.AgentType,
+		signal.Symbol,
+		signal.Signal,
+		signal.Confidence,
+		signal.Reasoning,
+		signal.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert agent signal: %w", err)
+	}
+	return nil
+}
+
+// ListSignalOutcomes returns the signals created in (from, to], oldest first,
+// priced on candles of the given interval: the close of the last candle that
+// closed before the signal and of the last that closed within horizon after
+// it. Candles match the signal's symbol with or without its slash. Signals
+// without candles on both sides are left out.
+func (db *DB) ListSignalOutcomes(ctx context.Context, from, to time.Time, interval string, horizon time.Duration) ([]*SignalOutcome, error) {
+	query := `
+		SELECT
+			s.agent_name, s.agent_type, s.symbol, s.signal::TEXT, s.confidence::DOUBLE PRECISION, s.created_at,
+			entry.close::DOUBLE PRECISION, exit.close::DOUBLE PRECISION
+		FROM agent_signals s
+		JOIN LATERAL (
+			SELECT c.close
+			FROM candlesticks c
+			WHERE c.symbol IN (s.symbol, REPLACE(s.symbol, '/', ''))
+				AND c.interval = $3
+				AND c.close_time <= s.created_at
+			ORDER BY c.close_time DESC
+			LIMIT 1
+		) entry ON TRUE
+		JOIN LATERAL (
+			SELECT c.close
+			FROM candlesticks c
+			WHERE c.symbol IN (s.symbol, REPLACE(s.symbol, '/', ''))
+				AND c.interval = $3
+				AND c.close_time > s.created_at
+				AND c.close_time <= s.created_at + make_interval(secs => $4)
+			ORDER BY c.close_time DESC
+			LIMIT 1
+		) exit ON TRUE
+		WHERE s.created_at > $1 AND s.created_at <= $2
+		ORDER BY s.created_at ASC
+	`
+
+	rows, err := db.pool.Query(ctx, query, from, to, interval, horizon.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signal outcomes: %w", err)
+	}
+	defer rows.Close()
+
+	var outcomes []*SignalOutcome
+	for rows.Next() {
+		var o SignalOutcome
+		if err := rows.Scan(
+			&o.AgentName,
+			&o.AgentType,
+			&o.Symbol,
+			&o.Signal,
+			&o.Confidence,
+			&o.CreatedAt,
+			&o.EntryPrice,
+			&o.ExitPrice,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan signal outcome: %w", err)
+		}
+		outcomes = append(outcomes, &o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating signal outcomes: %w", err)
+	}
+	return outcomes, nil
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AgentWeight is an agent's voting weight and the outcome statistics it was
+// learned from
+type AgentWeight struct {
+	AgentName      string     `db:"agent_name"`
+	AgentType      string     `db:"agent_type"`
+	Weight         float64    `db:"weight"`
+	LearnedWeight  float64    `db:"learned_weight"`
+	BaseWeight     float64    `db:"base_weight"`
+	HitRate        float64    `db:"hit_rate"`
+	BrierScore     float64    `db:"brier_score"`
+	Samples        int        `db:"samples"`
+	Frozen         bool       `db:"frozen"`
+	OverrideWeight *float64   `db:"override_weight"`
+	ScoredThrough  *time.Time `db:"scored_through"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+}
+
+// AgentWeightEvent is an entry in the audit trail of agent weight changes
+type AgentWeightEvent struct {
+	ID             uuid.UUID `db:"id"`
+	AgentName      string    `db:"agent_name"`
+	Reason         string    `db:"reason"` // learned, freeze, unfreeze, override, clear_override
+	PreviousWeight float64   `db:"previous_weight"`
+	Weight         float64   `db:"weight"`
+	HitRate        float64   `db:"hit_rate"`
+	BrierScore     float64   `db:"brier_score"`
+	Samples        int       `db:"samples"`
+	Actor          string    `db:"actor"`
+	Note           string    `db:"note"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+// ListAgentWeights returns the weight of every agent, by name
+func (db *DB) ListAgentWeights(ctx context.Context) ([]*AgentWeight, error) {
+	query := `
+		SELECT agent_name, agent_type, weight, learned_weight, base_weight, hit_rate, brier_score,
+			samples, frozen, override_weight, scored_through, updated_at
+		FROM agent_weights
+		ORDER BY agent_name ASC
+	`
+
+	rows, err := db.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agent weights: %w", err)
+	}
+	defer rows.Close()
+
+	var weights []*AgentWeight
+	for rows.Next() {
+		var w AgentWeight
+		if err := rows.Scan(
+			&w.AgentName,
+			&w.AgentType,
+			&w.Weight,
+			&w.LearnedWeight,
+			&w.BaseWeight,
+			&w.HitRate,
+			&w.BrierScore,
+			&w.Samples,
+			&w.Frozen,
+			&w.OverrideWeight,
+			&w.ScoredThrough,
+			&w.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan agent weight: %w", err)
+		}
+		weights = append(weights, &w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating agent weights: %w", err)
+	}
+	return weights, nil
+}
+
+// SaveAgentWeights upserts agent weights and appends their change events in
+// one transaction
+func (db *DB) SaveAgentWeights(ctx context.Context, weights []*AgentWeight, events []*AgentWeightEvent) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // Rollback if commit not called (error ignored as commit may have succeeded)
+
+	for _, w := range weights {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO agent_weights (
+				agent_name, agent_type, weight, learned_weight, base_weight, hit_rate, brier_score,
+				samples, frozen, override_weight, scored_through, updated_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
+			ON CONFLICT (agent_name) DO UPDATE SET
+				agent_type = EXCLUDED.agent_type,
+				weight = EXCLUDED.weight,
+				learned_weight = EXCLUDED.learned_weight,
+				base_weight = EXCLUDED.base_weight,
+				hit_rate = EXCLUDED.hit_rate,
+				brier_score = EXCLUDED.brier_score,
+				samples = EXCLUDED.samples,
+				frozen = EXCLUDED.frozen,
+				override_weight = EXCLUDED.override_weight,
+				scored_through = EXCLUDED.scored_through,
+				updated_at = NOW()
+		`, w.AgentName, w.AgentType, w.Weight, w.LearnedWeight, w.BaseWeight, w.HitRate, w.BrierScore,
+			w.Samples, w.Frozen, w.OverrideWeight, w.ScoredThrough)
+		if err != nil {
+			return fmt.Errorf("failed to save agent weight: %w", err)
+		}
+	}
+
+	for _, e := range events {
+		if e.ID == uuid.Nil {
+			e.ID = uuid.New()
+		}
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = time.Now()
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO agent_weight_events (
+				id, agent_name, reason, previous_weight, weight, hit_rate, brier_score, samples, actor, note, created_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11)
+		`, e.ID, e.AgentName, e.Reason, e.PreviousWeight, e.Weight, e.HitRate, e.BrierScore,
+			e.Samples, e.Actor, e.Note, e.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert agent weight event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit agent weights: %w", err)
+	}
+	return nil
+}
+
+// ListAgentWeightEvents returns an agent's latest weight changes, newest first
+func (db *DB) ListAgentWeightEvents(ctx context.Context, agentName string, limit int) ([]*AgentWeightEvent, error) {
+	query := `
+		SELECT id, agent_name, reason, previous_weight, weight, hit_rate, brier_score, samples,
+			COALESCE(actor, ''), COALESCE(note, ''), created_at
+		FROM agent_weight_events
+		WHERE agent_name = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := db.pool.Query(ctx, query, agentName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query agent weight events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*AgentWeightEvent
+	for rows.Next() {
+		var e AgentWeightEvent
+		if err := rows.Scan(
+			&e.ID,
+			&e.AgentName,
+			&e.Reason,
+			&e.PreviousWeight,
+			&e.Weight,
+			&e.HitRate,
+			&e.BrierScore,
+			&e.Samples,
+			&e.Actor,
+			&e.Note,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan agent weight event: %w", err)
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating agent weight events: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AccountBalance represents a persisted asset balance for an exchange account
+type AccountBalance struct {
+	Exchange  string    `db:"exchange" json:"exchange"`
+	Asset     string    `db:"asset" json:"asset"`
+	Free      float64   `db:"free" json:"free"`
+	Locked    float64   `db:"locked" json:"locked"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// UpsertAccountBalances inserts or updates asset balances in a single batch
+func (db *DB) UpsertAccountBalances(ctx context.Context, balances []*AccountBalance) error {
+	if len(balances) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO account_balances (exchange, asset, free, locked, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (exchange, asset) DO UPDATE SET
+			free = EXCLUDED.free,
+			locked = EXCLUDED.locked,
+			updated_at = NOW()
+	`
+
+	batch := &pgx.Batch{}
+	for _, b := range balances {
+		batch.Queue(query, b.Exchange, b.Asset, b.Free, b.Locked)
+	}
+
+	results := db.pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	for range balances {
+		if _, err := results.Exec(); err != nil {
+			return fmt.Errorf("failed to upsert account balance: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceAccountBalances atomically replaces the full balance snapshot for an exchange,
+// removing assets that are no longer held
+func (db *DB) ReplaceAccountBalances(ctx context.Context, exchange string, balances []*AccountBalance) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // Rollback if commit not called (error ignored as commit may have succeeded)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM account_balances WHERE exchange = $1`, exchange); err != nil {
+		return fmt.Errorf("failed to clear account balances: %w", err)
+	}
+
+	for _, b := range balances {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO account_balances (exchange, asset, free, locked, updated_at)
+			VALUES ($1, $2, $3, $4, NOW())
+		`, exchange, b.Asset, b.Free, b.Locked)
+		if err != nil {
+			return fmt.Errorf("failed to insert account balance: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit account balances: %w", err)
+	}
+
+	return nil
+}
+
+// GetAccountBalances retrieves balances for an exchange (all exchanges if empty)
+func (db *DB) GetAccountBalances(ctx context.Context, exchange string) ([]*AccountBalance, error) {
+	query := `
+		SELECT exchange, asset, free, locked, updated_at
+		FROM account_balances
+		WHERE ($1 = '' OR exchange = $1)
+		ORDER BY exchange ASC, asset ASC
+	`
+
+	rows, err := db.pool.Query(ctx, query, exchange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query account balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := make([]*AccountBalance, 0)
+	for rows.Next() {
+		var b AccountBalance
+		if err := rows.Scan(&b.Exchange, &b.Asset, &b.Free, &b.Locked, &b.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan account balance: %w", err)
+		}
+		balances = append(balances, &b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating account balances: %w", err)
+	}
+
+	return balances, nil
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetHourlyVolumeProfile returns the average traded volume for each UTC hour of day
+// (24 buckets) over the last N days, used to shape VWAP execution schedules
+func (db *DB) GetHourlyVolumeProfile(ctx context.Context, symbol string, days int) ([]float64, error) {
+	query := `
+		SELECT EXTRACT(HOUR FROM open_time AT TIME ZONE 'UTC')::INT AS hour,
+		       AVG(volume)::DOUBLE PRECISION AS avg_volume
+		FROM candlesticks
+		WHERE symbol = $1
+			AND open_time >= NOW() - INTERVAL '1 day' * $2
+		GROUP BY hour
+		ORDER BY hour ASC
+	`
+
+	rows, err := db.pool.Query(ctx, query, symbol, days)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query volume profile: %w", err)
+	}
+	defer rows.Close()
+
+	profile := make([]float64, 24)
+	for rows.Next() {
+		var hour int
+		var avgVolume float64
+		if err := rows.Scan(&hour, &avgVolume); err != nil {
+			return nil, fmt.Errorf("failed to scan volume profile row: %w", err)
+		}
+		if hour >= 0 && hour < 24 {
+			profile[hour] = avgVolume
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating volume profile rows: %w", err)
+	}
+
+	return profile, nil
+}
+
+// GetVolumeSince returns the total traded volume for a symbol since the given time
+func (db *DB) GetVolumeSince(ctx context.Context, symbol string, interval string, since time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(volume), 0)::DOUBLE PRECISION
+		FROM candlesticks
+		WHERE symbol = $1
+			AND interval = $2
+			AND open_time >= $3
+	`
+
+	var volume float64
+	if err := db.pool.QueryRow(ctx, query, symbol, interval, since).Scan(&volume); err != nil {
+		return 0, fmt.Errorf("failed to query volume: %w", err)
+	}
+
+	return volume, nil
+}
+
+// GetLatestClosePrice returns the close of the most recent candlestick for a symbol
+func (db *DB) GetLatestClosePrice(ctx context.Context, symbol string) (float64, error) {
+	query := `
+		SELECT close::DOUBLE PRECISION
+		FROM candlesticks
+		WHERE symbol = $1
+		ORDER BY open_time DESC
+		LIMIT 1
+	`
+
+	var price float64
+	if err := db.pool.QueryRow(ctx, query, symbol).Scan(&price); err != nil {
+		return 0, fmt.Errorf("failed to query latest close price: %w", err)
+	}
+
+	return price, nil
+}
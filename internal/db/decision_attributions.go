@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DecisionAttribution records the agents and strategies behind an orchestrator
+// decision, as shares of the winning weighted vote
+type DecisionAttribution struct {
+	ID         uuid.UUID          `db:"id"`
+	Symbol     string             `db:"symbol"`
+	Action     OrderSide          `db:"action"`
+	Agents     map[string]float64 `db:"agents"`
+	Strategies map[string]float64 `db:"strategies"`
+	Confidence float64            `db:"confidence"`
+	DecidedAt  time.Time          `db:"decided_at"`
+}
+
+// AttributedPosition is a position together with the attribution of the
+// latest decision on its symbol and side made before it was opened
+type AttributedPosition struct {
+	Symbol        string
+	Side          PositionSide
+	Quantity      float64
+	EntryPrice    float64
+	StopLoss      *float64
+	RealizedPnL   float64
+	UnrealizedPnL float64
+	Open          bool
+	Agents        map[string]float64
+	Strategies    map[string]float64
+}
+
+// InsertDecisionAttribution records the attribution of a decision
+func (db *DB) InsertDecisionAttribution(ctx context.Context, attribution *DecisionAttribution) error {
+	query := `
+		INSERT INTO decision_attributions (id, symbol, action, agents, strategies, confidence, decided_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	if attribution.ID == uuid.Nil {
+		attribution.ID = uuid.New()
+	}
+	if attribution.DecidedAt.IsZero() {
+		attribution.DecidedAt = time.Now()
+	}
+
+	_, err := db.pool.Exec(ctx, query,
+		attribution.ID,
+		attribution.Symbol,
+		attribution.Action,
+		attribution.Agents,
+		attribution.Strategies,
+		attribution.Confidence,
+		attribution.DecidedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert decision attribution: %w", err)
+	}
+	return nil
+}
+
+// ListAttributedPositions returns the open positions and the positions closed
+// since the given time that can be traced to a decision, across all sessions
+func (db *DB) ListAttributedPositions(ctx context.Context, since time.Time) ([]*AttributedPosition, error) {
+	query := `
+		SELECT
+			p.symbol, p.side, p.quantity, p.entry_price, p.stop_loss,
+			COALESCE(p.realized_pnl, 0), COALESCE(p.unrealized_pnl, 0),
+			p.exit_time IS NULL, a.agents, a.strategies
+		FROM positions p
+		JOIN LATERAL (
+			SELECT d.agents, d.strategies
+			FROM decision_attributions d
+			WHERE d.symbol = p.symbol
+				AND d.action = CASE WHEN p.side = 'SHORT' THEN 'SELL'::order_side ELSE 'BUY'::order_side END
+				AND d.decided_at <= p.entry_time
+			ORDER BY d.decided_at DESC
+			LIMIT 1
+		) a ON TRUE
+		WHERE p.exit_time IS NULL OR p.exit_time >= $1
+	`
+
+	rows, err := db.pool.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attributed positions: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []*AttributedPosition
+	for rows.Next() {
+		var position AttributedPosition
+		if err := rows.Scan(
+			&position.Symbol,
+			&position.Side,
+			&position.Quantity,
+			&position.EntryPrice,
+			&position.StopLoss,
+			&position.RealizedPnL,
+			&position.UnrealizedPnL,
+			&position.Open,
+			&position.Agents,
+			&position.Strategies,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan attributed position: %w", err)
+		}
+		positions = append(positions, &position)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attributed positions: %w", err)
+	}
+	return positions, nil
+}
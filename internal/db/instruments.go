@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Instrument represents the persisted trading rules for a symbol on an exchange
+type Instrument struct {
+	Exchange          string    `db:"exchange" json:"exchange"`
+	Symbol            string    `db:"symbol" json:"symbol"`
+	BaseAsset         string    `db:"base_asset" json:"base_asset,omitempty"`
+	QuoteAsset        string    `db:"quote_asset" json:"quote_asset,omitempty"`
+	TickSize          float64   `db:"tick_size" json:"tick_size"`
+	StepSize          float64   `db:"step_size" json:"step_size"`
+	MinQty            float64   `db:"min_qty" json:"min_qty"`
+	MaxQty            float64   `db:"max_qty" json:"max_qty"`
+	MinPrice          float64   `db:"min_price" json:"min_price"`
+	MaxPrice          float64   `db:"max_price" json:"max_price"`
+	MinNotional       float64   `db:"min_notional" json:"min_notional"`
+	PricePrecision    int       `db:"price_precision" json:"price_precision"`
+	QuantityPrecision int       `db:"quantity_precision" json:"quantity_precision"`
+	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// UpsertInstruments inserts or updates instrument rules for an exchange in a single batch
+func (db *DB) UpsertInstruments(ctx context.Context, instruments []*Instrument) error {
+	if len(instruments) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO instruments (
+			exchange, symbol, base_asset, quote_asset, tick_size, step_size,
+			min_qty, max_qty, min_price, max_price, min_notional,
+			price_precision, quantity_precision, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW())
+		ON CONFLICT (exchange, symbol) DO UPDATE SET
+			base_asset = EXCLUDED.base_asset,
+			quote_asset = EXCLUDED.quote_asset,
+			tick_size = EXCLUDED.tick_size,
+			step_size = EXCLUDED.step_size,
+			min_qty = EXCLUDED.min_qty,
+			max_qty = EXCLUDED.max_qty,
+			min_price = EXCLUDED.min_price,
+			max_price = EXCLUDED.max_price,
+			min_notional = EXCLUDED.min_notional,
+			price_precision = EXCLUDED.price_precision,
+			quantity_precision = EXCLUDED.quantity_precision,
+			updated_at = NOW()
+	`
+
+	batch := &pgx.Batch{}
+	for _, inst := range instruments {
+		batch.Queue(query,
+			inst.Exchange, inst.Symbol, inst.BaseAsset, inst.QuoteAsset,
+			inst.TickSize, inst.StepSize, inst.MinQty, inst.MaxQty,
+			inst.MinPrice, inst.MaxPrice, inst.MinNotional,
+			inst.PricePrecision, inst.QuantityPrecision,
+		)
+	}
+
+	results := db.pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	for range instruments {
+		if _, err := results.Exec(); err != nil {
+			return fmt.Errorf("failed to upsert instrument: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetInstruments retrieves all instrument rules for an exchange
+func (db *DB) GetInstruments(ctx context.Context, exchange string) ([]*Instrument, error) {
+	query := `
+		SELECT exchange, symbol, COALESCE(base_asset, ''), COALESCE(quote_asset, ''),
+		       tick_size, step_size, min_qty, max_qty, min_price, max_price, min_notional,
+		       price_precision, quantity_precision, updated_at
+		FROM instruments
+		WHERE exchange = $1
+		ORDER BY symbol ASC
+	`
+
+	rows, err := db.pool.Query(ctx, query, exchange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query instruments: %w", err)
+	}
+	defer rows.Close()
+
+	instruments := make([]*Instrument, 0)
+	for rows.Next() {
+		var inst Instrument
+		if err := rows.Scan(
+			&inst.Exchange,
+			&inst.Symbol,
+			&inst.BaseAsset,
+			&inst.QuoteAsset,
+			&inst.TickSize,
+			&inst.StepSize,
+			&inst.MinQty,
+			&inst.MaxQty,
+			&inst.MinPrice,
+			&inst.MaxPrice,
+			&inst.MinNotional,
+			&inst.PricePrecision,
+			&inst.QuantityPrecision,
+			&inst.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan instrument: %w", err)
+		}
+		instruments = append(instruments, &inst)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating instruments: %w", err)
+	}
+
+	return instruments, nil
+}
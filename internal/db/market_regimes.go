@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// MarketRegime is a regime estimate for a symbol from the regime service
+type MarketRegime struct {
+	ID            uuid.UUID          `db:"id"`
+	Symbol        string             `db:"symbol"`
+	Interval      string             `db:"interval"`
+	Regime        string             `db:"regime"`
+	Confidence    float64            `db:"confidence"`
+	Probabilities map[string]float64 `db:"probabilities"`
+	States        []byte             `db:"states"` // JSONB - fitted hidden states
+	LogLikelihood float64            `db:"log_likelihood"`
+	Observations  int                `db:"observations"`
+	DetectedAt    time.Time          `db:"detected_at"`
+}
+
+const marketRegimeColumns = `id, symbol, interval, regime, confidence, probabilities, states, log_likelihood, observations, detected_at`
+
+// InsertMarketRegime records a regime estimate
+func (db *DB) InsertMarketRegime(ctx context.Context, regime *MarketRegime) error {
+	query := `
+		INSERT INTO market_regimes (` + marketRegimeColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	if regime.ID == uuid.Nil {
+		regime.ID = uuid.New()
+	}
+	if regime.DetectedAt.IsZero() {
+		regime.DetectedAt = time.Now()
+	}
+	states := regime.States
+	if states == nil {
+		states = []byte("[]")
+	}
+
+	_, err := db.pool.Exec(ctx, query,
+		regime.ID,
+		regime.Symbol,
+		regime.Interval,
+		regime.Regime,
+		regime.Confidence,
+		regime.Probabilities,
+		states,
+		regime.LogLikelihood,
+		regime.Observations,
+		regime.DetectedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert market regime: %w", err)
+	}
+	return nil
+}
+
+// GetLatestMarketRegime returns the latest regime estimate for a symbol, or
+// nil when there is none
+func (db *DB) GetLatestMarketRegime(ctx context.Context, symbol string) (*MarketRegime, error) {
+	query := `
+		SELECT ` + marketRegimeColumns + `
+		FROM market_regimes
+		WHERE symbol = $1
+		ORDER BY detected_at DESC
+		LIMIT 1
+	`
+
+	regime, err := scanMarketRegime(db.pool.QueryRow(ctx, query, symbol))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest market regime: %w", err)
+	}
+	return regime, nil
+}
+
+// ListMarketRegimes returns a symbol's regime estimates between two times,
+// oldest first
+func (db *DB) ListMarketRegimes(ctx context.Context, symbol string, from, to time.Time) ([]*MarketRegime, error) {
+	query := `
+		SELECT ` + marketRegimeColumns + `
+		FROM market_regimes
+		WHERE symbol = $1 AND detected_at >= $2 AND detected_at <= $3
+		ORDER BY detected_at ASC
+	`
+
+	rows, err := db.pool.Query(ctx, query, symbol, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query market regimes: %w", err)
+	}
+	defer rows.Close()
+
+	var regimes []*MarketRegime
+	for rows.Next() {
+		regime, err := scanMarketRegime(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan market regime: %w", err)
+		}
+		regimes = append(regimes, regime)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating market regimes: %w", err)
+	}
+	return regimes, nil
+}
+
+func scanMarketRegime(row pgx.Row) (*MarketRegime, error) {
+	var regime MarketRegime
+	if err := row.Scan(
+		&regime.ID,
+		&regime.Symbol,
+		&regime.Interval,
+		&regime.Regime,
+		&regime.Confidence,
+		&regime.Probabilities,
+		&regime.States,
+		&regime.LogLikelihood,
+		&regime.Observations,
+		&regime.DetectedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &regime, nil
+}
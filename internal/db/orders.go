@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -35,6 +36,7 @@ const (
 	OrderStatusFilled          OrderStatus = "FILLED"
 	OrderStatusCanceled        OrderStatus = "CANCELED"
 	OrderStatusRejected        OrderStatus = "REJECTED"
+	OrderStatusExpired         OrderStatus = "EXPIRED"
 )
 
 // Order represents a database order record
@@ -59,10 +61,25 @@ type Order struct {
 	CanceledAt            *time.Time
 	ErrorMessage          *string
 	Metadata              map[string]interface{}
+	ParentOrderID         *uuid.UUID // Parent algo order for child slices
+	AlgoType              *string    // Execution algorithm for parent orders (twap, vwap, pov, iceberg)
+	Amendments            []OrderAmendment
 	CreatedAt             time.Time
 	UpdatedAt             time.Time
 }
 
+// OrderAmendment is one price/quantity change to a resting order, kept in
+// orders.amendments. Cancel-replace amendments change the exchange order ID.
+type OrderAmendment struct {
+	PreviousPrice           float64   `json:"previous_price"`
+	PreviousQuantity        float64   `json:"previous_quantity"`
+	Price                   float64   `json:"price"`
+	Quantity                float64   `json:"quantity"`
+	PreviousExchangeOrderID string    `json:"previous_exchange_order_id,omitempty"`
+	ExchangeOrderID         string    `json:"exchange_order_id,omitempty"`
+	AmendedAt               time.Time `json:"amended_at"`
+}
+
 // Trade represents a database trade record (fill)
 type Trade struct {
 	ID              uuid.UUID
@@ -89,10 +106,11 @@ func (db *DB) InsertOrder(ctx context.Context, order *Order) error {
 			id, session_id, position_id, exchange_order_id, symbol, exchange,
 			side, type, status, price, stop_price, quantity, executed_quantity,
 			executed_quote_quantity, time_in_force, placed_at, filled_at,
-			canceled_at, error_message, metadata, created_at, updated_at
+			canceled_at, error_message, metadata, parent_order_id, algo_type,
+			created_at, updated_at
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15,
-			$16, $17, $18, $19, $20, $21, $22
+			$16, $17, $18, $19, $20, $21, $22, $23, $24
 		)
 	`
 
@@ -117,6 +135,8 @@ func (db *DB) InsertOrder(ctx context.Context, order *Order) error {
 		order.CanceledAt,
 		order.ErrorMessage,
 		order.Metadata,
+		order.ParentOrderID,
+		order.AlgoType,
 		order.CreatedAt,
 		order.UpdatedAt,
 	)
@@ -183,6 +203,57 @@ func (db *DB) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status O
 	return nil
 }
 
+// AmendOrder applies an amendment's price, quantity and exchange order ID to an
+// order and appends it to the order's amendment history
+func (db *DB) AmendOrder(ctx context.Context, orderID uuid.UUID, amendment OrderAmendment) error {
+	history, err := json.Marshal([]OrderAmendment{amendment})
+	if err != nil {
+		return fmt.Errorf("failed to encode order amendment: %w", err)
+	}
+
+	var exchangeOrderID *string
+	if amendment.ExchangeOrderID != "" {
+		exchangeOrderID = &amendment.ExchangeOrderID
+	}
+
+	query := `
+		UPDATE orders
+		SET price = $1,
+		    quantity = $2,
+		    exchange_order_id = COALESCE($3, exchange_order_id),
+		    amendments = amendments || $4::jsonb,
+		    updated_at = NOW()
+		WHERE id = $5
+	`
+
+	result, err := db.pool.Exec(ctx, query,
+		amendment.Price,
+		amendment.Quantity,
+		exchangeOrderID,
+		string(history),
+		orderID,
+	)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("order_id", orderID.String()).
+			Msg("Failed to amend order")
+		return fmt.Errorf("failed to amend order: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("order not found: %s", orderID.String())
+	}
+
+	log.Debug().
+		Str("order_id", orderID.String()).
+		Float64("price", amendment.Price).
+		Float64("quantity", amendment.Quantity).
+		Msg("Order amended")
+
+	return nil
+}
+
 // InsertTrade inserts a new trade (fill) into the database
 func (db *DB) InsertTrade(ctx context.Context, trade *Trade) error {
 	query := `
@@ -238,7 +309,8 @@ func (db *DB) GetOrder(ctx context.Context, orderID uuid.UUID) (*Order, error) {
 		SELECT id, session_id, position_id, exchange_order_id, symbol, exchange,
 		       side, type, status, price, stop_price, quantity, executed_quantity,
 		       executed_quote_quantity, time_in_force, placed_at, filled_at,
-		       canceled_at, error_message, metadata, created_at, updated_at
+		       canceled_at, error_message, metadata, parent_order_id, algo_type,
+		       amendments, created_at, updated_at
 		FROM orders
 		WHERE id = $1
 	`
@@ -265,6 +337,9 @@ func (db *DB) GetOrder(ctx context.Context, orderID uuid.UUID) (*Order, error) {
 		&order.CanceledAt,
 		&order.ErrorMessage,
 		&order.Metadata,
+		&order.ParentOrderID,
+		&order.AlgoType,
+		&order.Amendments,
 		&order.CreatedAt,
 		&order.UpdatedAt,
 	)
@@ -326,6 +401,22 @@ func (db *DB) GetTradesByOrderID(ctx context.Context, orderID uuid.UUID) ([]*Tra
 	return trades, nil
 }
 
+// CountTradesSince counts the orders with fills executed since the given time,
+// across all sessions. Partial fills of one order count once.
+func (db *DB) CountTradesSince(ctx context.Context, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT COALESCE(order_id, id))
+		FROM trades
+		WHERE executed_at >= $1
+	`
+
+	var count int
+	if err := db.pool.QueryRow(ctx, query, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count trades since %s: %w", since.Format(time.RFC3339), err)
+	}
+	return count, nil
+}
+
 // ConvertOrderSide converts application order side to database enum
 func ConvertOrderSide(side string) OrderSide {
 	switch strings.ToUpper(side) {
@@ -363,6 +454,8 @@ func ConvertOrderStatus(status string) OrderStatus {
 		return OrderStatusCanceled
 	case "REJECTED":
 		return OrderStatusRejected
+	case "EXPIRED":
+		return OrderStatusExpired
 	default:
 		return OrderStatusNew // Default to new if unknown
 	}
@@ -379,7 +472,8 @@ func (db *DB) GetOrdersBySession(ctx context.Context, sessionID uuid.UUID) ([]*O
 		SELECT id, session_id, position_id, exchange_order_id, symbol, exchange,
 		       side, type, status, price, stop_price, quantity, executed_quantity,
 		       executed_quote_quantity, time_in_force, placed_at, filled_at,
-		       canceled_at, error_message, metadata, created_at, updated_at
+		       canceled_at, error_message, metadata, parent_order_id, algo_type,
+		       amendments, created_at, updated_at
 		FROM orders
 		WHERE session_id = $1
 		ORDER BY created_at DESC
@@ -400,7 +494,8 @@ func (db *DB) GetOrdersBySymbol(ctx context.Context, symbol string) ([]*Order, e
 		SELECT id, session_id, position_id, exchange_order_id, symbol, exchange,
 		       side, type, status, price, stop_price, quantity, executed_quantity,
 		       executed_quote_quantity, time_in_force, placed_at, filled_at,
-		       canceled_at, error_message, metadata, created_at, updated_at
+		       canceled_at, error_message, metadata, parent_order_id, algo_type,
+		       amendments, created_at, updated_at
 		FROM orders
 		WHERE symbol = $1
 		ORDER BY created_at DESC
@@ -421,7 +516,8 @@ func (db *DB) GetOrdersByStatus(ctx context.Context, status OrderStatus) ([]*Ord
 		SELECT id, session_id, position_id, exchange_order_id, symbol, exchange,
 		       side, type, status, price, stop_price, quantity, executed_quantity,
 		       executed_quote_quantity, time_in_force, placed_at, filled_at,
-		       canceled_at, error_message, metadata, created_at, updated_at
+		       canceled_at, error_message, metadata, parent_order_id, algo_type,
+		       amendments, created_at, updated_at
 		FROM orders
 		WHERE status = $1
 		ORDER BY created_at DESC
@@ -442,7 +538,8 @@ func (db *DB) GetRecentOrders(ctx context.Context, limit int) ([]*Order, error)
 		SELECT id, session_id, position_id, exchange_order_id, symbol, exchange,
 		       side, type, status, price, stop_price, quantity, executed_quantity,
 		       executed_quote_quantity, time_in_force, placed_at, filled_at,
-		       canceled_at, error_message, metadata, created_at, updated_at
+		       canceled_at, error_message, metadata, parent_order_id, algo_type,
+		       amendments, created_at, updated_at
 		FROM orders
 		ORDER BY created_at DESC
 		LIMIT $1
@@ -457,6 +554,59 @@ func (db *DB) GetRecentOrders(ctx context.Context, limit int) ([]*Order, error)
 	return scanOrders(rows)
 }
 
+// GetChildOrders retrieves all child orders sliced from a parent algo order
+func (db *DB) GetChildOrders(ctx context.Context, parentOrderID uuid.UUID) ([]*Order, error) {
+	query := `
+		SELECT id, session_id, position_id, exchange_order_id, symbol, exchange,
+		       side, type, status, price, stop_price, quantity, executed_quantity,
+		       executed_quote_quantity, time_in_force, placed_at, filled_at,
+		       canceled_at, error_message, metadata, parent_order_id, algo_type,
+		       amendments, created_at, updated_at
+		FROM orders
+		WHERE parent_order_id = $1
+		ORDER BY placed_at ASC
+	`
+
+	rows, err := db.pool.Query(ctx, query, parentOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query child orders: %w", err)
+	}
+	defer rows.Close()
+
+	return scanOrders(rows)
+}
+
+// CancelChildOrders marks all open child orders of a parent algo order as canceled
+// Returns the number of child orders that were canceled
+func (db *DB) CancelChildOrders(ctx context.Context, parentOrderID uuid.UUID, canceledAt time.Time) (int64, error) {
+	query := `
+		UPDATE orders
+		SET status = $1,
+		    canceled_at = $2,
+		    updated_at = NOW()
+		WHERE parent_order_id = $3
+		  AND status IN ($4, $5)
+	`
+
+	result, err := db.pool.Exec(ctx, query,
+		OrderStatusCanceled,
+		canceledAt,
+		parentOrderID,
+		OrderStatusNew,
+		OrderStatusPartiallyFilled,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel child orders: %w", err)
+	}
+
+	log.Debug().
+		Str("parent_order_id", parentOrderID.String()).
+		Int64("canceled", result.RowsAffected()).
+		Msg("Child orders canceled")
+
+	return result.RowsAffected(), nil
+}
+
 // scanOrders is a helper to scan multiple order rows
 func scanOrders(rows interface {
 	Next() bool
@@ -487,6 +637,9 @@ func scanOrders(rows interface {
 			&order.CanceledAt,
 			&order.ErrorMessage,
 			&order.Metadata,
+			&order.ParentOrderID,
+			&order.AlgoType,
+			&order.Amendments,
 			&order.CreatedAt,
 			&order.UpdatedAt,
 		)
@@ -353,6 +353,40 @@ func (db *DB) GetAllOpenPositions(ctx context.Context) ([]*Position, error) {
 	return scanPositions(rows)
 }
 
+// GetPnLSince returns the realized P&L of positions closed since the given time
+// plus the unrealized P&L of open positions, across all sessions
+func (db *DB) GetPnLSince(ctx context.Context, since time.Time) (float64, error) {
+	query := `
+		SELECT
+			COALESCE(SUM(realized_pnl) FILTER (WHERE exit_time >= $1), 0) +
+			COALESCE(SUM(unrealized_pnl) FILTER (WHERE exit_time IS NULL), 0)
+		FROM positions
+	`
+
+	var pnl float64
+	if err := db.pool.QueryRow(ctx, query, since).Scan(&pnl); err != nil {
+		return 0, fmt.Errorf("failed to query P&L since %s: %w", since.Format(time.RFC3339), err)
+	}
+	return pnl, nil
+}
+
+// CountLosingPositionsSince counts the positions closed at a loss since the
+// given time, across all sessions
+func (db *DB) CountLosingPositionsSince(ctx context.Context, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM positions
+		WHERE exit_time >= $1
+			AND realized_pnl < 0
+	`
+
+	var count int
+	if err := db.pool.QueryRow(ctx, query, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count losing positions since %s: %w", since.Format(time.RFC3339), err)
+	}
+	return count, nil
+}
+
 // GetPositionsBySession retrieves all positions (including closed) for a session
 func (db *DB) GetPositionsBySession(ctx context.Context, sessionID uuid.UUID) ([]*Position, error) {
 	query := `
@@ -577,8 +611,25 @@ func (db *DB) PartialClosePosition(ctx context.Context, id uuid.UUID, closeQuant
 		return nil, err
 	}
 
+	return db.partialClosePosition(ctx, position, closeQuantity, position.EntryPrice, position.EntryPrice, exitPrice, exitReason, fees)
+}
+
+// PartialClosePositionAtCost partially closes a position whose closed quantity
+// has a different cost basis than the position average (e.g. FIFO lots).
+// closeEntryPrice prices the closed record and remainingEntryPrice becomes the
+// entry price of the quantity left open.
+func (db *DB) PartialClosePositionAtCost(ctx context.Context, id uuid.UUID, closeQuantity, closeEntryPrice, remainingEntryPrice, exitPrice float64, exitReason string, fees float64) (*Position, error) {
+	position, err := db.GetPosition(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.partialClosePosition(ctx, position, closeQuantity, closeEntryPrice, remainingEntryPrice, exitPrice, exitReason, fees)
+}
+
+func (db *DB) partialClosePosition(ctx context.Context, position *Position, closeQuantity, closeEntryPrice, remainingEntryPrice, exitPrice float64, exitReason string, fees float64) (*Position, error) {
 	if position.ExitTime != nil {
-		return nil, fmt.Errorf("position already closed: %s", id)
+		return nil, fmt.Errorf("position already closed: %s", position.ID)
 	}
 
 	if closeQuantity >= position.Quantity {
@@ -588,9 +639,9 @@ func (db *DB) PartialClosePosition(ctx context.Context, id uuid.UUID, closeQuant
 	// Calculate realized P&L for closed portion
 	var realizedPnL float64
 	if position.Side == PositionSideLong {
-		realizedPnL = (exitPrice - position.EntryPrice) * closeQuantity
+		realizedPnL = (exitPrice - closeEntryPrice) * closeQuantity
 	} else {
-		realizedPnL = (position.EntryPrice - exitPrice) * closeQuantity
+		realizedPnL = (closeEntryPrice - exitPrice) * closeQuantity
 	}
 	realizedPnL -= fees
 
@@ -602,7 +653,7 @@ func (db *DB) PartialClosePosition(ctx context.Context, id uuid.UUID, closeQuant
 		Symbol:      position.Symbol,
 		Exchange:    position.Exchange,
 		Side:        position.Side,
-		EntryPrice:  position.EntryPrice,
+		EntryPrice:  closeEntryPrice,
 		ExitPrice:   &exitPrice,
 		Quantity:    closeQuantity,
 		EntryTime:   position.EntryTime,
@@ -619,14 +670,18 @@ func (db *DB) PartialClosePosition(ctx context.Context, id uuid.UUID, closeQuant
 	}
 
 	// Insert the closed portion as a new record
-	err = db.CreatePosition(ctx, closedPosition)
+	err := db.CreatePosition(ctx, closedPosition)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create closed position record: %w", err)
 	}
 
-	// Update the original position to reduce quantity
+	// Update the original position to reduce quantity (fees already accounted for in closed portion)
 	remainingQuantity := position.Quantity - closeQuantity
-	err = db.UpdatePositionQuantity(ctx, id, remainingQuantity, 0) // fees already accounted for in closed portion
+	if remainingEntryPrice == position.EntryPrice {
+		err = db.UpdatePositionQuantity(ctx, position.ID, remainingQuantity, 0)
+	} else {
+		err = db.UpdatePositionAveraging(ctx, position.ID, remainingEntryPrice, remainingQuantity, 0)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update remaining position quantity: %w", err)
 	}
@@ -33,6 +33,7 @@ type TradingSession struct {
 	TotalPnL       float64
 	MaxDrawdown    float64
 	SharpeRatio    *float64
+	Paused         bool // New orders are rejected while paused
 	Config         map[string]interface{}
 	Metadata       map[string]interface{}
 	CreatedAt      time.Time
@@ -93,7 +94,7 @@ func (db *DB) GetSession(ctx context.Context, sessionID uuid.UUID) (*TradingSess
 		SELECT id, mode, symbol, exchange, started_at, stopped_at,
 		       initial_capital, final_capital, total_trades, winning_trades,
 		       losing_trades, total_pnl, max_drawdown, sharpe_ratio,
-		       config, metadata, created_at, updated_at
+		       paused, config, metadata, created_at, updated_at
 		FROM trading_sessions
 		WHERE id = $1
 	`
@@ -114,6 +115,7 @@ func (db *DB) GetSession(ctx context.Context, sessionID uuid.UUID) (*TradingSess
 		&session.TotalPnL,
 		&session.MaxDrawdown,
 		&session.SharpeRatio,
+		&session.Paused,
 		&session.Config,
 		&session.Metadata,
 		&session.CreatedAt,
@@ -204,13 +206,54 @@ func (db *DB) StopSession(ctx context.Context, sessionID uuid.UUID, finalCapital
 	return nil
 }
 
+// SetSessionPaused pauses or resumes an active trading session
+func (db *DB) SetSessionPaused(ctx context.Context, sessionID uuid.UUID, paused bool) error {
+	query := `
+		UPDATE trading_sessions
+		SET paused = $1,
+		    updated_at = NOW()
+		WHERE id = $2
+		AND stopped_at IS NULL
+	`
+
+	result, err := db.pool.Exec(ctx, query, paused, sessionID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("session_id", sessionID.String()).
+			Msg("Failed to update session pause state")
+		return fmt.Errorf("failed to update session pause state: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("trading session not found or already stopped: %s", sessionID.String())
+	}
+
+	log.Info().
+		Str("session_id", sessionID.String()).
+		Bool("paused", paused).
+		Msg("Trading session pause state updated")
+
+	return nil
+}
+
+// IsSessionPaused reports whether a trading session is paused
+func (db *DB) IsSessionPaused(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	var paused bool
+	err := db.pool.QueryRow(ctx, `SELECT paused FROM trading_sessions WHERE id = $1`, sessionID).Scan(&paused)
+	if err != nil {
+		return false, fmt.Errorf("failed to get session pause state: %w", err)
+	}
+	return paused, nil
+}
+
 // ListActiveSessions retrieves all active (not stopped) trading sessions
 func (db *DB) ListActiveSessions(ctx context.Context) ([]*TradingSession, error) {
 	query := `
 		SELECT id, mode, symbol, exchange, started_at, stopped_at,
 		       initial_capital, final_capital, total_trades, winning_trades,
 		       losing_trades, total_pnl, max_drawdown, sharpe_ratio,
-		       config, metadata, created_at, updated_at
+		       paused, config, metadata, created_at, updated_at
 		FROM trading_sessions
 		WHERE stopped_at IS NULL
 		ORDER BY started_at DESC
@@ -243,6 +286,7 @@ func (db *DB) ListActiveSessions(ctx context.Context) ([]*TradingSession, error)
 			&session.TotalPnL,
 			&session.MaxDrawdown,
 			&session.SharpeRatio,
+			&session.Paused,
 			&session.Config,
 			&session.Metadata,
 			&session.CreatedAt,
@@ -274,7 +318,7 @@ func (db *DB) GetSessionsBySymbol(ctx context.Context, symbol string) ([]*Tradin
 		SELECT id, mode, symbol, exchange, started_at, stopped_at,
 		       initial_capital, final_capital, total_trades, winning_trades,
 		       losing_trades, total_pnl, max_drawdown, sharpe_ratio,
-		       config, metadata, created_at, updated_at
+		       paused, config, metadata, created_at, updated_at
 		FROM trading_sessions
 		WHERE symbol = $1
 		ORDER BY started_at DESC
@@ -308,6 +352,7 @@ func (db *DB) GetSessionsBySymbol(ctx context.Context, symbol string) ([]*Tradin
 			&session.TotalPnL,
 			&session.MaxDrawdown,
 			&session.SharpeRatio,
+			&session.Paused,
 			&session.Config,
 			&session.Metadata,
 			&session.CreatedAt,
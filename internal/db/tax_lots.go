@@ -0,0 +1,255 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TaxLot is quantity acquired by a single fill that later fills dispose of
+type TaxLot struct {
+	ID                uuid.UUID    `db:"id" json:"id"`
+	SessionID         *uuid.UUID   `db:"session_id" json:"session_id,omitempty"`
+	Symbol            string       `db:"symbol" json:"symbol"`
+	Side              PositionSide `db:"side" json:"side"`
+	Quantity          float64      `db:"quantity" json:"quantity"`
+	RemainingQuantity float64      `db:"remaining_quantity" json:"remaining_quantity"`
+	Price             float64      `db:"price" json:"price"`
+	Fees              float64      `db:"fees" json:"fees"` // Acquisition fees of the remaining quantity
+	OrderID           *string      `db:"order_id" json:"order_id,omitempty"`
+	AcquiredAt        time.Time    `db:"acquired_at" json:"acquired_at"`
+	ClosedAt          *time.Time   `db:"closed_at" json:"closed_at,omitempty"`
+}
+
+// RealizedGain is the disposal of (part of) a tax lot
+type RealizedGain struct {
+	ID         uuid.UUID    `db:"id" json:"id"`
+	SessionID  *uuid.UUID   `db:"session_id" json:"session_id,omitempty"`
+	LotID      uuid.UUID    `db:"lot_id" json:"lot_id"`
+	Symbol     string       `db:"symbol" json:"symbol"`
+	Side       PositionSide `db:"side" json:"side"`
+	Method     string       `db:"method" json:"method"`
+	Quantity   float64      `db:"quantity" json:"quantity"`
+	CostBasis  float64      `db:"cost_basis" json:"cost_basis"` // Includes allocated acquisition fees
+	Proceeds   float64      `db:"proceeds" json:"proceeds"`     // Net of allocated disposal fees
+	Gain       float64      `db:"gain" json:"gain"`
+	OrderID    *string      `db:"order_id" json:"order_id,omitempty"`
+	AcquiredAt time.Time    `db:"acquired_at" json:"acquired_at"`
+	DisposedAt time.Time    `db:"disposed_at" json:"disposed_at"`
+}
+
+// RealizedGainFilter narrows a realized gains query. Zero values are ignored.
+type RealizedGainFilter struct {
+	SessionID *uuid.UUID
+	Symbol    string
+	From      *time.Time // Inclusive
+	To        *time.Time // Exclusive
+}
+
+// CreateTaxLot inserts a new tax lot
+func (db *DB) CreateTaxLot(ctx context.Context, lot *TaxLot) error {
+	query := `
+		INSERT INTO tax_lots (
+			id, session_id, symbol, side, quantity, remaining_quantity,
+			price, fees, order_id, acquired_at, closed_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := db.pool.Exec(ctx, query,
+		lot.ID,
+		lot.SessionID,
+		lot.Symbol,
+		lot.Side,
+		lot.Quantity,
+		lot.RemainingQuantity,
+		lot.Price,
+		lot.Fees,
+		lot.OrderID,
+		lot.AcquiredAt,
+		lot.ClosedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create tax lot: %w", err)
+	}
+
+	return nil
+}
+
+// RecordLotDisposal reduces a tax lot and inserts the realized gain in one transaction.
+// The lot is closed once its remaining quantity reaches zero.
+func (db *DB) RecordLotDisposal(ctx context.Context, gain *RealizedGain, remainingQuantity, remainingFees float64) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // Rollback if commit not called (error ignored as commit may have succeeded)
+
+	var closedAt *time.Time
+	if remainingQuantity <= 0 {
+		closedAt = &gain.DisposedAt
+	}
+
+	result, err := tx.Exec(ctx, `
+		UPDATE tax_lots
+		SET remaining_quantity = $2, fees = $3, closed_at = $4
+		WHERE id = $1 AND closed_at IS NULL
+	`, gain.LotID, remainingQuantity, remainingFees, closedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update tax lot: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("tax lot not found or already closed: %s", gain.LotID)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO realized_gains (
+			id, session_id, lot_id, symbol, side, method, quantity,
+			cost_basis, proceeds, gain, order_id, acquired_at, disposed_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`,
+		gain.ID,
+		gain.SessionID,
+		gain.LotID,
+		gain.Symbol,
+		gain.Side,
+		gain.Method,
+		gain.Quantity,
+		gain.CostBasis,
+		gain.Proceeds,
+		gain.Gain,
+		gain.OrderID,
+		gain.AcquiredAt,
+		gain.DisposedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create realized gain: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit lot disposal: %w", err)
+	}
+
+	return nil
+}
+
+// GetOpenTaxLots returns the open tax lots of a session, oldest first
+func (db *DB) GetOpenTaxLots(ctx context.Context, sessionID uuid.UUID) ([]*TaxLot, error) {
+	query := `
+		SELECT
+			id, session_id, symbol, side, quantity, remaining_quantity,
+			price, fees, order_id, acquired_at, closed_at
+		FROM tax_lots
+		WHERE session_id = $1 AND closed_at IS NULL
+		ORDER BY acquired_at ASC
+	`
+
+	rows, err := db.pool.Query(ctx, query, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get open tax lots: %w", err)
+	}
+	defer rows.Close()
+
+	lots := make([]*TaxLot, 0)
+	for rows.Next() {
+		var lot TaxLot
+		if err := rows.Scan(
+			&lot.ID,
+			&lot.SessionID,
+			&lot.Symbol,
+			&lot.Side,
+			&lot.Quantity,
+			&lot.RemainingQuantity,
+			&lot.Price,
+			&lot.Fees,
+			&lot.OrderID,
+			&lot.AcquiredAt,
+			&lot.ClosedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan tax lot: %w", err)
+		}
+		lots = append(lots, &lot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tax lots: %w", err)
+	}
+
+	return lots, nil
+}
+
+// GetRealizedGains returns realized gains matching the filter ordered by disposal time
+func (db *DB) GetRealizedGains(ctx context.Context, filter RealizedGainFilter) ([]*RealizedGain, error) {
+	query := `
+		SELECT
+			id, session_id, lot_id, symbol, side, method, quantity,
+			cost_basis, proceeds, gain, order_id, acquired_at, disposed_at
+		FROM realized_gains
+	`
+
+	var conditions []string
+	var args []interface{}
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.SessionID != nil {
+		addCondition("session_id = $%d", *filter.SessionID)
+	}
+	if filter.Symbol != "" {
+		addCondition("symbol = $%d", filter.Symbol)
+	}
+	if filter.From != nil {
+		addCondition("disposed_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("disposed_at < $%d", *filter.To)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY disposed_at ASC, acquired_at ASC"
+
+	rows, err := db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get realized gains: %w", err)
+	}
+	defer rows.Close()
+
+	return scanRealizedGains(rows)
+}
+
+func scanRealizedGains(rows pgx.Rows) ([]*RealizedGain, error) {
+	gains := make([]*RealizedGain, 0)
+	for rows.Next() {
+		var gain RealizedGain
+		if err := rows.Scan(
+			&gain.ID,
+			&gain.SessionID,
+			&gain.LotID,
+			&gain.Symbol,
+			&gain.Side,
+			&gain.Method,
+			&gain.Quantity,
+			&gain.CostBasis,
+			&gain.Proceeds,
+			&gain.Gain,
+			&gain.OrderID,
+			&gain.AcquiredAt,
+			&gain.DisposedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan realized gain: %w", err)
+		}
+		gains = append(gains, &gain)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating realized gains: %w", err)
+	}
+
+	return gains, nil
+}
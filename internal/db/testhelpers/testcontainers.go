@@ -216,6 +216,8 @@ CREATE TABLE IF NOT EXISTS orders (
     canceled_at TIMESTAMP WITH TIME ZONE,
     error_message TEXT,
     metadata JSONB,
+    parent_order_id UUID REFERENCES orders(id),
+    algo_type TEXT,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
@@ -0,0 +1,1033 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// ExecutionAlgo identifies how a parent order is sliced into child orders
+type ExecutionAlgo string
+
+const (
+	AlgoTWAP    ExecutionAlgo = "twap"    // Equal slices at equal time intervals
+	AlgoVWAP    ExecutionAlgo = "vwap"    // Slices weighted by historical volume profile
+	AlgoPOV     ExecutionAlgo = "pov"     // Slices sized as a percentage of market volume
+	AlgoIceberg ExecutionAlgo = "iceberg" // Only a small visible quantity is exposed at a time
+)
+
+// AlgoStatus represents the lifecycle state of an algo (parent) order
+type AlgoStatus string
+
+const (
+	AlgoStatusRunning   AlgoStatus = "running"
+	AlgoStatusCompleted AlgoStatus = "completed"
+	AlgoStatusCancelled AlgoStatus = "cancelled"
+	AlgoStatusExpired   AlgoStatus = "expired" // Horizon elapsed before the full quantity was filled
+	AlgoStatusFailed    AlgoStatus = "failed"
+)
+
+const (
+	defaultAlgoSlices       = 10
+	defaultAlgoPollInterval = 500 * time.Millisecond
+	defaultVolumeLookback   = 30 // days of candlesticks used for volume profiles
+	algoQuantityEpsilon     = 1e-9
+)
+
+// AlgoOrderRequest describes a parent order to be executed by an algorithm
+type AlgoOrderRequest struct {
+	Symbol            string        `json:"symbol"`
+	Side              OrderSide     `json:"side"`
+	Quantity          float64       `json:"quantity"`
+	Algo              ExecutionAlgo `json:"algo"`
+	Duration          time.Duration `json:"duration"`                     // Execution horizon (twap, vwap, pov; optional for iceberg)
+	Slices            int           `json:"slices,omitempty"`             // Number of child orders (twap, vwap) or volume checks (pov)
+	LimitPrice        float64       `json:"limit_price,omitempty"`        // Optional: child orders are limit orders at this price
+	ParticipationRate float64       `json:"participation_rate,omitempty"` // POV: fraction of market volume (0-1]
+	DisplayQuantity   float64       `json:"display_quantity,omitempty"`   // Iceberg: visible size of each child order
+}
+
+// ChildSlice is one child order of an algo order
+type ChildSlice struct {
+	Index        int         `json:"index"`
+	Quantity     float64     `json:"quantity"`
+	ScheduledAt  time.Time   `json:"scheduled_at"`
+	OrderID      string      `json:"order_id,omitempty"`
+	Status       OrderStatus `json:"status,omitempty"`
+	FilledQty    float64     `json:"filled_qty"`
+	AvgFillPrice float64     `json:"avg_fill_price,omitempty"`
+	Error        string      `json:"error,omitempty"`
+
+	reportedFills int     // Fills already passed to the fill handler
+	reportedQty   float64 // Quantity of those fills
+}
+
+// AlgoOrder tracks the execution progress of a parent order
+type AlgoOrder struct {
+	ID           string           `json:"id"`
+	Request      AlgoOrderRequest `json:"request"`
+	Status       AlgoStatus       `json:"status"`
+	FilledQty    float64          `json:"filled_qty"`
+	AvgFillPrice float64          `json:"avg_fill_price,omitempty"`
+	Children     []ChildSlice     `json:"children"`
+	StartedAt    time.Time        `json:"started_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+	CompletedAt  *time.Time       `json:"completed_at,omitempty"`
+	Message      string           `json:"message,omitempty"`
+}
+
+// Progress returns the filled fraction of the parent order (0-1)
+func (a *AlgoOrder) Progress() float64 {
+	if a.Request.Quantity <= 0 {
+		return 0
+	}
+	return math.Min(a.FilledQty/a.Request.Quantity, 1.0)
+}
+
+// RemainingQty returns the quantity that has not been filled yet
+func (a *AlgoOrder) RemainingQty() float64 {
+	return math.Max(a.Request.Quantity-a.FilledQty, 0)
+}
+
+// VolumeSource provides historical and recent traded volume for execution algorithms
+type VolumeSource interface {
+	// HourlyVolumeProfile returns average traded volume for each UTC hour of day (24 buckets)
+	HourlyVolumeProfile(ctx context.Context, symbol string) ([]float64, error)
+
+	// VolumeSince returns the market volume traded since the given time
+	VolumeSince(ctx context.Context, symbol string, since time.Time) (float64, error)
+}
+
+// dbVolumeSource reads volume data from the candlesticks hypertable
+type dbVolumeSource struct {
+	db           *db.DB
+	lookbackDays int
+}
+
+// NewDBVolumeSource creates a volume source backed by stored candlesticks
+func NewDBVolumeSource(database *db.DB) VolumeSource {
+	return &dbVolumeSource{
+		db:           database,
+		lookbackDays: defaultVolumeLookback,
+	}
+}
+
+// HourlyVolumeProfile returns the average hourly volume profile from candlesticks
+func (v *dbVolumeSource) HourlyVolumeProfile(ctx context.Context, symbol string) ([]float64, error) {
+	return v.db.GetHourlyVolumeProfile(ctx, symbol, v.lookbackDays)
+}
+
+// VolumeSince returns traded volume from 1-minute candlesticks since the given time
+func (v *dbVolumeSource) VolumeSince(ctx context.Context, symbol string, since time.Time) (float64, error) {
+	return v.db.GetVolumeSince(ctx, symbol, "1m", since)
+}
+
+// ValidateAlgoOrderRequest validates algo order parameters
+func ValidateAlgoOrderRequest(req AlgoOrderRequest) error {
+	if req.Symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+
+	if req.Side != OrderSideBuy && req.Side != OrderSideSell {
+		return fmt.Errorf("invalid order side: %s", req.Side)
+	}
+
+	if req.Quantity <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+
+	if req.LimitPrice < 0 {
+		return fmt.Errorf("limit_price cannot be negative")
+	}
+
+	if req.Slices < 0 {
+		return fmt.Errorf("slices cannot be negative")
+	}
+
+	switch req.Algo {
+	case AlgoTWAP, AlgoVWAP:
+		if req.Duration <= 0 {
+			return fmt.Errorf("%s requires a positive duration", req.Algo)
+		}
+	case AlgoPOV:
+		if req.Duration <= 0 {
+			return fmt.Errorf("pov requires a positive duration")
+		}
+		if req.ParticipationRate <= 0 || req.ParticipationRate > 1 {
+			return fmt.Errorf("participation_rate must be between 0 and 1")
+		}
+	case AlgoIceberg:
+		if req.DisplayQuantity <= 0 {
+			return fmt.Errorf("iceberg requires a positive display_quantity")
+		}
+		if req.DisplayQuantity > req.Quantity {
+			return fmt.Errorf("display_quantity cannot exceed quantity")
+		}
+		if req.Duration < 0 {
+			return fmt.Errorf("duration cannot be negative")
+		}
+	default:
+		return fmt.Errorf("unsupported execution algorithm: %s", req.Algo)
+	}
+
+	return nil
+}
+
+// BuildTWAPSchedule splits a quantity into equal slices spread evenly over the duration
+func BuildTWAPSchedule(quantity float64, start time.Time, duration time.Duration, slices int) []ChildSlice {
+	if slices <= 0 {
+		slices = defaultAlgoSlices
+	}
+
+	interval := duration / time.Duration(slices)
+	sliceQty := quantity / float64(slices)
+
+	schedule := make([]ChildSlice, slices)
+	for i := 0; i < slices; i++ {
+		schedule[i] = ChildSlice{
+			Index:       i,
+			Quantity:    sliceQty,
+			ScheduledAt: start.Add(interval * time.Duration(i)),
+		}
+	}
+
+	// Put any floating point remainder on the last slice
+	schedule[slices-1].Quantity = quantity - sliceQty*float64(slices-1)
+
+	return schedule
+}
+
+// BuildVWAPSchedule splits a quantity over the duration in proportion to the historical
+// volume traded in each slice's hour of day. Falls back to TWAP without a usable profile.
+func BuildVWAPSchedule(quantity float64, start time.Time, duration time.Duration, slices int, hourlyProfile []float64) []ChildSlice {
+	schedule := BuildTWAPSchedule(quantity, start, duration, slices)
+	if len(hourlyProfile) != 24 {
+		return schedule
+	}
+
+	interval := duration / time.Duration(len(schedule))
+	weights := make([]float64, len(schedule))
+	var totalWeight float64
+	for i := range schedule {
+		// Weight each slice by the volume expected at the middle of its interval
+		mid := schedule[i].ScheduledAt.Add(interval / 2).UTC()
+		weights[i] = math.Max(hourlyProfile[mid.Hour()], 0)
+		totalWeight += weights[i]
+	}
+
+	if totalWeight <= 0 {
+		return schedule
+	}
+
+	var allocated float64
+	for i := range schedule {
+		if i == len(schedule)-1 {
+			schedule[i].Quantity = quantity - allocated
+			break
+		}
+		schedule[i].Quantity = quantity * weights[i] / totalWeight
+		allocated += schedule[i].Quantity
+	}
+
+	return schedule
+}
+
+// BuildIcebergSchedule splits a quantity into visible slices of the display quantity
+// Slices are released one at a time as the previous one fills
+func BuildIcebergSchedule(quantity, displayQty float64, start time.Time) []ChildSlice {
+	if displayQty <= 0 || displayQty >= quantity {
+		return []ChildSlice{{Index: 0, Quantity: quantity, ScheduledAt: start}}
+	}
+
+	count := int(math.Ceil(quantity/displayQty - algoQuantityEpsilon))
+	schedule := make([]ChildSlice, count)
+	remaining := quantity
+	for i := 0; i < count; i++ {
+		qty := math.Min(displayQty, remaining)
+		schedule[i] = ChildSlice{
+			Index:       i,
+			Quantity:    qty,
+			ScheduledAt: start,
+		}
+		remaining -= qty
+	}
+
+	return schedule
+}
+
+// AlgoFillHandler is called with each new batch of fills of an algo child order
+type AlgoFillHandler func(ctx context.Context, order *Order, fills []Fill)
+
+// algoRun holds the runtime state of a single algo order
+type algoRun struct {
+	mu     sync.RWMutex
+	order  *AlgoOrder
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// AlgoExecutor slices parent orders into child orders and executes them over time
+type AlgoExecutor struct {
+	exchange     Exchange
+	db           *db.DB
+	volume       VolumeSource
+	onFill       AlgoFillHandler
+	pollInterval time.Duration
+
+	mu   sync.RWMutex
+	runs map[string]*algoRun
+}
+
+// NewAlgoExecutor creates a new execution algorithm engine on top of an exchange
+func NewAlgoExecutor(exchange Exchange, database *db.DB, volume VolumeSource) *AlgoExecutor {
+	return &AlgoExecutor{
+		exchange:     exchange,
+		db:           database,
+		volume:       volume,
+		pollInterval: defaultAlgoPollInterval,
+		runs:         make(map[string]*algoRun),
+	}
+}
+
+// SetFillHandler sets the callback invoked when child orders fill (e.g., position updates)
+func (e *AlgoExecutor) SetFillHandler(handler AlgoFillHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.onFill = handler
+}
+
+// SetPollInterval sets how often resting child orders are polled for fills
+func (e *AlgoExecutor) SetPollInterval(interval time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if interval > 0 {
+		e.pollInterval = interval
+	}
+}
+
+// Submit validates and starts executing an algo order in the background
+// Execution is detached from ctx so it outlives the request that started it
+func (e *AlgoExecutor) Submit(ctx context.Context, req AlgoOrderRequest) (*AlgoOrder, error) {
+	if err := ValidateAlgoOrderRequest(req); err != nil {
+		return nil, err
+	}
+
+	if req.Algo == AlgoPOV && e.volume == nil {
+		return nil, fmt.Errorf("pov execution requires a volume source")
+	}
+
+	now := time.Now()
+	schedule, err := e.buildSchedule(ctx, req, now)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &AlgoOrder{
+		ID:        uuid.New().String(),
+		Request:   req,
+		Status:    AlgoStatusRunning,
+		Children:  schedule,
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+
+	e.persistParent(ctx, order)
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	run := &algoRun{
+		order:  order,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	e.mu.Lock()
+	e.runs[order.ID] = run
+	e.mu.Unlock()
+
+	log.Info().
+		Str("algo_order_id", order.ID).
+		Str("algo", string(req.Algo)).
+		Str("symbol", req.Symbol).
+		Str("side", string(req.Side)).
+		Float64("quantity", req.Quantity).
+		Int("slices", len(schedule)).
+		Dur("duration", req.Duration).
+		Msg("Algo order submitted")
+
+	go e.execute(runCtx, run)
+
+	return snapshotAlgoOrder(run), nil
+}
+
+// Get returns a snapshot of an algo order's current progress
+func (e *AlgoExecutor) Get(algoOrderID string) (*AlgoOrder, bool) {
+	e.mu.RLock()
+	run, exists := e.runs[algoOrderID]
+	e.mu.RUnlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	return snapshotAlgoOrder(run), true
+}
+
+// List returns snapshots of all algo orders known to the executor
+func (e *AlgoExecutor) List() []*AlgoOrder {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	orders := make([]*AlgoOrder, 0, len(e.runs))
+	for _, run := range e.runs {
+		orders = append(orders, snapshotAlgoOrder(run))
+	}
+
+	return orders
+}
+
+// Cancel stops an algo order and cancels any resting child orders
+func (e *AlgoExecutor) Cancel(ctx context.Context, algoOrderID string) (*AlgoOrder, error) {
+	e.mu.RLock()
+	run, exists := e.runs[algoOrderID]
+	e.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("algo order not found: %s", algoOrderID)
+	}
+
+	run.mu.RLock()
+	status := run.order.Status
+	run.mu.RUnlock()
+
+	if status != AlgoStatusRunning {
+		return nil, fmt.Errorf("cannot cancel algo order in status: %s", status)
+	}
+
+	// Stop the scheduler and wait for it to exit before touching child orders
+	run.cancel()
+	select {
+	case <-run.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timed out waiting for algo order to stop: %w", ctx.Err())
+	}
+
+	return snapshotAlgoOrder(run), nil
+}
+
+// buildSchedule creates the initial child slice schedule for an algo
+func (e *AlgoExecutor) buildSchedule(ctx context.Context, req AlgoOrderRequest, start time.Time) ([]ChildSlice, error) {
+	switch req.Algo {
+	case AlgoTWAP:
+		return BuildTWAPSchedule(req.Quantity, start, req.Duration, req.Slices), nil
+
+	case AlgoVWAP:
+		var profile []float64
+		if e.volume != nil {
+			var err error
+			profile, err = e.volume.HourlyVolumeProfile(ctx, req.Symbol)
+			if err != nil {
+				log.Warn().Err(err).Str("symbol", req.Symbol).Msg("Failed to load volume profile, falling back to TWAP schedule")
+			}
+		}
+		return BuildVWAPSchedule(req.Quantity, start, req.Duration, req.Slices, profile), nil
+
+	case AlgoIceberg:
+		return BuildIcebergSchedule(req.Quantity, req.DisplayQuantity, start), nil
+
+	case AlgoPOV:
+		// POV children are sized at runtime from observed market volume
+		return []ChildSlice{}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported execution algorithm: %s", req.Algo)
+	}
+}
+
+// execute runs the algo order until it is filled, cancelled or its horizon elapses
+func (e *AlgoExecutor) execute(ctx context.Context, run *algoRun) {
+	defer close(run.done)
+
+	run.mu.RLock()
+	req := run.order.Request
+	run.mu.RUnlock()
+
+	switch req.Algo {
+	case AlgoTWAP, AlgoVWAP:
+		e.executeScheduled(ctx, run)
+	case AlgoIceberg:
+		e.executeIceberg(ctx, run)
+	case AlgoPOV:
+		e.executePOV(ctx, run)
+	}
+
+	e.finalize(run, ctx.Err() != nil)
+}
+
+// executeScheduled places each child order at its scheduled time (TWAP, VWAP)
+// Resting limit children are polled for fills until the horizon ends
+func (e *AlgoExecutor) executeScheduled(ctx context.Context, run *algoRun) {
+	run.mu.RLock()
+	count := len(run.order.Children)
+	deadline := run.order.StartedAt.Add(run.order.Request.Duration)
+	run.mu.RUnlock()
+
+	for i := 0; i < count; i++ {
+		run.mu.RLock()
+		scheduledAt := run.order.Children[i].ScheduledAt
+		run.mu.RUnlock()
+
+		if !e.pollChildrenUntil(ctx, run, scheduledAt, false) || e.parentCancelledExternally(ctx, run) {
+			return
+		}
+
+		e.placeChild(ctx, run, i)
+	}
+
+	// Children still resting after the last slice keep their chance to fill;
+	// finalize cancels whatever is left when the horizon ends
+	e.pollChildrenUntil(ctx, run, deadline, true)
+}
+
+// executeIceberg releases one visible child at a time, waiting for each to fill
+func (e *AlgoExecutor) executeIceberg(ctx context.Context, run *algoRun) {
+	run.mu.RLock()
+	count := len(run.order.Children)
+	req := run.order.Request
+	startedAt := run.order.StartedAt
+	run.mu.RUnlock()
+
+	var deadline time.Time
+	if req.Duration > 0 {
+		deadline = startedAt.Add(req.Duration)
+	}
+
+	for i := 0; i < count; i++ {
+		if ctx.Err() != nil || e.parentCancelledExternally(ctx, run) {
+			return
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return
+		}
+
+		run.mu.Lock()
+		run.order.Children[i].ScheduledAt = time.Now()
+		run.mu.Unlock()
+
+		if !e.placeChild(ctx, run, i) {
+			return
+		}
+
+		if !e.waitForChildFill(ctx, run, i, deadline) {
+			return
+		}
+	}
+}
+
+// executePOV sizes each child as a fraction of the market volume observed since the last check
+func (e *AlgoExecutor) executePOV(ctx context.Context, run *algoRun) {
+	run.mu.RLock()
+	req := run.order.Request
+	startedAt := run.order.StartedAt
+	run.mu.RUnlock()
+
+	slices := req.Slices
+	if slices <= 0 {
+		slices = defaultAlgoSlices
+	}
+	interval := req.Duration / time.Duration(slices)
+	deadline := startedAt.Add(req.Duration)
+	lastCheck := startedAt
+
+	for i := 1; i <= slices; i++ {
+		if !e.pollChildrenUntil(ctx, run, startedAt.Add(interval*time.Duration(i)), false) || e.parentCancelledExternally(ctx, run) {
+			return
+		}
+
+		now := time.Now()
+		marketVolume, err := e.volume.VolumeSince(ctx, req.Symbol, lastCheck)
+		lastCheck = now
+		if err != nil {
+			log.Warn().Err(err).Str("symbol", req.Symbol).Msg("Failed to read market volume for POV slice")
+			continue
+		}
+
+		// Resting limit children are still working their quantity, so only the
+		// rest is sized and the final sweep does not send it a second time
+		run.mu.Lock()
+		remaining := math.Max(run.order.RemainingQty()-run.order.restingQty(), 0)
+		qty := math.Min(marketVolume*req.ParticipationRate, remaining)
+		// Sweep the remainder on the final check so the horizon is honored
+		if i == slices || now.After(deadline) {
+			qty = remaining
+		}
+		if qty <= algoQuantityEpsilon {
+			run.mu.Unlock()
+			continue
+		}
+		index := len(run.order.Children)
+		run.order.Children = append(run.order.Children, ChildSlice{
+			Index:       index,
+			Quantity:    qty,
+			ScheduledAt: now,
+		})
+		run.mu.Unlock()
+
+		e.placeChild(ctx, run, index)
+
+		run.mu.RLock()
+		done := run.order.RemainingQty() <= algoQuantityEpsilon
+		run.mu.RUnlock()
+		if done {
+			return
+		}
+	}
+
+	e.pollChildrenUntil(ctx, run, deadline, true)
+}
+
+// placeChild places child slice i on the exchange and records any immediate fills
+// Returns false if the child could not be placed
+func (e *AlgoExecutor) placeChild(ctx context.Context, run *algoRun, index int) bool {
+	run.mu.RLock()
+	parentID := run.order.ID
+	req := run.order.Request
+	qty := run.order.Children[index].Quantity
+	run.mu.RUnlock()
+
+	childReq := PlaceOrderRequest{
+		Symbol:        req.Symbol,
+		Side:          req.Side,
+		Type:          OrderTypeMarket,
+		Quantity:      qty,
+		ParentOrderID: parentID,
+	}
+	if req.LimitPrice > 0 {
+		childReq.Type = OrderTypeLimit
+		childReq.Price = req.LimitPrice
+	}
+
+	resp, err := e.exchange.PlaceOrder(ctx, childReq)
+	if err == nil && resp.Status == OrderStatusRejected {
+		err = fmt.Errorf("child order rejected: %s", resp.Message)
+	}
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("algo_order_id", parentID).
+			Int("slice", index).
+			Msg("Failed to place algo child order")
+
+		run.mu.Lock()
+		run.order.Children[index].Status = OrderStatusRejected
+		run.order.Children[index].Error = err.Error()
+		run.order.UpdatedAt = time.Now()
+		run.mu.Unlock()
+		return false
+	}
+
+	run.mu.Lock()
+	run.order.Children[index].OrderID = resp.OrderID
+	run.order.Children[index].Status = resp.Status
+	run.mu.Unlock()
+
+	e.refreshChild(ctx, run, index)
+	return true
+}
+
+// refreshChild updates a child slice from the exchange and applies new fills to the parent
+func (e *AlgoExecutor) refreshChild(ctx context.Context, run *algoRun, index int) {
+	run.mu.RLock()
+	child := run.order.Children[index]
+	run.mu.RUnlock()
+
+	if child.OrderID == "" {
+		return
+	}
+
+	order, err := e.exchange.GetOrder(ctx, child.OrderID)
+	if err != nil {
+		log.Warn().Err(err).Str("order_id", child.OrderID).Msg("Failed to refresh algo child order")
+		return
+	}
+
+	newlyFilled := order.FilledQty - child.FilledQty
+
+	run.mu.Lock()
+	c := &run.order.Children[index]
+	c.Status = order.Status
+	c.FilledQty = order.FilledQty
+	c.AvgFillPrice = order.AvgFillPrice
+	run.order.recalculateFills()
+	run.order.UpdatedAt = time.Now()
+	run.mu.Unlock()
+
+	if newlyFilled > algoQuantityEpsilon {
+		e.persistParentProgress(ctx, run)
+	}
+
+	// Forward fills as they happen, so partial fills of a child that is later
+	// cancelled still reach the handler
+	if order.FilledQty-child.reportedQty > algoQuantityEpsilon {
+		e.reportChildFills(ctx, run, index, order)
+	}
+}
+
+// reportChildFills passes the child's fills not yet reported to the fill handler
+func (e *AlgoExecutor) reportChildFills(ctx context.Context, run *algoRun, index int, order *Order) {
+	e.mu.RLock()
+	onFill := e.onFill
+	e.mu.RUnlock()
+
+	if onFill == nil {
+		return
+	}
+
+	fills, err := e.exchange.GetOrderFills(ctx, order.ID)
+	if err != nil {
+		log.Warn().Err(err).Str("order_id", order.ID).Msg("Failed to get algo child fills")
+		return
+	}
+
+	run.mu.Lock()
+	c := &run.order.Children[index]
+	if c.reportedFills >= len(fills) {
+		run.mu.Unlock()
+		return
+	}
+	newFills := append([]Fill(nil), fills[c.reportedFills:]...)
+	c.reportedFills = len(fills)
+	for _, fill := range newFills {
+		c.reportedQty += fill.Quantity
+	}
+	run.mu.Unlock()
+
+	onFill(ctx, order, newFills)
+}
+
+// pollChildrenUntil waits until t, refreshing resting children every poll
+// interval so their fills are recorded as they happen. With stopWhenIdle it
+// returns as soon as no child is resting. Returns false if ctx was cancelled.
+func (e *AlgoExecutor) pollChildrenUntil(ctx context.Context, run *algoRun, t time.Time, stopWhenIdle bool) bool {
+	e.mu.RLock()
+	pollInterval := e.pollInterval
+	e.mu.RUnlock()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		resting := run.restingChildren()
+		if stopWhenIdle && len(resting) == 0 {
+			return ctx.Err() == nil
+		}
+
+		delay := time.Until(t)
+		if delay <= 0 {
+			return ctx.Err() == nil
+		}
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+			return true
+		case <-ticker.C:
+			timer.Stop()
+			for _, i := range resting {
+				e.refreshChild(ctx, run, i)
+			}
+		}
+	}
+}
+
+// waitForChildFill polls a resting child until it fills, the deadline passes or ctx is done
+func (e *AlgoExecutor) waitForChildFill(ctx context.Context, run *algoRun, index int, deadline time.Time) bool {
+	e.mu.RLock()
+	pollInterval := e.pollInterval
+	e.mu.RUnlock()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		run.mu.RLock()
+		status := run.order.Children[index].Status
+		run.mu.RUnlock()
+
+		switch status {
+		case OrderStatusFilled:
+			return true
+		case OrderStatusCancelled, OrderStatusRejected:
+			return false
+		}
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			e.refreshChild(ctx, run, index)
+		}
+	}
+}
+
+// finalize cancels resting children and records the terminal state of the algo order
+func (e *AlgoExecutor) finalize(run *algoRun, cancelled bool) {
+	// Use a fresh context: the run context may already be cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	run.mu.RLock()
+	children := append([]ChildSlice(nil), run.order.Children...)
+	run.mu.RUnlock()
+
+	for i, child := range children {
+		if child.OrderID == "" || (child.Status != OrderStatusOpen && child.Status != OrderStatusPending) {
+			continue
+		}
+
+		// Pick up any fills that happened since the last poll before cancelling
+		e.refreshChild(ctx, run, i)
+
+		run.mu.RLock()
+		status := run.order.Children[i].Status
+		run.mu.RUnlock()
+		if status != OrderStatusOpen && status != OrderStatusPending {
+			continue
+		}
+
+		if _, err := e.exchange.CancelOrder(ctx, child.OrderID); err != nil {
+			log.Warn().Err(err).Str("order_id", child.OrderID).Msg("Failed to cancel resting algo child order")
+			continue
+		}
+
+		run.mu.Lock()
+		run.order.Children[i].Status = OrderStatusCancelled
+		run.mu.Unlock()
+	}
+
+	now := time.Now()
+	run.mu.Lock()
+	switch {
+	case run.order.RemainingQty() <= algoQuantityEpsilon:
+		run.order.Status = AlgoStatusCompleted
+	case cancelled:
+		run.order.Status = AlgoStatusCancelled
+		run.order.Message = "cancelled by request"
+	case run.order.FilledQty <= algoQuantityEpsilon && run.order.hasRejectedChildren():
+		run.order.Status = AlgoStatusFailed
+		run.order.Message = "child orders were rejected"
+	default:
+		run.order.Status = AlgoStatusExpired
+		run.order.Message = fmt.Sprintf("execution ended with %.8f unfilled", run.order.RemainingQty())
+	}
+	run.order.CompletedAt = &now
+	run.order.UpdatedAt = now
+	order := *run.order
+	run.mu.Unlock()
+
+	e.persistParentFinal(ctx, &order)
+
+	log.Info().
+		Str("algo_order_id", order.ID).
+		Str("algo", string(order.Request.Algo)).
+		Str("status", string(order.Status)).
+		Float64("filled_qty", order.FilledQty).
+		Float64("avg_fill_price", order.AvgFillPrice).
+		Int("children", len(order.Children)).
+		Msg("Algo order finished")
+}
+
+// parentCancelledExternally checks whether the parent order was cancelled outside
+// the executor (e.g., via DELETE /api/v1/orders/:id) and stops the run if so
+func (e *AlgoExecutor) parentCancelledExternally(ctx context.Context, run *algoRun) bool {
+	if e.db == nil {
+		return false
+	}
+
+	run.mu.RLock()
+	parentID, err := uuid.Parse(run.order.ID)
+	run.mu.RUnlock()
+	if err != nil {
+		return false
+	}
+
+	parent, err := e.db.GetOrder(ctx, parentID)
+	if err != nil || parent.Status != db.OrderStatusCanceled {
+		return false
+	}
+
+	log.Info().Str("algo_order_id", parentID.String()).Msg("Algo order cancelled externally, stopping execution")
+	run.cancel()
+	return true
+}
+
+// persistParent stores the parent order in the orders table
+func (e *AlgoExecutor) persistParent(ctx context.Context, order *AlgoOrder) {
+	if e.db == nil {
+		return
+	}
+
+	orderID, _ := uuid.Parse(order.ID)
+	algoType := string(order.Request.Algo)
+	orderType := db.OrderTypeMarket
+	var price *float64
+	if order.Request.LimitPrice > 0 {
+		orderType = db.OrderTypeLimit
+		limitPrice := order.Request.LimitPrice
+		price = &limitPrice
+	}
+
+	dbOrder := &db.Order{
+		ID:        orderID,
+		SessionID: e.exchange.GetSession(),
+		Symbol:    order.Request.Symbol,
+		Exchange:  "ALGO",
+		Side:      db.ConvertOrderSide(string(order.Request.Side)),
+		Type:      orderType,
+		Status:    db.OrderStatusNew,
+		Price:     price,
+		Quantity:  order.Request.Quantity,
+		PlacedAt:  order.StartedAt,
+		Metadata: map[string]interface{}{
+			"algo":               algoType,
+			"duration_seconds":   order.Request.Duration.Seconds(),
+			"slices":             len(order.Children),
+			"participation_rate": order.Request.ParticipationRate,
+			"display_quantity":   order.Request.DisplayQuantity,
+		},
+		AlgoType:  &algoType,
+		CreatedAt: order.StartedAt,
+		UpdatedAt: order.StartedAt,
+	}
+
+	if err := e.db.InsertOrder(ctx, dbOrder); err != nil {
+		log.Error().
+			Err(err).
+			Str("algo_order_id", order.ID).
+			Msg("Failed to persist algo parent order to database")
+	}
+}
+
+// persistParentProgress updates the parent order's executed quantity in the database
+func (e *AlgoExecutor) persistParentProgress(ctx context.Context, run *algoRun) {
+	if e.db == nil {
+		return
+	}
+
+	run.mu.RLock()
+	order := *run.order
+	run.mu.RUnlock()
+
+	orderID, _ := uuid.Parse(order.ID)
+	err := e.db.UpdateOrderStatus(ctx, orderID, db.OrderStatusPartiallyFilled,
+		order.FilledQty, order.FilledQty*order.AvgFillPrice, nil, nil, nil)
+	if err != nil {
+		log.Error().Err(err).Str("algo_order_id", order.ID).Msg("Failed to update algo parent progress")
+	}
+}
+
+// persistParentFinal records the terminal state of the parent order in the database
+func (e *AlgoExecutor) persistParentFinal(ctx context.Context, order *AlgoOrder) {
+	if e.db == nil {
+		return
+	}
+
+	orderID, _ := uuid.Parse(order.ID)
+	quoteQty := order.FilledQty * order.AvgFillPrice
+
+	var status db.OrderStatus
+	var filledAt, canceledAt *time.Time
+	var errorMsg *string
+	switch order.Status {
+	case AlgoStatusCompleted:
+		status = db.OrderStatusFilled
+		filledAt = order.CompletedAt
+	case AlgoStatusFailed:
+		status = db.OrderStatusRejected
+		errorMsg = &order.Message
+	case AlgoStatusExpired:
+		status = db.OrderStatusExpired
+		errorMsg = &order.Message
+	default:
+		status = db.OrderStatusCanceled
+		canceledAt = order.CompletedAt
+	}
+
+	if err := e.db.UpdateOrderStatus(ctx, orderID, status, order.FilledQty, quoteQty, filledAt, canceledAt, errorMsg); err != nil {
+		log.Error().Err(err).Str("algo_order_id", order.ID).Msg("Failed to persist algo parent final state")
+	}
+}
+
+// recalculateFills aggregates child fills into the parent's filled quantity and average price
+func (a *AlgoOrder) recalculateFills() {
+	var totalQty, totalValue float64
+	for _, child := range a.Children {
+		totalQty += child.FilledQty
+		totalValue += child.FilledQty * child.AvgFillPrice
+	}
+
+	a.FilledQty = totalQty
+	if totalQty > 0 {
+		a.AvgFillPrice = totalValue / totalQty
+	}
+}
+
+// restingChildren returns the indexes of children placed and not yet done
+func (r *algoRun) restingChildren() []int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var resting []int
+	for i, child := range r.order.Children {
+		if child.OrderID != "" && (child.Status == OrderStatusOpen || child.Status == OrderStatusPending) {
+			resting = append(resting, i)
+		}
+	}
+	return resting
+}
+
+// restingQty returns the unfilled quantity of children placed and not yet done
+func (a *AlgoOrder) restingQty() float64 {
+	var qty float64
+	for _, child := range a.Children {
+		if child.OrderID != "" && (child.Status == OrderStatusOpen || child.Status == OrderStatusPending) {
+			qty += math.Max(child.Quantity-child.FilledQty, 0)
+		}
+	}
+	return qty
+}
+
+// hasRejectedChildren reports whether any child order was rejected
+func (a *AlgoOrder) hasRejectedChildren() bool {
+	for _, child := range a.Children {
+		if child.Status == OrderStatusRejected {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotAlgoOrder returns a deep copy of an algo order that is safe to share
+func snapshotAlgoOrder(run *algoRun) *AlgoOrder {
+	run.mu.RLock()
+	defer run.mu.RUnlock()
+
+	snapshot := *run.order
+	snapshot.Children = append([]ChildSlice(nil), run.order.Children...)
+	return &snapshot
+}
@@ -0,0 +1,513 @@
+package exchange
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVolumeSource returns fixed volume data for algo tests
+type fakeVolumeSource struct {
+	profile       []float64
+	volumePerCall float64
+}
+
+func (f *fakeVolumeSource) HourlyVolumeProfile(ctx context.Context, symbol string) ([]float64, error) {
+	return f.profile, nil
+}
+
+func (f *fakeVolumeSource) VolumeSince(ctx context.Context, symbol string, since time.Time) (float64, error) {
+	return f.volumePerCall, nil
+}
+
+// waitForAlgoStatus polls until the algo order leaves the running state
+func waitForAlgoStatus(t *testing.T, executor *AlgoExecutor, id string) *AlgoOrder {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		order, ok := executor.Get(id)
+		require.True(t, ok)
+		if order.Status != AlgoStatusRunning {
+			return order
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("algo order %s did not finish in time", id)
+	return nil
+}
+
+func TestValidateAlgoOrderRequest(t *testing.T) {
+	base := AlgoOrderRequest{
+		Symbol:   "BTCUSDT",
+		Side:     OrderSideBuy,
+		Quantity: 1.0,
+		Algo:     AlgoTWAP,
+		Duration: time.Minute,
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(r *AlgoOrderRequest)
+		wantErr string
+	}{
+		{name: "valid twap", modify: func(r *AlgoOrderRequest) {}},
+		{name: "missing symbol", modify: func(r *AlgoOrderRequest) { r.Symbol = "" }, wantErr: "symbol"},
+		{name: "invalid side", modify: func(r *AlgoOrderRequest) { r.Side = "hold" }, wantErr: "side"},
+		{name: "zero quantity", modify: func(r *AlgoOrderRequest) { r.Quantity = 0 }, wantErr: "quantity"},
+		{name: "unknown algo", modify: func(r *AlgoOrderRequest) { r.Algo = "sniper" }, wantErr: "unsupported"},
+		{name: "twap without duration", modify: func(r *AlgoOrderRequest) { r.Duration = 0 }, wantErr: "duration"},
+		{name: "pov without rate", modify: func(r *AlgoOrderRequest) { r.Algo = AlgoPOV }, wantErr: "participation_rate"},
+		{name: "pov rate above one", modify: func(r *AlgoOrderRequest) {
+			r.Algo = AlgoPOV
+			r.ParticipationRate = 1.5
+		}, wantErr: "participation_rate"},
+		{name: "iceberg without display", modify: func(r *AlgoOrderRequest) { r.Algo = AlgoIceberg }, wantErr: "display_quantity"},
+		{name: "iceberg display above quantity", modify: func(r *AlgoOrderRequest) {
+			r.Algo = AlgoIceberg
+			r.DisplayQuantity = 2.0
+		}, wantErr: "display_quantity"},
+		{name: "valid iceberg without duration", modify: func(r *AlgoOrderRequest) {
+			r.Algo = AlgoIceberg
+			r.DisplayQuantity = 0.25
+			r.Duration = 0
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := base
+			tt.modify(&req)
+			err := ValidateAlgoOrderRequest(req)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildTWAPSchedule(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	schedule := BuildTWAPSchedule(1.0, start, 10*time.Minute, 4)
+
+	require.Len(t, schedule, 4)
+	var total float64
+	for i, slice := range schedule {
+		assert.Equal(t, i, slice.Index)
+		assert.InDelta(t, 0.25, slice.Quantity, 1e-12)
+		assert.Equal(t, start.Add(time.Duration(i)*150*time.Second), slice.ScheduledAt)
+		total += slice.Quantity
+	}
+	assert.InDelta(t, 1.0, total, 1e-12)
+
+	// Default slice count when not specified
+	assert.Len(t, BuildTWAPSchedule(1.0, start, time.Minute, 0), defaultAlgoSlices)
+}
+
+func TestBuildVWAPSchedule(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("Weights follow volume profile", func(t *testing.T) {
+		profile := make([]float64, 24)
+		profile[10] = 100
+		profile[11] = 300
+
+		schedule := BuildVWAPSchedule(4.0, start, 2*time.Hour, 2, profile)
+		require.Len(t, schedule, 2)
+		assert.InDelta(t, 1.0, schedule[0].Quantity, 1e-9)
+		assert.InDelta(t, 3.0, schedule[1].Quantity, 1e-9)
+	})
+
+	t.Run("Falls back to TWAP without profile", func(t *testing.T) {
+		schedule := BuildVWAPSchedule(4.0, start, 2*time.Hour, 2, nil)
+		require.Len(t, schedule, 2)
+		assert.InDelta(t, 2.0, schedule[0].Quantity, 1e-9)
+		assert.InDelta(t, 2.0, schedule[1].Quantity, 1e-9)
+	})
+
+	t.Run("Falls back to TWAP with empty profile", func(t *testing.T) {
+		schedule := BuildVWAPSchedule(4.0, start, 2*time.Hour, 2, make([]float64, 24))
+		assert.InDelta(t, 2.0, schedule[0].Quantity, 1e-9)
+	})
+}
+
+func TestBuildIcebergSchedule(t *testing.T) {
+	start := time.Now()
+
+	schedule := BuildIcebergSchedule(1.0, 0.3, start)
+	require.Len(t, schedule, 4)
+	assert.InDelta(t, 0.3, schedule[0].Quantity, 1e-12)
+	assert.InDelta(t, 0.1, schedule[3].Quantity, 1e-9)
+
+	exact := BuildIcebergSchedule(1.0, 0.25, start)
+	assert.Len(t, exact, 4)
+
+	single := BuildIcebergSchedule(1.0, 1.0, start)
+	require.Len(t, single, 1)
+	assert.Equal(t, 1.0, single[0].Quantity)
+}
+
+func TestAlgoExecutor_TWAPCompletes(t *testing.T) {
+	mock := NewMockExchange(nil)
+	mock.SetMarketPrice("BTCUSDT", 50000.0)
+	executor := NewAlgoExecutor(mock, nil, nil)
+
+	var mu sync.Mutex
+	var filledChildren int
+	executor.SetFillHandler(func(ctx context.Context, order *Order, fills []Fill) {
+		mu.Lock()
+		defer mu.Unlock()
+		filledChildren++
+	})
+
+	order, err := executor.Submit(context.Background(), AlgoOrderRequest{
+		Symbol:   "BTCUSDT",
+		Side:     OrderSideBuy,
+		Quantity: 0.5,
+		Algo:     AlgoTWAP,
+		Duration: 50 * time.Millisecond,
+		Slices:   5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, AlgoStatusRunning, order.Status)
+
+	final := waitForAlgoStatus(t, executor, order.ID)
+	assert.Equal(t, AlgoStatusCompleted, final.Status)
+	assert.InDelta(t, 0.5, final.FilledQty, 1e-9)
+	assert.InDelta(t, 1.0, final.Progress(), 1e-9)
+	assert.Greater(t, final.AvgFillPrice, 50000.0, "Buy fills include slippage")
+	require.NotNil(t, final.CompletedAt)
+
+	for _, child := range final.Children {
+		assert.NotEmpty(t, child.OrderID)
+		assert.Equal(t, OrderStatusFilled, child.Status)
+
+		childOrder, err := mock.GetOrder(context.Background(), child.OrderID)
+		require.NoError(t, err)
+		assert.Equal(t, order.ID, childOrder.ParentOrderID)
+	}
+
+	mu.Lock()
+	assert.Equal(t, 5, filledChildren)
+	mu.Unlock()
+}
+
+func TestAlgoExecutor_IcebergMarketChildren(t *testing.T) {
+	mock := NewMockExchange(nil)
+	executor := NewAlgoExecutor(mock, nil, nil)
+	executor.SetPollInterval(5 * time.Millisecond)
+
+	order, err := executor.Submit(context.Background(), AlgoOrderRequest{
+		Symbol:          "ETHUSDT",
+		Side:            OrderSideSell,
+		Quantity:        1.0,
+		Algo:            AlgoIceberg,
+		DisplayQuantity: 0.4,
+	})
+	require.NoError(t, err)
+
+	final := waitForAlgoStatus(t, executor, order.ID)
+	assert.Equal(t, AlgoStatusCompleted, final.Status)
+	require.Len(t, final.Children, 3)
+	assert.InDelta(t, 1.0, final.FilledQty, 1e-9)
+}
+
+func TestAlgoExecutor_IcebergLimitExpires(t *testing.T) {
+	mock := NewMockExchange(nil)
+	executor := NewAlgoExecutor(mock, nil, nil)
+	executor.SetPollInterval(5 * time.Millisecond)
+
+	// Mock limit orders rest on the book, so the iceberg cannot progress past the first slice
+	order, err := executor.Submit(context.Background(), AlgoOrderRequest{
+		Symbol:          "BTCUSDT",
+		Side:            OrderSideBuy,
+		Quantity:        1.0,
+		Algo:            AlgoIceberg,
+		DisplayQuantity: 0.5,
+		LimitPrice:      40000.0,
+		Duration:        30 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	final := waitForAlgoStatus(t, executor, order.ID)
+	assert.Equal(t, AlgoStatusExpired, final.Status)
+	require.NotEmpty(t, final.Children)
+	assert.Equal(t, OrderStatusCancelled, final.Children[0].Status, "Resting child is cancelled at horizon end")
+	assert.Empty(t, final.Children[1].OrderID, "Hidden slices are never released")
+}
+
+// partialFillExchange lets a test fill resting mock orders a piece at a time
+type partialFillExchange struct {
+	*MockExchange
+
+	mu    sync.Mutex
+	fills map[string][]Fill
+}
+
+func newPartialFillExchange() *partialFillExchange {
+	return &partialFillExchange{MockExchange: NewMockExchange(nil), fills: make(map[string][]Fill)}
+}
+
+func (p *partialFillExchange) fill(orderID string, qty, price float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fills[orderID] = append(p.fills[orderID], Fill{OrderID: orderID, Quantity: qty, Price: price, Timestamp: time.Now()})
+}
+
+func (p *partialFillExchange) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	order, err := p.MockExchange.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	partial := *order
+	var value float64
+	for _, fill := range p.fills[orderID] {
+		partial.FilledQty += fill.Quantity
+		value += fill.Quantity * fill.Price
+	}
+	if partial.FilledQty > 0 {
+		partial.AvgFillPrice = value / partial.FilledQty
+	}
+	return &partial, nil
+}
+
+func (p *partialFillExchange) GetOrderFills(ctx context.Context, orderID string) ([]Fill, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Fill(nil), p.fills[orderID]...), nil
+}
+
+func TestAlgoExecutor_LimitTWAPPartialFills(t *testing.T) {
+	exchange := newPartialFillExchange()
+	executor := NewAlgoExecutor(exchange, nil, nil)
+	executor.SetPollInterval(5 * time.Millisecond)
+
+	var mu sync.Mutex
+	var reported []Fill
+	executor.SetFillHandler(func(ctx context.Context, order *Order, fills []Fill) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, fills...)
+	})
+
+	order, err := executor.Submit(context.Background(), AlgoOrderRequest{
+		Symbol:     "BTCUSDT",
+		Side:       OrderSideBuy,
+		Quantity:   1.0,
+		Algo:       AlgoTWAP,
+		Duration:   time.Second,
+		Slices:     2,
+		LimitPrice: 40000.0,
+	})
+	require.NoError(t, err)
+
+	childOrderID := func(i int) string {
+		current, _ := executor.Get(order.ID)
+		return current.Children[i].OrderID
+	}
+	filled := func(qty float64) func() bool {
+		return func() bool {
+			current, _ := executor.Get(order.ID)
+			return current.FilledQty >= qty-1e-9
+		}
+	}
+
+	// The first child fills in two pieces while the second is still scheduled
+	require.Eventually(t, func() bool { return childOrderID(0) != "" }, 5*time.Second, 5*time.Millisecond)
+	exchange.fill(childOrderID(0), 0.1, 40000.0)
+	require.Eventually(t, filled(0.1), 400*time.Millisecond, 5*time.Millisecond)
+	exchange.fill(childOrderID(0), 0.15, 39990.0)
+	require.Eventually(t, filled(0.25), 400*time.Millisecond, 5*time.Millisecond)
+
+	// The last child keeps being polled after it is placed, until the horizon
+	require.Eventually(t, func() bool { return childOrderID(1) != "" }, 5*time.Second, 5*time.Millisecond)
+	exchange.fill(childOrderID(1), 0.2, 40000.0)
+	require.Eventually(t, filled(0.45), 400*time.Millisecond, 5*time.Millisecond)
+
+	final := waitForAlgoStatus(t, executor, order.ID)
+	assert.Equal(t, AlgoStatusExpired, final.Status)
+	assert.InDelta(t, 0.45, final.FilledQty, 1e-9)
+	for _, child := range final.Children {
+		assert.Equal(t, OrderStatusCancelled, child.Status, "Partially filled children are cancelled at horizon end")
+	}
+	assert.WithinDuration(t, final.StartedAt.Add(time.Second), *final.CompletedAt, 500*time.Millisecond)
+
+	// Every fill reaches the handler exactly once
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, reported, 3)
+	var total float64
+	for _, fill := range reported {
+		total += fill.Quantity
+	}
+	assert.InDelta(t, 0.45, total, 1e-9)
+}
+
+func TestAlgoExecutor_Cancel(t *testing.T) {
+	mock := NewMockExchange(nil)
+	executor := NewAlgoExecutor(mock, nil, nil)
+
+	order, err := executor.Submit(context.Background(), AlgoOrderRequest{
+		Symbol:   "BTCUSDT",
+		Side:     OrderSideBuy,
+		Quantity: 1.0,
+		Algo:     AlgoTWAP,
+		Duration: time.Hour,
+		Slices:   4,
+	})
+	require.NoError(t, err)
+
+	// Wait for the first slice, which is due immediately
+	require.Eventually(t, func() bool {
+		current, _ := executor.Get(order.ID)
+		return current.FilledQty > 0
+	}, 5*time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cancelled, err := executor.Cancel(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, AlgoStatusCancelled, cancelled.Status)
+	assert.InDelta(t, 0.25, cancelled.FilledQty, 1e-9, "Later slices are never placed")
+
+	_, err = executor.Cancel(ctx, order.ID)
+	assert.Error(t, err, "Cannot cancel a finished algo order")
+
+	_, err = executor.Cancel(ctx, "missing")
+	assert.Error(t, err)
+}
+
+func TestAlgoExecutor_POV(t *testing.T) {
+	mock := NewMockExchange(nil)
+
+	t.Run("Requires volume source", func(t *testing.T) {
+		executor := NewAlgoExecutor(mock, nil, nil)
+		_, err := executor.Submit(context.Background(), AlgoOrderRequest{
+			Symbol:            "BTCUSDT",
+			Side:              OrderSideBuy,
+			Quantity:          1.0,
+			Algo:              AlgoPOV,
+			Duration:          time.Second,
+			ParticipationRate: 0.1,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Sizes children from market volume", func(t *testing.T) {
+		volume := &fakeVolumeSource{volumePerCall: 2.0}
+		executor := NewAlgoExecutor(mock, nil, volume)
+
+		order, err := executor.Submit(context.Background(), AlgoOrderRequest{
+			Symbol:            "BTCUSDT",
+			Side:              OrderSideBuy,
+			Quantity:          1.0,
+			Algo:              AlgoPOV,
+			Duration:          40 * time.Millisecond,
+			Slices:            4,
+			ParticipationRate: 0.1,
+		})
+		require.NoError(t, err)
+
+		final := waitForAlgoStatus(t, executor, order.ID)
+		assert.Equal(t, AlgoStatusCompleted, final.Status)
+		require.Len(t, final.Children, 4)
+		assert.InDelta(t, 0.2, final.Children[0].Quantity, 1e-9, "10% of 2.0 market volume")
+		assert.InDelta(t, 0.4, final.Children[3].Quantity, 1e-9, "Final check sweeps the remainder")
+		assert.InDelta(t, 1.0, final.FilledQty, 1e-9)
+	})
+
+	t.Run("Final sweep leaves resting limit children out", func(t *testing.T) {
+		exchange := newPartialFillExchange()
+		volume := &fakeVolumeSource{volumePerCall: 2.0}
+		executor := NewAlgoExecutor(exchange, nil, volume)
+		executor.SetPollInterval(5 * time.Millisecond)
+
+		order, err := executor.Submit(context.Background(), AlgoOrderRequest{
+			Symbol:            "BTCUSDT",
+			Side:              OrderSideBuy,
+			Quantity:          1.0,
+			Algo:              AlgoPOV,
+			Duration:          200 * time.Millisecond,
+			Slices:            4,
+			ParticipationRate: 0.1,
+			LimitPrice:        40000.0,
+		})
+		require.NoError(t, err)
+
+		// Every child fills in full once the final slice is out
+		require.Eventually(t, func() bool {
+			current, _ := executor.Get(order.ID)
+			return len(current.Children) == 4 && current.Children[3].OrderID != ""
+		}, 5*time.Second, 5*time.Millisecond)
+		current, _ := executor.Get(order.ID)
+		for _, child := range current.Children {
+			exchange.fill(child.OrderID, child.Quantity, 40000.0)
+		}
+
+		final := waitForAlgoStatus(t, executor, order.ID)
+		var placed float64
+		for _, child := range final.Children {
+			placed += child.Quantity
+		}
+		assert.InDelta(t, 1.0, placed, 1e-9, "Children never add up to more than the parent")
+		assert.InDelta(t, 0.4, final.Children[3].Quantity, 1e-9, "Final check sweeps only what is not resting")
+		assert.LessOrEqual(t, final.FilledQty, 1.0+1e-9)
+	})
+}
+
+func TestServiceAlgoOrders(t *testing.T) {
+	service := NewServicePaper(nil)
+	ctx := context.Background()
+
+	t.Run("Invalid algo", func(t *testing.T) {
+		_, err := service.PlaceAlgoOrder(ctx, map[string]interface{}{
+			"symbol":   "BTCUSDT",
+			"side":     "buy",
+			"quantity": 1.0,
+			"algo":     "unknown",
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing algo_order_id", func(t *testing.T) {
+		_, err := service.GetAlgoOrderStatus(ctx, map[string]interface{}{})
+		assert.Error(t, err)
+		_, err = service.CancelAlgoOrder(ctx, map[string]interface{}{})
+		assert.Error(t, err)
+	})
+
+	t.Run("Place, query and cancel", func(t *testing.T) {
+		result, err := service.PlaceAlgoOrder(ctx, map[string]interface{}{
+			"symbol":           "BTCUSDT",
+			"side":             "buy",
+			"quantity":         1.0,
+			"algo":             "twap",
+			"duration_seconds": 3600,
+			"slices":           10,
+		})
+		require.NoError(t, err)
+
+		placed := result.(map[string]interface{})
+		id := placed["algo_order_id"].(string)
+		assert.Equal(t, "twap", placed["algo"])
+
+		status, err := service.GetAlgoOrderStatus(ctx, map[string]interface{}{"algo_order_id": id})
+		require.NoError(t, err)
+		assert.Equal(t, id, status.(map[string]interface{})["algo_order_id"])
+
+		cancelled, err := service.CancelAlgoOrder(ctx, map[string]interface{}{"algo_order_id": id})
+		require.NoError(t, err)
+		assert.Equal(t, string(AlgoStatusCancelled), cancelled.(map[string]interface{})["status"])
+	})
+}
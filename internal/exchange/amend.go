@@ -0,0 +1,99 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// resolveAmendment validates an amendment against the order it changes and returns
+// the new limit price and total quantity, rounded to the instrument rules
+func resolveAmendment(order *Order, req AmendOrderRequest, instruments *InstrumentRegistry) (float64, float64, error) {
+	if req.Price < 0 || req.Quantity < 0 {
+		return 0, 0, fmt.Errorf("amended price and quantity must not be negative")
+	}
+	if req.Price == 0 && req.Quantity == 0 {
+		return 0, 0, fmt.Errorf("amendment must change price or quantity")
+	}
+	if order.Type != OrderTypeLimit {
+		return 0, 0, fmt.Errorf("only limit orders can be amended, order is %s", order.Type)
+	}
+	if order.Status != OrderStatusOpen && order.Status != OrderStatusPending {
+		return 0, 0, fmt.Errorf("cannot amend order in status: %s", order.Status)
+	}
+
+	amended := PlaceOrderRequest{
+		Symbol:   order.Symbol,
+		Side:     order.Side,
+		Type:     OrderTypeLimit,
+		Quantity: order.Quantity,
+		Price:    order.Price,
+	}
+	if req.Price > 0 {
+		amended.Price = req.Price
+	}
+	if req.Quantity > 0 {
+		amended.Quantity = req.Quantity
+	}
+	if instruments != nil {
+		if err := instruments.NormalizeOrder(&amended, 0); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if amended.Quantity <= order.FilledQty+algoQuantityEpsilon {
+		return 0, 0, fmt.Errorf("amended quantity %.8f must exceed filled quantity %.8f", amended.Quantity, order.FilledQty)
+	}
+	if math.Abs(amended.Price-order.Price) <= algoQuantityEpsilon && math.Abs(amended.Quantity-order.Quantity) <= algoQuantityEpsilon {
+		return 0, 0, fmt.Errorf("amendment does not change the order")
+	}
+
+	return amended.Price, amended.Quantity, nil
+}
+
+// applyAmendment updates an order in place and appends the change to its history
+func applyAmendment(order *Order, price, quantity float64, exchangeOrderID string) OrderAmendment {
+	amendment := OrderAmendment{
+		PreviousPrice:           order.Price,
+		PreviousQuantity:        order.Quantity,
+		Price:                   price,
+		Quantity:                quantity,
+		PreviousExchangeOrderID: order.ExchangeOrderID,
+		ExchangeOrderID:         exchangeOrderID,
+		AmendedAt:               time.Now(),
+	}
+
+	order.Price = price
+	order.Quantity = quantity
+	order.ExchangeOrderID = exchangeOrderID
+	order.UpdatedAt = amendment.AmendedAt
+	order.Amendments = append(order.Amendments, amendment)
+
+	return amendment
+}
+
+// persistAmendment records an amendment in the order's database history.
+// Failures are logged; the exchange state remains authoritative.
+func persistAmendment(ctx context.Context, database *db.DB, orderID string, amendment OrderAmendment) {
+	if database == nil {
+		return
+	}
+
+	orderUUID, err := uuid.Parse(orderID)
+	if err != nil {
+		return
+	}
+
+	if err := database.AmendOrder(ctx, orderUUID, db.OrderAmendment(amendment)); err != nil {
+		log.Error().
+			Err(err).
+			Str("order_id", orderID).
+			Msg("Failed to persist order amendment")
+	}
+}
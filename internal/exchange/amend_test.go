@@ -0,0 +1,187 @@
+package exchange
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockExchange_AmendOrder(t *testing.T) {
+	mock := NewMockExchange(nil)
+	ctx := context.Background()
+
+	resp, err := mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.1, Price: 40000})
+	require.NoError(t, err)
+
+	order, err := mock.AmendOrder(ctx, AmendOrderRequest{OrderID: resp.OrderID, Price: 39000, Quantity: 0.2})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusOpen, order.Status)
+	assert.InDelta(t, 39000.0, order.Price, 1e-9)
+	assert.InDelta(t, 0.2, order.Quantity, 1e-12)
+	require.Len(t, order.Amendments, 1)
+	assert.InDelta(t, 40000.0, order.Amendments[0].PreviousPrice, 1e-9)
+	assert.InDelta(t, 0.1, order.Amendments[0].PreviousQuantity, 1e-12)
+
+	// Funds are re-reserved at the amended price and size
+	account, err := mock.GetAccount(ctx)
+	require.NoError(t, err)
+	usdt := account.Balance("USDT")
+	assert.InDelta(t, 7800.0, usdt.Locked, 1e-9)
+	assert.InDelta(t, 2200.0, usdt.Free, 1e-9)
+
+	// Price-only amendments keep the quantity
+	order, err = mock.AmendOrder(ctx, AmendOrderRequest{OrderID: resp.OrderID, Price: 39500})
+	require.NoError(t, err)
+	assert.InDelta(t, 0.2, order.Quantity, 1e-12)
+	assert.Len(t, order.Amendments, 2)
+
+	_, err = mock.AmendOrder(ctx, AmendOrderRequest{OrderID: resp.OrderID, Price: 39500})
+	assert.ErrorContains(t, err, "does not change")
+	_, err = mock.AmendOrder(ctx, AmendOrderRequest{OrderID: resp.OrderID})
+	assert.Error(t, err)
+	_, err = mock.AmendOrder(ctx, AmendOrderRequest{OrderID: "missing", Price: 1})
+	assert.ErrorContains(t, err, "order not found")
+}
+
+func TestResolveAmendment_Rejections(t *testing.T) {
+	open := &Order{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Status: OrderStatusOpen, Price: 40000, Quantity: 1, FilledQty: 0.4}
+
+	_, _, err := resolveAmendment(&Order{Type: OrderTypeMarket, Status: OrderStatusOpen}, AmendOrderRequest{Price: 1}, nil)
+	assert.ErrorContains(t, err, "only limit orders")
+
+	filled := *open
+	filled.Status = OrderStatusFilled
+	_, _, err = resolveAmendment(&filled, AmendOrderRequest{Price: 41000}, nil)
+	assert.ErrorContains(t, err, "cannot amend")
+
+	_, _, err = resolveAmendment(open, AmendOrderRequest{Quantity: 0.4}, nil)
+	assert.ErrorContains(t, err, "must exceed filled quantity")
+
+	_, _, err = resolveAmendment(open, AmendOrderRequest{Price: -1}, nil)
+	assert.Error(t, err)
+
+	price, quantity, err := resolveAmendment(open, AmendOrderRequest{Quantity: 0.5}, nil)
+	require.NoError(t, err)
+	assert.InDelta(t, 40000.0, price, 1e-9)
+	assert.InDelta(t, 0.5, quantity, 1e-12)
+}
+
+// fakeCancelReplaceAPI answers spot cancel-replace calls in order and records
+// the parameters of every cancel-replace and cancel
+type fakeCancelReplaceAPI struct {
+	mu        sync.Mutex
+	responses []string
+	cancel    string
+	replaces  []url.Values
+	cancels   []url.Values
+}
+
+func (a *fakeCancelReplaceAPI) serve(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	if form, err := url.ParseQuery(string(body)); err == nil {
+		for k, v := range form {
+			params[k] = v
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v3/order/cancelReplace" && len(a.responses) > 0:
+		a.replaces = append(a.replaces, params)
+		_, _ = w.Write([]byte(a.responses[0]))
+		a.responses = a.responses[1:]
+	case r.Method == http.MethodDelete && r.URL.Path == "/api/v3/order":
+		a.cancels = append(a.cancels, params)
+		_, _ = w.Write([]byte(a.cancel))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":-1,"msg":"no route"}`))
+	}
+}
+
+// newAmendTestBinance returns a spot exchange on a fake API with one resting
+// limit order: BUY 1.0 BTCUSDT at 40000 with 0.2 filled, exchange order 100
+func newAmendTestBinance(t *testing.T, api *fakeCancelReplaceAPI) (*BinanceExchange, *Order) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(api.serve))
+	t.Cleanup(server.Close)
+
+	ex, err := NewBinanceExchange(BinanceConfig{APIKey: "key", SecretKey: "secret", BaseURL: server.URL}, nil)
+	require.NoError(t, err)
+	ex.instruments.Set(Instrument{
+		Symbol: "BTCUSDT", TickSize: 0.01, StepSize: 0.0001, MinQty: 0.0001, MinNotional: 10,
+		PricePrecision: 2, QuantityPrecision: 4,
+	})
+
+	order := &Order{
+		ID: "order-1", ExchangeOrderID: "100", Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit,
+		Status: OrderStatusOpen, Price: 40000, Quantity: 1.0, FilledQty: 0.2, AvgFillPrice: 40000,
+	}
+	ex.orders[order.ID] = order
+	ex.exchangeOrderToInternal[order.ExchangeOrderID] = order.ID
+	return ex, order
+}
+
+func TestBinanceExchange_AmendOrderResizesAfterFillDuringCancelReplace(t *testing.T) {
+	// The original fills to 0.5 while it is cancelled, so the first replacement
+	// (sized for 0.2 filled) is 0.3 too large and is resized to 0.3
+	api := &fakeCancelReplaceAPI{responses: []string{
+		`{"cancelResult":"SUCCESS","newOrderResult":"SUCCESS",
+			"cancelResponse":{"symbol":"BTCUSDT","orderId":100,"executedQty":"0.5","cummulativeQuoteQty":"20000","status":"CANCELED"},
+			"newOrderResponse":{"symbol":"BTCUSDT","orderId":101,"executedQty":"0","cummulativeQuoteQty":"0","status":"NEW"}}`,
+		`{"cancelResult":"SUCCESS","newOrderResult":"SUCCESS",
+			"cancelResponse":{"symbol":"BTCUSDT","orderId":101,"executedQty":"0","cummulativeQuoteQty":"0","status":"CANCELED"},
+			"newOrderResponse":{"symbol":"BTCUSDT","orderId":102,"executedQty":"0","cummulativeQuoteQty":"0","status":"NEW"}}`,
+	}}
+	ex, order := newAmendTestBinance(t, api)
+
+	amended, err := ex.AmendOrder(context.Background(), AmendOrderRequest{OrderID: order.ID, Quantity: 0.8, Price: 40100})
+	require.NoError(t, err)
+
+	require.Len(t, api.replaces, 2)
+	assert.Equal(t, "0.6000", api.replaces[0].Get("quantity"))
+	assert.Equal(t, "100", api.replaces[0].Get("cancelOrderId"))
+	assert.Equal(t, "0.3000", api.replaces[1].Get("quantity"))
+	assert.Equal(t, "101", api.replaces[1].Get("cancelOrderId"))
+	assert.Empty(t, api.cancels)
+
+	assert.Equal(t, "102", amended.ExchangeOrderID)
+	assert.Equal(t, OrderStatusOpen, amended.Status)
+	assert.InDelta(t, 0.5, amended.FilledQty, 1e-9)
+	assert.InDelta(t, 0.8, amended.Quantity, 1e-9)
+}
+
+func TestBinanceExchange_AmendOrderWithdrawsReplacementWhenFilled(t *testing.T) {
+	// The original fills to 0.5 while it is cancelled, which is all of the
+	// amended quantity, so the replacement is cancelled
+	api := &fakeCancelReplaceAPI{
+		responses: []string{
+			`{"cancelResult":"SUCCESS","newOrderResult":"SUCCESS",
+				"cancelResponse":{"symbol":"BTCUSDT","orderId":100,"executedQty":"0.5","cummulativeQuoteQty":"20000","status":"CANCELED"},
+				"newOrderResponse":{"symbol":"BTCUSDT","orderId":101,"executedQty":"0","cummulativeQuoteQty":"0","status":"NEW"}}`,
+		},
+		cancel: `{"symbol":"BTCUSDT","orderId":101,"executedQty":"0","cummulativeQuoteQty":"0","status":"CANCELED"}`,
+	}
+	ex, order := newAmendTestBinance(t, api)
+
+	amended, err := ex.AmendOrder(context.Background(), AmendOrderRequest{OrderID: order.ID, Quantity: 0.5})
+	require.NoError(t, err)
+
+	require.Len(t, api.replaces, 1)
+	assert.Equal(t, "0.3000", api.replaces[0].Get("quantity"))
+	require.Len(t, api.cancels, 1)
+	assert.Equal(t, "101", api.cancels[0].Get("orderId"))
+
+	assert.Equal(t, OrderStatusFilled, amended.Status)
+	assert.InDelta(t, 0.5, amended.FilledQty, 1e-9)
+	assert.InDelta(t, 40000, amended.AvgFillPrice, 1e-9)
+}
@@ -0,0 +1,103 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockExchange_DefaultBalance(t *testing.T) {
+	mock := NewMockExchange(nil)
+
+	balances, err := mock.GetBalances(context.Background())
+	require.NoError(t, err)
+	require.Len(t, balances, 1)
+	assert.Equal(t, "USDT", balances[0].Asset)
+	assert.Equal(t, 10000.0, balances[0].Free)
+
+	account, err := mock.GetAccount(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "mock", account.Exchange)
+	assert.True(t, account.CanTrade)
+	assert.Equal(t, 10000.0, account.Balance("USDT").Total())
+	assert.Zero(t, account.Balance("BTC").Total())
+}
+
+func TestMockExchange_BalancesOnMarketFills(t *testing.T) {
+	mock := NewMockExchange(nil) // 0.1% taker fee
+	mock.SetBalance("USDT", 100000)
+	mock.SetMarketPrice("BTCUSDT", 50000)
+	ctx := context.Background()
+
+	resp, err := mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.5})
+	require.NoError(t, err)
+	buy, err := mock.GetOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+
+	buyNotional := buy.AvgFillPrice * buy.FilledQty
+	account, err := mock.GetAccount(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, account.Balance("BTC").Free, 1e-12)
+	assert.InDelta(t, 100000-buyNotional*1.001, account.Balance("USDT").Free, 1e-6)
+
+	resp, err = mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 0.5})
+	require.NoError(t, err)
+	sell, err := mock.GetOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+
+	sellNotional := sell.AvgFillPrice * sell.FilledQty
+	account, err = mock.GetAccount(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 0, account.Balance("BTC").Free, 1e-12)
+	assert.InDelta(t, 100000-buyNotional*1.001+sellNotional*0.999, account.Balance("USDT").Free, 1e-6)
+}
+
+func TestMockExchange_LimitOrderLocksFunds(t *testing.T) {
+	mock := NewMockExchange(nil)
+	ctx := context.Background()
+
+	resp, err := mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTC/USDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.1, Price: 40000})
+	require.NoError(t, err)
+	require.Equal(t, OrderStatusOpen, resp.Status)
+
+	account, err := mock.GetAccount(ctx)
+	require.NoError(t, err)
+	usdt := account.Balance("USDT")
+	assert.InDelta(t, 6000.0, usdt.Free, 1e-9)
+	assert.InDelta(t, 4000.0, usdt.Locked, 1e-9)
+	assert.InDelta(t, 10000.0, usdt.Total(), 1e-9)
+
+	_, err = mock.CancelOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+
+	account, err = mock.GetAccount(ctx)
+	require.NoError(t, err)
+	usdt = account.Balance("USDT")
+	assert.InDelta(t, 10000.0, usdt.Free, 1e-9)
+	assert.Zero(t, usdt.Locked)
+}
+
+func TestInstrumentRegistry_Assets(t *testing.T) {
+	registry := NewInstrumentRegistry(DefaultInstruments()...)
+
+	tests := []struct {
+		symbol string
+		base   string
+		quote  string
+	}{
+		{"BTCUSDT", "BTC", "USDT"},
+		{"ETH/USDT", "ETH", "USDT"},
+		{"LINKUSDC", "LINK", "USDC"},
+		{"ETHBTC", "ETH", "BTC"},
+		{"UNKNOWN", "UNKNOWN", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			base, quote := registry.Assets(tt.symbol)
+			assert.Equal(t, tt.base, base)
+			assert.Equal(t, tt.quote, quote)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package exchange
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -16,6 +17,9 @@ import (
 	"github.com/ajitpratap0/cryptofunk/internal/db"
 )
 
+// binanceExchangeName identifies Binance in persisted metadata
+const binanceExchangeName = "binance"
+
 // BinanceExchange implements Exchange interface for real Binance trading
 type BinanceExchange struct {
 	client *binance.Client
@@ -27,12 +31,25 @@ type BinanceExchange struct {
 	fills                   map[string][]Fill // Internal UUID -> Fills
 	exchangeOrderToInternal map[string]string // Exchange OrderID -> Internal UUID
 
+	// Fills of exchange orders replaced by AmendOrder (cancel-replace), so the
+	// internal order's filled quantity spans every exchange order behind it
+	replacedExecutions map[string]execution // Internal UUID -> Execution
+
 	// Session tracking
 	currentSessionID *uuid.UUID
 
+	// Instrument rules loaded from exchangeInfo
+	instruments *InstrumentRegistry
+
+	// Request weight and order rate budget shared by all REST calls
+	rateBudget *RateBudget
+
 	// Configuration
 	testnet bool
 
+	// Session view of a shared venue connection (see forSession)
+	forked bool
+
 	// WebSocket
 	// TODO: Will be used in Phase 11 for WebSocket streaming
 	// wsClient    *binance.Client
@@ -48,6 +65,16 @@ type BinanceConfig struct {
 	APIKey    string
 	SecretKey string
 	Testnet   bool
+
+	// BaseURL overrides the REST endpoint, e.g. a cassette recorder or replay server (optional)
+	BaseURL string
+
+	// WsBaseURL overrides the user data stream endpoint (optional). The go-binance
+	// endpoint is process-wide, so this affects every spot client in the process.
+	WsBaseURL string
+
+	// RateLimits overrides the request weight and order limits (zero uses DefaultSpotRateLimits)
+	RateLimits RateLimits
 }
 
 // NewBinanceExchange creates a new Binance exchange client
@@ -63,12 +90,30 @@ func NewBinanceExchange(config BinanceConfig, database *db.DB) (*BinanceExchange
 		log.Warn().Msg("Binance exchange initialized (LIVE TRADING mode)")
 	}
 
+	if config.BaseURL != "" {
+		client.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	}
+	if config.WsBaseURL != "" {
+		binance.BaseWsMainURL = strings.TrimRight(config.WsBaseURL, "/")
+		binance.BaseWsTestnetURL = binance.BaseWsMainURL
+	}
+
+	limits := config.RateLimits
+	if limits.RequestWeight1m == 0 {
+		limits = DefaultSpotRateLimits()
+	}
+	rateBudget := NewRateBudget(binanceExchangeName, limits)
+	client.HTTPClient = rateBudget.HTTPClient(client.HTTPClient, binanceSpotRequestCost)
+
 	exchange := &BinanceExchange{
 		client:                  client,
 		db:                      database,
 		orders:                  make(map[string]*Order),
 		fills:                   make(map[string][]Fill),
 		exchangeOrderToInternal: make(map[string]string),
+		replacedExecutions:      make(map[string]execution),
+		instruments:             NewInstrumentRegistry(),
+		rateBudget:              rateBudget,
 		testnet:                 config.Testnet,
 		wsStopChan:              make(chan struct{}),
 		wsErrChan:               make(chan error, 10),
@@ -78,8 +123,36 @@ func NewBinanceExchange(config BinanceConfig, database *db.DB) (*BinanceExchange
 	return exchange, nil
 }
 
+// forSession returns an exchange for one trading session on the same client,
+// rate budget and instrument rules. Orders, fills and the session are tracked
+// separately, so sessions stay isolated while sharing the per-IP limits.
+func (b *BinanceExchange) forSession() Exchange {
+	return &BinanceExchange{
+		client:                  b.client,
+		db:                      b.db,
+		orders:                  make(map[string]*Order),
+		fills:                   make(map[string][]Fill),
+		exchangeOrderToInternal: make(map[string]string),
+		replacedExecutions:      make(map[string]execution),
+		instruments:             b.instruments,
+		rateBudget:              b.rateBudget,
+		testnet:                 b.testnet,
+		forked:                  true,
+		wsStopChan:              make(chan struct{}),
+		wsErrChan:               make(chan error, 10),
+		positionMgr:             NewPositionManager(b.db),
+	}
+}
+
 // PlaceOrder places a new order on Binance
 func (b *BinanceExchange) PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*PlaceOrderResponse, error) {
+	// Lazily load instrument rules so orders are rounded before submission
+	if b.instruments.Len() == 0 {
+		if err := b.LoadInstruments(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to load instrument rules, submitting without precision checks")
+		}
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
@@ -97,6 +170,21 @@ func (b *BinanceExchange) PlaceOrder(ctx context.Context, req PlaceOrderRequest)
 		}, nil
 	}
 
+	// Round to tick/lot size and enforce exchange minimums before hitting the API
+	if err := b.instruments.NormalizeOrder(&req, 0); err != nil {
+		log.Warn().
+			Err(err).
+			Str("symbol", req.Symbol).
+			Float64("quantity", req.Quantity).
+			Msg("Order rejected by instrument rules")
+
+		return &PlaceOrderResponse{
+			Status:  OrderStatusRejected,
+			Message: err.Error(),
+		}, nil
+	}
+	quantityStr, priceStr := b.formatOrderValues(req)
+
 	// Create Binance order with retry logic
 	var binanceOrder *binance.CreateOrderResponse
 	var err error
@@ -114,7 +202,7 @@ func (b *BinanceExchange) PlaceOrder(ctx context.Context, req PlaceOrderRequest)
 				Symbol(req.Symbol).
 				Side(side).
 				Type(binance.OrderTypeMarket).
-				Quantity(fmt.Sprintf("%.8f", req.Quantity)).
+				Quantity(quantityStr).
 				Do(ctx)
 		} else {
 			// Limit order
@@ -123,8 +211,8 @@ func (b *BinanceExchange) PlaceOrder(ctx context.Context, req PlaceOrderRequest)
 				Side(side).
 				Type(binance.OrderTypeLimit).
 				TimeInForce(binance.TimeInForceTypeGTC).
-				Quantity(fmt.Sprintf("%.8f", req.Quantity)).
-				Price(fmt.Sprintf("%.8f", req.Price)).
+				Quantity(quantityStr).
+				Price(priceStr).
 				Do(ctx)
 		}
 		return err
@@ -256,6 +344,315 @@ func (b *BinanceExchange) CancelOrder(ctx context.Context, orderID string) (*Ord
 	return order, nil
 }
 
+// AmendOrder moves a resting limit order with Binance's native cancel-replace.
+// The replacement order carries the unfilled remainder and becomes the exchange
+// order behind the same internal order ID.
+func (b *BinanceExchange) AmendOrder(ctx context.Context, req AmendOrderRequest) (*Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	order, exists := b.orders[req.OrderID]
+	if !exists {
+		return nil, fmt.Errorf("order not found: %s", req.OrderID)
+	}
+
+	price, quantity, err := resolveAmendment(order, req, b.instruments)
+	if err != nil {
+		return nil, err
+	}
+
+	binanceOrderID, err := strconv.ParseInt(order.ExchangeOrderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exchange order ID format: %w", err)
+	}
+
+	quantityStr, priceStr := b.formatOrderValues(PlaceOrderRequest{
+		Symbol:   order.Symbol,
+		Quantity: quantity - order.FilledQty,
+		Price:    price,
+	})
+	side := binance.SideTypeBuy
+	if order.Side == OrderSideSell {
+		side = binance.SideTypeSell
+	}
+
+	// Everything a replaced exchange order filled stays with the internal order.
+	// Each replacement is sized from the fills known before the call, so fills
+	// that land during a cancel-replace are taken off the replacement with
+	// another cancel-replace, or it is cancelled when nothing is left.
+	cancelID := binanceOrderID
+	assumedQty := order.FilledQty - b.replacedExecutions[order.ID].qty
+	var newOrder *binance.CreateOrderResponse
+	var resizeErr error
+	for attempt := 0; attempt <= maxAmendResizes; attempt++ {
+		resp, err := b.cancelReplace(ctx, order.Symbol, side, cancelID, quantityStr, priceStr)
+		if err == nil && resp.NewOrderResponse == nil {
+			err = fmt.Errorf("cancel %s, new order %s", resp.CancelResult, resp.NewOrderResult)
+		}
+		if err != nil {
+			if newOrder == nil {
+				log.Error().
+					Err(err).
+					Str("order_id", order.ID).
+					Msg("Failed to amend order on Binance after retries")
+				return nil, fmt.Errorf("failed to amend order: %w", err)
+			}
+			resizeErr = err
+			break
+		}
+		newOrder = resp.NewOrderResponse
+
+		executedQty := 0.0
+		if cancelled := resp.CancelResponse; cancelled != nil {
+			executedQty = parseFloatOrZero(cancelled.ExecutedQuantity)
+			b.addReplacedExecution(order.ID, executedQty, parseFloatOrZero(cancelled.CummulativeQuoteQuantity))
+		}
+		if executedQty <= assumedQty+algoQuantityEpsilon {
+			break
+		}
+		if attempt == maxAmendResizes {
+			log.Warn().
+				Str("order_id", order.ID).
+				Msg("Order kept filling during cancel-replace; replacement may overfill the amended quantity")
+			break
+		}
+
+		// Resize the replacement to what is left of the amended quantity
+		assumedQty = parseFloatOrZero(newOrder.ExecutedQuantity)
+		remaining := quantity - b.replacedExecutions[order.ID].qty - assumedQty
+		log.Warn().
+			Str("order_id", order.ID).
+			Float64("executed_qty", executedQty).
+			Float64("remaining_qty", remaining).
+			Msg("Order filled during cancel-replace, resizing the replacement")
+
+		cancelID = newOrder.OrderID
+		quantityStr, _ = b.formatOrderValues(PlaceOrderRequest{Symbol: order.Symbol, Quantity: remaining, Price: price})
+		if parseFloatOrZero(quantityStr) <= 0 {
+			// The amended quantity has filled: withdraw the replacement
+			if err := b.cancelReplacement(ctx, order, newOrder); err != nil {
+				resizeErr = err
+			}
+			break
+		}
+	}
+
+	exchangeOrderID := strconv.FormatInt(newOrder.OrderID, 10)
+	b.exchangeOrderToInternal[exchangeOrderID] = order.ID
+	amendment := applyAmendment(order, price, quantity, exchangeOrderID)
+	b.setExecuted(order, parseFloatOrZero(newOrder.ExecutedQuantity), parseFloatOrZero(newOrder.CummulativeQuoteQuantity))
+	switch {
+	case newOrder.Status == binance.OrderStatusTypeFilled:
+		order.Status = OrderStatusFilled
+	case newOrder.Status == binance.OrderStatusTypeCanceled:
+		order.Status = OrderStatusCancelled
+		if order.FilledQty >= quantity-algoQuantityEpsilon {
+			order.Status = OrderStatusFilled
+		}
+	}
+
+	persistAmendment(ctx, b.db, order.ID, amendment)
+
+	log.Info().
+		Str("order_id", order.ID).
+		Str("previous_exchange_order_id", amendment.PreviousExchangeOrderID).
+		Str("exchange_order_id", exchangeOrderID).
+		Float64("price", price).
+		Float64("quantity", quantity).
+		Msg("Order amended on Binance")
+
+	if resizeErr != nil {
+		return order, fmt.Errorf("order filled during amend and the replacement could not be resized: %w", resizeErr)
+	}
+	return order, nil
+}
+
+// maxAmendResizes bounds the follow-up cancel-replaces that take fills landing
+// during an amendment off its replacement order
+const maxAmendResizes = 3
+
+// cancelReplace atomically cancels an exchange order and places a limit order
+// in its place. STOP_ON_FAILURE leaves the original order untouched if it
+// cannot be cancelled.
+func (b *BinanceExchange) cancelReplace(ctx context.Context, symbol string, side binance.SideType, cancelID int64, quantity, price string) (*binance.CancelReplaceOrderResponse, error) {
+	var resp *binance.CancelReplaceOrderResponse
+	err := retryWithBackoff(func() error {
+		var err error
+		resp, err = b.client.NewCancelReplaceOrderService().
+			Symbol(symbol).
+			Side(side).
+			Type(binance.OrderTypeLimit).
+			CancelReplaceMode(binance.CancelReplaceModeStopOnFailure).
+			CancelOrderID(cancelID).
+			TimeInForce(binance.TimeInForceTypeGTC).
+			Quantity(quantity).
+			Price(price).
+			Do(ctx)
+		return err
+	}, fmt.Sprintf("amend_order_%s", symbol))
+	return resp, err
+}
+
+// cancelReplacement cancels a replacement order that is no longer needed and
+// moves its fills to the order's replaced executions (caller must hold lock)
+func (b *BinanceExchange) cancelReplacement(ctx context.Context, order *Order, replacement *binance.CreateOrderResponse) error {
+	var cancelled *binance.CancelOrderResponse
+	err := retryWithBackoff(func() error {
+		var err error
+		cancelled, err = b.client.NewCancelOrderService().
+			Symbol(order.Symbol).
+			OrderID(replacement.OrderID).
+			Do(ctx)
+		return err
+	}, fmt.Sprintf("cancel_order_%s", order.Symbol))
+	if err != nil {
+		return err
+	}
+
+	b.addReplacedExecution(order.ID, parseFloatOrZero(cancelled.ExecutedQuantity), parseFloatOrZero(cancelled.CummulativeQuoteQuantity))
+	replacement.ExecutedQuantity = "0"
+	replacement.CummulativeQuoteQuantity = "0"
+	replacement.Status = binance.OrderStatusTypeCanceled
+	return nil
+}
+
+// addReplacedExecution records the fills of an exchange order that no longer
+// backs an internal order (caller must hold lock)
+func (b *BinanceExchange) addReplacedExecution(orderID string, qty, quote float64) {
+	replaced := b.replacedExecutions[orderID]
+	replaced.qty += qty
+	replaced.quote += quote
+	b.replacedExecutions[orderID] = replaced
+}
+
+// execution is the filled quantity and quote value of one or more exchange orders
+type execution struct {
+	qty   float64
+	quote float64
+}
+
+// setExecuted sets an order's filled quantity and average price from its current
+// exchange order plus any exchange orders it replaced (caller must hold lock)
+func (b *BinanceExchange) setExecuted(order *Order, executedQty, quoteQty float64) {
+	replaced := b.replacedExecutions[order.ID]
+	order.FilledQty = replaced.qty + executedQty
+	order.AvgFillPrice = 0
+	if order.FilledQty > 0 {
+		order.AvgFillPrice = (replaced.quote + quoteQty) / order.FilledQty
+	}
+}
+
+// CancelAllOrders cancels every open order on Binance for a symbol, or across all symbols when symbol is empty.
+// Orders placed outside this process are included; they are returned keyed by their exchange order ID.
+func (b *BinanceExchange) CancelAllOrders(ctx context.Context, symbol string) ([]*Order, error) {
+	symbols := []string{}
+	if symbol != "" {
+		symbols = append(symbols, NormalizeSymbol(symbol))
+	} else {
+		var openOrders []*binance.Order
+		err := retryWithBackoff(func() error {
+			var err error
+			openOrders, err = b.client.NewListOpenOrdersService().Do(ctx)
+			return err
+		}, "list_open_orders")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list open orders: %w", err)
+		}
+
+		seen := make(map[string]bool)
+		for _, o := range openOrders {
+			if !seen[o.Symbol] {
+				seen[o.Symbol] = true
+				symbols = append(symbols, o.Symbol)
+			}
+		}
+	}
+
+	cancelled := make([]*Order, 0)
+	for _, sym := range symbols {
+		var resp *binance.CancelOpenOrdersResponse
+		operationName := fmt.Sprintf("cancel_open_orders_%s", sym)
+		err := retryWithBackoff(func() error {
+			var err error
+			resp, err = b.client.NewCancelOpenOrdersService().Symbol(sym).Do(ctx)
+			return err
+		}, operationName)
+		if err != nil {
+			return cancelled, fmt.Errorf("failed to cancel open orders for %s: %w", sym, err)
+		}
+
+		for _, co := range resp.Orders {
+			cancelled = append(cancelled, b.markCancelled(ctx, co))
+		}
+	}
+
+	log.Info().
+		Int("count", len(cancelled)).
+		Strs("symbols", symbols).
+		Msg("Cancelled all open orders on Binance")
+
+	return cancelled, nil
+}
+
+// markCancelled records a bulk cancellation against the tracked order, if any
+func (b *BinanceExchange) markCancelled(ctx context.Context, co *binance.CancelOrderResponse) *Order {
+	exchangeOrderID := strconv.FormatInt(co.OrderID, 10)
+	cancelledAt := time.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	internalID, tracked := b.exchangeOrderToInternal[exchangeOrderID]
+	if !tracked {
+		return &Order{
+			ID:              exchangeOrderID,
+			ExchangeOrderID: exchangeOrderID,
+			Symbol:          co.Symbol,
+			Side:            OrderSide(strings.ToLower(string(co.Side))),
+			Type:            OrderType(strings.ToLower(string(co.Type))),
+			Quantity:        parseFloatOrZero(co.OrigQuantity),
+			Price:           parseFloatOrZero(co.Price),
+			FilledQty:       parseFloatOrZero(co.ExecutedQuantity),
+			Status:          OrderStatusCancelled,
+			UpdatedAt:       cancelledAt,
+		}
+	}
+
+	order := b.orders[internalID]
+	order.Status = OrderStatusCancelled
+	order.FilledQty = parseFloatOrZero(co.ExecutedQuantity)
+	order.UpdatedAt = cancelledAt
+
+	if b.db != nil {
+		orderUUID, _ := uuid.Parse(internalID)
+		err := b.db.UpdateOrderStatus(
+			ctx,
+			orderUUID,
+			db.ConvertOrderStatus(string(order.Status)),
+			order.FilledQty,
+			parseFloatOrZero(co.CummulativeQuoteQuantity),
+			order.FilledAt,
+			&cancelledAt,
+			nil,
+		)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("order_id", internalID).
+				Msg("Failed to update cancelled order in database")
+		}
+	}
+
+	return order
+}
+
+// OpenOrderCount returns the number of resting orders placed through this client
+func (b *BinanceExchange) OpenOrderCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return countOpenOrders(b.orders)
+}
+
 // GetOrder retrieves order details from Binance
 func (b *BinanceExchange) GetOrder(ctx context.Context, orderID string) (*Order, error) {
 	b.mu.RLock()
@@ -345,6 +742,188 @@ func (b *BinanceExchange) GetSession() *uuid.UUID {
 	return b.currentSessionID
 }
 
+// GetBalances returns non-zero asset balances from the Binance account
+func (b *BinanceExchange) GetBalances(ctx context.Context) ([]Balance, error) {
+	account, err := b.GetAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return account.Balances, nil
+}
+
+// GetAccount retrieves account permissions and non-zero balances from Binance
+func (b *BinanceExchange) GetAccount(ctx context.Context) (*Account, error) {
+	var binanceAccount *binance.Account
+	err := retryWithBackoff(func() error {
+		var err error
+		binanceAccount, err = b.client.NewGetAccountService().Do(ctx)
+		return err
+	}, "get_account")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	balances := make([]Balance, 0)
+	for _, bal := range binanceAccount.Balances {
+		balance := Balance{
+			Asset:  bal.Asset,
+			Free:   parseFloatOrZero(bal.Free),
+			Locked: parseFloatOrZero(bal.Locked),
+		}
+		if balance.Total() == 0 {
+			continue
+		}
+		balances = append(balances, balance)
+	}
+
+	updatedAt := time.Now()
+	if binanceAccount.UpdateTime > 0 {
+		updatedAt = time.UnixMilli(int64(binanceAccount.UpdateTime))
+	}
+
+	return &Account{
+		Exchange:    binanceExchangeName,
+		AccountType: binanceAccount.AccountType,
+		CanTrade:    binanceAccount.CanTrade,
+		Balances:    balances,
+		UpdatedAt:   updatedAt,
+	}, nil
+}
+
+// RateBudget returns the request weight and order rate budget
+func (b *BinanceExchange) RateBudget() *RateBudget {
+	return b.rateBudget
+}
+
+// GetInstrument returns the trading rules for a symbol
+func (b *BinanceExchange) GetInstrument(symbol string) (*Instrument, bool) {
+	return b.instruments.Get(symbol)
+}
+
+// GetQuote returns the best bid and ask from the book ticker endpoint
+func (b *BinanceExchange) GetQuote(ctx context.Context, symbol string) (*Quote, error) {
+	symbol = NormalizeSymbol(symbol)
+
+	var tickers []*binance.BookTicker
+	err := retryWithBackoff(func() error {
+		var err error
+		tickers, err = b.client.NewListBookTickersService().Symbol(symbol).Do(ctx)
+		return err
+	}, fmt.Sprintf("book_ticker_%s", symbol))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book ticker for %s: %w", symbol, err)
+	}
+
+	for _, ticker := range tickers {
+		if ticker.Symbol != symbol {
+			continue
+		}
+		return &Quote{
+			Symbol:  ticker.Symbol,
+			Bid:     parseFloatOrZero(ticker.BidPrice),
+			BidSize: parseFloatOrZero(ticker.BidQuantity),
+			Ask:     parseFloatOrZero(ticker.AskPrice),
+			AskSize: parseFloatOrZero(ticker.AskQuantity),
+			Time:    time.Now(),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no book ticker for symbol %s", symbol)
+}
+
+// Instruments returns the instrument registry backing order validation
+func (b *BinanceExchange) Instruments() *InstrumentRegistry {
+	return b.instruments
+}
+
+// LoadInstruments fetches tick size, lot size and min notional rules for all
+// trading symbols from the exchangeInfo endpoint
+func (b *BinanceExchange) LoadInstruments(ctx context.Context) error {
+	var info *binance.ExchangeInfo
+	err := retryWithBackoff(func() error {
+		var err error
+		info, err = b.client.NewExchangeInfoService().Do(ctx)
+		return err
+	}, "exchange_info")
+	if err != nil {
+		return fmt.Errorf("failed to fetch exchange info: %w", err)
+	}
+
+	loaded := 0
+	for i := range info.Symbols {
+		symbol := &info.Symbols[i]
+		if symbol.Status != string(binance.SymbolStatusTypeTrading) {
+			continue
+		}
+		b.instruments.Set(instrumentFromBinanceSymbol(symbol))
+		loaded++
+	}
+
+	log.Info().Int("instruments", loaded).Msg("Loaded Binance instrument rules")
+	return nil
+}
+
+// instrumentFromBinanceSymbol converts exchangeInfo symbol filters into an Instrument
+func instrumentFromBinanceSymbol(symbol *binance.Symbol) Instrument {
+	inst := Instrument{
+		Symbol:            symbol.Symbol,
+		BaseAsset:         symbol.BaseAsset,
+		QuoteAsset:        symbol.QuoteAsset,
+		PricePrecision:    symbol.QuotePrecision,
+		QuantityPrecision: symbol.BaseAssetPrecision,
+	}
+
+	if f := symbol.PriceFilter(); f != nil {
+		inst.TickSize = parseFloatOrZero(f.TickSize)
+		inst.MinPrice = parseFloatOrZero(f.MinPrice)
+		inst.MaxPrice = parseFloatOrZero(f.MaxPrice)
+		if inst.TickSize > 0 {
+			inst.PricePrecision = precisionFromString(f.TickSize)
+		}
+	}
+
+	if f := symbol.LotSizeFilter(); f != nil {
+		inst.StepSize = parseFloatOrZero(f.StepSize)
+		inst.MinQty = parseFloatOrZero(f.MinQuantity)
+		inst.MaxQty = parseFloatOrZero(f.MaxQuantity)
+		if inst.StepSize > 0 {
+			inst.QuantityPrecision = precisionFromString(f.StepSize)
+		}
+	}
+
+	if f := symbol.NotionalFilter(); f != nil {
+		inst.MinNotional = parseFloatOrZero(f.MinNotional)
+	} else {
+		// Older symbols still report the legacy MIN_NOTIONAL filter
+		for _, filter := range symbol.Filters {
+			if filter["filterType"] == "MIN_NOTIONAL" {
+				if v, ok := filter["minNotional"].(string); ok {
+					inst.MinNotional = parseFloatOrZero(v)
+				}
+			}
+		}
+	}
+
+	return inst
+}
+
+// formatOrderValues renders quantity and price with the instrument precision,
+// falling back to 8 decimals for symbols without loaded rules
+func (b *BinanceExchange) formatOrderValues(req PlaceOrderRequest) (string, string) {
+	if inst, ok := b.instruments.Get(req.Symbol); ok {
+		return inst.FormatQuantity(req.Quantity), inst.FormatPrice(req.Price)
+	}
+	return fmt.Sprintf("%.8f", req.Quantity), fmt.Sprintf("%.8f", req.Price)
+}
+
+func parseFloatOrZero(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
 // Helper methods
 
 // retryConfig holds retry configuration
@@ -359,6 +938,11 @@ func isRetryableError(err error) bool {
 		return false
 	}
 
+	// Shed by the local rate budget; retrying would only add load
+	if errors.Is(err, ErrRateBudgetExhausted) {
+		return false
+	}
+
 	errStr := err.Error()
 
 	// Network errors
@@ -445,6 +1029,11 @@ func retryWithBackoff(operation func() error, operationName string) error {
 }
 
 func (b *BinanceExchange) validateOrder(req PlaceOrderRequest) error {
+	return validatePlaceOrderRequest(req)
+}
+
+// validatePlaceOrderRequest checks the fields every exchange order needs
+func validatePlaceOrderRequest(req PlaceOrderRequest) error {
 	if req.Symbol == "" {
 		return fmt.Errorf("symbol is required")
 	}
@@ -515,6 +1104,8 @@ func (b *BinanceExchange) convertBinanceOrder(binanceOrder *binance.CreateOrderR
 		Status:          status,
 		CreatedAt:       now,
 		UpdatedAt:       now,
+		ParentOrderID:   req.ParentOrderID,
+		TaxLotIDs:       req.TaxLotIDs,
 	}
 }
 
@@ -523,15 +1114,8 @@ func (b *BinanceExchange) updateOrderFromBinance(order *Order, binanceOrder *bin
 	executedQty, _ := strconv.ParseFloat(binanceOrder.ExecutedQuantity, 64)
 	cummulativeQuoteQty, _ := strconv.ParseFloat(binanceOrder.CummulativeQuoteQuantity, 64)
 
-	// Calculate average fill price
-	var avgFillPrice float64
-	if executedQty > 0 {
-		avgFillPrice = cummulativeQuoteQty / executedQty
-	}
-
 	// Update order fields
-	order.FilledQty = executedQty
-	order.AvgFillPrice = avgFillPrice
+	b.setExecuted(order, executedQty, cummulativeQuoteQty)
 	order.UpdatedAt = time.Now()
 
 	// Map status
@@ -559,6 +1143,13 @@ func (b *BinanceExchange) convertToDBOrder(order *Order) *db.Order {
 		price = &order.Price
 	}
 
+	var parentOrderID *uuid.UUID
+	if order.ParentOrderID != "" {
+		if parsed, err := uuid.Parse(order.ParentOrderID); err == nil {
+			parentOrderID = &parsed
+		}
+	}
+
 	exchangeName := "BINANCE"
 	if b.testnet {
 		exchangeName = "BINANCE_TESTNET"
@@ -585,6 +1176,7 @@ func (b *BinanceExchange) convertToDBOrder(order *Order) *db.Order {
 		CanceledAt:            nil,
 		ErrorMessage:          nil,
 		Metadata:              nil,
+		ParentOrderID:         parentOrderID,
 		CreatedAt:             order.CreatedAt,
 		UpdatedAt:             order.UpdatedAt,
 	}
@@ -604,6 +1196,7 @@ func (b *BinanceExchange) StartUserDataStream(ctx context.Context) error {
 	b.wsConnected = true
 	// Recreate stop channel for this new stream
 	b.wsStopChan = make(chan struct{})
+	stopChan := b.wsStopChan
 	b.mu.Unlock()
 
 	// Create listen key for user data stream
@@ -625,10 +1218,10 @@ func (b *BinanceExchange) StartUserDataStream(ctx context.Context) error {
 		Msg("User data stream listen key created")
 
 	// Start WebSocket handler
-	go b.runUserDataStream(ctx, listenKey)
+	go b.runUserDataStream(ctx, listenKey, stopChan)
 
 	// Start listen key keep-alive goroutine
-	go b.keepAliveListenKey(ctx)
+	go b.keepAliveListenKey(ctx, stopChan)
 
 	return nil
 }
@@ -642,14 +1235,16 @@ func (b *BinanceExchange) StopUserDataStream(ctx context.Context) error {
 	}
 
 	listenKey := b.listenKey
+	stopChan := b.wsStopChan
 	b.wsConnected = false
 	b.mu.Unlock()
 
 	// Signal stop
-	close(b.wsStopChan)
+	close(stopChan)
 
-	// Close listen key
-	if listenKey != "" {
+	// Close listen key; the account's key is shared with other sessions of a
+	// forked exchange, so only the venue's own exchange closes it
+	if listenKey != "" && !b.forked {
 		err := b.client.NewCloseUserStreamService().ListenKey(listenKey).Do(ctx)
 		if err != nil {
 			log.Warn().Err(err).Msg("Failed to close listen key")
@@ -661,7 +1256,7 @@ func (b *BinanceExchange) StopUserDataStream(ctx context.Context) error {
 }
 
 // runUserDataStream handles the WebSocket connection
-func (b *BinanceExchange) runUserDataStream(ctx context.Context, listenKey string) {
+func (b *BinanceExchange) runUserDataStream(ctx context.Context, listenKey string, stopChan chan struct{}) {
 	defer func() {
 		b.mu.Lock()
 		b.wsConnected = false
@@ -701,7 +1296,7 @@ func (b *BinanceExchange) runUserDataStream(ctx context.Context, listenKey strin
 
 	// Wait for stop signal or context cancellation
 	select {
-	case <-b.wsStopChan:
+	case <-stopChan:
 		log.Info().Msg("Stop signal received, closing WebSocket")
 		stopC <- struct{}{}
 	case <-ctx.Done():
@@ -720,6 +1315,7 @@ func (b *BinanceExchange) handleUserDataEvent(event *binance.WsUserDataEvent) {
 		log.Debug().
 			Int("balance_count", len(event.AccountUpdate.WsAccountUpdates)).
 			Msg("Account position update received")
+		b.handleAccountUpdate(event)
 
 	case binance.UserDataEventTypeExecutionReport:
 		// Order update
@@ -739,6 +1335,30 @@ func (b *BinanceExchange) handleUserDataEvent(event *binance.WsUserDataEvent) {
 	}
 }
 
+// handleAccountUpdate persists balances changed by an outboundAccountPosition event
+func (b *BinanceExchange) handleAccountUpdate(event *binance.WsUserDataEvent) {
+	if b.db == nil {
+		return
+	}
+
+	rows := make([]*db.AccountBalance, 0, len(event.AccountUpdate.WsAccountUpdates))
+	for _, update := range event.AccountUpdate.WsAccountUpdates {
+		rows = append(rows, &db.AccountBalance{
+			Exchange: binanceExchangeName,
+			Asset:    update.Asset,
+			Free:     parseFloatOrZero(update.Free),
+			Locked:   parseFloatOrZero(update.Locked),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := b.db.UpsertAccountBalances(ctx, rows); err != nil {
+		log.Error().Err(err).Msg("Failed to persist account balance update")
+	}
+}
+
 // handleOrderUpdate processes order execution reports
 func (b *BinanceExchange) handleOrderUpdate(event *binance.WsUserDataEvent) {
 	orderUpdate := event.OrderUpdate
@@ -811,14 +1431,21 @@ func (b *BinanceExchange) handleOrderUpdate(event *binance.WsUserDataEvent) {
 		b.orders[internalID] = order
 	}
 
+	// An exchange order replaced by AmendOrder only contributes late fills; its
+	// cancellation does not end the internal order
+	if exchangeOrderID != order.ExchangeOrderID {
+		if orderUpdate.Status == string(binance.OrderStatusTypeFilled) ||
+			orderUpdate.Status == string(binance.OrderStatusTypePartiallyFilled) {
+			b.handleOrderFilled(order, &orderUpdate)
+		}
+		return
+	}
+
 	// Update order status
 	executedQty, _ := strconv.ParseFloat(orderUpdate.FilledVolume, 64)
 	filledQuoteVolume, _ := strconv.ParseFloat(orderUpdate.FilledQuoteVolume, 64)
 
-	order.FilledQty = executedQty
-	if executedQty > 0 {
-		order.AvgFillPrice = filledQuoteVolume / executedQty
-	}
+	b.setExecuted(order, executedQty, filledQuoteVolume)
 	order.UpdatedAt = time.Unix(0, orderUpdate.TransactionTime*int64(time.Millisecond))
 
 	switch orderUpdate.Status {
@@ -833,6 +1460,9 @@ func (b *BinanceExchange) handleOrderUpdate(event *binance.WsUserDataEvent) {
 	case string(binance.OrderStatusTypePartiallyFilled):
 		order.Status = OrderStatusOpen
 
+		// Record the partial execution so fills are not lost if the order is later cancelled
+		b.handleOrderFilled(order, &orderUpdate)
+
 	case string(binance.OrderStatusTypeCanceled):
 		order.Status = OrderStatusCancelled
 
@@ -866,7 +1496,7 @@ func (b *BinanceExchange) handleOrderUpdate(event *binance.WsUserDataEvent) {
 	}
 }
 
-// handleOrderFilled processes filled orders and updates positions
+// handleOrderFilled records the latest execution of a (partially) filled order and updates positions
 func (b *BinanceExchange) handleOrderFilled(order *Order, orderUpdate *binance.WsOrderUpdate) {
 	// Create fill records
 	lastQty, _ := strconv.ParseFloat(orderUpdate.LatestVolume, 64)
@@ -897,7 +1527,7 @@ func (b *BinanceExchange) handleOrderFilled(order *Order, orderUpdate *binance.W
 }
 
 // keepAliveListenKey keeps the listen key alive by pinging every 30 minutes
-func (b *BinanceExchange) keepAliveListenKey(ctx context.Context) {
+func (b *BinanceExchange) keepAliveListenKey(ctx context.Context, stopChan chan struct{}) {
 	ticker := time.NewTicker(30 * time.Minute)
 	defer ticker.Stop()
 
@@ -921,7 +1551,7 @@ func (b *BinanceExchange) keepAliveListenKey(ctx context.Context) {
 				log.Debug().Msg("Listen key kept alive")
 			}
 
-		case <-b.wsStopChan:
+		case <-stopChan:
 			log.Debug().Msg("Stop signal received, stopping keep-alive")
 			return
 
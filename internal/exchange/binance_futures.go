@@ -0,0 +1,1470 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/alerts"
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// binanceFuturesExchangeName identifies Binance USD-M futures in persisted metadata
+const binanceFuturesExchangeName = "binance_futures"
+
+const (
+	// maxFuturesLeverage is the highest leverage Binance accepts on any USD-M symbol
+	maxFuturesLeverage = 125
+
+	// errCodeNoNeedToChangeMarginType is returned when the symbol already uses the requested margin type
+	errCodeNoNeedToChangeMarginType = -4046
+)
+
+// MarginType selects how margin is shared between futures positions
+type MarginType string
+
+const (
+	// MarginTypeCrossed shares the wallet balance across all positions
+	MarginTypeCrossed MarginType = "CROSSED"
+	// MarginTypeIsolated limits each position's loss to the margin assigned to it
+	MarginTypeIsolated MarginType = "ISOLATED"
+)
+
+// ParseMarginType converts a configuration string ("cross", "crossed", "isolated") to a MarginType
+func ParseMarginType(s string) (MarginType, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "CROSS", string(MarginTypeCrossed):
+		return MarginTypeCrossed, nil
+	case string(MarginTypeIsolated):
+		return MarginTypeIsolated, nil
+	default:
+		return "", fmt.Errorf("invalid margin type %q (must be crossed or isolated)", s)
+	}
+}
+
+// PositionSide identifies a futures position. One-way mode uses BOTH; hedge mode
+// keeps separate LONG and SHORT positions per symbol.
+type PositionSide string
+
+const (
+	PositionSideBoth  PositionSide = "BOTH"
+	PositionSideLong  PositionSide = "LONG"
+	PositionSideShort PositionSide = "SHORT"
+)
+
+// MarkPrice is the mark and index price of a perpetual contract
+type MarkPrice struct {
+	Symbol          string    `json:"symbol"`
+	MarkPrice       float64   `json:"mark_price"`
+	IndexPrice      float64   `json:"index_price"`
+	FundingRate     float64   `json:"funding_rate"`
+	NextFundingTime time.Time `json:"next_funding_time"`
+	Time            time.Time `json:"time"`
+}
+
+// FuturesPosition is an open futures position as reported by the exchange
+type FuturesPosition struct {
+	Symbol           string       `json:"symbol"`
+	PositionSide     PositionSide `json:"position_side"`
+	Quantity         float64      `json:"quantity"` // Signed: negative for shorts in one-way mode
+	EntryPrice       float64      `json:"entry_price"`
+	MarkPrice        float64      `json:"mark_price"`
+	LiquidationPrice float64      `json:"liquidation_price"`
+	UnrealizedPnL    float64      `json:"unrealized_pnl"`
+	Leverage         int          `json:"leverage"`
+	MarginType       MarginType   `json:"margin_type"`
+	IsolatedMargin   float64      `json:"isolated_margin"`
+	IsolatedWallet   float64      `json:"isolated_wallet"` // Isolated margin excluding unrealized PnL
+	Notional         float64      `json:"notional"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+}
+
+// BinanceFuturesConfig contains configuration for the Binance USD-M futures exchange
+type BinanceFuturesConfig struct {
+	APIKey    string
+	SecretKey string
+	Testnet   bool
+
+	// BaseURL overrides the REST endpoint (optional)
+	BaseURL string
+
+	// WsBaseURL overrides the user data stream endpoint, e.g. a cassette
+	// recorder or replay server (optional). The go-binance endpoint is
+	// process-wide, so this affects every futures client in the process.
+	WsBaseURL string
+
+	// MaxLeverage caps the leverage SetLeverage accepts, usually the strategy's
+	// risk.max_leverage. Zero allows up to the exchange maximum.
+	MaxLeverage float64
+
+	// RateLimits overrides the request weight and order limits (zero uses DefaultFuturesRateLimits)
+	RateLimits RateLimits
+}
+
+// BinanceFuturesExchange implements Exchange for Binance USD-M perpetual futures
+type BinanceFuturesExchange struct {
+	client *futures.Client
+	db     *db.DB
+	mu     sync.RWMutex
+
+	// Order tracking
+	orders                  map[string]*Order // Internal UUID -> Order
+	fills                   map[string][]Fill // Internal UUID -> Fills
+	exchangeOrderToInternal map[string]string // Exchange OrderID -> Internal UUID
+
+	// Session tracking
+	currentSessionID *uuid.UUID
+
+	// Instrument rules loaded from exchangeInfo
+	instruments *InstrumentRegistry
+
+	// Per-symbol leverage and margin settings applied through this adapter
+	maxLeverage int
+	leverage    map[string]int
+	marginTypes map[string]MarginType
+
+	// Positions reported by ACCOUNT_UPDATE events, keyed by symbol and position side
+	positions map[string]*FuturesPosition
+
+	// Request weight and order rate budget shared by all REST calls
+	rateBudget *RateBudget
+
+	// Configuration
+	testnet bool
+
+	// Session view of a shared venue connection (see forSession)
+	forked bool
+
+	// WebSocket
+	listenKey   string
+	wsStopChan  chan struct{}
+	wsErrChan   chan error
+	wsConnected bool
+}
+
+// NewBinanceFuturesExchange creates a new Binance USD-M futures client
+func NewBinanceFuturesExchange(config BinanceFuturesConfig, database *db.DB) (*BinanceFuturesExchange, error) {
+	if config.MaxLeverage < 0 || config.MaxLeverage > maxFuturesLeverage {
+		return nil, fmt.Errorf("max leverage must be between 0 and %d, got %g", maxFuturesLeverage, config.MaxLeverage)
+	}
+
+	if config.Testnet {
+		futures.UseTestnet = true
+		log.Info().Msg("Binance futures exchange initialized (TESTNET mode)")
+	} else {
+		log.Warn().Msg("Binance futures exchange initialized (LIVE TRADING mode)")
+	}
+
+	client := futures.NewClient(config.APIKey, config.SecretKey)
+	if config.BaseURL != "" {
+		client.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	}
+	if config.WsBaseURL != "" {
+		futures.BaseWsMainUrl = strings.TrimRight(config.WsBaseURL, "/")
+		futures.BaseWsTestnetUrl = futures.BaseWsMainUrl
+	}
+
+	limits := config.RateLimits
+	if limits.RequestWeight1m == 0 {
+		limits = DefaultFuturesRateLimits()
+	}
+	rateBudget := NewRateBudget(binanceFuturesExchangeName, limits)
+	client.HTTPClient = rateBudget.HTTPClient(client.HTTPClient, binanceFuturesRequestCost)
+
+	maxLeverage := int(config.MaxLeverage)
+	if maxLeverage == 0 {
+		maxLeverage = maxFuturesLeverage
+	}
+
+	return &BinanceFuturesExchange{
+		client:                  client,
+		db:                      database,
+		orders:                  make(map[string]*Order),
+		fills:                   make(map[string][]Fill),
+		exchangeOrderToInternal: make(map[string]string),
+		instruments:             NewInstrumentRegistry(),
+		maxLeverage:             maxLeverage,
+		leverage:                make(map[string]int),
+		marginTypes:             make(map[string]MarginType),
+		positions:               make(map[string]*FuturesPosition),
+		rateBudget:              rateBudget,
+		testnet:                 config.Testnet,
+		wsStopChan:              make(chan struct{}),
+		wsErrChan:               make(chan error, 10),
+	}, nil
+}
+
+// forSession returns an exchange for one trading session on the same client,
+// rate budget and instrument rules. Orders, fills, leverage settings and the
+// session are tracked separately.
+func (f *BinanceFuturesExchange) forSession() Exchange {
+	return &BinanceFuturesExchange{
+		client:                  f.client,
+		db:                      f.db,
+		orders:                  make(map[string]*Order),
+		fills:                   make(map[string][]Fill),
+		exchangeOrderToInternal: make(map[string]string),
+		instruments:             f.instruments,
+		maxLeverage:             f.maxLeverage,
+		leverage:                make(map[string]int),
+		marginTypes:             make(map[string]MarginType),
+		positions:               make(map[string]*FuturesPosition),
+		rateBudget:              f.rateBudget,
+		testnet:                 f.testnet,
+		forked:                  true,
+		wsStopChan:              make(chan struct{}),
+		wsErrChan:               make(chan error, 10),
+	}
+}
+
+// PlaceOrder places a new futures order. Reduce-only and position-side flags
+// are passed through to the exchange.
+func (f *BinanceFuturesExchange) PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*PlaceOrderResponse, error) {
+	// Lazily load instrument rules so orders are rounded before submission
+	if f.instruments.Len() == 0 {
+		if err := f.LoadInstruments(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to load futures instrument rules, submitting without precision checks")
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := validateFuturesOrder(req); err != nil {
+		log.Warn().
+			Err(err).
+			Str("symbol", req.Symbol).
+			Str("side", string(req.Side)).
+			Msg("Futures order validation failed")
+
+		return &PlaceOrderResponse{
+			Status:  OrderStatusRejected,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Reduce-only orders may close a position below the minimum notional, so
+	// they are only rejected when the quantity rounds to zero
+	if err := f.instruments.NormalizeOrder(&req, 0); err != nil && (!req.ReduceOnly || req.Quantity <= 0) {
+		log.Warn().
+			Err(err).
+			Str("symbol", req.Symbol).
+			Float64("quantity", req.Quantity).
+			Msg("Futures order rejected by instrument rules")
+
+		return &PlaceOrderResponse{
+			Status:  OrderStatusRejected,
+			Message: err.Error(),
+		}, nil
+	}
+	quantityStr, priceStr := f.formatOrderValues(req)
+
+	side := futures.SideTypeBuy
+	if req.Side == OrderSideSell {
+		side = futures.SideTypeSell
+	}
+
+	var futuresOrder *futures.CreateOrderResponse
+	operationName := fmt.Sprintf("place_futures_%s_order_%s", req.Type, req.Symbol)
+	err := retryWithBackoff(func() error {
+		service := f.client.NewCreateOrderService().
+			Symbol(req.Symbol).
+			Side(side).
+			Quantity(quantityStr).
+			NewOrderResponseType(futures.NewOrderRespTypeRESULT)
+
+		if req.Type == OrderTypeMarket {
+			service = service.Type(futures.OrderTypeMarket)
+		} else {
+			service = service.
+				Type(futures.OrderTypeLimit).
+				TimeInForce(futures.TimeInForceTypeGTC).
+				Price(priceStr)
+		}
+		if req.PositionSide != "" {
+			service = service.PositionSide(futures.PositionSideType(req.PositionSide))
+		}
+		if req.ReduceOnly {
+			service = service.ReduceOnly(true)
+		}
+
+		var err error
+		futuresOrder, err = service.Do(ctx)
+		return err
+	}, operationName)
+
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("symbol", req.Symbol).
+			Str("side", string(req.Side)).
+			Bool("reduce_only", req.ReduceOnly).
+			Msg("Failed to place order on Binance futures after retries")
+
+		alerts.AlertOrderFailed(ctx, req.Symbol, string(req.Side), req.Quantity, err)
+
+		return &PlaceOrderResponse{
+			Status:  OrderStatusRejected,
+			Message: err.Error(),
+		}, fmt.Errorf("failed to place order: %w", err)
+	}
+
+	order := f.convertFuturesOrder(futuresOrder, req)
+
+	f.orders[order.ID] = order
+	f.exchangeOrderToInternal[order.ExchangeOrderID] = order.ID
+
+	if f.db != nil {
+		if err := f.db.InsertOrder(ctx, f.convertToDBOrder(order)); err != nil {
+			log.Error().
+				Err(err).
+				Str("order_id", order.ID).
+				Msg("Failed to persist futures order to database")
+		}
+	}
+
+	log.Info().
+		Str("order_id", order.ID).
+		Str("exchange_order_id", order.ExchangeOrderID).
+		Str("symbol", order.Symbol).
+		Str("side", string(order.Side)).
+		Str("position_side", string(order.PositionSide)).
+		Bool("reduce_only", order.ReduceOnly).
+		Str("status", string(order.Status)).
+		Msg("Order placed on Binance futures")
+
+	return &PlaceOrderResponse{
+		OrderID: order.ID,
+		Status:  order.Status,
+		Message: "Order placed successfully",
+	}, nil
+}
+
+// CancelOrder cancels an open futures order
+func (f *BinanceFuturesExchange) CancelOrder(ctx context.Context, orderID string) (*Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	order, exists := f.orders[orderID]
+	if !exists {
+		return nil, fmt.Errorf("order not found: %s", orderID)
+	}
+
+	if order.Status != OrderStatusOpen && order.Status != OrderStatusPending {
+		return nil, fmt.Errorf("cannot cancel order in status: %s", order.Status)
+	}
+
+	exchangeOrderID, err := strconv.ParseInt(order.ExchangeOrderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exchange order ID format: %w", err)
+	}
+
+	operationName := fmt.Sprintf("cancel_futures_order_%s", order.Symbol)
+	err = retryWithBackoff(func() error {
+		_, err := f.client.NewCancelOrderService().
+			Symbol(order.Symbol).
+			OrderID(exchangeOrderID).
+			Do(ctx)
+		return err
+	}, operationName)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("order_id", orderID).
+			Msg("Failed to cancel order on Binance futures after retries")
+
+		alerts.AlertOrderCancelFailed(ctx, orderID, order.Symbol, err)
+
+		return nil, fmt.Errorf("failed to cancel order: %w", err)
+	}
+
+	cancelledAt := time.Now()
+	order.Status = OrderStatusCancelled
+	order.UpdatedAt = cancelledAt
+	f.updateDBOrderStatus(ctx, order, &cancelledAt)
+
+	log.Info().
+		Str("order_id", orderID).
+		Msg("Order cancelled on Binance futures")
+
+	return order, nil
+}
+
+// AmendOrder changes a resting limit order in place with Binance's native order
+// modification; the exchange order ID is unchanged
+func (f *BinanceFuturesExchange) AmendOrder(ctx context.Context, req AmendOrderRequest) (*Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	order, exists := f.orders[req.OrderID]
+	if !exists {
+		return nil, fmt.Errorf("order not found: %s", req.OrderID)
+	}
+
+	price, quantity, err := resolveAmendment(order, req, f.instruments)
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeOrderID, err := strconv.ParseInt(order.ExchangeOrderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exchange order ID format: %w", err)
+	}
+
+	quantityStr, priceStr := f.formatOrderValues(PlaceOrderRequest{Symbol: order.Symbol, Quantity: quantity, Price: price})
+	side := futures.SideTypeBuy
+	if order.Side == OrderSideSell {
+		side = futures.SideTypeSell
+	}
+
+	var resp *futures.ModifyOrderResponse
+	operationName := fmt.Sprintf("amend_futures_order_%s", order.Symbol)
+	err = retryWithBackoff(func() error {
+		var err error
+		resp, err = f.client.NewModifyOrderService().
+			Symbol(order.Symbol).
+			OrderID(exchangeOrderID).
+			Side(side).
+			Quantity(quantityStr).
+			Price(priceStr).
+			Do(ctx)
+		return err
+	}, operationName)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("order_id", order.ID).
+			Msg("Failed to amend order on Binance futures after retries")
+		return nil, fmt.Errorf("failed to amend order: %w", err)
+	}
+
+	amendment := applyAmendment(order, price, quantity, order.ExchangeOrderID)
+	applyFuturesOrderState(order, resp.Status, parseFloatOrZero(resp.ExecutedQuantity), parseFloatOrZero(resp.AveragePrice), time.Now())
+
+	persistAmendment(ctx, f.db, order.ID, amendment)
+
+	log.Info().
+		Str("order_id", order.ID).
+		Float64("price", price).
+		Float64("quantity", quantity).
+		Msg("Order amended on Binance futures")
+
+	return order, nil
+}
+
+// CancelAllOrders cancels every open futures order for a symbol, or across all symbols when symbol is empty.
+// Orders placed outside this process are included; they are returned keyed by their exchange order ID.
+func (f *BinanceFuturesExchange) CancelAllOrders(ctx context.Context, symbol string) ([]*Order, error) {
+	if symbol != "" {
+		symbol = NormalizeSymbol(symbol)
+	}
+
+	// The allOpenOrders endpoint does not echo the cancelled orders, so list them first
+	var openOrders []*futures.Order
+	err := retryWithBackoff(func() error {
+		var err error
+		openOrders, err = f.client.NewListOpenOrdersService().Symbol(symbol).Do(ctx)
+		return err
+	}, "list_futures_open_orders")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open orders: %w", err)
+	}
+
+	bySymbol := make(map[string][]*futures.Order)
+	symbols := make([]string, 0)
+	for _, o := range openOrders {
+		if _, seen := bySymbol[o.Symbol]; !seen {
+			symbols = append(symbols, o.Symbol)
+		}
+		bySymbol[o.Symbol] = append(bySymbol[o.Symbol], o)
+	}
+
+	cancelled := make([]*Order, 0, len(openOrders))
+	for _, sym := range symbols {
+		operationName := fmt.Sprintf("cancel_futures_open_orders_%s", sym)
+		err := retryWithBackoff(func() error {
+			return f.client.NewCancelAllOpenOrdersService().Symbol(sym).Do(ctx)
+		}, operationName)
+		if err != nil {
+			return cancelled, fmt.Errorf("failed to cancel open orders for %s: %w", sym, err)
+		}
+
+		for _, o := range bySymbol[sym] {
+			cancelled = append(cancelled, f.markCancelled(ctx, o))
+		}
+	}
+
+	log.Info().
+		Int("count", len(cancelled)).
+		Strs("symbols", symbols).
+		Msg("Cancelled all open orders on Binance futures")
+
+	return cancelled, nil
+}
+
+// markCancelled records a bulk cancellation against the tracked order, if any
+func (f *BinanceFuturesExchange) markCancelled(ctx context.Context, o *futures.Order) *Order {
+	exchangeOrderID := strconv.FormatInt(o.OrderID, 10)
+	cancelledAt := time.Now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	internalID, tracked := f.exchangeOrderToInternal[exchangeOrderID]
+	if !tracked {
+		return &Order{
+			ID:              exchangeOrderID,
+			ExchangeOrderID: exchangeOrderID,
+			Symbol:          o.Symbol,
+			Side:            OrderSide(strings.ToLower(string(o.Side))),
+			Type:            OrderType(strings.ToLower(string(o.Type))),
+			Quantity:        parseFloatOrZero(o.OrigQuantity),
+			Price:           parseFloatOrZero(o.Price),
+			FilledQty:       parseFloatOrZero(o.ExecutedQuantity),
+			AvgFillPrice:    parseFloatOrZero(o.AvgPrice),
+			Status:          OrderStatusCancelled,
+			UpdatedAt:       cancelledAt,
+			ReduceOnly:      o.ReduceOnly,
+			PositionSide:    PositionSide(o.PositionSide),
+		}
+	}
+
+	order := f.orders[internalID]
+	order.Status = OrderStatusCancelled
+	order.FilledQty = parseFloatOrZero(o.ExecutedQuantity)
+	order.UpdatedAt = cancelledAt
+	f.updateDBOrderStatus(ctx, order, &cancelledAt)
+
+	return order
+}
+
+// OpenOrderCount returns the number of resting orders placed through this client
+func (f *BinanceFuturesExchange) OpenOrderCount() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return countOpenOrders(f.orders)
+}
+
+// GetOrder retrieves order details, refreshing them from the exchange when possible
+func (f *BinanceFuturesExchange) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	f.mu.RLock()
+	order, exists := f.orders[orderID]
+	f.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("order not found: %s", orderID)
+	}
+
+	exchangeOrderID, err := strconv.ParseInt(order.ExchangeOrderID, 10, 64)
+	if err != nil {
+		return order, nil // Return cached order if exchange ID parsing fails
+	}
+
+	var futuresOrder *futures.Order
+	operationName := fmt.Sprintf("get_futures_order_%s", order.Symbol)
+	err = retryWithBackoff(func() error {
+		var err error
+		futuresOrder, err = f.client.NewGetOrderService().
+			Symbol(order.Symbol).
+			OrderID(exchangeOrderID).
+			Do(ctx)
+		return err
+	}, operationName)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("order_id", orderID).
+			Msg("Failed to query futures order status after retries, returning cached")
+		return order, nil
+	}
+
+	f.mu.Lock()
+	applyFuturesOrderState(order, futuresOrder.Status, parseFloatOrZero(futuresOrder.ExecutedQuantity), parseFloatOrZero(futuresOrder.AvgPrice), time.Now())
+	f.mu.Unlock()
+
+	return order, nil
+}
+
+// GetOrderFills retrieves all fills for an order
+func (f *BinanceFuturesExchange) GetOrderFills(ctx context.Context, orderID string) ([]Fill, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	fills, exists := f.fills[orderID]
+	if !exists {
+		return []Fill{}, nil
+	}
+
+	return fills, nil
+}
+
+// SetMarketPrice is a no-op for real exchange (market prices come from exchange)
+func (f *BinanceFuturesExchange) SetMarketPrice(symbol string, price float64) {
+	log.Debug().
+		Str("symbol", symbol).
+		Float64("price", price).
+		Msg("SetMarketPrice called on BinanceFuturesExchange (no-op)")
+}
+
+// SetSession sets the current trading session
+func (f *BinanceFuturesExchange) SetSession(sessionID *uuid.UUID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.currentSessionID = sessionID
+
+	if sessionID != nil {
+		log.Info().
+			Str("session_id", sessionID.String()).
+			Msg("Trading session set for Binance futures exchange")
+	} else {
+		log.Info().Msg("Trading session cleared for Binance futures exchange")
+	}
+}
+
+// GetSession returns the current trading session ID
+func (f *BinanceFuturesExchange) GetSession() *uuid.UUID {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.currentSessionID
+}
+
+// GetBalances returns non-zero margin asset balances from the futures account
+func (f *BinanceFuturesExchange) GetBalances(ctx context.Context) ([]Balance, error) {
+	account, err := f.GetAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return account.Balances, nil
+}
+
+// GetAccount retrieves the futures account. Free is the balance available for new
+// positions; Locked is the rest of the wallet balance (position and order margin).
+func (f *BinanceFuturesExchange) GetAccount(ctx context.Context) (*Account, error) {
+	var futuresAccount *futures.Account
+	err := retryWithBackoff(func() error {
+		var err error
+		futuresAccount, err = f.client.NewGetAccountService().Do(ctx)
+		return err
+	}, "get_futures_account")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get futures account: %w", err)
+	}
+
+	balances := make([]Balance, 0)
+	for _, asset := range futuresAccount.Assets {
+		wallet := parseFloatOrZero(asset.WalletBalance)
+		available := parseFloatOrZero(asset.AvailableBalance)
+		if available > wallet {
+			available = wallet
+		}
+		balance := Balance{
+			Asset:  asset.Asset,
+			Free:   available,
+			Locked: wallet - available,
+		}
+		if balance.Total() == 0 {
+			continue
+		}
+		balances = append(balances, balance)
+	}
+
+	updatedAt := time.Now()
+	if futuresAccount.UpdateTime > 0 {
+		updatedAt = time.UnixMilli(futuresAccount.UpdateTime)
+	}
+
+	return &Account{
+		Exchange:    binanceFuturesExchangeName,
+		AccountType: "USDM_FUTURES",
+		CanTrade:    futuresAccount.CanTrade,
+		Balances:    balances,
+		UpdatedAt:   updatedAt,
+	}, nil
+}
+
+// RateBudget returns the request weight and order rate budget
+func (f *BinanceFuturesExchange) RateBudget() *RateBudget {
+	return f.rateBudget
+}
+
+// GetInstrument returns the trading rules for a symbol
+func (f *BinanceFuturesExchange) GetInstrument(symbol string) (*Instrument, bool) {
+	return f.instruments.Get(symbol)
+}
+
+// Instruments returns the instrument registry backing order validation
+func (f *BinanceFuturesExchange) Instruments() *InstrumentRegistry {
+	return f.instruments
+}
+
+// LoadInstruments fetches tick size, lot size and min notional rules for all
+// trading contracts from the futures exchangeInfo endpoint
+func (f *BinanceFuturesExchange) LoadInstruments(ctx context.Context) error {
+	var info *futures.ExchangeInfo
+	err := retryWithBackoff(func() error {
+		var err error
+		info, err = f.client.NewExchangeInfoService().Do(ctx)
+		return err
+	}, "futures_exchange_info")
+	if err != nil {
+		return fmt.Errorf("failed to fetch futures exchange info: %w", err)
+	}
+
+	loaded := 0
+	for i := range info.Symbols {
+		symbol := &info.Symbols[i]
+		if symbol.Status != string(futures.SymbolStatusTypeTrading) {
+			continue
+		}
+		f.instruments.Set(instrumentFromFuturesSymbol(symbol))
+		loaded++
+	}
+
+	log.Info().Int("instruments", loaded).Msg("Loaded Binance futures instrument rules")
+	return nil
+}
+
+// instrumentFromFuturesSymbol converts futures exchangeInfo symbol filters into an Instrument
+func instrumentFromFuturesSymbol(symbol *futures.Symbol) Instrument {
+	inst := Instrument{
+		Symbol:            symbol.Symbol,
+		BaseAsset:         symbol.BaseAsset,
+		QuoteAsset:        symbol.QuoteAsset,
+		PricePrecision:    symbol.PricePrecision,
+		QuantityPrecision: symbol.QuantityPrecision,
+	}
+
+	if filter := symbol.PriceFilter(); filter != nil {
+		inst.TickSize = parseFloatOrZero(filter.TickSize)
+		inst.MinPrice = parseFloatOrZero(filter.MinPrice)
+		inst.MaxPrice = parseFloatOrZero(filter.MaxPrice)
+	}
+
+	if filter := symbol.LotSizeFilter(); filter != nil {
+		inst.StepSize = parseFloatOrZero(filter.StepSize)
+		inst.MinQty = parseFloatOrZero(filter.MinQuantity)
+		inst.MaxQty = parseFloatOrZero(filter.MaxQuantity)
+	}
+
+	if filter := symbol.MinNotionalFilter(); filter != nil {
+		inst.MinNotional = parseFloatOrZero(filter.Notional)
+	}
+
+	return inst
+}
+
+// SetLeverage changes the initial leverage of a symbol and returns the leverage
+// the exchange applied. Requests above the configured maximum are refused.
+func (f *BinanceFuturesExchange) SetLeverage(ctx context.Context, symbol string, leverage int) (int, error) {
+	symbol = NormalizeSymbol(symbol)
+	if symbol == "" {
+		return 0, fmt.Errorf("symbol is required")
+	}
+	if leverage < 1 {
+		return 0, fmt.Errorf("leverage must be at least 1, got %d", leverage)
+	}
+	if leverage > f.maxLeverage {
+		return 0, fmt.Errorf("leverage %dx exceeds the maximum of %dx", leverage, f.maxLeverage)
+	}
+
+	var result *futures.SymbolLeverage
+	err := retryWithBackoff(func() error {
+		var err error
+		result, err = f.client.NewChangeLeverageService().Symbol(symbol).Leverage(leverage).Do(ctx)
+		return err
+	}, fmt.Sprintf("change_leverage_%s", symbol))
+	if err != nil {
+		return 0, fmt.Errorf("failed to set leverage for %s: %w", symbol, err)
+	}
+
+	f.mu.Lock()
+	f.leverage[symbol] = result.Leverage
+	f.mu.Unlock()
+
+	log.Info().
+		Str("symbol", symbol).
+		Int("leverage", result.Leverage).
+		Str("max_notional", result.MaxNotionalValue).
+		Msg("Futures leverage updated")
+
+	return result.Leverage, nil
+}
+
+// Leverage returns the leverage last set for a symbol through this adapter
+func (f *BinanceFuturesExchange) Leverage(symbol string) (int, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	leverage, ok := f.leverage[NormalizeSymbol(symbol)]
+	return leverage, ok
+}
+
+// SetMarginType switches a symbol between cross and isolated margin. Requesting
+// the margin type the symbol already uses is not an error.
+func (f *BinanceFuturesExchange) SetMarginType(ctx context.Context, symbol string, marginType MarginType) error {
+	symbol = NormalizeSymbol(symbol)
+	if symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+	if marginType != MarginTypeCrossed && marginType != MarginTypeIsolated {
+		return fmt.Errorf("invalid margin type: %s", marginType)
+	}
+
+	err := retryWithBackoff(func() error {
+		return f.client.NewChangeMarginTypeService().
+			Symbol(symbol).
+			MarginType(futures.MarginType(marginType)).
+			Do(ctx)
+	}, fmt.Sprintf("change_margin_type_%s", symbol))
+	if err != nil && !isAPIErrorCode(err, errCodeNoNeedToChangeMarginType) {
+		return fmt.Errorf("failed to set margin type for %s: %w", symbol, err)
+	}
+
+	f.mu.Lock()
+	f.marginTypes[symbol] = marginType
+	f.mu.Unlock()
+
+	log.Info().
+		Str("symbol", symbol).
+		Str("margin_type", string(marginType)).
+		Msg("Futures margin type updated")
+
+	return nil
+}
+
+// GetMarkPrice returns the mark price, index price and funding rate of a contract
+func (f *BinanceFuturesExchange) GetMarkPrice(ctx context.Context, symbol string) (*MarkPrice, error) {
+	symbol = NormalizeSymbol(symbol)
+
+	var indexes []*futures.PremiumIndex
+	err := retryWithBackoff(func() error {
+		var err error
+		indexes, err = f.client.NewPremiumIndexService().Symbol(symbol).Do(ctx)
+		return err
+	}, fmt.Sprintf("premium_index_%s", symbol))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mark price for %s: %w", symbol, err)
+	}
+
+	for _, index := range indexes {
+		if index.Symbol != symbol {
+			continue
+		}
+		return &MarkPrice{
+			Symbol:          index.Symbol,
+			MarkPrice:       parseFloatOrZero(index.MarkPrice),
+			IndexPrice:      parseFloatOrZero(index.IndexPrice),
+			FundingRate:     parseFloatOrZero(index.LastFundingRate),
+			NextFundingTime: time.UnixMilli(index.NextFundingTime),
+			Time:            time.UnixMilli(index.Time),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no mark price for symbol %s", symbol)
+}
+
+// GetQuote returns the best bid and ask from the futures book ticker endpoint
+func (f *BinanceFuturesExchange) GetQuote(ctx context.Context, symbol string) (*Quote, error) {
+	symbol = NormalizeSymbol(symbol)
+
+	var tickers []*futures.BookTicker
+	err := retryWithBackoff(func() error {
+		var err error
+		tickers, err = f.client.NewListBookTickersService().Symbol(symbol).Do(ctx)
+		return err
+	}, fmt.Sprintf("book_ticker_%s", symbol))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book ticker for %s: %w", symbol, err)
+	}
+
+	for _, ticker := range tickers {
+		if ticker.Symbol != symbol {
+			continue
+		}
+		return &Quote{
+			Symbol:  ticker.Symbol,
+			Bid:     parseFloatOrZero(ticker.BidPrice),
+			BidSize: parseFloatOrZero(ticker.BidQuantity),
+			Ask:     parseFloatOrZero(ticker.AskPrice),
+			AskSize: parseFloatOrZero(ticker.AskQuantity),
+			Time:    time.UnixMilli(ticker.Time),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no book ticker for symbol %s", symbol)
+}
+
+// GetPositions returns the open futures positions, optionally for one symbol
+func (f *BinanceFuturesExchange) GetPositions(ctx context.Context, symbol string) ([]FuturesPosition, error) {
+	service := f.client.NewGetPositionRiskService()
+	if symbol != "" {
+		service = service.Symbol(NormalizeSymbol(symbol))
+	}
+
+	var risks []*futures.PositionRisk
+	err := retryWithBackoff(func() error {
+		var err error
+		risks, err = service.Do(ctx)
+		return err
+	}, "get_position_risk")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get futures positions: %w", err)
+	}
+
+	positions := make([]FuturesPosition, 0, len(risks))
+	for _, risk := range risks {
+		position := futuresPositionFromRisk(risk)
+		if position.Quantity == 0 {
+			continue
+		}
+		positions = append(positions, position)
+	}
+
+	return positions, nil
+}
+
+// GetLiquidationPrice returns the liquidation price of the open position on a symbol.
+// An empty side matches the first open position (one-way mode has only BOTH).
+func (f *BinanceFuturesExchange) GetLiquidationPrice(ctx context.Context, symbol string, side PositionSide) (float64, error) {
+	positions, err := f.GetPositions(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, position := range positions {
+		if side == "" || position.PositionSide == side {
+			return position.LiquidationPrice, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no open %s position for %s", side, NormalizeSymbol(symbol))
+}
+
+// Positions returns the latest positions pushed over the user data stream
+func (f *BinanceFuturesExchange) Positions() []FuturesPosition {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	positions := make([]FuturesPosition, 0, len(f.positions))
+	for _, position := range f.positions {
+		positions = append(positions, *position)
+	}
+	return positions
+}
+
+// futuresPositionFromRisk converts a positionRisk entry into a FuturesPosition
+func futuresPositionFromRisk(risk *futures.PositionRisk) FuturesPosition {
+	leverage, _ := strconv.Atoi(risk.Leverage)
+
+	// positionRisk reports lowercase margin types ("cross", "isolated")
+	marginType, err := ParseMarginType(risk.MarginType)
+	if err != nil {
+		marginType = MarginType(strings.ToUpper(risk.MarginType))
+	}
+
+	return FuturesPosition{
+		Symbol:           risk.Symbol,
+		PositionSide:     PositionSide(risk.PositionSide),
+		Quantity:         parseFloatOrZero(risk.PositionAmt),
+		EntryPrice:       parseFloatOrZero(risk.EntryPrice),
+		MarkPrice:        parseFloatOrZero(risk.MarkPrice),
+		LiquidationPrice: parseFloatOrZero(risk.LiquidationPrice),
+		UnrealizedPnL:    parseFloatOrZero(risk.UnRealizedProfit),
+		Leverage:         leverage,
+		MarginType:       marginType,
+		IsolatedMargin:   parseFloatOrZero(risk.IsolatedMargin),
+		IsolatedWallet:   parseFloatOrZero(risk.IsolatedWallet),
+		Notional:         parseFloatOrZero(risk.Notional),
+		UpdatedAt:        time.Now(),
+	}
+}
+
+// validateFuturesOrder adds the futures-only checks to the common order validation
+func validateFuturesOrder(req PlaceOrderRequest) error {
+	if err := validatePlaceOrderRequest(req); err != nil {
+		return err
+	}
+
+	switch req.PositionSide {
+	case "", PositionSideBoth:
+	case PositionSideLong, PositionSideShort:
+		// Hedge mode closes a position by trading against its side; Binance rejects reduceOnly there
+		if req.ReduceOnly {
+			return fmt.Errorf("reduce_only cannot be combined with position side %s", req.PositionSide)
+		}
+	default:
+		return fmt.Errorf("invalid position side: %s", req.PositionSide)
+	}
+
+	return nil
+}
+
+// formatOrderValues renders quantity and price with the instrument precision,
+// falling back to 8 decimals for symbols without loaded rules
+func (f *BinanceFuturesExchange) formatOrderValues(req PlaceOrderRequest) (string, string) {
+	if inst, ok := f.instruments.Get(req.Symbol); ok {
+		return inst.FormatQuantity(req.Quantity), inst.FormatPrice(req.Price)
+	}
+	return fmt.Sprintf("%.8f", req.Quantity), fmt.Sprintf("%.8f", req.Price)
+}
+
+func (f *BinanceFuturesExchange) convertFuturesOrder(resp *futures.CreateOrderResponse, req PlaceOrderRequest) *Order {
+	now := time.Now()
+
+	executedQty := parseFloatOrZero(resp.ExecutedQuantity)
+	avgFillPrice := parseFloatOrZero(resp.AvgPrice)
+	if avgFillPrice == 0 && executedQty > 0 {
+		avgFillPrice = parseFloatOrZero(resp.CumQuote) / executedQty
+	}
+
+	positionSide := req.PositionSide
+	if resp.PositionSide != "" {
+		positionSide = PositionSide(resp.PositionSide)
+	}
+
+	order := &Order{
+		ID:              uuid.New().String(),
+		ExchangeOrderID: strconv.FormatInt(resp.OrderID, 10),
+		Symbol:          resp.Symbol,
+		Side:            req.Side,
+		Type:            req.Type,
+		Quantity:        req.Quantity,
+		Price:           req.Price,
+		CreatedAt:       now,
+		ParentOrderID:   req.ParentOrderID,
+		TaxLotIDs:       req.TaxLotIDs,
+		ReduceOnly:      req.ReduceOnly || resp.ReduceOnly,
+		PositionSide:    positionSide,
+	}
+	applyFuturesOrderState(order, resp.Status, executedQty, avgFillPrice, now)
+
+	return order
+}
+
+// applyFuturesOrderState updates fill progress and maps the futures order status
+func applyFuturesOrderState(order *Order, status futures.OrderStatusType, executedQty, avgFillPrice float64, at time.Time) {
+	order.FilledQty = executedQty
+	if avgFillPrice > 0 {
+		order.AvgFillPrice = avgFillPrice
+	}
+	order.UpdatedAt = at
+
+	switch status {
+	case futures.OrderStatusTypeNew, futures.OrderStatusTypePartiallyFilled:
+		order.Status = OrderStatusOpen
+	case futures.OrderStatusTypeFilled:
+		order.Status = OrderStatusFilled
+		if order.FilledAt == nil {
+			filledAt := at
+			order.FilledAt = &filledAt
+		}
+	case futures.OrderStatusTypeCanceled, futures.OrderStatusTypeExpired:
+		order.Status = OrderStatusCancelled
+	case futures.OrderStatusTypeRejected:
+		order.Status = OrderStatusRejected
+	default:
+		if order.Status == "" {
+			order.Status = OrderStatusPending
+		}
+	}
+}
+
+func (f *BinanceFuturesExchange) convertToDBOrder(order *Order) *db.Order {
+	orderID, _ := uuid.Parse(order.ID)
+
+	var price *float64
+	if order.Price > 0 {
+		price = &order.Price
+	}
+
+	var parentOrderID *uuid.UUID
+	if order.ParentOrderID != "" {
+		if parsed, err := uuid.Parse(order.ParentOrderID); err == nil {
+			parentOrderID = &parsed
+		}
+	}
+
+	exchangeName := "BINANCE_FUTURES"
+	if f.testnet {
+		exchangeName = "BINANCE_FUTURES_TESTNET"
+	}
+
+	metadata := map[string]interface{}{
+		"reduce_only": order.ReduceOnly,
+	}
+	if order.PositionSide != "" {
+		metadata["position_side"] = string(order.PositionSide)
+	}
+	if leverage, ok := f.leverage[order.Symbol]; ok {
+		metadata["leverage"] = leverage
+	}
+
+	return &db.Order{
+		ID:                    orderID,
+		SessionID:             f.currentSessionID,
+		ExchangeOrderID:       &order.ExchangeOrderID,
+		Symbol:                order.Symbol,
+		Exchange:              exchangeName,
+		Side:                  db.ConvertOrderSide(string(order.Side)),
+		Type:                  db.ConvertOrderType(string(order.Type)),
+		Status:                db.ConvertOrderStatus(string(order.Status)),
+		Price:                 price,
+		Quantity:              order.Quantity,
+		ExecutedQuantity:      order.FilledQty,
+		ExecutedQuoteQuantity: order.FilledQty * order.AvgFillPrice,
+		PlacedAt:              order.CreatedAt,
+		FilledAt:              order.FilledAt,
+		Metadata:              metadata,
+		ParentOrderID:         parentOrderID,
+		CreatedAt:             order.CreatedAt,
+		UpdatedAt:             order.UpdatedAt,
+	}
+}
+
+// updateDBOrderStatus persists the order's status and fill progress (caller holds f.mu)
+func (f *BinanceFuturesExchange) updateDBOrderStatus(ctx context.Context, order *Order, cancelledAt *time.Time) {
+	if f.db == nil {
+		return
+	}
+
+	orderUUID, err := uuid.Parse(order.ID)
+	if err != nil {
+		return
+	}
+
+	err = f.db.UpdateOrderStatus(
+		ctx,
+		orderUUID,
+		db.ConvertOrderStatus(string(order.Status)),
+		order.FilledQty,
+		order.FilledQty*order.AvgFillPrice,
+		order.FilledAt,
+		cancelledAt,
+		nil,
+	)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("order_id", order.ID).
+			Msg("Failed to update futures order status in database")
+	}
+}
+
+// isAPIErrorCode reports whether err is a Binance API error with the given code
+func isAPIErrorCode(err error, code int64) bool {
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == code
+	}
+	return false
+}
+
+// WebSocket Methods
+
+// StartUserDataStream starts the futures user data stream for order, account and margin call updates
+func (f *BinanceFuturesExchange) StartUserDataStream(ctx context.Context) error {
+	f.mu.Lock()
+	if f.wsConnected {
+		f.mu.Unlock()
+		log.Info().Msg("Futures user data stream already connected")
+		return nil
+	}
+	f.wsConnected = true
+	f.wsStopChan = make(chan struct{})
+	stopChan := f.wsStopChan
+	f.mu.Unlock()
+
+	listenKey, err := f.client.NewStartUserStreamService().Do(ctx)
+	if err != nil {
+		f.mu.Lock()
+		f.wsConnected = false
+		f.mu.Unlock()
+		return fmt.Errorf("failed to create futures listen key: %w", err)
+	}
+
+	f.mu.Lock()
+	f.listenKey = listenKey
+	f.mu.Unlock()
+
+	log.Info().Msg("Futures user data stream listen key created")
+
+	go f.runUserDataStream(ctx, listenKey, stopChan)
+	go f.keepAliveListenKey(ctx, stopChan)
+
+	return nil
+}
+
+// StopUserDataStream stops the futures user data stream
+func (f *BinanceFuturesExchange) StopUserDataStream(ctx context.Context) error {
+	f.mu.Lock()
+	if !f.wsConnected {
+		f.mu.Unlock()
+		return nil
+	}
+
+	listenKey := f.listenKey
+	stopChan := f.wsStopChan
+	f.wsConnected = false
+	f.mu.Unlock()
+
+	close(stopChan)
+
+	// Sessions of a forked exchange share the account's key with the venue
+	if listenKey != "" && !f.forked {
+		if err := f.client.NewCloseUserStreamService().ListenKey(listenKey).Do(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to close futures listen key")
+		}
+	}
+
+	log.Info().Msg("Futures user data stream stopped")
+	return nil
+}
+
+// runUserDataStream handles the futures WebSocket connection
+func (f *BinanceFuturesExchange) runUserDataStream(ctx context.Context, listenKey string, stopChan chan struct{}) {
+	defer func() {
+		f.mu.Lock()
+		f.wsConnected = false
+		f.mu.Unlock()
+	}()
+
+	errHandler := func(err error) {
+		log.Error().Err(err).Msg("Futures WebSocket error")
+		alerts.AlertConnectionError(context.Background(), "Binance Futures WebSocket", err)
+
+		select {
+		case f.wsErrChan <- err:
+		default:
+			// Channel full, drop error
+		}
+	}
+
+	doneC, stopC, err := futures.WsUserDataServe(listenKey, f.handleUserDataEvent, errHandler)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start futures user data WebSocket")
+		alerts.AlertConnectionError(ctx, "Binance Futures WebSocket", err)
+		return
+	}
+
+	log.Info().Msg("Futures user data WebSocket connected")
+
+	select {
+	case <-stopChan:
+		log.Info().Msg("Stop signal received, closing futures WebSocket")
+		stopC <- struct{}{}
+	case <-ctx.Done():
+		log.Info().Msg("Context cancelled, closing futures WebSocket")
+		stopC <- struct{}{}
+	case <-doneC:
+		log.Info().Msg("Futures WebSocket connection closed")
+	}
+}
+
+// handleUserDataEvent processes futures user data events
+func (f *BinanceFuturesExchange) handleUserDataEvent(event *futures.WsUserDataEvent) {
+	switch event.Event {
+	case futures.UserDataEventTypeOrderTradeUpdate:
+		f.handleOrderTradeUpdate(&event.OrderTradeUpdate)
+
+	case futures.UserDataEventTypeAccountUpdate:
+		f.handleAccountUpdate(&event.AccountUpdate, event.TransactionTime)
+
+	case futures.UserDataEventTypeMarginCall:
+		f.handleMarginCall(event)
+
+	case futures.UserDataEventTypeListenKeyExpired:
+		log.Warn().Msg("Futures listen key expired, user data stream must be restarted")
+		alerts.AlertConnectionError(context.Background(), "Binance Futures WebSocket", fmt.Errorf("listen key expired"))
+
+	default:
+		log.Debug().
+			Str("event_type", string(event.Event)).
+			Msg("Unhandled futures user data event received")
+	}
+}
+
+// handleOrderTradeUpdate applies an ORDER_TRADE_UPDATE event to the tracked order
+func (f *BinanceFuturesExchange) handleOrderTradeUpdate(update *futures.WsOrderTradeUpdate) {
+	exchangeOrderID := strconv.FormatInt(update.ID, 10)
+
+	log.Info().
+		Str("exchange_order_id", exchangeOrderID).
+		Str("symbol", update.Symbol).
+		Str("side", string(update.Side)).
+		Str("execution_type", string(update.ExecutionType)).
+		Str("status", string(update.Status)).
+		Str("filled_qty", update.AccumulatedFilledQty).
+		Msg("Futures order update received via WebSocket")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	internalID, mapped := f.exchangeOrderToInternal[exchangeOrderID]
+	if !mapped {
+		// Orders placed elsewhere, including liquidation and ADL orders
+		log.Warn().
+			Str("exchange_order_id", exchangeOrderID).
+			Str("order_type", string(update.OriginalType)).
+			Msg("Received futures order update for unknown exchange order ID")
+		return
+	}
+	order := f.orders[internalID]
+
+	at := time.UnixMilli(update.TradeTime)
+	if update.TradeTime == 0 {
+		at = time.Now()
+	}
+
+	if update.ExecutionType == futures.OrderExecutionTypeTrade {
+		lastQty := parseFloatOrZero(update.LastFilledQty)
+		lastPrice := parseFloatOrZero(update.LastFilledPrice)
+		if lastQty > 0 && lastPrice > 0 {
+			f.fills[order.ID] = append(f.fills[order.ID], Fill{
+				OrderID:   order.ID,
+				Quantity:  lastQty,
+				Price:     lastPrice,
+				Timestamp: at,
+			})
+		}
+	}
+
+	applyFuturesOrderState(order, update.Status, parseFloatOrZero(update.AccumulatedFilledQty), parseFloatOrZero(update.AveragePrice), at)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var cancelledAt *time.Time
+	if order.Status == OrderStatusCancelled {
+		cancelledAt = &at
+	}
+	f.updateDBOrderStatus(ctx, order, cancelledAt)
+}
+
+// handleAccountUpdate refreshes positions and persists wallet balances from an ACCOUNT_UPDATE event
+func (f *BinanceFuturesExchange) handleAccountUpdate(update *futures.WsAccountUpdate, transactionTime int64) {
+	updatedAt := time.Now()
+	if transactionTime > 0 {
+		updatedAt = time.UnixMilli(transactionTime)
+	}
+
+	f.mu.Lock()
+	for _, p := range update.Positions {
+		key := p.Symbol + ":" + string(p.Side)
+		quantity := parseFloatOrZero(p.Amount)
+		if quantity == 0 {
+			delete(f.positions, key)
+			continue
+		}
+
+		position, ok := f.positions[key]
+		if !ok {
+			position = &FuturesPosition{Symbol: p.Symbol, PositionSide: PositionSide(p.Side)}
+			f.positions[key] = position
+		}
+		position.Quantity = quantity
+		position.EntryPrice = parseFloatOrZero(p.EntryPrice)
+		position.UnrealizedPnL = parseFloatOrZero(p.UnrealizedPnL)
+		position.MarginType = MarginType(strings.ToUpper(string(p.MarginType)))
+		position.IsolatedMargin = parseFloatOrZero(p.IsolatedWallet)
+		position.IsolatedWallet = position.IsolatedMargin
+		position.UpdatedAt = updatedAt
+		if markPrice := parseFloatOrZero(p.MarkPrice); markPrice > 0 {
+			position.MarkPrice = markPrice
+		}
+		if leverage, ok := f.leverage[p.Symbol]; ok {
+			position.Leverage = leverage
+		}
+	}
+	f.mu.Unlock()
+
+	log.Debug().
+		Str("reason", string(update.Reason)).
+		Int("balances", len(update.Balances)).
+		Int("positions", len(update.Positions)).
+		Msg("Futures account update received")
+
+	if f.db == nil || len(update.Balances) == 0 {
+		return
+	}
+
+	rows := make([]*db.AccountBalance, 0, len(update.Balances))
+	for _, balance := range update.Balances {
+		rows = append(rows, &db.AccountBalance{
+			Exchange: binanceFuturesExchangeName,
+			Asset:    balance.Asset,
+			Free:     parseFloatOrZero(balance.CrossWalletBalance),
+			Locked:   parseFloatOrZero(balance.Balance) - parseFloatOrZero(balance.CrossWalletBalance),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := f.db.UpsertAccountBalances(ctx, rows); err != nil {
+		log.Error().Err(err).Msg("Failed to persist futures balance update")
+	}
+}
+
+// handleMarginCall raises a position risk alert for every position named in a MARGIN_CALL event
+func (f *BinanceFuturesExchange) handleMarginCall(event *futures.WsUserDataEvent) {
+	for _, p := range event.MarginCallPositions {
+		maintenance := parseFloatOrZero(p.MaintenanceMarginRequired)
+
+		log.Warn().
+			Str("symbol", p.Symbol).
+			Str("position_side", string(p.Side)).
+			Str("amount", p.Amount).
+			Str("mark_price", p.MarkPrice).
+			Float64("maintenance_margin", maintenance).
+			Msg("Futures margin call received")
+
+		alerts.AlertPositionRisk(context.Background(), p.Symbol, maintenance,
+			fmt.Sprintf("margin call on %s position (cross wallet balance %s)", p.Side, event.CrossWalletBalance))
+	}
+}
+
+// keepAliveListenKey keeps the listen key alive; futures keys expire after 60 minutes
+func (f *BinanceFuturesExchange) keepAliveListenKey(ctx context.Context, stopChan chan struct{}) {
+	ticker := time.NewTicker(30 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.mu.RLock()
+			listenKey := f.listenKey
+			connected := f.wsConnected
+			f.mu.RUnlock()
+
+			if !connected {
+				log.Debug().Msg("Futures WebSocket disconnected, stopping keep-alive")
+				return
+			}
+
+			if err := f.client.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to keep alive futures listen key")
+			} else {
+				log.Debug().Msg("Futures listen key kept alive")
+			}
+
+		case <-stopChan:
+			log.Debug().Msg("Stop signal received, stopping futures keep-alive")
+			return
+
+		case <-ctx.Done():
+			log.Debug().Msg("Context cancelled, stopping futures keep-alive")
+			return
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFuturesRoute is a canned response for one "METHOD /path" of the futures REST API
+type fakeFuturesRoute struct {
+	status int
+	body   string
+}
+
+// fakeFuturesRequest is a request received by the fake futures API
+type fakeFuturesRequest struct {
+	method string
+	path   string
+	params url.Values // Query string and form body combined
+}
+
+// fakeFuturesAPI serves canned futures REST responses and records every request
+type fakeFuturesAPI struct {
+	mu       sync.Mutex
+	routes   map[string]fakeFuturesRoute
+	requests []fakeFuturesRequest
+}
+
+func newFakeFuturesAPI(t *testing.T, routes map[string]fakeFuturesRoute) (*fakeFuturesAPI, *BinanceFuturesExchange) {
+	t.Helper()
+
+	api := &fakeFuturesAPI{routes: routes}
+	server := httptest.NewServer(http.HandlerFunc(api.serve))
+	t.Cleanup(server.Close)
+
+	ex, err := NewBinanceFuturesExchange(BinanceFuturesConfig{
+		APIKey:      "key",
+		SecretKey:   "secret",
+		BaseURL:     server.URL,
+		MaxLeverage: 10,
+	}, nil)
+	require.NoError(t, err)
+
+	return api, ex
+}
+
+func (a *fakeFuturesAPI) serve(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	if form, err := url.ParseQuery(string(body)); err == nil {
+		for k, v := range form {
+			params[k] = v
+		}
+	}
+
+	a.mu.Lock()
+	a.requests = append(a.requests, fakeFuturesRequest{method: r.Method, path: r.URL.Path, params: params})
+	route, ok := a.routes[r.Method+" "+r.URL.Path]
+	a.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":-1,"msg":"no route"}`))
+		return
+	}
+	if route.status != 0 {
+		w.WriteHeader(route.status)
+	}
+	_, _ = w.Write([]byte(route.body))
+}
+
+// find returns the requests sent to a method and path
+func (a *fakeFuturesAPI) find(method, path string) []fakeFuturesRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var found []fakeFuturesRequest
+	for _, req := range a.requests {
+		if req.method == method && req.path == path {
+			found = append(found, req)
+		}
+	}
+	return found
+}
+
+const futuresExchangeInfoJSON = `{"symbols":[{
+	"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT",
+	"pricePrecision":2,"quantityPrecision":3,
+	"filters":[
+		{"filterType":"PRICE_FILTER","tickSize":"0.10","minPrice":"556.80","maxPrice":"4529764"},
+		{"filterType":"LOT_SIZE","stepSize":"0.001","minQty":"0.001","maxQty":"1000"},
+		{"filterType":"MIN_NOTIONAL","notional":"100"}
+	]}]}`
+
+func TestBinanceFuturesExchange_PlaceOrderFlags(t *testing.T) {
+	api, ex := newFakeFuturesAPI(t, map[string]fakeFuturesRoute{
+		"GET /fapi/v1/exchangeInfo": {body: futuresExchangeInfoJSON},
+		"POST /fapi/v1/order": {body: `{"symbol":"BTCUSDT","orderId":42,"status":"FILLED","executedQty":"0.002",
+			"avgPrice":"50000.5","cumQuote":"100.001","reduceOnly":true,"positionSide":"BOTH"}`},
+	})
+	ctx := context.Background()
+
+	// 0.0029 rounds down to 0.002: 90 USDT is below the minimum notional, allowed only because it is reduce-only
+	resp, err := ex.PlaceOrder(ctx, PlaceOrderRequest{
+		Symbol: "BTCUSDT", Side: OrderSideSell, Type: OrderTypeLimit, Quantity: 0.0029, Price: 45000, ReduceOnly: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusFilled, resp.Status)
+
+	orders := api.find(http.MethodPost, "/fapi/v1/order")
+	require.Len(t, orders, 1)
+	params := orders[0].params
+	assert.Equal(t, "SELL", params.Get("side"))
+	assert.Equal(t, "LIMIT", params.Get("type"))
+	assert.Equal(t, "0.002", params.Get("quantity"))
+	assert.Equal(t, "45000.00", params.Get("price"))
+	assert.Equal(t, "true", params.Get("reduceOnly"))
+	assert.Equal(t, "RESULT", params.Get("newOrderRespType"))
+
+	order, err := ex.GetOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+	assert.True(t, order.ReduceOnly)
+	assert.Equal(t, PositionSideBoth, order.PositionSide)
+	assert.InDelta(t, 50000.5, order.AvgFillPrice, 1e-9)
+
+	// The same size without reduce-only is rejected locally
+	resp, err = ex.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideSell, Type: OrderTypeLimit, Quantity: 0.002, Price: 45000})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusRejected, resp.Status)
+	assert.Contains(t, resp.Message, "notional")
+
+	// Hedge-mode limit order carries its position side
+	_, err = ex.PlaceOrder(ctx, PlaceOrderRequest{
+		Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.01, Price: 49999.97, PositionSide: PositionSideLong,
+	})
+	require.NoError(t, err)
+	orders = api.find(http.MethodPost, "/fapi/v1/order")
+	require.Len(t, orders, 2)
+	params = orders[1].params
+	assert.Equal(t, "LONG", params.Get("positionSide"))
+	assert.Equal(t, "GTC", params.Get("timeInForce"))
+	assert.Equal(t, "50000.00", params.Get("price"))
+	assert.Empty(t, params.Get("reduceOnly"))
+
+	// Binance rejects reduceOnly in hedge mode, so it never reaches the API
+	resp, err = ex.PlaceOrder(ctx, PlaceOrderRequest{
+		Symbol: "BTCUSDT", Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 0.01, ReduceOnly: true, PositionSide: PositionSideLong,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusRejected, resp.Status)
+	assert.Len(t, api.find(http.MethodPost, "/fapi/v1/order"), 2)
+}
+
+func TestBinanceFuturesExchange_LeverageAndMarginType(t *testing.T) {
+	api, ex := newFakeFuturesAPI(t, map[string]fakeFuturesRoute{
+		"POST /fapi/v1/leverage":   {body: `{"leverage":5,"maxNotionalValue":"50000000","symbol":"BTCUSDT"}`},
+		"POST /fapi/v1/marginType": {status: http.StatusBadRequest, body: `{"code":-4046,"msg":"No need to change margin type."}`},
+	})
+	ctx := context.Background()
+
+	_, err := ex.SetLeverage(ctx, "BTCUSDT", 20)
+	require.Error(t, err, "above the configured maximum of 10x")
+	assert.Empty(t, api.find(http.MethodPost, "/fapi/v1/leverage"))
+
+	leverage, err := ex.SetLeverage(ctx, "btc/usdt", 5)
+	require.NoError(t, err)
+	assert.Equal(t, 5, leverage)
+
+	requests := api.find(http.MethodPost, "/fapi/v1/leverage")
+	require.Len(t, requests, 1)
+	assert.Equal(t, "BTCUSDT", requests[0].params.Get("symbol"))
+	assert.Equal(t, "5", requests[0].params.Get("leverage"))
+
+	stored, ok := ex.Leverage("BTCUSDT")
+	require.True(t, ok)
+	assert.Equal(t, 5, stored)
+
+	// Already isolated: -4046 is treated as success
+	require.NoError(t, ex.SetMarginType(ctx, "BTCUSDT", MarginTypeIsolated))
+	requests = api.find(http.MethodPost, "/fapi/v1/marginType")
+	require.Len(t, requests, 1)
+	assert.Equal(t, "ISOLATED", requests[0].params.Get("marginType"))
+
+	api.routes["POST /fapi/v1/marginType"] = fakeFuturesRoute{
+		status: http.StatusBadRequest,
+		body:   `{"code":-4048,"msg":"Margin type cannot be changed if there exists position."}`,
+	}
+	assert.Error(t, ex.SetMarginType(ctx, "BTCUSDT", MarginTypeCrossed))
+}
+
+func TestBinanceFuturesExchange_MarkAndLiquidationPrice(t *testing.T) {
+	_, ex := newFakeFuturesAPI(t, map[string]fakeFuturesRoute{
+		"GET /fapi/v1/premiumIndex": {body: `{"symbol":"BTCUSDT","markPrice":"50100.5","indexPrice":"50090.1",
+			"lastFundingRate":"0.0001","nextFundingTime":1700000000000,"time":1699999990000}`},
+		"GET /fapi/v2/positionRisk": {body: `[
+			{"symbol":"BTCUSDT","positionSide":"LONG","positionAmt":"0.5","entryPrice":"48000","markPrice":"50100.5",
+			 "liquidationPrice":"39000.25","unRealizedProfit":"1050.25","leverage":"5","marginType":"isolated","isolatedMargin":"4800"},
+			{"symbol":"BTCUSDT","positionSide":"SHORT","positionAmt":"0","entryPrice":"0","markPrice":"50100.5",
+			 "liquidationPrice":"0","unRealizedProfit":"0","leverage":"5","marginType":"isolated"}]`},
+	})
+	ctx := context.Background()
+
+	mark, err := ex.GetMarkPrice(ctx, "BTCUSDT")
+	require.NoError(t, err)
+	assert.InDelta(t, 50100.5, mark.MarkPrice, 1e-9)
+	assert.InDelta(t, 50090.1, mark.IndexPrice, 1e-9)
+	assert.InDelta(t, 0.0001, mark.FundingRate, 1e-12)
+	assert.Equal(t, int64(1700000000000), mark.NextFundingTime.UnixMilli())
+
+	positions, err := ex.GetPositions(ctx, "BTCUSDT")
+	require.NoError(t, err)
+	require.Len(t, positions, 1, "flat positions are skipped")
+	assert.Equal(t, PositionSideLong, positions[0].PositionSide)
+	assert.Equal(t, MarginTypeIsolated, positions[0].MarginType)
+	assert.Equal(t, 5, positions[0].Leverage)
+
+	liquidation, err := ex.GetLiquidationPrice(ctx, "BTCUSDT", PositionSideLong)
+	require.NoError(t, err)
+	assert.InDelta(t, 39000.25, liquidation, 1e-9)
+
+	_, err = ex.GetLiquidationPrice(ctx, "BTCUSDT", PositionSideShort)
+	assert.Error(t, err)
+}
+
+func TestBinanceFuturesExchange_AccountAndCancelAll(t *testing.T) {
+	api, ex := newFakeFuturesAPI(t, map[string]fakeFuturesRoute{
+		"GET /fapi/v2/account": {body: `{"canTrade":true,"updateTime":1700000000000,"assets":[
+			{"asset":"USDT","walletBalance":"1000","availableBalance":"750"},
+			{"asset":"BNB","walletBalance":"0","availableBalance":"0"}]}`},
+		"GET /fapi/v1/openOrders": {body: `[
+			{"symbol":"BTCUSDT","orderId":1,"side":"BUY","type":"LIMIT","origQty":"0.01","price":"40000","status":"NEW","reduceOnly":false,"positionSide":"BOTH"},
+			{"symbol":"ETHUSDT","orderId":2,"side":"SELL","type":"LIMIT","origQty":"1","price":"4000","status":"NEW","reduceOnly":true,"positionSide":"BOTH"}]`},
+		"DELETE /fapi/v1/allOpenOrders": {body: `{"code":200,"msg":"The operation of cancel all open order is done."}`},
+	})
+	ctx := context.Background()
+
+	account, err := ex.GetAccount(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, binanceFuturesExchangeName, account.Exchange)
+	require.Len(t, account.Balances, 1)
+	assert.Equal(t, Balance{Asset: "USDT", Free: 750, Locked: 250}, account.Balances[0])
+
+	cancelled, err := ex.CancelAllOrders(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, cancelled, 2)
+	assert.Equal(t, OrderStatusCancelled, cancelled[0].Status)
+	assert.True(t, cancelled[1].ReduceOnly)
+
+	deletes := api.find(http.MethodDelete, "/fapi/v1/allOpenOrders")
+	require.Len(t, deletes, 2)
+	assert.Equal(t, "BTCUSDT", deletes[0].params.Get("symbol"))
+	assert.Equal(t, "ETHUSDT", deletes[1].params.Get("symbol"))
+}
+
+func TestBinanceFuturesExchange_UserDataEvents(t *testing.T) {
+	_, ex := newFakeFuturesAPI(t, map[string]fakeFuturesRoute{
+		"POST /fapi/v1/order": {body: `{"symbol":"BTCUSDT","orderId":7,"status":"NEW","executedQty":"0","avgPrice":"0"}`},
+	})
+	ex.instruments.Set(Instrument{Symbol: "BTCUSDT", TickSize: 0.1, StepSize: 0.001})
+
+	resp, err := ex.PlaceOrder(context.Background(), PlaceOrderRequest{
+		Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.02, Price: 50000,
+	})
+	require.NoError(t, err)
+	require.Equal(t, OrderStatusOpen, resp.Status)
+
+	dispatch := func(raw string) {
+		event := new(futures.WsUserDataEvent)
+		require.NoError(t, json.Unmarshal([]byte(raw), event))
+		ex.handleUserDataEvent(event)
+	}
+
+	dispatch(`{"e":"ORDER_TRADE_UPDATE","E":1700000000100,"T":1700000000100,"o":{"s":"BTCUSDT","S":"BUY","o":"LIMIT",
+		"q":"0.02","p":"50000","ap":"50000","x":"TRADE","X":"PARTIALLY_FILLED","i":7,"l":"0.005","z":"0.005","L":"50000","T":1700000000100}}`)
+	dispatch(`{"e":"ORDER_TRADE_UPDATE","E":1700000000200,"T":1700000000200,"o":{"s":"BTCUSDT","S":"BUY","o":"LIMIT",
+		"q":"0.02","p":"50000","ap":"49999.25","x":"TRADE","X":"FILLED","i":7,"l":"0.015","z":"0.02","L":"49999","T":1700000000200}}`)
+
+	order, err := ex.GetOrderFills(context.Background(), resp.OrderID)
+	require.NoError(t, err)
+	require.Len(t, order, 2)
+	assert.InDelta(t, 0.015, order[1].Quantity, 1e-12)
+
+	ex.mu.RLock()
+	tracked := ex.orders[resp.OrderID]
+	ex.mu.RUnlock()
+	assert.Equal(t, OrderStatusFilled, tracked.Status)
+	assert.InDelta(t, 0.02, tracked.FilledQty, 1e-12)
+	assert.InDelta(t, 49999.25, tracked.AvgFillPrice, 1e-9)
+	require.NotNil(t, tracked.FilledAt)
+
+	dispatch(`{"e":"ACCOUNT_UPDATE","E":1700000000300,"T":1700000000300,"a":{"m":"ORDER",
+		"B":[{"a":"USDT","wb":"1000","cw":"900","bc":"0"}],
+		"P":[{"s":"BTCUSDT","ps":"BOTH","pa":"0.02","ep":"49999.25","up":"0.5","mt":"isolated","iw":"200"}]}}`)
+
+	positions := ex.Positions()
+	require.Len(t, positions, 1)
+	assert.InDelta(t, 0.02, positions[0].Quantity, 1e-12)
+	assert.Equal(t, MarginTypeIsolated, positions[0].MarginType)
+	assert.InDelta(t, 200, positions[0].IsolatedMargin, 1e-9)
+
+	dispatch(`{"e":"MARGIN_CALL","E":1700000000400,"cw":"3.16","p":[{"s":"BTCUSDT","ps":"BOTH","pa":"0.02",
+		"mt":"ISOLATED","iw":"1.2","mp":"40000","up":"-199","mm":"2.5"}]}`)
+
+	// A closing update removes the position
+	dispatch(`{"e":"ACCOUNT_UPDATE","E":1700000000500,"T":1700000000500,"a":{"m":"ORDER","B":[],
+		"P":[{"s":"BTCUSDT","ps":"BOTH","pa":"0","ep":"0","up":"0","mt":"isolated","iw":"0"}]}}`)
+	assert.Empty(t, ex.Positions())
+}
+
+func TestParseMarginType(t *testing.T) {
+	for input, want := range map[string]MarginType{
+		"cross":    MarginTypeCrossed,
+		"CROSSED":  MarginTypeCrossed,
+		"isolated": MarginTypeIsolated,
+	} {
+		got, err := ParseMarginType(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got)
+	}
+
+	_, err := ParseMarginType("portfolio")
+	assert.Error(t, err)
+}
+
+func TestBinanceFuturesExchange_ForSessionSharesVenue(t *testing.T) {
+	venue, err := NewBinanceFuturesExchange(BinanceFuturesConfig{APIKey: "key", SecretKey: "secret"}, nil)
+	require.NoError(t, err)
+
+	forked, ok := venue.forSession().(*BinanceFuturesExchange)
+	require.True(t, ok)
+	assert.Same(t, venue.client, forked.client)
+	assert.Same(t, venue.rateBudget, forked.rateBudget)
+	assert.Same(t, venue.instruments, forked.instruments)
+	assert.True(t, forked.forked)
+	assert.False(t, venue.forked)
+}
@@ -0,0 +1,106 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/exchange/cassette"
+)
+
+// newReplayBinanceExchange creates a spot exchange talking to a cassette replay server
+func newReplayBinanceExchange(t *testing.T, name string) (*BinanceExchange, *cassette.Server) {
+	t.Helper()
+
+	c, err := cassette.Load("testdata/cassettes/" + name + ".json")
+	require.NoError(t, err)
+	server := cassette.NewServer(c)
+
+	mainURL, testnetURL := binance.BaseWsMainURL, binance.BaseWsTestnetURL
+	t.Cleanup(func() {
+		server.Close()
+		binance.BaseWsMainURL, binance.BaseWsTestnetURL = mainURL, testnetURL
+	})
+
+	b, err := NewBinanceExchange(BinanceConfig{
+		APIKey:    "replay-key",
+		SecretKey: "replay-secret",
+		BaseURL:   server.URL(),
+		WsBaseURL: server.WebSocketURL(),
+	}, nil)
+	require.NoError(t, err)
+	return b, server
+}
+
+// userDataStreamConnected reports whether the exchange still considers its stream live
+func userDataStreamConnected(b *BinanceExchange) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.wsConnected
+}
+
+func TestBinanceExchange_ReplayPartialFillsAndDisconnect(t *testing.T) {
+	b, server := newReplayBinanceExchange(t, "binance_spot_partial_fill")
+	ctx := context.Background()
+
+	sessionID := uuid.New()
+	b.SetSession(&sessionID)
+	b.positionMgr.SetSession(&sessionID)
+
+	resp, err := b.PlaceOrder(ctx, PlaceOrderRequest{
+		Symbol:   "BTCUSDT",
+		Side:     OrderSideBuy,
+		Type:     OrderTypeLimit,
+		Quantity: 0.02,
+		Price:    45000,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusOpen, resp.Status)
+
+	// First stream delivers two partial fills, then the venue drops the connection
+	require.NoError(t, b.StartUserDataStream(ctx))
+	require.Eventually(t, func() bool { return !userDataStreamConnected(b) }, 5*time.Second, 10*time.Millisecond)
+
+	fills, err := b.GetOrderFills(ctx, resp.OrderID)
+	require.NoError(t, err)
+	require.Len(t, fills, 2)
+	assert.InDelta(t, 0.008, fills[0].Quantity, 1e-9)
+	assert.InDelta(t, 44990, fills[0].Price, 1e-9)
+	assert.InDelta(t, 0.005, fills[1].Quantity, 1e-9)
+
+	order, err := b.GetOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusOpen, order.Status)
+	assert.InDelta(t, 0.013, order.FilledQty, 1e-9)
+	assert.InDelta(t, 584.92/0.013, order.AvgFillPrice, 1e-6)
+
+	position, ok := b.positionMgr.GetNetPosition("BTCUSDT")
+	require.True(t, ok)
+	assert.InDelta(t, 0.013, position.Quantity, 1e-9)
+
+	// Reconnecting picks up the final fill
+	require.NoError(t, b.StartUserDataStream(ctx))
+	require.Eventually(t, func() bool {
+		fills, _ := b.GetOrderFills(ctx, resp.OrderID)
+		return len(fills) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, b.StopUserDataStream(ctx))
+
+	b.mu.RLock()
+	status := b.orders[resp.OrderID].Status
+	b.mu.RUnlock()
+	assert.Equal(t, OrderStatusFilled, status)
+
+	position, ok = b.positionMgr.GetNetPosition("BTCUSDT")
+	require.True(t, ok)
+	assert.InDelta(t, 0.02, position.Quantity, 1e-9)
+	assert.InDelta(t, 899.92/0.02, position.AvgEntryPrice, 1e-6)
+
+	assert.Empty(t, server.Unmatched())
+	assert.Empty(t, server.Pending())
+}
@@ -0,0 +1,207 @@
+// Package cassette records REST and WebSocket traffic between an exchange adapter
+// and its venue into cassette files, and replays them from a fake server so
+// adapters can be tested end-to-end without network access.
+//
+// Recording: run a Recorder in front of the real venue and point the adapter's
+// REST and WebSocket base URLs at it. Replay: load the cassette into a Replayer
+// (or NewServer in tests) and point the adapter at the replay server instead.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Request parameters that change on every call (or carry credentials). They are
+// never written to a cassette and are ignored when matching requests on replay.
+var volatileParams = map[string]bool{
+	"timestamp":        true,
+	"signature":        true,
+	"recvWindow":       true,
+	"newClientOrderId": true,
+}
+
+// Cassette is a recorded exchange session
+type Cassette struct {
+	Name         string        `json:"name"`
+	RecordedAt   time.Time     `json:"recorded_at"`
+	Interactions []Interaction `json:"interactions"`
+	Streams      []Stream      `json:"streams,omitempty"`
+}
+
+// Interaction is one REST request and the venue's response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request identifies a REST call by method, path and (non-volatile) parameters
+type Request struct {
+	Method string            `json:"method"`
+	Path   string            `json:"path"`
+	Params map[string]string `json:"params,omitempty"` // Query and form parameters merged
+}
+
+// Response is a recorded REST response
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"` // Content-Type and X-* headers (e.g. rate limit usage)
+	Body    json.RawMessage   `json:"body"`
+}
+
+// Stream is one recorded WebSocket connection
+type Stream struct {
+	Path     string    `json:"path"`
+	Messages []Message `json:"messages"`
+
+	// Disconnect is set when the venue dropped the connection after the last
+	// message; replay then closes the connection without a close frame
+	Disconnect bool `json:"disconnect,omitempty"`
+}
+
+// Message is one server-to-client WebSocket message
+type Message struct {
+	OffsetMS int64           `json:"offset_ms"` // Time since the connection was opened
+	Data     json.RawMessage `json:"data"`
+}
+
+// Load reads a cassette from a JSON file
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if c.Name == "" {
+		c.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return &c, nil
+}
+
+// Save writes the cassette as indented JSON, creating parent directories
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// key returns the canonical form used to match a request on replay
+func (r Request) key() string {
+	names := make([]string, 0, len(r.Params))
+	for name := range r.Params {
+		if !volatileParams[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(strings.ToUpper(r.Method))
+	b.WriteByte(' ')
+	b.WriteString(r.Path)
+	for i, name := range names {
+		if i == 0 {
+			b.WriteByte('?')
+		} else {
+			b.WriteByte('&')
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(r.Params[name])
+	}
+	return b.String()
+}
+
+// newRequest builds a Request from an HTTP request and its already read body,
+// merging query and form parameters and dropping volatile ones
+func newRequest(req *http.Request, body []byte) Request {
+	params := make(map[string]string)
+	addParams := func(values url.Values) {
+		for name, v := range values {
+			if volatileParams[name] || len(v) == 0 {
+				continue
+			}
+			params[name] = v[0]
+		}
+	}
+
+	addParams(req.URL.Query())
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			addParams(form)
+		}
+	}
+
+	if len(params) == 0 {
+		params = nil
+	}
+	return Request{Method: req.Method, Path: req.URL.Path, Params: params}
+}
+
+// rawBody stores a body as JSON, quoting it if the venue returned something else
+func rawBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return json.RawMessage("null")
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// bodyBytes reverses rawBody, compacting JSON that was indented when the cassette was saved
+func bodyBytes(raw json.RawMessage) []byte {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return []byte(s)
+		}
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return raw
+	}
+	return compact.Bytes()
+}
+
+// recordedHeaders keeps the response headers worth replaying
+func recordedHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for name, values := range header {
+		if len(values) == 0 {
+			continue
+		}
+		if name == "Content-Type" || strings.HasPrefix(name, "X-") {
+			headers[name] = values[0]
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
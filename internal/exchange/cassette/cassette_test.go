@@ -0,0 +1,156 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVenue serves a REST order endpoint and a stream that drops after two messages
+func fakeVenue(t *testing.T) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	orderPolls := 0
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"executionReport","X":"PARTIALLY_FILLED"}`))
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"e":"executionReport","X":"FILLED"}`))
+			_ = conn.Close() // Drop without a close frame
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Mbx-Used-Weight-1m", "7")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /api/v3/order":
+			assert.Equal(t, "secret-key", r.Header.Get("X-MBX-APIKEY"))
+			_, _ = io.WriteString(w, `{"orderId":42,"status":"NEW"}`)
+		case "GET /api/v3/order":
+			orderPolls++
+			if orderPolls == 1 {
+				_, _ = io.WriteString(w, `{"orderId":42,"status":"PARTIALLY_FILLED"}`)
+			} else {
+				_, _ = io.WriteString(w, `{"orderId":42,"status":"FILLED"}`)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"code":-1,"msg":"unknown"}`)
+		}
+	}))
+}
+
+func doRequest(t *testing.T, method, rawURL, body string) (int, string, http.Header) {
+	t.Helper()
+	req, err := http.NewRequest(method, rawURL, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("X-MBX-APIKEY", "secret-key")
+	if body != "" {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data), resp.Header
+}
+
+func readStream(t *testing.T, wsURL string) ([]string, error) {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	var messages []string
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, string(data))
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	venue := fakeVenue(t)
+	defer venue.Close()
+
+	recorder := NewRecorder("session", venue.URL, "ws"+strings.TrimPrefix(venue.URL, "http"))
+	proxy := httptest.NewServer(recorder)
+	defer proxy.Close()
+
+	orderForm := url.Values{"symbol": {"BTCUSDT"}, "side": {"BUY"}, "quantity": {"0.01"}, "timestamp": {"1"}, "signature": {"abc"}}
+	status, body, _ := doRequest(t, http.MethodPost, proxy.URL+"/api/v3/order", orderForm.Encode())
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"orderId":42,"status":"NEW"}`, body)
+	doRequest(t, http.MethodGet, proxy.URL+"/api/v3/order?symbol=BTCUSDT&orderId=42&timestamp=1", "")
+	doRequest(t, http.MethodGet, proxy.URL+"/api/v3/order?symbol=BTCUSDT&orderId=42&timestamp=2", "")
+
+	messages, _ := readStream(t, "ws"+strings.TrimPrefix(proxy.URL, "http")+"/ws/listen-key")
+	assert.Len(t, messages, 2)
+
+	recorded := recorder.Cassette()
+	require.Len(t, recorded.Interactions, 3)
+	require.Len(t, recorded.Streams, 1)
+	assert.True(t, recorded.Streams[0].Disconnect)
+	assert.Equal(t, "/ws/listen-key", recorded.Streams[0].Path)
+
+	// Credentials and volatile parameters never reach the cassette
+	params := recorded.Interactions[0].Request.Params
+	assert.Equal(t, "BTCUSDT", params["symbol"])
+	assert.NotContains(t, params, "signature")
+	assert.NotContains(t, params, "timestamp")
+	assert.Equal(t, "7", recorded.Interactions[0].Response.Headers["X-Mbx-Used-Weight-1m"])
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	require.NoError(t, recorded.Save(path))
+	loaded, err := Load(path)
+	require.NoError(t, err)
+
+	server := NewServer(loaded)
+	defer server.Close()
+
+	// Matching ignores signatures, timestamps and parameter order
+	replayForm := url.Values{"quantity": {"0.01"}, "side": {"BUY"}, "symbol": {"BTCUSDT"}, "timestamp": {"99"}, "signature": {"zzz"}}
+	status, body, headers := doRequest(t, http.MethodPost, server.URL()+"/api/v3/order", replayForm.Encode())
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"orderId":42,"status":"NEW"}`, body)
+	assert.Equal(t, "7", headers.Get("X-Mbx-Used-Weight-1m"))
+
+	// Repeated requests replay in order, then keep returning the last response
+	_, body, _ = doRequest(t, http.MethodGet, server.URL()+"/api/v3/order?orderId=42&symbol=BTCUSDT", "")
+	assert.Contains(t, body, "PARTIALLY_FILLED")
+	for i := 0; i < 2; i++ {
+		_, body, _ = doRequest(t, http.MethodGet, server.URL()+"/api/v3/order?orderId=42&symbol=BTCUSDT", "")
+		assert.Contains(t, body, `"FILLED"`)
+	}
+
+	status, _, _ = doRequest(t, http.MethodGet, server.URL()+"/api/v3/account", "")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, []string{"GET /api/v3/account"}, server.Unmatched())
+	assert.Empty(t, server.Pending())
+
+	// The stream replays its messages, then drops the connection without a close frame
+	messages, err = readStream(t, server.WebSocketURL()+"/listen-key")
+	assert.Equal(t, []string{`{"e":"executionReport","X":"PARTIALLY_FILLED"}`, `{"e":"executionReport","X":"FILLED"}`}, messages)
+	assert.False(t, websocket.IsCloseError(err, websocket.CloseNormalClosure))
+}
+
+func TestRequestKey(t *testing.T) {
+	a := Request{Method: "get", Path: "/api/v3/order", Params: map[string]string{"symbol": "BTCUSDT", "orderId": "1", "recvWindow": "5000"}}
+	b := Request{Method: "GET", Path: "/api/v3/order", Params: map[string]string{"orderId": "1", "symbol": "BTCUSDT"}}
+	assert.Equal(t, a.key(), b.key())
+	assert.Equal(t, "GET /api/v3/order?orderId=1&symbol=BTCUSDT", b.key())
+}
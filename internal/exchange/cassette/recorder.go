@@ -0,0 +1,205 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+// Headers that belong to a single hop and are not forwarded by the recorder
+var hopHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+	"Keep-Alive":        true,
+	"Upgrade":           true,
+}
+
+// Recorder is a proxy that forwards REST and WebSocket traffic to a venue and
+// records it into a cassette. Credentials (API key header, signatures) and
+// volatile parameters are never written to the cassette.
+type Recorder struct {
+	restUpstream string // e.g. https://testnet.binance.vision
+	wsUpstream   string // e.g. wss://stream.testnet.binance.vision (paths are appended)
+	client       *http.Client
+	dialer       *websocket.Dialer
+	upgrader     websocket.Upgrader
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecorder creates a recording proxy for the given REST and WebSocket upstreams
+func NewRecorder(name, restUpstream, wsUpstream string) *Recorder {
+	return &Recorder{
+		restUpstream: strings.TrimSuffix(restUpstream, "/"),
+		wsUpstream:   strings.TrimSuffix(wsUpstream, "/"),
+		client:       &http.Client{Timeout: 30 * time.Second},
+		dialer:       websocket.DefaultDialer,
+		upgrader:     websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }},
+		cassette: &Cassette{
+			Name:       name,
+			RecordedAt: time.Now().UTC(),
+		},
+	}
+}
+
+// Cassette returns a copy of everything recorded so far
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := *r.cassette
+	c.Interactions = append([]Interaction(nil), r.cassette.Interactions...)
+	c.Streams = make([]Stream, len(r.cassette.Streams))
+	for i, stream := range r.cassette.Streams {
+		c.Streams[i] = stream
+		c.Streams[i].Messages = append([]Message(nil), stream.Messages...)
+	}
+	return &c
+}
+
+// ServeHTTP proxies a REST request or WebSocket connection to the venue
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if websocket.IsWebSocketUpgrade(req) {
+		r.proxyStream(w, req)
+		return
+	}
+	r.proxyREST(w, req)
+}
+
+// proxyREST forwards a REST call and records the exchange
+func (r *Recorder) proxyREST(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	upstreamURL := r.restUpstream + req.URL.Path
+	if req.URL.RawQuery != "" {
+		upstreamURL += "?" + req.URL.RawQuery
+	}
+	upstreamReq, err := http.NewRequestWithContext(req.Context(), req.Method, upstreamURL, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	for name, values := range req.Header {
+		if hopHeaders[name] || name == "Accept-Encoding" {
+			continue
+		}
+		upstreamReq.Header[name] = values
+	}
+
+	resp, err := r.client.Do(upstreamReq)
+	if err != nil {
+		log.Warn().Err(err).Str("path", req.URL.Path).Msg("Recorder upstream request failed")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: newRequest(req, body),
+		Response: Response{
+			Status:  resp.StatusCode,
+			Headers: recordedHeaders(resp.Header),
+			Body:    rawBody(respBody),
+		},
+	})
+	r.mu.Unlock()
+
+	for name, values := range resp.Header {
+		if !hopHeaders[name] {
+			w.Header()[name] = values
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = w.Write(respBody)
+}
+
+// proxyStream relays a WebSocket connection, recording venue-to-client messages
+func (r *Recorder) proxyStream(w http.ResponseWriter, req *http.Request) {
+	upstreamURL := r.wsUpstream + req.URL.Path
+	if req.URL.RawQuery != "" {
+		upstreamURL += "?" + req.URL.RawQuery
+	}
+
+	upstream, _, err := r.dialer.DialContext(req.Context(), upstreamURL, nil)
+	if err != nil {
+		log.Warn().Err(err).Str("path", req.URL.Path).Msg("Recorder failed to dial upstream stream")
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = upstream.Close() }()
+
+	client, err := r.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+	defer func() { _ = client.Close() }()
+
+	r.mu.Lock()
+	index := len(r.cassette.Streams)
+	r.cassette.Streams = append(r.cassette.Streams, Stream{Path: req.URL.Path})
+	r.mu.Unlock()
+
+	// Forward client messages (subscriptions, pongs) without recording them
+	clientGone := make(chan struct{})
+	go func() {
+		for {
+			msgType, data, err := client.ReadMessage()
+			if err != nil {
+				close(clientGone)
+				_ = upstream.Close()
+				return
+			}
+			if err := upstream.WriteMessage(msgType, data); err != nil {
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	for {
+		msgType, data, err := upstream.ReadMessage()
+		if err != nil {
+			select {
+			case <-clientGone:
+				// Client hung up first; this is a normal end of stream
+			default:
+				r.mu.Lock()
+				r.cassette.Streams[index].Disconnect = true
+				r.mu.Unlock()
+				log.Warn().Err(err).Str("path", req.URL.Path).Msg("Upstream stream disconnected during recording")
+			}
+			return
+		}
+
+		r.mu.Lock()
+		r.cassette.Streams[index].Messages = append(r.cassette.Streams[index].Messages, Message{
+			OffsetMS: time.Since(start).Milliseconds(),
+			Data:     rawBody(data),
+		})
+		r.mu.Unlock()
+
+		if err := client.WriteMessage(msgType, data); err != nil {
+			return
+		}
+	}
+}
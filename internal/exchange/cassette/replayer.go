@@ -0,0 +1,249 @@
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+// Binance-style error code returned for requests the cassette does not contain
+const errCodeNotRecorded = -1000
+
+// Replayer serves a cassette. REST requests are matched by method, path and
+// non-volatile parameters; repeated requests consume recorded responses in order
+// and keep returning the last one once exhausted (e.g. order status polling).
+// WebSocket connections replay recorded streams in order.
+type Replayer struct {
+	cassette *Cassette
+	upgrader websocket.Upgrader
+
+	// RealTime replays stream messages at their recorded offsets instead of
+	// sending them back to back
+	RealTime bool
+
+	mu          sync.Mutex
+	used        []bool
+	lastServed  map[string]int // Request key -> last served interaction
+	streamsUsed []bool
+	unmatched   []string
+	conns       map[*websocket.Conn]bool
+}
+
+// NewReplayer creates a replayer for a cassette
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{
+		cassette:    c,
+		upgrader:    websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }},
+		used:        make([]bool, len(c.Interactions)),
+		lastServed:  make(map[string]int),
+		streamsUsed: make([]bool, len(c.Streams)),
+		conns:       make(map[*websocket.Conn]bool),
+	}
+}
+
+// ServeHTTP replays the recorded response for a REST request or stream for a WebSocket upgrade
+func (r *Replayer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if websocket.IsWebSocketUpgrade(req) {
+		r.serveStream(w, req)
+		return
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	key := newRequest(req, body).key()
+
+	interaction, ok := r.match(key)
+	if !ok {
+		r.mu.Lock()
+		r.unmatched = append(r.unmatched, key)
+		r.mu.Unlock()
+
+		log.Warn().Str("request", key).Str("cassette", r.cassette.Name).Msg("Request not found in cassette")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"code": errCodeNotRecorded,
+			"msg":  fmt.Sprintf("cassette %s has no recorded response for %s", r.cassette.Name, key),
+		})
+		return
+	}
+
+	for name, value := range interaction.Response.Headers {
+		w.Header().Set(name, value)
+	}
+	w.WriteHeader(interaction.Response.Status)
+	_, _ = w.Write(bodyBytes(interaction.Response.Body))
+}
+
+// match returns the next unused interaction for a request key, or the last one
+// served for it when all have been used
+func (r *Replayer) match(key string) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Request.key() != key {
+			continue
+		}
+		r.used[i] = true
+		r.lastServed[key] = i
+		return interaction, true
+	}
+
+	if i, ok := r.lastServed[key]; ok {
+		return r.cassette.Interactions[i], true
+	}
+	return Interaction{}, false
+}
+
+// serveStream replays the next recorded stream for the path (or the next unused
+// stream if none was recorded on this exact path)
+func (r *Replayer) serveStream(w http.ResponseWriter, req *http.Request) {
+	stream, ok := r.nextStream(req.URL.Path)
+	if !ok {
+		r.mu.Lock()
+		r.unmatched = append(r.unmatched, "WS "+req.URL.Path)
+		r.mu.Unlock()
+		http.Error(w, "no recorded stream", http.StatusNotFound)
+		return
+	}
+
+	conn, err := r.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		log.Warn().Err(err).Str("path", req.URL.Path).Msg("Failed to upgrade replay stream")
+		return
+	}
+	r.mu.Lock()
+	r.conns[conn] = true
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.conns, conn)
+		r.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	// Read until the client goes away so control frames are handled
+	clientGone := make(chan struct{})
+	go func() {
+		defer close(clientGone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	for _, msg := range stream.Messages {
+		if r.RealTime {
+			wait := time.Until(start.Add(time.Duration(msg.OffsetMS) * time.Millisecond))
+			select {
+			case <-time.After(wait):
+			case <-clientGone:
+				return
+			}
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, bodyBytes(msg.Data)); err != nil {
+			return
+		}
+	}
+
+	if stream.Disconnect {
+		// Drop the TCP connection without a close frame, as a venue outage would
+		return
+	}
+
+	<-clientGone
+}
+
+// nextStream claims the next unused stream, preferring an exact path match
+func (r *Replayer) nextStream(path string) (Stream, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, stream := range r.cassette.Streams {
+		if !r.streamsUsed[i] && stream.Path == path {
+			r.streamsUsed[i] = true
+			return stream, true
+		}
+	}
+	for i, stream := range r.cassette.Streams {
+		if !r.streamsUsed[i] {
+			r.streamsUsed[i] = true
+			return stream, true
+		}
+	}
+	return Stream{}, false
+}
+
+// Unmatched returns the requests that had no recorded response
+func (r *Replayer) Unmatched() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.unmatched...)
+}
+
+// Pending returns the recorded interactions that were never requested
+func (r *Replayer) Pending() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pending []Interaction
+	for i, interaction := range r.cassette.Interactions {
+		if !r.used[i] {
+			pending = append(pending, interaction)
+		}
+	}
+	return pending
+}
+
+// closeStreams drops every open stream connection
+func (r *Replayer) closeStreams() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for conn := range r.conns {
+		_ = conn.Close()
+	}
+}
+
+// Server is a replayer listening on a local test server
+type Server struct {
+	*Replayer
+	server *httptest.Server
+}
+
+// NewServer starts a local server replaying the cassette
+func NewServer(c *Cassette) *Server {
+	replayer := NewReplayer(c)
+	return &Server{
+		Replayer: replayer,
+		server:   httptest.NewServer(replayer),
+	}
+}
+
+// URL returns the REST base URL
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// WebSocketURL returns the WebSocket base URL (ws://host/ws), matching the
+// layout of Binance stream endpoints
+func (s *Server) WebSocketURL() string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http") + "/ws"
+}
+
+// Close shuts the server down, closing any open streams
+func (s *Server) Close() {
+	s.closeStreams()
+	s.server.Close()
+}
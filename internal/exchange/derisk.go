@@ -0,0 +1,143 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
+)
+
+// ErrNoReductions is returned when a de-risking request names no positions
+var ErrNoReductions = errors.New("no position reductions requested")
+
+// PositionReduction asks for part or all of an open position to be closed
+type PositionReduction struct {
+	PositionID string  `json:"position_id"`
+	Quantity   float64 `json:"quantity"`
+}
+
+// ReduceRequest is a de-risking step. Metadata, such as the risk metrics
+// before and after the step, is recorded with the step's audit event.
+type ReduceRequest struct {
+	Trigger    FlattenTrigger         `json:"trigger"`
+	Reductions []PositionReduction    `json:"reductions"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Reduce closes part or all of each listed position with the kill switch's
+// close method. Unlike a flatten it leaves open orders and the orchestrator
+// alone. It cannot run while a flatten is in progress.
+func (k *KillSwitch) Reduce(ctx context.Context, req ReduceRequest) (*FlattenReport, error) {
+	if len(req.Reductions) == 0 {
+		return nil, ErrNoReductions
+	}
+	if k.store == nil {
+		return nil, fmt.Errorf("de-risking needs a position store")
+	}
+	if k.HaltOnly() {
+		return nil, fmt.Errorf("de-risking needs an exchange")
+	}
+	if req.Trigger.Rule == "" {
+		req.Trigger.Rule = "manual"
+	}
+
+	k.mu.Lock()
+	if k.running {
+		k.mu.Unlock()
+		return nil, ErrFlattenInProgress
+	}
+	k.running = true
+	k.mu.Unlock()
+
+	defer func() {
+		k.mu.Lock()
+		k.running = false
+		k.mu.Unlock()
+	}()
+
+	// Cutting risk must not be shed or delayed by the exchange rate budget
+	ctx = WithRequestPriority(ctx, PriorityCritical)
+
+	report := &FlattenReport{
+		Trigger:         req.Trigger,
+		StartedAt:       time.Now(),
+		CancelledOrders: make([]string, 0),
+		ClosedPositions: make([]ClosedPosition, 0),
+	}
+
+	log.Warn().
+		Str("source", req.Trigger.Source).
+		Str("rule", req.Trigger.Rule).
+		Str("reason", req.Trigger.Reason).
+		Int("positions", len(req.Reductions)).
+		Msg("De-risking: reducing positions")
+
+	positions, err := k.store.GetAllOpenPositions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load open positions: %w", err)
+	}
+	byID := make(map[string]int, len(positions))
+	for i, position := range positions {
+		byID[position.ID.String()] = i
+	}
+
+	reduced := 0.0
+	for _, reduction := range req.Reductions {
+		i, ok := byID[reduction.PositionID]
+		if !ok {
+			report.Errors = append(report.Errors, fmt.Sprintf("position %s is not open", reduction.PositionID))
+			continue
+		}
+		if reduction.Quantity <= 0 {
+			report.Errors = append(report.Errors, fmt.Sprintf("position %s: quantity must be positive", reduction.PositionID))
+			continue
+		}
+
+		result := k.reducePosition(ctx, req.Trigger, positions[i], reduction.Quantity)
+		report.ClosedPositions = append(report.ClosedPositions, result)
+		reduced += result.FilledQty * result.AvgPrice
+		if result.Error != "" {
+			report.Errors = append(report.Errors, fmt.Sprintf("reduce %s position %s: %s", result.Symbol, result.PositionID, result.Error))
+		}
+	}
+
+	report.CompletedAt = time.Now()
+	report.Success = len(report.Errors) == 0
+
+	metadata := map[string]interface{}{
+		"positions":        len(report.ClosedPositions),
+		"reduced_notional": reduced,
+		"errors":           report.Errors,
+	}
+	for key, value := range req.Metadata {
+		metadata[key] = value
+	}
+	severity := audit.SeverityWarning
+	errMsg := ""
+	if !report.Success {
+		severity = audit.SeverityError
+		errMsg = fmt.Sprintf("%d step(s) failed", len(report.Errors))
+	}
+	k.record(ctx, req.Trigger, &audit.Event{
+		EventType: audit.EventTypeDeRiskExecuted,
+		Severity:  severity,
+		Action:    "De-risking step completed",
+		Success:   report.Success,
+		ErrorMsg:  errMsg,
+		Duration:  report.CompletedAt.Sub(report.StartedAt).Milliseconds(),
+		Metadata:  metadata,
+	})
+
+	log.Warn().
+		Str("rule", req.Trigger.Rule).
+		Int("positions", len(report.ClosedPositions)).
+		Float64("reduced_notional", reduced).
+		Int("errors", len(report.Errors)).
+		Msg("De-risking step completed")
+
+	return report, nil
+}
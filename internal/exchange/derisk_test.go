@@ -0,0 +1,71 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+func TestKillSwitch_ReducePartiallyClosesPositions(t *testing.T) {
+	ks, mock, store := newTestKillSwitch(t, DefaultKillSwitchConfig())
+	ctx := context.Background()
+
+	// Open orders are left alone by a reduction
+	mock.SetMarketPrice("BTCUSDT", 50000)
+	resp, err := mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.01, Price: 45000})
+	require.NoError(t, err)
+
+	longID := store.addPosition("BTCUSDT", db.PositionSideLong, 48000, 0.4)
+	shortID := store.addPosition("ETHUSDT", db.PositionSideShort, 3100, 2)
+
+	report, err := ks.Reduce(ctx, ReduceRequest{
+		Trigger: FlattenTrigger{Source: "risk_agent", Reason: "drawdown above soft limit", Rule: "soft_drawdown"},
+		Reductions: []PositionReduction{
+			{PositionID: longID.String(), Quantity: 0.1},
+			{PositionID: shortID.String(), Quantity: 2},
+		},
+		Metadata: map[string]interface{}{"before": map[string]float64{"exposure": 26200}},
+	})
+	require.NoError(t, err)
+	assert.True(t, report.Success, "errors: %v", report.Errors)
+	require.Len(t, report.ClosedPositions, 2)
+	assert.Empty(t, report.CancelledOrders)
+
+	long := report.ClosedPositions[0]
+	assert.Equal(t, OrderSideSell, long.Side)
+	assert.InDelta(t, 0.1, long.FilledQty, 1e-9)
+	assert.False(t, long.Closed)
+	assert.InDelta(t, 0.3, store.positions[longID].Quantity, 1e-9)
+	assert.Nil(t, store.positions[longID].ExitTime)
+
+	short := report.ClosedPositions[1]
+	assert.Equal(t, OrderSideBuy, short.Side)
+	assert.True(t, short.Closed)
+	require.NotNil(t, store.positions[shortID].ExitReason)
+	assert.Equal(t, "De-risking: drawdown above soft limit", *store.positions[shortID].ExitReason)
+
+	order, err := mock.GetOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusOpen, order.Status)
+}
+
+func TestKillSwitch_ReduceRejectsUnknownPositions(t *testing.T) {
+	ks, _, _ := newTestKillSwitch(t, DefaultKillSwitchConfig())
+	ctx := context.Background()
+
+	_, err := ks.Reduce(ctx, ReduceRequest{Trigger: FlattenTrigger{Source: "api", Reason: "test"}})
+	assert.ErrorIs(t, err, ErrNoReductions)
+
+	report, err := ks.Reduce(ctx, ReduceRequest{
+		Trigger:    FlattenTrigger{Source: "api", Reason: "test"},
+		Reductions: []PositionReduction{{PositionID: "missing", Quantity: 1}},
+	})
+	require.NoError(t, err)
+	assert.False(t, report.Success)
+	assert.Equal(t, "manual", report.Trigger.Rule)
+	assert.Len(t, report.Errors, 1)
+}
@@ -0,0 +1,340 @@
+package exchange
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// stepEpsilon absorbs floating point noise when checking step/tick multiples
+const stepEpsilon = 1e-9
+
+// Instrument describes the trading rules an exchange enforces for a symbol.
+// Zero values mean "no constraint" so partially configured instruments still work.
+type Instrument struct {
+	Symbol     string `json:"symbol"`
+	BaseAsset  string `json:"base_asset,omitempty"`
+	QuoteAsset string `json:"quote_asset,omitempty"`
+
+	TickSize    float64 `json:"tick_size"`    // Minimum price increment
+	StepSize    float64 `json:"step_size"`    // Minimum quantity increment (lot size)
+	MinQty      float64 `json:"min_qty"`      // Minimum order quantity
+	MaxQty      float64 `json:"max_qty"`      // Maximum order quantity (0 = unlimited)
+	MinPrice    float64 `json:"min_price"`    // Minimum limit price
+	MaxPrice    float64 `json:"max_price"`    // Maximum limit price (0 = unlimited)
+	MinNotional float64 `json:"min_notional"` // Minimum quantity * price
+
+	PricePrecision    int `json:"price_precision"`    // Decimal places for prices
+	QuantityPrecision int `json:"quantity_precision"` // Decimal places for quantities
+}
+
+// RoundPrice rounds a price to the nearest valid tick
+func (i *Instrument) RoundPrice(price float64) float64 {
+	if i.TickSize > 0 {
+		price = math.Round(price/i.TickSize) * i.TickSize
+	}
+	return roundToPrecision(price, i.PricePrecision)
+}
+
+// RoundQuantity rounds a quantity down to the lot step so an order never
+// exceeds the size that was requested
+func (i *Instrument) RoundQuantity(qty float64) float64 {
+	if i.StepSize > 0 {
+		qty = math.Floor(qty/i.StepSize+stepEpsilon) * i.StepSize
+	}
+	return roundToPrecision(qty, i.QuantityPrecision)
+}
+
+// FormatPrice renders a price with the instrument's price precision
+func (i *Instrument) FormatPrice(price float64) string {
+	return formatWithPrecision(price, i.PricePrecision)
+}
+
+// FormatQuantity renders a quantity with the instrument's quantity precision
+func (i *Instrument) FormatQuantity(qty float64) string {
+	return formatWithPrecision(qty, i.QuantityPrecision)
+}
+
+// Validate checks an already-rounded quantity and price against the instrument rules.
+// price is the limit price, or a reference market price for market orders (0 skips
+// the price and notional checks).
+func (i *Instrument) Validate(qty, price float64) error {
+	if qty <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if i.MinQty > 0 && qty < i.MinQty-stepEpsilon {
+		return fmt.Errorf("quantity %s is below minimum %s for %s",
+			i.FormatQuantity(qty), i.FormatQuantity(i.MinQty), i.Symbol)
+	}
+	if i.MaxQty > 0 && qty > i.MaxQty+stepEpsilon {
+		return fmt.Errorf("quantity %s exceeds maximum %s for %s",
+			i.FormatQuantity(qty), i.FormatQuantity(i.MaxQty), i.Symbol)
+	}
+	if !isMultipleOf(qty, i.StepSize) {
+		return fmt.Errorf("quantity %v is not a multiple of step size %v for %s", qty, i.StepSize, i.Symbol)
+	}
+
+	if price <= 0 {
+		return nil
+	}
+
+	if i.MinPrice > 0 && price < i.MinPrice-stepEpsilon {
+		return fmt.Errorf("price %s is below minimum %s for %s",
+			i.FormatPrice(price), i.FormatPrice(i.MinPrice), i.Symbol)
+	}
+	if i.MaxPrice > 0 && price > i.MaxPrice+stepEpsilon {
+		return fmt.Errorf("price %s exceeds maximum %s for %s",
+			i.FormatPrice(price), i.FormatPrice(i.MaxPrice), i.Symbol)
+	}
+	if !isMultipleOf(price, i.TickSize) {
+		return fmt.Errorf("price %v is not a multiple of tick size %v for %s", price, i.TickSize, i.Symbol)
+	}
+	if i.MinNotional > 0 && qty*price < i.MinNotional-stepEpsilon {
+		return fmt.Errorf("order notional %.2f is below minimum %.2f for %s", qty*price, i.MinNotional, i.Symbol)
+	}
+
+	return nil
+}
+
+// QuantityForNotional converts a quote-currency notional into the largest placeable
+// quantity at the given price. It returns 0 when the notional is too small to satisfy
+// the minimum quantity or minimum notional rules.
+func (i *Instrument) QuantityForNotional(notional, price float64) float64 {
+	if notional <= 0 || price <= 0 {
+		return 0
+	}
+
+	qty := i.RoundQuantity(notional / price)
+	if i.MaxQty > 0 && qty > i.MaxQty {
+		qty = i.RoundQuantity(i.MaxQty)
+	}
+	if err := i.Validate(qty, price); err != nil {
+		return 0
+	}
+	return qty
+}
+
+// InstrumentRegistry is a thread-safe collection of instruments keyed by symbol
+type InstrumentRegistry struct {
+	mu          sync.RWMutex
+	instruments map[string]Instrument
+}
+
+// NewInstrumentRegistry creates a registry seeded with the given instruments
+func NewInstrumentRegistry(instruments ...Instrument) *InstrumentRegistry {
+	r := &InstrumentRegistry{
+		instruments: make(map[string]Instrument, len(instruments)),
+	}
+	for _, inst := range instruments {
+		r.Set(inst)
+	}
+	return r
+}
+
+// NormalizeSymbol converts symbols such as "BTC/USDT" or "btc-usdt" to the
+// exchange form "BTCUSDT" used as the registry key
+func NormalizeSymbol(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	return strings.NewReplacer("/", "", "-", "", "_", "").Replace(symbol)
+}
+
+// Set adds or replaces an instrument
+func (r *InstrumentRegistry) Set(inst Instrument) {
+	inst.Symbol = NormalizeSymbol(inst.Symbol)
+	if inst.PricePrecision == 0 {
+		inst.PricePrecision = precisionFromStep(inst.TickSize)
+	}
+	if inst.QuantityPrecision == 0 {
+		inst.QuantityPrecision = precisionFromStep(inst.StepSize)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instruments[inst.Symbol] = inst
+}
+
+// Get returns the instrument for a symbol
+func (r *InstrumentRegistry) Get(symbol string) (*Instrument, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	inst, ok := r.instruments[NormalizeSymbol(symbol)]
+	if !ok {
+		return nil, false
+	}
+	return &inst, true
+}
+
+// List returns all instruments sorted by symbol
+func (r *InstrumentRegistry) List() []Instrument {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]Instrument, 0, len(r.instruments))
+	for _, inst := range r.instruments {
+		list = append(list, inst)
+	}
+	sort.Slice(list, func(a, b int) bool { return list[a].Symbol < list[b].Symbol })
+	return list
+}
+
+// Len returns the number of registered instruments
+func (r *InstrumentRegistry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.instruments)
+}
+
+// knownQuoteAssets are checked (longest first) when splitting symbols without metadata
+var knownQuoteAssets = []string{"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "EUR", "TRY", "BTC", "ETH", "BNB", "USD"}
+
+// Assets returns the base and quote asset of a symbol, using registered metadata
+// when available and falling back to splitting on "/" or a known quote suffix
+func (r *InstrumentRegistry) Assets(symbol string) (base, quote string) {
+	if inst, ok := r.Get(symbol); ok && inst.BaseAsset != "" && inst.QuoteAsset != "" {
+		return inst.BaseAsset, inst.QuoteAsset
+	}
+
+	if parts := strings.SplitN(strings.ToUpper(symbol), "/", 2); len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+
+	normalized := NormalizeSymbol(symbol)
+	for _, q := range knownQuoteAssets {
+		if strings.HasSuffix(normalized, q) && len(normalized) > len(q) {
+			return strings.TrimSuffix(normalized, q), q
+		}
+	}
+	return normalized, ""
+}
+
+// NormalizeOrder rounds the request quantity (down to the lot step) and limit price
+// (to the nearest tick) in place and validates the result. refPrice is used for the
+// notional check on market orders; pass 0 when no reference price is known.
+// Symbols without registered rules are left untouched.
+func (r *InstrumentRegistry) NormalizeOrder(req *PlaceOrderRequest, refPrice float64) error {
+	inst, ok := r.Get(req.Symbol)
+	if !ok {
+		return nil
+	}
+
+	req.Quantity = inst.RoundQuantity(req.Quantity)
+	if req.Quantity <= 0 {
+		return fmt.Errorf("quantity rounds to zero with step size %v for %s", inst.StepSize, inst.Symbol)
+	}
+
+	price := refPrice
+	if req.Type == OrderTypeLimit {
+		req.Price = inst.RoundPrice(req.Price)
+		price = req.Price
+	}
+
+	return inst.Validate(req.Quantity, price)
+}
+
+// DefaultInstruments returns Binance-like spot rules for commonly traded pairs.
+// They are used by the mock exchange and as a fallback when no exchange metadata
+// has been loaded.
+func DefaultInstruments() []Instrument {
+	return []Instrument{
+		{Symbol: "BTCUSDT", BaseAsset: "BTC", QuoteAsset: "USDT", TickSize: 0.01, StepSize: 0.00001, MinQty: 0.00001, MaxQty: 9000, MinNotional: 5},
+		{Symbol: "ETHUSDT", BaseAsset: "ETH", QuoteAsset: "USDT", TickSize: 0.01, StepSize: 0.0001, MinQty: 0.0001, MaxQty: 9000, MinNotional: 5},
+		{Symbol: "BNBUSDT", BaseAsset: "BNB", QuoteAsset: "USDT", TickSize: 0.01, StepSize: 0.001, MinQty: 0.001, MaxQty: 9000, MinNotional: 5},
+		{Symbol: "SOLUSDT", BaseAsset: "SOL", QuoteAsset: "USDT", TickSize: 0.01, StepSize: 0.001, MinQty: 0.001, MaxQty: 9000, MinNotional: 5},
+		{Symbol: "XRPUSDT", BaseAsset: "XRP", QuoteAsset: "USDT", TickSize: 0.0001, StepSize: 0.1, MinQty: 0.1, MaxQty: 9000000, MinNotional: 5},
+		{Symbol: "ADAUSDT", BaseAsset: "ADA", QuoteAsset: "USDT", TickSize: 0.0001, StepSize: 0.1, MinQty: 0.1, MaxQty: 9000000, MinNotional: 5},
+		{Symbol: "DOGEUSDT", BaseAsset: "DOGE", QuoteAsset: "USDT", TickSize: 0.00001, StepSize: 1, MinQty: 1, MaxQty: 9000000, MinNotional: 1},
+	}
+}
+
+// InstrumentsToDB converts instruments into database rows for the given exchange
+func InstrumentsToDB(exchangeName string, instruments []Instrument) []*db.Instrument {
+	rows := make([]*db.Instrument, 0, len(instruments))
+	for _, inst := range instruments {
+		rows = append(rows, &db.Instrument{
+			Exchange:          exchangeName,
+			Symbol:            inst.Symbol,
+			BaseAsset:         inst.BaseAsset,
+			QuoteAsset:        inst.QuoteAsset,
+			TickSize:          inst.TickSize,
+			StepSize:          inst.StepSize,
+			MinQty:            inst.MinQty,
+			MaxQty:            inst.MaxQty,
+			MinPrice:          inst.MinPrice,
+			MaxPrice:          inst.MaxPrice,
+			MinNotional:       inst.MinNotional,
+			PricePrecision:    inst.PricePrecision,
+			QuantityPrecision: inst.QuantityPrecision,
+		})
+	}
+	return rows
+}
+
+// InstrumentFromDB converts a persisted instrument row into an Instrument
+func InstrumentFromDB(row *db.Instrument) Instrument {
+	return Instrument{
+		Symbol:            row.Symbol,
+		BaseAsset:         row.BaseAsset,
+		QuoteAsset:        row.QuoteAsset,
+		TickSize:          row.TickSize,
+		StepSize:          row.StepSize,
+		MinQty:            row.MinQty,
+		MaxQty:            row.MaxQty,
+		MinPrice:          row.MinPrice,
+		MaxPrice:          row.MaxPrice,
+		MinNotional:       row.MinNotional,
+		PricePrecision:    row.PricePrecision,
+		QuantityPrecision: row.QuantityPrecision,
+	}
+}
+
+// precisionFromStep returns the number of decimal places implied by a step size
+// (e.g. 0.001 -> 3, 1 -> 0)
+func precisionFromStep(step float64) int {
+	if step <= 0 {
+		return 8
+	}
+	s := strconv.FormatFloat(step, 'f', -1, 64)
+	if idx := strings.IndexByte(s, '.'); idx >= 0 {
+		return len(s) - idx - 1
+	}
+	return 0
+}
+
+// precisionFromString returns the decimal places of a step string such as
+// "0.00100000" as reported by exchangeInfo
+func precisionFromString(step string) int {
+	v, err := strconv.ParseFloat(step, 64)
+	if err != nil {
+		return 8
+	}
+	return precisionFromStep(v)
+}
+
+func roundToPrecision(v float64, precision int) float64 {
+	if precision < 0 {
+		return v
+	}
+	pow := math.Pow(10, float64(precision))
+	return math.Round(v*pow) / pow
+}
+
+func formatWithPrecision(v float64, precision int) string {
+	if precision < 0 {
+		precision = 8
+	}
+	return strconv.FormatFloat(v, 'f', precision, 64)
+}
+
+func isMultipleOf(v, step float64) bool {
+	if step <= 0 {
+		return true
+	}
+	ratio := v / step
+	return math.Abs(ratio-math.Round(ratio)) < 1e-6
+}
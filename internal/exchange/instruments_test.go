@@ -0,0 +1,200 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+
+	binance "github.com/adshao/go-binance/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBTCInstrument() Instrument {
+	return Instrument{
+		Symbol:      "BTCUSDT",
+		TickSize:    0.01,
+		StepSize:    0.00001,
+		MinQty:      0.00001,
+		MaxQty:      9000,
+		MinNotional: 5,
+	}
+}
+
+func TestInstrument_RoundQuantityAndPrice(t *testing.T) {
+	registry := NewInstrumentRegistry(testBTCInstrument())
+	inst, ok := registry.Get("BTCUSDT")
+	require.True(t, ok)
+
+	tests := []struct {
+		name     string
+		qty      float64
+		expected float64
+	}{
+		{"exact step", 0.12345, 0.12345},
+		{"rounds down", 0.123459, 0.12345},
+		{"float noise", 0.1 + 0.2, 0.3},
+		{"below step", 0.000001, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, inst.RoundQuantity(tt.qty))
+		})
+	}
+
+	assert.Equal(t, 50000.13, inst.RoundPrice(50000.126))
+	assert.Equal(t, 50000.12, inst.RoundPrice(50000.124))
+	assert.Equal(t, "0.12345", inst.FormatQuantity(0.12345))
+	assert.Equal(t, "50000.10", inst.FormatPrice(50000.1))
+}
+
+func TestInstrument_Validate(t *testing.T) {
+	inst := testBTCInstrument()
+	inst.PricePrecision = 2
+	inst.QuantityPrecision = 5
+
+	tests := []struct {
+		name    string
+		qty     float64
+		price   float64
+		wantErr string
+	}{
+		{"valid limit", 0.001, 50000, ""},
+		{"valid market without reference price", 0.00001, 0, ""},
+		{"below min qty", 0.000001, 50000, "below minimum"},
+		{"above max qty", 10000, 50000, "exceeds maximum"},
+		{"off step", 0.000015, 50000, "step size"},
+		{"off tick", 0.001, 50000.005, "tick size"},
+		{"below min notional", 0.00005, 50000, "notional"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := inst.Validate(tt.qty, tt.price)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestInstrument_QuantityForNotional(t *testing.T) {
+	inst := testBTCInstrument()
+	inst.QuantityPrecision = 5
+
+	assert.Equal(t, 0.02, inst.QuantityForNotional(1000, 50000))
+	assert.Equal(t, 0.01234, inst.QuantityForNotional(617.4, 50000))
+	assert.Zero(t, inst.QuantityForNotional(4, 50000), "below min notional")
+	assert.Zero(t, inst.QuantityForNotional(1000, 0))
+}
+
+func TestInstrumentRegistry_NormalizeSymbolAndPrecision(t *testing.T) {
+	registry := NewInstrumentRegistry(Instrument{Symbol: "eth/usdt", TickSize: 0.01, StepSize: 0.0001})
+
+	inst, ok := registry.Get("ETH/USDT")
+	require.True(t, ok)
+	assert.Equal(t, "ETHUSDT", inst.Symbol)
+	assert.Equal(t, 2, inst.PricePrecision)
+	assert.Equal(t, 4, inst.QuantityPrecision)
+
+	_, ok = registry.Get("ETH-USDT")
+	assert.True(t, ok)
+	_, ok = registry.Get("SOLUSDT")
+	assert.False(t, ok)
+}
+
+func TestInstrumentRegistry_NormalizeOrder(t *testing.T) {
+	registry := NewInstrumentRegistry(testBTCInstrument())
+
+	req := PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.0012345, Price: 50000.129}
+	require.NoError(t, registry.NormalizeOrder(&req, 0))
+	assert.Equal(t, 0.00123, req.Quantity)
+	assert.Equal(t, 50000.13, req.Price)
+
+	// Market order notional is checked against the reference price
+	req = PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.00005}
+	err := registry.NormalizeOrder(&req, 50000)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "notional")
+
+	// Quantity smaller than a single step
+	req = PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 0.000001}
+	err = registry.NormalizeOrder(&req, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rounds to zero")
+
+	// Unknown symbols pass through untouched
+	req = PlaceOrderRequest{Symbol: "FOOBAR", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.123456789}
+	require.NoError(t, registry.NormalizeOrder(&req, 0))
+	assert.Equal(t, 0.123456789, req.Quantity)
+}
+
+func TestMockExchange_InstrumentRules(t *testing.T) {
+	mock := NewMockExchange(nil)
+	mock.SetMarketPrice("BTCUSDT", 50000)
+	ctx := context.Background()
+
+	// Quantity is rounded down to the lot step before the order is created
+	resp, err := mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.0012345})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusFilled, resp.Status)
+
+	order, err := mock.GetOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.00123, order.Quantity)
+
+	// Orders below min notional are rejected
+	resp, err = mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.00005})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusRejected, resp.Status)
+	assert.Contains(t, resp.Message, "notional")
+
+	// Configured rules override the defaults
+	mock.SetInstrument(Instrument{Symbol: "BTCUSDT", TickSize: 1, StepSize: 0.001, MinQty: 0.001, MinNotional: 10})
+	resp, err = mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.0019, Price: 49999.6})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusOpen, resp.Status)
+
+	order, err = mock.GetOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.001, order.Quantity)
+	assert.Equal(t, 50000.0, order.Price)
+
+	inst, ok := mock.GetInstrument("BTC/USDT")
+	require.True(t, ok)
+	assert.Equal(t, 10.0, inst.MinNotional)
+}
+
+func TestInstrumentFromBinanceSymbol(t *testing.T) {
+	symbol := &binance.Symbol{
+		Symbol:             "BTCUSDT",
+		Status:             "TRADING",
+		BaseAsset:          "BTC",
+		BaseAssetPrecision: 8,
+		QuoteAsset:         "USDT",
+		QuotePrecision:     8,
+		Filters: []map[string]interface{}{
+			{"filterType": "PRICE_FILTER", "minPrice": "0.01000000", "maxPrice": "1000000.00000000", "tickSize": "0.01000000"},
+			{"filterType": "LOT_SIZE", "minQty": "0.00001000", "maxQty": "9000.00000000", "stepSize": "0.00001000"},
+			{"filterType": "NOTIONAL", "minNotional": "5.00000000", "applyMinToMarket": true, "maxNotional": "9000000.00000000", "applyMaxToMarket": false, "avgPriceMins": float64(5)},
+		},
+	}
+
+	inst := instrumentFromBinanceSymbol(symbol)
+	assert.Equal(t, "BTCUSDT", inst.Symbol)
+	assert.Equal(t, "BTC", inst.BaseAsset)
+	assert.Equal(t, "USDT", inst.QuoteAsset)
+	assert.Equal(t, 0.01, inst.TickSize)
+	assert.Equal(t, 0.00001, inst.StepSize)
+	assert.Equal(t, 0.00001, inst.MinQty)
+	assert.Equal(t, 9000.0, inst.MaxQty)
+	assert.Equal(t, 5.0, inst.MinNotional)
+	assert.Equal(t, 2, inst.PricePrecision)
+	assert.Equal(t, 5, inst.QuantityPrecision)
+
+	// Legacy MIN_NOTIONAL filter
+	symbol.Filters[2] = map[string]interface{}{"filterType": "MIN_NOTIONAL", "minNotional": "10.00000000"}
+	inst = instrumentFromBinanceSymbol(symbol)
+	assert.Equal(t, 10.0, inst.MinNotional)
+}
@@ -15,6 +15,13 @@ type Exchange interface {
 	// CancelOrder cancels an existing order
 	CancelOrder(ctx context.Context, orderID string) (*Order, error)
 
+	// AmendOrder changes the price and/or quantity of a resting limit order.
+	// The order keeps its ID; venues without native amendment cancel and replace it.
+	AmendOrder(ctx context.Context, req AmendOrderRequest) (*Order, error)
+
+	// CancelAllOrders cancels every open order for a symbol, or across all symbols when symbol is empty
+	CancelAllOrders(ctx context.Context, symbol string) ([]*Order, error)
+
 	// GetOrder retrieves order details
 	GetOrder(ctx context.Context, orderID string) (*Order, error)
 
@@ -29,4 +36,13 @@ type Exchange interface {
 
 	// GetSession returns the current trading session ID
 	GetSession() *uuid.UUID
+
+	// GetBalances returns the account's asset balances
+	GetBalances(ctx context.Context) ([]Balance, error)
+
+	// GetAccount returns account details including balances
+	GetAccount(ctx context.Context) (*Account, error)
+
+	// GetInstrument returns the trading rules (tick size, lot size, min notional) for a symbol
+	GetInstrument(symbol string) (*Instrument, bool)
 }
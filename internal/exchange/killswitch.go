@@ -0,0 +1,743 @@
+package exchange
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// CloseMethod determines how the kill switch closes open positions
+type CloseMethod string
+
+const (
+	CloseMethodMarket     CloseMethod = "market"      // Close with a single market order
+	CloseMethodLimitChase CloseMethod = "limit_chase" // Re-priced limit orders, falling back to market
+)
+
+// Kill switch errors
+var (
+	ErrInvalidFlattenToken = errors.New("invalid or already used confirmation token")
+	ErrFlattenTokenExpired = errors.New("confirmation token expired")
+	ErrFlattenInProgress   = errors.New("flatten already in progress")
+)
+
+// auditSystemIP is recorded for kill switch steps that have no originating client
+const auditSystemIP = "127.0.0.1"
+
+// KillSwitchConfig controls how positions are flattened
+type KillSwitchConfig struct {
+	CloseMethod    CloseMethod
+	ChaseAttempts  int           // Limit re-prices before falling back to market
+	ChaseInterval  time.Duration // Wait for a limit order to fill before re-pricing
+	ChaseOffsetBps float64       // Limit price offset through the reference price
+	TokenTTL       time.Duration // Confirmation token lifetime
+	FeeRate        float64       // Fee rate used to estimate closing fees
+}
+
+// DefaultKillSwitchConfig returns market closes with a two minute confirmation window
+func DefaultKillSwitchConfig() KillSwitchConfig {
+	return KillSwitchConfig{
+		CloseMethod:    CloseMethodMarket,
+		ChaseAttempts:  3,
+		ChaseInterval:  2 * time.Second,
+		ChaseOffsetBps: 5,
+		TokenTTL:       2 * time.Minute,
+		FeeRate:        0.001,
+	}
+}
+
+// FlattenStore is the persistence the kill switch uses to find open orders and
+// positions across every session and to settle them. *db.DB implements it.
+type FlattenStore interface {
+	GetOrdersByStatus(ctx context.Context, status db.OrderStatus) ([]*db.Order, error)
+	UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status db.OrderStatus, executedQty, executedQuoteQty float64, filledAt, canceledAt *time.Time, errorMsg *string) error
+	GetAllOpenPositions(ctx context.Context) ([]*db.Position, error)
+	ClosePosition(ctx context.Context, id uuid.UUID, exitPrice float64, exitReason string, fees float64) error
+	PartialClosePosition(ctx context.Context, id uuid.UUID, closeQuantity, exitPrice float64, exitReason string, fees float64) (*db.Position, error)
+}
+
+// PriceSource returns a reference price for a symbol, used to price limit-chase
+// orders and to fill paper market orders at a realistic level
+type PriceSource func(ctx context.Context, symbol string) (float64, error)
+
+// FlattenTrigger identifies who or what requested a flatten
+type FlattenTrigger struct {
+	Source      string `json:"source"` // api, telegram, risk_agent
+	RequestedBy string `json:"requested_by,omitempty"`
+	IPAddress   string `json:"ip_address,omitempty"`
+	Reason      string `json:"reason"`
+	Rule        string `json:"rule,omitempty"` // De-risking rule behind a reduction; empty for a flatten
+}
+
+// label names the operation a trigger started in logs, audit events and exit reasons
+func (t FlattenTrigger) label() string {
+	if t.Rule != "" {
+		return "De-risking"
+	}
+	return "Kill switch"
+}
+
+// FlattenTicket is issued when the kill switch is armed. The token must be
+// presented to Execute before it expires.
+type FlattenTicket struct {
+	Token         string         `json:"token"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+	Trigger       FlattenTrigger `json:"trigger"`
+	OpenOrders    int            `json:"open_orders"`
+	OpenPositions int            `json:"open_positions"`
+	HaltOnly      bool           `json:"halt_only,omitempty"` // Confirming halts trading without flattening
+}
+
+// ClosedPosition describes how a single position was flattened
+type ClosedPosition struct {
+	PositionID string      `json:"position_id"`
+	Symbol     string      `json:"symbol"`
+	Side       OrderSide   `json:"side"` // Side of the closing order
+	Quantity   float64     `json:"quantity"`
+	FilledQty  float64     `json:"filled_qty"`
+	AvgPrice   float64     `json:"avg_price"`
+	Fees       float64     `json:"fees"`
+	Method     CloseMethod `json:"method"` // Method that completed the close
+	Closed     bool        `json:"closed"` // The whole position was settled
+	OrderIDs   []string    `json:"order_ids"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// FlattenReport summarizes a completed flatten run
+type FlattenReport struct {
+	Trigger         FlattenTrigger   `json:"trigger"`
+	StartedAt       time.Time        `json:"started_at"`
+	CompletedAt     time.Time        `json:"completed_at"`
+	CancelledOrders []string         `json:"cancelled_orders"`
+	ClosedPositions []ClosedPosition `json:"closed_positions"`
+	Errors          []string         `json:"errors,omitempty"`
+	Success         bool             `json:"success"`
+	HaltOnly        bool             `json:"halt_only,omitempty"` // Trading was halted, orders and positions were left in place
+}
+
+// KillSwitch cancels every open order and closes every open position.
+// Flattening is a two-step operation: Arm issues a short-lived confirmation
+// token and Execute performs the flatten when presented with it. Without an
+// exchange the kill switch only halts trading through its trigger hook.
+type KillSwitch struct {
+	exchange Exchange     // Optional: without it orders and positions are not flattened
+	store    FlattenStore // Optional: without it only exchange-side orders are cancelled
+	audit    *audit.Logger
+	prices   PriceSource
+	config   KillSwitchConfig
+
+	// onTrigger runs after confirmation and before anything is cancelled,
+	// e.g. to pause the orchestrator so no new orders are generated
+	onTrigger func(ctx context.Context, trigger FlattenTrigger) error
+
+	mu      sync.Mutex
+	tickets map[string]*FlattenTicket
+	running bool
+}
+
+// NewKillSwitch creates a kill switch for an exchange. A nil exchange creates a
+// halt-only kill switch, for paper trading where the orders and positions live
+// in another process.
+func NewKillSwitch(exchange Exchange, store FlattenStore, auditLogger *audit.Logger, config KillSwitchConfig) *KillSwitch {
+	defaults := DefaultKillSwitchConfig()
+	if config.CloseMethod == "" {
+		config.CloseMethod = defaults.CloseMethod
+	}
+	if config.ChaseInterval <= 0 {
+		config.ChaseInterval = defaults.ChaseInterval
+	}
+	if config.TokenTTL <= 0 {
+		config.TokenTTL = defaults.TokenTTL
+	}
+
+	return &KillSwitch{
+		exchange: exchange,
+		store:    store,
+		audit:    auditLogger,
+		config:   config,
+		tickets:  make(map[string]*FlattenTicket),
+	}
+}
+
+// SetPriceSource sets the reference price source for closing orders
+func (k *KillSwitch) SetPriceSource(prices PriceSource) {
+	k.prices = prices
+}
+
+// OnTrigger sets a hook that runs once a flatten is confirmed, before any
+// order is cancelled. Its error is reported but does not stop the flatten.
+func (k *KillSwitch) OnTrigger(fn func(ctx context.Context, trigger FlattenTrigger) error) {
+	k.onTrigger = fn
+}
+
+// HaltOnly reports whether the kill switch halts trading without flattening
+func (k *KillSwitch) HaltOnly() bool {
+	return k.exchange == nil
+}
+
+// Arm issues a confirmation token for a flatten request
+func (k *KillSwitch) Arm(ctx context.Context, trigger FlattenTrigger) (*FlattenTicket, error) {
+	token, err := newFlattenToken()
+	if err != nil {
+		return nil, err
+	}
+
+	openOrders, openPositions := k.exposure(ctx)
+	now := time.Now()
+	ticket := &FlattenTicket{
+		Token:         token,
+		ExpiresAt:     now.Add(k.config.TokenTTL),
+		Trigger:       trigger,
+		OpenOrders:    openOrders,
+		OpenPositions: openPositions,
+		HaltOnly:      k.HaltOnly(),
+	}
+
+	k.mu.Lock()
+	for t, pending := range k.tickets {
+		if now.After(pending.ExpiresAt) {
+			delete(k.tickets, t)
+		}
+	}
+	k.tickets[token] = ticket
+	k.mu.Unlock()
+
+	k.record(ctx, trigger, &audit.Event{
+		EventType: audit.EventTypeKillSwitchArmed,
+		Severity:  audit.SeverityWarning,
+		Action:    "Kill switch armed",
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"expires_at":     ticket.ExpiresAt,
+			"open_orders":    openOrders,
+			"open_positions": openPositions,
+		},
+	})
+
+	return ticket, nil
+}
+
+// Execute flattens everything if the token is valid. Tokens are single use.
+func (k *KillSwitch) Execute(ctx context.Context, token string) (*FlattenReport, error) {
+	k.mu.Lock()
+	ticket, exists := k.tickets[token]
+	if !exists {
+		k.mu.Unlock()
+		return nil, ErrInvalidFlattenToken
+	}
+	if k.running {
+		k.mu.Unlock()
+		return nil, ErrFlattenInProgress
+	}
+	delete(k.tickets, token)
+	if time.Now().After(ticket.ExpiresAt) {
+		k.mu.Unlock()
+		k.record(ctx, ticket.Trigger, &audit.Event{
+			EventType: audit.EventTypeKillSwitchTriggered,
+			Severity:  audit.SeverityWarning,
+			Action:    "Kill switch confirmation rejected",
+			Success:   false,
+			ErrorMsg:  ErrFlattenTokenExpired.Error(),
+		})
+		return nil, ErrFlattenTokenExpired
+	}
+	k.running = true
+	k.mu.Unlock()
+
+	defer func() {
+		k.mu.Lock()
+		k.running = false
+		k.mu.Unlock()
+	}()
+
+	return k.flatten(ctx, ticket.Trigger), nil
+}
+
+// Trip arms and immediately executes the kill switch. It is meant for automated
+// triggers such as risk circuit breakers, which confirm their own request.
+func (k *KillSwitch) Trip(ctx context.Context, trigger FlattenTrigger) (*FlattenReport, error) {
+	ticket, err := k.Arm(ctx, trigger)
+	if err != nil {
+		return nil, err
+	}
+	return k.Execute(ctx, ticket.Token)
+}
+
+// flatten cancels all orders, then closes all positions
+func (k *KillSwitch) flatten(ctx context.Context, trigger FlattenTrigger) *FlattenReport {
+	// Flattening must not be shed or delayed by the exchange rate budget
+	ctx = WithRequestPriority(ctx, PriorityCritical)
+
+	report := &FlattenReport{
+		Trigger:         trigger,
+		StartedAt:       time.Now(),
+		CancelledOrders: make([]string, 0),
+		ClosedPositions: make([]ClosedPosition, 0),
+	}
+
+	message := "KILL SWITCH TRIGGERED: cancelling all orders and flattening all positions"
+	if k.HaltOnly() {
+		message = "KILL SWITCH TRIGGERED: halting trading, orders and positions are not flattened"
+	}
+	log.Warn().
+		Str("source", trigger.Source).
+		Str("requested_by", trigger.RequestedBy).
+		Str("reason", trigger.Reason).
+		Msg(message)
+
+	k.record(ctx, trigger, &audit.Event{
+		EventType: audit.EventTypeKillSwitchTriggered,
+		Severity:  audit.SeverityCritical,
+		Action:    "Kill switch triggered",
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"close_method": string(k.config.CloseMethod),
+			"halt_only":    k.HaltOnly(),
+		},
+	})
+
+	if k.onTrigger != nil {
+		if err := k.onTrigger(ctx, trigger); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("halt trading: %v", err))
+		}
+	}
+
+	if k.HaltOnly() {
+		report.HaltOnly = true
+	} else {
+		k.cancelAllOrders(ctx, report)
+		k.closeAllPositions(ctx, report)
+	}
+
+	report.CompletedAt = time.Now()
+	report.Success = len(report.Errors) == 0
+
+	severity := audit.SeverityInfo
+	errMsg := ""
+	if !report.Success {
+		severity = audit.SeverityError
+		errMsg = fmt.Sprintf("%d step(s) failed", len(report.Errors))
+	}
+	k.record(ctx, trigger, &audit.Event{
+		EventType: audit.EventTypeKillSwitchCompleted,
+		Severity:  severity,
+		Action:    "Kill switch completed",
+		Success:   report.Success,
+		ErrorMsg:  errMsg,
+		Duration:  report.CompletedAt.Sub(report.StartedAt).Milliseconds(),
+		Metadata: map[string]interface{}{
+			"cancelled_orders": len(report.CancelledOrders),
+			"closed_positions": len(report.ClosedPositions),
+			"errors":           report.Errors,
+		},
+	})
+
+	log.Warn().
+		Int("cancelled_orders", len(report.CancelledOrders)).
+		Int("closed_positions", len(report.ClosedPositions)).
+		Int("errors", len(report.Errors)).
+		Msg("Kill switch completed")
+
+	return report
+}
+
+// cancelAllOrders cancels open orders on the exchange, then marks any open
+// orders left in the database (placed by other sessions or processes) as cancelled
+func (k *KillSwitch) cancelAllOrders(ctx context.Context, report *FlattenReport) {
+	cancelled := make(map[string]bool)
+
+	orders, err := k.exchange.CancelAllOrders(ctx, "")
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("cancel exchange orders: %v", err))
+	}
+	for _, order := range orders {
+		cancelled[order.ID] = true
+		report.CancelledOrders = append(report.CancelledOrders, order.ID)
+		k.recordOrder(ctx, report.Trigger, audit.EventTypeOrderCanceled, order.ID, map[string]interface{}{
+			"symbol": order.Symbol,
+			"side":   string(order.Side),
+			"scope":  "exchange",
+		}, nil)
+	}
+
+	if k.store == nil {
+		return
+	}
+
+	errorMsg := "Cancelled by kill switch"
+	for _, status := range []db.OrderStatus{db.OrderStatusNew, db.OrderStatusPartiallyFilled} {
+		dbOrders, err := k.store.GetOrdersByStatus(ctx, status)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("load %s orders: %v", status, err))
+			continue
+		}
+
+		for _, order := range dbOrders {
+			orderID := order.ID.String()
+			if cancelled[orderID] {
+				continue
+			}
+
+			canceledAt := time.Now()
+			err := k.store.UpdateOrderStatus(ctx, order.ID, db.OrderStatusCanceled,
+				order.ExecutedQuantity, order.ExecutedQuoteQuantity, order.FilledAt, &canceledAt, &errorMsg)
+			k.recordOrder(ctx, report.Trigger, audit.EventTypeOrderCanceled, orderID, map[string]interface{}{
+				"symbol": order.Symbol,
+				"side":   string(order.Side),
+				"scope":  "database",
+			}, err)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("cancel order %s: %v", orderID, err))
+				continue
+			}
+
+			cancelled[orderID] = true
+			report.CancelledOrders = append(report.CancelledOrders, orderID)
+		}
+	}
+}
+
+// closeAllPositions closes every open position across sessions
+func (k *KillSwitch) closeAllPositions(ctx context.Context, report *FlattenReport) {
+	if k.store == nil {
+		return
+	}
+
+	positions, err := k.store.GetAllOpenPositions(ctx)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("load open positions: %v", err))
+		return
+	}
+
+	for _, position := range positions {
+		result := k.closePosition(ctx, report.Trigger, position)
+		report.ClosedPositions = append(report.ClosedPositions, result)
+		if result.Error != "" {
+			report.Errors = append(report.Errors, fmt.Sprintf("close %s position %s: %s", position.Symbol, result.PositionID, result.Error))
+		}
+	}
+}
+
+// closePosition submits closing orders for a position and settles it in the store
+func (k *KillSwitch) closePosition(ctx context.Context, trigger FlattenTrigger, position *db.Position) ClosedPosition {
+	return k.reducePosition(ctx, trigger, position, position.Quantity)
+}
+
+// reducePosition submits closing orders for quantity of a position and settles
+// the filled part in the store. The position is closed once nothing tradable is left.
+func (k *KillSwitch) reducePosition(ctx context.Context, trigger FlattenTrigger, position *db.Position, quantity float64) ClosedPosition {
+	side := OrderSideSell
+	if position.Side == db.PositionSideShort {
+		side = OrderSideBuy
+	}
+	if quantity > position.Quantity {
+		quantity = position.Quantity
+	}
+
+	result := ClosedPosition{
+		PositionID: position.ID.String(),
+		Symbol:     position.Symbol,
+		Side:       side,
+		Quantity:   quantity,
+		Method:     CloseMethodMarket,
+		OrderIDs:   make([]string, 0),
+	}
+
+	remaining := quantity
+	var filledValue float64
+
+	fill := func(order *Order) {
+		if order.FilledQty <= 0 {
+			return
+		}
+		result.FilledQty += order.FilledQty
+		filledValue += order.FilledQty * order.AvgFillPrice
+		remaining -= order.FilledQty
+	}
+
+	refPrice := k.referencePrice(ctx, position)
+
+	if k.config.CloseMethod == CloseMethodLimitChase && refPrice > 0 {
+		result.Method = CloseMethodLimitChase
+		for attempt := 1; attempt <= k.config.ChaseAttempts && !k.isDust(position.Symbol, remaining); attempt++ {
+			order, err := k.chase(ctx, trigger, position, side, remaining, refPrice)
+			if err != nil {
+				log.Warn().Err(err).Str("symbol", position.Symbol).Int("attempt", attempt).Msg("Kill switch limit chase failed, falling back to market")
+				break
+			}
+			result.OrderIDs = append(result.OrderIDs, order.ID)
+			fill(order)
+			refPrice = k.referencePrice(ctx, position)
+		}
+	}
+
+	if !k.isDust(position.Symbol, remaining) {
+		result.Method = CloseMethodMarket
+		order, err := k.submit(ctx, trigger, position, PlaceOrderRequest{
+			Symbol:   position.Symbol,
+			Side:     side,
+			Type:     OrderTypeMarket,
+			Quantity: remaining,
+		}, refPrice)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.OrderIDs = append(result.OrderIDs, order.ID)
+			fill(order)
+			if order.Status != OrderStatusFilled {
+				result.Error = fmt.Sprintf("market order %s ended in status %s", order.ID, order.Status)
+			}
+		}
+	}
+
+	if result.FilledQty <= 0 {
+		if result.Error == "" {
+			result.Error = "no fills"
+		}
+		k.recordPosition(ctx, trigger, &result)
+		return result
+	}
+
+	result.AvgPrice = filledValue / result.FilledQty
+	result.Fees = filledValue * k.config.FeeRate
+	exitReason := trigger.label() + ": " + trigger.Reason
+
+	var err error
+	if k.isDust(position.Symbol, position.Quantity-result.FilledQty) {
+		err = k.store.ClosePosition(ctx, position.ID, result.AvgPrice, exitReason, result.Fees)
+		result.Closed = err == nil
+	} else {
+		_, err = k.store.PartialClosePosition(ctx, position.ID, result.FilledQty, result.AvgPrice, exitReason, result.Fees)
+		if err == nil && result.Error == "" && !k.isDust(position.Symbol, remaining) {
+			result.Error = fmt.Sprintf("%.8f left open", remaining)
+		}
+	}
+	if err != nil {
+		result.Error = fmt.Sprintf("settle position: %v", err)
+	}
+
+	k.recordPosition(ctx, trigger, &result)
+	return result
+}
+
+// chase places a limit order through the reference price, waits for it to fill
+// and cancels whatever is left
+func (k *KillSwitch) chase(ctx context.Context, trigger FlattenTrigger, position *db.Position, side OrderSide, quantity, refPrice float64) (*Order, error) {
+	offset := k.config.ChaseOffsetBps / 10000
+	price := refPrice * (1 - offset)
+	if side == OrderSideBuy {
+		price = refPrice * (1 + offset)
+	}
+
+	order, err := k.submit(ctx, trigger, position, PlaceOrderRequest{
+		Symbol:   position.Symbol,
+		Side:     side,
+		Type:     OrderTypeLimit,
+		Quantity: quantity,
+		Price:    price,
+	}, refPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status == OrderStatusOpen || order.Status == OrderStatusPending {
+		select {
+		case <-ctx.Done():
+		case <-time.After(k.config.ChaseInterval):
+		}
+
+		if _, err := k.exchange.CancelOrder(ctx, order.ID); err != nil {
+			log.Debug().Err(err).Str("order_id", order.ID).Msg("Kill switch chase order not cancelled")
+		}
+		if refreshed, err := k.exchange.GetOrder(ctx, order.ID); err == nil {
+			order = refreshed
+		}
+	}
+
+	return order, nil
+}
+
+// submit places a closing order and returns its latest state
+func (k *KillSwitch) submit(ctx context.Context, trigger FlattenTrigger, position *db.Position, req PlaceOrderRequest, refPrice float64) (*Order, error) {
+	if refPrice > 0 {
+		k.exchange.SetMarketPrice(req.Symbol, refPrice)
+	}
+
+	metadata := map[string]interface{}{
+		"symbol":      req.Symbol,
+		"side":        string(req.Side),
+		"type":        string(req.Type),
+		"quantity":    req.Quantity,
+		"position_id": position.ID.String(),
+	}
+	if req.Type == OrderTypeLimit {
+		metadata["price"] = req.Price
+	}
+
+	resp, err := k.exchange.PlaceOrder(ctx, req)
+	if err == nil && resp.Status == OrderStatusRejected {
+		err = fmt.Errorf("order rejected: %s", resp.Message)
+	}
+	if err != nil {
+		k.recordOrder(ctx, trigger, audit.EventTypeOrderPlaced, position.ID.String(), metadata, err)
+		return nil, err
+	}
+
+	k.recordOrder(ctx, trigger, audit.EventTypeOrderPlaced, resp.OrderID, metadata, nil)
+
+	order, err := k.exchange.GetOrder(ctx, resp.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order %s: %w", resp.OrderID, err)
+	}
+	return order, nil
+}
+
+// referencePrice returns the price source's price for a position's symbol,
+// falling back to the entry price when no source is configured or it fails
+func (k *KillSwitch) referencePrice(ctx context.Context, position *db.Position) float64 {
+	if k.prices != nil {
+		price, err := k.prices(ctx, position.Symbol)
+		if err == nil && price > 0 {
+			return price
+		}
+		log.Warn().Err(err).Str("symbol", position.Symbol).Msg("No reference price for kill switch close, using entry price")
+	}
+	return position.EntryPrice
+}
+
+// isDust reports whether a remaining quantity is too small to trade
+func (k *KillSwitch) isDust(symbol string, quantity float64) bool {
+	if quantity <= stepEpsilon {
+		return true
+	}
+	if inst, ok := k.exchange.GetInstrument(symbol); ok && inst.StepSize > 0 {
+		return inst.RoundQuantity(quantity) <= 0
+	}
+	return false
+}
+
+// exposure counts open orders and positions for the confirmation ticket
+func (k *KillSwitch) exposure(ctx context.Context) (int, int) {
+	if k.store == nil {
+		return 0, 0
+	}
+
+	openOrders := 0
+	for _, status := range []db.OrderStatus{db.OrderStatusNew, db.OrderStatusPartiallyFilled} {
+		orders, err := k.store.GetOrdersByStatus(ctx, status)
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to count open orders for kill switch")
+			continue
+		}
+		openOrders += len(orders)
+	}
+
+	positions, err := k.store.GetAllOpenPositions(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to count open positions for kill switch")
+	}
+
+	return openOrders, len(positions)
+}
+
+// recordOrder audits a single order placed or cancelled by the kill switch
+func (k *KillSwitch) recordOrder(ctx context.Context, trigger FlattenTrigger, eventType audit.EventType, resource string, metadata map[string]interface{}, err error) {
+	event := &audit.Event{
+		EventType: eventType,
+		Severity:  audit.SeverityWarning,
+		Resource:  resource,
+		Action:    fmt.Sprintf("%s %s", trigger.label(), eventType),
+		Success:   err == nil,
+		Metadata:  metadata,
+	}
+	if err != nil {
+		event.Severity = audit.SeverityError
+		event.ErrorMsg = err.Error()
+	}
+	k.record(ctx, trigger, event)
+}
+
+// recordPosition audits the outcome of closing or reducing a position
+func (k *KillSwitch) recordPosition(ctx context.Context, trigger FlattenTrigger, result *ClosedPosition) {
+	eventType := audit.EventTypePositionClosed
+	action := trigger.label() + " closed position"
+	if trigger.Rule != "" && !result.Closed {
+		eventType = audit.EventTypePositionReduced
+		action = trigger.label() + " reduced position"
+	}
+	event := &audit.Event{
+		EventType: eventType,
+		Severity:  audit.SeverityWarning,
+		Resource:  result.PositionID,
+		Action:    action,
+		Success:   result.Error == "",
+		ErrorMsg:  result.Error,
+		Metadata: map[string]interface{}{
+			"symbol":     result.Symbol,
+			"side":       string(result.Side),
+			"quantity":   result.Quantity,
+			"filled_qty": result.FilledQty,
+			"avg_price":  result.AvgPrice,
+			"method":     string(result.Method),
+			"order_ids":  result.OrderIDs,
+		},
+	}
+	if result.Error != "" {
+		event.Severity = audit.SeverityError
+	}
+	k.record(ctx, trigger, event)
+}
+
+// record stamps an audit event with the trigger and writes it
+func (k *KillSwitch) record(ctx context.Context, trigger FlattenTrigger, event *audit.Event) {
+	if k.audit == nil {
+		return
+	}
+
+	event.UserID = trigger.RequestedBy
+	event.IPAddress = trigger.IPAddress
+	if event.IPAddress == "" {
+		event.IPAddress = auditSystemIP
+	}
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]interface{})
+	}
+	if trigger.Rule != "" {
+		event.Metadata["derisk_rule"] = trigger.Rule
+	} else {
+		event.Metadata["kill_switch"] = true
+	}
+	event.Metadata["source"] = trigger.Source
+	event.Metadata["reason"] = trigger.Reason
+
+	if err := k.audit.Log(ctx, event); err != nil {
+		log.Error().Err(err).Str("event_type", string(event.EventType)).Msg("Failed to audit kill switch step")
+	}
+}
+
+// newFlattenToken generates a random confirmation token
+func newFlattenToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// ParseCloseMethod converts a configured close method, defaulting to market
+func ParseCloseMethod(method string) CloseMethod {
+	if CloseMethod(method) == CloseMethodLimitChase {
+		return CloseMethodLimitChase
+	}
+	return CloseMethodMarket
+}
@@ -0,0 +1,306 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// fakeFlattenStore is an in-memory FlattenStore
+type fakeFlattenStore struct {
+	mu        sync.Mutex
+	orders    map[uuid.UUID]*db.Order
+	positions map[uuid.UUID]*db.Position
+}
+
+func newFakeFlattenStore() *fakeFlattenStore {
+	return &fakeFlattenStore{
+		orders:    make(map[uuid.UUID]*db.Order),
+		positions: make(map[uuid.UUID]*db.Position),
+	}
+}
+
+func (f *fakeFlattenStore) addOrder(symbol string, status db.OrderStatus) uuid.UUID {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := uuid.New()
+	f.orders[id] = &db.Order{ID: id, Symbol: symbol, Side: db.OrderSideBuy, Status: status}
+	return id
+}
+
+func (f *fakeFlattenStore) addPosition(symbol string, side db.PositionSide, entry, qty float64) uuid.UUID {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := uuid.New()
+	session := uuid.New()
+	f.positions[id] = &db.Position{ID: id, SessionID: &session, Symbol: symbol, Side: side, EntryPrice: entry, Quantity: qty}
+	return id
+}
+
+func (f *fakeFlattenStore) GetOrdersByStatus(ctx context.Context, status db.OrderStatus) ([]*db.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	orders := make([]*db.Order, 0)
+	for _, o := range f.orders {
+		if o.Status == status {
+			orders = append(orders, o)
+		}
+	}
+	return orders, nil
+}
+
+func (f *fakeFlattenStore) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, status db.OrderStatus, executedQty, executedQuoteQty float64, filledAt, canceledAt *time.Time, errorMsg *string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	o, ok := f.orders[orderID]
+	if !ok {
+		return fmt.Errorf("order not found: %s", orderID)
+	}
+	o.Status = status
+	o.CanceledAt = canceledAt
+	return nil
+}
+
+func (f *fakeFlattenStore) GetAllOpenPositions(ctx context.Context) ([]*db.Position, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	positions := make([]*db.Position, 0)
+	for _, p := range f.positions {
+		if p.ExitTime == nil {
+			positions = append(positions, p)
+		}
+	}
+	return positions, nil
+}
+
+func (f *fakeFlattenStore) ClosePosition(ctx context.Context, id uuid.UUID, exitPrice float64, exitReason string, fees float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.positions[id]
+	if !ok {
+		return fmt.Errorf("position not found: %s", id)
+	}
+	now := time.Now()
+	p.ExitTime = &now
+	p.ExitPrice = &exitPrice
+	p.ExitReason = &exitReason
+	return nil
+}
+
+func (f *fakeFlattenStore) PartialClosePosition(ctx context.Context, id uuid.UUID, closeQuantity, exitPrice float64, exitReason string, fees float64) (*db.Position, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.positions[id]
+	if !ok {
+		return nil, fmt.Errorf("position not found: %s", id)
+	}
+	p.Quantity -= closeQuantity
+	return p, nil
+}
+
+func newTestKillSwitch(t *testing.T, config KillSwitchConfig) (*KillSwitch, *MockExchange, *fakeFlattenStore) {
+	t.Helper()
+	mock := NewMockExchange(nil)
+	store := newFakeFlattenStore()
+	ks := NewKillSwitch(mock, store, audit.NewLogger(nil, true), config)
+	ks.SetPriceSource(func(ctx context.Context, symbol string) (float64, error) {
+		return map[string]float64{"BTCUSDT": 50000, "ETHUSDT": 3000}[symbol], nil
+	})
+	return ks, mock, store
+}
+
+func TestKillSwitch_FlattenCancelsOrdersAndClosesPositions(t *testing.T) {
+	ks, mock, store := newTestKillSwitch(t, DefaultKillSwitchConfig())
+	ctx := context.Background()
+
+	mock.SetMarketPrice("BTCUSDT", 50000)
+	resp, err := mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.01, Price: 45000})
+	require.NoError(t, err)
+	require.Equal(t, OrderStatusOpen, resp.Status)
+
+	staleOrder := store.addOrder("ETHUSDT", db.OrderStatusNew)
+	filledOrder := store.addOrder("ETHUSDT", db.OrderStatusFilled)
+	longID := store.addPosition("BTCUSDT", db.PositionSideLong, 48000, 0.05)
+	shortID := store.addPosition("ETHUSDT", db.PositionSideShort, 3100, 1)
+
+	ticket, err := ks.Arm(ctx, FlattenTrigger{Source: "api", RequestedBy: "tester", Reason: "test"})
+	require.NoError(t, err)
+	assert.Len(t, ticket.Token, 32)
+	assert.Equal(t, 1, ticket.OpenOrders)
+	assert.Equal(t, 2, ticket.OpenPositions)
+
+	report, err := ks.Execute(ctx, ticket.Token)
+	require.NoError(t, err)
+	assert.True(t, report.Success, "errors: %v", report.Errors)
+	assert.ElementsMatch(t, []string{resp.OrderID, staleOrder.String()}, report.CancelledOrders)
+
+	order, err := mock.GetOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusCancelled, order.Status)
+	assert.Equal(t, db.OrderStatusCanceled, store.orders[staleOrder].Status)
+	assert.Equal(t, db.OrderStatusFilled, store.orders[filledOrder].Status)
+
+	require.Len(t, report.ClosedPositions, 2)
+	for _, closed := range report.ClosedPositions {
+		assert.Empty(t, closed.Error)
+		assert.Equal(t, CloseMethodMarket, closed.Method)
+		assert.InDelta(t, closed.Quantity, closed.FilledQty, 1e-9)
+		switch closed.Symbol {
+		case "BTCUSDT":
+			assert.Equal(t, OrderSideSell, closed.Side)
+			assert.InDelta(t, 50000, closed.AvgPrice, 50000*0.01)
+		case "ETHUSDT":
+			assert.Equal(t, OrderSideBuy, closed.Side)
+			assert.InDelta(t, 3000, closed.AvgPrice, 3000*0.01)
+		}
+	}
+	assert.NotNil(t, store.positions[longID].ExitTime)
+	assert.NotNil(t, store.positions[shortID].ExitTime)
+	assert.Contains(t, *store.positions[longID].ExitReason, "Kill switch")
+}
+
+func TestKillSwitch_TokenIsRequiredAndSingleUse(t *testing.T) {
+	ks, _, _ := newTestKillSwitch(t, DefaultKillSwitchConfig())
+	ctx := context.Background()
+
+	var triggered []FlattenTrigger
+	ks.OnTrigger(func(ctx context.Context, trigger FlattenTrigger) error {
+		triggered = append(triggered, trigger)
+		return nil
+	})
+
+	_, err := ks.Execute(ctx, "not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidFlattenToken)
+	assert.Empty(t, triggered)
+
+	ticket, err := ks.Arm(ctx, FlattenTrigger{Source: "telegram", Reason: "test"})
+	require.NoError(t, err)
+
+	_, err = ks.Execute(ctx, ticket.Token)
+	require.NoError(t, err)
+	require.Len(t, triggered, 1)
+	assert.Equal(t, "telegram", triggered[0].Source)
+
+	_, err = ks.Execute(ctx, ticket.Token)
+	assert.ErrorIs(t, err, ErrInvalidFlattenToken)
+	assert.Len(t, triggered, 1)
+}
+
+func TestKillSwitch_HaltOnly(t *testing.T) {
+	store := newFakeFlattenStore()
+	orderID := store.addOrder("BTCUSDT", db.OrderStatusNew)
+	positionID := store.addPosition("BTCUSDT", db.PositionSideLong, 50000, 0.1)
+	ks := NewKillSwitch(nil, store, audit.NewLogger(nil, true), DefaultKillSwitchConfig())
+	require.True(t, ks.HaltOnly())
+	ctx := context.Background()
+
+	halted := 0
+	ks.OnTrigger(func(ctx context.Context, trigger FlattenTrigger) error {
+		halted++
+		return fmt.Errorf("orchestrator unreachable")
+	})
+
+	ticket, err := ks.Arm(ctx, FlattenTrigger{Source: "risk_agent", Reason: "drawdown"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, ticket.OpenOrders)
+	assert.Equal(t, 1, ticket.OpenPositions)
+
+	report, err := ks.Execute(ctx, ticket.Token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, halted)
+	assert.True(t, report.HaltOnly)
+	assert.Empty(t, report.CancelledOrders)
+	assert.Empty(t, report.ClosedPositions)
+	assert.False(t, report.Success, "a failed halt must not be reported as success")
+	require.Len(t, report.Errors, 1)
+	assert.Contains(t, report.Errors[0], "orchestrator unreachable")
+
+	// Orders and positions are left to the process that holds them
+	assert.Equal(t, db.OrderStatusNew, store.orders[orderID].Status)
+	assert.Nil(t, store.positions[positionID].ExitTime)
+
+	_, err = ks.Reduce(ctx, ReduceRequest{
+		Trigger:    FlattenTrigger{Source: "risk_agent", Reason: "exposure"},
+		Reductions: []PositionReduction{{PositionID: positionID.String(), Quantity: 0.05}},
+	})
+	assert.Error(t, err)
+}
+
+func TestKillSwitch_ExpiredToken(t *testing.T) {
+	config := DefaultKillSwitchConfig()
+	config.TokenTTL = time.Millisecond
+	ks, _, store := newTestKillSwitch(t, config)
+	ctx := context.Background()
+	positionID := store.addPosition("BTCUSDT", db.PositionSideLong, 48000, 0.05)
+
+	ticket, err := ks.Arm(ctx, FlattenTrigger{Source: "api", Reason: "test"})
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = ks.Execute(ctx, ticket.Token)
+	assert.ErrorIs(t, err, ErrFlattenTokenExpired)
+	assert.Nil(t, store.positions[positionID].ExitTime, "position must stay open")
+}
+
+func TestKillSwitch_LimitChaseFallsBackToMarket(t *testing.T) {
+	config := DefaultKillSwitchConfig()
+	config.CloseMethod = CloseMethodLimitChase
+	config.ChaseAttempts = 2
+	config.ChaseInterval = time.Millisecond
+	ks, mock, store := newTestKillSwitch(t, config)
+	ctx := context.Background()
+	store.addPosition("BTCUSDT", db.PositionSideLong, 48000, 0.05)
+
+	report, err := ks.Trip(ctx, FlattenTrigger{Source: "risk_agent", Reason: "drawdown"})
+	require.NoError(t, err)
+	require.True(t, report.Success, "errors: %v", report.Errors)
+	require.Len(t, report.ClosedPositions, 1)
+
+	closed := report.ClosedPositions[0]
+	// Paper limit orders rest without filling, so both chases are cancelled before the market close
+	require.Len(t, closed.OrderIDs, 3)
+	assert.Equal(t, CloseMethodMarket, closed.Method)
+	for _, id := range closed.OrderIDs[:2] {
+		order, err := mock.GetOrder(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, OrderTypeLimit, order.Type)
+		assert.Equal(t, OrderStatusCancelled, order.Status)
+		assert.Equal(t, 49975.0, order.Price, "sell limit priced 5 bps through the reference")
+	}
+	assert.InDelta(t, 0.05, closed.FilledQty, 1e-9)
+}
+
+func TestMockExchange_CancelAllOrders(t *testing.T) {
+	mock := NewMockExchange(nil)
+	ctx := context.Background()
+	mock.SetMarketPrice("BTCUSDT", 50000)
+	mock.SetMarketPrice("ETHUSDT", 3000)
+
+	btc, err := mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.01, Price: 45000})
+	require.NoError(t, err)
+	eth, err := mock.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "ETHUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.1, Price: 2500})
+	require.NoError(t, err)
+
+	cancelled, err := mock.CancelAllOrders(ctx, "BTC/USDT")
+	require.NoError(t, err)
+	require.Len(t, cancelled, 1)
+	assert.Equal(t, btc.OrderID, cancelled[0].ID)
+
+	cancelled, err = mock.CancelAllOrders(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, cancelled, 1)
+	assert.Equal(t, eth.OrderID, cancelled[0].ID)
+
+	// Funds reserved by the resting orders are released
+	usdt := mock.balance("USDT")
+	assert.Zero(t, usdt.Locked)
+}
@@ -0,0 +1,470 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/alerts"
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// DefaultMarginCheckInterval is how often the margin guard checks positions
+const DefaultMarginCheckInterval = 30 * time.Second
+
+// marginQuoteAsset is the margin asset of USD-M futures
+const marginQuoteAsset = "USDT"
+
+// AddPositionMargin moves amount of the margin asset from the cross wallet to
+// an isolated position
+func (f *BinanceFuturesExchange) AddPositionMargin(ctx context.Context, symbol string, side PositionSide, amount float64) error {
+	symbol = NormalizeSymbol(symbol)
+	if amount <= 0 {
+		return fmt.Errorf("margin amount must be positive, got %g", amount)
+	}
+	if side == "" {
+		side = PositionSideBoth
+	}
+
+	err := retryWithBackoff(func() error {
+		return f.client.NewUpdatePositionMarginService().
+			Symbol(symbol).
+			PositionSide(futures.PositionSideType(side)).
+			Amount(strconv.FormatFloat(amount, 'f', 2, 64)).
+			Type(1). // 1 adds margin, 2 removes it
+			Do(ctx)
+	}, fmt.Sprintf("add_position_margin_%s", symbol))
+	if err != nil {
+		return fmt.Errorf("failed to add margin to %s position: %w", symbol, err)
+	}
+
+	log.Info().
+		Str("symbol", symbol).
+		Str("position_side", string(side)).
+		Float64("amount", amount).
+		Msg("Futures position margin added")
+	return nil
+}
+
+// marginVenue is a futures venue the margin guard watches and acts on.
+// *BinanceFuturesExchange implements it.
+type marginVenue interface {
+	GetPositions(ctx context.Context, symbol string) ([]FuturesPosition, error)
+	GetAccount(ctx context.Context) (*Account, error)
+	AddPositionMargin(ctx context.Context, symbol string, side PositionSide, amount float64) error
+	PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*PlaceOrderResponse, error)
+}
+
+// MarginConfig controls the margin guard
+type MarginConfig struct {
+	Policy   risk.MarginPolicy
+	Interval time.Duration // DefaultMarginCheckInterval when zero
+	Tiers    []risk.MarginTier
+}
+
+// MarginOrderPlacer places a margin guard order, with the reason it was taken
+type MarginOrderPlacer func(ctx context.Context, req PlaceOrderRequest, reason string) (*PlaceOrderResponse, error)
+
+// MarginGuard watches the liquidation distance and margin ratio of futures
+// positions, alerts as they approach liquidation and, when the policy allows,
+// tops up isolated positions and reduces cross positions
+type MarginGuard struct {
+	venue    marginVenue
+	place    MarginOrderPlacer
+	model    risk.MarginModel
+	monitor  *risk.MarginMonitor
+	interval time.Duration
+
+	mu      sync.RWMutex
+	last    *risk.MarginCheck
+	alerted map[string]risk.MarginAlertLevel // Position key -> level last alerted
+}
+
+// NewMarginGuard creates a margin guard for a futures venue
+func NewMarginGuard(venue marginVenue, config MarginConfig) *MarginGuard {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultMarginCheckInterval
+	}
+	model := risk.MarginModel{Tiers: config.Tiers}
+	return &MarginGuard{
+		venue: venue,
+		place: func(ctx context.Context, req PlaceOrderRequest, _ string) (*PlaceOrderResponse, error) {
+			return venue.PlaceOrder(ctx, req)
+		},
+		model:    model,
+		monitor:  risk.NewMarginMonitor(model, config.Policy),
+		interval: interval,
+		alerted:  make(map[string]risk.MarginAlertLevel),
+	}
+}
+
+// SetOrderPlacer routes reduce orders through place instead of straight to
+// the venue
+func (g *MarginGuard) SetOrderPlacer(place MarginOrderPlacer) {
+	g.place = place
+}
+
+// Run checks positions every interval until ctx is cancelled
+func (g *MarginGuard) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := g.Check(ctx); err != nil {
+			log.Warn().Err(err).Msg("Margin check failed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Last returns the latest margin check, or nil before the first
+func (g *MarginGuard) Last() *risk.MarginCheck {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.last
+}
+
+// Status computes the current margin state without alerting or acting
+func (g *MarginGuard) Status(ctx context.Context) (*risk.AccountMargin, error) {
+	wallet, positions, _, err := g.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return g.model.Account(wallet, positions)
+}
+
+// Check loads the venue's positions, raises alerts for those near
+// liquidation and executes the monitor's actions
+func (g *MarginGuard) Check(ctx context.Context) (*risk.MarginCheck, error) {
+	wallet, positions, sides, err := g.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	check, err := g.monitor.Check(wallet, positions, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// Alert when a position crosses into a worse level, not on every check
+	var raised []risk.MarginAlert
+	g.mu.Lock()
+	g.last = check
+	current := make(map[string]risk.MarginAlertLevel, len(check.Alerts))
+	for _, alert := range check.Alerts {
+		key := alert.Symbol + "/" + alert.Side
+		current[key] = alert.Level
+		if previous, ok := g.alerted[key]; !ok || (previous == risk.MarginAlertWarning && alert.Level == risk.MarginAlertCritical) {
+			raised = append(raised, alert)
+		}
+	}
+	g.alerted = current
+	g.mu.Unlock()
+
+	for _, alert := range raised {
+		g.alert(ctx, alert)
+	}
+	for _, action := range check.Actions {
+		if err := g.execute(ctx, action, sides[action.Symbol+"/"+action.Side]); err != nil {
+			log.Error().
+				Err(err).
+				Str("symbol", action.Symbol).
+				Str("side", action.Side).
+				Str("action", string(action.Type)).
+				Msg("Margin action failed")
+			alerts.AlertSystemError(ctx, "margin_guard", err)
+		}
+	}
+	return check, nil
+}
+
+// load returns the wallet balance and open positions of the venue, with the
+// exchange position side of each position
+func (g *MarginGuard) load(ctx context.Context) (float64, []risk.MarginPosition, map[string]PositionSide, error) {
+	account, err := g.venue.GetAccount(ctx)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	futuresPositions, err := g.venue.GetPositions(ctx, "")
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	wallet := account.Balance(marginQuoteAsset).Total()
+
+	positions := make([]risk.MarginPosition, 0, len(futuresPositions))
+	sides := make(map[string]PositionSide, len(futuresPositions))
+	for _, p := range futuresPositions {
+		position := marginPosition(p)
+		if position.Quantity == 0 || position.MarkPrice <= 0 {
+			continue
+		}
+		positions = append(positions, position)
+		sides[position.Symbol+"/"+position.Side] = p.PositionSide
+	}
+	return wallet, positions, sides, nil
+}
+
+// marginPosition converts a futures position for the margin model. One-way
+// positions take their side from the sign of the quantity.
+func marginPosition(p FuturesPosition) risk.MarginPosition {
+	side := "LONG"
+	if p.PositionSide == PositionSideShort || (p.PositionSide != PositionSideLong && p.Quantity < 0) {
+		side = "SHORT"
+	}
+	quantity := p.Quantity
+	if quantity < 0 {
+		quantity = -quantity
+	}
+	leverage := float64(p.Leverage)
+	if leverage <= 0 {
+		leverage = 1
+	}
+	return risk.MarginPosition{
+		Symbol:         p.Symbol,
+		Side:           side,
+		Quantity:       quantity,
+		EntryPrice:     p.EntryPrice,
+		MarkPrice:      p.MarkPrice,
+		Leverage:       leverage,
+		Isolated:       p.MarginType == MarginTypeIsolated,
+		IsolatedWallet: p.IsolatedWallet,
+	}
+}
+
+// alert reports a position near liquidation
+func (g *MarginGuard) alert(ctx context.Context, alert risk.MarginAlert) {
+	event := log.Warn()
+	if alert.Level == risk.MarginAlertCritical {
+		event = log.Error()
+	}
+	event.
+		Str("symbol", alert.Symbol).
+		Str("side", alert.Side).
+		Float64("margin_ratio", alert.MarginRatio).
+		Float64("liquidation_price", alert.LiquidationPrice).
+		Float64("liquidation_distance", alert.LiquidationDistance).
+		Msg("Position approaching liquidation")
+
+	message := fmt.Sprintf("%s %s position: %s (liquidation price %.4f)", alert.Symbol, alert.Side, alert.Reason, alert.LiquidationPrice)
+	if alert.Level == risk.MarginAlertCritical {
+		alerts.AlertPositionRisk(ctx, alert.Symbol, alert.MarginRatio, message)
+		return
+	}
+	if err := alerts.GetDefaultManager().SendWarning(ctx, "Margin Warning", message, map[string]interface{}{
+		"symbol":               alert.Symbol,
+		"side":                 alert.Side,
+		"margin_ratio":         alert.MarginRatio,
+		"liquidation_distance": alert.LiquidationDistance,
+	}); err != nil {
+		log.Debug().Err(err).Msg("Failed to send margin warning")
+	}
+}
+
+// execute tops up or reduces a position. Reductions are reduce-only market
+// orders so they can never flip the position.
+func (g *MarginGuard) execute(ctx context.Context, action risk.MarginAction, positionSide PositionSide) error {
+	ctx = WithRequestPriority(ctx, PriorityCritical)
+
+	log.Warn().
+		Str("symbol", action.Symbol).
+		Str("side", action.Side).
+		Str("action", string(action.Type)).
+		Float64("amount", action.Amount).
+		Float64("quantity", action.Quantity).
+		Str("reason", action.Reason).
+		Msg("Margin guard acting on position")
+
+	switch action.Type {
+	case risk.MarginActionTopUp:
+		return g.venue.AddPositionMargin(ctx, action.Symbol, positionSide, action.Amount)
+	case risk.MarginActionReduce:
+		side := OrderSideSell
+		if action.Side == "SHORT" {
+			side = OrderSideBuy
+		}
+		resp, err := g.place(ctx, PlaceOrderRequest{
+			Symbol:       action.Symbol,
+			Side:         side,
+			Type:         OrderTypeMarket,
+			Quantity:     action.Quantity,
+			ReduceOnly:   true,
+			PositionSide: positionSide,
+		}, action.Reason)
+		if err != nil {
+			return err
+		}
+		if resp.Status == OrderStatusRejected {
+			return fmt.Errorf("reduce order rejected: %s", resp.Message)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown margin action %q", action.Type)
+	}
+}
+
+// StartMarginGuard starts checking futures positions for approaching
+// liquidation. It does nothing for spot and paper exchanges.
+func (s *Service) StartMarginGuard(ctx context.Context) {
+	if s.marginGuard == nil {
+		log.Debug().Msg("Margin guard only runs for futures exchanges")
+		return
+	}
+
+	policy := s.marginGuard.monitor.Policy()
+	log.Info().
+		Bool("actions", policy.Enabled).
+		Bool("top_up", policy.TopUp).
+		Bool("reduce", policy.Reduce).
+		Dur("interval", s.marginGuard.interval).
+		Msg("Margin guard started")
+	go s.marginGuard.Run(ctx)
+}
+
+// placeMarginOrder places a margin guard reduce order like any other order:
+// through the live session trading the symbol, whose positions its fills
+// update, and recorded in the audit log
+func (s *Service) placeMarginOrder(ctx context.Context, req PlaceOrderRequest, reason string) (*PlaceOrderResponse, error) {
+	session := s.marginSession(req.Symbol)
+
+	resp, err := session.exchange.PlaceOrder(ctx, req)
+	if err == nil && resp.Status == OrderStatusRejected {
+		err = fmt.Errorf("reduce order rejected: %s", resp.Message)
+	}
+	s.recordMarginOrder(ctx, session, req, resp, reason, err)
+	if err != nil {
+		return resp, err
+	}
+
+	order, err := session.exchange.GetOrder(ctx, resp.OrderID)
+	if err != nil {
+		log.Error().Err(err).Str("order_id", resp.OrderID).Msg("Failed to retrieve margin guard order after placement")
+		return resp, nil
+	}
+	if order.FilledQty <= 0 {
+		return resp, nil
+	}
+	fills, err := session.exchange.GetOrderFills(ctx, order.ID)
+	if err != nil {
+		log.Error().Err(err).Str("order_id", order.ID).Msg("Failed to get margin guard order fills for position update")
+		return resp, nil
+	}
+	if len(fills) > 0 {
+		if err := session.positionManager.OnOrderFilled(ctx, order, fills); err != nil {
+			log.Error().Err(err).Str("order_id", order.ID).Msg("Failed to update positions after margin guard order")
+		}
+	}
+	return resp, nil
+}
+
+// marginSession returns the live session trading symbol on the guarded
+// venue, otherwise the sessionless context
+func (s *Service) marginSession(symbol string) *tradingSession {
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+
+	for _, session := range s.sessions {
+		if s.sharesMarginAccount(session) && NormalizeSymbol(session.symbol) == NormalizeSymbol(symbol) {
+			return session
+		}
+	}
+	return s.sessionless()
+}
+
+// sharesMarginAccount reports whether a session trades on the venue the
+// margin guard watches. Live sessions share one venue connection, which is
+// the service's own exchange when the service itself trades live.
+func (s *Service) sharesMarginAccount(session *tradingSession) bool {
+	if session.id == nil {
+		return true
+	}
+	if session.mode != TradingModeLive {
+		return false
+	}
+	s.liveVenueMu.Lock()
+	defer s.liveVenueMu.Unlock()
+	return s.liveVenue == s.exchange
+}
+
+// recordMarginOrder audits an order placed by the margin guard
+func (s *Service) recordMarginOrder(ctx context.Context, session *tradingSession, req PlaceOrderRequest, resp *PlaceOrderResponse, reason string, err error) {
+	if s.audit == nil {
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"symbol":        req.Symbol,
+		"side":          string(req.Side),
+		"type":          string(req.Type),
+		"quantity":      req.Quantity,
+		"reduce_only":   req.ReduceOnly,
+		"position_side": string(req.PositionSide),
+		"reason":        reason,
+		"source":        "margin_guard",
+	}
+	if session.id != nil {
+		metadata["session_id"] = session.id.String()
+	}
+
+	event := &audit.Event{
+		EventType: audit.EventTypeOrderPlaced,
+		Severity:  audit.SeverityWarning,
+		IPAddress: auditSystemIP,
+		Resource:  req.Symbol,
+		Action:    "Margin guard reduced position",
+		Success:   err == nil,
+		Metadata:  metadata,
+	}
+	if resp != nil && resp.OrderID != "" {
+		event.Resource = resp.OrderID
+	}
+	if err != nil {
+		event.Severity = audit.SeverityError
+		event.ErrorMsg = err.Error()
+	}
+	if auditErr := s.audit.Log(ctx, event); auditErr != nil {
+		log.Error().Err(auditErr).Str("symbol", req.Symbol).Msg("Failed to audit margin guard order")
+	}
+}
+
+// GetMarginStatus returns the margin ratio, liquidation price and liquidation
+// distance of every futures position, with the alerts and actions of the
+// guard's latest check. Margin is account-wide: live sessions share one
+// futures account, so a session's status covers every position on it.
+func (s *Service) GetMarginStatus(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+	if s.marginGuard == nil || !s.sharesMarginAccount(session) {
+		return nil, fmt.Errorf("margin status is only available for futures exchanges")
+	}
+
+	statusCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	account, err := s.marginGuard.Status(statusCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get margin status: %w", err)
+	}
+
+	result := map[string]interface{}{"account": account}
+	if session.id != nil {
+		result["session_id"] = session.id.String()
+	}
+	if last := s.marginGuard.Last(); last != nil {
+		result["alerts"] = last.Alerts
+		result["actions"] = last.Actions
+	}
+	return result, nil
+}
@@ -0,0 +1,184 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// fakeMarginVenue serves fixed positions and records margin actions
+type fakeMarginVenue struct {
+	wallet    float64
+	positions []FuturesPosition
+	topUps    []float64
+	orders    []PlaceOrderRequest
+}
+
+func (f *fakeMarginVenue) GetPositions(_ context.Context, _ string) ([]FuturesPosition, error) {
+	return f.positions, nil
+}
+
+func (f *fakeMarginVenue) GetAccount(_ context.Context) (*Account, error) {
+	return &Account{Balances: []Balance{{Asset: marginQuoteAsset, Free: f.wallet}}}, nil
+}
+
+func (f *fakeMarginVenue) AddPositionMargin(_ context.Context, _ string, _ PositionSide, amount float64) error {
+	f.topUps = append(f.topUps, amount)
+	return nil
+}
+
+func (f *fakeMarginVenue) PlaceOrder(_ context.Context, req PlaceOrderRequest) (*PlaceOrderResponse, error) {
+	f.orders = append(f.orders, req)
+	return &PlaceOrderResponse{Status: OrderStatusFilled}, nil
+}
+
+func TestMarginPosition(t *testing.T) {
+	short := marginPosition(FuturesPosition{Symbol: "BTCUSDT", PositionSide: PositionSideBoth, Quantity: -0.5, MarkPrice: 100, Leverage: 10})
+	assert.Equal(t, "SHORT", short.Side)
+	assert.Equal(t, 0.5, short.Quantity)
+	assert.False(t, short.Isolated)
+
+	long := marginPosition(FuturesPosition{Symbol: "BTCUSDT", PositionSide: PositionSideLong, Quantity: 1, MarginType: MarginTypeIsolated, IsolatedWallet: 50})
+	assert.Equal(t, "LONG", long.Side)
+	assert.True(t, long.Isolated)
+	assert.Equal(t, 50.0, long.IsolatedWallet)
+	assert.Equal(t, 1.0, long.Leverage, "unknown leverage counts as unleveraged")
+}
+
+func TestMarginGuard_TopsUpIsolatedPositions(t *testing.T) {
+	venue := &fakeMarginVenue{
+		wallet: 5000,
+		positions: []FuturesPosition{
+			// Isolated long about 4.7% from liquidation
+			{Symbol: "BTCUSDT", PositionSide: PositionSideBoth, Quantity: 1, EntryPrice: 10_000, MarkPrice: 9900, Leverage: 20, MarginType: MarginTypeIsolated, IsolatedWallet: 600},
+		},
+	}
+	guard := NewMarginGuard(venue, MarginConfig{Policy: risk.MarginPolicy{Enabled: true, TopUp: true, Reduce: true}})
+
+	check, err := guard.Check(context.Background())
+	require.NoError(t, err)
+	require.Len(t, check.Alerts, 1)
+	require.Len(t, check.Actions, 1)
+	assert.Same(t, check, guard.Last())
+
+	// Margin comes out of the cross wallet
+	require.Len(t, venue.topUps, 1)
+	assert.Equal(t, check.Actions[0].Amount, venue.topUps[0])
+	assert.Empty(t, venue.orders)
+
+	// Status reports without acting
+	account, err := guard.Status(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, account.Positions, 1)
+	assert.Len(t, venue.topUps, 1)
+}
+
+func TestMarginGuard_ReducesCrossPositions(t *testing.T) {
+	venue := &fakeMarginVenue{
+		wallet: 1900,
+		positions: []FuturesPosition{
+			// Cross short with the cross wallet nearly exhausted
+			{Symbol: "ETHUSDT", PositionSide: PositionSideBoth, Quantity: -10, EntryPrice: 2000, MarkPrice: 2180, Leverage: 20, MarginType: MarginTypeCrossed},
+		},
+	}
+	guard := NewMarginGuard(venue, MarginConfig{Policy: risk.MarginPolicy{Enabled: true, TopUp: true, Reduce: true}})
+
+	check, err := guard.Check(context.Background())
+	require.NoError(t, err)
+	require.Len(t, check.Actions, 1)
+
+	// The short is cut with a reduce-only buy
+	require.Len(t, venue.orders, 1)
+	order := venue.orders[0]
+	assert.Equal(t, "ETHUSDT", order.Symbol)
+	assert.Equal(t, OrderSideBuy, order.Side)
+	assert.Equal(t, OrderTypeMarket, order.Type)
+	assert.True(t, order.ReduceOnly)
+	assert.Equal(t, PositionSideBoth, order.PositionSide)
+	assert.Equal(t, check.Actions[0].Quantity, order.Quantity)
+}
+
+func TestMarginGuard_RoutesReducesThroughOrderPlacer(t *testing.T) {
+	venue := &fakeMarginVenue{
+		wallet: 1900,
+		positions: []FuturesPosition{
+			{Symbol: "ETHUSDT", PositionSide: PositionSideBoth, Quantity: -10, EntryPrice: 2000, MarkPrice: 2180, Leverage: 20, MarginType: MarginTypeCrossed},
+		},
+	}
+	guard := NewMarginGuard(venue, MarginConfig{Policy: risk.MarginPolicy{Enabled: true, TopUp: true, Reduce: true}})
+
+	var reasons []string
+	guard.SetOrderPlacer(func(_ context.Context, req PlaceOrderRequest, reason string) (*PlaceOrderResponse, error) {
+		reasons = append(reasons, reason)
+		return &PlaceOrderResponse{Status: OrderStatusFilled}, nil
+	})
+
+	check, err := guard.Check(context.Background())
+	require.NoError(t, err)
+	require.Len(t, check.Actions, 1)
+	assert.Equal(t, []string{check.Actions[0].Reason}, reasons)
+	assert.Empty(t, venue.orders, "reduce orders do not bypass the order path")
+}
+
+func TestService_PlaceMarginOrder_SettlesSessionPositions(t *testing.T) {
+	service := NewServicePaper(nil)
+	ctx := context.Background()
+
+	// A live session on the guarded venue holds the position
+	session := openTestSession(t, service, "BTCUSDT")
+	session.mode = TradingModeLive
+	service.liveVenue = service.exchange
+
+	_, err := service.PlaceMarketOrder(ctx, map[string]interface{}{
+		"session_id": session.id.String(),
+		"symbol":     "BTCUSDT",
+		"side":       "buy",
+		"quantity":   0.1,
+	})
+	require.NoError(t, err)
+
+	resp, err := service.placeMarginOrder(ctx, PlaceOrderRequest{
+		Symbol:     "BTCUSDT",
+		Side:       OrderSideSell,
+		Type:       OrderTypeMarket,
+		Quantity:   0.04,
+		ReduceOnly: true,
+	}, "margin ratio above target")
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusFilled, resp.Status)
+
+	positions := session.positionManager.GetOpenPositions()
+	require.Len(t, positions, 1)
+	assert.InDelta(t, 0.06, positions[0].Quantity, 1e-9)
+}
+
+func TestService_GetMarginStatus_RequiresFutures(t *testing.T) {
+	service := &Service{}
+	_, err := service.GetMarginStatus(context.Background(), nil)
+	assert.Error(t, err)
+}
+
+func TestService_GetMarginStatus_ResolvesSession(t *testing.T) {
+	service := NewServicePaper(nil)
+	service.marginGuard = NewMarginGuard(&fakeMarginVenue{wallet: 1000}, MarginConfig{})
+	session := openTestSession(t, service, "BTCUSDT")
+
+	_, err := service.GetMarginStatus(context.Background(), map[string]interface{}{"session_id": uuid.New().String()})
+	assert.ErrorContains(t, err, "trading session not found")
+
+	// Paper sessions have their own simulated account
+	_, err = service.GetMarginStatus(context.Background(), map[string]interface{}{"session_id": session.id.String()})
+	assert.ErrorContains(t, err, "only available for futures exchanges")
+
+	// Live sessions share the guarded account
+	session.mode = TradingModeLive
+	service.liveVenue = service.exchange
+	result, err := service.GetMarginStatus(context.Background(), map[string]interface{}{"session_id": session.id.String()})
+	require.NoError(t, err)
+	assert.Equal(t, session.id.String(), result.(map[string]interface{})["session_id"])
+}
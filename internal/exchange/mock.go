@@ -3,6 +3,8 @@ package exchange
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +15,15 @@ import (
 	"github.com/ajitpratap0/cryptofunk/internal/db"
 )
 
+const (
+	// mockExchangeName identifies the paper trading exchange in persisted metadata
+	mockExchangeName = "mock"
+
+	// Paper accounts start with this quote balance unless configured otherwise
+	defaultPaperQuoteAsset = "USDT"
+	defaultPaperBalance    = 10000.0
+)
+
 // MockExchange simulates a trading exchange for paper trading
 type MockExchange struct {
 	orders map[string]*Order
@@ -22,6 +33,14 @@ type MockExchange struct {
 	// Mock market data for order fills
 	marketPrices map[string]float64
 
+	// Instrument rules (tick size, lot size, min notional) enforced on placement
+	instruments *InstrumentRegistry
+
+	// Simulated account inventory, debited and credited on fills and fees.
+	// Insufficient funds are not rejected; negative balances represent borrowed inventory.
+	balances   map[string]*Balance
+	orderLocks map[string]Balance // Order ID -> funds reserved by the open order
+
 	// Market simulation parameters
 	baseSlippage float64 // Base slippage percentage
 	marketImpact float64 // Market impact per unit of quantity
@@ -61,6 +80,11 @@ func NewMockExchangeWithFees(database *db.DB, fees config.FeeConfig) *MockExchan
 		orders:       make(map[string]*Order),
 		fills:        make(map[string][]Fill),
 		marketPrices: make(map[string]float64),
+		instruments:  NewInstrumentRegistry(DefaultInstruments()...),
+		balances: map[string]*Balance{
+			defaultPaperQuoteAsset: {Asset: defaultPaperQuoteAsset, Free: defaultPaperBalance},
+		},
+		orderLocks: make(map[string]Balance),
 
 		// Configurable market simulation parameters
 		baseSlippage: fees.BaseSlippage,
@@ -92,19 +116,35 @@ func (m *MockExchange) PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*
 		}, nil
 	}
 
+	// Round quantity/price to the instrument rules and enforce minimums
+	if err := m.instruments.NormalizeOrder(&req, m.marketPrices[req.Symbol]); err != nil {
+		log.Warn().
+			Err(err).
+			Str("symbol", req.Symbol).
+			Float64("quantity", req.Quantity).
+			Msg("Order rejected by instrument rules")
+
+		return &PlaceOrderResponse{
+			Status:  OrderStatusRejected,
+			Message: err.Error(),
+		}, nil
+	}
+
 	// Create order
 	now := time.Now()
 	order := &Order{
-		ID:        uuid.New().String(),
-		Symbol:    req.Symbol,
-		Side:      req.Side,
-		Type:      req.Type,
-		Quantity:  req.Quantity,
-		Price:     req.Price,
-		FilledQty: 0,
-		Status:    OrderStatusPending,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:            uuid.New().String(),
+		Symbol:        req.Symbol,
+		Side:          req.Side,
+		Type:          req.Type,
+		Quantity:      req.Quantity,
+		Price:         req.Price,
+		FilledQty:     0,
+		Status:        OrderStatusPending,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		ParentOrderID: req.ParentOrderID,
+		TaxLotIDs:     req.TaxLotIDs,
 	}
 
 	// Store order
@@ -137,6 +177,9 @@ func (m *MockExchange) PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*
 		order.Status = OrderStatusOpen
 		order.UpdatedAt = time.Now()
 
+		// Reserve funds for the resting order
+		m.lockOrderFunds(ctx, order)
+
 		// Update status in database
 		if m.db != nil {
 			m.updateOrderStatusInDB(ctx, order)
@@ -164,10 +207,74 @@ func (m *MockExchange) CancelOrder(ctx context.Context, orderID string) (*Order,
 		return nil, fmt.Errorf("cannot cancel order in status: %s", order.Status)
 	}
 
+	m.cancelOrderLocked(ctx, order)
+
+	return order, nil
+}
+
+// AmendOrder changes a resting limit order's price and/or quantity in place,
+// re-reserving its funds at the new values
+func (m *MockExchange) AmendOrder(ctx context.Context, req AmendOrderRequest) (*Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, exists := m.orders[req.OrderID]
+	if !exists {
+		return nil, fmt.Errorf("order not found: %s", req.OrderID)
+	}
+
+	price, quantity, err := resolveAmendment(order, req, m.instruments)
+	if err != nil {
+		return nil, err
+	}
+
+	m.releaseOrderFunds(ctx, order.ID)
+	amendment := applyAmendment(order, price, quantity, order.ExchangeOrderID)
+	m.lockOrderFunds(ctx, order)
+
+	persistAmendment(ctx, m.db, order.ID, amendment)
+
+	log.Info().
+		Str("order_id", order.ID).
+		Float64("price", price).
+		Float64("quantity", quantity).
+		Msg("Order amended")
+
+	return order, nil
+}
+
+// CancelAllOrders cancels every open order for a symbol, or across all symbols when symbol is empty
+func (m *MockExchange) CancelAllOrders(ctx context.Context, symbol string) ([]*Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	symbol = NormalizeSymbol(symbol)
+	cancelled := make([]*Order, 0)
+	for _, order := range m.orders {
+		if order.Status != OrderStatusOpen && order.Status != OrderStatusPending {
+			continue
+		}
+		if symbol != "" && NormalizeSymbol(order.Symbol) != symbol {
+			continue
+		}
+		m.cancelOrderLocked(ctx, order)
+		cancelled = append(cancelled, order)
+	}
+
+	return cancelled, nil
+}
+
+// cancelOrderLocked marks an open order as cancelled, releases its reserved funds
+// and persists the new status. Caller must hold m.mu.
+func (m *MockExchange) cancelOrderLocked(ctx context.Context, order *Order) {
+	orderID := order.ID
 	order.Status = OrderStatusCancelled
 	cancelledAt := time.Now()
 	order.UpdatedAt = cancelledAt
 
+	// Return reserved funds
+	m.releaseOrderFunds(ctx, orderID)
+
 	// Update in database
 	if m.db != nil {
 		orderUUID, _ := uuid.Parse(orderID)
@@ -193,8 +300,6 @@ func (m *MockExchange) CancelOrder(ctx context.Context, orderID string) (*Order,
 	log.Info().
 		Str("order_id", orderID).
 		Msg("Order cancelled")
-
-	return order, nil
 }
 
 // GetOrder retrieves order details
@@ -210,6 +315,13 @@ func (m *MockExchange) GetOrder(ctx context.Context, orderID string) (*Order, er
 	return order, nil
 }
 
+// OpenOrderCount returns the number of resting orders
+func (m *MockExchange) OpenOrderCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return countOpenOrders(m.orders)
+}
+
 // GetOrderFills retrieves all fills for an order
 func (m *MockExchange) GetOrderFills(ctx context.Context, orderID string) ([]Fill, error) {
 	m.mu.RLock()
@@ -231,6 +343,166 @@ func (m *MockExchange) SetMarketPrice(symbol string, price float64) {
 	m.marketPrices[symbol] = price
 }
 
+// GetQuote returns the simulated top of book: bid and ask at the last set market
+// price with unknown depth
+func (m *MockExchange) GetQuote(ctx context.Context, symbol string) (*Quote, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	price, ok := m.marketPrices[symbol]
+	if !ok || price <= 0 {
+		return nil, fmt.Errorf("no market price for symbol %s", symbol)
+	}
+	return &Quote{Symbol: symbol, Bid: price, Ask: price, Time: time.Now()}, nil
+}
+
+// SetInstrument configures the trading rules for a symbol, replacing any defaults
+func (m *MockExchange) SetInstrument(inst Instrument) {
+	m.instruments.Set(inst)
+}
+
+// GetInstrument returns the trading rules for a symbol
+func (m *MockExchange) GetInstrument(symbol string) (*Instrument, bool) {
+	return m.instruments.Get(symbol)
+}
+
+// SetBalance sets the free balance of an asset in the simulated account
+func (m *MockExchange) SetBalance(asset string, free float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	asset = strings.ToUpper(asset)
+	if b, ok := m.balances[asset]; ok {
+		b.Free = free
+		return
+	}
+	m.balances[asset] = &Balance{Asset: asset, Free: free}
+}
+
+// GetBalances returns the simulated account balances sorted by asset
+func (m *MockExchange) GetBalances(ctx context.Context) ([]Balance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.balanceSnapshot(), nil
+}
+
+// GetAccount returns the simulated account with its balances
+func (m *MockExchange) GetAccount(ctx context.Context) (*Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return &Account{
+		Exchange:    mockExchangeName,
+		AccountType: "SPOT",
+		CanTrade:    true,
+		Balances:    m.balanceSnapshot(),
+		UpdatedAt:   time.Now(),
+	}, nil
+}
+
+// balanceSnapshot copies the current balances (caller must hold lock)
+func (m *MockExchange) balanceSnapshot() []Balance {
+	balances := make([]Balance, 0, len(m.balances))
+	for _, b := range m.balances {
+		balances = append(balances, *b)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Asset < balances[j].Asset })
+	return balances
+}
+
+// balance returns the mutable balance for an asset, creating it if needed (caller must hold lock)
+func (m *MockExchange) balance(asset string) *Balance {
+	b, ok := m.balances[asset]
+	if !ok {
+		b = &Balance{Asset: asset}
+		m.balances[asset] = b
+	}
+	return b
+}
+
+// applyFillsToBalances moves base and quote inventory for executed fills (caller must hold lock)
+func (m *MockExchange) applyFillsToBalances(ctx context.Context, order *Order, fills []Fill, feeRate float64) {
+	baseAsset, quoteAsset := m.instruments.Assets(order.Symbol)
+	if quoteAsset == "" {
+		return
+	}
+
+	base := m.balance(baseAsset)
+	quote := m.balance(quoteAsset)
+
+	for _, fill := range fills {
+		notional := fill.Price * fill.Quantity
+		fee := notional * feeRate
+
+		if order.Side == OrderSideBuy {
+			base.Free += fill.Quantity
+			quote.Free -= notional + fee
+		} else {
+			base.Free -= fill.Quantity
+			quote.Free += notional - fee
+		}
+	}
+
+	m.persistBalancesInDB(ctx, base, quote)
+}
+
+// lockOrderFunds reserves the funds a resting limit order could consume (caller must hold lock)
+func (m *MockExchange) lockOrderFunds(ctx context.Context, order *Order) {
+	baseAsset, quoteAsset := m.instruments.Assets(order.Symbol)
+	if quoteAsset == "" {
+		return
+	}
+
+	lock := Balance{Asset: baseAsset, Locked: order.Quantity}
+	if order.Side == OrderSideBuy {
+		lock = Balance{Asset: quoteAsset, Locked: order.Quantity * order.Price}
+	}
+
+	b := m.balance(lock.Asset)
+	b.Free -= lock.Locked
+	b.Locked += lock.Locked
+	m.orderLocks[order.ID] = lock
+
+	m.persistBalancesInDB(ctx, b)
+}
+
+// releaseOrderFunds returns funds reserved by an order to the free balance (caller must hold lock)
+func (m *MockExchange) releaseOrderFunds(ctx context.Context, orderID string) {
+	lock, ok := m.orderLocks[orderID]
+	if !ok {
+		return
+	}
+	delete(m.orderLocks, orderID)
+
+	b := m.balance(lock.Asset)
+	b.Locked -= lock.Locked
+	b.Free += lock.Locked
+
+	m.persistBalancesInDB(ctx, b)
+}
+
+// persistBalancesInDB publishes changed balances so other services can read them
+func (m *MockExchange) persistBalancesInDB(ctx context.Context, balances ...*Balance) {
+	if m.db == nil {
+		return
+	}
+
+	rows := make([]*db.AccountBalance, 0, len(balances))
+	for _, b := range balances {
+		rows = append(rows, &db.AccountBalance{
+			Exchange: mockExchangeName,
+			Asset:    b.Asset,
+			Free:     b.Free,
+			Locked:   b.Locked,
+		})
+	}
+
+	if err := m.db.UpsertAccountBalances(ctx, rows); err != nil {
+		log.Error().Err(err).Msg("Failed to persist account balances to database")
+	}
+}
+
 // validateOrder validates order parameters
 func (m *MockExchange) validateOrder(req PlaceOrderRequest) error {
 	if req.Symbol == "" {
@@ -302,6 +574,9 @@ func (m *MockExchange) simulateMarketFill(ctx context.Context, order *Order) {
 	// Store fills
 	m.fills[order.ID] = fills
 
+	// Debit/credit the simulated account (taker fee charged in quote asset)
+	m.applyFillsToBalances(ctx, order, fills, m.takerFee)
+
 	// Persist fills to database
 	if m.db != nil {
 		for _, fill := range fills {
@@ -407,6 +682,13 @@ func (m *MockExchange) convertToDBOrder(order *Order) *db.Order {
 		price = &order.Price
 	}
 
+	var parentOrderID *uuid.UUID
+	if order.ParentOrderID != "" {
+		if parsed, err := uuid.Parse(order.ParentOrderID); err == nil {
+			parentOrderID = &parsed
+		}
+	}
+
 	var exchangeOrderID *string
 	if order.ID != "" {
 		exchangeOrderID = &order.ID
@@ -433,6 +715,7 @@ func (m *MockExchange) convertToDBOrder(order *Order) *db.Order {
 		CanceledAt:            nil,
 		ErrorMessage:          nil,
 		Metadata:              nil,
+		ParentOrderID:         parentOrderID,
 		CreatedAt:             order.CreatedAt,
 		UpdatedAt:             order.UpdatedAt,
 	}
@@ -0,0 +1,212 @@
+package exchange
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// LotMethod selects which open lots a reducing fill consumes
+type LotMethod string
+
+const (
+	// LotMethodAverageCost keeps a single lot at the volume-weighted entry price
+	LotMethodAverageCost LotMethod = "average_cost"
+	// LotMethodFIFO closes the oldest lots first
+	LotMethodFIFO LotMethod = "fifo"
+)
+
+// ParseLotMethod converts a configuration string to a LotMethod.
+// An empty string selects average cost.
+func ParseLotMethod(s string) (LotMethod, error) {
+	switch LotMethod(strings.ToLower(strings.TrimSpace(s))) {
+	case "", LotMethodAverageCost:
+		return LotMethodAverageCost, nil
+	case LotMethodFIFO:
+		return LotMethodFIFO, nil
+	default:
+		return "", fmt.Errorf("invalid lot method %q (must be average_cost or fifo)", s)
+	}
+}
+
+// Lot is an open quantity acquired at a single price
+type Lot struct {
+	Quantity float64   `json:"quantity"` // Always positive; direction comes from the position sign
+	Price    float64   `json:"price"`
+	OpenedAt time.Time `json:"opened_at"`
+}
+
+// NetPosition is the signed position in a single symbol.
+// Quantity > 0 is long, Quantity < 0 is short.
+type NetPosition struct {
+	Symbol        string    `json:"symbol"`
+	Method        LotMethod `json:"lot_method"`
+	Quantity      float64   `json:"quantity"`
+	AvgEntryPrice float64   `json:"avg_entry_price"`
+	Lots          []Lot     `json:"lots"`
+	RealizedPnL   float64   `json:"realized_pnl"` // Net of the fees charged on closing fills
+	Fees          float64   `json:"fees"`
+}
+
+// FillResult describes how a fill changed a NetPosition
+type FillResult struct {
+	ClosedQty        float64 // Quantity that reduced the existing position
+	ClosedEntryPrice float64 // Average entry price of the closed lots
+	OpenedQty        float64 // Quantity that opened or added to a position
+	RealizedPnL      float64 // P&L of the closed quantity net of CloseFees
+	CloseFees        float64 // Share of the fill's fees allocated to the closed quantity
+	OpenFees         float64 // Share of the fill's fees allocated to the opened quantity
+	Flipped          bool    // Position crossed zero (long to short or short to long)
+}
+
+// NewNetPosition creates a flat position
+func NewNetPosition(symbol string, method LotMethod) *NetPosition {
+	if method == "" {
+		method = LotMethodAverageCost
+	}
+	return &NetPosition{Symbol: symbol, Method: method}
+}
+
+// netPositionFromDB seeds a NetPosition from an open database position.
+// Individual lots are not persisted, so the position starts as a single lot.
+func netPositionFromDB(position *db.Position, method LotMethod) *NetPosition {
+	net := NewNetPosition(position.Symbol, method)
+	if position.Quantity <= 0 {
+		return net
+	}
+	net.Quantity = position.Quantity
+	if position.Side == db.PositionSideShort {
+		net.Quantity = -position.Quantity
+	}
+	net.AvgEntryPrice = position.EntryPrice
+	net.Fees = position.Fees
+	net.Lots = []Lot{{Quantity: position.Quantity, Price: position.EntryPrice, OpenedAt: position.EntryTime}}
+	return net
+}
+
+// IsFlat reports whether there is no open quantity
+func (p *NetPosition) IsFlat() bool {
+	return math.Abs(p.Quantity) <= stepEpsilon
+}
+
+// Side returns the database side of the position
+func (p *NetPosition) Side() db.PositionSide {
+	switch {
+	case p.IsFlat():
+		return db.PositionSideFlat
+	case p.Quantity > 0:
+		return db.PositionSideLong
+	default:
+		return db.PositionSideShort
+	}
+}
+
+// UnrealizedPnL returns the mark-to-market P&L at price
+func (p *NetPosition) UnrealizedPnL(price float64) float64 {
+	if p.IsFlat() {
+		return 0
+	}
+	return (price - p.AvgEntryPrice) * p.Quantity
+}
+
+// Apply books a fill against the position. Fees are split pro rata between
+// the closing and opening quantity so a flip does not double count them.
+func (p *NetPosition) Apply(side OrderSide, quantity, price, fees float64, at time.Time) FillResult {
+	var result FillResult
+	if quantity <= 0 {
+		return result
+	}
+
+	signed := quantity
+	if side == OrderSideSell {
+		signed = -quantity
+	}
+
+	remaining := quantity
+	// Reduce the existing position when the fill is in the opposite direction
+	if !p.IsFlat() && (p.Quantity > 0) != (signed > 0) {
+		closeQty := math.Min(quantity, math.Abs(p.Quantity))
+		costBasis := p.consume(closeQty)
+		direction := 1.0
+		if p.Quantity < 0 {
+			direction = -1.0
+		}
+
+		result.ClosedQty = closeQty
+		result.ClosedEntryPrice = costBasis / closeQty
+		result.CloseFees = fees * closeQty / quantity
+		result.RealizedPnL = (price*closeQty-costBasis)*direction - result.CloseFees
+
+		p.Quantity -= closeQty * direction
+		p.RealizedPnL += result.RealizedPnL
+		remaining -= closeQty
+	}
+
+	if remaining > stepEpsilon {
+		result.Flipped = result.ClosedQty > 0
+		result.OpenedQty = remaining
+		result.OpenFees = fees - result.CloseFees
+
+		if signed > 0 {
+			p.Quantity += remaining
+		} else {
+			p.Quantity -= remaining
+		}
+		p.addLot(Lot{Quantity: remaining, Price: price, OpenedAt: at})
+	}
+
+	p.Fees += fees
+	if p.IsFlat() {
+		p.Quantity = 0
+		p.Lots = nil
+	}
+	p.AvgEntryPrice = p.lotAverage()
+
+	return result
+}
+
+// consume removes quantity from the open lots and returns its cost basis
+func (p *NetPosition) consume(quantity float64) float64 {
+	var cost float64
+	for quantity > stepEpsilon && len(p.Lots) > 0 {
+		lot := &p.Lots[0]
+		take := math.Min(quantity, lot.Quantity)
+		cost += take * lot.Price
+		lot.Quantity -= take
+		quantity -= take
+		if lot.Quantity <= stepEpsilon {
+			p.Lots = p.Lots[1:]
+		}
+	}
+	return cost
+}
+
+// addLot records newly opened quantity according to the lot method
+func (p *NetPosition) addLot(lot Lot) {
+	if p.Method == LotMethodFIFO || len(p.Lots) == 0 {
+		p.Lots = append(p.Lots, lot)
+		return
+	}
+
+	// Average cost collapses everything into one lot that keeps the original open time
+	merged := &p.Lots[0]
+	total := merged.Quantity + lot.Quantity
+	merged.Price = (merged.Price*merged.Quantity + lot.Price*lot.Quantity) / total
+	merged.Quantity = total
+}
+
+// lotAverage returns the volume-weighted price of the open lots
+func (p *NetPosition) lotAverage() float64 {
+	var qty, cost float64
+	for _, lot := range p.Lots {
+		qty += lot.Quantity
+		cost += lot.Quantity * lot.Price
+	}
+	if qty <= stepEpsilon {
+		return 0
+	}
+	return cost / qty
+}
@@ -0,0 +1,291 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+type testFill struct {
+	side  OrderSide
+	qty   float64
+	price float64
+	fees  float64
+}
+
+func TestNetPosition_Apply(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       LotMethod
+		fills        []testFill
+		wantQty      float64
+		wantAvg      float64
+		wantRealized float64
+		wantLots     int
+		wantFlipped  bool // Flipped flag of the last fill
+	}{
+		{
+			name:    "open long and average",
+			method:  LotMethodAverageCost,
+			fills:   []testFill{{OrderSideBuy, 1, 100, 0}, {OrderSideBuy, 1, 110, 0}},
+			wantQty: 2, wantAvg: 105, wantLots: 1,
+		},
+		{
+			name:    "open short and average",
+			method:  LotMethodAverageCost,
+			fills:   []testFill{{OrderSideSell, 2, 100, 0}, {OrderSideSell, 2, 90, 0}},
+			wantQty: -4, wantAvg: 95, wantLots: 1,
+		},
+		{
+			name:         "partial close long",
+			method:       LotMethodAverageCost,
+			fills:        []testFill{{OrderSideBuy, 10, 100, 0}, {OrderSideSell, 4, 110, 0}},
+			wantQty:      6,
+			wantAvg:      100,
+			wantRealized: 40,
+			wantLots:     1,
+		},
+		{
+			name:         "partial close short",
+			method:       LotMethodAverageCost,
+			fills:        []testFill{{OrderSideSell, 10, 100, 0}, {OrderSideBuy, 3, 90, 0}},
+			wantQty:      -7,
+			wantAvg:      100,
+			wantRealized: 30,
+			wantLots:     1,
+		},
+		{
+			name:         "exact close to flat",
+			method:       LotMethodAverageCost,
+			fills:        []testFill{{OrderSideBuy, 1, 100, 0}, {OrderSideSell, 1, 90, 0}},
+			wantQty:      0,
+			wantAvg:      0,
+			wantRealized: -10,
+		},
+		{
+			name:         "long flips to short in one fill",
+			method:       LotMethodAverageCost,
+			fills:        []testFill{{OrderSideBuy, 1, 100, 0}, {OrderSideSell, 3, 120, 0}},
+			wantQty:      -2,
+			wantAvg:      120,
+			wantRealized: 20,
+			wantLots:     1,
+			wantFlipped:  true,
+		},
+		{
+			name:         "short flips to long in one fill",
+			method:       LotMethodAverageCost,
+			fills:        []testFill{{OrderSideSell, 2, 100, 0}, {OrderSideBuy, 5, 110, 0}},
+			wantQty:      3,
+			wantAvg:      110,
+			wantRealized: -20,
+			wantLots:     1,
+			wantFlipped:  true,
+		},
+		{
+			name:   "partial fills cross zero",
+			method: LotMethodAverageCost,
+			fills: []testFill{
+				{OrderSideBuy, 1.5, 100, 0},
+				{OrderSideSell, 0.5, 104, 0},  // long 1.0
+				{OrderSideSell, 0.75, 106, 0}, // long 0.25
+				{OrderSideSell, 0.75, 108, 0}, // crosses zero: short 0.5
+			},
+			wantQty:      -0.5,
+			wantAvg:      108,
+			wantRealized: 0.5*4 + 0.75*6 + 0.25*8,
+			wantLots:     1,
+			wantFlipped:  true,
+		},
+		{
+			name:   "fifo closes oldest lots first",
+			method: LotMethodFIFO,
+			fills: []testFill{
+				{OrderSideBuy, 1, 100, 0},
+				{OrderSideBuy, 1, 120, 0},
+				{OrderSideSell, 1.5, 130, 0},
+			},
+			wantQty:      0.5,
+			wantAvg:      120,
+			wantRealized: 1*30 + 0.5*10,
+			wantLots:     1,
+		},
+		{
+			name:   "average cost closes at blended price",
+			method: LotMethodAverageCost,
+			fills: []testFill{
+				{OrderSideBuy, 1, 100, 0},
+				{OrderSideBuy, 1, 120, 0},
+				{OrderSideSell, 1.5, 130, 0},
+			},
+			wantQty:      0.5,
+			wantAvg:      110,
+			wantRealized: 1.5 * 20,
+			wantLots:     1,
+		},
+		{
+			name:   "fifo short partial fills cross zero",
+			method: LotMethodFIFO,
+			fills: []testFill{
+				{OrderSideSell, 1, 100, 0},
+				{OrderSideSell, 1, 90, 0},
+				{OrderSideBuy, 0.5, 95, 0}, // closes half of the 100 lot
+				{OrderSideBuy, 2, 80, 0},   // closes the rest and opens long 0.5
+			},
+			wantQty:      0.5,
+			wantAvg:      80,
+			wantRealized: 0.5*5 + 0.5*20 + 1*10,
+			wantLots:     1,
+			wantFlipped:  true,
+		},
+		{
+			name:   "fees are split across a flip",
+			method: LotMethodAverageCost,
+			fills: []testFill{
+				{OrderSideBuy, 1, 100, 1},
+				{OrderSideSell, 4, 100, 4}, // 1 closes, 3 open short
+			},
+			wantQty:      -3,
+			wantAvg:      100,
+			wantRealized: -1,
+			wantLots:     1,
+			wantFlipped:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			net := NewNetPosition("BTCUSDT", tt.method)
+			at := time.Now()
+
+			var result FillResult
+			for i, f := range tt.fills {
+				result = net.Apply(f.side, f.qty, f.price, f.fees, at.Add(time.Duration(i)*time.Second))
+			}
+
+			assert.InDelta(t, tt.wantQty, net.Quantity, 1e-9)
+			assert.InDelta(t, tt.wantAvg, net.AvgEntryPrice, 1e-9)
+			assert.InDelta(t, tt.wantRealized, net.RealizedPnL, 1e-9)
+			assert.Len(t, net.Lots, tt.wantLots)
+			assert.Equal(t, tt.wantFlipped, result.Flipped)
+		})
+	}
+}
+
+func TestNetPosition_FlipResult(t *testing.T) {
+	net := NewNetPosition("BTCUSDT", LotMethodAverageCost)
+	net.Apply(OrderSideBuy, 2, 100, 0, time.Now())
+
+	result := net.Apply(OrderSideSell, 5, 90, 5, time.Now())
+	assert.True(t, result.Flipped)
+	assert.InDelta(t, 2, result.ClosedQty, 1e-9)
+	assert.InDelta(t, 100, result.ClosedEntryPrice, 1e-9)
+	assert.InDelta(t, 3, result.OpenedQty, 1e-9)
+	assert.InDelta(t, 2, result.CloseFees, 1e-9)
+	assert.InDelta(t, 3, result.OpenFees, 1e-9)
+	assert.InDelta(t, -22, result.RealizedPnL, 1e-9)
+	assert.Equal(t, db.PositionSideShort, net.Side())
+	assert.InDelta(t, 15, net.UnrealizedPnL(85), 1e-9)
+}
+
+func TestParseLotMethod(t *testing.T) {
+	method, err := ParseLotMethod("")
+	require.NoError(t, err)
+	assert.Equal(t, LotMethodAverageCost, method)
+
+	method, err = ParseLotMethod("FIFO")
+	require.NoError(t, err)
+	assert.Equal(t, LotMethodFIFO, method)
+
+	_, err = ParseLotMethod("lifo")
+	assert.Error(t, err)
+}
+
+func TestPositionManager_OnOrderFilledFlipsPosition(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      LotMethod
+		fills       []testFill
+		wantSide    db.PositionSide
+		wantQty     float64
+		wantEntry   float64
+		wantRealize float64
+	}{
+		{
+			name:        "long partial fills cross into short",
+			method:      LotMethodAverageCost,
+			fills:       []testFill{{OrderSideBuy, 1, 100, 0}, {OrderSideSell, 0.6, 110, 0}, {OrderSideSell, 0.6, 120, 0}},
+			wantSide:    db.PositionSideShort,
+			wantQty:     0.2,
+			wantEntry:   120,
+			wantRealize: 0.6*10 + 0.4*20,
+		},
+		{
+			name:        "short partial fills cross into long",
+			method:      LotMethodFIFO,
+			fills:       []testFill{{OrderSideSell, 1, 100, 0}, {OrderSideSell, 1, 110, 0}, {OrderSideBuy, 1.5, 90, 0}, {OrderSideBuy, 1, 95, 0}},
+			wantSide:    db.PositionSideLong,
+			wantQty:     0.5,
+			wantEntry:   95,
+			wantRealize: 1*10 + 0.5*20 + 0.5*15,
+		},
+		{
+			name:        "fifo partial close reprices remaining quantity",
+			method:      LotMethodFIFO,
+			fills:       []testFill{{OrderSideBuy, 1, 100, 0}, {OrderSideBuy, 1, 120, 0}, {OrderSideSell, 1, 130, 0}},
+			wantSide:    db.PositionSideLong,
+			wantQty:     1,
+			wantEntry:   120,
+			wantRealize: 30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := NewPositionManagerWithFees(nil, 0)
+			sessionID := uuid.New()
+			pm.SetSessionWithLotMethod(&sessionID, tt.method)
+			assert.Equal(t, tt.method, pm.LotMethod())
+
+			for _, f := range tt.fills {
+				order := &Order{Symbol: "BTCUSDT", Side: f.side, Quantity: f.qty}
+				require.NoError(t, pm.OnOrderFilled(context.Background(), order, []Fill{{Price: f.price, Quantity: f.qty}}))
+			}
+
+			pos, ok := pm.GetPosition("BTCUSDT")
+			require.True(t, ok)
+			assert.Equal(t, tt.wantSide, pos.Side)
+			assert.InDelta(t, tt.wantQty, pos.Quantity, 1e-9)
+			assert.InDelta(t, tt.wantEntry, pos.EntryPrice, 1e-9)
+			assert.InDelta(t, tt.wantRealize, pm.GetTotalRealizedPnL(), 1e-9)
+
+			net, ok := pm.GetNetPosition("BTCUSDT")
+			require.True(t, ok)
+			assert.Equal(t, tt.wantSide, net.Side())
+			assert.InDelta(t, tt.wantEntry, net.AvgEntryPrice, 1e-9)
+		})
+	}
+}
+
+func TestPositionManager_SeedsNetPositionFromOpenPosition(t *testing.T) {
+	pm := NewPositionManagerWithFees(nil, 0)
+	sessionID := uuid.New()
+	pm.SetSession(&sessionID)
+	pm.openPositions["BTCUSDT"] = &db.Position{
+		ID: uuid.New(), SessionID: &sessionID, Symbol: "BTCUSDT",
+		Side: db.PositionSideShort, EntryPrice: 100, Quantity: 2, EntryTime: time.Now(),
+	}
+
+	order := &Order{Symbol: "BTCUSDT", Side: OrderSideBuy, Quantity: 2}
+	require.NoError(t, pm.OnOrderFilled(context.Background(), order, []Fill{{Price: 90, Quantity: 2}}))
+
+	_, ok := pm.GetPosition("BTCUSDT")
+	assert.False(t, ok, "short should be fully closed")
+	assert.InDelta(t, 20, pm.GetTotalRealizedPnL(), 1e-9)
+}
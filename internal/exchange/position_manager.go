@@ -3,6 +3,7 @@ package exchange
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,12 +13,17 @@ import (
 	"github.com/ajitpratap0/cryptofunk/internal/db"
 )
 
-// PositionManager handles position tracking and P&L calculation
+// PositionManager handles position tracking and P&L calculation.
+// Each symbol has a single net position: fills in the opposite direction reduce
+// it, and fills that cross zero close it and open the opposite side.
 type PositionManager struct {
 	db               *db.DB
 	mu               sync.RWMutex
 	openPositions    map[string]*db.Position // symbol -> position
+	netPositions     map[string]*NetPosition // symbol -> signed position with open lots
+	taxLots          *TaxLotLedger           // Tax lots of the current session (nil without a session)
 	currentSessionID *uuid.UUID
+	lotMethod        LotMethod
 	feeRate          float64 // Average fee rate for calculations
 }
 
@@ -32,16 +38,30 @@ func NewPositionManagerWithFees(database *db.DB, feeRate float64) *PositionManag
 	return &PositionManager{
 		db:            database,
 		openPositions: make(map[string]*db.Position),
+		netPositions:  make(map[string]*NetPosition),
+		lotMethod:     LotMethodAverageCost,
 		feeRate:       feeRate,
 	}
 }
 
-// SetSession sets the current trading session
+// SetSession sets the current trading session using average-cost lots
 func (pm *PositionManager) SetSession(sessionID *uuid.UUID) {
+	pm.SetSessionWithLotMethod(sessionID, LotMethodAverageCost)
+}
+
+// SetSessionWithLotMethod sets the current trading session and the lot method
+// used to match closing fills against open lots for that session
+func (pm *PositionManager) SetSessionWithLotMethod(sessionID *uuid.UUID, method LotMethod) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
+	if method == "" {
+		method = LotMethodAverageCost
+	}
 	pm.currentSessionID = sessionID
+	pm.lotMethod = method
+	pm.netPositions = make(map[string]*NetPosition)
+	pm.taxLots = nil
 
 	// Load open positions for this session (only if database is available)
 	if sessionID != nil && pm.db != nil {
@@ -49,6 +69,41 @@ func (pm *PositionManager) SetSession(sessionID *uuid.UUID) {
 	} else {
 		pm.openPositions = make(map[string]*db.Position)
 	}
+
+	if sessionID != nil {
+		var store TaxLotStore
+		if pm.db != nil {
+			store = pm.db
+		}
+		pm.taxLots = NewTaxLotLedger(store, sessionID, TaxLotFIFO)
+		if err := pm.taxLots.Load(context.Background()); err != nil {
+			log.Error().Err(err).Msg("Failed to load open tax lots")
+		}
+	}
+}
+
+// SetTaxLotMethod sets how closing fills of the current session are matched against tax lots
+func (pm *PositionManager) SetTaxLotMethod(method TaxLotMethod) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if pm.taxLots != nil {
+		pm.taxLots.SetMethod(method)
+	}
+}
+
+// TaxLots returns the tax-lot ledger of the current session (nil without a session)
+func (pm *PositionManager) TaxLots() *TaxLotLedger {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.taxLots
+}
+
+// LotMethod returns the lot method of the current session
+func (pm *PositionManager) LotMethod() LotMethod {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.lotMethod
 }
 
 // loadOpenPositions loads open positions from database
@@ -70,6 +125,23 @@ func (pm *PositionManager) loadOpenPositions(sessionID uuid.UUID) {
 		Msg("Loaded open positions from database")
 }
 
+// netPosition returns the net position for a symbol, seeding it from the open
+// database position when the symbol has not traded since the session was loaded
+func (pm *PositionManager) netPosition(symbol string) *NetPosition {
+	if net, ok := pm.netPositions[symbol]; ok {
+		return net
+	}
+
+	var net *NetPosition
+	if pos, ok := pm.openPositions[symbol]; ok {
+		net = netPositionFromDB(pos, pm.lotMethod)
+	} else {
+		net = NewNetPosition(symbol, pm.lotMethod)
+	}
+	pm.netPositions[symbol] = net
+	return net
+}
+
 // OnOrderFilled handles order fill events and updates positions
 func (pm *PositionManager) OnOrderFilled(ctx context.Context, order *Order, fills []Fill) error {
 	pm.mu.Lock()
@@ -91,100 +163,87 @@ func (pm *PositionManager) OnOrderFilled(ctx context.Context, order *Order, fill
 		totalFees += fill.Price * fill.Quantity * pm.feeRate
 	}
 
+	if totalQty <= 0 {
+		return nil
+	}
 	avgFillPrice := totalValue / totalQty
 
-	// Check if we have an existing position for this symbol
+	net := pm.netPosition(order.Symbol)
+	previous := *net
+	previous.Lots = append([]Lot(nil), net.Lots...)
+
+	now := time.Now()
+	result := net.Apply(order.Side, totalQty, avgFillPrice, totalFees, now)
+
+	if err := pm.syncPosition(ctx, order, net, result, avgFillPrice); err != nil {
+		// Keep the in-memory net position consistent with what was persisted
+		*net = previous
+		return err
+	}
+
+	// Tax lots are reporting data; a ledger failure must not unwind the position update
+	if pm.taxLots != nil {
+		if _, err := pm.taxLots.Apply(ctx, order, totalQty, avgFillPrice, totalFees, now); err != nil {
+			log.Error().Err(err).Str("symbol", order.Symbol).Msg("Failed to record tax lots")
+		}
+	}
+
+	log.Debug().
+		Str("symbol", order.Symbol).
+		Str("lot_method", string(net.Method)).
+		Float64("net_quantity", net.Quantity).
+		Float64("avg_entry_price", net.AvgEntryPrice).
+		Float64("realized_pnl", result.RealizedPnL).
+		Bool("flipped", result.Flipped).
+		Msg("Net position updated")
+
+	return nil
+}
+
+// syncPosition mirrors a net position change into the position records
+func (pm *PositionManager) syncPosition(ctx context.Context, order *Order, net *NetPosition, result FillResult, fillPrice float64) error {
 	existingPos, hasPosition := pm.openPositions[order.Symbol]
 
-	if order.Side == OrderSideBuy {
-		// BUY order
-		if hasPosition {
-			if existingPos.Side == db.PositionSideShort {
-				// Closing or reducing SHORT position
-				if totalQty >= existingPos.Quantity {
-					// Fully closing SHORT position
-					closeQty := existingPos.Quantity
-					err := pm.closePosition(ctx, existingPos, avgFillPrice, "Closed by BUY order", totalFees)
-					if err != nil {
-						return err
-					}
-
-					// If quantity > existing position, open new LONG position
-					if totalQty > closeQty {
-						remainingQty := totalQty - closeQty
-						err := pm.openPosition(ctx, order.Symbol, db.PositionSideLong, avgFillPrice, remainingQty, "Opened after closing SHORT", totalFees)
-						if err != nil {
-							return err
-						}
-					}
-				} else {
-					// Partially closing SHORT position
-					err := pm.partialClosePosition(ctx, existingPos, totalQty, avgFillPrice, "Partially closed by BUY order", totalFees)
-					if err != nil {
-						return err
-					}
-				}
-			} else {
-				// Adding to LONG position (position averaging)
-				err := pm.averagePosition(ctx, existingPos, avgFillPrice, totalQty, totalFees)
-				if err != nil {
-					return err
-				}
+	if result.ClosedQty > 0 && hasPosition {
+		orderSide := strings.ToUpper(string(order.Side))
+		closeReason := fmt.Sprintf("Closed by %s order", orderSide)
+		if net.IsFlat() || result.Flipped {
+			if err := pm.closePosition(ctx, existingPos, fillPrice, closeReason, result.CloseFees); err != nil {
+				return err
 			}
+			hasPosition = false
 		} else {
-			// Opening new LONG position
-			err := pm.openPosition(ctx, order.Symbol, db.PositionSideLong, avgFillPrice, totalQty, "Opened by BUY order", totalFees)
-			if err != nil {
+			closeReason = fmt.Sprintf("Partially closed by %s order", orderSide)
+			if err := pm.partialClosePosition(ctx, existingPos, result.ClosedQty, result.ClosedEntryPrice, net.AvgEntryPrice, fillPrice, closeReason, result.CloseFees); err != nil {
 				return err
 			}
 		}
-	} else {
-		// SELL order
+	}
+
+	if result.OpenedQty > 0 {
 		if hasPosition {
-			if existingPos.Side == db.PositionSideLong {
-				// Closing or reducing LONG position
-				if totalQty >= existingPos.Quantity {
-					// Fully closing LONG position
-					closeQty := existingPos.Quantity
-					err := pm.closePosition(ctx, existingPos, avgFillPrice, "Closed by SELL order", totalFees)
-					if err != nil {
-						return err
-					}
-
-					// If quantity > existing position, open new SHORT position
-					if totalQty > closeQty {
-						remainingQty := totalQty - closeQty
-						err := pm.openPosition(ctx, order.Symbol, db.PositionSideShort, avgFillPrice, remainingQty, "Opened after closing LONG", totalFees)
-						if err != nil {
-							return err
-						}
-					}
-				} else {
-					// Partially closing LONG position
-					err := pm.partialClosePosition(ctx, existingPos, totalQty, avgFillPrice, "Partially closed by SELL order", totalFees)
-					if err != nil {
-						return err
-					}
-				}
-			} else {
-				// Adding to SHORT position (position averaging)
-				err := pm.averagePosition(ctx, existingPos, avgFillPrice, totalQty, totalFees)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			// Opening new SHORT position
-			err := pm.openPosition(ctx, order.Symbol, db.PositionSideShort, avgFillPrice, totalQty, "Opened by SELL order", totalFees)
-			if err != nil {
-				return err
-			}
+			// Adding to the existing position (position averaging)
+			return pm.averagePosition(ctx, existingPos, fillPrice, result.OpenedQty, result.OpenFees)
+		}
+
+		openReason := fmt.Sprintf("Opened by %s order", strings.ToUpper(string(order.Side)))
+		if result.Flipped {
+			openReason = fmt.Sprintf("Opened after closing %s", flipSide(net.Side()))
 		}
+		return pm.openPosition(ctx, order.Symbol, net.Side(), fillPrice, result.OpenedQty, openReason, result.OpenFees)
 	}
 
 	return nil
 }
 
+// flipSide returns the opposite position side
+func flipSide(side db.PositionSide) db.PositionSide {
+	if side == db.PositionSideLong {
+		return db.PositionSideShort
+	}
+	return db.PositionSideLong
+}
+
 // openPosition creates a new position
 func (pm *PositionManager) openPosition(ctx context.Context, symbol string, side db.PositionSide, entryPrice, quantity float64, reason string, fees float64) error {
 	position := &db.Position{
@@ -200,9 +259,6 @@ func (pm *PositionManager) openPosition(ctx context.Context, symbol string, side
 		EntryReason: &reason,
 	}
 
-	// Store in memory
-	pm.openPositions[symbol] = position
-
 	// Create in database (if available)
 	if pm.db != nil {
 		err := pm.db.CreatePosition(ctx, position)
@@ -212,6 +268,9 @@ func (pm *PositionManager) openPosition(ctx context.Context, symbol string, side
 		}
 	}
 
+	// Store in memory
+	pm.openPositions[symbol] = position
+
 	log.Info().
 		Str("position_id", position.ID.String()).
 		Str("symbol", symbol).
@@ -225,9 +284,6 @@ func (pm *PositionManager) openPosition(ctx context.Context, symbol string, side
 
 // closePosition closes an existing position
 func (pm *PositionManager) closePosition(ctx context.Context, position *db.Position, exitPrice float64, reason string, fees float64) error {
-	// Remove from memory
-	delete(pm.openPositions, position.Symbol)
-
 	// Close in database (if available)
 	if pm.db != nil {
 		err := pm.db.ClosePosition(ctx, position.ID, exitPrice, reason, fees)
@@ -237,6 +293,9 @@ func (pm *PositionManager) closePosition(ctx context.Context, position *db.Posit
 		}
 	}
 
+	// Remove from memory
+	delete(pm.openPositions, position.Symbol)
+
 	// Calculate realized P&L for logging
 	var realizedPnL float64
 	if position.Side == db.PositionSideLong {
@@ -258,32 +317,42 @@ func (pm *PositionManager) closePosition(ctx context.Context, position *db.Posit
 	return nil
 }
 
-// partialClosePosition partially closes a position
-func (pm *PositionManager) partialClosePosition(ctx context.Context, position *db.Position, closeQuantity, exitPrice float64, reason string, fees float64) error {
+// partialClosePosition partially closes a position. closeEntryPrice is the cost
+// basis of the closed lots and remainingEntryPrice the average of the lots left open.
+func (pm *PositionManager) partialClosePosition(ctx context.Context, position *db.Position, closeQuantity, closeEntryPrice, remainingEntryPrice, exitPrice float64, reason string, fees float64) error {
+	realizedPnL := (exitPrice - closeEntryPrice) * closeQuantity
+	if position.Side == db.PositionSideShort {
+		realizedPnL = -realizedPnL
+	}
+	realizedPnL -= fees
+
 	// Use database method for partial close
+	closedID := ""
 	if pm.db != nil {
-		closedPos, err := pm.db.PartialClosePosition(ctx, position.ID, closeQuantity, exitPrice, reason, fees)
+		closedPos, err := pm.db.PartialClosePositionAtCost(ctx, position.ID, closeQuantity, closeEntryPrice, remainingEntryPrice, exitPrice, reason, fees)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to partial close position in database")
 			return err
 		}
-
-		// Update in-memory position
-		position.Quantity -= closeQuantity
-		position.Fees += fees
-
-		log.Info().
-			Str("position_id", position.ID.String()).
-			Str("closed_position_id", closedPos.ID.String()).
-			Str("symbol", position.Symbol).
-			Str("side", string(position.Side)).
-			Float64("close_quantity", closeQuantity).
-			Float64("remaining_quantity", position.Quantity).
-			Float64("exit_price", exitPrice).
-			Float64("realized_pnl", *closedPos.RealizedPnL).
-			Msg("Position partially closed")
+		closedID = closedPos.ID.String()
 	}
 
+	// Update in-memory position
+	position.Quantity -= closeQuantity
+	position.EntryPrice = remainingEntryPrice
+	position.Fees += fees
+
+	log.Info().
+		Str("position_id", position.ID.String()).
+		Str("closed_position_id", closedID).
+		Str("symbol", position.Symbol).
+		Str("side", string(position.Side)).
+		Float64("close_quantity", closeQuantity).
+		Float64("remaining_quantity", position.Quantity).
+		Float64("exit_price", exitPrice).
+		Float64("realized_pnl", realizedPnL).
+		Msg("Position partially closed")
+
 	return nil
 }
 
@@ -401,3 +470,41 @@ func (pm *PositionManager) GetTotalUnrealizedPnL() float64 {
 
 	return total
 }
+
+// GetNetPosition returns a copy of the net position for a symbol
+func (pm *PositionManager) GetNetPosition(symbol string) (NetPosition, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if net, ok := pm.netPositions[symbol]; ok {
+		snapshot := *net
+		snapshot.Lots = append([]Lot(nil), net.Lots...)
+		return snapshot, true
+	}
+	if pos, ok := pm.openPositions[symbol]; ok {
+		return *netPositionFromDB(pos, pm.lotMethod), true
+	}
+	return NetPosition{}, false
+}
+
+// GetTotalRealizedPnL returns realized P&L booked through fills this session
+func (pm *PositionManager) GetTotalRealizedPnL() float64 {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var total float64
+	for _, net := range pm.netPositions {
+		total += net.RealizedPnL
+	}
+
+	return total
+}
+
+// RemovePosition drops a position that was closed outside of order fills
+func (pm *PositionManager) RemovePosition(symbol string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	delete(pm.openPositions, symbol)
+	delete(pm.netPositions, symbol)
+}
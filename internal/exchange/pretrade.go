@@ -0,0 +1,553 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
+)
+
+// ErrPreTradeRejected is wrapped by every pre-trade rejection
+var ErrPreTradeRejected = errors.New("order rejected by pre-trade risk checks")
+
+// Names of the built-in pre-trade checks
+const (
+	CheckRestrictedSymbol = "restricted_symbol"
+	CheckMaxNotional      = "max_notional"
+	CheckPriceBand        = "price_band"
+	CheckMaxPosition      = "max_position"
+	CheckMaxOpenOrders    = "max_open_orders"
+	CheckDailyLoss        = "daily_loss"
+)
+
+// PreTradeConfig sets the limits of the built-in pre-trade checks. A zero limit
+// disables its check.
+type PreTradeConfig struct {
+	MaxOrderNotional    float64  // Largest single order value in quote currency
+	MaxPositionNotional float64  // Largest resulting position value per symbol
+	PriceBand           float64  // Max limit price deviation from the last price, e.g. 0.05 for 5%
+	MaxOpenOrders       int      // Resting orders per session
+	RestrictedSymbols   []string // Symbols that may not be traded
+	MaxDailyLoss        float64  // Daily loss as a fraction of capital, e.g. 0.02 for 2%
+}
+
+// PreTradeOrder is an order together with the account state the checks evaluate
+type PreTradeOrder struct {
+	SessionID *uuid.UUID
+	Source    string // mcp, api or algo
+	Symbol    string
+	Side      OrderSide
+	Type      OrderType
+	Quantity  float64
+	Price     float64 // Limit price; zero for market orders
+
+	LastPrice   float64 // Reference price; zero when unknown
+	PositionQty float64 // Current signed net position in the symbol
+	OpenOrders  int     // Resting orders; negative when unknown
+	Capital     float64 // Capital the daily loss limit is relative to; zero when unknown
+	DailyPnL    float64 // P&L since UTC midnight
+
+	RequestedBy string // Caller recorded in the audit log (optional)
+	IPAddress   string // Client address recorded in the audit log (optional)
+}
+
+// referencePrice is the price used to value the order
+func (o *PreTradeOrder) referencePrice() float64 {
+	if o.Price > 0 {
+		return o.Price
+	}
+	return o.LastPrice
+}
+
+// resultingPositionQty is the signed position after the order fills completely
+func (o *PreTradeOrder) resultingPositionQty() float64 {
+	if o.Side == OrderSideSell {
+		return o.PositionQty - o.Quantity
+	}
+	return o.PositionQty + o.Quantity
+}
+
+// reducesExposure reports whether the order only shrinks the current position
+func (o *PreTradeOrder) reducesExposure() bool {
+	return math.Abs(o.resultingPositionQty()) < math.Abs(o.PositionQty)
+}
+
+// openOrderCounter is implemented by exchanges that track their resting orders
+type openOrderCounter interface {
+	OpenOrderCount() int
+}
+
+// countOpenOrders counts the pending and open orders of an order map
+func countOpenOrders(orders map[string]*Order) int {
+	var count int
+	for _, order := range orders {
+		if order.Status == OrderStatusOpen || order.Status == OrderStatusPending {
+			count++
+		}
+	}
+	return count
+}
+
+// PreTradeRejection describes why a check rejected an order
+type PreTradeRejection struct {
+	Check  string  `json:"check"`
+	Reason string  `json:"reason"`
+	Limit  float64 `json:"limit,omitempty"`
+	Actual float64 `json:"actual,omitempty"`
+}
+
+// PreTradeError is returned for an order rejected by a pre-trade check
+type PreTradeError struct {
+	Symbol    string            `json:"symbol"`
+	Rejection PreTradeRejection `json:"rejection"`
+}
+
+func (e *PreTradeError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", ErrPreTradeRejected.Error(), e.Rejection.Reason, e.Rejection.Check)
+}
+
+func (e *PreTradeError) Unwrap() error {
+	return ErrPreTradeRejected
+}
+
+// PreTradeCheck is one step of the pre-trade chain. Check returns nil to pass the order.
+type PreTradeCheck interface {
+	Name() string
+	Check(order *PreTradeOrder) *PreTradeRejection
+}
+
+// PreTradeRisk runs an ordered chain of pre-trade checks on every order and
+// audits rejections. The first rejecting check stops the chain.
+type PreTradeRisk struct {
+	mu     sync.RWMutex
+	checks []PreTradeCheck
+	audit  *audit.Logger
+	prices PriceSource
+	pnl    PnLSource
+	now    func() time.Time
+
+	// P&L at the first order of the current UTC day, per session; only used
+	// without a P&L source
+	dayMu    sync.Mutex
+	dayMarks map[string]dayMark
+}
+
+// PnLSource returns the account's P&L since a time: realized on positions
+// closed since then plus unrealized on open positions (db.GetPnLSince)
+type PnLSource func(ctx context.Context, since time.Time) (float64, error)
+
+// dayMark is the cumulative P&L observed when a day started
+type dayMark struct {
+	day string
+	pnl float64
+}
+
+// NewPreTradeRisk creates a pre-trade chain with the built-in checks enabled by config
+func NewPreTradeRisk(config PreTradeConfig, auditLogger *audit.Logger) *PreTradeRisk {
+	return &PreTradeRisk{
+		checks:   DefaultPreTradeChecks(config),
+		audit:    auditLogger,
+		now:      time.Now,
+		dayMarks: make(map[string]dayMark),
+	}
+}
+
+// DefaultPreTradeChecks returns the built-in checks with a non-zero limit, in
+// evaluation order
+func DefaultPreTradeChecks(config PreTradeConfig) []PreTradeCheck {
+	var checks []PreTradeCheck
+	if len(config.RestrictedSymbols) > 0 {
+		checks = append(checks, NewRestrictedSymbolsCheck(config.RestrictedSymbols))
+	}
+	if config.MaxOrderNotional > 0 {
+		checks = append(checks, MaxNotionalCheck{Limit: config.MaxOrderNotional})
+	}
+	if config.PriceBand > 0 {
+		checks = append(checks, PriceBandCheck{MaxDeviation: config.PriceBand})
+	}
+	if config.MaxPositionNotional > 0 {
+		checks = append(checks, MaxPositionCheck{Limit: config.MaxPositionNotional})
+	}
+	if config.MaxOpenOrders > 0 {
+		checks = append(checks, MaxOpenOrdersCheck{Limit: config.MaxOpenOrders})
+	}
+	if config.MaxDailyLoss > 0 {
+		checks = append(checks, DailyLossCheck{MaxLoss: config.MaxDailyLoss})
+	}
+	return checks
+}
+
+// Add appends a check to the end of the chain
+func (r *PreTradeRisk) Add(check PreTradeCheck) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check)
+}
+
+// Checks returns the names of the checks in evaluation order
+func (r *PreTradeRisk) Checks() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.checks))
+	for _, check := range r.checks {
+		names = append(names, check.Name())
+	}
+	return names
+}
+
+// SetAuditLogger sets the logger rejections are recorded with
+func (r *PreTradeRisk) SetAuditLogger(auditLogger *audit.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audit = auditLogger
+}
+
+// SetPriceSource sets the fallback source of last prices
+func (r *PreTradeRisk) SetPriceSource(prices PriceSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prices = prices
+}
+
+// SetPnLSource sets the source of the account's P&L. With one set, the daily
+// loss check measures the P&L recorded since UTC midnight, the same figure the
+// API checks manual orders against.
+func (r *PreTradeRisk) SetPnLSource(pnl PnLSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pnl = pnl
+}
+
+// LastPrice returns the last price from the price source, or zero when unknown
+func (r *PreTradeRisk) LastPrice(ctx context.Context, symbol string) float64 {
+	r.mu.RLock()
+	prices := r.prices
+	r.mu.RUnlock()
+
+	if prices == nil {
+		return 0
+	}
+	price, err := prices(ctx, symbol)
+	if err != nil {
+		log.Debug().Err(err).Str("symbol", symbol).Msg("No last price for pre-trade checks")
+		return 0
+	}
+	return price
+}
+
+// DailyPnL returns the P&L since UTC midnight from the P&L source. Without a
+// source, or when it fails, a session's cumulative P&L is measured against its
+// value at the session's first order of the day.
+func (r *PreTradeRisk) DailyPnL(ctx context.Context, sessionKey string, cumulativePnL float64) float64 {
+	r.mu.RLock()
+	source := r.pnl
+	r.mu.RUnlock()
+
+	now := r.now().UTC()
+	if source != nil {
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		pnl, err := source(ctx, midnight)
+		if err == nil {
+			return pnl
+		}
+		log.Warn().Err(err).Msg("Failed to load daily P&L, using the session's P&L since its first order of the day")
+	}
+
+	r.dayMu.Lock()
+	defer r.dayMu.Unlock()
+
+	day := now.Format("2006-01-02")
+	mark, ok := r.dayMarks[sessionKey]
+	if !ok || mark.day != day {
+		mark = dayMark{day: day, pnl: cumulativePnL}
+		r.dayMarks[sessionKey] = mark
+	}
+	return cumulativePnL - mark.pnl
+}
+
+// Evaluate runs the chain and returns a *PreTradeError for the first rejection
+func (r *PreTradeRisk) Evaluate(ctx context.Context, order *PreTradeOrder) error {
+	r.mu.RLock()
+	checks := r.checks
+	auditLogger := r.audit
+	r.mu.RUnlock()
+
+	for _, check := range checks {
+		rejection := check.Check(order)
+		if rejection == nil {
+			continue
+		}
+		if rejection.Check == "" {
+			rejection.Check = check.Name()
+		}
+
+		log.Warn().
+			Str("check", rejection.Check).
+			Str("symbol", order.Symbol).
+			Str("side", string(order.Side)).
+			Float64("quantity", order.Quantity).
+			Str("reason", rejection.Reason).
+			Msg("Order rejected by pre-trade check")
+
+		recordRejection(ctx, auditLogger, order, rejection)
+		return &PreTradeError{Symbol: order.Symbol, Rejection: *rejection}
+	}
+	return nil
+}
+
+// recordRejection audits a rejected order
+func recordRejection(ctx context.Context, auditLogger *audit.Logger, order *PreTradeOrder, rejection *PreTradeRejection) {
+	if auditLogger == nil {
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"check":    rejection.Check,
+		"limit":    rejection.Limit,
+		"actual":   rejection.Actual,
+		"symbol":   order.Symbol,
+		"side":     string(order.Side),
+		"type":     string(order.Type),
+		"quantity": order.Quantity,
+		"price":    order.Price,
+		"source":   order.Source,
+	}
+	if order.SessionID != nil {
+		metadata["session_id"] = order.SessionID.String()
+	}
+
+	ipAddress := order.IPAddress
+	if ipAddress == "" {
+		ipAddress = auditSystemIP
+	}
+
+	event := &audit.Event{
+		EventType: audit.EventTypeOrderRejected,
+		Severity:  audit.SeverityWarning,
+		UserID:    order.RequestedBy,
+		IPAddress: ipAddress,
+		Resource:  order.Symbol,
+		Action:    "Pre-trade check rejected order",
+		Success:   false,
+		ErrorMsg:  rejection.Reason,
+		Metadata:  metadata,
+	}
+	if err := auditLogger.Log(ctx, event); err != nil {
+		log.Error().Err(err).Str("check", rejection.Check).Msg("Failed to audit pre-trade rejection")
+	}
+}
+
+// RestrictedSymbolsCheck rejects orders for symbols that may not be traded
+type RestrictedSymbolsCheck struct {
+	symbols map[string]bool
+}
+
+// NewRestrictedSymbolsCheck creates a check rejecting the given symbols
+func NewRestrictedSymbolsCheck(symbols []string) RestrictedSymbolsCheck {
+	check := RestrictedSymbolsCheck{symbols: make(map[string]bool, len(symbols))}
+	for _, symbol := range symbols {
+		check.symbols[NormalizeSymbol(symbol)] = true
+	}
+	return check
+}
+
+func (c RestrictedSymbolsCheck) Name() string { return CheckRestrictedSymbol }
+
+func (c RestrictedSymbolsCheck) Check(order *PreTradeOrder) *PreTradeRejection {
+	if !c.symbols[NormalizeSymbol(order.Symbol)] {
+		return nil
+	}
+	return &PreTradeRejection{
+		Check:  CheckRestrictedSymbol,
+		Reason: fmt.Sprintf("symbol %s is restricted", order.Symbol),
+	}
+}
+
+// MaxNotionalCheck rejects orders worth more than Limit. Market orders are
+// valued at the last price and pass when it is unknown.
+type MaxNotionalCheck struct {
+	Limit float64
+}
+
+func (c MaxNotionalCheck) Name() string { return CheckMaxNotional }
+
+func (c MaxNotionalCheck) Check(order *PreTradeOrder) *PreTradeRejection {
+	notional := order.Quantity * order.referencePrice()
+	if notional <= c.Limit {
+		return nil
+	}
+	return &PreTradeRejection{
+		Check:  CheckMaxNotional,
+		Reason: fmt.Sprintf("order notional %.2f exceeds limit %.2f", notional, c.Limit),
+		Limit:  c.Limit,
+		Actual: notional,
+	}
+}
+
+// PriceBandCheck rejects limit orders priced further than MaxDeviation (a
+// fraction) from the last price, catching fat-finger prices
+type PriceBandCheck struct {
+	MaxDeviation float64
+}
+
+func (c PriceBandCheck) Name() string { return CheckPriceBand }
+
+func (c PriceBandCheck) Check(order *PreTradeOrder) *PreTradeRejection {
+	if order.Price <= 0 || order.LastPrice <= 0 {
+		return nil
+	}
+	deviation := math.Abs(order.Price-order.LastPrice) / order.LastPrice
+	if deviation <= c.MaxDeviation {
+		return nil
+	}
+	return &PreTradeRejection{
+		Check:  CheckPriceBand,
+		Reason: fmt.Sprintf("price %.8g is %.2f%% away from last price %.8g (band %.2f%%)", order.Price, deviation*100, order.LastPrice, c.MaxDeviation*100),
+		Limit:  c.MaxDeviation,
+		Actual: deviation,
+	}
+}
+
+// MaxPositionCheck rejects orders that would leave a position worth more than
+// Limit. Orders that reduce the position always pass.
+type MaxPositionCheck struct {
+	Limit float64
+}
+
+func (c MaxPositionCheck) Name() string { return CheckMaxPosition }
+
+func (c MaxPositionCheck) Check(order *PreTradeOrder) *PreTradeRejection {
+	if order.reducesExposure() {
+		return nil
+	}
+	notional := math.Abs(order.resultingPositionQty()) * order.referencePrice()
+	if notional <= c.Limit {
+		return nil
+	}
+	return &PreTradeRejection{
+		Check:  CheckMaxPosition,
+		Reason: fmt.Sprintf("resulting position notional %.2f exceeds limit %.2f", notional, c.Limit),
+		Limit:  c.Limit,
+		Actual: notional,
+	}
+}
+
+// MaxOpenOrdersCheck rejects limit orders once Limit orders are resting
+type MaxOpenOrdersCheck struct {
+	Limit int
+}
+
+func (c MaxOpenOrdersCheck) Name() string { return CheckMaxOpenOrders }
+
+func (c MaxOpenOrdersCheck) Check(order *PreTradeOrder) *PreTradeRejection {
+	if order.Type != OrderTypeLimit || order.OpenOrders < c.Limit {
+		return nil
+	}
+	return &PreTradeRejection{
+		Check:  CheckMaxOpenOrders,
+		Reason: fmt.Sprintf("%d open orders reached the limit of %d", order.OpenOrders, c.Limit),
+		Limit:  float64(c.Limit),
+		Actual: float64(order.OpenOrders),
+	}
+}
+
+// DailyLossCheck rejects orders that add exposure once the day's loss exceeds
+// MaxLoss (a fraction of capital). Orders that reduce the position still pass.
+type DailyLossCheck struct {
+	MaxLoss float64
+}
+
+func (c DailyLossCheck) Name() string { return CheckDailyLoss }
+
+func (c DailyLossCheck) Check(order *PreTradeOrder) *PreTradeRejection {
+	if order.Capital <= 0 || order.DailyPnL >= 0 || order.reducesExposure() {
+		return nil
+	}
+	loss := -order.DailyPnL / order.Capital
+	if loss < c.MaxLoss {
+		return nil
+	}
+	return &PreTradeRejection{
+		Check:  CheckDailyLoss,
+		Reason: fmt.Sprintf("daily loss %.2f%% reached the limit of %.2f%%", loss*100, c.MaxLoss*100),
+		Limit:  c.MaxLoss,
+		Actual: loss,
+	}
+}
+
+// PreTrade returns the service's pre-trade check chain, e.g. to add custom checks
+func (s *Service) PreTrade() *PreTradeRisk {
+	return s.preTrade
+}
+
+// SetAuditLogger sets the logger pre-trade rejections and margin guard orders
+// are recorded with
+func (s *Service) SetAuditLogger(auditLogger *audit.Logger) {
+	s.preTrade.SetAuditLogger(auditLogger)
+	s.audit = auditLogger
+}
+
+// checkPreTrade runs the pre-trade chain on an order about to be placed in a session
+func (s *Service) checkPreTrade(ctx context.Context, session *tradingSession, req PlaceOrderRequest, source string) error {
+	return s.preTrade.Evaluate(ctx, s.preTradeOrder(ctx, session, req, source))
+}
+
+// checkAmendPreTrade runs the pre-trade chain on a resting order as amended.
+// req carries the amended price and the quantity still to be filled; the
+// order already counts as one of the session's open orders.
+func (s *Service) checkAmendPreTrade(ctx context.Context, session *tradingSession, req PlaceOrderRequest, source string) error {
+	order := s.preTradeOrder(ctx, session, req, source)
+	if order.OpenOrders > 0 {
+		order.OpenOrders--
+	}
+	return s.preTrade.Evaluate(ctx, order)
+}
+
+// preTradeOrder describes an order in a session for the pre-trade chain
+func (s *Service) preTradeOrder(ctx context.Context, session *tradingSession, req PlaceOrderRequest, source string) *PreTradeOrder {
+	order := &PreTradeOrder{
+		SessionID:  session.id,
+		Source:     source,
+		Symbol:     req.Symbol,
+		Side:       req.Side,
+		Type:       req.Type,
+		Quantity:   req.Quantity,
+		Price:      req.Price,
+		LastPrice:  s.lastPrice(ctx, session, req.Symbol),
+		OpenOrders: -1,
+		Capital:    s.config.capital(),
+	}
+	if net, ok := session.positionManager.GetNetPosition(req.Symbol); ok {
+		order.PositionQty = net.Quantity
+	}
+	if counter, ok := session.exchange.(openOrderCounter); ok {
+		order.OpenOrders = counter.OpenOrderCount()
+	}
+
+	var sessionKey string
+	if session.id != nil {
+		sessionKey = session.id.String()
+	}
+	cumulativePnL := session.positionManager.GetTotalRealizedPnL() + session.positionManager.GetTotalUnrealizedPnL()
+	order.DailyPnL = s.preTrade.DailyPnL(ctx, sessionKey, cumulativePnL)
+	return order
+}
+
+// lastPrice returns the session exchange's mid price, falling back to the
+// pre-trade price source; zero when neither knows the symbol
+func (s *Service) lastPrice(ctx context.Context, session *tradingSession, symbol string) float64 {
+	if quotes, ok := session.exchange.(QuoteSource); ok {
+		if quote, err := quotes.GetQuote(ctx, symbol); err == nil && quote.Bid > 0 && quote.Ask > 0 {
+			return (quote.Bid + quote.Ask) / 2
+		}
+	}
+	return s.preTrade.LastPrice(ctx, symbol)
+}
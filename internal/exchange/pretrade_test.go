@@ -0,0 +1,295 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
+)
+
+func TestPreTradeChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		check  PreTradeCheck
+		order  PreTradeOrder
+		reject bool
+	}{
+		{
+			name:   "restricted symbol",
+			check:  NewRestrictedSymbolsCheck([]string{"LUNA/USDT"}),
+			order:  PreTradeOrder{Symbol: "LUNAUSDT", Side: OrderSideBuy, Quantity: 1},
+			reject: true,
+		},
+		{
+			name:  "unrestricted symbol",
+			check: NewRestrictedSymbolsCheck([]string{"LUNAUSDT"}),
+			order: PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideBuy, Quantity: 1},
+		},
+		{
+			name:   "market order notional at last price",
+			check:  MaxNotionalCheck{Limit: 1000},
+			order:  PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.1, LastPrice: 50000},
+			reject: true,
+		},
+		{
+			name:  "market order with unknown price",
+			check: MaxNotionalCheck{Limit: 1000},
+			order: PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.1},
+		},
+		{
+			name:   "limit price outside band",
+			check:  PriceBandCheck{MaxDeviation: 0.05},
+			order:  PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.1, Price: 5000, LastPrice: 50000},
+			reject: true,
+		},
+		{
+			name:  "limit price inside band",
+			check: PriceBandCheck{MaxDeviation: 0.05},
+			order: PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideSell, Type: OrderTypeLimit, Quantity: 0.1, Price: 51000, LastPrice: 50000},
+		},
+		{
+			name:   "position grows past limit",
+			check:  MaxPositionCheck{Limit: 10000},
+			order:  PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.1, LastPrice: 50000, PositionQty: 0.15},
+			reject: true,
+		},
+		{
+			name:  "reducing an oversized position",
+			check: MaxPositionCheck{Limit: 10000},
+			order: PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 0.1, LastPrice: 50000, PositionQty: 0.5},
+		},
+		{
+			name:   "flipping into an oversized short",
+			check:  MaxPositionCheck{Limit: 10000},
+			order:  PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 1, LastPrice: 50000, PositionQty: 0.5},
+			reject: true,
+		},
+		{
+			name:   "open order limit reached",
+			check:  MaxOpenOrdersCheck{Limit: 2},
+			order:  PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.1, Price: 50000, OpenOrders: 2},
+			reject: true,
+		},
+		{
+			name:  "market orders do not rest",
+			check: MaxOpenOrdersCheck{Limit: 2},
+			order: PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.1, OpenOrders: 2},
+		},
+		{
+			name:   "daily loss limit reached",
+			check:  DailyLossCheck{MaxLoss: 0.02},
+			order:  PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideBuy, Quantity: 0.1, Capital: 10000, DailyPnL: -250},
+			reject: true,
+		},
+		{
+			name:  "closing after the daily loss limit",
+			check: DailyLossCheck{MaxLoss: 0.02},
+			order: PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideSell, Quantity: 0.1, Capital: 10000, DailyPnL: -250, PositionQty: 0.1},
+		},
+		{
+			name:  "daily loss within limit",
+			check: DailyLossCheck{MaxLoss: 0.02},
+			order: PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideBuy, Quantity: 0.1, Capital: 10000, DailyPnL: -150},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejection := tt.check.Check(&tt.order)
+			if !tt.reject {
+				assert.Nil(t, rejection)
+				return
+			}
+			require.NotNil(t, rejection)
+			assert.Equal(t, tt.check.Name(), rejection.Check)
+			assert.NotEmpty(t, rejection.Reason)
+		})
+	}
+}
+
+func TestPreTradeRisk_FirstRejectionStopsChain(t *testing.T) {
+	risk := NewPreTradeRisk(PreTradeConfig{
+		RestrictedSymbols: []string{"LUNAUSDT"},
+		MaxOrderNotional:  1000,
+		PriceBand:         0.05,
+	}, audit.NewLogger(nil, true))
+	assert.Equal(t, []string{CheckRestrictedSymbol, CheckMaxNotional, CheckPriceBand}, risk.Checks())
+
+	err := risk.Evaluate(context.Background(), &PreTradeOrder{
+		Symbol:    "BTCUSDT",
+		Side:      OrderSideBuy,
+		Type:      OrderTypeLimit,
+		Quantity:  1,
+		Price:     5000,
+		LastPrice: 50000,
+	})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrPreTradeRejected))
+
+	var rejection *PreTradeError
+	require.True(t, errors.As(err, &rejection))
+	assert.Equal(t, CheckMaxNotional, rejection.Rejection.Check)
+	assert.Equal(t, 1000.0, rejection.Rejection.Limit)
+	assert.Equal(t, 5000.0, rejection.Rejection.Actual)
+}
+
+// rejectAllCheck is a custom check rejecting every order
+type rejectAllCheck struct{}
+
+func (rejectAllCheck) Name() string { return "reject_all" }
+
+func (rejectAllCheck) Check(order *PreTradeOrder) *PreTradeRejection {
+	return &PreTradeRejection{Reason: "trading halted"}
+}
+
+func TestPreTradeRisk_CustomCheck(t *testing.T) {
+	risk := NewPreTradeRisk(PreTradeConfig{}, nil)
+	order := &PreTradeOrder{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 1}
+	require.NoError(t, risk.Evaluate(context.Background(), order))
+
+	risk.Add(rejectAllCheck{})
+	err := risk.Evaluate(context.Background(), order)
+
+	var rejection *PreTradeError
+	require.True(t, errors.As(err, &rejection))
+	assert.Equal(t, "reject_all", rejection.Rejection.Check)
+}
+
+func TestPreTradeRisk_DailyPnL(t *testing.T) {
+	risk := NewPreTradeRisk(PreTradeConfig{}, nil)
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	risk.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	assert.Equal(t, 0.0, risk.DailyPnL(ctx, "session", 100))
+	assert.Equal(t, -150.0, risk.DailyPnL(ctx, "session", -50))
+	assert.Equal(t, 0.0, risk.DailyPnL(ctx, "other", -50))
+
+	// A new UTC day starts from the cumulative P&L at its first order
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, 0.0, risk.DailyPnL(ctx, "session", -50))
+	assert.Equal(t, -25.0, risk.DailyPnL(ctx, "session", -75))
+}
+
+func TestPreTradeRisk_DailyPnLFromSource(t *testing.T) {
+	risk := NewPreTradeRisk(PreTradeConfig{}, nil)
+	risk.now = func() time.Time { return time.Date(2024, 3, 1, 15, 30, 0, 0, time.FixedZone("EST", -5*3600)) }
+
+	var since time.Time
+	var sourceErr error
+	risk.SetPnLSource(func(ctx context.Context, t time.Time) (float64, error) {
+		since = t
+		return -320, sourceErr
+	})
+
+	// The recorded P&L since UTC midnight wins over the session's own figures
+	ctx := context.Background()
+	assert.Equal(t, -320.0, risk.DailyPnL(ctx, "session", 100))
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), since)
+
+	// A failing source falls back to the session's P&L since its first order of the day
+	sourceErr = errors.New("database unavailable")
+	assert.Equal(t, 0.0, risk.DailyPnL(ctx, "session", 100))
+	assert.Equal(t, -50.0, risk.DailyPnL(ctx, "session", 50))
+}
+
+func TestService_PreTradeChecks(t *testing.T) {
+	service, err := NewService(nil, ServiceConfig{
+		Mode: TradingModePaper,
+		PreTrade: PreTradeConfig{
+			MaxOrderNotional: 10000,
+			PriceBand:        0.05,
+			MaxOpenOrders:    1,
+		},
+	})
+	require.NoError(t, err)
+	service.exchange.SetMarketPrice("BTCUSDT", 50000.0)
+	ctx := context.Background()
+
+	t.Run("Fat-finger limit price", func(t *testing.T) {
+		_, err := service.PlaceLimitOrder(ctx, map[string]interface{}{
+			"symbol":   "BTCUSDT",
+			"side":     "buy",
+			"quantity": 0.01,
+			"price":    55000.0,
+		})
+		var rejection *PreTradeError
+		require.True(t, errors.As(err, &rejection))
+		assert.Equal(t, CheckPriceBand, rejection.Rejection.Check)
+	})
+
+	t.Run("Market order notional", func(t *testing.T) {
+		_, err := service.PlaceMarketOrder(ctx, map[string]interface{}{
+			"symbol":   "BTCUSDT",
+			"side":     "buy",
+			"quantity": 1.0,
+		})
+		var rejection *PreTradeError
+		require.True(t, errors.As(err, &rejection))
+		assert.Equal(t, CheckMaxNotional, rejection.Rejection.Check)
+	})
+
+	t.Run("Open orders", func(t *testing.T) {
+		args := map[string]interface{}{
+			"symbol":   "BTCUSDT",
+			"side":     "buy",
+			"quantity": 0.01,
+			"price":    49000.0,
+		}
+		result, err := service.PlaceLimitOrder(ctx, args)
+		require.NoError(t, err)
+		resting := result.(*Order)
+
+		_, err = service.PlaceLimitOrder(ctx, args)
+		var rejection *PreTradeError
+		require.True(t, errors.As(err, &rejection))
+		assert.Equal(t, CheckMaxOpenOrders, rejection.Rejection.Check)
+
+		// Amendments pass the same checks; the amended order is already open
+		_, err = service.AmendOrder(ctx, map[string]interface{}{"order_id": resting.ID, "quantity": 1.0})
+		require.True(t, errors.As(err, &rejection))
+		assert.Equal(t, CheckMaxNotional, rejection.Rejection.Check)
+
+		_, err = service.AmendOrder(ctx, map[string]interface{}{"order_id": resting.ID, "price": 45000.0})
+		require.True(t, errors.As(err, &rejection))
+		assert.Equal(t, CheckPriceBand, rejection.Rejection.Check)
+
+		_, err = service.AmendOrder(ctx, map[string]interface{}{"order_id": resting.ID, "quantity": 0.02})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Algo parent order", func(t *testing.T) {
+		_, err := service.PlaceAlgoOrder(ctx, map[string]interface{}{
+			"symbol":   "BTCUSDT",
+			"side":     "sell",
+			"quantity": 1.0,
+			"algo":     "twap",
+		})
+		var rejection *PreTradeError
+		require.True(t, errors.As(err, &rejection))
+		assert.Equal(t, CheckMaxNotional, rejection.Rejection.Check)
+	})
+}
+
+func TestService_AmendOrder_RefusedWhilePaused(t *testing.T) {
+	service := NewServicePaper(nil)
+	session := openTestSession(t, service, "BTCUSDT")
+	ctx := context.Background()
+
+	result, err := service.PlaceLimitOrder(ctx, map[string]interface{}{
+		"symbol":   "BTCUSDT",
+		"side":     "buy",
+		"quantity": 0.01,
+		"price":    49000.0,
+	})
+	require.NoError(t, err)
+
+	session.setPaused(true)
+	_, err = service.AmendOrder(ctx, map[string]interface{}{"order_id": result.(*Order).ID, "quantity": 0.02})
+	assert.ErrorContains(t, err, "is paused")
+}
@@ -0,0 +1,462 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/metrics"
+)
+
+// ErrRateBudgetExhausted is returned when a low-priority request is shed because
+// the local rate budget is close to the venue's limits. It is not retried.
+var ErrRateBudgetExhausted = errors.New("exchange rate budget exhausted")
+
+// RequestPriority decides what happens to a request when the rate budget runs low
+type RequestPriority int
+
+const (
+	// PriorityLow requests (market data, status polling) are shed first
+	PriorityLow RequestPriority = iota
+	// PriorityHigh requests (new orders, user stream keepalives) wait for the next window
+	PriorityHigh
+	// PriorityCritical requests (cancels, kill switch) may use the whole budget
+	PriorityCritical
+)
+
+// String returns the priority name
+func (p RequestPriority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// Endpoint classes used to label rate budget metrics
+const (
+	EndpointClassOrder      = "order"
+	EndpointClassAccount    = "account"
+	EndpointClassMarketData = "market_data"
+	EndpointClassUserStream = "user_stream"
+)
+
+type requestPriorityKey struct{}
+
+// WithRequestPriority overrides the default priority of exchange calls made with ctx
+func WithRequestPriority(ctx context.Context, priority RequestPriority) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, priority)
+}
+
+// requestPriority returns the priority set on ctx, or fallback
+func requestPriority(ctx context.Context, fallback RequestPriority) RequestPriority {
+	if priority, ok := ctx.Value(requestPriorityKey{}).(RequestPriority); ok {
+		return priority
+	}
+	return fallback
+}
+
+// RequestCost is what one REST call consumes from the rate budget
+type RequestCost struct {
+	Class    string
+	Weight   int
+	Order    bool            // Counts towards the order rate limits
+	Priority RequestPriority // Default priority, overridable with WithRequestPriority
+}
+
+// RateLimits are a venue's limits and the local thresholds applied to them
+type RateLimits struct {
+	RequestWeight1m int // Request weight per minute
+	Orders10s       int // Orders per 10 seconds
+	Orders1m        int // Orders per minute (0 = no limit)
+	Orders1d        int // Orders per day (0 = no limit)
+
+	// ShedAt is the fraction of a limit above which low-priority requests are shed
+	ShedAt float64
+	// DelayAt is the fraction of a limit above which high-priority requests wait
+	// for the window to reset. Critical requests wait only at the limit itself.
+	DelayAt float64
+}
+
+// DefaultSpotRateLimits returns Binance spot limits
+func DefaultSpotRateLimits() RateLimits {
+	return RateLimits{
+		RequestWeight1m: 6000,
+		Orders10s:       100,
+		Orders1d:        200000,
+		ShedAt:          0.7,
+		DelayAt:         0.9,
+	}
+}
+
+// DefaultFuturesRateLimits returns Binance USD-M futures limits
+func DefaultFuturesRateLimits() RateLimits {
+	return RateLimits{
+		RequestWeight1m: 2400,
+		Orders10s:       300,
+		Orders1m:        1200,
+		ShedAt:          0.7,
+		DelayAt:         0.9,
+	}
+}
+
+// RateBudgetUsage is the state of one limit window
+type RateBudgetUsage struct {
+	Limit    string    `json:"limit"`
+	Used     int       `json:"used"`
+	Max      int       `json:"max"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+// rateWindow counts usage in a fixed window aligned to the clock, as the venue does
+type rateWindow struct {
+	name   string // Metric label
+	header string // Response header carrying the venue's count
+	size   time.Duration
+	limit  int
+	start  time.Time
+	used   int
+}
+
+// roll resets the window once the clock has moved past it
+func (w *rateWindow) roll(now time.Time) {
+	if start := now.Truncate(w.size); start.After(w.start) {
+		w.start = start
+		w.used = 0
+	}
+}
+
+// fits reports whether n more units stay within fraction of the limit
+func (w *rateWindow) fits(n int, fraction float64) bool {
+	return float64(w.used+n) <= fraction*float64(w.limit)
+}
+
+// RateBudget tracks request weight and order counts against a venue's limits.
+// Usage is estimated locally as requests are sent and corrected from the
+// venue's usage headers on every response, so other clients sharing the IP
+// or API key are accounted for.
+type RateBudget struct {
+	exchange string
+	limits   RateLimits
+
+	mu           sync.Mutex
+	weight       *rateWindow
+	orders       []*rateWindow
+	blockedUntil time.Time // Set by 429/418 responses
+
+	// Clock, replaceable in tests
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+}
+
+// NewRateBudget creates a rate budget. Zero thresholds use the defaults.
+func NewRateBudget(exchange string, limits RateLimits) *RateBudget {
+	if limits.ShedAt <= 0 {
+		limits.ShedAt = 0.7
+	}
+	if limits.DelayAt <= 0 {
+		limits.DelayAt = 0.9
+	}
+
+	r := &RateBudget{
+		exchange: exchange,
+		limits:   limits,
+		weight:   &rateWindow{name: "request_weight_1m", header: "X-Mbx-Used-Weight-1m", size: time.Minute, limit: limits.RequestWeight1m},
+		now:      time.Now,
+		after:    time.After,
+	}
+	for _, w := range []*rateWindow{
+		{name: "orders_10s", header: "X-Mbx-Order-Count-10s", size: 10 * time.Second, limit: limits.Orders10s},
+		{name: "orders_1m", header: "X-Mbx-Order-Count-1m", size: time.Minute, limit: limits.Orders1m},
+		{name: "orders_1d", header: "X-Mbx-Order-Count-1d", size: 24 * time.Hour, limit: limits.Orders1d},
+	} {
+		if w.limit > 0 {
+			r.orders = append(r.orders, w)
+		}
+	}
+	return r
+}
+
+// Acquire reserves budget for a request. Low-priority requests are shed with
+// ErrRateBudgetExhausted once usage passes ShedAt; others wait for the window
+// to reset or for ctx to be done.
+func (r *RateBudget) Acquire(ctx context.Context, cost RequestCost, priority RequestPriority) error {
+	start := r.now()
+	delayed := false
+
+	for {
+		wait, ok := r.reserve(cost, priority)
+		if ok {
+			decision := metrics.RateBudgetAllowed
+			if delayed {
+				decision = metrics.RateBudgetDelayed
+			}
+			metrics.RecordRateBudgetDecision(r.exchange, cost.Class, decision, float64(r.now().Sub(start).Milliseconds()))
+			return nil
+		}
+
+		if priority == PriorityLow {
+			metrics.RecordRateBudgetDecision(r.exchange, cost.Class, metrics.RateBudgetShed, 0)
+			return fmt.Errorf("%w: shed %s request (weight %d)", ErrRateBudgetExhausted, cost.Class, cost.Weight)
+		}
+
+		if !delayed {
+			log.Warn().
+				Str("exchange", r.exchange).
+				Str("class", cost.Class).
+				Str("priority", priority.String()).
+				Dur("wait", wait).
+				Msg("Rate budget low, delaying request")
+		}
+		delayed = true
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.after(wait):
+		}
+	}
+}
+
+// reserve takes budget for the request if it fits under the priority's threshold,
+// otherwise returns how long until the blocking window resets
+func (r *RateBudget) reserve(cost RequestCost, priority RequestPriority) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.rollLocked(now)
+
+	if now.Before(r.blockedUntil) {
+		return r.blockedUntil.Sub(now), false
+	}
+
+	fraction := 1.0
+	switch priority {
+	case PriorityLow:
+		fraction = r.limits.ShedAt
+	case PriorityHigh:
+		fraction = r.limits.DelayAt
+	}
+
+	var wait time.Duration
+	blocked := func(w *rateWindow, n int) {
+		if w.limit > 0 && !w.fits(n, fraction) {
+			if reset := w.start.Add(w.size).Sub(now); reset > wait {
+				wait = reset
+			}
+		}
+	}
+	blocked(r.weight, cost.Weight)
+	if cost.Order {
+		for _, w := range r.orders {
+			blocked(w, 1)
+		}
+	}
+	if wait > 0 {
+		return wait, false
+	}
+
+	r.weight.used += cost.Weight
+	if cost.Order {
+		for _, w := range r.orders {
+			w.used++
+		}
+	}
+	r.publishLocked()
+	return 0, true
+}
+
+// Observe updates usage from a venue response. The venue's counts replace the
+// local estimate when higher; 429 and 418 responses block all requests until
+// Retry-After has passed.
+func (r *RateBudget) Observe(status int, header http.Header) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.rollLocked(now)
+
+	for _, w := range append([]*rateWindow{r.weight}, r.orders...) {
+		if used, err := strconv.Atoi(header.Get(w.header)); err == nil && used > w.used {
+			w.used = used
+		}
+	}
+
+	if status == http.StatusTooManyRequests || status == http.StatusTeapot {
+		until := r.weight.start.Add(r.weight.size)
+		if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+			until = now.Add(time.Duration(seconds) * time.Second)
+		}
+		if until.After(r.blockedUntil) {
+			r.blockedUntil = until
+		}
+		log.Error().
+			Str("exchange", r.exchange).
+			Int("status", status).
+			Time("blocked_until", r.blockedUntil).
+			Msg("Exchange rate limit hit, blocking requests")
+	}
+
+	r.publishLocked()
+}
+
+// Usage returns the current state of each limit window
+func (r *RateBudget) Usage() []RateBudgetUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rollLocked(r.now())
+	usage := make([]RateBudgetUsage, 0, len(r.orders)+1)
+	for _, w := range append([]*rateWindow{r.weight}, r.orders...) {
+		usage = append(usage, RateBudgetUsage{
+			Limit:    w.name,
+			Used:     w.used,
+			Max:      w.limit,
+			ResetsAt: w.start.Add(w.size),
+		})
+	}
+	return usage
+}
+
+func (r *RateBudget) rollLocked(now time.Time) {
+	r.weight.roll(now)
+	for _, w := range r.orders {
+		w.roll(now)
+	}
+}
+
+func (r *RateBudget) publishLocked() {
+	for _, w := range append([]*rateWindow{r.weight}, r.orders...) {
+		metrics.UpdateExchangeRateLimit(r.exchange, w.name, float64(w.used), float64(w.limit))
+	}
+}
+
+// HTTPClient returns a copy of base whose requests go through the budget, with
+// costs looked up per request
+func (r *RateBudget) HTTPClient(base *http.Client, costs func(*http.Request) RequestCost) *http.Client {
+	if base == nil {
+		base = http.DefaultClient
+	}
+	transport := base.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	client := *base
+	client.Transport = &rateBudgetTransport{budget: r, costs: costs, base: transport}
+	return &client
+}
+
+// rateBudgetTransport acquires budget before each request and observes the response
+type rateBudgetTransport struct {
+	budget *RateBudget
+	costs  func(*http.Request) RequestCost
+	base   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rateBudgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cost := t.costs(req)
+	if err := t.budget.Acquire(req.Context(), cost, requestPriority(req.Context(), cost.Priority)); err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.budget.Observe(resp.StatusCode, resp.Header)
+	return resp, nil
+}
+
+// binanceSpotRequestCost returns the weight and class of a Binance spot REST call
+func binanceSpotRequestCost(req *http.Request) RequestCost {
+	hasSymbol := req.URL.Query().Get("symbol") != ""
+
+	switch path := req.URL.Path; {
+	case path == "/api/v3/order" || path == "/api/v3/openOrders":
+		switch req.Method {
+		case http.MethodPost:
+			return RequestCost{Class: EndpointClassOrder, Weight: 1, Order: true, Priority: PriorityHigh}
+		case http.MethodDelete:
+			return RequestCost{Class: EndpointClassOrder, Weight: 1, Priority: PriorityCritical}
+		}
+		if path == "/api/v3/order" {
+			return RequestCost{Class: EndpointClassAccount, Weight: 4, Priority: PriorityLow}
+		}
+		if hasSymbol {
+			return RequestCost{Class: EndpointClassAccount, Weight: 6, Priority: PriorityLow}
+		}
+		return RequestCost{Class: EndpointClassAccount, Weight: 80, Priority: PriorityLow}
+	case path == "/api/v3/order/cancelReplace":
+		return RequestCost{Class: EndpointClassOrder, Weight: 1, Order: true, Priority: PriorityHigh}
+	case path == "/api/v3/account" || path == "/api/v3/myTrades" || path == "/api/v3/allOrders":
+		return RequestCost{Class: EndpointClassAccount, Weight: 20, Priority: PriorityLow}
+	case path == "/api/v3/userDataStream":
+		return RequestCost{Class: EndpointClassUserStream, Weight: 2, Priority: PriorityHigh}
+	case path == "/api/v3/exchangeInfo":
+		return RequestCost{Class: EndpointClassMarketData, Weight: 20, Priority: PriorityLow}
+	case strings.HasPrefix(path, "/api/v3/ticker"):
+		if hasSymbol {
+			return RequestCost{Class: EndpointClassMarketData, Weight: 2, Priority: PriorityLow}
+		}
+		return RequestCost{Class: EndpointClassMarketData, Weight: 80, Priority: PriorityLow}
+	default:
+		return RequestCost{Class: EndpointClassMarketData, Weight: 5, Priority: PriorityLow}
+	}
+}
+
+// binanceFuturesRequestCost returns the weight and class of a Binance USD-M futures REST call
+func binanceFuturesRequestCost(req *http.Request) RequestCost {
+	hasSymbol := req.URL.Query().Get("symbol") != ""
+	path := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, "/fapi/v1"), "/fapi/v2")
+	path = strings.TrimPrefix(path, "/fapi/v3")
+
+	switch path {
+	case "/order", "/batchOrders", "/allOpenOrders", "/openOrders":
+		switch req.Method {
+		case http.MethodPost, http.MethodPut:
+			return RequestCost{Class: EndpointClassOrder, Weight: 1, Order: true, Priority: PriorityHigh}
+		case http.MethodDelete:
+			return RequestCost{Class: EndpointClassOrder, Weight: 1, Priority: PriorityCritical}
+		}
+		if path == "/openOrders" && !hasSymbol {
+			return RequestCost{Class: EndpointClassAccount, Weight: 40, Priority: PriorityLow}
+		}
+		return RequestCost{Class: EndpointClassAccount, Weight: 1, Priority: PriorityLow}
+	case "/leverage", "/marginType", "/positionSide/dual":
+		if req.Method == http.MethodPost {
+			return RequestCost{Class: EndpointClassOrder, Weight: 1, Priority: PriorityHigh}
+		}
+		return RequestCost{Class: EndpointClassAccount, Weight: 30, Priority: PriorityLow}
+	case "/account", "/balance", "/positionRisk", "/userTrades":
+		return RequestCost{Class: EndpointClassAccount, Weight: 5, Priority: PriorityLow}
+	case "/listenKey":
+		return RequestCost{Class: EndpointClassUserStream, Weight: 1, Priority: PriorityHigh}
+	case "/exchangeInfo":
+		return RequestCost{Class: EndpointClassMarketData, Weight: 1, Priority: PriorityLow}
+	case "/ticker/bookTicker", "/ticker/price":
+		if hasSymbol {
+			return RequestCost{Class: EndpointClassMarketData, Weight: 2, Priority: PriorityLow}
+		}
+		return RequestCost{Class: EndpointClassMarketData, Weight: 5, Priority: PriorityLow}
+	default:
+		return RequestCost{Class: EndpointClassMarketData, Weight: 5, Priority: PriorityLow}
+	}
+}
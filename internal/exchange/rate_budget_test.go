@@ -0,0 +1,170 @@
+package exchange
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRateBudget creates a budget on a fake clock that advances when it waits
+func newTestRateBudget(limits RateLimits) (*RateBudget, *time.Time) {
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	r := NewRateBudget("test", limits)
+	r.now = func() time.Time { return now }
+	r.after = func(d time.Duration) <-chan time.Time {
+		now = now.Add(d)
+		ch := make(chan time.Time, 1)
+		ch <- now
+		return ch
+	}
+	return r, &now
+}
+
+func testRateLimits() RateLimits {
+	return RateLimits{RequestWeight1m: 100, Orders10s: 5, ShedAt: 0.5, DelayAt: 0.8}
+}
+
+func TestRateBudget_PriorityThresholds(t *testing.T) {
+	r, _ := newTestRateBudget(testRateLimits())
+	ctx := context.Background()
+	query := RequestCost{Class: EndpointClassMarketData, Weight: 10}
+
+	// Low priority is shed once usage would pass 50%
+	for i := 0; i < 5; i++ {
+		require.NoError(t, r.Acquire(ctx, query, PriorityLow))
+	}
+	err := r.Acquire(ctx, query, PriorityLow)
+	assert.ErrorIs(t, err, ErrRateBudgetExhausted)
+	assert.False(t, isRetryableError(err))
+
+	// High priority keeps going to 80%, critical to the limit
+	for i := 0; i < 3; i++ {
+		require.NoError(t, r.Acquire(ctx, query, PriorityHigh))
+	}
+	require.NoError(t, r.Acquire(ctx, query, PriorityCritical))
+	require.NoError(t, r.Acquire(ctx, query, PriorityCritical))
+
+	usage := r.Usage()
+	require.Len(t, usage, 2)
+	assert.Equal(t, RateBudgetUsage{
+		Limit:    "request_weight_1m",
+		Used:     100,
+		Max:      100,
+		ResetsAt: time.Date(2026, 1, 5, 12, 1, 0, 0, time.UTC),
+	}, usage[0])
+}
+
+func TestRateBudget_HighPriorityWaitsForWindow(t *testing.T) {
+	r, now := newTestRateBudget(testRateLimits())
+	ctx := context.Background()
+	order := RequestCost{Class: EndpointClassOrder, Weight: 1, Order: true}
+
+	*now = now.Add(3 * time.Second)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, r.Acquire(ctx, order, PriorityHigh))
+	}
+
+	// The fifth order would pass 80% of the 10s order limit, so it waits for the next window
+	require.NoError(t, r.Acquire(ctx, order, PriorityHigh))
+	assert.Equal(t, time.Date(2026, 1, 5, 12, 0, 10, 0, time.UTC), *now)
+	assert.Equal(t, 1, r.Usage()[1].Used)
+
+	// A cancelled context stops the wait
+	for i := 0; i < 3; i++ {
+		require.NoError(t, r.Acquire(ctx, order, PriorityHigh))
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	r.after = func(time.Duration) <-chan time.Time { return nil }
+	assert.ErrorIs(t, r.Acquire(cancelled, order, PriorityHigh), context.Canceled)
+}
+
+func TestRateBudget_ObserveHeaders(t *testing.T) {
+	r, now := newTestRateBudget(testRateLimits())
+	ctx := context.Background()
+
+	// Usage reported by the venue (e.g. other clients on the same IP) replaces a lower local estimate
+	header := http.Header{}
+	header.Set("X-MBX-USED-WEIGHT-1M", "60")
+	header.Set("X-MBX-ORDER-COUNT-10S", "2")
+	r.Observe(http.StatusOK, header)
+	usage := r.Usage()
+	assert.Equal(t, 60, usage[0].Used)
+	assert.Equal(t, 2, usage[1].Used)
+
+	header.Set("X-MBX-USED-WEIGHT-1M", "10")
+	r.Observe(http.StatusOK, header)
+	assert.Equal(t, 60, r.Usage()[0].Used)
+	assert.ErrorIs(t, r.Acquire(ctx, RequestCost{Class: EndpointClassAccount, Weight: 1}, PriorityLow), ErrRateBudgetExhausted)
+
+	// A 429 blocks everything until Retry-After has passed
+	header = http.Header{}
+	header.Set("Retry-After", "30")
+	r.Observe(http.StatusTooManyRequests, header)
+	*now = now.Add(time.Minute) // New weight window, but still inside Retry-After from the new time
+	r.Observe(http.StatusTooManyRequests, header)
+	assert.ErrorIs(t, r.Acquire(ctx, RequestCost{Class: EndpointClassMarketData, Weight: 1}, PriorityLow), ErrRateBudgetExhausted)
+
+	blockedFrom := *now
+	require.NoError(t, r.Acquire(ctx, RequestCost{Class: EndpointClassOrder, Weight: 1}, PriorityCritical))
+	assert.Equal(t, blockedFrom.Add(30*time.Second), *now)
+}
+
+func TestRateBudget_HTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Mbx-Used-Weight-1m", "5990")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	r := NewRateBudget("test", DefaultSpotRateLimits())
+	client := r.HTTPClient(nil, binanceSpotRequestCost)
+	assert.NotSame(t, http.DefaultClient, client)
+	assert.Nil(t, http.DefaultClient.Transport)
+
+	get := func(ctx context.Context, path string) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	require.NoError(t, get(context.Background(), "/api/v3/ticker/bookTicker?symbol=BTCUSDT"))
+	assert.Equal(t, 5990, r.Usage()[0].Used)
+
+	// Market data is shed near the limit, but callers can raise its priority
+	err := get(context.Background(), "/api/v3/ticker/bookTicker?symbol=BTCUSDT")
+	assert.ErrorIs(t, err, ErrRateBudgetExhausted)
+	assert.False(t, isRetryableError(err))
+	require.NoError(t, get(WithRequestPriority(context.Background(), PriorityCritical), "/api/v3/ticker/bookTicker?symbol=BTCUSDT"))
+}
+
+func TestBinanceRequestCost(t *testing.T) {
+	cost := func(costs func(*http.Request) RequestCost, method, target string) RequestCost {
+		return costs(httptest.NewRequest(method, target, nil))
+	}
+
+	assert.Equal(t, RequestCost{Class: EndpointClassOrder, Weight: 1, Order: true, Priority: PriorityHigh},
+		cost(binanceSpotRequestCost, http.MethodPost, "/api/v3/order"))
+	assert.Equal(t, RequestCost{Class: EndpointClassOrder, Weight: 1, Priority: PriorityCritical},
+		cost(binanceSpotRequestCost, http.MethodDelete, "/api/v3/openOrders?symbol=BTCUSDT"))
+	assert.Equal(t, RequestCost{Class: EndpointClassOrder, Weight: 1, Order: true, Priority: PriorityHigh},
+		cost(binanceSpotRequestCost, http.MethodPost, "/api/v3/order/cancelReplace"))
+	assert.Equal(t, 80, cost(binanceSpotRequestCost, http.MethodGet, "/api/v3/openOrders").Weight)
+	assert.Equal(t, EndpointClassUserStream, cost(binanceSpotRequestCost, http.MethodPut, "/api/v3/userDataStream").Class)
+
+	assert.Equal(t, RequestCost{Class: EndpointClassOrder, Weight: 1, Order: true, Priority: PriorityHigh},
+		cost(binanceFuturesRequestCost, http.MethodPost, "/fapi/v1/order"))
+	assert.True(t, cost(binanceFuturesRequestCost, http.MethodPut, "/fapi/v1/order").Order)
+	assert.Equal(t, RequestCost{Class: EndpointClassAccount, Weight: 5, Priority: PriorityLow},
+		cost(binanceFuturesRequestCost, http.MethodGet, "/fapi/v2/positionRisk"))
+	assert.Equal(t, PriorityCritical, cost(binanceFuturesRequestCost, http.MethodDelete, "/fapi/v1/allOpenOrders").Priority)
+}
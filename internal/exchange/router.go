@@ -0,0 +1,869 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/sony/gobreaker"
+
+	"github.com/ajitpratap0/cryptofunk/internal/config"
+)
+
+// smartRouterExchangeName identifies the multi-venue router in logs and persisted metadata
+const smartRouterExchangeName = "smart_router"
+
+// Router health defaults
+const (
+	defaultRouterFailureThreshold = 3                // Consecutive venue failures before it is marked unhealthy
+	defaultRouterOpenTimeout      = 30 * time.Second // How long an unhealthy venue is skipped
+	defaultRouterCountInterval    = 60 * time.Second // Window for counting venue failures
+)
+
+// Quote is the top of the order book for a symbol on one venue
+type Quote struct {
+	Symbol  string    `json:"symbol"`
+	Bid     float64   `json:"bid"`
+	BidSize float64   `json:"bid_size"` // Zero when the depth is unknown
+	Ask     float64   `json:"ask"`
+	AskSize float64   `json:"ask_size"` // Zero when the depth is unknown
+	Time    time.Time `json:"time"`
+}
+
+// QuoteSource is implemented by exchanges that can report top-of-book prices
+type QuoteSource interface {
+	GetQuote(ctx context.Context, symbol string) (*Quote, error)
+}
+
+// Venue is one exchange the smart router can send orders to
+type Venue struct {
+	Name     string
+	Exchange Exchange
+	Fees     config.FeeConfig
+
+	// Margin marks venues whose quote balance is margin collateral (futures):
+	// sells are sized from the quote balance instead of base inventory
+	Margin bool
+}
+
+// RouterConfig controls venue health tracking
+type RouterConfig struct {
+	FailureThreshold uint32        // Consecutive failures that mark a venue unhealthy
+	OpenTimeout      time.Duration // How long an unhealthy venue is skipped before it is retried
+}
+
+// DefaultRouterConfig returns the default venue health settings
+func DefaultRouterConfig() RouterConfig {
+	return RouterConfig{
+		FailureThreshold: defaultRouterFailureThreshold,
+		OpenTimeout:      defaultRouterOpenTimeout,
+	}
+}
+
+// ChildOrder is the part of a routed order sent to one venue
+type ChildOrder struct {
+	Venue    string  `json:"venue"`
+	OrderID  string  `json:"order_id"`
+	Quantity float64 `json:"quantity"`
+}
+
+// VenueHealth reports whether a venue is currently eligible for routing
+type VenueHealth struct {
+	Venue   string `json:"venue"`
+	State   string `json:"state"` // closed, half-open or open (circuit breaker state)
+	Healthy bool   `json:"healthy"`
+}
+
+// SmartRouter implements Exchange over several venues. Each order is routed to the
+// venue with the best fee-adjusted top-of-book price and split across venues when
+// book depth or balances on a single venue are insufficient. Venues that keep
+// failing are skipped until their circuit breaker closes again.
+type SmartRouter struct {
+	venues   []*routerVenue
+	byName   map[string]*routerVenue
+	mu       sync.RWMutex
+	parents  map[string]*routedOrder // Parent order ID -> routed order
+	children map[string]string       // Child order ID -> venue name
+
+	currentSessionID *uuid.UUID
+}
+
+// routerVenue is a venue with its health breaker
+type routerVenue struct {
+	Venue
+	breaker *gobreaker.CircuitBreaker
+}
+
+// routedOrder tracks a parent order and its per-venue children
+type routedOrder struct {
+	order    *Order
+	children []ChildOrder
+}
+
+// venueCandidate is a venue ranked for one order
+type venueCandidate struct {
+	venue     *routerVenue
+	price     float64 // Fee-adjusted price; zero when the venue has no quote
+	depth     float64 // Top-of-book size on the relevant side (zero when unknown)
+	capacity  float64 // Quantity the venue balance can cover
+	allocated float64
+}
+
+// NewSmartRouter creates a router over the given venues (at least one)
+func NewSmartRouter(venues []Venue, cfg RouterConfig) (*SmartRouter, error) {
+	if len(venues) == 0 {
+		return nil, fmt.Errorf("smart router requires at least one venue")
+	}
+	if cfg.FailureThreshold == 0 {
+		cfg.FailureThreshold = defaultRouterFailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaultRouterOpenTimeout
+	}
+
+	r := &SmartRouter{
+		byName:   make(map[string]*routerVenue),
+		parents:  make(map[string]*routedOrder),
+		children: make(map[string]string),
+	}
+
+	for _, v := range venues {
+		if v.Name == "" || v.Exchange == nil {
+			return nil, fmt.Errorf("venue name and exchange are required")
+		}
+		if _, exists := r.byName[v.Name]; exists {
+			return nil, fmt.Errorf("duplicate venue %s", v.Name)
+		}
+
+		threshold := cfg.FailureThreshold
+		venue := &routerVenue{
+			Venue: v,
+			breaker: gobreaker.NewCircuitBreaker(gobreaker.Settings{
+				Name:        "venue_" + v.Name,
+				MaxRequests: 1,
+				Interval:    defaultRouterCountInterval,
+				Timeout:     cfg.OpenTimeout,
+				ReadyToTrip: func(counts gobreaker.Counts) bool {
+					return counts.ConsecutiveFailures >= threshold
+				},
+				OnStateChange: func(name string, from, to gobreaker.State) {
+					log.Warn().
+						Str("venue", name).
+						Str("from", from.String()).
+						Str("to", to.String()).
+						Msg("Venue health changed")
+				},
+			}),
+		}
+		r.venues = append(r.venues, venue)
+		r.byName[v.Name] = venue
+	}
+
+	log.Info().Int("venues", len(r.venues)).Msg("Smart order router initialized")
+	return r, nil
+}
+
+// forSession returns a router for one trading session. Venues whose
+// connection can be shared are forked; venue health is shared as well, since
+// every session trades on the same venues.
+func (r *SmartRouter) forSession() Exchange {
+	forked := &SmartRouter{
+		byName:   make(map[string]*routerVenue),
+		parents:  make(map[string]*routedOrder),
+		children: make(map[string]string),
+	}
+	for _, v := range r.venues {
+		venue := &routerVenue{Venue: v.Venue, breaker: v.breaker}
+		if shared, ok := v.Exchange.(sessionVenue); ok {
+			venue.Exchange = shared.forSession()
+		}
+		forked.venues = append(forked.venues, venue)
+		forked.byName[v.Name] = venue
+	}
+	return forked
+}
+
+// Venues returns the health of every venue in configured order
+func (r *SmartRouter) Venues() []VenueHealth {
+	health := make([]VenueHealth, 0, len(r.venues))
+	for _, v := range r.venues {
+		state := v.breaker.State()
+		health = append(health, VenueHealth{
+			Venue:   v.Name,
+			State:   state.String(),
+			Healthy: state != gobreaker.StateOpen,
+		})
+	}
+	return health
+}
+
+// ChildOrders returns the per-venue child orders of a routed order
+func (r *SmartRouter) ChildOrders(orderID string) ([]ChildOrder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routed, ok := r.parents[orderID]
+	if !ok {
+		return nil, false
+	}
+	return append([]ChildOrder(nil), routed.children...), true
+}
+
+// PlaceOrder routes an order to one or more venues. The returned order ID refers
+// to the parent order; its fills and status aggregate the child orders.
+func (r *SmartRouter) PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*PlaceOrderResponse, error) {
+	if err := validatePlaceOrderRequest(req); err != nil {
+		return &PlaceOrderResponse{
+			Status:  OrderStatusRejected,
+			Message: err.Error(),
+		}, nil
+	}
+
+	candidates := r.rankVenues(ctx, req)
+	if len(candidates) == 0 {
+		return &PlaceOrderResponse{
+			Status:  OrderStatusRejected,
+			Message: "no healthy venue available",
+		}, nil
+	}
+	if err := allocate(candidates, req.Quantity); err != nil {
+		return &PlaceOrderResponse{
+			Status:  OrderStatusRejected,
+			Message: err.Error(),
+		}, nil
+	}
+
+	var children []ChildOrder
+	var failures []string
+	failed := make(map[string]bool)
+	for _, c := range candidates {
+		if c.allocated <= algoQuantityEpsilon {
+			continue
+		}
+
+		child, err := r.placeWithFallback(ctx, req, c.allocated, c, candidates, failed)
+		if err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+		children = append(children, *child)
+	}
+
+	if len(children) == 0 {
+		return &PlaceOrderResponse{
+			Status:  OrderStatusRejected,
+			Message: fmt.Sprintf("all venues rejected the order: %s", strings.Join(failures, "; ")),
+		}, nil
+	}
+
+	now := time.Now()
+	parent := &Order{
+		ID:            uuid.New().String(),
+		Symbol:        req.Symbol,
+		Side:          req.Side,
+		Type:          req.Type,
+		Price:         req.Price,
+		Status:        OrderStatusOpen,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		ParentOrderID: req.ParentOrderID,
+		TaxLotIDs:     req.TaxLotIDs,
+		ReduceOnly:    req.ReduceOnly,
+		PositionSide:  req.PositionSide,
+	}
+	for _, child := range children {
+		parent.Quantity += child.Quantity
+	}
+	if len(failures) > 0 {
+		parent.RejectReason = fmt.Sprintf("%.8f of %.8f could not be routed: %s",
+			req.Quantity-parent.Quantity, req.Quantity, strings.Join(failures, "; "))
+	}
+
+	r.mu.Lock()
+	r.parents[parent.ID] = &routedOrder{order: parent, children: children}
+	for _, child := range children {
+		r.children[child.OrderID] = child.Venue
+	}
+	r.mu.Unlock()
+
+	order, err := r.GetOrder(ctx, parent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	venues := make([]string, 0, len(children))
+	for _, child := range children {
+		venues = append(venues, child.Venue)
+	}
+	log.Info().
+		Str("order_id", order.ID).
+		Str("symbol", req.Symbol).
+		Str("side", string(req.Side)).
+		Float64("quantity", order.Quantity).
+		Strs("venues", venues).
+		Msg("Order routed")
+
+	return &PlaceOrderResponse{
+		OrderID: order.ID,
+		Status:  order.Status,
+		Message: order.RejectReason,
+	}, nil
+}
+
+// placeWithFallback places one allocation on its venue, moving it to the next
+// ranked venue that has not failed yet if the venue errors or rejects it
+func (r *SmartRouter) placeWithFallback(ctx context.Context, req PlaceOrderRequest, quantity float64,
+	first *venueCandidate, ranked []*venueCandidate, failed map[string]bool) (*ChildOrder, error) {
+	attempts := []*venueCandidate{first}
+	for _, c := range ranked {
+		if c != first {
+			attempts = append(attempts, c)
+		}
+	}
+
+	var reasons []string
+	for _, c := range attempts {
+		if failed[c.venue.Name] {
+			continue
+		}
+
+		childReq := req
+		childReq.Quantity = quantity
+		resp, err := placeOnVenue(ctx, c.venue, childReq)
+		if err != nil {
+			failed[c.venue.Name] = true
+			reasons = append(reasons, fmt.Sprintf("%s: %v", c.venue.Name, err))
+			log.Warn().Err(err).Str("venue", c.venue.Name).Msg("Venue order failed, falling back")
+			continue
+		}
+		if resp.Status == OrderStatusRejected {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", c.venue.Name, resp.Message))
+			log.Warn().Str("venue", c.venue.Name).Str("reason", resp.Message).Msg("Venue rejected order, falling back")
+			continue
+		}
+
+		return &ChildOrder{Venue: c.venue.Name, OrderID: resp.OrderID, Quantity: quantity}, nil
+	}
+
+	return nil, fmt.Errorf("%s", strings.Join(reasons, ", "))
+}
+
+// placeOnVenue places an order through the venue's health breaker. Only order
+// placement errors count against venue health; rejections are returned as responses.
+func placeOnVenue(ctx context.Context, v *routerVenue, req PlaceOrderRequest) (*PlaceOrderResponse, error) {
+	result, err := v.breaker.Execute(func() (interface{}, error) {
+		return v.Exchange.PlaceOrder(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*PlaceOrderResponse), nil
+}
+
+// rankVenues orders healthy venues by fee-adjusted price (best first) and sizes
+// what each one can take. Venues without a quote are kept as a last resort.
+func (r *SmartRouter) rankVenues(ctx context.Context, req PlaceOrderRequest) []*venueCandidate {
+	var quoted, unquoted []*venueCandidate
+	for _, v := range r.venues {
+		if v.breaker.State() == gobreaker.StateOpen {
+			log.Debug().Str("venue", v.Name).Msg("Skipping unhealthy venue")
+			continue
+		}
+
+		c := &venueCandidate{venue: v, capacity: math.Inf(1)}
+		quote := venueQuote(ctx, v, req.Symbol)
+		if quote != nil {
+			c.price, c.depth = effectivePrice(req, quote, v.Fees)
+		}
+
+		refPrice := c.price
+		if req.Type == OrderTypeLimit && req.Price > 0 {
+			refPrice = req.Price
+		}
+		c.capacity = venueCapacity(ctx, v, req, refPrice)
+
+		if c.price > 0 {
+			quoted = append(quoted, c)
+		} else {
+			unquoted = append(unquoted, c)
+		}
+	}
+
+	sort.SliceStable(quoted, func(i, j int) bool {
+		if req.Side == OrderSideBuy {
+			return quoted[i].price < quoted[j].price
+		}
+		return quoted[i].price > quoted[j].price
+	})
+	return append(quoted, unquoted...)
+}
+
+// venueQuote fetches the venue's top of book, or nil if it has none
+func venueQuote(ctx context.Context, v *routerVenue, symbol string) *Quote {
+	source, ok := v.Exchange.(QuoteSource)
+	if !ok {
+		return nil
+	}
+
+	quote, err := source.GetQuote(ctx, symbol)
+	if err != nil {
+		log.Warn().Err(err).Str("venue", v.Name).Str("symbol", symbol).Msg("Failed to get venue quote")
+		return nil
+	}
+	return quote
+}
+
+// effectivePrice returns the fee-adjusted price and top-of-book size on the side
+// the order would trade against. Limit orders are assumed to pay maker fees.
+func effectivePrice(req PlaceOrderRequest, quote *Quote, fees config.FeeConfig) (price, depth float64) {
+	fee := fees.Taker
+	if req.Type == OrderTypeLimit {
+		fee = fees.Maker
+	}
+
+	if req.Side == OrderSideBuy {
+		if quote.Ask <= 0 {
+			return 0, 0
+		}
+		return quote.Ask * (1 + fee), quote.AskSize
+	}
+	if quote.Bid <= 0 {
+		return 0, 0
+	}
+	return quote.Bid * (1 - fee), quote.BidSize
+}
+
+// venueCapacity returns the quantity the venue's free balance can cover at the
+// reference price. Unknown balances or prices do not limit the venue.
+func venueCapacity(ctx context.Context, v *routerVenue, req PlaceOrderRequest, refPrice float64) float64 {
+	if req.ReduceOnly {
+		return math.Inf(1) // Closing a position releases margin rather than consuming it
+	}
+
+	balances, err := v.Exchange.GetBalances(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("venue", v.Name).Msg("Failed to get venue balances")
+		return math.Inf(1)
+	}
+
+	base, quote := v.assets(req.Symbol)
+	free := func(asset string) float64 {
+		for _, b := range balances {
+			if b.Asset == asset {
+				return b.Free
+			}
+		}
+		return 0
+	}
+
+	if req.Side == OrderSideSell && !v.Margin {
+		return math.Max(free(base), 0)
+	}
+	if refPrice <= 0 || quote == "" {
+		return math.Inf(1)
+	}
+	fee := math.Max(v.Fees.Maker, v.Fees.Taker)
+	return math.Max(free(quote), 0) / (refPrice * (1 + fee))
+}
+
+// assets returns the base and quote asset of a symbol on this venue
+func (v *routerVenue) assets(symbol string) (base, quote string) {
+	if inst, ok := v.Exchange.GetInstrument(symbol); ok && inst.BaseAsset != "" && inst.QuoteAsset != "" {
+		return inst.BaseAsset, inst.QuoteAsset
+	}
+	return NewInstrumentRegistry().Assets(symbol)
+}
+
+// allocate splits quantity across ranked venues. The first pass takes what each
+// venue's top of book and balance allow in price order; the second pass places
+// any remainder on the best venues with balance left, walking past top of book.
+func allocate(candidates []*venueCandidate, quantity float64) error {
+	remaining := quantity
+	for _, c := range candidates {
+		if remaining <= algoQuantityEpsilon {
+			break
+		}
+		take := math.Min(remaining, c.capacity)
+		if c.depth > 0 {
+			take = math.Min(take, c.depth)
+		}
+		c.allocated = take
+		remaining -= take
+	}
+
+	for _, c := range candidates {
+		if remaining <= algoQuantityEpsilon {
+			break
+		}
+		take := math.Min(remaining, c.capacity-c.allocated)
+		if take <= 0 {
+			continue
+		}
+		c.allocated += take
+		remaining -= take
+	}
+
+	if remaining > algoQuantityEpsilon {
+		return fmt.Errorf("insufficient balance across venues: %.8f of %.8f unallocated", remaining, quantity)
+	}
+	return nil
+}
+
+// CancelOrder cancels a routed order's open children, or a single child order by its ID
+func (r *SmartRouter) CancelOrder(ctx context.Context, orderID string) (*Order, error) {
+	r.mu.RLock()
+	routed, isParent := r.parents[orderID]
+	var children []ChildOrder
+	if isParent {
+		children = append(children, routed.children...)
+	}
+	r.mu.RUnlock()
+
+	if !isParent {
+		venue, err := r.venueForChild(orderID)
+		if err != nil {
+			return nil, err
+		}
+		return venue.Exchange.CancelOrder(ctx, orderID)
+	}
+
+	var errs []error
+	for _, child := range children {
+		venue := r.byName[child.Venue]
+		order, err := venue.Exchange.GetOrder(ctx, child.OrderID)
+		if err == nil && !isOpenStatus(order.Status) {
+			continue
+		}
+		if _, err := venue.Exchange.CancelOrder(ctx, child.OrderID); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", child.Venue, err))
+		}
+	}
+
+	order, err := r.GetOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return order, fmt.Errorf("failed to cancel child orders: %w", errors.Join(errs...))
+	}
+	return order, nil
+}
+
+// AmendOrder amends a single child order on its venue, or moves the price of every
+// open child of a routed order. The quantity of a split order cannot be amended
+// as a whole; amend its child orders instead.
+func (r *SmartRouter) AmendOrder(ctx context.Context, req AmendOrderRequest) (*Order, error) {
+	r.mu.RLock()
+	routed, isParent := r.parents[req.OrderID]
+	var children []ChildOrder
+	if isParent {
+		children = append(children, routed.children...)
+	}
+	r.mu.RUnlock()
+
+	if !isParent {
+		venue, err := r.venueForChild(req.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		return venue.Exchange.AmendOrder(ctx, req)
+	}
+
+	if req.Quantity > 0 {
+		return nil, fmt.Errorf("cannot amend the quantity of routed order %s; amend its child orders instead", req.OrderID)
+	}
+
+	var errs []error
+	amended := 0
+	for _, child := range children {
+		venue := r.byName[child.Venue]
+		order, err := venue.Exchange.GetOrder(ctx, child.OrderID)
+		if err == nil && !isOpenStatus(order.Status) {
+			continue
+		}
+		if _, err := venue.Exchange.AmendOrder(ctx, AmendOrderRequest{OrderID: child.OrderID, Price: req.Price}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", child.Venue, err))
+			continue
+		}
+		amended++
+	}
+
+	if amended == 0 && len(errs) == 0 {
+		return nil, fmt.Errorf("routed order %s has no open child orders to amend", req.OrderID)
+	}
+
+	order, err := r.GetOrder(ctx, req.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) > 0 {
+		return order, fmt.Errorf("failed to amend child orders: %w", errors.Join(errs...))
+	}
+	return order, nil
+}
+
+// CancelAllOrders cancels open orders on every venue. Venue errors are collected
+// so one failing venue does not leave orders open on the others.
+func (r *SmartRouter) CancelAllOrders(ctx context.Context, symbol string) ([]*Order, error) {
+	var cancelled []*Order
+	var errs []error
+	for _, v := range r.venues {
+		orders, err := v.Exchange.CancelAllOrders(ctx, symbol)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name, err))
+		}
+		cancelled = append(cancelled, orders...)
+	}
+	return cancelled, errors.Join(errs...)
+}
+
+// OpenOrderCount returns the number of resting orders across all venues
+func (r *SmartRouter) OpenOrderCount() int {
+	var count int
+	for _, v := range r.venues {
+		if counter, ok := v.Exchange.(openOrderCounter); ok {
+			count += counter.OpenOrderCount()
+		}
+	}
+	return count
+}
+
+// GetOrder returns a routed order aggregated from its children, or a child order by its ID
+func (r *SmartRouter) GetOrder(ctx context.Context, orderID string) (*Order, error) {
+	r.mu.RLock()
+	routed, isParent := r.parents[orderID]
+	var parent Order
+	var children []ChildOrder
+	if isParent {
+		parent = *routed.order
+		children = append(children, routed.children...)
+	}
+	r.mu.RUnlock()
+
+	if !isParent {
+		venue, err := r.venueForChild(orderID)
+		if err != nil {
+			return nil, err
+		}
+		return venue.Exchange.GetOrder(ctx, orderID)
+	}
+
+	childOrders := make([]*Order, 0, len(children))
+	for _, child := range children {
+		order, err := r.byName[child.Venue].Exchange.GetOrder(ctx, child.OrderID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get child order %s on %s: %w", child.OrderID, child.Venue, err)
+		}
+		childOrders = append(childOrders, order)
+	}
+
+	aggregateChildOrders(&parent, childOrders)
+	return &parent, nil
+}
+
+// aggregateChildOrders sets the parent's fill totals and status from its children
+func aggregateChildOrders(parent *Order, children []*Order) {
+	var filledQty, notional float64
+	var lastFill *time.Time
+	open, rejected, filled := 0, 0, 0
+	for _, child := range children {
+		filledQty += child.FilledQty
+		notional += child.FilledQty * child.AvgFillPrice
+		if child.FilledAt != nil && (lastFill == nil || child.FilledAt.After(*lastFill)) {
+			lastFill = child.FilledAt
+		}
+		if child.UpdatedAt.After(parent.UpdatedAt) {
+			parent.UpdatedAt = child.UpdatedAt
+		}
+
+		switch child.Status {
+		case OrderStatusFilled:
+			filled++
+		case OrderStatusRejected:
+			rejected++
+		case OrderStatusPending, OrderStatusOpen:
+			open++
+		}
+	}
+
+	parent.FilledQty = filledQty
+	parent.AvgFillPrice = 0
+	if filledQty > 0 {
+		parent.AvgFillPrice = notional / filledQty
+	}
+
+	switch {
+	case open > 0:
+		parent.Status = OrderStatusOpen
+	case filled == len(children):
+		parent.Status = OrderStatusFilled
+		parent.FilledAt = lastFill
+	case rejected == len(children):
+		parent.Status = OrderStatusRejected
+	default:
+		parent.Status = OrderStatusCancelled
+	}
+}
+
+// GetOrderFills returns the fills of every child of a routed order, or of a single child order
+func (r *SmartRouter) GetOrderFills(ctx context.Context, orderID string) ([]Fill, error) {
+	children, isParent := r.ChildOrders(orderID)
+	if !isParent {
+		venue, err := r.venueForChild(orderID)
+		if err != nil {
+			return nil, err
+		}
+		return venue.Exchange.GetOrderFills(ctx, orderID)
+	}
+
+	var fills []Fill
+	for _, child := range children {
+		childFills, err := r.byName[child.Venue].Exchange.GetOrderFills(ctx, child.OrderID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get fills for child order %s on %s: %w", child.OrderID, child.Venue, err)
+		}
+		fills = append(fills, childFills...)
+	}
+	sort.SliceStable(fills, func(i, j int) bool { return fills[i].Timestamp.Before(fills[j].Timestamp) })
+	return fills, nil
+}
+
+// venueForChild finds the venue holding an order that was not routed in this
+// process (e.g. loaded from the database) by asking each venue in turn
+func (r *SmartRouter) venueForChild(orderID string) (*routerVenue, error) {
+	r.mu.RLock()
+	name, ok := r.children[orderID]
+	r.mu.RUnlock()
+	if ok {
+		return r.byName[name], nil
+	}
+
+	for _, v := range r.venues {
+		if _, err := v.Exchange.GetOrder(context.Background(), orderID); err == nil {
+			r.mu.Lock()
+			r.children[orderID] = v.Name
+			r.mu.Unlock()
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("order not found: %s", orderID)
+}
+
+// SetMarketPrice forwards a market price to every venue
+func (r *SmartRouter) SetMarketPrice(symbol string, price float64) {
+	for _, v := range r.venues {
+		v.Exchange.SetMarketPrice(symbol, price)
+	}
+}
+
+// SetSession sets the trading session on every venue
+func (r *SmartRouter) SetSession(sessionID *uuid.UUID) {
+	r.mu.Lock()
+	r.currentSessionID = sessionID
+	r.mu.Unlock()
+
+	for _, v := range r.venues {
+		v.Exchange.SetSession(sessionID)
+	}
+}
+
+// GetSession returns the current trading session ID
+func (r *SmartRouter) GetSession() *uuid.UUID {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.currentSessionID
+}
+
+// GetBalances returns balances summed across all venues
+func (r *SmartRouter) GetBalances(ctx context.Context) ([]Balance, error) {
+	account, err := r.GetAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return account.Balances, nil
+}
+
+// GetAccount returns one account whose balances are summed across all venues.
+// Trading is allowed if any venue allows it.
+func (r *SmartRouter) GetAccount(ctx context.Context) (*Account, error) {
+	totals := make(map[string]*Balance)
+	canTrade := false
+	for _, v := range r.venues {
+		account, err := v.Exchange.GetAccount(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account for venue %s: %w", v.Name, err)
+		}
+		canTrade = canTrade || account.CanTrade
+		for _, b := range account.Balances {
+			total, ok := totals[b.Asset]
+			if !ok {
+				total = &Balance{Asset: b.Asset}
+				totals[b.Asset] = total
+			}
+			total.Free += b.Free
+			total.Locked += b.Locked
+		}
+	}
+
+	balances := make([]Balance, 0, len(totals))
+	for _, b := range totals {
+		balances = append(balances, *b)
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Asset < balances[j].Asset })
+
+	return &Account{
+		Exchange:    smartRouterExchangeName,
+		AccountType: "ROUTED",
+		CanTrade:    canTrade,
+		Balances:    balances,
+		UpdatedAt:   time.Now(),
+	}, nil
+}
+
+// GetInstrument returns the trading rules from the first venue that lists the symbol
+func (r *SmartRouter) GetInstrument(symbol string) (*Instrument, bool) {
+	for _, v := range r.venues {
+		if inst, ok := v.Exchange.GetInstrument(symbol); ok {
+			return inst, true
+		}
+	}
+	return nil, false
+}
+
+// StartUserDataStream starts the user data stream on every venue that has one
+func (r *SmartRouter) StartUserDataStream(ctx context.Context) error {
+	for _, v := range r.venues {
+		if stream, ok := v.Exchange.(userDataStreamer); ok {
+			if err := stream.StartUserDataStream(ctx); err != nil {
+				return fmt.Errorf("venue %s: %w", v.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// StopUserDataStream stops the user data stream on every venue that has one
+func (r *SmartRouter) StopUserDataStream(ctx context.Context) error {
+	var errs []error
+	for _, v := range r.venues {
+		if stream, ok := v.Exchange.(userDataStreamer); ok {
+			if err := stream.StopUserDataStream(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("venue %s: %w", v.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// isOpenStatus reports whether an order can still fill or be cancelled
+func isOpenStatus(status OrderStatus) bool {
+	return status == OrderStatusOpen || status == OrderStatusPending
+}
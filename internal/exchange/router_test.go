@@ -0,0 +1,192 @@
+package exchange
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/config"
+)
+
+// failingExchange is a venue whose order placement always errors
+type failingExchange struct {
+	*MockExchange
+	calls int
+}
+
+func (f *failingExchange) PlaceOrder(ctx context.Context, req PlaceOrderRequest) (*PlaceOrderResponse, error) {
+	f.calls++
+	return nil, errors.New("venue unavailable")
+}
+
+func newRouterVenue(t *testing.T, name string, price, usdt float64, fees config.FeeConfig) Venue {
+	t.Helper()
+	mock := NewMockExchangeWithFees(nil, fees)
+	mock.SetMarketPrice("BTCUSDT", price)
+	mock.SetBalance("USDT", usdt)
+	return Venue{Name: name, Exchange: mock, Fees: fees}
+}
+
+func TestSmartRouter_RoutesToBestFeeAdjustedVenue(t *testing.T) {
+	ctx := context.Background()
+	// Cheaper quote but higher fees loses to a slightly higher quote without fees
+	cheap := newRouterVenue(t, "cheap", 50000, 100000, config.FeeConfig{Taker: 0.005})
+	noFee := newRouterVenue(t, "nofee", 50100, 100000, config.FeeConfig{})
+
+	router, err := NewSmartRouter([]Venue{cheap, noFee}, DefaultRouterConfig())
+	require.NoError(t, err)
+
+	resp, err := router.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.1})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusFilled, resp.Status)
+
+	children, ok := router.ChildOrders(resp.OrderID)
+	require.True(t, ok)
+	require.Len(t, children, 1)
+	assert.Equal(t, "nofee", children[0].Venue)
+
+	order, err := router.GetOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.1, order.FilledQty, 1e-9)
+
+	// Child orders can be looked up directly
+	child, err := router.GetOrder(ctx, children[0].OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, children[0].OrderID, child.ID)
+}
+
+func TestSmartRouter_SplitsByBalance(t *testing.T) {
+	ctx := context.Background()
+	best := newRouterVenue(t, "best", 50000, 2000, config.FeeConfig{}) // Covers ~0.04 BTC
+	other := newRouterVenue(t, "other", 50100, 100000, config.FeeConfig{})
+
+	router, err := NewSmartRouter([]Venue{other, best}, DefaultRouterConfig())
+	require.NoError(t, err)
+
+	resp, err := router.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.1})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusFilled, resp.Status)
+
+	children, ok := router.ChildOrders(resp.OrderID)
+	require.True(t, ok)
+	require.Len(t, children, 2)
+	assert.Equal(t, "best", children[0].Venue)
+	assert.InDelta(t, 0.04, children[0].Quantity, 1e-9)
+	assert.Equal(t, "other", children[1].Venue)
+	assert.InDelta(t, 0.06, children[1].Quantity, 1e-9)
+
+	order, err := router.GetOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusFilled, order.Status)
+	assert.InDelta(t, 0.1, order.FilledQty, 1e-9)
+	assert.Greater(t, order.AvgFillPrice, 50000.0)
+	assert.Less(t, order.AvgFillPrice, 50200.0)
+
+	fills, err := router.GetOrderFills(ctx, resp.OrderID)
+	require.NoError(t, err)
+	var filled float64
+	for _, f := range fills {
+		filled += f.Quantity
+	}
+	assert.InDelta(t, 0.1, filled, 1e-9)
+
+	account, err := router.GetAccount(ctx)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.1, account.Balance("BTC").Free, 1e-9)
+}
+
+func TestSmartRouter_SellsUseBaseInventory(t *testing.T) {
+	ctx := context.Background()
+	high := newRouterVenue(t, "high", 50100, 0, config.FeeConfig{})
+	low := newRouterVenue(t, "low", 50000, 0, config.FeeConfig{})
+	low.Exchange.(*MockExchange).SetBalance("BTC", 1)
+
+	router, err := NewSmartRouter([]Venue{high, low}, DefaultRouterConfig())
+	require.NoError(t, err)
+
+	// The better bid has no BTC to sell, so the order goes to the other venue
+	resp, err := router.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 0.5})
+	require.NoError(t, err)
+	children, _ := router.ChildOrders(resp.OrderID)
+	require.Len(t, children, 1)
+	assert.Equal(t, "low", children[0].Venue)
+
+	resp, err = router.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 2})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusRejected, resp.Status)
+	assert.Contains(t, resp.Message, "insufficient balance")
+}
+
+func TestSmartRouter_FallsBackFromUnhealthyVenue(t *testing.T) {
+	ctx := context.Background()
+	broken := &failingExchange{MockExchange: NewMockExchangeWithFees(nil, config.FeeConfig{})}
+	broken.SetMarketPrice("BTCUSDT", 49000)
+	broken.SetBalance("USDT", 100000)
+	healthy := newRouterVenue(t, "healthy", 50000, 100000, config.FeeConfig{})
+
+	router, err := NewSmartRouter([]Venue{
+		{Name: "broken", Exchange: broken},
+		healthy,
+	}, RouterConfig{FailureThreshold: 2})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		resp, err := router.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 0.01})
+		require.NoError(t, err)
+		assert.Equal(t, OrderStatusFilled, resp.Status)
+
+		children, _ := router.ChildOrders(resp.OrderID)
+		require.Len(t, children, 1)
+		assert.Equal(t, "healthy", children[0].Venue)
+	}
+
+	// The broken venue is skipped once its breaker opens
+	assert.Equal(t, 2, broken.calls)
+	health := router.Venues()
+	require.Len(t, health, 2)
+	assert.False(t, health[0].Healthy)
+	assert.True(t, health[1].Healthy)
+}
+
+func TestSmartRouter_CancelOrderCancelsChildren(t *testing.T) {
+	ctx := context.Background()
+	a := newRouterVenue(t, "a", 50000, 1000, config.FeeConfig{})
+	b := newRouterVenue(t, "b", 50000, 100000, config.FeeConfig{})
+
+	router, err := NewSmartRouter([]Venue{a, b}, DefaultRouterConfig())
+	require.NoError(t, err)
+
+	resp, err := router.PlaceOrder(ctx, PlaceOrderRequest{Symbol: "BTCUSDT", Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 0.1, Price: 40000})
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusOpen, resp.Status)
+
+	children, _ := router.ChildOrders(resp.OrderID)
+	require.Len(t, children, 2)
+
+	order, err := router.CancelOrder(ctx, resp.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderStatusCancelled, order.Status)
+
+	for _, child := range children {
+		childOrder, err := router.GetOrder(ctx, child.OrderID)
+		require.NoError(t, err)
+		assert.Equal(t, OrderStatusCancelled, childOrder.Status)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	inf := func() *venueCandidate { return &venueCandidate{capacity: 1e18} }
+
+	// Top of book is filled first, then the best venue takes the remainder
+	first, second := inf(), inf()
+	first.depth, second.depth = 1, 1
+	require.NoError(t, allocate([]*venueCandidate{first, second}, 3))
+	assert.Equal(t, 2.0, first.allocated)
+	assert.Equal(t, 1.0, second.allocated)
+
+	limited := &venueCandidate{capacity: 1}
+	assert.Error(t, allocate([]*venueCandidate{limited}, 2))
+}
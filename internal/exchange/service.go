@@ -3,11 +3,16 @@ package exchange
 import (
 	"context"
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/sony/gobreaker"
 
+	"github.com/ajitpratap0/cryptofunk/internal/audit"
 	"github.com/ajitpratap0/cryptofunk/internal/config"
 	"github.com/ajitpratap0/cryptofunk/internal/db"
 	"github.com/ajitpratap0/cryptofunk/internal/risk"
@@ -21,13 +26,36 @@ const (
 	TradingModeLive  TradingMode = "live"
 )
 
-// Service provides order execution functionality
+// Binance markets a live service can trade
+const (
+	BinanceMarketSpot    = "spot"
+	BinanceMarketFutures = "futures" // USD-M perpetual futures
+)
+
+// Service provides order execution functionality. It runs any number of
+// concurrent trading sessions; tool calls pick one with the "session_id"
+// argument. Without active sessions, calls use the service's own exchange.
 type Service struct {
 	exchange        Exchange // Interface - can be MockExchange or BinanceExchange
+	exchangeName    string
 	db              *db.DB
 	mode            TradingMode
+	config          ServiceConfig
 	positionManager *PositionManager
 	circuitBreaker  *risk.CircuitBreakerManager
+	algoExecutor    *AlgoExecutor
+	volumeSource    VolumeSource
+	preTrade        *PreTradeRisk
+	marginGuard     *MarginGuard // Futures exchanges only
+	audit           *audit.Logger
+
+	sessionsMu sync.RWMutex
+	sessions   map[uuid.UUID]*tradingSession
+
+	// Venue connection shared by live sessions, created on first use
+	liveVenueMu   sync.Mutex
+	liveVenue     Exchange
+	liveVenueName string
 }
 
 // ServiceConfig contains configuration for the exchange service
@@ -36,34 +64,179 @@ type ServiceConfig struct {
 	BinanceAPIKey  string
 	BinanceSecret  string
 	BinanceTestnet bool
+	BinanceMarket  string           // "spot" (default) or "futures"
+	MaxLeverage    float64          // Futures leverage cap; zero allows the exchange maximum
 	Fees           config.FeeConfig // Exchange fee configuration
+	Instruments    []Instrument     // Instrument rule overrides for paper trading (optional)
+
+	// BinanceBaseURL and BinanceWsBaseURL redirect REST and user data stream
+	// traffic, e.g. to a cassette recorder or replay server (optional)
+	BinanceBaseURL   string
+	BinanceWsBaseURL string
+
+	// InitialBalances seeds the paper trading account (asset -> free balance).
+	// Defaults to 10,000 USDT when empty.
+	InitialBalances map[string]float64
+
+	// Venues enables smart order routing when more than one is configured. Each
+	// venue is created like a single exchange from its own credentials and fees;
+	// paper venues split InitialBalances evenly.
+	Venues []VenueConfig
+
+	// PreTrade sets the limits of the pre-trade checks every order must pass
+	PreTrade PreTradeConfig
+
+	// Margin sets the thresholds of the margin guard run for futures exchanges
+	Margin MarginConfig
 }
 
-// NewService creates a new exchange service with specified trading mode
-func NewService(database *db.DB, config ServiceConfig) (*Service, error) {
-	var exchange Exchange
-	var err error
+// capital is the configured trading capital in the quote currency
+func (c ServiceConfig) capital() float64 {
+	if balance := c.InitialBalances[defaultPaperQuoteAsset]; balance > 0 {
+		return balance
+	}
+	return defaultPaperBalance
+}
+
+// VenueConfig configures one venue of the smart order router
+type VenueConfig struct {
+	Name        string
+	APIKey      string
+	Secret      string
+	Testnet     bool
+	Market      string // "spot" (default) or "futures"
+	MaxLeverage float64
+	Fees        config.FeeConfig
+}
+
+// NewExchange creates the exchange implementation for a trading mode and returns it with its name
+func NewExchange(database *db.DB, config ServiceConfig) (Exchange, string, error) {
+	if len(config.Venues) > 1 {
+		return newSmartRouter(database, config)
+	}
 
 	switch config.Mode {
 	case TradingModeLive:
+		if config.BinanceMarket == BinanceMarketFutures {
+			return newBinanceFuturesExchange(database, config)
+		}
+
 		// Create Binance exchange for live trading
 		binanceConfig := BinanceConfig{
 			APIKey:    config.BinanceAPIKey,
 			SecretKey: config.BinanceSecret,
 			Testnet:   config.BinanceTestnet,
+			BaseURL:   config.BinanceBaseURL,
+			WsBaseURL: config.BinanceWsBaseURL,
 		}
-		exchange, err = NewBinanceExchange(binanceConfig, database)
+		binanceExchange, err := NewBinanceExchange(binanceConfig, database)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create Binance exchange: %w", err)
+			return nil, "", fmt.Errorf("failed to create Binance exchange: %w", err)
+		}
+
+		// Load tick/lot size rules up front; PlaceOrder retries lazily if this fails
+		loadCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		if err := binanceExchange.LoadInstruments(loadCtx); err != nil {
+			log.Warn().Err(err).Msg("Failed to load Binance instrument rules")
 		}
+		cancel()
+
 		log.Info().Bool("testnet", config.BinanceTestnet).Msg("Exchange service initialized (LIVE trading)")
+		return binanceExchange, binanceExchangeName, nil
 
 	case TradingModePaper:
 		fallthrough
 	default:
 		// Create mock exchange for paper trading with configured fees
-		exchange = NewMockExchangeWithFees(database, config.Fees)
+		mockExchange := NewMockExchangeWithFees(database, config.Fees)
+		for _, inst := range config.Instruments {
+			mockExchange.SetInstrument(inst)
+		}
+		for asset, amount := range config.InitialBalances {
+			mockExchange.SetBalance(asset, amount)
+		}
 		log.Info().Msg("Exchange service initialized (PAPER trading)")
+		return mockExchange, mockExchangeName, nil
+	}
+}
+
+// newBinanceFuturesExchange creates the USD-M futures exchange for live trading
+func newBinanceFuturesExchange(database *db.DB, config ServiceConfig) (Exchange, string, error) {
+	futuresExchange, err := NewBinanceFuturesExchange(BinanceFuturesConfig{
+		APIKey:      config.BinanceAPIKey,
+		SecretKey:   config.BinanceSecret,
+		Testnet:     config.BinanceTestnet,
+		BaseURL:     config.BinanceBaseURL,
+		WsBaseURL:   config.BinanceWsBaseURL,
+		MaxLeverage: config.MaxLeverage,
+	}, database)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create Binance futures exchange: %w", err)
+	}
+
+	loadCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	if err := futuresExchange.LoadInstruments(loadCtx); err != nil {
+		log.Warn().Err(err).Msg("Failed to load Binance futures instrument rules")
+	}
+	cancel()
+
+	log.Info().
+		Bool("testnet", config.BinanceTestnet).
+		Float64("max_leverage", config.MaxLeverage).
+		Msg("Exchange service initialized (LIVE futures trading)")
+	return futuresExchange, binanceFuturesExchangeName, nil
+}
+
+// newSmartRouter creates an exchange per configured venue and routes orders across them
+func newSmartRouter(database *db.DB, config ServiceConfig) (Exchange, string, error) {
+	venues := make([]Venue, 0, len(config.Venues))
+	for _, vc := range config.Venues {
+		venueConfig := ServiceConfig{
+			Mode:           config.Mode,
+			BinanceAPIKey:  vc.APIKey,
+			BinanceSecret:  vc.Secret,
+			BinanceTestnet: vc.Testnet,
+			BinanceMarket:  vc.Market,
+			MaxLeverage:    vc.MaxLeverage,
+			Fees:           vc.Fees,
+			Instruments:    config.Instruments,
+		}
+		if len(config.InitialBalances) > 0 {
+			venueConfig.InitialBalances = make(map[string]float64, len(config.InitialBalances))
+			for asset, amount := range config.InitialBalances {
+				venueConfig.InitialBalances[asset] = amount / float64(len(config.Venues))
+			}
+		}
+
+		ex, _, err := NewExchange(database, venueConfig)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create venue %s: %w", vc.Name, err)
+		}
+		venues = append(venues, Venue{
+			Name:     vc.Name,
+			Exchange: ex,
+			Fees:     vc.Fees,
+			Margin:   config.Mode == TradingModeLive && vc.Market == BinanceMarketFutures,
+		})
+	}
+
+	router, err := NewSmartRouter(venues, DefaultRouterConfig())
+	if err != nil {
+		return nil, "", err
+	}
+	return router, smartRouterExchangeName, nil
+}
+
+// NewService creates a new exchange service with specified trading mode
+func NewService(database *db.DB, config ServiceConfig) (*Service, error) {
+	exchange, exchangeName, err := NewExchange(database, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Publish instrument rules so other components (e.g. risk agent sizing) can use them
+	if database != nil {
+		persistInstruments(database, exchangeName, exchange)
 	}
 
 	// Create position manager with configured fee rate (average of maker/taker)
@@ -73,15 +246,178 @@ func NewService(database *db.DB, config ServiceConfig) (*Service, error) {
 	// Create circuit breaker manager
 	circuitBreaker := risk.NewCircuitBreakerManager()
 
-	return &Service{
+	// Create execution algorithm engine (volume data comes from stored candlesticks)
+	var volumeSource VolumeSource
+	if database != nil {
+		volumeSource = NewDBVolumeSource(database)
+	}
+	algoExecutor := NewAlgoExecutor(exchange, database, volumeSource)
+	algoExecutor.SetFillHandler(func(ctx context.Context, order *Order, fills []Fill) {
+		if err := positionManager.OnOrderFilled(ctx, order, fills); err != nil {
+			log.Error().Err(err).Str("order_id", order.ID).Msg("Failed to update positions after algo child fill")
+		}
+	})
+
+	// Every order passes the pre-trade checks. Stored candles price symbols the exchange has no
+	// quote for, and the daily loss limit uses the P&L recorded since UTC midnight, as the API does.
+	preTrade := NewPreTradeRisk(config.PreTrade, nil)
+	if database != nil {
+		preTrade.SetPriceSource(database.GetLatestClosePrice)
+		preTrade.SetPnLSource(database.GetPnLSince)
+	}
+
+	service := &Service{
 		exchange:        exchange,
+		exchangeName:    exchangeName,
 		db:              database,
 		mode:            config.Mode,
+		config:          config,
 		positionManager: positionManager,
 		circuitBreaker:  circuitBreaker,
+		algoExecutor:    algoExecutor,
+		volumeSource:    volumeSource,
+		preTrade:        preTrade,
+		sessions:        make(map[uuid.UUID]*tradingSession),
+	}
+
+	// Leveraged futures positions are watched for approaching liquidation
+	if futuresExchange, ok := exchange.(*BinanceFuturesExchange); ok {
+		service.marginGuard = NewMarginGuard(futuresExchange, config.Margin)
+		service.marginGuard.SetOrderPlacer(service.placeMarginOrder)
+	}
+
+	// Publish the starting paper account so sizing sees funds before the first fill
+	if mockExchange, ok := exchange.(*MockExchange); ok && database != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		account, _ := mockExchange.GetAccount(ctx)
+		service.persistAccount(ctx, exchangeName, account)
+		cancel()
+	}
+
+	return service, nil
+}
+
+// persistInstruments stores the exchange's instrument rules in the database (best effort)
+func persistInstruments(database *db.DB, exchangeName string, exchange Exchange) {
+	var instruments []Instrument
+	switch ex := exchange.(type) {
+	case *MockExchange:
+		instruments = ex.instruments.List()
+	case *BinanceExchange:
+		instruments = ex.instruments.List()
+	case *BinanceFuturesExchange:
+		instruments = ex.instruments.List()
+	case *SmartRouter:
+		for _, v := range ex.venues {
+			persistInstruments(database, v.Name, v.Exchange)
+		}
+		return
+	}
+	if len(instruments) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := database.UpsertInstruments(ctx, InstrumentsToDB(exchangeName, instruments)); err != nil {
+		log.Warn().Err(err).Str("exchange", exchangeName).Msg("Failed to persist instrument rules")
+		return
+	}
+	log.Debug().Str("exchange", exchangeName).Int("instruments", len(instruments)).Msg("Instrument rules persisted")
+}
+
+// GetInstrument returns the trading rules for a symbol
+func (s *Service) GetInstrument(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	symbol, ok := args["symbol"].(string)
+	if !ok || symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+
+	inst, found := session.exchange.GetInstrument(symbol)
+	if !found {
+		return nil, fmt.Errorf("no instrument rules for symbol %s", symbol)
+	}
+
+	return inst, nil
+}
+
+// GetAccount returns the exchange account and its balances. If "asset" is given,
+// only that asset's balance is returned. The snapshot is persisted so the API and
+// agents can read current funds.
+func (s *Service) GetAccount(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("GetAccount called")
+
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	cbResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
+		return session.exchange.GetAccount(exchangeCtx)
+	})
+	if err != nil {
+		s.circuitBreaker.Metrics().RecordRequest("exchange", false)
+		if err == gobreaker.ErrOpenState {
+			return nil, fmt.Errorf("exchange circuit breaker is open, system unavailable")
+		}
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	s.circuitBreaker.Metrics().RecordRequest("exchange", true)
+	account := cbResult.(*Account)
+
+	s.persistAccount(ctx, session.exchangeName, account)
+
+	if asset, ok := args["asset"].(string); ok && asset != "" {
+		return balanceResult(account.Balance(strings.ToUpper(asset))), nil
+	}
+
+	balances := make([]map[string]interface{}, 0, len(account.Balances))
+	for _, b := range account.Balances {
+		balances = append(balances, balanceResult(b))
+	}
+
+	return map[string]interface{}{
+		"exchange":     account.Exchange,
+		"account_type": account.AccountType,
+		"can_trade":    account.CanTrade,
+		"balances":     balances,
+		"updated_at":   account.UpdatedAt,
 	}, nil
 }
 
+// persistAccount stores the account balance snapshot in the database (best effort)
+func (s *Service) persistAccount(ctx context.Context, exchangeName string, account *Account) {
+	if s.db == nil {
+		return
+	}
+
+	rows := make([]*db.AccountBalance, 0, len(account.Balances))
+	for _, b := range account.Balances {
+		rows = append(rows, &db.AccountBalance{Exchange: exchangeName, Asset: b.Asset, Free: b.Free, Locked: b.Locked})
+	}
+	if err := s.db.ReplaceAccountBalances(ctx, exchangeName, rows); err != nil {
+		log.Warn().Err(err).Str("exchange", exchangeName).Msg("Failed to persist account balances")
+	}
+}
+
+func balanceResult(b Balance) map[string]interface{} {
+	return map[string]interface{}{
+		"asset":  b.Asset,
+		"free":   b.Free,
+		"locked": b.Locked,
+		"total":  b.Total(),
+	}
+}
+
 // NewServicePaper creates a service in paper trading mode with default fees (for backward compatibility)
 func NewServicePaper(database *db.DB) *Service {
 	// Default Binance-like fees
@@ -103,6 +439,14 @@ func NewServicePaper(database *db.DB) *Service {
 func (s *Service) PlaceMarketOrder(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("PlaceMarketOrder called")
 
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTradable(ctx, session); err != nil {
+		return nil, err
+	}
+
 	// Create context with 30-second timeout for exchange API calls
 	exchangeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
@@ -134,16 +478,22 @@ func (s *Service) PlaceMarketOrder(ctx context.Context, args map[string]interfac
 
 	// Create request
 	req := PlaceOrderRequest{
-		Symbol:   symbol,
-		Side:     side,
-		Type:     OrderTypeMarket,
-		Quantity: quantity,
+		Symbol:    symbol,
+		Side:      side,
+		Type:      OrderTypeMarket,
+		Quantity:  quantity,
+		TaxLotIDs: extractStrings(args, "tax_lot_ids"),
+	}
+	extractFuturesOrderArgs(&req, args)
+
+	if err := s.checkPreTrade(ctx, session, req, "mcp"); err != nil {
+		return nil, err
 	}
 
 	// Place order through circuit breaker
 	var resp *PlaceOrderResponse
 	cbResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
-		return s.exchange.PlaceOrder(exchangeCtx, req)
+		return session.exchange.PlaceOrder(exchangeCtx, req)
 	})
 
 	if err != nil {
@@ -162,7 +512,7 @@ func (s *Service) PlaceMarketOrder(ctx context.Context, args map[string]interfac
 	// Get order details through circuit breaker
 	var order *Order
 	orderResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
-		return s.exchange.GetOrder(exchangeCtx, resp.OrderID)
+		return session.exchange.GetOrder(exchangeCtx, resp.OrderID)
 	})
 
 	if err != nil {
@@ -184,7 +534,7 @@ func (s *Service) PlaceMarketOrder(ctx context.Context, args map[string]interfac
 	if order.Status == OrderStatusFilled {
 		// Get order fills through circuit breaker
 		fillsResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
-			return s.exchange.GetOrderFills(exchangeCtx, order.ID)
+			return session.exchange.GetOrderFills(exchangeCtx, order.ID)
 		})
 
 		if err != nil {
@@ -200,7 +550,7 @@ func (s *Service) PlaceMarketOrder(ctx context.Context, args map[string]interfac
 			s.circuitBreaker.Metrics().RecordRequest("exchange", true)
 			fills := fillsResult.([]Fill)
 			if len(fills) > 0 {
-				if err := s.positionManager.OnOrderFilled(exchangeCtx, order, fills); err != nil {
+				if err := session.positionManager.OnOrderFilled(exchangeCtx, order, fills); err != nil {
 					log.Error().Err(err).Msg("Failed to update positions after order fill")
 				}
 			}
@@ -214,6 +564,14 @@ func (s *Service) PlaceMarketOrder(ctx context.Context, args map[string]interfac
 func (s *Service) PlaceLimitOrder(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("PlaceLimitOrder called")
 
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTradable(ctx, session); err != nil {
+		return nil, err
+	}
+
 	// Create context with 30-second timeout for exchange API calls
 	exchangeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
@@ -254,17 +612,23 @@ func (s *Service) PlaceLimitOrder(ctx context.Context, args map[string]interface
 
 	// Create request
 	req := PlaceOrderRequest{
-		Symbol:   symbol,
-		Side:     side,
-		Type:     OrderTypeLimit,
-		Quantity: quantity,
-		Price:    price,
+		Symbol:    symbol,
+		Side:      side,
+		Type:      OrderTypeLimit,
+		Quantity:  quantity,
+		Price:     price,
+		TaxLotIDs: extractStrings(args, "tax_lot_ids"),
+	}
+	extractFuturesOrderArgs(&req, args)
+
+	if err := s.checkPreTrade(ctx, session, req, "mcp"); err != nil {
+		return nil, err
 	}
 
 	// Place order through circuit breaker
 	var resp *PlaceOrderResponse
 	cbResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
-		return s.exchange.PlaceOrder(exchangeCtx, req)
+		return session.exchange.PlaceOrder(exchangeCtx, req)
 	})
 
 	if err != nil {
@@ -283,7 +647,7 @@ func (s *Service) PlaceLimitOrder(ctx context.Context, args map[string]interface
 	// Get order details through circuit breaker
 	var order *Order
 	orderResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
-		return s.exchange.GetOrder(exchangeCtx, resp.OrderID)
+		return session.exchange.GetOrder(exchangeCtx, resp.OrderID)
 	})
 
 	if err != nil {
@@ -305,7 +669,7 @@ func (s *Service) PlaceLimitOrder(ctx context.Context, args map[string]interface
 	if order.Status == OrderStatusFilled {
 		// Get order fills through circuit breaker
 		fillsResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
-			return s.exchange.GetOrderFills(exchangeCtx, order.ID)
+			return session.exchange.GetOrderFills(exchangeCtx, order.ID)
 		})
 
 		if err != nil {
@@ -321,7 +685,7 @@ func (s *Service) PlaceLimitOrder(ctx context.Context, args map[string]interface
 			s.circuitBreaker.Metrics().RecordRequest("exchange", true)
 			fills := fillsResult.([]Fill)
 			if len(fills) > 0 {
-				if err := s.positionManager.OnOrderFilled(exchangeCtx, order, fills); err != nil {
+				if err := session.positionManager.OnOrderFilled(exchangeCtx, order, fills); err != nil {
 					log.Error().Err(err).Msg("Failed to update positions after order fill")
 				}
 			}
@@ -335,6 +699,11 @@ func (s *Service) PlaceLimitOrder(ctx context.Context, args map[string]interface
 func (s *Service) CancelOrder(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("CancelOrder called")
 
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+
 	exchangeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
@@ -347,7 +716,7 @@ func (s *Service) CancelOrder(ctx context.Context, args map[string]interface{})
 	// Cancel order through circuit breaker
 	var order *Order
 	cbResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
-		return s.exchange.CancelOrder(exchangeCtx, orderID)
+		return session.exchange.CancelOrder(exchangeCtx, orderID)
 	})
 
 	if err != nil {
@@ -366,10 +735,95 @@ func (s *Service) CancelOrder(ctx context.Context, args map[string]interface{})
 	return order, nil
 }
 
+// AmendOrder changes the price and/or quantity of a resting limit order
+func (s *Service) AmendOrder(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("AmendOrder called")
+
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTradable(ctx, session); err != nil {
+		return nil, err
+	}
+
+	exchangeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	orderID, ok := args["order_id"].(string)
+	if !ok || orderID == "" {
+		return nil, fmt.Errorf("order_id is required and must be a string")
+	}
+
+	req := AmendOrderRequest{OrderID: orderID}
+	if _, ok := args["price"]; ok {
+		price, err := extractFloat(args, "price")
+		if err != nil {
+			return nil, err
+		}
+		req.Price = price
+	}
+	if _, ok := args["quantity"]; ok {
+		quantity, err := extractFloat(args, "quantity")
+		if err != nil {
+			return nil, err
+		}
+		req.Quantity = quantity
+	}
+	if req.Price <= 0 && req.Quantity <= 0 {
+		return nil, fmt.Errorf("price or quantity must be a positive number")
+	}
+
+	// The amended order passes the same pre-trade checks as a new one
+	current, err := session.exchange.GetOrder(exchangeCtx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+	amended := PlaceOrderRequest{
+		Symbol:   current.Symbol,
+		Side:     current.Side,
+		Type:     current.Type,
+		Quantity: current.Quantity,
+		Price:    current.Price,
+	}
+	if req.Price > 0 {
+		amended.Price = req.Price
+	}
+	if req.Quantity > 0 {
+		amended.Quantity = req.Quantity
+	}
+	amended.Quantity = math.Max(amended.Quantity-current.FilledQty, 0)
+	if err := s.checkAmendPreTrade(ctx, session, amended, "mcp"); err != nil {
+		return nil, err
+	}
+
+	// Amend order through circuit breaker
+	cbResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
+		return session.exchange.AmendOrder(exchangeCtx, req)
+	})
+
+	if err != nil {
+		if err == gobreaker.ErrOpenState {
+			s.circuitBreaker.Metrics().RecordRequest("exchange", false)
+			return nil, fmt.Errorf("exchange circuit breaker is open, system unavailable")
+		}
+		s.circuitBreaker.Metrics().RecordRequest("exchange", false)
+		return nil, fmt.Errorf("failed to amend order: %w", err)
+	}
+
+	s.circuitBreaker.Metrics().RecordRequest("exchange", true)
+	return cbResult.(*Order), nil
+}
+
 // GetOrderStatus retrieves order status
 func (s *Service) GetOrderStatus(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("GetOrderStatus called")
 
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+
 	exchangeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
@@ -382,7 +836,7 @@ func (s *Service) GetOrderStatus(ctx context.Context, args map[string]interface{
 	// Get order through circuit breaker
 	var order *Order
 	cbResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
-		return s.exchange.GetOrder(exchangeCtx, orderID)
+		return session.exchange.GetOrder(exchangeCtx, orderID)
 	})
 
 	if err != nil {
@@ -401,7 +855,7 @@ func (s *Service) GetOrderStatus(ctx context.Context, args map[string]interface{
 	// Get fills through circuit breaker
 	var fills []Fill
 	fillsResult, err := s.circuitBreaker.Exchange().Execute(func() (interface{}, error) {
-		return s.exchange.GetOrderFills(exchangeCtx, orderID)
+		return session.exchange.GetOrderFills(exchangeCtx, orderID)
 	})
 
 	if err != nil {
@@ -425,6 +879,169 @@ func (s *Service) GetOrderStatus(ctx context.Context, args map[string]interface{
 	}, nil
 }
 
+// PlaceAlgoOrder starts executing a parent order with an execution algorithm
+// (twap, vwap, pov or iceberg). Child orders are placed in the background.
+func (s *Service) PlaceAlgoOrder(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("PlaceAlgoOrder called")
+
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTradable(ctx, session); err != nil {
+		return nil, err
+	}
+
+	// Extract symbol
+	symbol, ok := args["symbol"].(string)
+	if !ok || symbol == "" {
+		return nil, fmt.Errorf("symbol is required and must be a string")
+	}
+
+	// Extract side
+	sideStr, ok := args["side"].(string)
+	if !ok || sideStr == "" {
+		return nil, fmt.Errorf("side is required and must be a string")
+	}
+	side := OrderSide(sideStr)
+	if side != OrderSideBuy && side != OrderSideSell {
+		return nil, fmt.Errorf("side must be 'buy' or 'sell'")
+	}
+
+	// Extract quantity
+	quantity, err := extractFloat(args, "quantity")
+	if err != nil {
+		return nil, fmt.Errorf("quantity error: %w", err)
+	}
+
+	// Extract algorithm
+	algo, ok := args["algo"].(string)
+	if !ok || algo == "" {
+		return nil, fmt.Errorf("algo is required and must be a string")
+	}
+
+	req := AlgoOrderRequest{
+		Symbol:   symbol,
+		Side:     side,
+		Quantity: quantity,
+		Algo:     ExecutionAlgo(algo),
+	}
+
+	// Extract optional parameters
+	if _, ok := args["duration_seconds"]; ok {
+		durationSeconds, err := extractFloat(args, "duration_seconds")
+		if err != nil {
+			return nil, fmt.Errorf("duration_seconds error: %w", err)
+		}
+		req.Duration = time.Duration(durationSeconds * float64(time.Second))
+	}
+	if _, ok := args["slices"]; ok {
+		slices, err := extractFloat(args, "slices")
+		if err != nil {
+			return nil, fmt.Errorf("slices error: %w", err)
+		}
+		req.Slices = int(slices)
+	}
+	if _, ok := args["limit_price"]; ok {
+		if req.LimitPrice, err = extractFloat(args, "limit_price"); err != nil {
+			return nil, fmt.Errorf("limit_price error: %w", err)
+		}
+	}
+	if _, ok := args["participation_rate"]; ok {
+		if req.ParticipationRate, err = extractFloat(args, "participation_rate"); err != nil {
+			return nil, fmt.Errorf("participation_rate error: %w", err)
+		}
+	}
+	if _, ok := args["display_quantity"]; ok {
+		if req.DisplayQuantity, err = extractFloat(args, "display_quantity"); err != nil {
+			return nil, fmt.Errorf("display_quantity error: %w", err)
+		}
+	}
+
+	// The parent order is checked once; its child orders are not checked again
+	parent := PlaceOrderRequest{Symbol: symbol, Side: side, Type: OrderTypeMarket, Quantity: quantity}
+	if req.LimitPrice > 0 {
+		parent.Type, parent.Price = OrderTypeLimit, req.LimitPrice
+	}
+	if err := s.checkPreTrade(ctx, session, parent, "algo"); err != nil {
+		return nil, err
+	}
+
+	algoOrder, err := session.algoExecutor.Submit(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start algo order: %w", err)
+	}
+
+	return algoOrderResult(algoOrder), nil
+}
+
+// GetAlgoOrderStatus retrieves the progress of an algo order and its child orders
+func (s *Service) GetAlgoOrderStatus(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("GetAlgoOrderStatus called")
+
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+
+	algoOrderID, ok := args["algo_order_id"].(string)
+	if !ok || algoOrderID == "" {
+		return nil, fmt.Errorf("algo_order_id is required and must be a string")
+	}
+
+	algoOrder, exists := session.algoExecutor.Get(algoOrderID)
+	if !exists {
+		return nil, fmt.Errorf("algo order not found: %s", algoOrderID)
+	}
+
+	return algoOrderResult(algoOrder), nil
+}
+
+// CancelAlgoOrder stops an algo order and cancels its resting child orders
+func (s *Service) CancelAlgoOrder(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("CancelAlgoOrder called")
+
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+
+	algoOrderID, ok := args["algo_order_id"].(string)
+	if !ok || algoOrderID == "" {
+		return nil, fmt.Errorf("algo_order_id is required and must be a string")
+	}
+
+	exchangeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	algoOrder, err := session.algoExecutor.Cancel(exchangeCtx, algoOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel algo order: %w", err)
+	}
+
+	return algoOrderResult(algoOrder), nil
+}
+
+// algoOrderResult formats an algo order for tool responses
+func algoOrderResult(algoOrder *AlgoOrder) map[string]interface{} {
+	return map[string]interface{}{
+		"algo_order_id":  algoOrder.ID,
+		"algo":           string(algoOrder.Request.Algo),
+		"symbol":         algoOrder.Request.Symbol,
+		"side":           string(algoOrder.Request.Side),
+		"quantity":       algoOrder.Request.Quantity,
+		"status":         string(algoOrder.Status),
+		"filled_qty":     algoOrder.FilledQty,
+		"remaining_qty":  algoOrder.RemainingQty(),
+		"avg_fill_price": algoOrder.AvgFillPrice,
+		"progress":       algoOrder.Progress(),
+		"children":       algoOrder.Children,
+		"started_at":     algoOrder.StartedAt,
+		"completed_at":   algoOrder.CompletedAt,
+		"message":        algoOrder.Message,
+	}
+}
+
 // StartSession starts a new trading session
 func (s *Service) StartSession(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("StartSession called")
@@ -450,12 +1067,55 @@ func (s *Service) StartSession(ctx context.Context, args map[string]interface{})
 		config = configArg
 	}
 
+	// Lot method for matching closing fills (stored with the session config)
+	lotMethodArg, _ := args["lot_method"].(string)
+	if lotMethodArg == "" && config != nil {
+		lotMethodArg, _ = config["lot_method"].(string)
+	}
+	lotMethod, err := ParseLotMethod(lotMethodArg)
+	if err != nil {
+		return nil, err
+	}
+	// Tax-lot matching for realized gain reporting
+	taxLotMethodArg, _ := args["tax_lot_method"].(string)
+	if taxLotMethodArg == "" && config != nil {
+		taxLotMethodArg, _ = config["tax_lot_method"].(string)
+	}
+	taxLotMethod, err := ParseTaxLotMethod(taxLotMethodArg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trading mode of the session, defaulting to the service mode
+	modeArg, _ := args["mode"].(string)
+	mode, err := s.parseTradingMode(modeArg)
+	if err != nil {
+		return nil, err
+	}
+	if s.db == nil {
+		return nil, fmt.Errorf("trading sessions require a database")
+	}
+
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	config["lot_method"] = string(lotMethod)
+	config["tax_lot_method"] = string(taxLotMethod)
+
+	// Each session trades on its own exchange so order books and P&L stay isolated
+	sessionID := uuid.New()
+	ts, err := s.openSession(sessionID, symbol, mode, lotMethod, taxLotMethod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session exchange: %w", err)
+	}
+
 	// Create session in database
 	session := &db.TradingSession{
-		Mode:           db.TradingModePaper,
+		ID:             sessionID,
+		Mode:           sessionRecordMode(mode),
 		Symbol:         symbol,
-		Exchange:       "PAPER",
-		StartedAt:      time.Now(),
+		Exchange:       sessionRecordExchange(mode, ts.exchangeName),
+		StartedAt:      ts.startedAt,
 		InitialCapital: initialCapital,
 		Config:         config,
 	}
@@ -463,19 +1123,24 @@ func (s *Service) StartSession(ctx context.Context, args map[string]interface{})
 	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	if err := s.db.CreateSession(dbCtx, session); err != nil {
+		s.closeSession(dbCtx, ts)
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
 
-	// Set session in exchange
-	s.exchange.SetSession(&session.ID)
-
-	// Set session in position manager
-	s.positionManager.SetSession(&session.ID)
+	// Live sessions receive fills over their own user data stream
+	if stream, ok := ts.exchange.(userDataStreamer); ok && mode == TradingModeLive {
+		if err := stream.StartUserDataStream(context.Background()); err != nil {
+			log.Warn().Err(err).Str("session_id", sessionID.String()).Msg("Failed to start user data stream for session")
+		}
+	}
 
 	log.Info().
 		Str("session_id", session.ID.String()).
 		Str("symbol", symbol).
+		Str("mode", string(mode)).
 		Float64("initial_capital", initialCapital).
+		Str("lot_method", string(lotMethod)).
+		Str("tax_lot_method", string(taxLotMethod)).
 		Msg("Trading session started")
 
 	return map[string]interface{}{
@@ -484,19 +1149,21 @@ func (s *Service) StartSession(ctx context.Context, args map[string]interface{})
 		"exchange":        session.Exchange,
 		"mode":            string(session.Mode),
 		"initial_capital": session.InitialCapital,
+		"lot_method":      string(lotMethod),
+		"tax_lot_method":  string(taxLotMethod),
 		"started_at":      session.StartedAt,
 	}, nil
 }
 
-// StopSession stops the current trading session
+// StopSession stops a trading session
 func (s *Service) StopSession(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("StopSession called")
 
-	// Get current session from exchange
-	sessionID := s.exchange.GetSession()
-	if sessionID == nil {
-		return nil, fmt.Errorf("no active trading session")
+	ts, err := s.activeSession(args)
+	if err != nil {
+		return nil, err
 	}
+	sessionID := ts.id
 
 	// Extract final capital
 	finalCapital, err := extractFloat(args, "final_capital")
@@ -514,11 +1181,8 @@ func (s *Service) StopSession(ctx context.Context, args map[string]interface{})
 		return nil, fmt.Errorf("failed to stop session: %w", err)
 	}
 
-	// Clear session from exchange
-	s.exchange.SetSession(nil)
-
-	// Clear session from position manager
-	s.positionManager.SetSession(nil)
+	// Stop the session's algo orders and release its exchange
+	s.closeSession(dbCtx, ts)
 
 	// Get final session data
 	session, err := s.db.GetSession(dbCtx, *sessionID)
@@ -555,20 +1219,19 @@ func (s *Service) StopSession(ctx context.Context, args map[string]interface{})
 	}, nil
 }
 
-// GetSessionStats retrieves current session statistics
+// GetSessionStats retrieves session statistics
 func (s *Service) GetSessionStats(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("GetSessionStats called")
 
-	// Get current session from exchange
-	sessionID := s.exchange.GetSession()
-	if sessionID == nil {
-		return nil, fmt.Errorf("no active trading session")
+	ts, err := s.activeSession(args)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get session from database
 	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	session, err := s.db.GetSession(dbCtx, *sessionID)
+	session, err := s.db.GetSession(dbCtx, *ts.id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
@@ -586,6 +1249,10 @@ func (s *Service) GetSessionStats(ctx context.Context, args map[string]interface
 		"total_pnl":       session.TotalPnL,
 		"max_drawdown":    session.MaxDrawdown,
 		"sharpe_ratio":    session.SharpeRatio,
+		"paused":          session.Paused,
+		"open_positions":  len(ts.positionManager.GetOpenPositions()),
+		"unrealized_pnl":  ts.positionManager.GetTotalUnrealizedPnL(),
+		"realized_pnl":    ts.positionManager.GetTotalRealizedPnL(),
 	}, nil
 }
 
@@ -593,7 +1260,12 @@ func (s *Service) GetSessionStats(ctx context.Context, args map[string]interface
 func (s *Service) GetPositions(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("GetPositions called")
 
-	positions := s.positionManager.GetOpenPositions()
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := session.positionManager.GetOpenPositions()
 
 	return map[string]interface{}{
 		"positions": positions,
@@ -605,12 +1277,17 @@ func (s *Service) GetPositions(ctx context.Context, args map[string]interface{})
 func (s *Service) GetPositionBySymbol(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("GetPositionBySymbol called")
 
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+
 	symbol, ok := args["symbol"].(string)
 	if !ok || symbol == "" {
 		return nil, fmt.Errorf("symbol is required and must be a string")
 	}
 
-	position, exists := s.positionManager.GetPosition(symbol)
+	position, exists := session.positionManager.GetPosition(symbol)
 	if !exists {
 		return map[string]interface{}{
 			"position": nil,
@@ -618,8 +1295,11 @@ func (s *Service) GetPositionBySymbol(ctx context.Context, args map[string]inter
 		}, nil
 	}
 
+	net, _ := session.positionManager.GetNetPosition(symbol)
+
 	return map[string]interface{}{
 		"position": position,
+		"net":      net,
 		"exists":   true,
 	}, nil
 }
@@ -628,6 +1308,11 @@ func (s *Service) GetPositionBySymbol(ctx context.Context, args map[string]inter
 func (s *Service) UpdatePositionPnL(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("UpdatePositionPnL called")
 
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+
 	// Extract prices map
 	pricesArg, ok := args["prices"].(map[string]interface{})
 	if !ok {
@@ -651,12 +1336,12 @@ func (s *Service) UpdatePositionPnL(ctx context.Context, args map[string]interfa
 
 	dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	err := s.positionManager.UpdateUnrealizedPnL(dbCtx, prices)
+	err = session.positionManager.UpdateUnrealizedPnL(dbCtx, prices)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update P&L: %w", err)
 	}
 
-	totalUnrealizedPnL := s.positionManager.GetTotalUnrealizedPnL()
+	totalUnrealizedPnL := session.positionManager.GetTotalUnrealizedPnL()
 
 	return map[string]interface{}{
 		"total_unrealized_pnl": totalUnrealizedPnL,
@@ -668,6 +1353,11 @@ func (s *Service) UpdatePositionPnL(ctx context.Context, args map[string]interfa
 func (s *Service) ClosePositionBySymbol(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("ClosePositionBySymbol called")
 
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+
 	symbol, ok := args["symbol"].(string)
 	if !ok || symbol == "" {
 		return nil, fmt.Errorf("symbol is required and must be a string")
@@ -683,7 +1373,7 @@ func (s *Service) ClosePositionBySymbol(ctx context.Context, args map[string]int
 		exitReason = "Manual close"
 	}
 
-	position, exists := s.positionManager.GetPosition(symbol)
+	position, exists := session.positionManager.GetPosition(symbol)
 	if !exists {
 		return nil, fmt.Errorf("no open position for symbol: %s", symbol)
 	}
@@ -694,6 +1384,7 @@ func (s *Service) ClosePositionBySymbol(ctx context.Context, args map[string]int
 	if err != nil {
 		return nil, fmt.Errorf("failed to close position: %w", err)
 	}
+	session.positionManager.RemovePosition(symbol)
 
 	log.Info().
 		Str("symbol", symbol).
@@ -707,6 +1398,13 @@ func (s *Service) ClosePositionBySymbol(ctx context.Context, args map[string]int
 	}, nil
 }
 
+// userDataStreamer is implemented by exchanges that push order and account updates
+// over a user data stream (Binance spot and futures)
+type userDataStreamer interface {
+	StartUserDataStream(ctx context.Context) error
+	StopUserDataStream(ctx context.Context) error
+}
+
 // StartWebSocketUpdates starts real-time WebSocket updates (Binance only)
 // This enables real-time order and position updates via WebSocket
 func (s *Service) StartWebSocketUpdates(ctx context.Context) error {
@@ -715,13 +1413,12 @@ func (s *Service) StartWebSocketUpdates(ctx context.Context) error {
 		return nil // Not an error, just not applicable
 	}
 
-	// Type assert to BinanceExchange
-	binanceExchange, ok := s.exchange.(*BinanceExchange)
+	stream, ok := s.exchange.(userDataStreamer)
 	if !ok {
 		return fmt.Errorf("WebSocket updates only supported for Binance exchange")
 	}
 
-	if err := binanceExchange.StartUserDataStream(ctx); err != nil {
+	if err := stream.StartUserDataStream(ctx); err != nil {
 		return fmt.Errorf("failed to start WebSocket updates: %w", err)
 	}
 
@@ -735,13 +1432,12 @@ func (s *Service) StopWebSocketUpdates(ctx context.Context) error {
 		return nil // Not applicable in paper mode
 	}
 
-	// Type assert to BinanceExchange
-	binanceExchange, ok := s.exchange.(*BinanceExchange)
+	stream, ok := s.exchange.(userDataStreamer)
 	if !ok {
 		return fmt.Errorf("WebSocket updates only supported for Binance exchange")
 	}
 
-	if err := binanceExchange.StopUserDataStream(ctx); err != nil {
+	if err := stream.StopUserDataStream(ctx); err != nil {
 		return fmt.Errorf("failed to stop WebSocket updates: %w", err)
 	}
 
@@ -749,6 +1445,32 @@ func (s *Service) StopWebSocketUpdates(ctx context.Context) error {
 	return nil
 }
 
+// extractFuturesOrderArgs copies the optional futures order flags from the args map
+func extractFuturesOrderArgs(req *PlaceOrderRequest, args map[string]interface{}) {
+	if reduceOnly, ok := args["reduce_only"].(bool); ok {
+		req.ReduceOnly = reduceOnly
+	}
+	if positionSide, ok := args["position_side"].(string); ok && positionSide != "" {
+		req.PositionSide = PositionSide(strings.ToUpper(positionSide))
+	}
+}
+
+// extractStrings extracts an optional list of strings from the args map
+func extractStrings(args map[string]interface{}, key string) []string {
+	var values []string
+	switch v := args[key].(type) {
+	case []string:
+		values = v
+	case []interface{}:
+		for _, item := range v {
+			if str, ok := item.(string); ok && str != "" {
+				values = append(values, str)
+			}
+		}
+	}
+	return values
+}
+
 // extractFloat extracts a float64 from the args map
 func extractFloat(args map[string]interface{}, key string) (float64, error) {
 	value, ok := args[key]
@@ -0,0 +1,130 @@
+package exchange
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ajitpratap0/cryptofunk/internal/config"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// ServiceConfigFromConfig maps the application configuration to the exchange
+// service configuration, applying the TRADING_MODE and BINANCE_* environment
+// overrides. Every process that talks to the exchange builds its
+// configuration here so they all trade on the same venues.
+func ServiceConfigFromConfig(cfg *config.Config) (ServiceConfig, error) {
+	mode := os.Getenv("TRADING_MODE")
+	if mode == "" {
+		mode = cfg.Trading.Mode
+	}
+	serviceConfig := ServiceConfig{
+		Mode: TradingMode(mode),
+
+		// Point the adapter at a cassette recorder or replay server (offline testing)
+		BinanceBaseURL:   os.Getenv("BINANCE_BASE_URL"),
+		BinanceWsBaseURL: os.Getenv("BINANCE_WS_BASE_URL"),
+	}
+
+	if binanceCfg, ok := cfg.Exchanges["binance"]; ok {
+		serviceConfig.BinanceAPIKey = binanceCfg.APIKey
+		serviceConfig.BinanceSecret = binanceCfg.SecretKey
+		serviceConfig.BinanceTestnet = binanceCfg.Testnet
+		serviceConfig.BinanceMarket = strings.ToLower(binanceCfg.Market)
+		serviceConfig.MaxLeverage = binanceCfg.MaxLeverage
+		serviceConfig.Fees = binanceCfg.Fees
+
+		for symbol, inst := range binanceCfg.Instruments {
+			serviceConfig.Instruments = append(serviceConfig.Instruments, Instrument{
+				Symbol:            symbol,
+				TickSize:          inst.TickSize,
+				StepSize:          inst.StepSize,
+				MinQty:            inst.MinQty,
+				MaxQty:            inst.MaxQty,
+				MinNotional:       inst.MinNotional,
+				PricePrecision:    inst.PricePrecision,
+				QuantityPrecision: inst.QuantityPrecision,
+			})
+		}
+	}
+
+	// Allow environment variable override for development
+	if envKey := os.Getenv("BINANCE_API_KEY"); envKey != "" {
+		serviceConfig.BinanceAPIKey = envKey
+	}
+	if envSecret := os.Getenv("BINANCE_API_SECRET"); envSecret != "" {
+		serviceConfig.BinanceSecret = envSecret
+	}
+	if os.Getenv("BINANCE_TESTNET") == "true" {
+		serviceConfig.BinanceTestnet = true
+	}
+	if envMarket := os.Getenv("BINANCE_MARKET"); envMarket != "" {
+		serviceConfig.BinanceMarket = strings.ToLower(envMarket)
+	}
+
+	if cfg.Trading.InitialCapital > 0 {
+		serviceConfig.InitialBalances = map[string]float64{defaultPaperQuoteAsset: cfg.Trading.InitialCapital}
+	}
+	if preTrade := cfg.Risk.PreTrade; preTrade.Enabled {
+		serviceConfig.PreTrade = PreTradeConfig{
+			MaxOrderNotional:    preTrade.MaxOrderNotional,
+			MaxPositionNotional: preTrade.MaxPositionNotional,
+			PriceBand:           preTrade.PriceBand,
+			MaxOpenOrders:       preTrade.MaxOpenOrders,
+			RestrictedSymbols:   preTrade.RestrictedSymbols,
+			MaxDailyLoss:        cfg.Risk.MaxDailyLoss,
+		}
+	}
+
+	margin := cfg.Risk.Margin
+	serviceConfig.Margin = MarginConfig{
+		Interval: margin.GetCheckInterval(),
+		Policy: risk.MarginPolicy{
+			Enabled:        margin.Enabled,
+			WarnRatio:      margin.WarnRatio,
+			ActionRatio:    margin.ActionRatio,
+			TargetRatio:    margin.TargetRatio,
+			WarnDistance:   margin.WarnDistance,
+			ActionDistance: margin.ActionDistance,
+			TopUp:          margin.TopUp,
+			MaxTopUp:       margin.MaxTopUp,
+			Reduce:         margin.Reduce,
+			ReduceFraction: margin.ReduceFraction,
+			Cooldown:       margin.GetCooldown(),
+		},
+	}
+	if err := serviceConfig.Margin.Policy.Validate(); err != nil {
+		return ServiceConfig{}, fmt.Errorf("invalid risk.margin configuration: %w", err)
+	}
+
+	// Route orders across venues when more than one exchange is configured
+	if len(cfg.Exchanges) > 1 {
+		names := make([]string, 0, len(cfg.Exchanges))
+		for name := range cfg.Exchanges {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			venueCfg := cfg.Exchanges[name]
+			venue := VenueConfig{
+				Name:        name,
+				APIKey:      venueCfg.APIKey,
+				Secret:      venueCfg.SecretKey,
+				Testnet:     venueCfg.Testnet,
+				Market:      strings.ToLower(venueCfg.Market),
+				MaxLeverage: venueCfg.MaxLeverage,
+				Fees:        venueCfg.Fees,
+			}
+			if name == "binance" {
+				// Keep the environment overrides applied above
+				venue.APIKey, venue.Secret = serviceConfig.BinanceAPIKey, serviceConfig.BinanceSecret
+				venue.Testnet, venue.Market = serviceConfig.BinanceTestnet, serviceConfig.BinanceMarket
+			}
+			serviceConfig.Venues = append(serviceConfig.Venues, venue)
+		}
+	}
+
+	return serviceConfig, nil
+}
@@ -0,0 +1,52 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/config"
+)
+
+func TestServiceConfigFromConfig(t *testing.T) {
+	t.Setenv("TRADING_MODE", "")
+	t.Setenv("BINANCE_API_KEY", "env-key")
+	t.Setenv("BINANCE_API_SECRET", "")
+	t.Setenv("BINANCE_TESTNET", "")
+	t.Setenv("BINANCE_MARKET", "FUTURES")
+	t.Setenv("BINANCE_BASE_URL", "")
+	t.Setenv("BINANCE_WS_BASE_URL", "")
+
+	cfg := &config.Config{
+		Trading: config.TradingConfig{Mode: "live", InitialCapital: 5000},
+		Exchanges: map[string]config.ExchangeConfig{
+			"binance": {
+				APIKey:      "cfg-key",
+				SecretKey:   "cfg-secret",
+				Market:      "spot",
+				MaxLeverage: 3,
+				Fees:        config.FeeConfig{Maker: 0.0002, Taker: 0.0004},
+			},
+			"alt": {APIKey: "alt-key", Market: "Spot"},
+		},
+	}
+
+	serviceConfig, err := ServiceConfigFromConfig(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, TradingModeLive, serviceConfig.Mode)
+	assert.Equal(t, "env-key", serviceConfig.BinanceAPIKey)
+	assert.Equal(t, "cfg-secret", serviceConfig.BinanceSecret)
+	assert.Equal(t, BinanceMarketFutures, serviceConfig.BinanceMarket)
+	assert.Equal(t, 3.0, serviceConfig.MaxLeverage)
+	assert.Equal(t, 0.0004, serviceConfig.Fees.Taker)
+	assert.Equal(t, 5000.0, serviceConfig.InitialBalances["USDT"])
+
+	require.Len(t, serviceConfig.Venues, 2)
+	assert.Equal(t, "alt", serviceConfig.Venues[0].Name)
+	assert.Equal(t, "spot", serviceConfig.Venues[0].Market)
+	assert.Equal(t, "binance", serviceConfig.Venues[1].Name)
+	assert.Equal(t, "env-key", serviceConfig.Venues[1].APIKey)
+	assert.Equal(t, BinanceMarketFutures, serviceConfig.Venues[1].Market)
+}
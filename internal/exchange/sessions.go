@@ -0,0 +1,340 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// tradingSession is an active trading session. Each session has its own
+// exchange, so order books, positions and P&L are isolated from other sessions.
+// Live sessions' exchanges share one venue connection (see sessionVenue).
+type tradingSession struct {
+	id              *uuid.UUID // nil for the service's sessionless context
+	symbol          string
+	mode            TradingMode
+	startedAt       time.Time
+	exchange        Exchange
+	exchangeName    string
+	positionManager *PositionManager
+	algoExecutor    *AlgoExecutor
+
+	mu     sync.RWMutex
+	paused bool
+}
+
+// isPaused reports whether the session rejects new orders
+func (ts *tradingSession) isPaused() bool {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.paused
+}
+
+// setPaused pauses or resumes the session
+func (ts *tradingSession) setPaused(paused bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.paused = paused
+}
+
+// summary formats the session for tool responses
+func (ts *tradingSession) summary() map[string]interface{} {
+	return map[string]interface{}{
+		"session_id":     ts.id.String(),
+		"symbol":         ts.symbol,
+		"mode":           string(ts.mode),
+		"exchange":       ts.exchangeName,
+		"paused":         ts.isPaused(),
+		"started_at":     ts.startedAt,
+		"open_positions": len(ts.positionManager.GetOpenPositions()),
+		"unrealized_pnl": ts.positionManager.GetTotalUnrealizedPnL(),
+		"realized_pnl":   ts.positionManager.GetTotalRealizedPnL(),
+	}
+}
+
+// sessionless returns the service's default trading context, used by tool calls
+// when no session is active
+func (s *Service) sessionless() *tradingSession {
+	return &tradingSession{
+		mode:            s.mode,
+		exchange:        s.exchange,
+		exchangeName:    s.exchangeName,
+		positionManager: s.positionManager,
+		algoExecutor:    s.algoExecutor,
+	}
+}
+
+// session returns the trading session a tool call targets: the one named by
+// session_id, otherwise the only active session, otherwise the sessionless context
+func (s *Service) session(args map[string]interface{}) (*tradingSession, error) {
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+
+	if idArg, ok := args["session_id"].(string); ok && idArg != "" {
+		sessionID, err := uuid.Parse(idArg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session_id: %w", err)
+		}
+		session, exists := s.sessions[sessionID]
+		if !exists {
+			return nil, fmt.Errorf("trading session not found: %s", idArg)
+		}
+		return session, nil
+	}
+
+	switch len(s.sessions) {
+	case 0:
+		return s.sessionless(), nil
+	case 1:
+		for _, session := range s.sessions {
+			return session, nil
+		}
+	}
+	return nil, fmt.Errorf("session_id is required when %d trading sessions are active", len(s.sessions))
+}
+
+// activeSession is like session but fails when no trading session is active
+func (s *Service) activeSession(args map[string]interface{}) (*tradingSession, error) {
+	session, err := s.session(args)
+	if err != nil {
+		return nil, err
+	}
+	if session.id == nil {
+		return nil, fmt.Errorf("no active trading session")
+	}
+	return session, nil
+}
+
+// sessionVenue is implemented by live exchanges whose venue connection can be
+// shared by trading sessions. forSession returns an exchange with its own
+// order and session bookkeeping on the same client and rate budget, so
+// sessions neither split the per-IP request budget nor publish colliding
+// rate metrics.
+type sessionVenue interface {
+	forSession() Exchange
+}
+
+// openSession creates the exchange, position manager and algo engine of a new
+// session and registers it. Paper sessions get their own simulated account.
+func (s *Service) openSession(sessionID uuid.UUID, symbol string, mode TradingMode, lotMethod LotMethod, taxLotMethod TaxLotMethod) (*tradingSession, error) {
+	exchange, exchangeName, err := s.sessionExchange(mode)
+	if err != nil {
+		return nil, err
+	}
+	exchange.SetSession(&sessionID)
+
+	avgFeeRate := (s.config.Fees.Maker + s.config.Fees.Taker) / 2.0
+	positionManager := NewPositionManagerWithFees(s.db, avgFeeRate)
+	positionManager.SetSessionWithLotMethod(&sessionID, lotMethod)
+	positionManager.SetTaxLotMethod(taxLotMethod)
+
+	algoExecutor := NewAlgoExecutor(exchange, s.db, s.volumeSource)
+	algoExecutor.SetFillHandler(func(ctx context.Context, order *Order, fills []Fill) {
+		if err := positionManager.OnOrderFilled(ctx, order, fills); err != nil {
+			log.Error().Err(err).Str("order_id", order.ID).Str("session_id", sessionID.String()).Msg("Failed to update positions after algo child fill")
+		}
+	})
+
+	session := &tradingSession{
+		id:              &sessionID,
+		symbol:          symbol,
+		mode:            mode,
+		startedAt:       time.Now(),
+		exchange:        exchange,
+		exchangeName:    exchangeName,
+		positionManager: positionManager,
+		algoExecutor:    algoExecutor,
+	}
+
+	s.sessionsMu.Lock()
+	s.sessions[sessionID] = session
+	s.sessionsMu.Unlock()
+
+	return session, nil
+}
+
+// sessionExchange creates the exchange of a new session. Live sessions fork
+// the live venue, which is created once and shared by every live session.
+func (s *Service) sessionExchange(mode TradingMode) (Exchange, string, error) {
+	sessionConfig := s.config
+	sessionConfig.Mode = mode
+	if mode != TradingModeLive {
+		return NewExchange(s.db, sessionConfig)
+	}
+
+	s.liveVenueMu.Lock()
+	defer s.liveVenueMu.Unlock()
+
+	if s.liveVenue == nil {
+		if _, ok := s.exchange.(sessionVenue); ok && s.mode == TradingModeLive {
+			s.liveVenue, s.liveVenueName = s.exchange, s.exchangeName
+		} else {
+			venue, name, err := NewExchange(s.db, sessionConfig)
+			if err != nil {
+				return nil, "", err
+			}
+			s.liveVenue, s.liveVenueName = venue, name
+		}
+	}
+
+	shared, ok := s.liveVenue.(sessionVenue)
+	if !ok {
+		return nil, "", fmt.Errorf("exchange %s cannot be shared by live sessions", s.liveVenueName)
+	}
+	return shared.forSession(), s.liveVenueName, nil
+}
+
+// closeSession stops the session's running algo orders and unregisters it
+func (s *Service) closeSession(ctx context.Context, session *tradingSession) {
+	for _, algoOrder := range session.algoExecutor.List() {
+		if algoOrder.Status != AlgoStatusRunning {
+			continue
+		}
+		if _, err := session.algoExecutor.Cancel(ctx, algoOrder.ID); err != nil {
+			log.Warn().Err(err).Str("algo_order_id", algoOrder.ID).Msg("Failed to cancel algo order of stopped session")
+		}
+	}
+
+	if stream, ok := session.exchange.(userDataStreamer); ok && session.mode == TradingModeLive {
+		if err := stream.StopUserDataStream(ctx); err != nil {
+			log.Warn().Err(err).Str("session_id", session.id.String()).Msg("Failed to stop user data stream of stopped session")
+		}
+	}
+
+	session.exchange.SetSession(nil)
+	session.positionManager.SetSession(nil)
+
+	s.sessionsMu.Lock()
+	delete(s.sessions, *session.id)
+	s.sessionsMu.Unlock()
+}
+
+// checkTradable rejects new orders for a paused session. The pause flag is also
+// read from the database so sessions paused through the API stop trading here.
+func (s *Service) checkTradable(ctx context.Context, session *tradingSession) error {
+	if session.id == nil {
+		return nil
+	}
+	if session.isPaused() {
+		return fmt.Errorf("trading session %s is paused", session.id.String())
+	}
+	if s.db == nil {
+		return nil
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	paused, err := s.db.IsSessionPaused(dbCtx, *session.id)
+	if err != nil {
+		log.Warn().Err(err).Str("session_id", session.id.String()).Msg("Failed to read session pause state")
+		return nil
+	}
+	session.setPaused(paused)
+	if paused {
+		return fmt.Errorf("trading session %s is paused", session.id.String())
+	}
+	return nil
+}
+
+// parseTradingMode parses a session mode argument, defaulting to the service mode
+func (s *Service) parseTradingMode(mode string) (TradingMode, error) {
+	switch TradingMode(strings.ToLower(mode)) {
+	case "":
+		if s.mode == TradingModeLive {
+			return TradingModeLive, nil
+		}
+		return TradingModePaper, nil
+	case TradingModePaper:
+		return TradingModePaper, nil
+	case TradingModeLive:
+		return TradingModeLive, nil
+	default:
+		return "", fmt.Errorf("mode must be 'paper' or 'live'")
+	}
+}
+
+// ListSessions returns the trading sessions active in this service
+func (s *Service) ListSessions(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("ListSessions called")
+
+	s.sessionsMu.RLock()
+	sessions := make([]*tradingSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	s.sessionsMu.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].startedAt.Before(sessions[j].startedAt)
+	})
+
+	summaries := make([]map[string]interface{}, 0, len(sessions))
+	for _, session := range sessions {
+		summaries = append(summaries, session.summary())
+	}
+
+	return map[string]interface{}{
+		"sessions": summaries,
+		"count":    len(summaries),
+	}, nil
+}
+
+// PauseSession stops a session from placing new orders. Resting orders,
+// cancels and amendments are unaffected.
+func (s *Service) PauseSession(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("PauseSession called")
+	return s.setSessionPaused(ctx, args, true)
+}
+
+// ResumeSession lets a paused session place orders again
+func (s *Service) ResumeSession(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("ResumeSession called")
+	return s.setSessionPaused(ctx, args, false)
+}
+
+func (s *Service) setSessionPaused(ctx context.Context, args map[string]interface{}, paused bool) (interface{}, error) {
+	session, err := s.activeSession(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.db != nil {
+		dbCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		if err := s.db.SetSessionPaused(dbCtx, *session.id, paused); err != nil {
+			return nil, fmt.Errorf("failed to update session: %w", err)
+		}
+	}
+	session.setPaused(paused)
+
+	log.Info().
+		Str("session_id", session.id.String()).
+		Bool("paused", paused).
+		Msg("Trading session pause state changed")
+
+	return session.summary(), nil
+}
+
+// sessionRecordExchange is the exchange stored with a session record
+func sessionRecordExchange(mode TradingMode, exchangeName string) string {
+	if mode == TradingModePaper {
+		return "PAPER"
+	}
+	return strings.ToUpper(exchangeName)
+}
+
+// sessionRecordMode is the database trading mode of a session
+func sessionRecordMode(mode TradingMode) db.TradingMode {
+	if mode == TradingModeLive {
+		return db.TradingModeLive
+	}
+	return db.TradingModePaper
+}
@@ -0,0 +1,201 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openTestSession registers a paper session without a database
+func openTestSession(t *testing.T, service *Service, symbol string) *tradingSession {
+	t.Helper()
+	session, err := service.openSession(uuid.New(), symbol, TradingModePaper, LotMethodAverageCost, TaxLotFIFO)
+	require.NoError(t, err)
+	session.exchange.SetMarketPrice(symbol, 50000.0)
+	return session
+}
+
+func TestService_SessionsAreIsolated(t *testing.T) {
+	service := NewServicePaper(nil)
+	ctx := context.Background()
+
+	first := openTestSession(t, service, "BTCUSDT")
+	second := openTestSession(t, service, "BTCUSDT")
+
+	result, err := service.PlaceMarketOrder(ctx, map[string]interface{}{
+		"session_id": first.id.String(),
+		"symbol":     "BTCUSDT",
+		"side":       "buy",
+		"quantity":   0.1,
+	})
+	require.NoError(t, err)
+	order := result.(*Order)
+	assert.Equal(t, OrderStatusFilled, order.Status)
+
+	// The order and position belong to the first session only
+	_, err = service.GetOrderStatus(ctx, map[string]interface{}{
+		"session_id": second.id.String(),
+		"order_id":   order.ID,
+	})
+	assert.Error(t, err)
+
+	_, err = service.GetOrderStatus(ctx, map[string]interface{}{
+		"session_id": first.id.String(),
+		"order_id":   order.ID,
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, first.positionManager.GetOpenPositions(), 1)
+	assert.Empty(t, second.positionManager.GetOpenPositions())
+	assert.Empty(t, service.positionManager.GetOpenPositions())
+}
+
+func TestService_SessionResolution(t *testing.T) {
+	service := NewServicePaper(nil)
+
+	t.Run("No sessions uses the sessionless context", func(t *testing.T) {
+		session, err := service.session(map[string]interface{}{})
+		require.NoError(t, err)
+		assert.Nil(t, session.id)
+
+		_, err = service.activeSession(map[string]interface{}{})
+		assert.EqualError(t, err, "no active trading session")
+	})
+
+	first := openTestSession(t, service, "BTCUSDT")
+
+	t.Run("Single session is the default", func(t *testing.T) {
+		session, err := service.session(map[string]interface{}{})
+		require.NoError(t, err)
+		assert.Same(t, first, session)
+	})
+
+	second := openTestSession(t, service, "ETHUSDT")
+
+	t.Run("Multiple sessions require session_id", func(t *testing.T) {
+		_, err := service.session(map[string]interface{}{})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "session_id is required")
+
+		session, err := service.session(map[string]interface{}{"session_id": second.id.String()})
+		require.NoError(t, err)
+		assert.Same(t, second, session)
+	})
+
+	t.Run("Unknown or invalid session_id", func(t *testing.T) {
+		_, err := service.session(map[string]interface{}{"session_id": uuid.New().String()})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "not found")
+
+		_, err = service.session(map[string]interface{}{"session_id": "not-a-uuid"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid session_id")
+	})
+
+	t.Run("Closed sessions are unregistered", func(t *testing.T) {
+		service.closeSession(context.Background(), second)
+
+		session, err := service.session(map[string]interface{}{})
+		require.NoError(t, err)
+		assert.Same(t, first, session)
+	})
+}
+
+func TestService_PauseSession(t *testing.T) {
+	service := NewServicePaper(nil)
+	ctx := context.Background()
+	session := openTestSession(t, service, "BTCUSDT")
+	args := map[string]interface{}{
+		"session_id": session.id.String(),
+		"symbol":     "BTCUSDT",
+		"side":       "buy",
+		"quantity":   0.1,
+	}
+
+	result, err := service.PauseSession(ctx, map[string]interface{}{"session_id": session.id.String()})
+	require.NoError(t, err)
+	assert.Equal(t, true, result.(map[string]interface{})["paused"])
+
+	_, err = service.PlaceMarketOrder(ctx, args)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "paused")
+
+	_, err = service.ResumeSession(ctx, map[string]interface{}{"session_id": session.id.String()})
+	require.NoError(t, err)
+
+	_, err = service.PlaceMarketOrder(ctx, args)
+	assert.NoError(t, err)
+}
+
+func TestService_ListSessions(t *testing.T) {
+	service := NewServicePaper(nil)
+
+	result, err := service.ListSessions(context.Background(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.(map[string]interface{})["count"])
+
+	first := openTestSession(t, service, "BTCUSDT")
+	second := openTestSession(t, service, "ETHUSDT")
+
+	result, err = service.ListSessions(context.Background(), map[string]interface{}{})
+	require.NoError(t, err)
+	sessions := result.(map[string]interface{})["sessions"].([]map[string]interface{})
+	require.Len(t, sessions, 2)
+	assert.Equal(t, first.id.String(), sessions[0]["session_id"])
+	assert.Equal(t, second.id.String(), sessions[1]["session_id"])
+	assert.Equal(t, "paper", sessions[0]["mode"])
+}
+
+func TestService_ParseTradingMode(t *testing.T) {
+	paper := NewServicePaper(nil)
+	live := &Service{mode: TradingModeLive}
+
+	mode, err := paper.parseTradingMode("")
+	require.NoError(t, err)
+	assert.Equal(t, TradingModePaper, mode)
+
+	mode, err = live.parseTradingMode("")
+	require.NoError(t, err)
+	assert.Equal(t, TradingModeLive, mode)
+
+	mode, err = live.parseTradingMode("PAPER")
+	require.NoError(t, err)
+	assert.Equal(t, TradingModePaper, mode)
+
+	_, err = paper.parseTradingMode("margin")
+	assert.Error(t, err)
+}
+
+func TestService_LiveSessionsShareVenue(t *testing.T) {
+	venue, err := NewBinanceExchange(BinanceConfig{APIKey: "key", SecretKey: "secret"}, nil)
+	require.NoError(t, err)
+	service := NewServicePaper(nil)
+	service.mode = TradingModeLive
+	service.exchange, service.exchangeName = venue, binanceExchangeName
+
+	first, err := service.openSession(uuid.New(), "BTCUSDT", TradingModeLive, LotMethodAverageCost, TaxLotFIFO)
+	require.NoError(t, err)
+	second, err := service.openSession(uuid.New(), "ETHUSDT", TradingModeLive, LotMethodAverageCost, TaxLotFIFO)
+	require.NoError(t, err)
+
+	// One client and request budget, separate order books and sessions
+	for _, session := range []*tradingSession{first, second} {
+		exchange, ok := session.exchange.(*BinanceExchange)
+		require.True(t, ok)
+		assert.NotSame(t, venue, exchange)
+		assert.Same(t, venue.client, exchange.client)
+		assert.Same(t, venue.RateBudget(), exchange.RateBudget())
+		assert.Equal(t, session.id, exchange.GetSession())
+		assert.Equal(t, binanceExchangeName, session.exchangeName)
+	}
+	assert.NotSame(t, first.exchange, second.exchange)
+	assert.Nil(t, venue.GetSession())
+
+	// Paper sessions keep their own simulated account
+	paper, err := service.openSession(uuid.New(), "BTCUSDT", TradingModePaper, LotMethodAverageCost, TaxLotFIFO)
+	require.NoError(t, err)
+	assert.IsType(t, &MockExchange{}, paper.exchange)
+}
@@ -0,0 +1,304 @@
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// TaxLotMethod selects which tax lots a disposal consumes
+type TaxLotMethod string
+
+const (
+	// TaxLotFIFO disposes of the oldest lots first
+	TaxLotFIFO TaxLotMethod = "fifo"
+	// TaxLotLIFO disposes of the newest lots first
+	TaxLotLIFO TaxLotMethod = "lifo"
+	// TaxLotHIFO disposes of the lots that realize the smallest gain first
+	// (highest cost for longs, lowest entry for shorts)
+	TaxLotHIFO TaxLotMethod = "hifo"
+	// TaxLotSpecificID disposes of the lots named on the order, then falls back to FIFO
+	TaxLotSpecificID TaxLotMethod = "specific_id"
+)
+
+// ParseTaxLotMethod converts a configuration string to a TaxLotMethod.
+// An empty string selects FIFO.
+func ParseTaxLotMethod(s string) (TaxLotMethod, error) {
+	switch TaxLotMethod(strings.ToLower(strings.TrimSpace(s))) {
+	case "", TaxLotFIFO:
+		return TaxLotFIFO, nil
+	case TaxLotLIFO:
+		return TaxLotLIFO, nil
+	case TaxLotHIFO:
+		return TaxLotHIFO, nil
+	case TaxLotSpecificID:
+		return TaxLotSpecificID, nil
+	default:
+		return "", fmt.Errorf("invalid tax lot method %q (must be fifo, lifo, hifo or specific_id)", s)
+	}
+}
+
+// TaxLotStore persists tax lots and realized gains (*db.DB satisfies it)
+type TaxLotStore interface {
+	CreateTaxLot(ctx context.Context, lot *db.TaxLot) error
+	RecordLotDisposal(ctx context.Context, gain *db.RealizedGain, remainingQuantity, remainingFees float64) error
+	GetOpenTaxLots(ctx context.Context, sessionID uuid.UUID) ([]*db.TaxLot, error)
+}
+
+// TaxLotLedger tracks the tax lots of one trading session. Every opening fill
+// creates a lot carrying its share of the fill's fees; closing fills consume
+// lots according to the session's method and record a realized gain per lot.
+type TaxLotLedger struct {
+	mu        sync.Mutex
+	store     TaxLotStore // nil keeps the ledger in memory only
+	sessionID *uuid.UUID
+	method    TaxLotMethod
+	lots      map[string][]*db.TaxLot // symbol -> open lots in acquisition order
+}
+
+// NewTaxLotLedger creates an empty ledger for a session
+func NewTaxLotLedger(store TaxLotStore, sessionID *uuid.UUID, method TaxLotMethod) *TaxLotLedger {
+	if method == "" {
+		method = TaxLotFIFO
+	}
+	return &TaxLotLedger{
+		store:     store,
+		sessionID: sessionID,
+		method:    method,
+		lots:      make(map[string][]*db.TaxLot),
+	}
+}
+
+// Load restores the session's open lots from the store
+func (l *TaxLotLedger) Load(ctx context.Context) error {
+	if l.store == nil || l.sessionID == nil {
+		return nil
+	}
+
+	lots, err := l.store.GetOpenTaxLots(ctx, *l.sessionID)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lots = make(map[string][]*db.TaxLot)
+	for _, lot := range lots {
+		l.lots[lot.Symbol] = append(l.lots[lot.Symbol], lot)
+	}
+	return nil
+}
+
+// Method returns the disposal method
+func (l *TaxLotLedger) Method() TaxLotMethod {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.method
+}
+
+// SetMethod changes the disposal method for subsequent closing fills
+func (l *TaxLotLedger) SetMethod(method TaxLotMethod) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.method = method
+}
+
+// OpenLots returns copies of the open lots for a symbol in acquisition order
+func (l *TaxLotLedger) OpenLots(symbol string) []db.TaxLot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lots := make([]db.TaxLot, 0, len(l.lots[symbol]))
+	for _, lot := range l.lots[symbol] {
+		lots = append(lots, *lot)
+	}
+	return lots
+}
+
+// Apply books a fill. Quantity in the direction opposite to the open lots
+// disposes of them; anything left over opens a new lot. Fees are split pro rata
+// between the disposed and the opened quantity.
+func (l *TaxLotLedger) Apply(ctx context.Context, order *Order, quantity, price, fees float64, at time.Time) ([]*db.RealizedGain, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if quantity <= 0 {
+		return nil, nil
+	}
+
+	side := db.PositionSideLong
+	if order.Side == OrderSideSell {
+		side = db.PositionSideShort
+	}
+
+	var gains []*db.RealizedGain
+	remaining := quantity
+	open := l.lots[order.Symbol]
+
+	if len(open) > 0 && open[0].Side != side {
+		var openQty float64
+		for _, lot := range open {
+			openQty += lot.RemainingQuantity
+		}
+		disposeQty := math.Min(quantity, openQty)
+		disposalFees := fees * disposeQty / quantity
+
+		var err error
+		gains, err = l.dispose(ctx, order, disposeQty, price, disposalFees, at)
+		if err != nil {
+			return gains, err
+		}
+		remaining -= disposeQty
+	}
+
+	if remaining > stepEpsilon {
+		lot := &db.TaxLot{
+			ID:                uuid.New(),
+			SessionID:         l.sessionID,
+			Symbol:            order.Symbol,
+			Side:              side,
+			Quantity:          remaining,
+			RemainingQuantity: remaining,
+			Price:             price,
+			Fees:              fees * remaining / quantity,
+			AcquiredAt:        at,
+		}
+		if order.ID != "" {
+			orderID := order.ID
+			lot.OrderID = &orderID
+		}
+
+		if l.store != nil {
+			if err := l.store.CreateTaxLot(ctx, lot); err != nil {
+				return gains, err
+			}
+		}
+		l.lots[order.Symbol] = append(l.lots[order.Symbol], lot)
+	}
+
+	return gains, nil
+}
+
+// dispose consumes quantity from the open lots in method order
+func (l *TaxLotLedger) dispose(ctx context.Context, order *Order, quantity, price, fees float64, at time.Time) ([]*db.RealizedGain, error) {
+	var gains []*db.RealizedGain
+	left := quantity
+
+	// Drop closed lots afterwards, keeping acquisition order
+	defer func() {
+		open := l.lots[order.Symbol][:0]
+		for _, lot := range l.lots[order.Symbol] {
+			if lot.ClosedAt == nil {
+				open = append(open, lot)
+			}
+		}
+		l.lots[order.Symbol] = open
+	}()
+
+	for _, lot := range l.disposalOrder(order) {
+		if left <= stepEpsilon {
+			break
+		}
+
+		take := math.Min(left, lot.RemainingQuantity)
+		lotFees := lot.Fees * take / lot.RemainingQuantity
+		disposalFees := fees * take / quantity
+
+		// Longs are bought then sold; shorts are sold then bought back
+		var costBasis, proceeds float64
+		if lot.Side == db.PositionSideLong {
+			costBasis = lot.Price*take + lotFees
+			proceeds = price*take - disposalFees
+		} else {
+			costBasis = price*take + disposalFees
+			proceeds = lot.Price*take - lotFees
+		}
+
+		gain := &db.RealizedGain{
+			ID:         uuid.New(),
+			SessionID:  l.sessionID,
+			LotID:      lot.ID,
+			Symbol:     lot.Symbol,
+			Side:       lot.Side,
+			Method:     string(l.method),
+			Quantity:   take,
+			CostBasis:  costBasis,
+			Proceeds:   proceeds,
+			Gain:       proceeds - costBasis,
+			AcquiredAt: lot.AcquiredAt,
+			DisposedAt: at,
+		}
+		if order.ID != "" {
+			orderID := order.ID
+			gain.OrderID = &orderID
+		}
+
+		remainingQty := lot.RemainingQuantity - take
+		if remainingQty <= stepEpsilon {
+			remainingQty = 0
+		}
+		remainingFees := lot.Fees - lotFees
+		if remainingQty == 0 {
+			remainingFees = 0
+		}
+
+		if l.store != nil {
+			if err := l.store.RecordLotDisposal(ctx, gain, remainingQty, remainingFees); err != nil {
+				return gains, err
+			}
+		}
+
+		lot.RemainingQuantity = remainingQty
+		lot.Fees = remainingFees
+		if remainingQty == 0 {
+			lot.ClosedAt = &at
+		}
+		gains = append(gains, gain)
+		left -= take
+	}
+
+	return gains, nil
+}
+
+// disposalOrder returns the open lots in the order the method consumes them
+func (l *TaxLotLedger) disposalOrder(order *Order) []*db.TaxLot {
+	lots := append([]*db.TaxLot(nil), l.lots[order.Symbol]...)
+
+	switch l.method {
+	case TaxLotLIFO:
+		for i, j := 0, len(lots)-1; i < j; i, j = i+1, j-1 {
+			lots[i], lots[j] = lots[j], lots[i]
+		}
+	case TaxLotHIFO:
+		sort.SliceStable(lots, func(i, j int) bool {
+			if lots[i].Side == db.PositionSideShort {
+				return lots[i].Price < lots[j].Price
+			}
+			return lots[i].Price > lots[j].Price
+		})
+	case TaxLotSpecificID:
+		rank := make(map[string]int, len(order.TaxLotIDs))
+		for i, id := range order.TaxLotIDs {
+			rank[id] = i
+		}
+		sort.SliceStable(lots, func(i, j int) bool {
+			ri, iok := rank[lots[i].ID.String()]
+			rj, jok := rank[lots[j].ID.String()]
+			if iok && jok {
+				return ri < rj
+			}
+			return iok && !jok
+		})
+	}
+
+	return lots
+}
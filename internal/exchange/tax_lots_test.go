@@ -0,0 +1,196 @@
+package exchange
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+func TestTaxLotLedger_DisposalMethods(t *testing.T) {
+	// Three long lots: 1 @ 100, 1 @ 150, 1 @ 120, then sell 1.5 @ 200
+	buys := []float64{100, 150, 120}
+
+	tests := []struct {
+		name      string
+		method    TaxLotMethod
+		lotIDs    func(lots []db.TaxLot) []string
+		wantPrice []float64 // Entry price of each disposed lot, in disposal order
+		wantGain  float64
+		wantOpen  []float64 // Remaining quantity per open lot, in acquisition order
+	}{
+		{
+			name:      "fifo",
+			method:    TaxLotFIFO,
+			wantPrice: []float64{100, 150},
+			wantGain:  1*100 + 0.5*50,
+			wantOpen:  []float64{0.5, 1},
+		},
+		{
+			name:      "lifo",
+			method:    TaxLotLIFO,
+			wantPrice: []float64{120, 150},
+			wantGain:  1*80 + 0.5*50,
+			wantOpen:  []float64{1, 0.5},
+		},
+		{
+			name:      "hifo",
+			method:    TaxLotHIFO,
+			wantPrice: []float64{150, 120},
+			wantGain:  1*50 + 0.5*80,
+			wantOpen:  []float64{1, 0.5},
+		},
+		{
+			name:   "specific id",
+			method: TaxLotSpecificID,
+			lotIDs: func(lots []db.TaxLot) []string {
+				return []string{lots[2].ID.String()}
+			},
+			wantPrice: []float64{120, 100},
+			wantGain:  1*80 + 0.5*100,
+			wantOpen:  []float64{0.5, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			sessionID := uuid.New()
+			ledger := NewTaxLotLedger(nil, &sessionID, tt.method)
+			start := time.Now()
+
+			for i, price := range buys {
+				_, err := ledger.Apply(ctx, &Order{ID: uuid.NewString(), Symbol: "BTCUSDT", Side: OrderSideBuy}, 1, price, 0, start.Add(time.Duration(i)*time.Hour))
+				require.NoError(t, err)
+			}
+
+			sell := &Order{ID: uuid.NewString(), Symbol: "BTCUSDT", Side: OrderSideSell}
+			if tt.lotIDs != nil {
+				sell.TaxLotIDs = tt.lotIDs(ledger.OpenLots("BTCUSDT"))
+			}
+			gains, err := ledger.Apply(ctx, sell, 1.5, 200, 0, start.Add(time.Hour*24))
+			require.NoError(t, err)
+			require.Len(t, gains, len(tt.wantPrice))
+
+			var total float64
+			for i, gain := range gains {
+				assert.InDelta(t, tt.wantPrice[i]*gain.Quantity, gain.CostBasis, 1e-9)
+				assert.Equal(t, string(tt.method), gain.Method)
+				assert.Equal(t, sell.ID, *gain.OrderID)
+				total += gain.Gain
+			}
+			assert.InDelta(t, tt.wantGain, total, 1e-9)
+
+			open := ledger.OpenLots("BTCUSDT")
+			require.Len(t, open, len(tt.wantOpen))
+			for i, qty := range tt.wantOpen {
+				assert.InDelta(t, qty, open[i].RemainingQuantity, 1e-9)
+			}
+		})
+	}
+}
+
+func TestTaxLotLedger_FeesAllocatedToLots(t *testing.T) {
+	ctx := context.Background()
+	ledger := NewTaxLotLedger(nil, nil, TaxLotFIFO)
+	at := time.Now()
+
+	// Buy 2 @ 100 paying 2 in fees, then sell 1 @ 110 paying 1.1
+	_, err := ledger.Apply(ctx, &Order{Symbol: "ETHUSDT", Side: OrderSideBuy}, 2, 100, 2, at)
+	require.NoError(t, err)
+	gains, err := ledger.Apply(ctx, &Order{Symbol: "ETHUSDT", Side: OrderSideSell}, 1, 110, 1.1, at.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, gains, 1)
+
+	assert.InDelta(t, 101, gains[0].CostBasis, 1e-9, "half of the acquisition fees")
+	assert.InDelta(t, 108.9, gains[0].Proceeds, 1e-9, "net of disposal fees")
+	assert.InDelta(t, 7.9, gains[0].Gain, 1e-9)
+
+	open := ledger.OpenLots("ETHUSDT")
+	require.Len(t, open, 1)
+	assert.InDelta(t, 1, open[0].Fees, 1e-9, "remaining quantity keeps the rest of the fees")
+}
+
+func TestTaxLotLedger_ShortsAndFlips(t *testing.T) {
+	ctx := context.Background()
+	ledger := NewTaxLotLedger(nil, nil, TaxLotFIFO)
+	at := time.Now()
+
+	// Short 1 @ 100, then buy 3 @ 90 with 3 in fees: covers the short and opens a 2 lot long
+	_, err := ledger.Apply(ctx, &Order{Symbol: "BTCUSDT", Side: OrderSideSell}, 1, 100, 0, at)
+	require.NoError(t, err)
+	gains, err := ledger.Apply(ctx, &Order{Symbol: "BTCUSDT", Side: OrderSideBuy}, 3, 90, 3, at.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, gains, 1)
+
+	assert.Equal(t, db.PositionSideShort, gains[0].Side)
+	assert.InDelta(t, 100, gains[0].Proceeds, 1e-9)
+	assert.InDelta(t, 91, gains[0].CostBasis, 1e-9, "cover cost includes its share of the fees")
+	assert.InDelta(t, 9, gains[0].Gain, 1e-9)
+
+	open := ledger.OpenLots("BTCUSDT")
+	require.Len(t, open, 1)
+	assert.Equal(t, db.PositionSideLong, open[0].Side)
+	assert.InDelta(t, 2, open[0].RemainingQuantity, 1e-9)
+	assert.InDelta(t, 2, open[0].Fees, 1e-9)
+}
+
+func TestTaxLotLedger_HIFOShortCoversLowestEntryFirst(t *testing.T) {
+	ctx := context.Background()
+	ledger := NewTaxLotLedger(nil, nil, TaxLotHIFO)
+	at := time.Now()
+
+	for i, price := range []float64{100, 90, 110} {
+		_, err := ledger.Apply(ctx, &Order{Symbol: "BTCUSDT", Side: OrderSideSell}, 1, price, 0, at.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	gains, err := ledger.Apply(ctx, &Order{Symbol: "BTCUSDT", Side: OrderSideBuy}, 1, 95, 0, at.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, gains, 1)
+	assert.InDelta(t, 90, gains[0].Proceeds, 1e-9)
+	assert.InDelta(t, -5, gains[0].Gain, 1e-9)
+}
+
+func TestPositionManager_RecordsTaxLots(t *testing.T) {
+	pm := NewPositionManagerWithFees(nil, 0.001)
+	sessionID := uuid.New()
+	pm.SetSession(&sessionID)
+	pm.SetTaxLotMethod(TaxLotLIFO)
+	ctx := context.Background()
+
+	require.NoError(t, pm.OnOrderFilled(ctx, &Order{Symbol: "BTCUSDT", Side: OrderSideBuy}, []Fill{{Price: 100, Quantity: 1}}))
+	require.NoError(t, pm.OnOrderFilled(ctx, &Order{Symbol: "BTCUSDT", Side: OrderSideBuy}, []Fill{{Price: 120, Quantity: 1}}))
+	require.NoError(t, pm.OnOrderFilled(ctx, &Order{Symbol: "BTCUSDT", Side: OrderSideSell}, []Fill{{Price: 130, Quantity: 1}}))
+
+	ledger := pm.TaxLots()
+	require.NotNil(t, ledger)
+	assert.Equal(t, TaxLotLIFO, ledger.Method())
+
+	open := ledger.OpenLots("BTCUSDT")
+	require.Len(t, open, 1)
+	assert.Equal(t, 100.0, open[0].Price, "LIFO disposes of the newer 120 lot")
+	assert.InDelta(t, 0.1, open[0].Fees, 1e-9)
+}
+
+func TestParseTaxLotMethod(t *testing.T) {
+	for input, want := range map[string]TaxLotMethod{
+		"":            TaxLotFIFO,
+		"FIFO":        TaxLotFIFO,
+		"lifo":        TaxLotLIFO,
+		"hifo":        TaxLotHIFO,
+		"specific_id": TaxLotSpecificID,
+	} {
+		got, err := ParseTaxLotMethod(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got)
+	}
+
+	_, err := ParseTaxLotMethod("average")
+	assert.Error(t, err)
+}
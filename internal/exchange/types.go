@@ -31,20 +31,39 @@ const (
 
 // Order represents a trading order
 type Order struct {
-	ID              string      `json:"id"`
-	ExchangeOrderID string      `json:"exchange_order_id,omitempty"` // Exchange-specific order ID (e.g., Binance int64 as string)
-	Symbol          string      `json:"symbol"`
-	Side            OrderSide   `json:"side"`
-	Type            OrderType   `json:"type"`
-	Quantity        float64     `json:"quantity"`
-	Price           float64     `json:"price,omitempty"` // For limit orders
-	FilledQty       float64     `json:"filled_qty"`
-	AvgFillPrice    float64     `json:"avg_fill_price,omitempty"`
-	Status          OrderStatus `json:"status"`
-	CreatedAt       time.Time   `json:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at"`
-	FilledAt        *time.Time  `json:"filled_at,omitempty"`
-	RejectReason    string      `json:"reject_reason,omitempty"`
+	ID              string       `json:"id"`
+	ExchangeOrderID string       `json:"exchange_order_id,omitempty"` // Exchange-specific order ID (e.g., Binance int64 as string)
+	Symbol          string       `json:"symbol"`
+	Side            OrderSide    `json:"side"`
+	Type            OrderType    `json:"type"`
+	Quantity        float64      `json:"quantity"`
+	Price           float64      `json:"price,omitempty"` // For limit orders
+	FilledQty       float64      `json:"filled_qty"`
+	AvgFillPrice    float64      `json:"avg_fill_price,omitempty"`
+	Status          OrderStatus  `json:"status"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
+	FilledAt        *time.Time   `json:"filled_at,omitempty"`
+	RejectReason    string       `json:"reject_reason,omitempty"`
+	ParentOrderID   string       `json:"parent_order_id,omitempty"` // Parent algo order for child slices
+	TaxLotIDs       []string     `json:"tax_lot_ids,omitempty"`     // Lots to dispose of first under specific-ID matching
+	ReduceOnly      bool         `json:"reduce_only,omitempty"`     // Futures: may only reduce an open position
+	PositionSide    PositionSide `json:"position_side,omitempty"`   // Futures: BOTH (one-way mode), LONG or SHORT (hedge mode)
+
+	// Amendments lists price/quantity changes made with AmendOrder, oldest first
+	Amendments []OrderAmendment `json:"amendments,omitempty"`
+}
+
+// OrderAmendment records one price/quantity change to a resting order.
+// Cancel-replace amendments also change the exchange order ID.
+type OrderAmendment struct {
+	PreviousPrice           float64   `json:"previous_price"`
+	PreviousQuantity        float64   `json:"previous_quantity"`
+	Price                   float64   `json:"price"`
+	Quantity                float64   `json:"quantity"`
+	PreviousExchangeOrderID string    `json:"previous_exchange_order_id,omitempty"`
+	ExchangeOrderID         string    `json:"exchange_order_id,omitempty"`
+	AmendedAt               time.Time `json:"amended_at"`
 }
 
 // Fill represents a partial or complete order fill
@@ -62,6 +81,26 @@ type PlaceOrderRequest struct {
 	Type     OrderType `json:"type"`
 	Quantity float64   `json:"quantity"`
 	Price    float64   `json:"price,omitempty"` // For limit orders
+
+	// ParentOrderID links a child slice to its parent algo order (optional)
+	ParentOrderID string `json:"parent_order_id,omitempty"`
+
+	// TaxLotIDs selects the tax lots a closing order disposes of (specific-ID matching, optional)
+	TaxLotIDs []string `json:"tax_lot_ids,omitempty"`
+
+	// ReduceOnly restricts a futures order to reducing the open position (optional)
+	ReduceOnly bool `json:"reduce_only,omitempty"`
+
+	// PositionSide targets a futures position: BOTH in one-way mode, LONG or SHORT in hedge mode (optional)
+	PositionSide PositionSide `json:"position_side,omitempty"`
+}
+
+// AmendOrderRequest changes the price and/or quantity of a resting limit order.
+// Quantity is the new total order quantity, including anything already filled.
+type AmendOrderRequest struct {
+	OrderID  string  `json:"order_id"`
+	Price    float64 `json:"price,omitempty"`    // New limit price (0 = unchanged)
+	Quantity float64 `json:"quantity,omitempty"` // New total quantity (0 = unchanged)
 }
 
 // PlaceOrderResponse represents the response after placing an order
@@ -70,3 +109,34 @@ type PlaceOrderResponse struct {
 	Status  OrderStatus `json:"status"`
 	Message string      `json:"message,omitempty"`
 }
+
+// Balance represents the holdings of a single asset
+type Balance struct {
+	Asset  string  `json:"asset"`
+	Free   float64 `json:"free"`   // Available for trading
+	Locked float64 `json:"locked"` // Reserved by open orders
+}
+
+// Total returns free plus locked holdings
+func (b Balance) Total() float64 {
+	return b.Free + b.Locked
+}
+
+// Account represents an exchange account and its asset inventory
+type Account struct {
+	Exchange    string    `json:"exchange"`
+	AccountType string    `json:"account_type,omitempty"`
+	CanTrade    bool      `json:"can_trade"`
+	Balances    []Balance `json:"balances"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Balance returns the balance for an asset (zero balance if not held)
+func (a *Account) Balance(asset string) Balance {
+	for _, b := range a.Balances {
+		if b.Asset == asset {
+			return b
+		}
+	}
+	return Balance{Asset: asset}
+}
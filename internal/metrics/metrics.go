@@ -37,6 +37,11 @@ const (
 	ExchangeErrorInvalidReq  = "invalid_request"
 	ExchangeErrorServerError = "server_error"
 	ExchangeErrorOther       = "other"
+
+	// Exchange rate budget decisions (bounded set)
+	RateBudgetAllowed = "allowed"
+	RateBudgetDelayed = "delayed"
+	RateBudgetShed    = "shed"
 )
 
 // NormalizeCircuitBreakerReason maps arbitrary reasons to bounded set
@@ -396,6 +401,31 @@ var (
 		Help:    "Order execution latency in milliseconds",
 		Buckets: []float64{100, 250, 500, 1000, 2500, 5000},
 	})
+
+	// Exchange rate limit usage (request weight, order counts) per limit window
+	ExchangeRateLimitUsed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cryptofunk_exchange_rate_limit_used",
+		Help: "Exchange rate limit units used in the current window",
+	}, []string{"exchange", "limit"})
+
+	// Exchange rate limit capacity per limit window
+	ExchangeRateLimitMax = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cryptofunk_exchange_rate_limit_max",
+		Help: "Exchange rate limit capacity of the current window",
+	}, []string{"exchange", "limit"})
+
+	// Rate budget decisions by endpoint class
+	ExchangeRateBudgetDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cryptofunk_exchange_rate_budget_decisions_total",
+		Help: "Exchange requests allowed, delayed or shed by the local rate budget",
+	}, []string{"exchange", "class", "decision"})
+
+	// Time requests spent waiting for rate budget
+	ExchangeRateBudgetWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cryptofunk_exchange_rate_budget_wait_ms",
+		Help:    "Time exchange requests waited for rate budget in milliseconds",
+		Buckets: []float64{10, 100, 500, 1000, 5000, 10000, 30000, 60000},
+	}, []string{"exchange", "class"})
 )
 
 // Vector Search Metrics
@@ -525,6 +555,20 @@ func RecordExchangeAPICall(exchange, endpoint string, durationMs float64, err er
 	}
 }
 
+// UpdateExchangeRateLimit records usage of one exchange rate limit window
+func UpdateExchangeRateLimit(exchange, limit string, used, capacity float64) {
+	ExchangeRateLimitUsed.WithLabelValues(exchange, limit).Set(used)
+	ExchangeRateLimitMax.WithLabelValues(exchange, limit).Set(capacity)
+}
+
+// RecordRateBudgetDecision records whether a request was allowed, delayed or shed
+func RecordRateBudgetDecision(exchange, class, decision string, waitMs float64) {
+	ExchangeRateBudgetDecisions.WithLabelValues(exchange, class, decision).Inc()
+	if decision == RateBudgetDelayed {
+		ExchangeRateBudgetWait.WithLabelValues(exchange, class).Observe(waitMs)
+	}
+}
+
 // RecordOrderExecution records order execution latency
 func RecordOrderExecution(durationMs float64) {
 	OrderExecutionLatency.Observe(durationMs)
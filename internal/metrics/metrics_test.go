@@ -3,6 +3,7 @@ package metrics
 import (
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -582,6 +583,21 @@ func TestRecordExchangeAPICall(t *testing.T) {
 	}
 }
 
+func TestExchangeRateBudgetMetrics(t *testing.T) {
+	UpdateExchangeRateLimit("binance", "request_weight_1m", 1200, 6000)
+	assert.Equal(t, 1200.0, testutil.ToFloat64(ExchangeRateLimitUsed.WithLabelValues("binance", "request_weight_1m")))
+	assert.Equal(t, 6000.0, testutil.ToFloat64(ExchangeRateLimitMax.WithLabelValues("binance", "request_weight_1m")))
+
+	before := testutil.ToFloat64(ExchangeRateBudgetDecisions.WithLabelValues("binance", "market_data", RateBudgetShed))
+	RecordRateBudgetDecision("binance", "market_data", RateBudgetShed, 0)
+	assert.Equal(t, before+1, testutil.ToFloat64(ExchangeRateBudgetDecisions.WithLabelValues("binance", "market_data", RateBudgetShed)))
+
+	assert.NotPanics(t, func() {
+		RecordRateBudgetDecision("binance", "order", RateBudgetDelayed, 850)
+		RecordRateBudgetDecision("binance", "order", RateBudgetAllowed, 0)
+	})
+}
+
 func TestRecordOrderExecution(t *testing.T) {
 	tests := []struct {
 		name       string
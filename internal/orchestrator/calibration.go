@@ -0,0 +1,282 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ajitpratap0/cryptofunk/internal/calibration"
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+const (
+	defaultCalibrationInterval   = 6 * time.Hour
+	defaultCalibrationLookback   = 30 * 24 * time.Hour
+	defaultCalibrationMinSamples = 100
+)
+
+// CalibrationConfig fits a mapping per agent from its stated confidence to
+// how often its signals were right, and votes with the mapped confidence.
+// Signals are scored like weight learning: a hit is a return over Horizon
+// that agrees with the signal. Agents with fewer than MinSamples scored
+// signals vote with their raw confidence.
+type CalibrationConfig struct {
+	Enabled       bool          `json:"enabled" yaml:"enabled" mapstructure:"enabled"`
+	Method        string        `json:"method" yaml:"method" mapstructure:"method"`                         // isotonic or platt
+	Interval      time.Duration `json:"interval" yaml:"interval" mapstructure:"interval"`                   // How often mappings are refitted
+	Lookback      time.Duration `json:"lookback" yaml:"lookback" mapstructure:"lookback"`                   // How far back each fit looks
+	Horizon       time.Duration `json:"horizon" yaml:"horizon" mapstructure:"horizon"`                      // Return horizon a signal is scored over
+	PriceInterval string        `json:"price_interval" yaml:"price_interval" mapstructure:"price_interval"` // Candle interval returns are measured on
+	Deadband      float64       `json:"deadband" yaml:"deadband" mapstructure:"deadband"`                   // Returns within ±deadband count as flat
+	MinSamples    int           `json:"min_samples" yaml:"min_samples" mapstructure:"min_samples"`          // Scored signals before a mapping applies
+	Bins          int           `json:"bins" yaml:"bins" mapstructure:"bins"`                               // Reliability diagram bins
+}
+
+// withDefaults fills unset fields with defaults
+func (c CalibrationConfig) withDefaults() CalibrationConfig {
+	if c.Method == "" {
+		c.Method = string(calibration.MethodIsotonic)
+	}
+	if c.Interval <= 0 {
+		c.Interval = defaultCalibrationInterval
+	}
+	if c.Lookback <= 0 {
+		c.Lookback = defaultCalibrationLookback
+	}
+	if c.Horizon <= 0 {
+		c.Horizon = defaultWeightLearningHorizon
+	}
+	if c.PriceInterval == "" {
+		c.PriceInterval = defaultWeightPriceInterval
+	}
+	if c.MinSamples <= 0 {
+		c.MinSamples = defaultCalibrationMinSamples
+	}
+	if c.Bins <= 0 {
+		c.Bins = calibration.DefaultBins
+	}
+	return c
+}
+
+// validate rejects unknown methods and negative deadbands
+func (c CalibrationConfig) validate() error {
+	if _, err := calibration.ParseMethod(c.Method); err != nil {
+		return err
+	}
+	if c.Deadband < 0 {
+		return fmt.Errorf("calibration deadband must not be negative")
+	}
+	return nil
+}
+
+// AgentCalibration is an agent's confidence mapping with its reliability
+// diagrams and Brier scores before and after calibration. The calibrated
+// figures are measured on the signals the mapping was fitted to.
+type AgentCalibration struct {
+	Agent                 string               `json:"agent"`
+	Calibrated            bool                 `json:"calibrated"` // False below MinSamples: confidences pass through
+	Samples               int                  `json:"samples"`
+	Mapping               *calibration.Mapping `json:"mapping,omitempty"`
+	BrierScore            float64              `json:"brier_score"`
+	CalibratedBrierScore  float64              `json:"calibrated_brier_score"`
+	Reliability           []calibration.Bin    `json:"reliability"`
+	CalibratedReliability []calibration.Bin    `json:"calibrated_reliability,omitempty"`
+	From                  time.Time            `json:"from"`
+	To                    time.Time            `json:"to"`
+	FittedAt              time.Time            `json:"fitted_at"`
+}
+
+// Apply returns an agent's calibrated confidence
+func (c *AgentCalibration) Apply(confidence float64) float64 {
+	if c == nil || !c.Calibrated {
+		return confidence
+	}
+	return c.Mapping.Apply(confidence)
+}
+
+// calibrationSource supplies scored signals to fit calibrations on
+type calibrationSource interface {
+	Outcomes(ctx context.Context, from, to time.Time, interval string, horizon time.Duration) ([]SignalOutcome, error)
+}
+
+// dbCalibrationSource scores signals recorded in the database
+type dbCalibrationSource struct {
+	db *db.DB
+}
+
+// Outcomes returns the signals created in (from, to] with the returns that
+// followed them
+func (s *dbCalibrationSource) Outcomes(ctx context.Context, from, to time.Time, interval string, horizon time.Duration) ([]SignalOutcome, error) {
+	return loadSignalOutcomes(ctx, s.db, from, to, interval, horizon)
+}
+
+// fitAgentCalibrations groups outcomes by agent and fits a mapping for each
+// agent with enough of them
+func fitAgentCalibrations(config CalibrationConfig, outcomes []SignalOutcome, from, to, now time.Time) (map[string]*AgentCalibration, error) {
+	method, err := calibration.ParseMethod(config.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make(map[string][]calibration.Sample)
+	for _, outcome := range outcomes {
+		samples[outcome.Agent] = append(samples[outcome.Agent], calibration.Sample{
+			Confidence: outcome.Confidence,
+			Hit:        signalHit(outcome.Signal, outcome.Return, config.Deadband),
+		})
+	}
+
+	calibrations := make(map[string]*AgentCalibration, len(samples))
+	for agent, agentSamples := range samples {
+		c := &AgentCalibration{
+			Agent:       agent,
+			Samples:     len(agentSamples),
+			BrierScore:  calibration.Brier(agentSamples),
+			Reliability: calibration.Reliability(agentSamples, config.Bins),
+			From:        from,
+			To:          to,
+			FittedAt:    now,
+		}
+		c.CalibratedBrierScore = c.BrierScore
+		if len(agentSamples) >= config.MinSamples {
+			mapping, err := calibration.Fit(method, agentSamples)
+			if err != nil {
+				return nil, fmt.Errorf("failed to calibrate %s: %w", agent, err)
+			}
+			calibrated := calibration.Calibrated(mapping, agentSamples)
+			c.Calibrated = true
+			c.Mapping = mapping
+			c.CalibratedBrierScore = calibration.Brier(calibrated)
+			c.CalibratedReliability = calibration.Reliability(calibrated, config.Bins)
+		}
+		calibrations[agent] = c
+	}
+	return calibrations, nil
+}
+
+// calibrationLoop fits calibrations at startup and then periodically
+func (o *Orchestrator) calibrationLoop() {
+	defer o.wg.Done()
+
+	o.fitCalibrations(o.ctx, time.Now())
+
+	ticker := time.NewTicker(o.config.Calibration.withDefaults().Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			o.fitCalibrations(o.ctx, time.Now())
+		}
+	}
+}
+
+// fitCalibrations refits every agent's calibration on the signals whose
+// horizon ended within the lookback
+func (o *Orchestrator) fitCalibrations(ctx context.Context, now time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	config := o.config.Calibration.withDefaults()
+	to := now.Add(-config.Horizon)
+	from := to.Add(-config.Lookback)
+
+	outcomes, err := o.calibrationSource.Outcomes(ctx, from, to, config.PriceInterval, config.Horizon)
+	if err != nil {
+		o.log.Warn().Err(err).Msg("Failed to load signal outcomes for calibration, keeping current calibrations")
+		return
+	}
+	calibrations, err := fitAgentCalibrations(config, outcomes, from, to, now)
+	if err != nil {
+		o.log.Warn().Err(err).Msg("Failed to fit calibrations, keeping current calibrations")
+		return
+	}
+
+	o.calibrationMutex.Lock()
+	o.calibrations = calibrations
+	o.calibrationMutex.Unlock()
+
+	calibrated := 0
+	for agent, c := range calibrations {
+		o.metrics.CalibrationBrier.WithLabelValues(agent, "raw").Set(c.BrierScore)
+		o.metrics.CalibrationBrier.WithLabelValues(agent, "calibrated").Set(c.CalibratedBrierScore)
+		if c.Calibrated {
+			calibrated++
+		}
+	}
+	o.log.Info().
+		Int("signals", len(outcomes)).
+		Int("agents", len(calibrations)).
+		Int("calibrated", calibrated).
+		Str("method", config.Method).
+		Msg("Fitted agent confidence calibrations")
+}
+
+// agentCalibration returns an agent's latest calibration, or nil
+func (o *Orchestrator) agentCalibration(agent string) *AgentCalibration {
+	o.calibrationMutex.RLock()
+	defer o.calibrationMutex.RUnlock()
+	return o.calibrations[agent]
+}
+
+// calibratedConfidence maps an agent's stated confidence through its
+// calibration; uncalibrated agents keep theirs. The mapping gives the
+// probability p that the signal is right, which votes as 2p-1, its edge over
+// a coin flip, on the scale agents state confidence in (0 is no information).
+func (o *Orchestrator) calibratedConfidence(agent string, confidence float64) float64 {
+	c := o.agentCalibration(agent)
+	if c == nil || !c.Calibrated {
+		return confidence
+	}
+	return math.Max(0, 2*c.Apply(confidence)-1)
+}
+
+// AgentCalibrations returns the latest calibration of every agent, by name
+func (o *Orchestrator) AgentCalibrations() []*AgentCalibration {
+	o.calibrationMutex.RLock()
+	defer o.calibrationMutex.RUnlock()
+
+	calibrations := make([]*AgentCalibration, 0, len(o.calibrations))
+	for _, c := range o.calibrations {
+		calibrations = append(calibrations, c)
+	}
+	sort.Slice(calibrations, func(i, j int) bool { return calibrations[i].Agent < calibrations[j].Agent })
+	return calibrations
+}
+
+// HandleAgentCalibrationRequest handles GET /api/v1/agents/{name}/calibration
+func (o *Orchestrator) HandleAgentCalibrationRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.PathValue("name")
+	config := o.config.Calibration.withDefaults()
+	if !config.Enabled {
+		o.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"agent":   name,
+			"enabled": false,
+		})
+		return
+	}
+
+	c := o.agentCalibration(name)
+	if c == nil {
+		http.Error(w, fmt.Sprintf("No scored signals for agent %s", name), http.StatusNotFound)
+		return
+	}
+	o.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"agent":       name,
+		"enabled":     true,
+		"method":      config.Method,
+		"horizon":     config.Horizon.String(),
+		"min_samples": config.MinSamples,
+		"calibration": c,
+	})
+}
@@ -0,0 +1,170 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCalibrationSource serves fixed outcomes
+type fakeCalibrationSource struct {
+	outcomes []SignalOutcome
+	from, to time.Time
+}
+
+func (f *fakeCalibrationSource) Outcomes(ctx context.Context, from, to time.Time, interval string, horizon time.Duration) ([]SignalOutcome, error) {
+	f.from, f.to = from, to
+	return f.outcomes, nil
+}
+
+// overconfidentOutcomes has trend-agent say 0.9 and be right half the time,
+// and a new agent with too few signals to calibrate
+func overconfidentOutcomes() []SignalOutcome {
+	var outcomes []SignalOutcome
+	for i := 0; i < 20; i++ {
+		ret := 0.02
+		if i%2 == 1 {
+			ret = -0.02
+		}
+		outcomes = append(outcomes, SignalOutcome{Agent: "trend-agent", AgentType: "trend", Signal: "BUY", Confidence: 0.9, Return: ret})
+	}
+	outcomes = append(outcomes, SignalOutcome{Agent: "new-agent", AgentType: "reversion", Signal: "SELL", Confidence: 0.7, Return: -0.02})
+	return outcomes
+}
+
+func newCalibrationTestOrchestrator(t *testing.T, method string) (*Orchestrator, *fakeCalibrationSource) {
+	orch := newRiskBudgetTestOrchestrator(t, RiskBudgetConfig{}, &fakeRiskBudgetSource{})
+	orch.config.Calibration = CalibrationConfig{Enabled: true, Method: method, MinSamples: 10, Bins: 5}
+	source := &fakeCalibrationSource{outcomes: overconfidentOutcomes()}
+	orch.calibrationSource = source
+	return orch, source
+}
+
+func TestCalibrationConfig_Validate(t *testing.T) {
+	assert.NoError(t, CalibrationConfig{}.validate())
+	assert.Error(t, CalibrationConfig{Method: "beta"}.validate())
+	assert.Error(t, CalibrationConfig{Deadband: -1}.validate())
+
+	_, err := NewOrchestrator(&OrchestratorConfig{Calibration: CalibrationConfig{Method: "beta"}}, zerolog.Nop(), nil, 0)
+	assert.Error(t, err)
+}
+
+func TestFitCalibrations(t *testing.T) {
+	for _, method := range []string{"isotonic", "platt"} {
+		t.Run(method, func(t *testing.T) {
+			orch, source := newCalibrationTestOrchestrator(t, method)
+			now := time.Now()
+			orch.fitCalibrations(context.Background(), now)
+
+			config := orch.config.Calibration.withDefaults()
+			assert.Equal(t, now.Add(-config.Horizon), source.to)
+			assert.Equal(t, source.to.Add(-config.Lookback), source.from)
+
+			trend := orch.agentCalibration("trend-agent")
+			require.NotNil(t, trend)
+			assert.True(t, trend.Calibrated)
+			assert.Equal(t, 20, trend.Samples)
+			assert.InDelta(t, (0.01+0.81)/2, trend.BrierScore, 1e-9)
+			assert.Less(t, trend.CalibratedBrierScore, trend.BrierScore)
+			require.Len(t, trend.Reliability, 5)
+			assert.Equal(t, 20, trend.Reliability[4].Count)
+			assert.InDelta(t, 0.5, trend.Reliability[4].HitRate, 1e-9)
+			assert.InDelta(t, 0.5, trend.Apply(0.9), 0.05)
+			// A coin flip votes with no confidence
+			assert.InDelta(t, 0.0, orch.calibratedConfidence("trend-agent", 0.9), 0.1)
+
+			// Too few signals: diagnostics without a mapping
+			fresh := orch.agentCalibration("new-agent")
+			require.NotNil(t, fresh)
+			assert.False(t, fresh.Calibrated)
+			assert.Nil(t, fresh.CalibratedReliability)
+			assert.Equal(t, 0.7, orch.calibratedConfidence("new-agent", 0.7))
+			assert.Equal(t, 0.6, orch.calibratedConfidence("unknown-agent", 0.6))
+
+			assert.Len(t, orch.AgentCalibrations(), 2)
+		})
+	}
+}
+
+func TestCalculateDecision_UsesCalibratedConfidence(t *testing.T) {
+	orch, _ := newCalibrationTestOrchestrator(t, "isotonic")
+	orch.config.MinConfidence = 0.6
+	signal := AgentSignal{AgentName: "trend-agent", AgentType: "trend", Signal: "BUY", Confidence: 0.9}
+
+	decision := decide(orch, signal)
+	assert.Equal(t, "BUY", decision.Action)
+	assert.InDelta(t, 0.9, decision.Confidence, 1e-9)
+
+	// A coin flip at stated 0.9 no longer clears the confidence threshold
+	orch.fitCalibrations(context.Background(), time.Now())
+	decision = decide(orch, signal)
+	assert.Equal(t, "HOLD", decision.Action)
+	assert.Contains(t, decision.Reasoning, "trend-agent(BUY): 0.90 confidence (0.00 calibrated)")
+}
+
+func TestCalculateDecision_BayesianVotesWithCalibratedEdge(t *testing.T) {
+	orch, _ := newCalibrationTestOrchestrator(t, "isotonic")
+	orch.config.MinConsensus = 0
+	orch.config.MinConfidence = 0
+	orch.votingSource = &fakeVotingSource{settings: VotingSettings{Method: VotingBayesian}}
+	orch.refreshVoting(context.Background())
+	signals := []AgentSignal{
+		{AgentName: "trend-agent", AgentType: "trend", Signal: "BUY", Confidence: 0.9},
+		{AgentName: "new-agent", AgentType: "reversion", Signal: "SELL", Confidence: 0.3},
+	}
+
+	decision := decide(orch, signals...)
+	assert.Equal(t, VotingBayesian, decision.VotingMethod)
+	assert.Equal(t, "BUY", decision.Action)
+
+	// Once calibrated, trend-agent's coin flips carry no evidence, so the
+	// uncalibrated SELL decides. Voting with P(hit) = 0.5 as the confidence
+	// would still count it as a 75% BUY.
+	orch.fitCalibrations(context.Background(), time.Now())
+	decision = decide(orch, signals...)
+	assert.Equal(t, "SELL", decision.Action)
+	assert.Contains(t, decision.Reasoning, "trend-agent(BUY): 0.90 confidence (0.00 calibrated)")
+}
+
+func TestHandleAgentCalibrationRequest(t *testing.T) {
+	orch, _ := newCalibrationTestOrchestrator(t, "platt")
+	orch.fitCalibrations(context.Background(), time.Now())
+
+	get := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/agents/"+name+"/calibration", nil)
+		req.SetPathValue("name", name)
+		w := httptest.NewRecorder()
+		orch.HandleAgentCalibrationRequest(w, req)
+		return w
+	}
+
+	w := get("trend-agent")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Agent       string           `json:"agent"`
+		Enabled     bool             `json:"enabled"`
+		Method      string           `json:"method"`
+		Calibration AgentCalibration `json:"calibration"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "trend-agent", response.Agent)
+	assert.True(t, response.Enabled)
+	assert.Equal(t, "platt", response.Method)
+	assert.True(t, response.Calibration.Calibrated)
+	require.NotNil(t, response.Calibration.Mapping)
+	assert.Len(t, response.Calibration.CalibratedReliability, 5)
+
+	assert.Equal(t, http.StatusNotFound, get("unknown-agent").Code)
+
+	orch.config.Calibration.Enabled = false
+	w = get("trend-agent")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"enabled":false`)
+}
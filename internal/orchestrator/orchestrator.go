@@ -81,6 +81,8 @@ type TradingDecision struct {
 	Reasoning           string                 `json:"reasoning"`
 	Timestamp           time.Time              `json:"timestamp"`
 	Metadata            map[string]interface{} `json:"metadata,omitempty"`
+	Attribution         *DecisionAttribution   `json:"attribution,omitempty"` // Agents and strategies behind a BUY or SELL
+	VotingMethod        VotingMethod           `json:"voting_method"`
 }
 
 // OrchestratorConfig holds orchestrator configuration
@@ -95,6 +97,22 @@ type OrchestratorConfig struct {
 	MinConfidence       float64       `json:"min_confidence" yaml:"min_confidence"`   // Minimum confidence threshold
 	MaxSignalAge        time.Duration `json:"max_signal_age" yaml:"max_signal_age"`   // Discard signals older than this
 	HealthCheckInterval time.Duration `json:"health_check_interval" yaml:"health_check_interval"`
+
+	// Trading circuit breakers (thresholds come from the active strategy)
+	CircuitBreakerInterval time.Duration `json:"circuit_breaker_interval" yaml:"circuit_breaker_interval"` // How often thresholds are evaluated
+	CircuitBreakerCooldown time.Duration `json:"circuit_breaker_cooldown" yaml:"circuit_breaker_cooldown"` // Minimum halt for trade-rate and volatility trips
+
+	// Risk budgets per agent and strategy
+	RiskBudgets RiskBudgetConfig `json:"risk_budgets" yaml:"risk_budgets"`
+
+	// Market regime service and regime-conditional voting weights
+	Regime RegimeConfig `json:"regime" yaml:"regime"`
+
+	// Voting weights learned from the outcomes of agent signals
+	WeightLearning WeightLearningConfig `json:"weight_learning" yaml:"weight_learning"`
+
+	// Per-agent confidence calibration applied before voting
+	Calibration CalibrationConfig `json:"calibration" yaml:"calibration"`
 }
 
 // OrchestratorMetrics holds Prometheus metrics for orchestrator
@@ -107,6 +125,10 @@ type OrchestratorMetrics struct {
 	ActiveAgents     prometheus.Gauge
 	ConsensusScore   prometheus.Histogram
 	VotingDuration   prometheus.Histogram
+	DecisionsByVote  *prometheus.CounterVec
+	AgentWeight      *prometheus.GaugeVec
+	WeightChanges    *prometheus.CounterVec
+	CalibrationBrier *prometheus.GaugeVec
 }
 
 // Global metrics instance (singleton pattern to avoid Prometheus registration conflicts)
@@ -155,6 +177,22 @@ func getOrCreateOrchestratorMetrics() *OrchestratorMetrics {
 				Help:    "Duration of weighted voting calculation",
 				Buckets: prometheus.DefBuckets,
 			}),
+			DecisionsByVote: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "orchestrator_decisions_by_voting_method_total",
+				Help: "Trading decisions by voting method and action",
+			}, []string{"method", "action"}),
+			AgentWeight: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "orchestrator_agent_weight",
+				Help: "Learned voting weight of each agent",
+			}, []string{"agent"}),
+			WeightChanges: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "orchestrator_agent_weight_changes_total",
+				Help: "Agent weight changes by reason (learned, freeze, unfreeze, override, clear_override)",
+			}, []string{"reason"}),
+			CalibrationBrier: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "orchestrator_agent_calibration_brier_score",
+				Help: "Brier score of each agent's signals before (raw) and after (calibrated) confidence calibration",
+			}, []string{"agent", "stage"}),
 		}
 	})
 	return orchestratorMetricsInstance
@@ -189,14 +227,44 @@ type Orchestrator struct {
 	startTime time.Time
 
 	// Trading control
-	paused      bool
-	pausedMutex sync.RWMutex
+	paused       bool
+	pausedByName string // Who paused trading ("api", "circuit_breaker")
+	pausedMutex  sync.RWMutex
 
 	// Metrics
 	metrics *OrchestratorMetrics
 
 	// Circuit breaker for external dependencies
 	circuitBreaker *risk.CircuitBreakerManager
+
+	// Trading circuit breakers from the active strategy (source is nil without a database)
+	tradingBreaker *risk.TradingBreaker
+	breakerSource  tradingBreakerSource
+
+	// Risk budgets per agent and strategy (source is nil without a database)
+	budgetSource      riskBudgetSource
+	budgetMetric      risk.BudgetMetric
+	budgetUsage       []risk.BudgetUsage
+	budgetThrottles   map[string]float64 // "scope/name" -> voting weight multiplier
+	budgetEvaluatedAt time.Time
+	budgetsMutex      sync.RWMutex
+
+	// Latest market regimes (nil unless the regime service is enabled with a database)
+	regimes regimeSource
+
+	// Vote aggregation selected by the active strategy (source is nil without a database)
+	votingSource votingSource
+	aggregator   VoteAggregator
+	votingMutex  sync.RWMutex
+
+	// Online agent weight learning (nil unless enabled; source is nil without a database)
+	weights      *WeightLearner
+	weightSource weightSource
+
+	// Per-agent confidence calibrations (source is nil without a database)
+	calibrationSource calibrationSource
+	calibrations      map[string]*AgentCalibration
+	calibrationMutex  sync.RWMutex
 }
 
 // NewOrchestrator creates a new orchestrator instance
@@ -218,17 +286,57 @@ func NewOrchestrator(config *OrchestratorConfig, log zerolog.Logger, database *d
 	// Suppress unused variable warning for API compatibility
 	_ = metricsPort
 
-	return &Orchestrator{
-		config:         config,
-		log:            orchestratorLog,
-		db:             database,
-		agents:         make(map[string]*AgentSession),
-		natsConn:       nil, // Will be set in Initialize()
-		signalBuffer:   make([]*AgentSignal, 0),
-		metrics:        orchestratorMetrics,
-		circuitBreaker: circuitBreaker,
-		startTime:      time.Now(),
-	}, nil
+	budgetMetric, err := risk.ParseBudgetMetric(config.RiskBudgets.Metric)
+	if err != nil {
+		return nil, fmt.Errorf("invalid risk budget config: %w", err)
+	}
+	if err := validateRegimeWeights(config.Regime.Weights); err != nil {
+		return nil, fmt.Errorf("invalid regime config: %w", err)
+	}
+	if err := config.WeightLearning.validate(); err != nil {
+		return nil, fmt.Errorf("invalid weight learning config: %w", err)
+	}
+	if err := config.Calibration.validate(); err != nil {
+		return nil, fmt.Errorf("invalid calibration config: %w", err)
+	}
+
+	var breakerSource tradingBreakerSource
+	var budgetSource riskBudgetSource
+	var voting votingSource
+	var weights weightSource
+	var calibrations calibrationSource
+	if database != nil {
+		calculator := risk.NewCalculatorWithPool(database.Pool())
+		breakerSource = &dbTradingBreakerSource{db: database, calculator: calculator}
+		budgetSource = &dbRiskBudgetSource{db: database, calculator: calculator}
+		voting = &dbVotingSource{db: database}
+		weights = &dbWeightSource{db: database}
+		calibrations = &dbCalibrationSource{db: database}
+	}
+
+	o := &Orchestrator{
+		config:            config,
+		log:               orchestratorLog,
+		db:                database,
+		agents:            make(map[string]*AgentSession),
+		natsConn:          nil, // Will be set in Initialize()
+		signalBuffer:      make([]*AgentSignal, 0),
+		metrics:           orchestratorMetrics,
+		circuitBreaker:    circuitBreaker,
+		tradingBreaker:    risk.NewTradingBreaker(config.CircuitBreakerCooldown),
+		breakerSource:     breakerSource,
+		budgetSource:      budgetSource,
+		budgetMetric:      budgetMetric,
+		votingSource:      voting,
+		aggregator:        weightedConsensus{},
+		weightSource:      weights,
+		calibrationSource: calibrations,
+		startTime:         time.Now(),
+	}
+	if config.WeightLearning.Enabled {
+		o.weights = NewWeightLearner(config.WeightLearning, o.getDefaultWeight)
+	}
+	return o, nil
 }
 
 // Initialize sets up the orchestrator
@@ -246,6 +354,9 @@ func (o *Orchestrator) Initialize(ctx context.Context) error {
 		} else {
 			o.pausedMutex.Lock()
 			o.paused = state.Paused
+			if state.PausedBy != nil {
+				o.pausedByName = *state.PausedBy
+			}
 			o.pausedMutex.Unlock()
 
 			if state.Paused {
@@ -292,6 +403,33 @@ func (o *Orchestrator) Initialize(ctx context.Context) error {
 	o.wg.Add(1)
 	go o.healthCheckLoop()
 
+	// Start trading circuit breaker routine
+	if o.breakerSource != nil {
+		o.wg.Add(1)
+		go o.tradingBreakerLoop()
+	}
+
+	// Start risk budget routine
+	if o.budgetSource != nil {
+		o.wg.Add(1)
+		go o.riskBudgetLoop()
+	}
+
+	// Start market regime service
+	o.startRegimeService()
+
+	// Start agent weight learning routine
+	if o.weights != nil && o.weightSource != nil {
+		o.wg.Add(1)
+		go o.weightLearningLoop()
+	}
+
+	// Start confidence calibration routine
+	if o.config.Calibration.Enabled && o.calibrationSource != nil {
+		o.wg.Add(1)
+		go o.calibrationLoop()
+	}
+
 	o.log.Info().Msg("Orchestrator initialized successfully")
 	return nil
 }
@@ -344,6 +482,9 @@ func (o *Orchestrator) handleSignal(msg *nats.Msg) {
 	// Update agent session
 	o.updateAgentSession(signal.AgentName, signal.AgentType, &signal)
 
+	// Keep the signal for scoring once its outcome is known
+	o.recordSignal(&signal)
+
 	// Add to signal buffer
 	o.signalBufferMutex.Lock()
 	// Check buffer size and evict oldest signals if at capacity
@@ -392,7 +533,7 @@ func (o *Orchestrator) handleHeartbeat(msg *nats.Msg) {
 			Name:            heartbeat.AgentName,
 			Type:            heartbeat.AgentType,
 			Enabled:         true,
-			Weight:          o.getDefaultWeight(heartbeat.AgentType),
+			Weight:          o.agentWeight(heartbeat.AgentName, heartbeat.AgentType),
 			LastHeartbeat:   heartbeat.Timestamp,
 			HealthStatus:    heartbeat.Status,
 			PerformanceData: make(map[string]interface{}),
@@ -421,7 +562,7 @@ func (o *Orchestrator) updateAgentSession(name, agentType string, signal *AgentS
 			Name:            name,
 			Type:            agentType,
 			Enabled:         true,
-			Weight:          o.getDefaultWeight(agentType),
+			Weight:          o.agentWeight(name, agentType),
 			LastSignal:      signal.Timestamp,
 			SignalCount:     1,
 			HealthStatus:    HealthStatusUnknown,
@@ -474,6 +615,9 @@ func (o *Orchestrator) makeDecision(ctx context.Context) error {
 		return nil
 	}
 
+	// Vote with the active strategy's voting method
+	o.refreshVoting(ctx)
+
 	// Get recent signals grouped by symbol
 	symbolSignals := o.getRecentSignalsBySymbol()
 
@@ -509,8 +653,11 @@ func (o *Orchestrator) makeDecision(ctx context.Context) error {
 		// Publish all decisions including HOLD (needed for monitoring and testing)
 		if err := o.publishDecision(decision); err != nil {
 			o.log.Error().Err(err).Str("symbol", decision.Symbol).Msg("Failed to publish decision")
+		} else {
+			o.recordAttribution(decision)
 		}
 		o.metrics.DecisionsTotal.Inc()
+		o.metrics.DecisionsByVote.WithLabelValues(string(decision.VotingMethod), decision.Action).Inc()
 		o.metrics.ConsensusScore.Observe(decision.Consensus)
 
 		o.log.Info().
@@ -519,6 +666,7 @@ func (o *Orchestrator) makeDecision(ctx context.Context) error {
 			Float64("confidence", decision.Confidence).
 			Float64("consensus", decision.Consensus).
 			Int("agents", decision.ParticipatingAgents).
+			Str("voting_method", string(decision.VotingMethod)).
 			Msg("Trading decision made")
 	}
 
@@ -546,7 +694,8 @@ func (o *Orchestrator) getRecentSignalsBySymbol() map[string][]*AgentSignal {
 	return result
 }
 
-// calculateDecision implements weighted voting and consensus logic
+// calculateDecision collects the weighted votes of enabled agents and
+// aggregates them with the active voting method
 func (o *Orchestrator) calculateDecision(ctx *DecisionContext) *TradingDecision {
 	start := time.Now()
 	defer func() {
@@ -554,18 +703,18 @@ func (o *Orchestrator) calculateDecision(ctx *DecisionContext) *TradingDecision
 		o.metrics.VotingDuration.Observe(duration.Seconds())
 	}()
 
-	// Initialize voting scores
-	votingScores := map[string]float64{
-		"BUY":  0.0,
-		"SELL": 0.0,
-		"HOLD": 0.0,
-	}
-
-	totalWeight := 0.0
-	participatingAgents := 0
 	var reasoning []string
+	var ballot Ballot
+
+	// Voting weights are conditional on the symbol's market regime
+	estimate := o.regimeEstimate(ctx.Symbol)
 
 	o.agentsMutex.RLock()
+	for _, session := range o.agents {
+		if session.Enabled {
+			ballot.Eligible++
+		}
+	}
 	for _, signal := range ctx.Signals {
 		// Get agent session for weight
 		session, exists := o.agents[signal.AgentName]
@@ -573,56 +722,78 @@ func (o *Orchestrator) calculateDecision(ctx *DecisionContext) *TradingDecision
 			continue
 		}
 
-		// Calculate weighted vote
-		weight := session.Weight
-		confidence := signal.Confidence
-		vote := weight * confidence
-
-		votingScores[signal.Signal] += vote
-		totalWeight += weight
-
-		participatingAgents++
-		reasoning = append(reasoning, fmt.Sprintf("%s(%s): %.2f confidence",
-			signal.AgentName, signal.Signal, signal.Confidence))
-	}
-	o.agentsMutex.RUnlock()
+		// Calculate voting weight, throttled by the agent's and its strategy's risk budgets
+		weight := session.Weight * o.budgetThrottle(session.Name, session.Type)
+		if weight <= 0 && session.Weight > 0 {
+			reasoning = append(reasoning, fmt.Sprintf("%s: risk budget exhausted", signal.AgentName))
+			continue
+		}
+		weight *= o.regimeMultiplier(estimate, session.Type)
+		if weight <= 0 && session.Weight > 0 {
+			reasoning = append(reasoning, fmt.Sprintf("%s: muted in %s regime", signal.AgentName, estimate.Regime))
+			continue
+		}
 
-	// Find winning action
-	maxScore := 0.0
-	winningAction := "HOLD"
-	for action, score := range votingScores {
-		if score > maxScore {
-			maxScore = score
-			winningAction = action
+		// Agents vote with their calibrated confidence
+		confidence := o.calibratedConfidence(signal.AgentName, signal.Confidence)
+		ballot.Votes = append(ballot.Votes, Vote{
+			Agent:      signal.AgentName,
+			AgentType:  session.Type,
+			Action:     signal.Signal,
+			Weight:     weight,
+			Confidence: confidence,
+		})
+		if confidence != signal.Confidence {
+			reasoning = append(reasoning, fmt.Sprintf("%s(%s): %.2f confidence (%.2f calibrated)",
+				signal.AgentName, signal.Signal, signal.Confidence, confidence))
+		} else {
+			reasoning = append(reasoning, fmt.Sprintf("%s(%s): %.2f confidence",
+				signal.AgentName, signal.Signal, signal.Confidence))
 		}
 	}
+	o.agentsMutex.RUnlock()
 
-	// Calculate consensus (agreement level)
-	consensus := 0.0
-	if totalWeight > 0 {
-		consensus = maxScore / totalWeight
+	aggregator := o.voteAggregator()
+	tally := aggregator.Aggregate(ballot)
+	if tally.Reason != "" {
+		reasoning = append(reasoning, tally.Reason)
 	}
 
-	// Calculate final confidence
-	confidence := maxScore / totalWeight
-
 	// Check thresholds
-	if consensus < ctx.MinConsensus || confidence < ctx.MinConfidence {
+	winningAction := tally.Action
+	if winningAction != "HOLD" && (tally.Consensus < ctx.MinConsensus || tally.Confidence < ctx.MinConfidence) {
 		winningAction = "HOLD"
 		reasoning = append(reasoning, fmt.Sprintf(
 			"Insufficient consensus (%.2f < %.2f) or confidence (%.2f < %.2f)",
-			consensus, ctx.MinConsensus, confidence, ctx.MinConfidence))
+			tally.Consensus, ctx.MinConsensus, tally.Confidence, ctx.MinConfidence))
+	}
+
+	var attribution *DecisionAttribution
+	if winningAction != "HOLD" {
+		attribution = attributeDecision(winningAction, ballot.Votes)
+	}
+
+	var metadata map[string]interface{}
+	if estimate != nil {
+		metadata = map[string]interface{}{
+			"regime":               estimate.Regime,
+			"regime_confidence":    estimate.Confidence,
+			"regime_probabilities": estimate.Probabilities,
+		}
 	}
 
 	return &TradingDecision{
 		Symbol:              ctx.Symbol,
 		Action:              winningAction,
-		Confidence:          confidence,
-		Consensus:           consensus,
-		ParticipatingAgents: participatingAgents,
-		VotingResults:       votingScores,
-		Reasoning:           fmt.Sprintf("Weighted voting: %v", reasoning),
+		Confidence:          tally.Confidence,
+		Consensus:           tally.Consensus,
+		ParticipatingAgents: len(ballot.Votes),
+		VotingResults:       tally.Scores,
+		Reasoning:           fmt.Sprintf("%s voting: %v", aggregator.Method(), reasoning),
 		Timestamp:           ctx.Timestamp,
+		Attribution:         attribution,
+		Metadata:            metadata,
+		VotingMethod:        aggregator.Method(),
 	}
 }
 
@@ -781,6 +952,11 @@ func (o *Orchestrator) publishWithRetry(topic string, data []byte, maxRetries in
 // Pause pauses all trading decision-making
 // Uses database-level locking to prevent race conditions and ensures atomic state updates
 func (o *Orchestrator) Pause() error {
+	return o.pause("api", "manual_pause")
+}
+
+// pause pauses trading on behalf of pausedBy
+func (o *Orchestrator) pause(pausedBy, reason string) error {
 	o.pausedMutex.Lock()
 	defer o.pausedMutex.Unlock()
 
@@ -795,7 +971,7 @@ func (o *Orchestrator) Pause() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		if err := o.db.SetOrchestratorPaused(ctx, "api", "manual_pause"); err != nil {
+		if err := o.db.SetOrchestratorPaused(ctx, pausedBy, reason); err != nil {
 			o.log.Error().Err(err).Msg("Failed to persist pause state to database")
 			// CRITICAL: If database write fails, do NOT update in-memory state
 			// This prevents divergence between DB and memory state
@@ -806,14 +982,16 @@ func (o *Orchestrator) Pause() error {
 
 	// Update in-memory state only after successful DB write
 	o.paused = true
-	o.log.Info().Msg("Trading paused")
+	o.pausedByName = pausedBy
+	o.log.Info().Str("paused_by", pausedBy).Str("reason", reason).Msg("Trading paused")
 
 	// Broadcast pause event to all agents via NATS with retry logic
 	if o.natsConn != nil {
 		pauseEvent := map[string]interface{}{
 			"event":     "trading_paused",
 			"timestamp": time.Now(),
-			"reason":    "manual_pause",
+			"paused_by": pausedBy,
+			"reason":    reason,
 		}
 
 		data, err := json.Marshal(pauseEvent)
@@ -838,9 +1016,20 @@ func (o *Orchestrator) Pause() error {
 	return nil
 }
 
-// Resume resumes trading decision-making
+// Resume resumes trading decision-making and resets the trading circuit breakers
 // Uses database-level locking to prevent race conditions and ensures atomic state updates
 func (o *Orchestrator) Resume() error {
+	if err := o.resume(); err != nil {
+		return err
+	}
+	if trip := o.tradingBreaker.Reset(time.Now()); trip != nil {
+		o.log.Info().Str("reason", string(trip.Reason)).Msg("Trading circuit breaker reset by manual resume")
+	}
+	return nil
+}
+
+// resume resumes trading without touching the trading circuit breakers
+func (o *Orchestrator) resume() error {
 	o.pausedMutex.Lock()
 	defer o.pausedMutex.Unlock()
 
@@ -866,6 +1055,7 @@ func (o *Orchestrator) Resume() error {
 
 	// Update in-memory state only after successful DB write
 	o.paused = false
+	o.pausedByName = ""
 	o.log.Info().Msg("Trading resumed")
 
 	// Broadcast resume event to all agents via NATS with retry logic
@@ -904,6 +1094,13 @@ func (o *Orchestrator) IsPaused() bool {
 	return o.paused
 }
 
+// pausedBy returns who paused trading, or "" when trading is active
+func (o *Orchestrator) pausedBy() string {
+	o.pausedMutex.RLock()
+	defer o.pausedMutex.RUnlock()
+	return o.pausedByName
+}
+
 // HTTP handlers for control endpoints (exported for use by cmd/orchestrator/http.go)
 
 // HandlePauseRequest handles POST /pause to pause trading
@@ -983,13 +1180,18 @@ func (o *Orchestrator) HandleControlStatusRequest(w http.ResponseWriter, r *http
 	activeAgents := len(o.agents)
 	o.agentsMutex.RUnlock()
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+	status := map[string]interface{}{
 		"paused":        isPaused,
 		"active_agents": activeAgents,
 		"timestamp":     time.Now().UTC().Format(time.RFC3339),
-	}); err != nil {
+	}
+	if trip := o.tradingBreaker.Trip(); trip != nil {
+		status["circuit_breaker"] = trip
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
 		o.log.Error().Err(err).Msg("Failed to encode status response")
 	}
 }
@@ -0,0 +1,89 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"github.com/ajitpratap0/cryptofunk/internal/regime"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// RegimeConfig runs the market regime service in the orchestrator and makes
+// voting weights conditional on the regime. Weights maps a regime to voting
+// weight multipliers by agent type (e.g. "volatile": {"trend": 0.5}); agent
+// types without one keep their weight.
+type RegimeConfig struct {
+	Service regime.ServiceConfig          `json:"service" yaml:",inline" mapstructure:",squash"`
+	Weights map[string]map[string]float64 `json:"weights" yaml:"weights" mapstructure:"weights"`
+}
+
+// regimeSource supplies the latest regime estimate of a symbol
+type regimeSource interface {
+	Latest(symbol string) *regime.Estimate
+}
+
+// startRegimeService starts the regime service when it is enabled and a
+// database is available
+func (o *Orchestrator) startRegimeService() {
+	if !o.config.Regime.Service.Enabled || o.db == nil {
+		return
+	}
+
+	var publisher regime.Publisher
+	if o.natsConn != nil {
+		publisher = o.natsConn
+	}
+	service := regime.NewService(o.config.Regime.Service, risk.NewCalculatorWithPool(o.db.Pool()), o.db, publisher)
+	o.regimes = service
+
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		service.Run(o.ctx)
+	}()
+}
+
+// regimeEstimate returns the latest regime estimate for a symbol, or nil
+func (o *Orchestrator) regimeEstimate(symbol string) *regime.Estimate {
+	if o.regimes == nil {
+		return nil
+	}
+	return o.regimes.Latest(symbol)
+}
+
+// regimeMultiplier is an agent type's voting weight multiplier averaged over
+// the regime probabilities
+func (o *Orchestrator) regimeMultiplier(estimate *regime.Estimate, agentType string) float64 {
+	if estimate == nil || len(o.config.Regime.Weights) == 0 {
+		return 1
+	}
+	multiplier := 0.0
+	for r, p := range estimate.Probabilities {
+		weight := 1.0
+		if weights, ok := o.config.Regime.Weights[string(r)]; ok {
+			if w, ok := weights[agentType]; ok {
+				weight = w
+			}
+		}
+		multiplier += p * weight
+	}
+	return multiplier
+}
+
+// validateRegimeWeights rejects weights for unknown regimes and negative weights
+func validateRegimeWeights(weights map[string]map[string]float64) error {
+	known := make(map[string]bool, len(regime.Regimes))
+	for _, r := range regime.Regimes {
+		known[string(r)] = true
+	}
+	for name, byType := range weights {
+		if !known[name] {
+			return fmt.Errorf("unknown regime %q in regime weights", name)
+		}
+		for agentType, w := range byType {
+			if w < 0 {
+				return fmt.Errorf("regime weight for %s in %s regime must not be negative", agentType, name)
+			}
+		}
+	}
+	return nil
+}
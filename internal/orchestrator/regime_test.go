@@ -0,0 +1,82 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/regime"
+)
+
+// fakeRegimeSource returns fixed estimates by symbol
+type fakeRegimeSource map[string]*regime.Estimate
+
+func (f fakeRegimeSource) Latest(symbol string) *regime.Estimate {
+	return f[symbol]
+}
+
+func TestNewOrchestrator_InvalidRegimeWeights(t *testing.T) {
+	_, err := NewOrchestrator(&OrchestratorConfig{Regime: RegimeConfig{
+		Weights: map[string]map[string]float64{"euphoric": {"trend": 2}},
+	}}, zerolog.Nop(), nil, 0)
+	assert.Error(t, err)
+
+	_, err = NewOrchestrator(&OrchestratorConfig{Regime: RegimeConfig{
+		Weights: map[string]map[string]float64{"volatile": {"trend": -1}},
+	}}, zerolog.Nop(), nil, 0)
+	assert.Error(t, err)
+}
+
+func TestCalculateDecision_RegimeConditionalWeights(t *testing.T) {
+	orch := newRiskBudgetTestOrchestrator(t, RiskBudgetConfig{}, &fakeRiskBudgetSource{})
+	orch.config.Regime.Weights = map[string]map[string]float64{
+		"volatile": {"trend": 0},
+		"sideways": {"reversion": 2},
+	}
+	orch.config.MinConsensus = 0
+	orch.config.MinConfidence = 0
+
+	signals := []AgentSignal{
+		{AgentName: "trend-agent", AgentType: "trend", Signal: "BUY", Confidence: 0.8},
+		{AgentName: "reversion-agent", AgentType: "reversion", Signal: "SELL", Confidence: 0.8},
+	}
+
+	// Without a regime the trend agent's larger weight wins
+	decision := decide(orch, signals...)
+	assert.Equal(t, "BUY", decision.Action)
+	assert.Nil(t, decision.Metadata)
+
+	// A sideways market doubles the reversion agent's weight
+	orch.regimes = fakeRegimeSource{"BTC/USDT": {
+		Regime:        regime.RegimeSideways,
+		Confidence:    1,
+		Probabilities: map[regime.Regime]float64{regime.RegimeSideways: 1},
+	}}
+	decision = decide(orch, signals...)
+	assert.Equal(t, "SELL", decision.Action)
+	assert.InDelta(t, 0.25*2*0.8, decision.VotingResults["SELL"], 1e-9)
+	assert.Equal(t, regime.RegimeSideways, decision.Metadata["regime"])
+
+	// Multipliers are averaged over the regime probabilities
+	orch.regimes = fakeRegimeSource{"BTC/USDT": {
+		Regime:        regime.RegimeVolatile,
+		Confidence:    0.75,
+		Probabilities: map[regime.Regime]float64{regime.RegimeVolatile: 0.75, regime.RegimeBullish: 0.25},
+	}}
+	decision = decide(orch, signals...)
+	assert.InDelta(t, 0.30*0.25*0.8, decision.VotingResults["BUY"], 1e-9)
+
+	// A certain volatile regime mutes the trend agent
+	orch.regimes = fakeRegimeSource{"BTC/USDT": {
+		Regime:        regime.RegimeVolatile,
+		Confidence:    1,
+		Probabilities: map[regime.Regime]float64{regime.RegimeVolatile: 1},
+	}}
+	decision = decide(orch, signals...)
+	assert.Equal(t, 1, decision.ParticipatingAgents)
+	assert.True(t, strings.Contains(decision.Reasoning, "trend-agent: muted in volatile regime"))
+	require.Equal(t, "SELL", decision.Action)
+}
@@ -0,0 +1,372 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ajitpratap0/cryptofunk/internal/alerts"
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+const (
+	// defaultRiskBudgetInterval is how often risk budgets are evaluated
+	defaultRiskBudgetInterval = time.Minute
+	// defaultRiskBudgetPeriod is how long closed positions count against budgets
+	defaultRiskBudgetPeriod = 24 * time.Hour
+	// VaR budgets use daily returns over the same lookback as the risk agent
+	budgetReturnsInterval = "1d"
+	budgetReturnsDays     = 90
+)
+
+// RiskBudgetConfig allocates risk budgets, in the quote currency, to agents by
+// name and to strategies by agent type (e.g. "trend"). Agents whose budget, or
+// whose strategy's budget, is used up lose their voting weight.
+type RiskBudgetConfig struct {
+	Metric        string             `json:"metric" yaml:"metric" mapstructure:"metric"`                         // capital_at_risk or var
+	Period        time.Duration      `json:"period" yaml:"period" mapstructure:"period"`                         // How long closed positions count
+	Interval      time.Duration      `json:"interval" yaml:"interval" mapstructure:"interval"`                   // How often budgets are evaluated
+	ThrottleStart float64            `json:"throttle_start" yaml:"throttle_start" mapstructure:"throttle_start"` // Utilization at which weights start shrinking
+	Agents        map[string]float64 `json:"agents" yaml:"agents" mapstructure:"agents"`
+	Strategies    map[string]float64 `json:"strategies" yaml:"strategies" mapstructure:"strategies"`
+}
+
+// budgets lists the configured budgets
+func (c RiskBudgetConfig) budgets() []risk.RiskBudget {
+	budgets := make([]risk.RiskBudget, 0, len(c.Agents)+len(c.Strategies))
+	for name, limit := range c.Agents {
+		budgets = append(budgets, risk.RiskBudget{Scope: risk.BudgetScopeAgent, Name: name, Limit: limit})
+	}
+	for name, limit := range c.Strategies {
+		budgets = append(budgets, risk.RiskBudget{Scope: risk.BudgetScopeStrategy, Name: name, Limit: limit})
+	}
+	return budgets
+}
+
+// period returns how long closed positions count against budgets
+func (c RiskBudgetConfig) period() time.Duration {
+	if c.Period <= 0 {
+		return defaultRiskBudgetPeriod
+	}
+	return c.Period
+}
+
+// throttleStart returns the utilization at which voting weights start shrinking
+func (c RiskBudgetConfig) throttleStart() float64 {
+	if c.ThrottleStart <= 0 || c.ThrottleStart >= 1 {
+		return risk.DefaultBudgetThrottleStart
+	}
+	return c.ThrottleStart
+}
+
+// DecisionAttribution is the share of a decision's winning vote owed to each
+// agent and strategy; the shares of each sum to 1
+type DecisionAttribution struct {
+	Agents     map[string]float64 `json:"agents"`
+	Strategies map[string]float64 `json:"strategies"`
+}
+
+// riskBudgetSource records decision attributions and supplies the attributed
+// positions and return history budgets are measured on
+type riskBudgetSource interface {
+	RecordAttribution(ctx context.Context, decision *TradingDecision) error
+	Positions(ctx context.Context, since time.Time) ([]risk.AttributedPosition, error)
+	Returns(ctx context.Context, symbols []string) (map[string][]float64, error)
+}
+
+// dbRiskBudgetSource keeps attributions in the database
+type dbRiskBudgetSource struct {
+	db         *db.DB
+	calculator *risk.Calculator
+}
+
+// RecordAttribution stores the attribution of a BUY or SELL decision
+func (s *dbRiskBudgetSource) RecordAttribution(ctx context.Context, decision *TradingDecision) error {
+	return s.db.InsertDecisionAttribution(ctx, &db.DecisionAttribution{
+		Symbol:     decision.Symbol,
+		Action:     db.ConvertOrderSide(decision.Action),
+		Agents:     decision.Attribution.Agents,
+		Strategies: decision.Attribution.Strategies,
+		Confidence: decision.Confidence,
+		DecidedAt:  decision.Timestamp,
+	})
+}
+
+// Positions returns the open positions and those closed since the given time,
+// attributed to the decisions that opened them
+func (s *dbRiskBudgetSource) Positions(ctx context.Context, since time.Time) ([]risk.AttributedPosition, error) {
+	rows, err := s.db.ListAttributedPositions(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	positions := make([]risk.AttributedPosition, 0, len(rows))
+	for _, row := range rows {
+		positions = append(positions, attributedPosition(row))
+	}
+	return positions, nil
+}
+
+// Returns loads aligned daily returns of the symbols
+func (s *dbRiskBudgetSource) Returns(ctx context.Context, symbols []string) (map[string][]float64, error) {
+	positions := make([]risk.PortfolioPosition, len(symbols))
+	for i, symbol := range symbols {
+		positions[i] = risk.PortfolioPosition{Symbol: symbol}
+	}
+	portfolio, err := s.calculator.LoadPortfolioReturns(ctx, positions, budgetReturnsInterval, budgetReturnsDays)
+	if err != nil {
+		return nil, err
+	}
+	returns := make(map[string][]float64, len(portfolio.Positions))
+	for _, position := range portfolio.Positions {
+		returns[position.Symbol] = position.Returns
+	}
+	return returns, nil
+}
+
+// attributedPosition values an open position at the price implied by its
+// unrealized P&L. Capital at risk is the distance to the stop, or the whole
+// value without one.
+func attributedPosition(row *db.AttributedPosition) risk.AttributedPosition {
+	position := risk.AttributedPosition{
+		Symbol:        row.Symbol,
+		RealizedPnL:   row.RealizedPnL,
+		UnrealizedPnL: row.UnrealizedPnL,
+		Agents:        row.Agents,
+		Strategies:    row.Strategies,
+	}
+	if !row.Open || row.Quantity <= 0 {
+		return position
+	}
+
+	direction := 1.0
+	if row.Side == db.PositionSideShort {
+		direction = -1.0
+	}
+	price := row.EntryPrice + direction*row.UnrealizedPnL/row.Quantity
+	position.Value = direction * row.Quantity * price
+	position.CapitalAtRisk = math.Abs(position.Value)
+	if row.StopLoss != nil && *row.StopLoss > 0 {
+		position.CapitalAtRisk = row.Quantity * math.Max(0, direction*(price-*row.StopLoss))
+	}
+	return position
+}
+
+// attributeDecision splits the winning vote of a decision among the agents and
+// strategies that cast it, in proportion to weight times confidence
+func attributeDecision(action string, votes []Vote) *DecisionAttribution {
+	total := 0.0
+	for _, vote := range votes {
+		if vote.Action == action {
+			total += vote.Weight * vote.Confidence
+		}
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	attribution := &DecisionAttribution{
+		Agents:     make(map[string]float64),
+		Strategies: make(map[string]float64),
+	}
+	for _, vote := range votes {
+		if vote.Action == action {
+			share := vote.Weight * vote.Confidence / total
+			attribution.Agents[vote.Agent] += share
+			attribution.Strategies[vote.AgentType] += share
+		}
+	}
+	return attribution
+}
+
+// budgetKey identifies a budget in the throttle table
+func budgetKey(scope risk.BudgetScope, name string) string {
+	return string(scope) + "/" + name
+}
+
+// budgetThrottle returns the voting weight multiplier of an agent: the lower of
+// its own and its strategy's budget throttles
+func (o *Orchestrator) budgetThrottle(agent, agentType string) float64 {
+	o.budgetsMutex.RLock()
+	defer o.budgetsMutex.RUnlock()
+
+	throttle := 1.0
+	if value, ok := o.budgetThrottles[budgetKey(risk.BudgetScopeAgent, agent)]; ok {
+		throttle = math.Min(throttle, value)
+	}
+	if value, ok := o.budgetThrottles[budgetKey(risk.BudgetScopeStrategy, agentType)]; ok {
+		throttle = math.Min(throttle, value)
+	}
+	return throttle
+}
+
+// recordAttribution stores the attribution of a published decision
+func (o *Orchestrator) recordAttribution(decision *TradingDecision) {
+	if o.budgetSource == nil || decision.Attribution == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := o.budgetSource.RecordAttribution(ctx, decision); err != nil {
+		o.log.Warn().Err(err).Str("symbol", decision.Symbol).Msg("Failed to record decision attribution")
+	}
+}
+
+// riskBudgetLoop periodically evaluates the risk budgets
+func (o *Orchestrator) riskBudgetLoop() {
+	defer o.wg.Done()
+
+	interval := o.config.RiskBudgets.Interval
+	if interval <= 0 {
+		interval = defaultRiskBudgetInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			o.checkRiskBudgets(o.ctx)
+		}
+	}
+}
+
+// checkRiskBudgets measures each agent and strategy against its budget and
+// updates the voting weight throttles
+func (o *Orchestrator) checkRiskBudgets(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	positions, err := o.budgetSource.Positions(ctx, now.Add(-o.config.RiskBudgets.period()))
+	if err != nil {
+		o.log.Warn().Err(err).Msg("Failed to load attributed positions for risk budgets")
+		return
+	}
+
+	var returns map[string][]float64
+	if o.budgetMetric == risk.BudgetMetricVaR {
+		if symbols := openSymbols(positions); len(symbols) > 0 {
+			if returns, err = o.budgetSource.Returns(ctx, symbols); err != nil {
+				o.log.Warn().Err(err).Msg("Failed to load returns for VaR budgets, using capital at risk")
+			}
+		}
+	}
+
+	usages, err := risk.EvaluateRiskBudgets(o.config.RiskBudgets.budgets(), positions, returns, risk.BudgetOptions{
+		Metric:        o.budgetMetric,
+		ThrottleStart: o.config.RiskBudgets.throttleStart(),
+	})
+	if err != nil {
+		o.log.Warn().Err(err).Msg("Failed to evaluate risk budgets")
+		return
+	}
+	risk.RecordBudgetUsage(usages)
+
+	throttles := make(map[string]float64)
+	for _, usage := range usages {
+		if usage.Limit > 0 {
+			throttles[budgetKey(usage.Scope, usage.Name)] = usage.Throttle
+		}
+	}
+
+	o.budgetsMutex.Lock()
+	previous := o.budgetThrottles
+	o.budgetThrottles = throttles
+	o.budgetUsage = usages
+	o.budgetEvaluatedAt = now
+	o.budgetsMutex.Unlock()
+
+	for _, usage := range usages {
+		if usage.Limit <= 0 {
+			continue
+		}
+		before, ok := previous[budgetKey(usage.Scope, usage.Name)]
+		if !ok {
+			before = 1
+		}
+
+		switch {
+		case usage.Exhausted() && before > 0:
+			o.log.Warn().
+				Str("scope", string(usage.Scope)).
+				Str("name", usage.Name).
+				Float64("used", usage.Used).
+				Float64("limit", usage.Limit).
+				Msg("Risk budget exhausted, voting weight zeroed")
+			_ = alerts.GetDefaultManager().SendWarning(ctx, "Risk Budget Exhausted",
+				fmt.Sprintf("%s %s used %.2f of its %.2f risk budget; its votes are ignored until risk is released",
+					usage.Scope, usage.Name, usage.Used, usage.Limit),
+				map[string]interface{}{
+					"scope": usage.Scope,
+					"name":  usage.Name,
+					"used":  usage.Used,
+					"limit": usage.Limit,
+				})
+		case !usage.Exhausted() && before == 0:
+			o.log.Info().
+				Str("scope", string(usage.Scope)).
+				Str("name", usage.Name).
+				Float64("throttle", usage.Throttle).
+				Msg("Risk budget released, voting weight restored")
+		}
+	}
+}
+
+// openSymbols lists the symbols of open positions
+func openSymbols(positions []risk.AttributedPosition) []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	for _, position := range positions {
+		if position.Value != 0 && !seen[position.Symbol] {
+			seen[position.Symbol] = true
+			symbols = append(symbols, position.Symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// RiskBudgets returns the latest budget usage of each agent and strategy and
+// when it was evaluated
+func (o *Orchestrator) RiskBudgets() ([]risk.BudgetUsage, time.Time) {
+	o.budgetsMutex.RLock()
+	defer o.budgetsMutex.RUnlock()
+	return append([]risk.BudgetUsage(nil), o.budgetUsage...), o.budgetEvaluatedAt
+}
+
+// HandleRiskBudgetsRequest handles GET /api/v1/risk-budgets
+func (o *Orchestrator) HandleRiskBudgetsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	usages, evaluatedAt := o.RiskBudgets()
+	if usages == nil {
+		usages = []risk.BudgetUsage{}
+	}
+	response := map[string]interface{}{
+		"enabled":        o.budgetSource != nil,
+		"metric":         o.budgetMetric,
+		"period":         o.config.RiskBudgets.period().String(),
+		"throttle_start": o.config.RiskBudgets.throttleStart(),
+		"budgets":        usages,
+	}
+	if !evaluatedAt.IsZero() {
+		response["evaluated_at"] = evaluatedAt.UTC().Format(time.RFC3339)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		o.log.Error().Err(err).Msg("Failed to encode risk budgets response")
+	}
+}
@@ -0,0 +1,249 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// fakeRiskBudgetSource returns fixed positions and records attributions
+type fakeRiskBudgetSource struct {
+	positions    []risk.AttributedPosition
+	returns      map[string][]float64
+	attributions []*TradingDecision
+}
+
+func (f *fakeRiskBudgetSource) RecordAttribution(ctx context.Context, decision *TradingDecision) error {
+	f.attributions = append(f.attributions, decision)
+	return nil
+}
+
+func (f *fakeRiskBudgetSource) Positions(ctx context.Context, since time.Time) ([]risk.AttributedPosition, error) {
+	return f.positions, nil
+}
+
+func (f *fakeRiskBudgetSource) Returns(ctx context.Context, symbols []string) (map[string][]float64, error) {
+	return f.returns, nil
+}
+
+func newRiskBudgetTestOrchestrator(t *testing.T, budgets RiskBudgetConfig, source *fakeRiskBudgetSource) *Orchestrator {
+	config := &OrchestratorConfig{
+		Name:                "test-orchestrator",
+		StepInterval:        30 * time.Second,
+		MinConsensus:        0.5,
+		MinConfidence:       0.5,
+		MaxSignalAge:        5 * time.Minute,
+		HealthCheckInterval: time.Minute,
+		RiskBudgets:         budgets,
+	}
+	orch, err := NewOrchestrator(config, zerolog.Nop(), nil, 0)
+	require.NoError(t, err)
+	orch.budgetSource = source
+	return orch
+}
+
+// decide registers the signals' agents and runs one vote
+func decide(orch *Orchestrator, signals ...AgentSignal) *TradingDecision {
+	pointers := make([]*AgentSignal, len(signals))
+	for i := range signals {
+		signals[i].Symbol = "BTC/USDT"
+		signals[i].Timestamp = time.Now()
+		orch.updateAgentSession(signals[i].AgentName, signals[i].AgentType, &signals[i])
+		pointers[i] = &signals[i]
+	}
+	return orch.calculateDecision(&DecisionContext{
+		Symbol:        "BTC/USDT",
+		Signals:       pointers,
+		Timestamp:     time.Now(),
+		MinConsensus:  orch.config.MinConsensus,
+		MinConfidence: orch.config.MinConfidence,
+	})
+}
+
+func TestNewOrchestrator_InvalidBudgetMetric(t *testing.T) {
+	_, err := NewOrchestrator(&OrchestratorConfig{RiskBudgets: RiskBudgetConfig{Metric: "notional"}}, zerolog.Nop(), nil, 0)
+	assert.Error(t, err)
+}
+
+func TestCalculateDecision_Attribution(t *testing.T) {
+	orch := newRiskBudgetTestOrchestrator(t, RiskBudgetConfig{}, &fakeRiskBudgetSource{})
+
+	decision := decide(orch,
+		AgentSignal{AgentName: "trend-agent", AgentType: "trend", Signal: "BUY", Confidence: 0.8},
+		AgentSignal{AgentName: "technical-agent", AgentType: "technical", Signal: "BUY", Confidence: 0.8},
+		AgentSignal{AgentName: "sentiment-agent", AgentType: "sentiment", Signal: "SELL", Confidence: 0.2},
+	)
+	require.Equal(t, "BUY", decision.Action)
+	require.NotNil(t, decision.Attribution)
+
+	// Votes: trend 0.30×0.8, technical 0.25×0.8; the SELL voter gets nothing
+	assert.InDelta(t, 0.30/0.55, decision.Attribution.Agents["trend-agent"], 1e-9)
+	assert.InDelta(t, 0.25/0.55, decision.Attribution.Agents["technical-agent"], 1e-9)
+	assert.NotContains(t, decision.Attribution.Agents, "sentiment-agent")
+	assert.InDelta(t, 0.30/0.55, decision.Attribution.Strategies["trend"], 1e-9)
+
+	// HOLD decisions open nothing to attribute
+	hold := decide(orch, AgentSignal{AgentName: "trend-agent", AgentType: "trend", Signal: "HOLD", Confidence: 0.9})
+	assert.Nil(t, hold.Attribution)
+}
+
+func TestCheckRiskBudgets_ThrottlesWeights(t *testing.T) {
+	source := &fakeRiskBudgetSource{positions: []risk.AttributedPosition{{
+		Symbol: "BTC/USDT", Value: 10000, CapitalAtRisk: 900,
+		Agents:     map[string]float64{"trend-agent": 1},
+		Strategies: map[string]float64{"trend": 1},
+	}}}
+	orch := newRiskBudgetTestOrchestrator(t, RiskBudgetConfig{
+		ThrottleStart: 0.8,
+		Agents:        map[string]float64{"trend-agent": 1000},
+		Strategies:    map[string]float64{"trend": 5000},
+	}, source)
+
+	orch.checkRiskBudgets(context.Background())
+	assert.InDelta(t, 0.5, orch.budgetThrottle("trend-agent", "trend"), 1e-9)
+	assert.Equal(t, 1.0, orch.budgetThrottle("technical-agent", "technical"))
+
+	usages, evaluatedAt := orch.RiskBudgets()
+	assert.Len(t, usages, 2)
+	assert.False(t, evaluatedAt.IsZero())
+
+	// A realized loss uses up the rest of the budget
+	source.positions = append(source.positions, risk.AttributedPosition{
+		Symbol: "ETH/USDT", RealizedPnL: -200,
+		Agents:     map[string]float64{"trend-agent": 1},
+		Strategies: map[string]float64{"trend": 1},
+	})
+	orch.checkRiskBudgets(context.Background())
+	assert.Equal(t, 0.0, orch.budgetThrottle("trend-agent", "trend"))
+
+	decision := decide(orch,
+		AgentSignal{AgentName: "trend-agent", AgentType: "trend", Signal: "BUY", Confidence: 0.9},
+		AgentSignal{AgentName: "technical-agent", AgentType: "technical", Signal: "SELL", Confidence: 0.9},
+	)
+	assert.Equal(t, "SELL", decision.Action)
+	assert.Equal(t, 1, decision.ParticipatingAgents)
+	assert.Contains(t, decision.Reasoning, "trend-agent: risk budget exhausted")
+	assert.Equal(t, map[string]float64{"technical-agent": 1}, decision.Attribution.Agents)
+
+	// Every agent excluded: HOLD with zero confidence rather than NaN
+	hold := decide(orch, AgentSignal{AgentName: "trend-agent", AgentType: "trend", Signal: "BUY", Confidence: 0.9})
+	assert.Equal(t, "HOLD", hold.Action)
+	assert.Zero(t, hold.Confidence)
+	_, err := json.Marshal(hold)
+	assert.NoError(t, err)
+
+	// Releasing the risk restores the weight
+	source.positions = nil
+	orch.checkRiskBudgets(context.Background())
+	assert.Equal(t, 1.0, orch.budgetThrottle("trend-agent", "trend"))
+}
+
+func TestCheckRiskBudgets_StrategyBudget(t *testing.T) {
+	source := &fakeRiskBudgetSource{positions: []risk.AttributedPosition{{
+		Symbol: "BTC/USDT", RealizedPnL: -600,
+		Agents:     map[string]float64{"reversion-agent": 1},
+		Strategies: map[string]float64{"reversion": 1},
+	}}}
+	orch := newRiskBudgetTestOrchestrator(t, RiskBudgetConfig{
+		Strategies: map[string]float64{"reversion": 500},
+	}, source)
+
+	orch.checkRiskBudgets(context.Background())
+
+	// Every agent of the exhausted strategy loses its vote
+	assert.Equal(t, 0.0, orch.budgetThrottle("reversion-agent", "reversion"))
+	assert.Equal(t, 0.0, orch.budgetThrottle("other-reversion-agent", "reversion"))
+	assert.Equal(t, 1.0, orch.budgetThrottle("trend-agent", "trend"))
+}
+
+func TestRecordAttribution(t *testing.T) {
+	source := &fakeRiskBudgetSource{}
+	orch := newRiskBudgetTestOrchestrator(t, RiskBudgetConfig{}, source)
+
+	orch.recordAttribution(&TradingDecision{Symbol: "BTC/USDT", Action: "HOLD"})
+	assert.Empty(t, source.attributions)
+
+	decision := &TradingDecision{
+		Symbol:      "BTC/USDT",
+		Action:      "BUY",
+		Attribution: &DecisionAttribution{Agents: map[string]float64{"trend-agent": 1}},
+	}
+	orch.recordAttribution(decision)
+	assert.Equal(t, []*TradingDecision{decision}, source.attributions)
+}
+
+func TestAttributedPosition(t *testing.T) {
+	stop := 95.0
+	agents := map[string]float64{"trend-agent": 1}
+
+	// Long 2 @ 100, up $10: priced at 105 with $20 to the stop
+	long := attributedPosition(&db.AttributedPosition{
+		Symbol: "BTC/USDT", Side: db.PositionSideLong, Quantity: 2, EntryPrice: 100,
+		StopLoss: &stop, UnrealizedPnL: 10, Open: true, Agents: agents,
+	})
+	assert.InDelta(t, 210, long.Value, 1e-9)
+	assert.InDelta(t, 20, long.CapitalAtRisk, 1e-9)
+	assert.Equal(t, agents, long.Agents)
+
+	// Short 2 @ 100, down $10 and without a stop: all of it is at risk
+	short := attributedPosition(&db.AttributedPosition{
+		Symbol: "BTC/USDT", Side: db.PositionSideShort, Quantity: 2, EntryPrice: 100,
+		UnrealizedPnL: -10, Open: true,
+	})
+	assert.InDelta(t, -210, short.Value, 1e-9)
+	assert.InDelta(t, 210, short.CapitalAtRisk, 1e-9)
+
+	closed := attributedPosition(&db.AttributedPosition{
+		Symbol: "BTC/USDT", Side: db.PositionSideLong, Quantity: 2, EntryPrice: 100, RealizedPnL: -30,
+	})
+	assert.Zero(t, closed.Value)
+	assert.Zero(t, closed.CapitalAtRisk)
+	assert.Equal(t, -30.0, closed.RealizedPnL)
+}
+
+func TestHandleRiskBudgetsRequest(t *testing.T) {
+	source := &fakeRiskBudgetSource{positions: []risk.AttributedPosition{{
+		Symbol: "BTC/USDT", Value: 1000, CapitalAtRisk: 100,
+		Agents: map[string]float64{"trend-agent": 1},
+	}}}
+	orch := newRiskBudgetTestOrchestrator(t, RiskBudgetConfig{
+		Period: 12 * time.Hour,
+		Agents: map[string]float64{"trend-agent": 1000},
+	}, source)
+	orch.checkRiskBudgets(context.Background())
+
+	recorder := httptest.NewRecorder()
+	orch.HandleRiskBudgetsRequest(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/risk-budgets", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response struct {
+		Enabled       bool               `json:"enabled"`
+		Metric        string             `json:"metric"`
+		Period        string             `json:"period"`
+		ThrottleStart float64            `json:"throttle_start"`
+		Budgets       []risk.BudgetUsage `json:"budgets"`
+		EvaluatedAt   string             `json:"evaluated_at"`
+	}
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&response))
+	assert.True(t, response.Enabled)
+	assert.Equal(t, "capital_at_risk", response.Metric)
+	assert.Equal(t, "12h0m0s", response.Period)
+	assert.Equal(t, risk.DefaultBudgetThrottleStart, response.ThrottleStart)
+	require.Len(t, response.Budgets, 1)
+	assert.InDelta(t, 0.1, response.Budgets[0].Utilization, 1e-9)
+	assert.NotEmpty(t, response.EvaluatedAt)
+
+	recorder = httptest.NewRecorder()
+	orch.HandleRiskBudgetsRequest(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/risk-budgets", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
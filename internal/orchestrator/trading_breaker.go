@@ -0,0 +1,185 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ajitpratap0/cryptofunk/internal/alerts"
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+const (
+	// pausedByCircuitBreaker marks pauses the trading breaker may lift on its own
+	pausedByCircuitBreaker = "circuit_breaker"
+	// defaultCircuitBreakerInterval is how often circuit breakers are evaluated
+	defaultCircuitBreakerInterval = time.Minute
+)
+
+// tradingBreakerSource supplies the active strategy's circuit breaker limits
+// and recent trading activity
+type tradingBreakerSource interface {
+	Limits(ctx context.Context) (risk.TradingBreakerLimits, error)
+	Activity(ctx context.Context, now time.Time) (risk.TradingActivity, error)
+}
+
+// dbTradingBreakerSource reads limits and activity from the database
+type dbTradingBreakerSource struct {
+	db         *db.DB
+	calculator *risk.Calculator
+}
+
+// Limits returns the circuit breakers of the active strategy
+func (s *dbTradingBreakerSource) Limits(ctx context.Context) (risk.TradingBreakerLimits, error) {
+	active, err := db.NewStrategyRepository(s.db).GetActive(ctx)
+	if err != nil {
+		return risk.TradingBreakerLimits{}, fmt.Errorf("failed to load active strategy: %w", err)
+	}
+
+	breakers := active.Risk.CircuitBreakers
+	return risk.TradingBreakerLimits{
+		Enabled:             breakers.Enabled,
+		MaxTradesPerHour:    breakers.MaxTradesPerHour,
+		MaxLossesPerDay:     breakers.MaxLossesPerDay,
+		VolatilityThreshold: breakers.VolatilityThreshold,
+		DrawdownHalt:        breakers.DrawdownHalt,
+	}, nil
+}
+
+// Activity counts recent trades and losses, and measures the equity of the
+// active sessions and the realized volatility of their symbols over a day of
+// hourly candles
+func (s *dbTradingBreakerSource) Activity(ctx context.Context, now time.Time) (risk.TradingActivity, error) {
+	var activity risk.TradingActivity
+	var err error
+
+	if activity.TradesLastHour, err = s.db.CountTradesSince(ctx, now.Add(-time.Hour)); err != nil {
+		return activity, err
+	}
+	midnight := now.UTC().Truncate(24 * time.Hour)
+	if activity.LossesToday, err = s.db.CountLosingPositionsSince(ctx, midnight); err != nil {
+		return activity, err
+	}
+
+	sessions, err := s.db.ListActiveSessions(ctx)
+	if err != nil {
+		return activity, err
+	}
+	if len(sessions) == 0 {
+		return activity, nil
+	}
+
+	since := sessions[0].StartedAt
+	symbols := make(map[string]bool, len(sessions))
+	for _, session := range sessions {
+		activity.Equity += session.InitialCapital
+		if session.StartedAt.Before(since) {
+			since = session.StartedAt
+		}
+		symbols[session.Symbol] = true
+	}
+	pnl, err := s.db.GetPnLSince(ctx, since)
+	if err != nil {
+		return activity, err
+	}
+	activity.Equity += pnl
+
+	for symbol := range symbols {
+		prices, err := s.calculator.LoadHistoricalPrices(ctx, symbol, "1h", 1)
+		if err != nil {
+			continue // No recent candles; volatility is unknown rather than high
+		}
+		if volatility := risk.RealizedDailyVolatility(prices.Returns, 24); volatility > activity.Volatility {
+			activity.Volatility = volatility
+		}
+	}
+
+	return activity, nil
+}
+
+// tradingBreakerLoop periodically evaluates the trading circuit breakers
+func (o *Orchestrator) tradingBreakerLoop() {
+	defer o.wg.Done()
+
+	interval := o.config.CircuitBreakerInterval
+	if interval <= 0 {
+		interval = defaultCircuitBreakerInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			o.checkTradingBreaker(o.ctx)
+		}
+	}
+}
+
+// checkTradingBreaker pauses trading when a circuit breaker trips, and resumes
+// it when the breaker resets a pause it caused
+func (o *Orchestrator) checkTradingBreaker(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	limits, err := o.breakerSource.Limits(ctx)
+	if err != nil {
+		o.log.Debug().Err(err).Msg("No circuit breaker limits, skipping trading breaker")
+		return
+	}
+
+	now := time.Now()
+	activity, err := o.breakerSource.Activity(ctx, now)
+	if err != nil {
+		o.log.Warn().Err(err).Msg("Failed to load trading activity for circuit breakers")
+		return
+	}
+
+	tripped, reset := o.tradingBreaker.Evaluate(now, limits, activity)
+	if tripped != nil {
+		o.log.Warn().
+			Str("reason", string(tripped.Reason)).
+			Float64("value", tripped.Value).
+			Float64("limit", tripped.Limit).
+			Msg("Trading circuit breaker tripped")
+
+		if err := o.pause(pausedByCircuitBreaker, string(tripped.Reason)); err != nil {
+			o.log.Warn().Err(err).Msg("Trading circuit breaker could not pause trading")
+		}
+
+		metadata := map[string]interface{}{
+			"reason": tripped.Reason,
+			"value":  tripped.Value,
+			"limit":  tripped.Limit,
+		}
+		message := tripped.Message + "; trading paused until manually resumed"
+		if tripped.ResetAt != nil {
+			metadata["reset_at"] = tripped.ResetAt.UTC().Format(time.RFC3339)
+			message = fmt.Sprintf("%s; trading paused until %s", tripped.Message, tripped.ResetAt.UTC().Format(time.RFC3339))
+		}
+		_ = alerts.GetDefaultManager().SendCritical(ctx, "Trading Circuit Breaker Tripped", message, metadata)
+	}
+
+	if reset != nil {
+		o.log.Info().Str("reason", string(reset.Reason)).Msg("Trading circuit breaker reset")
+
+		// Only lift pauses the breaker caused; operator pauses stay in place
+		if o.pausedBy() != pausedByCircuitBreaker {
+			return
+		}
+		if err := o.resume(); err != nil {
+			o.log.Warn().Err(err).Msg("Trading circuit breaker could not resume trading")
+			return
+		}
+		_ = alerts.GetDefaultManager().SendInfo(ctx, "Trading Circuit Breaker Reset",
+			fmt.Sprintf("%s cleared; trading resumed", reset.Reason), map[string]interface{}{"reason": reset.Reason})
+	}
+}
+
+// TradingBreakerTrip returns the active trading circuit breaker trip, or nil
+func (o *Orchestrator) TradingBreakerTrip() *risk.TradingBreakerTrip {
+	return o.tradingBreaker.Trip()
+}
@@ -0,0 +1,110 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// fakeTradingBreakerSource returns fixed limits and activity
+type fakeTradingBreakerSource struct {
+	limits   risk.TradingBreakerLimits
+	activity risk.TradingActivity
+}
+
+func (f *fakeTradingBreakerSource) Limits(ctx context.Context) (risk.TradingBreakerLimits, error) {
+	return f.limits, nil
+}
+
+func (f *fakeTradingBreakerSource) Activity(ctx context.Context, now time.Time) (risk.TradingActivity, error) {
+	return f.activity, nil
+}
+
+func newTradingBreakerTestOrchestrator(t *testing.T, source *fakeTradingBreakerSource) *Orchestrator {
+	config := &OrchestratorConfig{
+		Name:                   "test-orchestrator",
+		StepInterval:           30 * time.Second,
+		MaxSignalAge:           5 * time.Minute,
+		HealthCheckInterval:    time.Minute,
+		CircuitBreakerCooldown: time.Nanosecond, // Trips may reset on the next check
+	}
+	orch, err := NewOrchestrator(config, zerolog.Nop(), nil, 0)
+	require.NoError(t, err)
+	orch.breakerSource = source
+	return orch
+}
+
+func TestCheckTradingBreaker_PausesAndResumes(t *testing.T) {
+	source := &fakeTradingBreakerSource{
+		limits:   risk.TradingBreakerLimits{Enabled: true, MaxTradesPerHour: 5},
+		activity: risk.TradingActivity{TradesLastHour: 6},
+	}
+	orch := newTradingBreakerTestOrchestrator(t, source)
+	ctx := context.Background()
+
+	orch.checkTradingBreaker(ctx)
+	assert.True(t, orch.IsPaused())
+	assert.Equal(t, pausedByCircuitBreaker, orch.pausedBy())
+	require.NotNil(t, orch.TradingBreakerTrip())
+	assert.Equal(t, risk.TripReasonTradeRate, orch.TradingBreakerTrip().Reason)
+
+	// Still trading too fast: stays paused
+	orch.checkTradingBreaker(ctx)
+	assert.True(t, orch.IsPaused())
+
+	source.activity.TradesLastHour = 1
+	orch.checkTradingBreaker(ctx)
+	assert.False(t, orch.IsPaused())
+	assert.Nil(t, orch.TradingBreakerTrip())
+}
+
+func TestCheckTradingBreaker_KeepsOperatorPause(t *testing.T) {
+	source := &fakeTradingBreakerSource{
+		limits:   risk.TradingBreakerLimits{Enabled: true, MaxTradesPerHour: 5},
+		activity: risk.TradingActivity{TradesLastHour: 6},
+	}
+	orch := newTradingBreakerTestOrchestrator(t, source)
+	ctx := context.Background()
+
+	require.NoError(t, orch.Pause())
+	orch.checkTradingBreaker(ctx)
+	require.NotNil(t, orch.TradingBreakerTrip())
+
+	// The breaker resets but the operator's pause stays
+	source.activity.TradesLastHour = 0
+	orch.checkTradingBreaker(ctx)
+	assert.Nil(t, orch.TradingBreakerTrip())
+	assert.True(t, orch.IsPaused())
+	assert.Equal(t, "api", orch.pausedBy())
+}
+
+func TestResume_ResetsTradingBreaker(t *testing.T) {
+	source := &fakeTradingBreakerSource{
+		limits:   risk.TradingBreakerLimits{Enabled: true, DrawdownHalt: 0.1},
+		activity: risk.TradingActivity{Equity: 10000},
+	}
+	orch := newTradingBreakerTestOrchestrator(t, source)
+	ctx := context.Background()
+
+	orch.checkTradingBreaker(ctx)
+	source.activity.Equity = 8500
+	orch.checkTradingBreaker(ctx)
+	require.True(t, orch.IsPaused())
+	assert.Equal(t, risk.TripReasonDrawdown, orch.TradingBreakerTrip().Reason)
+
+	// Drawdown halts only lift when an operator resumes
+	source.activity.Equity = 9000
+	orch.checkTradingBreaker(ctx)
+	assert.True(t, orch.IsPaused())
+
+	require.NoError(t, orch.Resume())
+	assert.Nil(t, orch.TradingBreakerTrip())
+	orch.checkTradingBreaker(ctx)
+	assert.False(t, orch.IsPaused())
+}
@@ -0,0 +1,417 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// VotingMethod names a way of aggregating agent votes into a decision
+type VotingMethod string
+
+const (
+	VotingWeightedConsensus VotingMethod = "weighted_consensus" // Confidence-weighted vote shares
+	VotingMajority          VotingMethod = "majority"           // One agent, one vote; needs a strict majority
+	VotingBorda             VotingMethod = "borda"              // Weighted Borda count over BUY > HOLD > SELL rankings
+	VotingBayesian          VotingMethod = "bayesian"           // Summed log-odds of a rising price
+)
+
+// VotingMethods lists the supported voting methods
+var VotingMethods = []VotingMethod{VotingWeightedConsensus, VotingMajority, VotingBorda, VotingBayesian}
+
+// voteActions is the order scores are reported and ties are broken in
+var voteActions = []string{"BUY", "SELL", "HOLD"}
+
+// Vote is one agent's vote in a decision. Weight is the agent's voting weight
+// after risk budget and regime adjustments.
+type Vote struct {
+	Agent      string
+	AgentType  string
+	Action     string // BUY, SELL, HOLD
+	Weight     float64
+	Confidence float64 // 0.0-1.0
+}
+
+// Ballot holds the votes cast on a symbol in one decision cycle
+type Ballot struct {
+	Votes    []Vote
+	Eligible int // Enabled agents that could have voted
+}
+
+// Tally is the outcome of a vote. Consensus and Confidence are compared with
+// the orchestrator's thresholds; Scores become the decision's voting results.
+type Tally struct {
+	Action     string
+	Scores     map[string]float64
+	Consensus  float64
+	Confidence float64
+	Reason     string // Why the aggregator held, when it overrode the vote
+}
+
+// VoteAggregator turns a ballot into a tally
+type VoteAggregator interface {
+	Method() VotingMethod
+	Aggregate(ballot Ballot) Tally
+}
+
+// VotingSettings selects the voting method and the gates applied to it
+type VotingSettings struct {
+	Method     VotingMethod
+	MinVotes   int      // Fewer votes hold
+	Quorum     float64  // Lower participation of enabled agents holds (0.0-1.0)
+	VetoAgents []string // Agent types that can block BUY and SELL decisions
+}
+
+// NewVoteAggregator builds the aggregator of a voting method, wrapped in a veto
+// and a quorum gate when the settings ask for them
+func NewVoteAggregator(settings VotingSettings) (VoteAggregator, error) {
+	var aggregator VoteAggregator
+	switch settings.Method {
+	case VotingWeightedConsensus, "":
+		aggregator = weightedConsensus{}
+	case VotingMajority:
+		aggregator = majorityVote{}
+	case VotingBorda:
+		aggregator = bordaCount{}
+	case VotingBayesian:
+		aggregator = bayesianVote{}
+	default:
+		return nil, fmt.Errorf("unknown voting method %q, expected one of %v", settings.Method, VotingMethods)
+	}
+	if settings.MinVotes < 0 {
+		return nil, fmt.Errorf("min votes must not be negative, got %d", settings.MinVotes)
+	}
+	if settings.Quorum < 0 || settings.Quorum > 1 {
+		return nil, fmt.Errorf("quorum must be between 0 and 1, got %.2f", settings.Quorum)
+	}
+
+	if len(settings.VetoAgents) > 0 {
+		vetoers := make(map[string]bool, len(settings.VetoAgents))
+		for _, agentType := range settings.VetoAgents {
+			vetoers[agentType] = true
+		}
+		aggregator = vetoGate{VoteAggregator: aggregator, vetoers: vetoers}
+	}
+	if settings.MinVotes > 0 || settings.Quorum > 0 {
+		aggregator = quorumGate{VoteAggregator: aggregator, minVotes: settings.MinVotes, quorum: settings.Quorum}
+	}
+	return aggregator, nil
+}
+
+// newScores returns zeroed scores for every action
+func newScores() map[string]float64 {
+	scores := make(map[string]float64, len(voteActions))
+	for _, action := range voteActions {
+		scores[action] = 0
+	}
+	return scores
+}
+
+// leader returns the highest scoring action. Ties and empty votes hold.
+func leader(scores map[string]float64) string {
+	best, bestScore, tied := "HOLD", 0.0, false
+	for _, action := range voteActions {
+		switch score := scores[action]; {
+		case score > bestScore:
+			best, bestScore, tied = action, score, false
+		case score == bestScore && score > 0:
+			tied = true
+		}
+	}
+	if tied {
+		return "HOLD"
+	}
+	return best
+}
+
+// agreement is the share of voting weight cast for an action
+func agreement(votes []Vote, action string) float64 {
+	agreeing, total := 0.0, 0.0
+	for _, vote := range votes {
+		total += vote.Weight
+		if vote.Action == action {
+			agreeing += vote.Weight
+		}
+	}
+	if total <= 0 {
+		return 0
+	}
+	return agreeing / total
+}
+
+// weightedConsensus scores each action by the confidence-weighted share of the
+// voting weight cast for it; consensus and confidence are the winner's share
+type weightedConsensus struct{}
+
+func (weightedConsensus) Method() VotingMethod { return VotingWeightedConsensus }
+
+func (weightedConsensus) Aggregate(ballot Ballot) Tally {
+	scores := newScores()
+	totalWeight := 0.0
+	for _, vote := range ballot.Votes {
+		scores[vote.Action] += vote.Weight * vote.Confidence
+		totalWeight += vote.Weight
+	}
+
+	tally := Tally{Action: leader(scores), Scores: scores}
+	if totalWeight > 0 {
+		tally.Consensus = scores[tally.Action] / totalWeight
+		tally.Confidence = tally.Consensus
+	}
+	return tally
+}
+
+// majorityVote gives every agent one vote regardless of weight. The winner
+// needs more than half the votes; its confidence is the mean confidence of
+// the agents that voted for it. Scores are vote shares.
+type majorityVote struct{}
+
+func (majorityVote) Method() VotingMethod { return VotingMajority }
+
+func (majorityVote) Aggregate(ballot Ballot) Tally {
+	scores := newScores()
+	confidence := make(map[string]float64, len(voteActions))
+	for _, vote := range ballot.Votes {
+		scores[vote.Action]++
+		confidence[vote.Action] += vote.Confidence
+	}
+
+	tally := Tally{Action: "HOLD", Scores: scores}
+	n := float64(len(ballot.Votes))
+	if n == 0 {
+		return tally
+	}
+	action, count := "HOLD", 0.0
+	for _, a := range voteActions {
+		if scores[a] > count {
+			action, count = a, scores[a]
+		}
+		scores[a] /= n
+	}
+	if count*2 <= n {
+		tally.Reason = fmt.Sprintf("no majority (%s has %.0f of %.0f votes)", action, count, n)
+		return tally
+	}
+
+	tally.Action = action
+	tally.Consensus = count / n
+	tally.Confidence = confidence[action] / count
+	return tally
+}
+
+// bordaCount ranks the actions BUY > HOLD > SELL for a BUY vote and the reverse
+// for a SELL vote; a HOLD vote ranks HOLD first and splits its second place
+// between BUY and SELL. Ranks earn 2, 1 and 0 points scaled by weight times
+// confidence, so a split between BUY and SELL can settle on HOLD. Scores and
+// confidence are points as a share of the most an action could have earned.
+type bordaCount struct{}
+
+func (bordaCount) Method() VotingMethod { return VotingBorda }
+
+func (bordaCount) Aggregate(ballot Ballot) Tally {
+	scores := newScores()
+	maxPoints := 0.0
+	for _, vote := range ballot.Votes {
+		strength := vote.Weight * vote.Confidence
+		switch vote.Action {
+		case "BUY":
+			scores["BUY"] += 2 * strength
+			scores["HOLD"] += strength
+		case "SELL":
+			scores["SELL"] += 2 * strength
+			scores["HOLD"] += strength
+		default:
+			scores["HOLD"] += 2 * strength
+			scores["BUY"] += strength / 2
+			scores["SELL"] += strength / 2
+		}
+		maxPoints += 2 * vote.Weight
+	}
+	if maxPoints > 0 {
+		for _, action := range voteActions {
+			scores[action] /= maxPoints
+		}
+	}
+
+	tally := Tally{Action: leader(scores), Scores: scores}
+	tally.Consensus = agreement(ballot.Votes, tally.Action)
+	tally.Confidence = scores[tally.Action]
+	return tally
+}
+
+// bayesianMaxProbability caps a single vote's probability so that one fully
+// confident agent cannot settle the posterior alone
+const bayesianMaxProbability = 0.99
+
+// bayesianVote treats each BUY or SELL vote as independent evidence that the
+// price will rise, with probability 0.5 + confidence/2, and sums the log-odds
+// weighted relative to the mean voting weight. HOLD votes carry no evidence.
+// Scores are the posterior probabilities of BUY and SELL and the weight share
+// of HOLD; confidence is 2p-1 on the winning side, the same scale agents use.
+type bayesianVote struct{}
+
+func (bayesianVote) Method() VotingMethod { return VotingBayesian }
+
+func (bayesianVote) Aggregate(ballot Ballot) Tally {
+	scores := newScores()
+	tally := Tally{Action: "HOLD", Scores: scores}
+
+	totalWeight := 0.0
+	for _, vote := range ballot.Votes {
+		totalWeight += vote.Weight
+	}
+	if totalWeight <= 0 {
+		return tally
+	}
+	meanWeight := totalWeight / float64(len(ballot.Votes))
+
+	logOdds := 0.0
+	for _, vote := range ballot.Votes {
+		p := math.Min(0.5+vote.Confidence/2, bayesianMaxProbability)
+		evidence := vote.Weight / meanWeight * math.Log(p/(1-p))
+		switch vote.Action {
+		case "BUY":
+			logOdds += evidence
+		case "SELL":
+			logOdds -= evidence
+		}
+	}
+
+	up := 1 / (1 + math.Exp(-logOdds))
+	scores["BUY"] = up
+	scores["SELL"] = 1 - up
+	scores["HOLD"] = agreement(ballot.Votes, "HOLD")
+
+	switch {
+	case up > 0.5:
+		tally.Action = "BUY"
+	case up < 0.5:
+		tally.Action = "SELL"
+	default:
+		tally.Reason = "evidence is balanced"
+		return tally
+	}
+	tally.Consensus = agreement(ballot.Votes, tally.Action)
+	tally.Confidence = math.Abs(2*up - 1)
+	return tally
+}
+
+// vetoGate holds a BUY or SELL decision when an agent of a veto type voted
+// HOLD or for the opposite side
+type vetoGate struct {
+	VoteAggregator
+	vetoers map[string]bool
+}
+
+func (g vetoGate) Aggregate(ballot Ballot) Tally {
+	tally := g.VoteAggregator.Aggregate(ballot)
+	if tally.Action == "HOLD" {
+		return tally
+	}
+
+	var vetoes []string
+	for _, vote := range ballot.Votes {
+		if g.vetoers[vote.AgentType] && vote.Action != tally.Action {
+			vetoes = append(vetoes, fmt.Sprintf("%s(%s)", vote.Agent, vote.Action))
+		}
+	}
+	if len(vetoes) > 0 {
+		tally.Reason = fmt.Sprintf("%s vetoed by %s", tally.Action, strings.Join(vetoes, ", "))
+		tally.Action = "HOLD"
+	}
+	return tally
+}
+
+// quorumGate holds when too few votes were cast or too few of the enabled
+// agents took part
+type quorumGate struct {
+	VoteAggregator
+	minVotes int
+	quorum   float64
+}
+
+func (g quorumGate) Aggregate(ballot Ballot) Tally {
+	tally := g.VoteAggregator.Aggregate(ballot)
+
+	votes := len(ballot.Votes)
+	switch {
+	case votes < g.minVotes:
+		tally.Reason = fmt.Sprintf("%d votes below minimum of %d", votes, g.minVotes)
+	case ballot.Eligible > 0 && float64(votes)/float64(ballot.Eligible) < g.quorum:
+		tally.Reason = fmt.Sprintf("participation %d/%d below quorum of %.2f", votes, ballot.Eligible, g.quorum)
+	default:
+		return tally
+	}
+	tally.Action = "HOLD"
+	return tally
+}
+
+// votingSource supplies the voting settings of the active strategy
+type votingSource interface {
+	Voting(ctx context.Context) (VotingSettings, error)
+}
+
+// dbVotingSource reads voting settings from the active strategy
+type dbVotingSource struct {
+	db *db.DB
+}
+
+// Voting returns the active strategy's voting settings. Strategies with voting
+// disabled use plain weighted consensus without gates.
+func (s *dbVotingSource) Voting(ctx context.Context) (VotingSettings, error) {
+	active, err := db.NewStrategyRepository(s.db).GetActive(ctx)
+	if err != nil {
+		return VotingSettings{}, fmt.Errorf("failed to load active strategy: %w", err)
+	}
+
+	orchestration := active.Orchestration
+	if !orchestration.VotingEnabled {
+		return VotingSettings{Method: VotingWeightedConsensus}, nil
+	}
+	return VotingSettings{
+		Method:     VotingMethod(orchestration.VotingMethod),
+		MinVotes:   orchestration.MinVotes,
+		Quorum:     orchestration.Quorum,
+		VetoAgents: orchestration.VetoAgents,
+	}, nil
+}
+
+// refreshVoting switches to the active strategy's voting settings. The current
+// aggregator is kept when the settings cannot be loaded or are invalid.
+func (o *Orchestrator) refreshVoting(ctx context.Context) {
+	if o.votingSource == nil {
+		return
+	}
+	settings, err := o.votingSource.Voting(ctx)
+	if err != nil {
+		o.log.Warn().Err(err).Msg("Failed to load voting settings, keeping current voting method")
+		return
+	}
+	aggregator, err := NewVoteAggregator(settings)
+	if err != nil {
+		o.log.Warn().Err(err).Msg("Invalid voting settings, keeping current voting method")
+		return
+	}
+
+	o.votingMutex.Lock()
+	previous := o.aggregator
+	o.aggregator = aggregator
+	o.votingMutex.Unlock()
+
+	if previous == nil || previous.Method() != aggregator.Method() {
+		o.log.Info().Str("method", string(aggregator.Method())).Msg("Voting method selected")
+	}
+}
+
+// voteAggregator returns the aggregator decisions are made with
+func (o *Orchestrator) voteAggregator() VoteAggregator {
+	o.votingMutex.RLock()
+	defer o.votingMutex.RUnlock()
+	if o.aggregator == nil {
+		return weightedConsensus{}
+	}
+	return o.aggregator
+}
@@ -0,0 +1,209 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVotingSource returns fixed voting settings
+type fakeVotingSource struct {
+	settings VotingSettings
+	err      error
+}
+
+func (f *fakeVotingSource) Voting(ctx context.Context) (VotingSettings, error) {
+	return f.settings, f.err
+}
+
+func aggregate(t *testing.T, settings VotingSettings, votes ...Vote) Tally {
+	t.Helper()
+	aggregator, err := NewVoteAggregator(settings)
+	require.NoError(t, err)
+	return aggregator.Aggregate(Ballot{Votes: votes, Eligible: len(votes)})
+}
+
+func TestNewVoteAggregator(t *testing.T) {
+	for _, method := range VotingMethods {
+		aggregator, err := NewVoteAggregator(VotingSettings{Method: method, MinVotes: 2, Quorum: 0.5, VetoAgents: []string{"risk"}})
+		require.NoError(t, err)
+		assert.Equal(t, method, aggregator.Method(), "gates report the method they wrap")
+	}
+
+	_, err := NewVoteAggregator(VotingSettings{Method: "plurality"})
+	assert.Error(t, err)
+	_, err = NewVoteAggregator(VotingSettings{Quorum: 1.5})
+	assert.Error(t, err)
+	_, err = NewVoteAggregator(VotingSettings{MinVotes: -1})
+	assert.Error(t, err)
+}
+
+func TestWeightedConsensus(t *testing.T) {
+	tally := aggregate(t, VotingSettings{Method: VotingWeightedConsensus},
+		Vote{Agent: "trend-agent", Action: "BUY", Weight: 0.30, Confidence: 0.8},
+		Vote{Agent: "technical-agent", Action: "BUY", Weight: 0.25, Confidence: 0.6},
+		Vote{Agent: "reversion-agent", Action: "SELL", Weight: 0.25, Confidence: 0.9},
+	)
+	assert.Equal(t, "BUY", tally.Action)
+	assert.InDelta(t, 0.39, tally.Scores["BUY"], 1e-9)
+	assert.InDelta(t, 0.225, tally.Scores["SELL"], 1e-9)
+	assert.InDelta(t, 0.39/0.8, tally.Consensus, 1e-9)
+	assert.Equal(t, tally.Consensus, tally.Confidence)
+
+	// Ties hold instead of picking a side at random
+	tally = aggregate(t, VotingSettings{},
+		Vote{Agent: "a", Action: "BUY", Weight: 1, Confidence: 0.5},
+		Vote{Agent: "b", Action: "SELL", Weight: 1, Confidence: 0.5},
+	)
+	assert.Equal(t, "HOLD", tally.Action)
+}
+
+func TestMajorityVote(t *testing.T) {
+	// Weight does not count: the heavy SELL agent is outvoted
+	tally := aggregate(t, VotingSettings{Method: VotingMajority},
+		Vote{Agent: "a", Action: "BUY", Weight: 0.2, Confidence: 0.6},
+		Vote{Agent: "b", Action: "BUY", Weight: 0.2, Confidence: 0.8},
+		Vote{Agent: "c", Action: "SELL", Weight: 10, Confidence: 0.9},
+	)
+	assert.Equal(t, "BUY", tally.Action)
+	assert.InDelta(t, 2.0/3, tally.Consensus, 1e-9)
+	assert.InDelta(t, 0.7, tally.Confidence, 1e-9)
+	assert.InDelta(t, 1.0/3, tally.Scores["SELL"], 1e-9)
+
+	// Half the votes is not a majority
+	tally = aggregate(t, VotingSettings{Method: VotingMajority},
+		Vote{Agent: "a", Action: "BUY", Weight: 1, Confidence: 0.9},
+		Vote{Agent: "b", Action: "SELL", Weight: 1, Confidence: 0.9},
+	)
+	assert.Equal(t, "HOLD", tally.Action)
+	assert.Contains(t, tally.Reason, "no majority")
+}
+
+func TestBordaCount(t *testing.T) {
+	// Unanimous votes score their confidence
+	tally := aggregate(t, VotingSettings{Method: VotingBorda},
+		Vote{Agent: "a", Action: "BUY", Weight: 1, Confidence: 0.8},
+		Vote{Agent: "b", Action: "BUY", Weight: 1, Confidence: 0.8},
+	)
+	assert.Equal(t, "BUY", tally.Action)
+	assert.InDelta(t, 0.8, tally.Confidence, 1e-9)
+	assert.InDelta(t, 0.4, tally.Scores["HOLD"], 1e-9)
+	assert.Equal(t, 1.0, tally.Consensus)
+
+	// A split between BUY and SELL settles on HOLD, which weighted consensus would not
+	votes := []Vote{
+		{Agent: "a", Action: "BUY", Weight: 1, Confidence: 0.9},
+		{Agent: "b", Action: "SELL", Weight: 1, Confidence: 0.8},
+		{Agent: "c", Action: "HOLD", Weight: 1, Confidence: 0.6},
+	}
+	tally = aggregate(t, VotingSettings{Method: VotingBorda}, votes...)
+	assert.Equal(t, "HOLD", tally.Action)
+	assert.InDelta(t, 2.9/6, tally.Scores["HOLD"], 1e-9)
+	assert.Equal(t, "BUY", aggregate(t, VotingSettings{}, votes...).Action)
+}
+
+func TestBayesianVote(t *testing.T) {
+	// Agreeing agents compound their evidence
+	tally := aggregate(t, VotingSettings{Method: VotingBayesian},
+		Vote{Agent: "a", Action: "BUY", Weight: 1, Confidence: 0.8},
+		Vote{Agent: "b", Action: "BUY", Weight: 1, Confidence: 0.8},
+		Vote{Agent: "c", Action: "HOLD", Weight: 1, Confidence: 0.9},
+	)
+	up := 81.0 / 82 // Odds of 9:1 twice
+	assert.Equal(t, "BUY", tally.Action)
+	assert.InDelta(t, up, tally.Scores["BUY"], 1e-9)
+	assert.InDelta(t, 2*up-1, tally.Confidence, 1e-9)
+	assert.InDelta(t, 2.0/3, tally.Consensus, 1e-9)
+	assert.InDelta(t, 1.0/3, tally.Scores["HOLD"], 1e-9)
+
+	// Heavier agents carry more evidence
+	tally = aggregate(t, VotingSettings{Method: VotingBayesian},
+		Vote{Agent: "a", Action: "BUY", Weight: 0.1, Confidence: 0.9},
+		Vote{Agent: "b", Action: "SELL", Weight: 0.5, Confidence: 0.9},
+	)
+	assert.Equal(t, "SELL", tally.Action)
+
+	// Balanced evidence holds
+	tally = aggregate(t, VotingSettings{Method: VotingBayesian},
+		Vote{Agent: "a", Action: "BUY", Weight: 1, Confidence: 1},
+		Vote{Agent: "b", Action: "SELL", Weight: 1, Confidence: 1},
+	)
+	assert.Equal(t, "HOLD", tally.Action)
+	assert.Zero(t, tally.Confidence)
+}
+
+func TestVetoGate(t *testing.T) {
+	settings := VotingSettings{Method: VotingWeightedConsensus, VetoAgents: []string{"risk"}}
+
+	tally := aggregate(t, settings,
+		Vote{Agent: "trend-agent", AgentType: "trend", Action: "BUY", Weight: 0.3, Confidence: 0.9},
+		Vote{Agent: "risk-agent", AgentType: "risk", Action: "HOLD", Weight: 1, Confidence: 0.2},
+	)
+	assert.Equal(t, "HOLD", tally.Action)
+	assert.Equal(t, "BUY vetoed by risk-agent(HOLD)", tally.Reason)
+
+	// A risk agent that agrees does not veto
+	tally = aggregate(t, settings,
+		Vote{Agent: "trend-agent", AgentType: "trend", Action: "BUY", Weight: 0.3, Confidence: 0.9},
+		Vote{Agent: "risk-agent", AgentType: "risk", Action: "BUY", Weight: 1, Confidence: 0.6},
+	)
+	assert.Equal(t, "BUY", tally.Action)
+	assert.Empty(t, tally.Reason)
+}
+
+func TestQuorumGate(t *testing.T) {
+	aggregator, err := NewVoteAggregator(VotingSettings{MinVotes: 2, Quorum: 0.6})
+	require.NoError(t, err)
+	buy := Vote{Agent: "a", Action: "BUY", Weight: 1, Confidence: 0.9}
+
+	tally := aggregator.Aggregate(Ballot{Votes: []Vote{buy}, Eligible: 1})
+	assert.Equal(t, "HOLD", tally.Action)
+	assert.Equal(t, "1 votes below minimum of 2", tally.Reason)
+	assert.InDelta(t, 0.9, tally.Scores["BUY"], 1e-9, "scores are kept for monitoring")
+
+	tally = aggregator.Aggregate(Ballot{Votes: []Vote{buy, buy}, Eligible: 4})
+	assert.Equal(t, "HOLD", tally.Action)
+	assert.Contains(t, tally.Reason, "below quorum")
+
+	tally = aggregator.Aggregate(Ballot{Votes: []Vote{buy, buy, buy}, Eligible: 4})
+	assert.Equal(t, "BUY", tally.Action)
+}
+
+func TestCalculateDecision_VotingMethodFromStrategy(t *testing.T) {
+	orch := newRiskBudgetTestOrchestrator(t, RiskBudgetConfig{}, &fakeRiskBudgetSource{})
+	orch.config.MinConsensus = 0
+	orch.config.MinConfidence = 0
+	signals := []AgentSignal{
+		{AgentName: "trend-agent", AgentType: "trend", Signal: "BUY", Confidence: 0.9},
+		{AgentName: "reversion-agent", AgentType: "reversion", Signal: "SELL", Confidence: 0.6},
+	}
+
+	// Without a strategy the orchestrator uses weighted consensus
+	decision := decide(orch, signals...)
+	assert.Equal(t, VotingWeightedConsensus, decision.VotingMethod)
+	assert.Equal(t, "BUY", decision.Action)
+
+	source := &fakeVotingSource{settings: VotingSettings{Method: VotingMajority}}
+	orch.votingSource = source
+	orch.refreshVoting(context.Background())
+
+	decision = decide(orch, signals...)
+	assert.Equal(t, VotingMajority, decision.VotingMethod)
+	assert.Equal(t, "HOLD", decision.Action)
+	assert.Contains(t, decision.Reasoning, "majority voting:")
+	assert.Contains(t, decision.Reasoning, "no majority")
+	assert.Nil(t, decision.Attribution)
+
+	// Unloadable and invalid settings keep the current method
+	source.err = errors.New("database unavailable")
+	orch.refreshVoting(context.Background())
+	assert.Equal(t, VotingMajority, orch.voteAggregator().Method())
+
+	source.err = nil
+	source.settings = VotingSettings{Method: "plurality"}
+	orch.refreshVoting(context.Background())
+	assert.Equal(t, VotingMajority, orch.voteAggregator().Method())
+}
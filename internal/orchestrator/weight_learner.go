@@ -0,0 +1,440 @@
+package orchestrator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Weight learning methods
+const (
+	WeightLearningHedge   = "hedge"    // Multiplicative weights on each round's Brier loss
+	WeightLearningHitRate = "hit_rate" // Base weight scaled by the hit rate
+	WeightLearningBrier   = "brier"    // Base weight scaled by the Brier score
+)
+
+// Reasons recorded in the weight audit trail
+const (
+	WeightChangeLearned       = "learned"
+	WeightChangeFreeze        = "freeze"
+	WeightChangeUnfreeze      = "unfreeze"
+	WeightChangeOverride      = "override"
+	WeightChangeClearOverride = "clear_override"
+)
+
+const (
+	defaultWeightLearningInterval = time.Hour
+	defaultWeightLearningHorizon  = 4 * time.Hour
+	defaultWeightPriceInterval    = "1h"
+	defaultWeightLookback         = 7 * 24 * time.Hour
+	defaultWeightHalfLife         = 50
+	defaultWeightMinSamples       = 20
+	// A coin flip at confidence 0.5: the prior every agent starts from
+	priorHitRate = 0.5
+	priorBrier   = 0.25
+)
+
+// WeightLearningConfig learns each agent's voting weight online from how its
+// signals fared. A signal is a hit when the symbol's return over Horizon
+// agrees with it: up for BUY, down for SELL and within Deadband for HOLD.
+// Learned weights stay within [MinWeight, MaxWeight]; agent types in
+// ExcludeTypes keep their default weight.
+type WeightLearningConfig struct {
+	Enabled       bool          `json:"enabled" yaml:"enabled" mapstructure:"enabled"`
+	Method        string        `json:"method" yaml:"method" mapstructure:"method"`                         // hedge, hit_rate or brier
+	Interval      time.Duration `json:"interval" yaml:"interval" mapstructure:"interval"`                   // How often signals are scored
+	Horizon       time.Duration `json:"horizon" yaml:"horizon" mapstructure:"horizon"`                      // Return horizon a signal is scored over
+	PriceInterval string        `json:"price_interval" yaml:"price_interval" mapstructure:"price_interval"` // Candle interval returns are measured on
+	Deadband      float64       `json:"deadband" yaml:"deadband" mapstructure:"deadband"`                   // Returns within ±deadband count as flat
+	HalfLife      float64       `json:"half_life" yaml:"half_life" mapstructure:"half_life"`                // Signals after which an outcome counts half
+	LearningRate  float64       `json:"learning_rate" yaml:"learning_rate" mapstructure:"learning_rate"`    // Hedge learning rate
+	MinSamples    int           `json:"min_samples" yaml:"min_samples" mapstructure:"min_samples"`          // Scored signals before the learned weight applies
+	MinWeight     float64       `json:"min_weight" yaml:"min_weight" mapstructure:"min_weight"`
+	MaxWeight     float64       `json:"max_weight" yaml:"max_weight" mapstructure:"max_weight"`
+	Lookback      time.Duration `json:"lookback" yaml:"lookback" mapstructure:"lookback"` // How far back the first round scores
+	ExcludeTypes  []string      `json:"exclude_types" yaml:"exclude_types" mapstructure:"exclude_types"`
+}
+
+// withDefaults fills unset fields with defaults
+func (c WeightLearningConfig) withDefaults() WeightLearningConfig {
+	if c.Method == "" {
+		c.Method = WeightLearningHedge
+	}
+	if c.Interval <= 0 {
+		c.Interval = defaultWeightLearningInterval
+	}
+	if c.Horizon <= 0 {
+		c.Horizon = defaultWeightLearningHorizon
+	}
+	if c.PriceInterval == "" {
+		c.PriceInterval = defaultWeightPriceInterval
+	}
+	if c.HalfLife <= 0 {
+		c.HalfLife = defaultWeightHalfLife
+	}
+	if c.LearningRate <= 0 {
+		c.LearningRate = 1
+	}
+	if c.MinSamples <= 0 {
+		c.MinSamples = defaultWeightMinSamples
+	}
+	if c.MaxWeight <= 0 {
+		c.MaxWeight = 1
+	}
+	if c.Lookback <= 0 {
+		c.Lookback = defaultWeightLookback
+	}
+	return c
+}
+
+// validate rejects unknown methods and inconsistent bounds
+func (c WeightLearningConfig) validate() error {
+	switch c.Method {
+	case "", WeightLearningHedge, WeightLearningHitRate, WeightLearningBrier:
+	default:
+		return fmt.Errorf("unknown weight learning method %q (want %s, %s or %s)",
+			c.Method, WeightLearningHedge, WeightLearningHitRate, WeightLearningBrier)
+	}
+	if c.Deadband < 0 {
+		return fmt.Errorf("weight learning deadband must not be negative")
+	}
+	if c.MinWeight < 0 {
+		return fmt.Errorf("minimum agent weight must not be negative")
+	}
+	if c.MaxWeight > 0 && c.MinWeight > c.MaxWeight {
+		return fmt.Errorf("minimum agent weight %.2f exceeds maximum %.2f", c.MinWeight, c.MaxWeight)
+	}
+	return nil
+}
+
+// AgentWeightState is an agent's voting weight and the outcome statistics it
+// was learned from. Weight is what the agent votes with: the override, the
+// weight it had when frozen, the learned weight once MinSamples signals have
+// been scored, or else its default weight.
+type AgentWeightState struct {
+	Agent         string    `json:"agent"`
+	AgentType     string    `json:"agent_type"`
+	Weight        float64   `json:"weight"`
+	LearnedWeight float64   `json:"learned_weight"`
+	BaseWeight    float64   `json:"base_weight"`
+	HitRate       float64   `json:"hit_rate"`
+	BrierScore    float64   `json:"brier_score"`
+	Samples       int       `json:"samples"`
+	Frozen        bool      `json:"frozen"`
+	Override      *float64  `json:"override,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// WeightChange is an entry in the audit trail of agent weights
+type WeightChange struct {
+	Agent          string    `json:"agent"`
+	Reason         string    `json:"reason"`
+	PreviousWeight float64   `json:"previous_weight"`
+	Weight         float64   `json:"weight"`
+	HitRate        float64   `json:"hit_rate"`
+	BrierScore     float64   `json:"brier_score"`
+	Samples        int       `json:"samples"`
+	Actor          string    `json:"actor,omitempty"`
+	Note           string    `json:"note,omitempty"`
+	At             time.Time `json:"at"`
+}
+
+// SignalOutcome is a scored agent signal: the symbol's return from just
+// before the signal to the end of its horizon
+type SignalOutcome struct {
+	Agent      string
+	AgentType  string
+	Signal     string // BUY, SELL, HOLD
+	Confidence float64
+	Return     float64
+}
+
+// WeightLearner tracks exponentially weighted hit rates and Brier scores of
+// agents' signals and turns them into voting weights. It is safe for
+// concurrent use.
+type WeightLearner struct {
+	config        WeightLearningConfig
+	base          func(agentType string) float64
+	alpha         float64 // Weight of the newest outcome in the running averages
+	excluded      map[string]bool
+	states        map[string]*AgentWeightState
+	scoredThrough time.Time
+	mu            sync.RWMutex
+}
+
+// NewWeightLearner creates a learner whose agents start from the given
+// default weights
+func NewWeightLearner(config WeightLearningConfig, base func(agentType string) float64) *WeightLearner {
+	config = config.withDefaults()
+	excluded := make(map[string]bool, len(config.ExcludeTypes))
+	for _, agentType := range config.ExcludeTypes {
+		excluded[agentType] = true
+	}
+	return &WeightLearner{
+		config:   config,
+		base:     base,
+		alpha:    1 - math.Pow(0.5, 1/config.HalfLife),
+		excluded: excluded,
+		states:   make(map[string]*AgentWeightState),
+	}
+}
+
+// Config returns the learner's configuration with defaults applied
+func (l *WeightLearner) Config() WeightLearningConfig {
+	return l.config
+}
+
+// Restore replaces the learner's state with persisted weights
+func (l *WeightLearner) Restore(states []AgentWeightState, scoredThrough time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.states = make(map[string]*AgentWeightState, len(states))
+	for i := range states {
+		state := states[i]
+		if l.excluded[state.AgentType] {
+			continue
+		}
+		state.BaseWeight = l.base(state.AgentType)
+		state.Weight = l.resolve(&state)
+		l.states[state.Agent] = &state
+	}
+	l.scoredThrough = scoredThrough
+}
+
+// ScoredThrough returns the time up to which signals have been scored
+func (l *WeightLearner) ScoredThrough() time.Time {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.scoredThrough
+}
+
+// Weight returns the voting weight of an agent, starting to track it if it
+// is new
+func (l *WeightLearner) Weight(agent, agentType string) float64 {
+	if l.excluded[agentType] {
+		return l.base(agentType)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.state(agent, agentType).Weight
+}
+
+// state returns the state of an agent, creating it at its default weight.
+// Must be called with mu held.
+func (l *WeightLearner) state(agent, agentType string) *AgentWeightState {
+	if state, ok := l.states[agent]; ok {
+		return state
+	}
+	base := l.base(agentType)
+	state := &AgentWeightState{
+		Agent:         agent,
+		AgentType:     agentType,
+		Weight:        l.clamp(base),
+		LearnedWeight: l.clamp(base),
+		BaseWeight:    base,
+		HitRate:       priorHitRate,
+		BrierScore:    priorBrier,
+	}
+	l.states[agent] = state
+	return state
+}
+
+// Learn scores a round of signal outcomes, oldest first, and returns the
+// weights that changed. Signals up to scoredThrough are then considered
+// scored.
+func (l *WeightLearner) Learn(outcomes []SignalOutcome, scoredThrough, now time.Time) []WeightChange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	losses := make(map[string]float64)
+	counts := make(map[string]int)
+	var order []string
+	for _, outcome := range outcomes {
+		if l.excluded[outcome.AgentType] {
+			continue
+		}
+		hit := 0.0
+		if l.hit(outcome.Signal, outcome.Return) {
+			hit = 1
+		}
+		brier := (outcome.Confidence - hit) * (outcome.Confidence - hit)
+
+		state := l.state(outcome.Agent, outcome.AgentType)
+		state.HitRate += l.alpha * (hit - state.HitRate)
+		state.BrierScore += l.alpha * (brier - state.BrierScore)
+		state.Samples++
+
+		if counts[outcome.Agent] == 0 {
+			order = append(order, outcome.Agent)
+		}
+		losses[outcome.Agent] += brier
+		counts[outcome.Agent]++
+	}
+	l.scoredThrough = scoredThrough
+	if len(order) == 0 {
+		return nil
+	}
+
+	// Hedge compares each agent with the others scored in the round, so a
+	// market that fooled everyone moves no weights
+	mean := 0.0
+	for _, agent := range order {
+		mean += losses[agent] / float64(counts[agent])
+	}
+	mean /= float64(len(order))
+
+	var changes []WeightChange
+	for _, agent := range order {
+		state := l.states[agent]
+		switch l.config.Method {
+		case WeightLearningHitRate:
+			state.LearnedWeight = l.clamp(state.BaseWeight * 2 * state.HitRate)
+		case WeightLearningBrier:
+			state.LearnedWeight = l.clamp(state.BaseWeight * (2 - 4*state.BrierScore))
+		default:
+			loss := losses[agent] / float64(counts[agent])
+			state.LearnedWeight = l.clamp(state.LearnedWeight * math.Exp(-l.config.LearningRate*(loss-mean)))
+		}
+
+		previous := state.Weight
+		state.Weight = l.resolve(state)
+		state.UpdatedAt = now
+		if math.Abs(state.Weight-previous) > 1e-9 {
+			changes = append(changes, change(state, WeightChangeLearned, previous, "", "", now))
+		}
+	}
+	return changes
+}
+
+// hit reports whether a return confirms a signal
+func (l *WeightLearner) hit(signal string, ret float64) bool {
+	return signalHit(signal, ret, l.config.Deadband)
+}
+
+// signalHit reports whether a return confirms a signal: up for BUY, down for
+// SELL and within the deadband for HOLD
+func signalHit(signal string, ret, deadband float64) bool {
+	switch signal {
+	case "BUY":
+		return ret > deadband
+	case "SELL":
+		return ret < -deadband
+	default:
+		return math.Abs(ret) <= deadband
+	}
+}
+
+// resolve returns the weight an agent votes with
+func (l *WeightLearner) resolve(state *AgentWeightState) float64 {
+	switch {
+	case state.Override != nil:
+		return *state.Override
+	case state.Frozen:
+		return state.Weight
+	case state.Samples >= l.config.MinSamples:
+		return state.LearnedWeight
+	default:
+		return l.clamp(state.BaseWeight)
+	}
+}
+
+// clamp keeps a weight within the configured bounds
+func (l *WeightLearner) clamp(weight float64) float64 {
+	return math.Max(l.config.MinWeight, math.Min(l.config.MaxWeight, weight))
+}
+
+// change builds an audit entry for an agent's new weight
+func change(state *AgentWeightState, reason string, previous float64, actor, note string, now time.Time) WeightChange {
+	return WeightChange{
+		Agent:          state.Agent,
+		Reason:         reason,
+		PreviousWeight: previous,
+		Weight:         state.Weight,
+		HitRate:        state.HitRate,
+		BrierScore:     state.BrierScore,
+		Samples:        state.Samples,
+		Actor:          actor,
+		Note:           note,
+		At:             now,
+	}
+}
+
+// adjust applies a manual change to a known agent and records it
+func (l *WeightLearner) adjust(agent, reason, actor, note string, now time.Time, apply func(*AgentWeightState) error) (WeightChange, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.states[agent]
+	if !ok {
+		return WeightChange{}, fmt.Errorf("agent %s has no learned weight", agent)
+	}
+	if err := apply(state); err != nil {
+		return WeightChange{}, err
+	}
+	previous := state.Weight
+	state.Weight = l.resolve(state)
+	state.UpdatedAt = now
+	return change(state, reason, previous, actor, note, now), nil
+}
+
+// Freeze keeps an agent's current weight while its statistics keep learning
+func (l *WeightLearner) Freeze(agent, actor, note string, now time.Time) (WeightChange, error) {
+	return l.adjust(agent, WeightChangeFreeze, actor, note, now, func(state *AgentWeightState) error {
+		if state.Frozen {
+			return fmt.Errorf("agent %s is already frozen", agent)
+		}
+		state.Frozen = true
+		return nil
+	})
+}
+
+// Unfreeze lets an agent's weight follow its learned weight again
+func (l *WeightLearner) Unfreeze(agent, actor, note string, now time.Time) (WeightChange, error) {
+	return l.adjust(agent, WeightChangeUnfreeze, actor, note, now, func(state *AgentWeightState) error {
+		if !state.Frozen {
+			return fmt.Errorf("agent %s is not frozen", agent)
+		}
+		state.Frozen = false
+		return nil
+	})
+}
+
+// Override pins an agent's weight, within the configured bounds, until the
+// override is cleared
+func (l *WeightLearner) Override(agent string, weight float64, actor, note string, now time.Time) (WeightChange, error) {
+	if weight < l.config.MinWeight || weight > l.config.MaxWeight {
+		return WeightChange{}, fmt.Errorf("weight %.4f is outside [%.4f, %.4f]", weight, l.config.MinWeight, l.config.MaxWeight)
+	}
+	return l.adjust(agent, WeightChangeOverride, actor, note, now, func(state *AgentWeightState) error {
+		state.Override = &weight
+		return nil
+	})
+}
+
+// ClearOverride removes an agent's override. A frozen agent keeps the
+// overridden weight until it is unfrozen.
+func (l *WeightLearner) ClearOverride(agent, actor, note string, now time.Time) (WeightChange, error) {
+	return l.adjust(agent, WeightChangeClearOverride, actor, note, now, func(state *AgentWeightState) error {
+		if state.Override == nil {
+			return fmt.Errorf("agent %s has no weight override", agent)
+		}
+		state.Override = nil
+		return nil
+	})
+}
+
+// Weights returns the state of every tracked agent, by name
+func (l *WeightLearner) Weights() []AgentWeightState {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	states := make([]AgentWeightState, 0, len(l.states))
+	for _, state := range l.states {
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Agent < states[j].Agent })
+	return states
+}
@@ -0,0 +1,281 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWeightSource serves fixed outcomes and records saved weights
+type fakeWeightSource struct {
+	signals  []*AgentSignal
+	outcomes []SignalOutcome
+	states   []AgentWeightState
+	through  time.Time
+	changes  []WeightChange
+	from, to time.Time
+}
+
+func (f *fakeWeightSource) RecordSignal(ctx context.Context, signal *AgentSignal) error {
+	f.signals = append(f.signals, signal)
+	return nil
+}
+
+func (f *fakeWeightSource) Outcomes(ctx context.Context, from, to time.Time, interval string, horizon time.Duration) ([]SignalOutcome, error) {
+	f.from, f.to = from, to
+	return f.outcomes, nil
+}
+
+func (f *fakeWeightSource) Load(ctx context.Context) ([]AgentWeightState, time.Time, error) {
+	return f.states, f.through, nil
+}
+
+func (f *fakeWeightSource) Save(ctx context.Context, states []AgentWeightState, scoredThrough time.Time, changes []WeightChange) error {
+	f.states, f.through = states, scoredThrough
+	f.changes = append(f.changes, changes...)
+	return nil
+}
+
+func (f *fakeWeightSource) History(ctx context.Context, agent string, limit int) ([]WeightChange, error) {
+	var history []WeightChange
+	for i := len(f.changes) - 1; i >= 0 && len(history) < limit; i-- {
+		if f.changes[i].Agent == agent {
+			history = append(history, f.changes[i])
+		}
+	}
+	return history, nil
+}
+
+func baseWeight(agentType string) float64 {
+	if agentType == "risk" {
+		return 1
+	}
+	return 0.25
+}
+
+// rounds builds n outcomes per agent where the first agent is always right and
+// the second always wrong
+func rounds(n int) []SignalOutcome {
+	var outcomes []SignalOutcome
+	for i := 0; i < n; i++ {
+		outcomes = append(outcomes,
+			SignalOutcome{Agent: "good-agent", AgentType: "trend", Signal: "BUY", Confidence: 0.8, Return: 0.02},
+			SignalOutcome{Agent: "bad-agent", AgentType: "reversion", Signal: "SELL", Confidence: 0.8, Return: 0.02},
+			SignalOutcome{Agent: "risk-agent", AgentType: "risk", Signal: "SELL", Confidence: 0.9, Return: 0.02},
+		)
+	}
+	return outcomes
+}
+
+func TestWeightLearningConfig_Validate(t *testing.T) {
+	assert.NoError(t, WeightLearningConfig{}.validate())
+	assert.Error(t, WeightLearningConfig{Method: "sharpe"}.validate())
+	assert.Error(t, WeightLearningConfig{Deadband: -0.01}.validate())
+	assert.Error(t, WeightLearningConfig{MinWeight: 0.5, MaxWeight: 0.2}.validate())
+
+	_, err := NewOrchestrator(&OrchestratorConfig{WeightLearning: WeightLearningConfig{Method: "sharpe"}}, zerolog.Nop(), nil, 0)
+	assert.Error(t, err)
+}
+
+func TestWeightLearner_Hit(t *testing.T) {
+	learner := NewWeightLearner(WeightLearningConfig{Deadband: 0.01}, baseWeight)
+	assert.True(t, learner.hit("BUY", 0.02))
+	assert.False(t, learner.hit("BUY", 0.005), "moves inside the deadband are flat")
+	assert.True(t, learner.hit("SELL", -0.02))
+	assert.True(t, learner.hit("HOLD", -0.005))
+	assert.False(t, learner.hit("HOLD", 0.02))
+}
+
+func TestWeightLearner_Methods(t *testing.T) {
+	now := time.Now()
+	for _, method := range []string{WeightLearningHedge, WeightLearningHitRate, WeightLearningBrier} {
+		t.Run(method, func(t *testing.T) {
+			learner := NewWeightLearner(WeightLearningConfig{
+				Method:       method,
+				MinSamples:   5,
+				MinWeight:    0.05,
+				MaxWeight:    0.6,
+				HalfLife:     10,
+				ExcludeTypes: []string{"risk"},
+			}, baseWeight)
+
+			// Before MinSamples the default weight applies
+			changes := learner.Learn(rounds(4), now, now)
+			assert.Empty(t, changes)
+			assert.Equal(t, 0.25, learner.Weight("good-agent", "trend"))
+
+			for i := 0; i < 20; i++ {
+				learner.Learn(rounds(1), now, now)
+			}
+			good := learner.Weight("good-agent", "trend")
+			bad := learner.Weight("bad-agent", "reversion")
+			assert.Greater(t, good, 0.25)
+			assert.Less(t, bad, 0.25)
+			assert.LessOrEqual(t, good, 0.6)
+			assert.GreaterOrEqual(t, bad, 0.05)
+
+			// Excluded types are never tracked
+			assert.Equal(t, 1.0, learner.Weight("risk-agent", "risk"))
+			assert.Len(t, learner.Weights(), 2)
+		})
+	}
+}
+
+func TestWeightLearner_Statistics(t *testing.T) {
+	learner := NewWeightLearner(WeightLearningConfig{HalfLife: 1}, baseWeight)
+	now := time.Now()
+
+	learner.Learn([]SignalOutcome{{Agent: "a", AgentType: "trend", Signal: "BUY", Confidence: 0.8, Return: 0.02}}, now, now)
+	state := learner.Weights()[0]
+	assert.Equal(t, 1, state.Samples)
+	// A half-life of one signal moves halfway from the prior
+	assert.InDelta(t, 0.75, state.HitRate, 1e-9)
+	assert.InDelta(t, (0.25+0.04)/2, state.BrierScore, 1e-9)
+	assert.Equal(t, now, learner.ScoredThrough())
+}
+
+func TestWeightLearner_FreezeAndOverride(t *testing.T) {
+	learner := NewWeightLearner(WeightLearningConfig{MinSamples: 1, MaxWeight: 0.6}, baseWeight)
+	now := time.Now()
+	learner.Learn(rounds(1), now, now)
+
+	_, err := learner.Freeze("unknown-agent", "ops", "", now)
+	assert.Error(t, err)
+
+	change, err := learner.Freeze("good-agent", "ops", "earnings week", now)
+	require.NoError(t, err)
+	assert.Equal(t, WeightChangeFreeze, change.Reason)
+	assert.Equal(t, "ops", change.Actor)
+	frozen := learner.Weight("good-agent", "trend")
+	_, err = learner.Freeze("good-agent", "ops", "", now)
+	assert.Error(t, err, "already frozen")
+
+	// Frozen agents keep learning statistics but not weight
+	learner.Learn(rounds(10), now, now)
+	assert.Equal(t, frozen, learner.Weight("good-agent", "trend"))
+	assert.Equal(t, 11, learner.Weights()[1].Samples)
+
+	_, err = learner.Override("good-agent", 0.9, "ops", "", now)
+	assert.Error(t, err, "overrides stay within the bounds")
+	change, err = learner.Override("good-agent", 0.4, "ops", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, frozen, change.PreviousWeight)
+	assert.Equal(t, 0.4, learner.Weight("good-agent", "trend"))
+
+	// Clearing the override of a frozen agent keeps the overridden weight
+	_, err = learner.ClearOverride("good-agent", "ops", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, 0.4, learner.Weight("good-agent", "trend"))
+
+	change, err = learner.Unfreeze("good-agent", "ops", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, learner.Weights()[1].LearnedWeight, change.Weight)
+}
+
+func TestWeightLearner_Restore(t *testing.T) {
+	learner := NewWeightLearner(WeightLearningConfig{MinSamples: 5, ExcludeTypes: []string{"risk"}}, baseWeight)
+	through := time.Now().Add(-time.Hour)
+	override := 0.1
+	learner.Restore([]AgentWeightState{
+		{Agent: "trend-agent", AgentType: "trend", Weight: 0.4, LearnedWeight: 0.4, Samples: 30},
+		{Agent: "young-agent", AgentType: "trend", Weight: 0.4, LearnedWeight: 0.4, Samples: 2},
+		{Agent: "pinned-agent", AgentType: "trend", Weight: 0.3, Override: &override},
+		{Agent: "risk-agent", AgentType: "risk", Weight: 0.2},
+	}, through)
+
+	assert.Equal(t, through, learner.ScoredThrough())
+	assert.Equal(t, 0.4, learner.Weight("trend-agent", "trend"))
+	assert.Equal(t, 0.25, learner.Weight("young-agent", "trend"), "too few samples for the learned weight")
+	assert.Equal(t, 0.1, learner.Weight("pinned-agent", "trend"))
+	assert.Equal(t, 1.0, learner.Weight("risk-agent", "risk"))
+}
+
+func newWeightTestOrchestrator(t *testing.T, source *fakeWeightSource) *Orchestrator {
+	orch := newRiskBudgetTestOrchestrator(t, RiskBudgetConfig{}, &fakeRiskBudgetSource{})
+	orch.config.WeightLearning = WeightLearningConfig{Enabled: true, MinSamples: 3, MaxWeight: 0.6, ExcludeTypes: []string{"risk"}}
+	orch.weights = NewWeightLearner(orch.config.WeightLearning, orch.getDefaultWeight)
+	orch.weightSource = source
+	return orch
+}
+
+func TestLearnWeights_UpdatesSessions(t *testing.T) {
+	source := &fakeWeightSource{}
+	orch := newWeightTestOrchestrator(t, source)
+	decide(orch,
+		AgentSignal{AgentName: "good-agent", AgentType: "trend", Signal: "BUY", Confidence: 0.8},
+		AgentSignal{AgentName: "bad-agent", AgentType: "reversion", Signal: "SELL", Confidence: 0.8},
+	)
+
+	now := time.Now()
+	source.outcomes = rounds(5)
+	orch.learnWeights(context.Background(), now)
+
+	// The first round scores the lookback up to the horizon
+	horizon := orch.weights.Config().Horizon
+	assert.Equal(t, now.Add(-horizon), source.to)
+	assert.Equal(t, source.to.Add(-orch.weights.Config().Lookback), source.from)
+	assert.Equal(t, source.to, source.through)
+
+	sessions := orch.GetAgentSessions()
+	assert.Greater(t, sessions["good-agent"].Weight, orch.getDefaultWeight("trend"))
+	assert.Less(t, sessions["bad-agent"].Weight, orch.getDefaultWeight("reversion"))
+	require.Len(t, source.changes, 2)
+	assert.Equal(t, WeightChangeLearned, source.changes[0].Reason)
+
+	// The next round picks up where the last stopped
+	source.outcomes = nil
+	orch.learnWeights(context.Background(), now.Add(time.Hour))
+	assert.Equal(t, now.Add(-horizon), source.from)
+}
+
+func TestHandleAgentWeightsRequest(t *testing.T) {
+	source := &fakeWeightSource{}
+	orch := newWeightTestOrchestrator(t, source)
+	source.outcomes = rounds(5)
+	orch.learnWeights(context.Background(), time.Now())
+
+	post := func(body map[string]interface{}) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		orch.HandleAgentWeightsRequest(w, httptest.NewRequest(http.MethodPost, "/api/v1/agent-weights", bytes.NewReader(payload)))
+		return w
+	}
+
+	w := post(map[string]interface{}{"agent": "good-agent", "action": "override", "weight": 0.5, "actor": "ops"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 0.5, orch.weights.Weight("good-agent", "trend"))
+	assert.Equal(t, WeightChangeOverride, source.changes[len(source.changes)-1].Reason)
+
+	assert.Equal(t, http.StatusBadRequest, post(map[string]interface{}{"agent": "good-agent", "action": "boost"}).Code)
+	assert.Equal(t, http.StatusBadRequest, post(map[string]interface{}{"agent": "good-agent", "action": "override", "weight": 2}).Code)
+	assert.Equal(t, http.StatusBadRequest, post(map[string]interface{}{"action": "freeze"}).Code)
+
+	w = httptest.NewRecorder()
+	orch.HandleAgentWeightsRequest(w, httptest.NewRequest(http.MethodGet, "/api/v1/agent-weights?agent=good-agent", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Enabled bool               `json:"enabled"`
+		Method  string             `json:"method"`
+		Weights []AgentWeightState `json:"weights"`
+		History []WeightChange     `json:"history"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.Enabled)
+	assert.Equal(t, WeightLearningHedge, response.Method)
+	assert.Len(t, response.Weights, 2)
+	require.Len(t, response.History, 2)
+	assert.Equal(t, WeightChangeOverride, response.History[0].Reason)
+	assert.Equal(t, "ops", response.History[0].Actor)
+
+	// Without learning the weights cannot be adjusted
+	orch.weights = nil
+	assert.Equal(t, http.StatusServiceUnavailable, post(map[string]interface{}{"agent": "good-agent", "action": "freeze"}).Code)
+}
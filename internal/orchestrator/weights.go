@@ -0,0 +1,394 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+)
+
+// weightHistoryLimit is how many audit entries the weights endpoint returns
+const weightHistoryLimit = 50
+
+// weightSource records agent signals, scores them against the returns that
+// followed, and persists learned weights with their audit trail
+type weightSource interface {
+	RecordSignal(ctx context.Context, signal *AgentSignal) error
+	Outcomes(ctx context.Context, from, to time.Time, interval string, horizon time.Duration) ([]SignalOutcome, error)
+	Load(ctx context.Context) ([]AgentWeightState, time.Time, error)
+	Save(ctx context.Context, states []AgentWeightState, scoredThrough time.Time, changes []WeightChange) error
+	History(ctx context.Context, agent string, limit int) ([]WeightChange, error)
+}
+
+// dbWeightSource keeps signals and weights in the database
+type dbWeightSource struct {
+	db *db.DB
+}
+
+// RecordSignal stores a signal for scoring once its horizon has passed
+func (s *dbWeightSource) RecordSignal(ctx context.Context, signal *AgentSignal) error {
+	return s.db.InsertAgentSignal(ctx, &db.AgentSignal{
+		AgentName:  signal.AgentName,
+		AgentType:  signal.AgentType,
+		Symbol:     signal.Symbol,
+		Signal:     signal.Signal,
+		Confidence: signal.Confidence,
+		Reasoning:  signal.Reasoning,
+		CreatedAt:  signal.Timestamp,
+	})
+}
+
+// Outcomes returns the signals created in (from, to] with the returns that
+// followed them
+func (s *dbWeightSource) Outcomes(ctx context.Context, from, to time.Time, interval string, horizon time.Duration) ([]SignalOutcome, error) {
+	return loadSignalOutcomes(ctx, s.db, from, to, interval, horizon)
+}
+
+// loadSignalOutcomes returns the signals created in (from, to] with the
+// returns over the horizon that followed them
+func loadSignalOutcomes(ctx context.Context, database *db.DB, from, to time.Time, interval string, horizon time.Duration) ([]SignalOutcome, error) {
+	rows, err := database.ListSignalOutcomes(ctx, from, to, interval, horizon)
+	if err != nil {
+		return nil, err
+	}
+	outcomes := make([]SignalOutcome, 0, len(rows))
+	for _, row := range rows {
+		if row.EntryPrice <= 0 {
+			continue
+		}
+		outcomes = append(outcomes, SignalOutcome{
+			Agent:      row.AgentName,
+			AgentType:  row.AgentType,
+			Signal:     row.Signal,
+			Confidence: row.Confidence,
+			Return:     row.ExitPrice/row.EntryPrice - 1,
+		})
+	}
+	return outcomes, nil
+}
+
+// Load returns the persisted weights and the time signals were scored through
+func (s *dbWeightSource) Load(ctx context.Context) ([]AgentWeightState, time.Time, error) {
+	rows, err := s.db.ListAgentWeights(ctx)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var scoredThrough time.Time
+	states := make([]AgentWeightState, 0, len(rows))
+	for _, row := range rows {
+		states = append(states, AgentWeightState{
+			Agent:         row.AgentName,
+			AgentType:     row.AgentType,
+			Weight:        row.Weight,
+			LearnedWeight: row.LearnedWeight,
+			BaseWeight:    row.BaseWeight,
+			HitRate:       row.HitRate,
+			BrierScore:    row.BrierScore,
+			Samples:       row.Samples,
+			Frozen:        row.Frozen,
+			Override:      row.OverrideWeight,
+			UpdatedAt:     row.UpdatedAt,
+		})
+		if row.ScoredThrough != nil && row.ScoredThrough.After(scoredThrough) {
+			scoredThrough = *row.ScoredThrough
+		}
+	}
+	return states, scoredThrough, nil
+}
+
+// Save upserts the weights and appends the changes to the audit trail
+func (s *dbWeightSource) Save(ctx context.Context, states []AgentWeightState, scoredThrough time.Time, changes []WeightChange) error {
+	var through *time.Time
+	if !scoredThrough.IsZero() {
+		through = &scoredThrough
+	}
+	weights := make([]*db.AgentWeight, 0, len(states))
+	for _, state := range states {
+		weights = append(weights, &db.AgentWeight{
+			AgentName:      state.Agent,
+			AgentType:      state.AgentType,
+			Weight:         state.Weight,
+			LearnedWeight:  state.LearnedWeight,
+			BaseWeight:     state.BaseWeight,
+			HitRate:        state.HitRate,
+			BrierScore:     state.BrierScore,
+			Samples:        state.Samples,
+			Frozen:         state.Frozen,
+			OverrideWeight: state.Override,
+			ScoredThrough:  through,
+		})
+	}
+	events := make([]*db.AgentWeightEvent, 0, len(changes))
+	for _, c := range changes {
+		events = append(events, &db.AgentWeightEvent{
+			AgentName:      c.Agent,
+			Reason:         c.Reason,
+			PreviousWeight: c.PreviousWeight,
+			Weight:         c.Weight,
+			HitRate:        c.HitRate,
+			BrierScore:     c.BrierScore,
+			Samples:        c.Samples,
+			Actor:          c.Actor,
+			Note:           c.Note,
+			CreatedAt:      c.At,
+		})
+	}
+	return s.db.SaveAgentWeights(ctx, weights, events)
+}
+
+// History returns an agent's latest weight changes, newest first
+func (s *dbWeightSource) History(ctx context.Context, agent string, limit int) ([]WeightChange, error) {
+	rows, err := s.db.ListAgentWeightEvents(ctx, agent, limit)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]WeightChange, 0, len(rows))
+	for _, row := range rows {
+		changes = append(changes, WeightChange{
+			Agent:          row.AgentName,
+			Reason:         row.Reason,
+			PreviousWeight: row.PreviousWeight,
+			Weight:         row.Weight,
+			HitRate:        row.HitRate,
+			BrierScore:     row.BrierScore,
+			Samples:        row.Samples,
+			Actor:          row.Actor,
+			Note:           row.Note,
+			At:             row.CreatedAt,
+		})
+	}
+	return changes, nil
+}
+
+// agentWeight returns the voting weight a new agent session starts with
+func (o *Orchestrator) agentWeight(name, agentType string) float64 {
+	if o.weights == nil {
+		return o.getDefaultWeight(agentType)
+	}
+	return o.weights.Weight(name, agentType)
+}
+
+// recordSignal stores a received signal for weight learning and confidence
+// calibration
+func (o *Orchestrator) recordSignal(signal *AgentSignal) {
+	if o.weightSource == nil || (o.weights == nil && !o.config.Calibration.Enabled) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := o.weightSource.RecordSignal(ctx, signal); err != nil {
+		o.log.Warn().Err(err).Str("agent", signal.AgentName).Msg("Failed to record agent signal")
+	}
+}
+
+// weightLearningLoop restores persisted weights and periodically learns new
+// ones from the signals whose horizon has passed
+func (o *Orchestrator) weightLearningLoop() {
+	defer o.wg.Done()
+
+	ctx, cancel := context.WithTimeout(o.ctx, 30*time.Second)
+	states, scoredThrough, err := o.weightSource.Load(ctx)
+	cancel()
+	if err != nil {
+		o.log.Warn().Err(err).Msg("Failed to load agent weights, learning from default weights")
+	} else {
+		o.weights.Restore(states, scoredThrough)
+		o.applyAgentWeights()
+	}
+
+	ticker := time.NewTicker(o.weights.Config().Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.ctx.Done():
+			return
+		case <-ticker.C:
+			o.learnWeights(o.ctx, time.Now())
+		}
+	}
+}
+
+// learnWeights scores the signals whose horizon ended since the last round
+// and applies the learned weights
+func (o *Orchestrator) learnWeights(ctx context.Context, now time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	config := o.weights.Config()
+	through := now.Add(-config.Horizon)
+	from := o.weights.ScoredThrough()
+	if from.IsZero() {
+		from = through.Add(-config.Lookback)
+	}
+	if !through.After(from) {
+		return
+	}
+
+	outcomes, err := o.weightSource.Outcomes(ctx, from, through, config.PriceInterval, config.Horizon)
+	if err != nil {
+		o.log.Warn().Err(err).Msg("Failed to load signal outcomes for weight learning")
+		return
+	}
+
+	changes := o.weights.Learn(outcomes, through, now)
+	if err := o.weightSource.Save(ctx, o.weights.Weights(), through, changes); err != nil {
+		o.log.Warn().Err(err).Msg("Failed to save learned agent weights")
+	}
+	o.applyAgentWeights()
+
+	for _, c := range changes {
+		o.metrics.WeightChanges.WithLabelValues(c.Reason).Inc()
+	}
+	o.log.Info().
+		Int("signals", len(outcomes)).
+		Int("changed", len(changes)).
+		Time("scored_through", through).
+		Msg("Learned agent weights")
+}
+
+// applyAgentWeights sets the voting weight of every agent session to its
+// learned weight
+func (o *Orchestrator) applyAgentWeights() {
+	o.agentsMutex.Lock()
+	defer o.agentsMutex.Unlock()
+
+	for _, session := range o.agents {
+		session.Weight = o.weights.Weight(session.Name, session.Type)
+	}
+	for _, state := range o.weights.Weights() {
+		o.metrics.AgentWeight.WithLabelValues(state.Agent).Set(state.Weight)
+	}
+}
+
+// adjustAgentWeight freezes, unfreezes or overrides an agent's weight and
+// persists the change
+func (o *Orchestrator) adjustAgentWeight(ctx context.Context, agent, action string, weight float64, actor, note string) (WeightChange, error) {
+	now := time.Now()
+	var c WeightChange
+	var err error
+	switch action {
+	case "freeze":
+		c, err = o.weights.Freeze(agent, actor, note, now)
+	case "unfreeze":
+		c, err = o.weights.Unfreeze(agent, actor, note, now)
+	case "override":
+		c, err = o.weights.Override(agent, weight, actor, note, now)
+	case "clear_override":
+		c, err = o.weights.ClearOverride(agent, actor, note, now)
+	default:
+		return WeightChange{}, errors.New("action must be freeze, unfreeze, override or clear_override")
+	}
+	if err != nil {
+		return WeightChange{}, err
+	}
+
+	if o.weightSource != nil {
+		if err := o.weightSource.Save(ctx, o.weights.Weights(), o.weights.ScoredThrough(), []WeightChange{c}); err != nil {
+			o.log.Warn().Err(err).Str("agent", agent).Msg("Failed to save agent weight change")
+		}
+	}
+	o.applyAgentWeights()
+	o.metrics.WeightChanges.WithLabelValues(c.Reason).Inc()
+
+	o.log.Info().
+		Str("agent", agent).
+		Str("action", action).
+		Float64("previous_weight", c.PreviousWeight).
+		Float64("weight", c.Weight).
+		Str("actor", actor).
+		Msg("Agent weight adjusted")
+	return c, nil
+}
+
+// HandleAgentWeightsRequest handles GET and POST /api/v1/agent-weights. GET
+// lists the weights, with the audit trail of ?agent=<name>; POST freezes,
+// unfreezes, overrides or clears the override of an agent's weight.
+func (o *Orchestrator) HandleAgentWeightsRequest(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		o.handleGetAgentWeights(w, r)
+	case http.MethodPost:
+		o.handleAdjustAgentWeight(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (o *Orchestrator) handleGetAgentWeights(w http.ResponseWriter, r *http.Request) {
+	if o.weights == nil {
+		o.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"enabled": false,
+			"weights": []AgentWeightState{},
+		})
+		return
+	}
+
+	config := o.weights.Config()
+	response := map[string]interface{}{
+		"enabled":    true,
+		"method":     config.Method,
+		"horizon":    config.Horizon.String(),
+		"min_weight": config.MinWeight,
+		"max_weight": config.MaxWeight,
+		"weights":    o.weights.Weights(),
+	}
+	if through := o.weights.ScoredThrough(); !through.IsZero() {
+		response["scored_through"] = through.UTC().Format(time.RFC3339)
+	}
+
+	if agent := r.URL.Query().Get("agent"); agent != "" && o.weightSource != nil {
+		history, err := o.weightSource.History(r.Context(), agent, weightHistoryLimit)
+		if err != nil {
+			o.log.Error().Err(err).Str("agent", agent).Msg("Failed to load agent weight history")
+			http.Error(w, "Failed to load agent weight history", http.StatusInternalServerError)
+			return
+		}
+		response["history"] = history
+	}
+
+	o.writeJSON(w, http.StatusOK, response)
+}
+
+func (o *Orchestrator) handleAdjustAgentWeight(w http.ResponseWriter, r *http.Request) {
+	if o.weights == nil {
+		http.Error(w, "Agent weight learning is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	var request struct {
+		Agent  string  `json:"agent"`
+		Action string  `json:"action"` // freeze, unfreeze, override, clear_override
+		Weight float64 `json:"weight"` // Required for override
+		Actor  string  `json:"actor"`
+		Note   string  `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if request.Agent == "" {
+		http.Error(w, "agent is required", http.StatusBadRequest)
+		return
+	}
+
+	c, err := o.adjustAgentWeight(r.Context(), request.Agent, request.Action, request.Weight, request.Actor, request.Note)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	o.writeJSON(w, http.StatusOK, c)
+}
+
+// writeJSON writes a JSON response
+func (o *Orchestrator) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		o.log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
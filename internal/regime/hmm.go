@@ -0,0 +1,427 @@
+// Package regime detects market regimes by fitting a Gaussian hidden Markov
+// model to returns and volatility, and shares the regime probabilities over
+// NATS and the database
+package regime
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// varianceFloor keeps a state's variance from collapsing onto a few points,
+// as a fraction of the overall variance of each feature
+const varianceFloor = 1e-3
+
+// ErrTooFewObservations is returned when there is too little data to fit a model
+var ErrTooFewObservations = errors.New("too few observations to fit regime model")
+
+// HMM is a Gaussian hidden Markov model with diagonal covariances
+type HMM struct {
+	Initial    []float64   `json:"initial"`    // P(state) at the first observation
+	Transition [][]float64 `json:"transition"` // P(next state | state)
+	Means      [][]float64 `json:"means"`      // Per state, per feature
+	Variances  [][]float64 `json:"variances"`  // Per state, per feature
+}
+
+// States returns the number of hidden states
+func (m *HMM) States() int {
+	return len(m.Initial)
+}
+
+// FitHMM fits a model with the given number of states to the observations by
+// Baum-Welch. The starting point comes from k-means on standardized features,
+// so a fit on the same data is reproducible. It returns the model and the log
+// likelihood of the observations under it.
+func FitHMM(obs [][]float64, states, maxIterations int, tolerance float64) (*HMM, float64, error) {
+	if states < 1 {
+		return nil, 0, fmt.Errorf("states must be at least 1, got %d", states)
+	}
+	if len(obs) < 2*states || len(obs) < 2 {
+		return nil, 0, fmt.Errorf("%w: %d for %d states", ErrTooFewObservations, len(obs), states)
+	}
+	dim := len(obs[0])
+	for _, o := range obs {
+		if len(o) != dim {
+			return nil, 0, fmt.Errorf("observations have mixed dimensions")
+		}
+	}
+
+	_, globalVar := moments(obs, nil)
+	floor := make([]float64, dim)
+	for d := range floor {
+		floor[d] = math.Max(globalVar[d]*varianceFloor, 1e-12)
+	}
+
+	m := initialModel(obs, states, globalVar, floor)
+	T := len(obs)
+
+	logLik := math.Inf(-1)
+	for iter := 0; iter < maxIterations; iter++ {
+		b, shift := m.emissions(obs)
+		alpha, scale := m.forward(b)
+		beta := m.backward(b, scale)
+
+		current := 0.0
+		for t := 0; t < T; t++ {
+			current += math.Log(scale[t]) + shift[t]
+		}
+
+		// E-step: state and transition posteriors
+		gamma := make([][]float64, T)
+		for t := range gamma {
+			gamma[t] = make([]float64, states)
+			sum := 0.0
+			for k := 0; k < states; k++ {
+				gamma[t][k] = alpha[t][k] * beta[t][k]
+				sum += gamma[t][k]
+			}
+			normalize(gamma[t], sum)
+		}
+		xi := make([][]float64, states)
+		for j := range xi {
+			xi[j] = make([]float64, states)
+		}
+		for t := 0; t < T-1; t++ {
+			sum := 0.0
+			step := make([][]float64, states)
+			for j := 0; j < states; j++ {
+				step[j] = make([]float64, states)
+				for k := 0; k < states; k++ {
+					step[j][k] = alpha[t][j] * m.Transition[j][k] * b[t+1][k] * beta[t+1][k]
+					sum += step[j][k]
+				}
+			}
+			if sum <= 0 {
+				continue
+			}
+			for j := 0; j < states; j++ {
+				for k := 0; k < states; k++ {
+					xi[j][k] += step[j][k] / sum
+				}
+			}
+		}
+
+		// M-step
+		copy(m.Initial, gamma[0])
+		for j := 0; j < states; j++ {
+			sum := 0.0
+			for k := 0; k < states; k++ {
+				sum += xi[j][k]
+			}
+			if sum <= 0 {
+				continue // State never left; keep its transitions
+			}
+			for k := 0; k < states; k++ {
+				m.Transition[j][k] = xi[j][k] / sum
+			}
+		}
+		for k := 0; k < states; k++ {
+			weights := make([]float64, T)
+			for t := range weights {
+				weights[t] = gamma[t][k]
+			}
+			mean, variance := moments(obs, weights)
+			if mean == nil {
+				continue // State has no weight; keep its emissions
+			}
+			m.Means[k] = mean
+			for d := range variance {
+				variance[d] = math.Max(variance[d], floor[d])
+			}
+			m.Variances[k] = variance
+		}
+
+		if iter > 0 && current-logLik < tolerance {
+			logLik = current
+			break
+		}
+		logLik = current
+	}
+
+	return m, m.LogLikelihood(obs), nil
+}
+
+// Filter returns P(state at t | observations up to t) for every t
+func (m *HMM) Filter(obs [][]float64) [][]float64 {
+	if len(obs) == 0 {
+		return nil
+	}
+	b, _ := m.emissions(obs)
+	alpha, _ := m.forward(b)
+	return alpha
+}
+
+// LogLikelihood returns the log likelihood of the observations
+func (m *HMM) LogLikelihood(obs [][]float64) float64 {
+	if len(obs) == 0 {
+		return 0
+	}
+	b, shift := m.emissions(obs)
+	_, scale := m.forward(b)
+	logLik := 0.0
+	for t := range scale {
+		logLik += math.Log(scale[t]) + shift[t]
+	}
+	return logLik
+}
+
+// emissions returns each state's observation density at every t, divided by
+// the largest one at t to avoid underflow, and the log of that divisor
+func (m *HMM) emissions(obs [][]float64) ([][]float64, []float64) {
+	states := m.States()
+	b := make([][]float64, len(obs))
+	shift := make([]float64, len(obs))
+	for t, o := range obs {
+		b[t] = make([]float64, states)
+		maxLog := math.Inf(-1)
+		for k := 0; k < states; k++ {
+			b[t][k] = m.logDensity(k, o)
+			maxLog = math.Max(maxLog, b[t][k])
+		}
+		for k := 0; k < states; k++ {
+			b[t][k] = math.Exp(b[t][k] - maxLog)
+		}
+		shift[t] = maxLog
+	}
+	return b, shift
+}
+
+// logDensity is the log of state k's Gaussian density at o
+func (m *HMM) logDensity(k int, o []float64) float64 {
+	logP := 0.0
+	for d, x := range o {
+		v := m.Variances[k][d]
+		diff := x - m.Means[k][d]
+		logP -= 0.5 * (math.Log(2*math.Pi*v) + diff*diff/v)
+	}
+	return logP
+}
+
+// forward runs the scaled forward pass. Each row of alpha sums to 1 and is
+// the filtered state distribution; scale holds the normalizers.
+func (m *HMM) forward(b [][]float64) ([][]float64, []float64) {
+	states := m.States()
+	T := len(b)
+	alpha := make([][]float64, T)
+	scale := make([]float64, T)
+	for t := 0; t < T; t++ {
+		alpha[t] = make([]float64, states)
+		for k := 0; k < states; k++ {
+			if t == 0 {
+				alpha[t][k] = m.Initial[k] * b[t][k]
+				continue
+			}
+			p := 0.0
+			for j := 0; j < states; j++ {
+				p += alpha[t-1][j] * m.Transition[j][k]
+			}
+			alpha[t][k] = p * b[t][k]
+		}
+		sum := 0.0
+		for _, a := range alpha[t] {
+			sum += a
+		}
+		if sum <= 0 {
+			// No state explains the observation; fall back to uniform
+			sum = 1e-300
+			for k := range alpha[t] {
+				alpha[t][k] = 1.0 / float64(states)
+			}
+			scale[t] = sum
+			continue
+		}
+		scale[t] = sum
+		normalize(alpha[t], sum)
+	}
+	return alpha, scale
+}
+
+// backward runs the backward pass with the forward pass's scaling
+func (m *HMM) backward(b [][]float64, scale []float64) [][]float64 {
+	states := m.States()
+	T := len(b)
+	beta := make([][]float64, T)
+	beta[T-1] = make([]float64, states)
+	for k := range beta[T-1] {
+		beta[T-1][k] = 1
+	}
+	for t := T - 2; t >= 0; t-- {
+		beta[t] = make([]float64, states)
+		for j := 0; j < states; j++ {
+			sum := 0.0
+			for k := 0; k < states; k++ {
+				sum += m.Transition[j][k] * b[t+1][k] * beta[t+1][k]
+			}
+			beta[t][j] = sum / scale[t+1]
+		}
+	}
+	return beta
+}
+
+// initialModel seeds the states with k-means clusters of the standardized
+// observations, started from quantiles of their feature sum
+func initialModel(obs [][]float64, states int, globalVar, floor []float64) *HMM {
+	dim := len(obs[0])
+	mean, _ := moments(obs, nil)
+	std := make([]float64, dim)
+	for d := range std {
+		std[d] = math.Sqrt(math.Max(globalVar[d], 1e-24))
+	}
+	z := make([][]float64, len(obs))
+	for t, o := range obs {
+		z[t] = make([]float64, dim)
+		for d := range o {
+			z[t][d] = (o[d] - mean[d]) / std[d]
+		}
+	}
+
+	order := make([]int, len(z))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return sum(z[order[i]]) < sum(z[order[j]]) })
+	centroids := make([][]float64, states)
+	for k := range centroids {
+		centroids[k] = append([]float64(nil), z[order[(2*k+1)*len(z)/(2*states)]]...)
+	}
+
+	assign := make([]int, len(z))
+	for iter := 0; iter < 20; iter++ {
+		changed := false
+		for t, p := range z {
+			best, bestDist := 0, math.Inf(1)
+			for k, c := range centroids {
+				dist := 0.0
+				for d := range p {
+					dist += (p[d] - c[d]) * (p[d] - c[d])
+				}
+				if dist < bestDist {
+					best, bestDist = k, dist
+				}
+			}
+			if assign[t] != best {
+				assign[t] = best
+				changed = true
+			}
+		}
+		for k := range centroids {
+			count := 0
+			next := make([]float64, dim)
+			for t, p := range z {
+				if assign[t] != k {
+					continue
+				}
+				count++
+				for d := range p {
+					next[d] += p[d]
+				}
+			}
+			if count == 0 {
+				continue
+			}
+			for d := range next {
+				next[d] /= float64(count)
+			}
+			centroids[k] = next
+		}
+		if !changed {
+			break
+		}
+	}
+
+	m := &HMM{
+		Initial:    make([]float64, states),
+		Transition: make([][]float64, states),
+		Means:      make([][]float64, states),
+		Variances:  make([][]float64, states),
+	}
+	stay := 0.9
+	if states == 1 {
+		stay = 1
+	}
+	for k := 0; k < states; k++ {
+		m.Initial[k] = 1.0 / float64(states)
+		m.Transition[k] = make([]float64, states)
+		for j := range m.Transition[k] {
+			if j == k {
+				m.Transition[k][j] = stay
+			} else {
+				m.Transition[k][j] = (1 - stay) / float64(states-1)
+			}
+		}
+
+		weights := make([]float64, len(obs))
+		for t := range weights {
+			if assign[t] == k {
+				weights[t] = 1
+			}
+		}
+		means, variance := moments(obs, weights)
+		if means == nil {
+			means, variance = mean, append([]float64(nil), globalVar...)
+		}
+		for d := range variance {
+			variance[d] = math.Max(variance[d], floor[d])
+		}
+		m.Means[k] = means
+		m.Variances[k] = variance
+	}
+	return m
+}
+
+// moments returns the weighted mean and variance of each feature, or nil when
+// the weights sum to zero. Nil weights count every observation once.
+func moments(obs [][]float64, weights []float64) ([]float64, []float64) {
+	dim := len(obs[0])
+	mean := make([]float64, dim)
+	total := 0.0
+	for t, o := range obs {
+		w := 1.0
+		if weights != nil {
+			w = weights[t]
+		}
+		total += w
+		for d, x := range o {
+			mean[d] += w * x
+		}
+	}
+	if total <= 0 {
+		return nil, nil
+	}
+	for d := range mean {
+		mean[d] /= total
+	}
+	variance := make([]float64, dim)
+	for t, o := range obs {
+		w := 1.0
+		if weights != nil {
+			w = weights[t]
+		}
+		for d, x := range o {
+			variance[d] += w * (x - mean[d]) * (x - mean[d])
+		}
+	}
+	for d := range variance {
+		variance[d] /= total
+	}
+	return mean, variance
+}
+
+func normalize(values []float64, total float64) {
+	if total <= 0 {
+		return
+	}
+	for i := range values {
+		values[i] /= total
+	}
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
@@ -0,0 +1,234 @@
+package regime
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Regime is a market regime
+type Regime string
+
+const (
+	RegimeBullish  Regime = "bullish"  // Drifting up
+	RegimeBearish  Regime = "bearish"  // Drifting down
+	RegimeSideways Regime = "sideways" // No clear drift
+	RegimeVolatile Regime = "volatile" // Volatility well above the calm states
+)
+
+// Regimes lists every regime
+var Regimes = []Regime{RegimeBullish, RegimeBearish, RegimeSideways, RegimeVolatile}
+
+// Detector defaults
+const (
+	DefaultStates           = 3
+	DefaultVolatilityWindow = 20
+	DefaultMaxIterations    = 100
+	DefaultTolerance        = 1e-4
+
+	// volatileRatio is how much more volatile than the calmest state a state
+	// must be to be labelled volatile
+	volatileRatio = 1.5
+	// minObservationsPerState is the least data per state worth fitting
+	minObservationsPerState = 15
+)
+
+// Config configures a Detector. Zero values use the defaults.
+type Config struct {
+	States           int     `json:"states" yaml:"states" mapstructure:"states"`                                  // Hidden states in the model
+	VolatilityWindow int     `json:"volatility_window" yaml:"volatility_window" mapstructure:"volatility_window"` // Returns in the rolling volatility feature
+	MaxIterations    int     `json:"max_iterations" yaml:"max_iterations" mapstructure:"max_iterations"`          // Baum-Welch iterations
+	Tolerance        float64 `json:"tolerance" yaml:"tolerance" mapstructure:"tolerance"`                         // Stop when the log likelihood improves by less
+}
+
+// withDefaults fills in zero values
+func (c Config) withDefaults() Config {
+	if c.States <= 0 {
+		c.States = DefaultStates
+	}
+	if c.VolatilityWindow <= 1 {
+		c.VolatilityWindow = DefaultVolatilityWindow
+	}
+	if c.MaxIterations <= 0 {
+		c.MaxIterations = DefaultMaxIterations
+	}
+	if c.Tolerance <= 0 {
+		c.Tolerance = DefaultTolerance
+	}
+	return c
+}
+
+// MinPrices returns the fewest closing prices Detect accepts
+func (c Config) MinPrices() int {
+	c = c.withDefaults()
+	return c.VolatilityWindow + 1 + c.States*minObservationsPerState
+}
+
+// StateEstimate describes one hidden state of a fitted model
+type StateEstimate struct {
+	Regime      Regime  `json:"regime"`
+	Probability float64 `json:"probability"` // P(state) at the latest observation
+	MeanReturn  float64 `json:"mean_return"` // Mean log return per candle
+	Volatility  float64 `json:"volatility"`  // Typical rolling volatility of log returns per candle
+	Persistence float64 `json:"persistence"` // P(staying in the state for the next candle)
+}
+
+// Estimate is the regime of a symbol at its latest candle
+type Estimate struct {
+	Symbol        string             `json:"symbol"`
+	Interval      string             `json:"interval"`
+	Regime        Regime             `json:"regime"`        // Most likely regime
+	Confidence    float64            `json:"confidence"`    // Its probability
+	Probabilities map[Regime]float64 `json:"probabilities"` // Every regime; states with the same label are summed
+	States        []StateEstimate    `json:"states"`
+	LogLikelihood float64            `json:"log_likelihood"`
+	Observations  int                `json:"observations"`
+	DetectedAt    time.Time          `json:"detected_at"`
+}
+
+// Probability returns the probability of a regime
+func (e *Estimate) Probability(r Regime) float64 {
+	if e == nil {
+		return 0
+	}
+	return e.Probabilities[r]
+}
+
+// Detector fits a Gaussian HMM to the log returns and log rolling volatility
+// of a price series and labels its states
+type Detector struct {
+	config Config
+}
+
+// NewDetector creates a detector
+func NewDetector(config Config) *Detector {
+	return &Detector{config: config.withDefaults()}
+}
+
+// Config returns the detector's configuration with defaults filled in
+func (d *Detector) Config() Config {
+	return d.config
+}
+
+// Detect fits a model to closing prices, oldest first, and returns the
+// regime probabilities at the last price. Symbol, interval and time are left
+// for the caller to fill in.
+func (d *Detector) Detect(prices []float64) (*Estimate, error) {
+	if len(prices) < d.config.MinPrices() {
+		return nil, fmt.Errorf("%w: need %d prices, got %d", ErrTooFewObservations, d.config.MinPrices(), len(prices))
+	}
+	obs, err := Features(prices, d.config.VolatilityWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	model, logLik, err := FitHMM(obs, d.config.States, d.config.MaxIterations, d.config.Tolerance)
+	if err != nil {
+		return nil, err
+	}
+	filtered := model.Filter(obs)
+	latest := filtered[len(filtered)-1]
+	labels := Label(model)
+
+	estimate := &Estimate{
+		Probabilities: make(map[Regime]float64, len(Regimes)),
+		States:        make([]StateEstimate, model.States()),
+		LogLikelihood: logLik,
+		Observations:  len(obs),
+	}
+	for _, r := range Regimes {
+		estimate.Probabilities[r] = 0
+	}
+	for k := range estimate.States {
+		estimate.States[k] = StateEstimate{
+			Regime:      labels[k],
+			Probability: latest[k],
+			MeanReturn:  model.Means[k][0],
+			Volatility:  math.Exp(model.Means[k][1]),
+			Persistence: model.Transition[k][k],
+		}
+		estimate.Probabilities[labels[k]] += latest[k]
+	}
+	for _, r := range Regimes {
+		if p := estimate.Probabilities[r]; p > estimate.Confidence {
+			estimate.Regime, estimate.Confidence = r, p
+		}
+	}
+	return estimate, nil
+}
+
+// Features turns closing prices into observations of [log return, log
+// rolling volatility]. The first observation is at the window-th return.
+func Features(prices []float64, window int) ([][]float64, error) {
+	if window < 2 {
+		return nil, fmt.Errorf("volatility window must be at least 2, got %d", window)
+	}
+	returns := make([]float64, 0, len(prices))
+	for i := 1; i < len(prices); i++ {
+		if prices[i-1] <= 0 || prices[i] <= 0 {
+			return nil, fmt.Errorf("non-positive price at index %d", i)
+		}
+		returns = append(returns, math.Log(prices[i]/prices[i-1]))
+	}
+	if len(returns) < window {
+		return nil, fmt.Errorf("%w: %d returns for a %d-return window", ErrTooFewObservations, len(returns), window)
+	}
+
+	obs := make([][]float64, 0, len(returns)-window+1)
+	for t := window - 1; t < len(returns); t++ {
+		recent := returns[t-window+1 : t+1]
+		mean := sum(recent) / float64(window)
+		variance := 0.0
+		for _, r := range recent {
+			variance += (r - mean) * (r - mean)
+		}
+		vol := math.Sqrt(variance / float64(window-1))
+		obs = append(obs, []float64{returns[t], math.Log(vol + 1e-12)})
+	}
+	return obs, nil
+}
+
+// Label names the states of a model fitted on Features. With three or more
+// states the most volatile is volatile when it is clearly more volatile than
+// the calmest. Of the rest, the state whose drift is smallest relative to its
+// return volatility is sideways, and the others are bullish or bearish by the
+// sign of their drift.
+func Label(m *HMM) []Regime {
+	states := m.States()
+	labels := make([]Regime, states)
+	remaining := make([]int, 0, states)
+	for k := 0; k < states; k++ {
+		remaining = append(remaining, k)
+	}
+
+	if states >= 3 {
+		sort.SliceStable(remaining, func(i, j int) bool {
+			return m.Means[remaining[i]][1] < m.Means[remaining[j]][1]
+		})
+		calmest, wildest := remaining[0], remaining[len(remaining)-1]
+		if math.Exp(m.Means[wildest][1]-m.Means[calmest][1]) >= volatileRatio {
+			labels[wildest] = RegimeVolatile
+			remaining = remaining[:len(remaining)-1]
+		}
+	}
+
+	sideways, smallest := -1, math.Inf(1)
+	for _, k := range remaining {
+		drift := math.Abs(m.Means[k][0]) / math.Sqrt(m.Variances[k][0])
+		if drift < smallest {
+			sideways, smallest = k, drift
+		}
+	}
+	for _, k := range remaining {
+		switch {
+		case k == sideways:
+			labels[k] = RegimeSideways
+		case m.Means[k][0] > 0:
+			labels[k] = RegimeBullish
+		default:
+			labels[k] = RegimeBearish
+		}
+	}
+	return labels
+}
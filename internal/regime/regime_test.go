@@ -0,0 +1,139 @@
+package regime
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// segment is a stretch of candles with a constant drift and volatility
+type segment struct {
+	n     int
+	drift float64
+	vol   float64
+}
+
+// syntheticPrices builds a price path from segments of log returns
+func syntheticPrices(seed int64, segments ...segment) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	prices := []float64{100}
+	for _, s := range segments {
+		for i := 0; i < s.n; i++ {
+			r := s.drift + s.vol*rng.NormFloat64()
+			prices = append(prices, prices[len(prices)-1]*math.Exp(r))
+		}
+	}
+	return prices
+}
+
+func TestFitHMM_RecoversSeparatedStates(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	var obs [][]float64
+	for block := 0; block < 6; block++ {
+		mean := 0.0
+		if block%2 == 1 {
+			mean = 5
+		}
+		for i := 0; i < 50; i++ {
+			obs = append(obs, []float64{mean + rng.NormFloat64()})
+		}
+	}
+
+	m, logLik, err := FitHMM(obs, 2, 100, 1e-6)
+	require.NoError(t, err)
+	assert.False(t, math.IsNaN(logLik))
+
+	low, high := 0, 1
+	if m.Means[0][0] > m.Means[1][0] {
+		low, high = 1, 0
+	}
+	assert.InDelta(t, 0, m.Means[low][0], 0.3)
+	assert.InDelta(t, 5, m.Means[high][0], 0.3)
+	assert.Greater(t, m.Transition[low][low], 0.9, "blocks of 50 are persistent")
+
+	filtered := m.Filter(obs)
+	require.Len(t, filtered, len(obs))
+	assert.Greater(t, filtered[len(obs)-1][high], 0.99)
+	assert.Greater(t, filtered[25][low], 0.99)
+	assert.InDelta(t, 1, filtered[100][0]+filtered[100][1], 1e-9)
+
+	// Refitting the same data gives the same model
+	again, againLogLik, err := FitHMM(obs, 2, 100, 1e-6)
+	require.NoError(t, err)
+	assert.Equal(t, m.Means, again.Means)
+	assert.Equal(t, logLik, againLogLik)
+}
+
+func TestFitHMM_RejectsTooLittleData(t *testing.T) {
+	_, _, err := FitHMM([][]float64{{1}, {2}}, 3, 10, 1e-4)
+	assert.ErrorIs(t, err, ErrTooFewObservations)
+	_, _, err = FitHMM([][]float64{{1}, {2}}, 0, 10, 1e-4)
+	assert.Error(t, err)
+}
+
+func TestFeatures(t *testing.T) {
+	prices := []float64{100, 101, 100, 102, 101}
+	obs, err := Features(prices, 3)
+	require.NoError(t, err)
+	require.Len(t, obs, 2)
+	assert.InDelta(t, math.Log(102.0/100), obs[0][0], 1e-12)
+
+	_, err = Features(prices, 5)
+	assert.ErrorIs(t, err, ErrTooFewObservations)
+	_, err = Features([]float64{100, 0, 100}, 2)
+	assert.Error(t, err)
+}
+
+func TestLabel(t *testing.T) {
+	m := &HMM{
+		Initial: []float64{0.25, 0.25, 0.25, 0.25},
+		Means: [][]float64{
+			{0.002, math.Log(0.01)},   // Drifting up
+			{-0.002, math.Log(0.01)},  // Drifting down
+			{0.0001, math.Log(0.008)}, // Flat
+			{0, math.Log(0.04)},       // Wild
+		},
+		Variances: [][]float64{{1e-4, 0.1}, {1e-4, 0.1}, {1e-4, 0.1}, {1e-3, 0.1}},
+	}
+	assert.Equal(t, []Regime{RegimeBullish, RegimeBearish, RegimeSideways, RegimeVolatile}, Label(m))
+
+	// A state only slightly more volatile than the rest is not volatile
+	m.Means[3][1] = math.Log(0.011)
+	assert.NotContains(t, Label(m), RegimeVolatile)
+}
+
+func TestDetector_Detect(t *testing.T) {
+	d := NewDetector(Config{})
+
+	calm := segment{n: 250, drift: 0, vol: 0.004}
+	rally := segment{n: 250, drift: 0.004, vol: 0.004}
+	crash := segment{n: 250, drift: 0, vol: 0.03}
+
+	estimate, err := d.Detect(syntheticPrices(1, calm, crash, rally))
+	require.NoError(t, err)
+	assert.Equal(t, RegimeBullish, estimate.Regime)
+	assert.Greater(t, estimate.Confidence, 0.8)
+	assert.Len(t, estimate.States, DefaultStates)
+	assert.Contains(t, []Regime{estimate.States[0].Regime, estimate.States[1].Regime, estimate.States[2].Regime}, RegimeVolatile)
+
+	total := 0.0
+	for _, r := range Regimes {
+		total += estimate.Probability(r)
+	}
+	assert.InDelta(t, 1, total, 1e-9)
+
+	estimate, err = d.Detect(syntheticPrices(2, rally, calm, crash))
+	require.NoError(t, err)
+	assert.Equal(t, RegimeVolatile, estimate.Regime)
+
+	selloff := segment{n: 250, drift: -0.004, vol: 0.004}
+	estimate, err = d.Detect(syntheticPrices(4, crash, calm, selloff))
+	require.NoError(t, err)
+	assert.Equal(t, RegimeBearish, estimate.Regime)
+
+	_, err = d.Detect(syntheticPrices(3, segment{n: 30, vol: 0.01}))
+	assert.ErrorIs(t, err, ErrTooFewObservations)
+}
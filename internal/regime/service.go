@@ -0,0 +1,289 @@
+package regime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// Service defaults
+const (
+	DefaultSubject      = "cryptofunk.market.regime"
+	DefaultInterval     = 15 * time.Minute
+	DefaultTimeframe    = "1h"
+	DefaultLookbackDays = 60
+)
+
+// ServiceConfig configures the regime service
+type ServiceConfig struct {
+	Enabled      bool          `json:"enabled" yaml:"enabled" mapstructure:"enabled"`
+	Symbols      []string      `json:"symbols" yaml:"symbols" mapstructure:"symbols"`                   // Symbols to detect regimes for
+	Interval     time.Duration `json:"interval" yaml:"interval" mapstructure:"interval"`                // How often models are refitted
+	Timeframe    string        `json:"timeframe" yaml:"timeframe" mapstructure:"timeframe"`             // Candle interval the model is fitted on
+	LookbackDays int           `json:"lookback_days" yaml:"lookback_days" mapstructure:"lookback_days"` // Days of candles in each fit
+	Subject      string        `json:"subject" yaml:"subject" mapstructure:"subject"`                   // NATS subject prefix; estimates go to <subject>.<symbol>
+	Model        Config        `json:"model" yaml:"model" mapstructure:"model"`
+}
+
+// withDefaults fills in zero values
+func (c ServiceConfig) withDefaults() ServiceConfig {
+	if c.Interval <= 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.Timeframe == "" {
+		c.Timeframe = DefaultTimeframe
+	}
+	if c.LookbackDays <= 0 {
+		c.LookbackDays = DefaultLookbackDays
+	}
+	if c.Subject == "" {
+		c.Subject = DefaultSubject
+	}
+	return c
+}
+
+// PriceSource supplies closing prices; *risk.Calculator is one
+type PriceSource interface {
+	LoadHistoricalPrices(ctx context.Context, symbol, interval string, days int) (*risk.HistoricalData, error)
+}
+
+// Store persists estimates; *db.DB is one
+type Store interface {
+	InsertMarketRegime(ctx context.Context, regime *db.MarketRegime) error
+}
+
+// Publisher sends estimates to subscribers; *nats.Conn is one
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// Subject returns the NATS subject a symbol's estimates are published on
+func Subject(prefix, symbol string) string {
+	return prefix + "." + symbolKey(symbol)
+}
+
+// symbolKey reduces a symbol to a NATS token that is the same for "BTC/USDT",
+// "BTC-USDT" and "btcusdt"
+func symbolKey(symbol string) string {
+	return strings.ToUpper(strings.NewReplacer("/", "", "-", "", "_", "", ".", "", " ", "").Replace(symbol))
+}
+
+// regimeMetrics holds Prometheus metrics for the regime service
+type regimeMetrics struct {
+	probability *prometheus.GaugeVec
+	changes     *prometheus.CounterVec
+	failures    *prometheus.CounterVec
+}
+
+var (
+	regimeMetricsInstance *regimeMetrics
+	regimeMetricsOnce     sync.Once
+)
+
+// getRegimeMetrics returns the singleton metrics instance
+func getRegimeMetrics() *regimeMetrics {
+	regimeMetricsOnce.Do(func() {
+		regimeMetricsInstance = &regimeMetrics{
+			probability: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "cryptofunk_market_regime_probability",
+				Help: "Probability of each market regime at the latest candle",
+			}, []string{"symbol", "regime"}),
+			changes: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "cryptofunk_market_regime_changes_total",
+				Help: "Changes of the most likely market regime",
+			}, []string{"symbol", "regime"}),
+			failures: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "cryptofunk_market_regime_failures_total",
+				Help: "Regime model fits that failed",
+			}, []string{"symbol"}),
+		}
+	})
+	return regimeMetricsInstance
+}
+
+// Service periodically fits a regime model per symbol, stores the estimates
+// and publishes them to NATS. The latest estimates are also kept in memory.
+type Service struct {
+	config    ServiceConfig
+	detector  *Detector
+	source    PriceSource
+	store     Store
+	publisher Publisher
+	metrics   *regimeMetrics
+
+	mu     sync.RWMutex
+	latest map[string]*Estimate // symbol key -> estimate
+}
+
+// NewService creates a regime service. The store and publisher are optional.
+func NewService(config ServiceConfig, source PriceSource, store Store, publisher Publisher) *Service {
+	config = config.withDefaults()
+	return &Service{
+		config:    config,
+		detector:  NewDetector(config.Model),
+		source:    source,
+		store:     store,
+		publisher: publisher,
+		metrics:   getRegimeMetrics(),
+		latest:    make(map[string]*Estimate),
+	}
+}
+
+// Config returns the service configuration with defaults filled in
+func (s *Service) Config() ServiceConfig {
+	return s.config
+}
+
+// Run detects regimes at once and then every interval until ctx is done
+func (s *Service) Run(ctx context.Context) {
+	log.Info().
+		Strs("symbols", s.config.Symbols).
+		Dur("interval", s.config.Interval).
+		Str("timeframe", s.config.Timeframe).
+		Int("states", s.detector.config.States).
+		Msg("Starting market regime service")
+
+	s.DetectAll(ctx)
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Market regime service stopped")
+			return
+		case <-ticker.C:
+			s.DetectAll(ctx)
+		}
+	}
+}
+
+// DetectAll detects the regime of every configured symbol. A symbol that
+// fails is logged and skipped.
+func (s *Service) DetectAll(ctx context.Context) {
+	for _, symbol := range s.config.Symbols {
+		if _, err := s.Detect(ctx, symbol); err != nil {
+			s.metrics.failures.WithLabelValues(symbol).Inc()
+			log.Warn().Err(err).Str("symbol", symbol).Msg("Market regime detection failed")
+		}
+	}
+}
+
+// Detect fits the regime model to a symbol's recent candles, then stores and
+// publishes the estimate
+func (s *Service) Detect(ctx context.Context, symbol string) (*Estimate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	history, err := s.source.LoadHistoricalPrices(ctx, symbol, s.config.Timeframe, s.config.LookbackDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prices: %w", err)
+	}
+	estimate, err := s.detector.Detect(history.Prices)
+	if err != nil {
+		return nil, err
+	}
+	estimate.Symbol = symbol
+	estimate.Interval = s.config.Timeframe
+	estimate.DetectedAt = time.Now()
+
+	previous := s.Latest(symbol)
+	s.mu.Lock()
+	s.latest[symbolKey(symbol)] = estimate
+	s.mu.Unlock()
+
+	for _, r := range Regimes {
+		s.metrics.probability.WithLabelValues(symbol, string(r)).Set(estimate.Probabilities[r])
+	}
+	if previous == nil || previous.Regime != estimate.Regime {
+		s.metrics.changes.WithLabelValues(symbol, string(estimate.Regime)).Inc()
+		log.Info().
+			Str("symbol", symbol).
+			Str("regime", string(estimate.Regime)).
+			Float64("confidence", estimate.Confidence).
+			Msg("Market regime changed")
+	}
+
+	if s.store != nil {
+		if err := s.store.InsertMarketRegime(ctx, ToRecord(estimate)); err != nil {
+			log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to store market regime")
+		}
+	}
+	if s.publisher != nil {
+		data, err := json.Marshal(estimate)
+		if err != nil {
+			return estimate, fmt.Errorf("failed to marshal regime estimate: %w", err)
+		}
+		if err := s.publisher.Publish(Subject(s.config.Subject, symbol), data); err != nil {
+			log.Warn().Err(err).Str("symbol", symbol).Msg("Failed to publish market regime")
+		}
+	}
+
+	log.Debug().
+		Str("symbol", symbol).
+		Str("regime", string(estimate.Regime)).
+		Interface("probabilities", estimate.Probabilities).
+		Int("observations", estimate.Observations).
+		Msg("Market regime detected")
+	return estimate, nil
+}
+
+// Latest returns the latest estimate for a symbol, or nil
+func (s *Service) Latest(symbol string) *Estimate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.latest[symbolKey(symbol)]
+}
+
+// ToRecord converts an estimate to its database record
+func ToRecord(e *Estimate) *db.MarketRegime {
+	probabilities := make(map[string]float64, len(e.Probabilities))
+	for r, p := range e.Probabilities {
+		probabilities[string(r)] = p
+	}
+	states, _ := json.Marshal(e.States) // Plain structs always marshal
+	return &db.MarketRegime{
+		Symbol:        e.Symbol,
+		Interval:      e.Interval,
+		Regime:        string(e.Regime),
+		Confidence:    e.Confidence,
+		Probabilities: probabilities,
+		States:        states,
+		LogLikelihood: e.LogLikelihood,
+		Observations:  e.Observations,
+		DetectedAt:    e.DetectedAt,
+	}
+}
+
+// FromRecord converts a database record back to an estimate
+func FromRecord(record *db.MarketRegime) (*Estimate, error) {
+	estimate := &Estimate{
+		Symbol:        record.Symbol,
+		Interval:      record.Interval,
+		Regime:        Regime(record.Regime),
+		Confidence:    record.Confidence,
+		Probabilities: make(map[Regime]float64, len(record.Probabilities)),
+		LogLikelihood: record.LogLikelihood,
+		Observations:  record.Observations,
+		DetectedAt:    record.DetectedAt,
+	}
+	for r, p := range record.Probabilities {
+		estimate.Probabilities[Regime(r)] = p
+	}
+	if len(record.States) > 0 {
+		if err := json.Unmarshal(record.States, &estimate.States); err != nil {
+			return nil, fmt.Errorf("failed to decode regime states: %w", err)
+		}
+	}
+	return estimate, nil
+}
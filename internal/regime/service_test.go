@@ -0,0 +1,105 @@
+package regime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+type fakePriceSource map[string][]float64
+
+func (f fakePriceSource) LoadHistoricalPrices(_ context.Context, symbol, _ string, _ int) (*risk.HistoricalData, error) {
+	prices, ok := f[symbol]
+	if !ok {
+		return nil, errors.New("no prices")
+	}
+	return &risk.HistoricalData{Prices: prices}, nil
+}
+
+type fakeStore struct{ records []*db.MarketRegime }
+
+func (f *fakeStore) InsertMarketRegime(_ context.Context, regime *db.MarketRegime) error {
+	f.records = append(f.records, regime)
+	return nil
+}
+
+type fakePublisher struct {
+	subjects []string
+	messages [][]byte
+}
+
+func (f *fakePublisher) Publish(subject string, data []byte) error {
+	f.subjects = append(f.subjects, subject)
+	f.messages = append(f.messages, data)
+	return nil
+}
+
+func TestService_DetectStoresAndPublishes(t *testing.T) {
+	source := fakePriceSource{
+		"BTC/USDT": syntheticPrices(1,
+			segment{n: 250, vol: 0.004},
+			segment{n: 250, vol: 0.03},
+			segment{n: 250, drift: 0.004, vol: 0.004}),
+	}
+	store := &fakeStore{}
+	publisher := &fakePublisher{}
+	service := NewService(ServiceConfig{Symbols: []string{"BTC/USDT", "ETH/USDT"}}, source, store, publisher)
+
+	service.DetectAll(context.Background())
+
+	// ETH has no prices and is skipped
+	require.Len(t, store.records, 1)
+	record := store.records[0]
+	assert.Equal(t, "BTC/USDT", record.Symbol)
+	assert.Equal(t, DefaultTimeframe, record.Interval)
+	assert.Equal(t, string(RegimeBullish), record.Regime)
+	assert.Len(t, record.Probabilities, len(Regimes))
+
+	restored, err := FromRecord(record)
+	require.NoError(t, err)
+	assert.Len(t, restored.States, DefaultStates)
+
+	require.Equal(t, []string{"cryptofunk.market.regime.BTCUSDT"}, publisher.subjects)
+	var published Estimate
+	require.NoError(t, json.Unmarshal(publisher.messages[0], &published))
+	assert.Equal(t, RegimeBullish, published.Regime)
+
+	latest := service.Latest("btcusdt")
+	require.NotNil(t, latest)
+	assert.Equal(t, RegimeBullish, latest.Regime)
+	assert.Nil(t, service.Latest("ETH/USDT"))
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker(time.Hour)
+	now := time.Now()
+
+	data, err := json.Marshal(&Estimate{Symbol: "BTC/USDT", Regime: RegimeVolatile, DetectedAt: now})
+	require.NoError(t, err)
+	require.NoError(t, tracker.Handle(data))
+
+	estimate, ok := tracker.Get("BTCUSDT")
+	require.True(t, ok)
+	assert.Equal(t, RegimeVolatile, estimate.Regime)
+
+	// Older estimates do not replace newer ones
+	tracker.Update(&Estimate{Symbol: "BTC/USDT", Regime: RegimeSideways, DetectedAt: now.Add(-time.Minute)})
+	estimate, _ = tracker.Get("BTC/USDT")
+	assert.Equal(t, RegimeVolatile, estimate.Regime)
+
+	// Stale estimates are ignored
+	tracker.Update(&Estimate{Symbol: "ETH/USDT", Regime: RegimeBearish, DetectedAt: now.Add(-2 * time.Hour)})
+	_, ok = tracker.Get("ETH/USDT")
+	assert.False(t, ok)
+
+	assert.Error(t, tracker.Handle([]byte(`{"regime":"bullish"}`)))
+	assert.Error(t, tracker.Handle([]byte(`not json`)))
+}
@@ -0,0 +1,75 @@
+package regime
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// Tracker keeps the latest regime estimate per symbol from the regime
+// service's NATS messages, for agents that consume regimes
+type Tracker struct {
+	maxAge time.Duration // Estimates older than this are ignored (0 = never)
+
+	mu     sync.RWMutex
+	latest map[string]*Estimate // symbol key -> estimate
+}
+
+// NewTracker creates a tracker. maxAge should cover a few service intervals.
+func NewTracker(maxAge time.Duration) *Tracker {
+	return &Tracker{
+		maxAge: maxAge,
+		latest: make(map[string]*Estimate),
+	}
+}
+
+// Subscribe tracks the estimates published under a subject prefix
+func (t *Tracker) Subscribe(nc *nats.Conn, prefix string) (*nats.Subscription, error) {
+	if prefix == "" {
+		prefix = DefaultSubject
+	}
+	return nc.Subscribe(prefix+".>", func(msg *nats.Msg) {
+		if err := t.Handle(msg.Data); err != nil {
+			log.Warn().Err(err).Str("subject", msg.Subject).Msg("Invalid market regime message")
+		}
+	})
+}
+
+// Handle records an estimate published by the regime service
+func (t *Tracker) Handle(data []byte) error {
+	var estimate Estimate
+	if err := json.Unmarshal(data, &estimate); err != nil {
+		return fmt.Errorf("failed to decode regime estimate: %w", err)
+	}
+	if estimate.Symbol == "" {
+		return fmt.Errorf("regime estimate has no symbol")
+	}
+	t.Update(&estimate)
+	return nil
+}
+
+// Update records an estimate unless a newer one is already known
+func (t *Tracker) Update(estimate *Estimate) {
+	key := symbolKey(estimate.Symbol)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if current, ok := t.latest[key]; ok && current.DetectedAt.After(estimate.DetectedAt) {
+		return
+	}
+	t.latest[key] = estimate
+}
+
+// Get returns the latest estimate for a symbol that is not too old
+func (t *Tracker) Get(symbol string) (*Estimate, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	estimate, ok := t.latest[symbolKey(symbol)]
+	if !ok || (t.maxAge > 0 && time.Since(estimate.DetectedAt) > t.maxAge) {
+		return nil, false
+	}
+	return estimate, true
+}
@@ -0,0 +1,278 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultBudgetThrottleStart is the budget utilization at which voting weights
+// start shrinking
+const DefaultBudgetThrottleStart = 0.8
+
+// BudgetMetric selects how risk budgets measure the risk of open positions
+type BudgetMetric string
+
+const (
+	// BudgetMetricCapitalAtRisk counts what open positions lose if their stops
+	// are hit, or their whole value when they have no stop
+	BudgetMetricCapitalAtRisk BudgetMetric = "capital_at_risk"
+	// BudgetMetricVaR counts the VaR of the open positions
+	BudgetMetricVaR BudgetMetric = "var"
+)
+
+// ParseBudgetMetric converts a metric name to a BudgetMetric. An empty name
+// selects capital at risk.
+func ParseBudgetMetric(name string) (BudgetMetric, error) {
+	switch BudgetMetric(name) {
+	case "", BudgetMetricCapitalAtRisk:
+		return BudgetMetricCapitalAtRisk, nil
+	case BudgetMetricVaR:
+		return BudgetMetricVaR, nil
+	default:
+		return "", fmt.Errorf("invalid budget metric %q (must be capital_at_risk or var)", name)
+	}
+}
+
+// BudgetScope says whether a budget belongs to an agent or a strategy
+type BudgetScope string
+
+const (
+	BudgetScopeAgent    BudgetScope = "agent"
+	BudgetScopeStrategy BudgetScope = "strategy" // Agent type, e.g. "trend"
+)
+
+// RiskBudget is the risk, in the quote currency, an agent or strategy may use
+type RiskBudget struct {
+	Scope BudgetScope `json:"scope"`
+	Name  string      `json:"name"`
+	Limit float64     `json:"limit"`
+}
+
+// AttributedPosition is a position together with the shares of it owed to the
+// agents and strategies behind the decision that opened it. Shares of each
+// scope sum to 1.
+type AttributedPosition struct {
+	Symbol        string
+	Value         float64 // Signed market value; zero once closed
+	CapitalAtRisk float64 // Loss if the stop is hit; zero once closed
+	RealizedPnL   float64
+	UnrealizedPnL float64
+	Agents        map[string]float64
+	Strategies    map[string]float64
+}
+
+// BudgetUsage is how much of its budget an agent or strategy uses. Used is the
+// risk of its open positions plus its net loss; Throttle scales its voting
+// weight.
+type BudgetUsage struct {
+	Scope         BudgetScope  `json:"scope"`
+	Name          string       `json:"name"`
+	Metric        BudgetMetric `json:"metric"`
+	Limit         float64      `json:"limit"` // Zero when no budget is allocated
+	RealizedPnL   float64      `json:"realized_pnl"`
+	UnrealizedPnL float64      `json:"unrealized_pnl"`
+	OpenRisk      float64      `json:"open_risk"`
+	Used          float64      `json:"used"`
+	Utilization   float64      `json:"utilization"`
+	Throttle      float64      `json:"throttle"`
+	Positions     int          `json:"positions"`
+}
+
+// Exhausted reports whether the budget is used up
+func (u BudgetUsage) Exhausted() bool {
+	return u.Limit > 0 && u.Utilization >= 1
+}
+
+// BudgetOptions configures budget evaluation. Zero values select the defaults.
+type BudgetOptions struct {
+	Metric        BudgetMetric
+	ThrottleStart float64   // DefaultBudgetThrottleStart
+	VaRMethod     VaRMethod // Historical simulation
+	VaR           VaROptions
+}
+
+// BudgetThrottle returns the voting weight multiplier for a budget utilization:
+// 1 up to throttleStart, falling linearly to 0 when the budget is used up
+func BudgetThrottle(utilization, throttleStart float64) float64 {
+	if throttleStart <= 0 || throttleStart >= 1 {
+		throttleStart = DefaultBudgetThrottleStart
+	}
+	switch {
+	case utilization >= 1:
+		return 0
+	case utilization <= throttleStart:
+		return 1
+	default:
+		return (1 - utilization) / (1 - throttleStart)
+	}
+}
+
+// EvaluateRiskBudgets attributes the risk and P&L of positions to their agents
+// and strategies and measures each against its budget. Owners without a budget
+// are reported but never throttled. With the VaR metric, returns holds the
+// aligned period returns of the open symbols; open positions without returns
+// count their capital at risk.
+func EvaluateRiskBudgets(budgets []RiskBudget, positions []AttributedPosition, returns map[string][]float64, options BudgetOptions) ([]BudgetUsage, error) {
+	if options.Metric == "" {
+		options.Metric = BudgetMetricCapitalAtRisk
+	}
+	if options.VaRMethod == "" {
+		options.VaRMethod = VaRMethodHistorical
+	}
+	var engine *VaREngine
+	if options.Metric == BudgetMetricVaR {
+		var err error
+		if engine, err = NewVaREngine(options.VaR); err != nil {
+			return nil, err
+		}
+	}
+
+	type ownerKey struct {
+		scope BudgetScope
+		name  string
+	}
+	owners := make(map[ownerKey]*BudgetUsage)
+	exposures := make(map[ownerKey]map[string]float64) // symbol -> attributed value
+	owner := func(scope BudgetScope, name string) *BudgetUsage {
+		key := ownerKey{scope, name}
+		usage, ok := owners[key]
+		if !ok {
+			usage = &BudgetUsage{Scope: scope, Name: name, Metric: options.Metric}
+			owners[key] = usage
+			exposures[key] = make(map[string]float64)
+		}
+		return usage
+	}
+
+	for _, budget := range budgets {
+		owner(budget.Scope, budget.Name).Limit = budget.Limit
+	}
+
+	for _, position := range positions {
+		for scope, shares := range map[BudgetScope]map[string]float64{
+			BudgetScopeAgent:    position.Agents,
+			BudgetScopeStrategy: position.Strategies,
+		} {
+			for name, share := range shares {
+				usage := owner(scope, name)
+				usage.Positions++
+				usage.RealizedPnL += share * position.RealizedPnL
+				usage.UnrealizedPnL += share * position.UnrealizedPnL
+				if position.Value == 0 {
+					continue
+				}
+				if _, ok := returns[position.Symbol]; options.Metric == BudgetMetricVaR && ok {
+					exposures[ownerKey{scope, name}][position.Symbol] += share * position.Value
+				} else {
+					usage.OpenRisk += share * position.CapitalAtRisk
+				}
+			}
+		}
+	}
+
+	usages := make([]BudgetUsage, 0, len(owners))
+	for key, usage := range owners {
+		portfolio := &Portfolio{}
+		for symbol, value := range exposures[key] {
+			if value != 0 { // Offsetting longs and shorts carry no exposure
+				portfolio.Positions = append(portfolio.Positions, PortfolioPosition{Symbol: symbol, Value: value, Returns: returns[symbol]})
+			}
+		}
+		if engine != nil && len(portfolio.Positions) > 0 {
+			sort.Slice(portfolio.Positions, func(i, j int) bool {
+				return portfolio.Positions[i].Symbol < portfolio.Positions[j].Symbol
+			})
+			estimate, err := engine.Estimate(portfolio, options.VaRMethod)
+			if err != nil {
+				return nil, fmt.Errorf("failed to estimate VaR of %s %s: %w", key.scope, key.name, err)
+			}
+			usage.OpenRisk += estimate.VaRAmount
+		}
+
+		usage.Used = usage.OpenRisk + math.Max(0, -(usage.RealizedPnL+usage.UnrealizedPnL))
+		usage.Throttle = 1
+		if usage.Limit > 0 {
+			usage.Utilization = usage.Used / usage.Limit
+			usage.Throttle = BudgetThrottle(usage.Utilization, options.ThrottleStart)
+		}
+		usages = append(usages, *usage)
+	}
+
+	sort.Slice(usages, func(i, j int) bool {
+		if usages[i].Scope != usages[j].Scope {
+			return usages[i].Scope < usages[j].Scope
+		}
+		return usages[i].Name < usages[j].Name
+	})
+	return usages, nil
+}
+
+// budgetMetrics holds Prometheus metrics for risk budgets
+type budgetMetrics struct {
+	limit       *prometheus.GaugeVec
+	used        *prometheus.GaugeVec
+	utilization *prometheus.GaugeVec
+	throttle    *prometheus.GaugeVec
+	pnl         *prometheus.GaugeVec
+}
+
+var (
+	budgetMetricsInstance *budgetMetrics
+	budgetMetricsOnce     sync.Once
+)
+
+// getBudgetMetrics registers the risk budget metrics exactly once
+func getBudgetMetrics() *budgetMetrics {
+	budgetMetricsOnce.Do(func() {
+		labels := []string{"scope", "name"}
+		budgetMetricsInstance = &budgetMetrics{
+			limit: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "cryptofunk_risk_budget_limit",
+				Help: "Risk budget allocated to an agent or strategy (quote currency)",
+			}, labels),
+			used: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "cryptofunk_risk_budget_used",
+				Help: "Risk budget used by an agent or strategy: open risk plus net loss (quote currency)",
+			}, labels),
+			utilization: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "cryptofunk_risk_budget_utilization",
+				Help: "Fraction of its risk budget an agent or strategy uses",
+			}, labels),
+			throttle: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "cryptofunk_risk_budget_throttle",
+				Help: "Voting weight multiplier applied for risk budget use (1=full weight, 0=excluded)",
+			}, labels),
+			pnl: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "cryptofunk_risk_budget_attributed_pnl",
+				Help: "P&L attributed to an agent or strategy by kind (realized, unrealized)",
+			}, []string{"scope", "name", "kind"}),
+		}
+	})
+	return budgetMetricsInstance
+}
+
+// RecordBudgetUsage publishes budget usage, replacing the previous snapshot so
+// owners that no longer have positions or budgets disappear
+func RecordBudgetUsage(usages []BudgetUsage) {
+	m := getBudgetMetrics()
+	m.limit.Reset()
+	m.used.Reset()
+	m.utilization.Reset()
+	m.throttle.Reset()
+	m.pnl.Reset()
+
+	for _, usage := range usages {
+		scope, name := string(usage.Scope), usage.Name
+		m.limit.WithLabelValues(scope, name).Set(usage.Limit)
+		m.used.WithLabelValues(scope, name).Set(usage.Used)
+		m.utilization.WithLabelValues(scope, name).Set(usage.Utilization)
+		m.throttle.WithLabelValues(scope, name).Set(usage.Throttle)
+		m.pnl.WithLabelValues(scope, name, "realized").Set(usage.RealizedPnL)
+		m.pnl.WithLabelValues(scope, name, "unrealized").Set(usage.UnrealizedPnL)
+	}
+}
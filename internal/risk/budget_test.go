@@ -0,0 +1,127 @@
+package risk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBudgetMetric(t *testing.T) {
+	metric, err := ParseBudgetMetric("")
+	require.NoError(t, err)
+	assert.Equal(t, BudgetMetricCapitalAtRisk, metric)
+
+	metric, err = ParseBudgetMetric("var")
+	require.NoError(t, err)
+	assert.Equal(t, BudgetMetricVaR, metric)
+
+	_, err = ParseBudgetMetric("notional")
+	assert.Error(t, err)
+}
+
+func TestBudgetThrottle(t *testing.T) {
+	assert.Equal(t, 1.0, BudgetThrottle(0.5, 0.8))
+	assert.Equal(t, 1.0, BudgetThrottle(0.8, 0.8))
+	assert.InDelta(t, 0.5, BudgetThrottle(0.9, 0.8), 1e-9)
+	assert.Equal(t, 0.0, BudgetThrottle(1.0, 0.8))
+	assert.Equal(t, 0.0, BudgetThrottle(1.7, 0.8))
+
+	// Out-of-range starts use the default
+	assert.InDelta(t, 0.5, BudgetThrottle(0.9, 0), 1e-9)
+}
+
+func TestEvaluateRiskBudgets_CapitalAtRisk(t *testing.T) {
+	budgets := []RiskBudget{
+		{Scope: BudgetScopeAgent, Name: "trend-agent", Limit: 1000},
+		{Scope: BudgetScopeAgent, Name: "technical-agent", Limit: 1000},
+		{Scope: BudgetScopeStrategy, Name: "reversion", Limit: 500},
+	}
+	positions := []AttributedPosition{
+		{
+			// Open: $400 to the stop, $100 under water
+			Symbol: "BTCUSDT", Value: 9900, CapitalAtRisk: 400, UnrealizedPnL: -100,
+			Agents:     map[string]float64{"trend-agent": 0.75, "technical-agent": 0.25},
+			Strategies: map[string]float64{"trend": 1},
+		},
+		{
+			// Closed at a $600 loss
+			Symbol: "ETHUSDT", RealizedPnL: -600,
+			Agents:     map[string]float64{"trend-agent": 0.5, "reversion-agent": 0.5},
+			Strategies: map[string]float64{"trend": 0.5, "reversion": 0.5},
+		},
+	}
+
+	usages, err := EvaluateRiskBudgets(budgets, positions, nil, BudgetOptions{ThrottleStart: 0.5})
+	require.NoError(t, err)
+	byName := make(map[string]BudgetUsage)
+	for _, usage := range usages {
+		byName[string(usage.Scope)+"/"+usage.Name] = usage
+	}
+	require.Len(t, byName, 5)
+
+	trend := byName["agent/trend-agent"]
+	assert.InDelta(t, 300, trend.OpenRisk, 1e-9)
+	assert.InDelta(t, -300, trend.RealizedPnL, 1e-9)
+	assert.InDelta(t, -75, trend.UnrealizedPnL, 1e-9)
+	assert.InDelta(t, 675, trend.Used, 1e-9)
+	assert.InDelta(t, 0.675, trend.Utilization, 1e-9)
+	assert.InDelta(t, 0.65, trend.Throttle, 1e-9)
+	assert.Equal(t, 2, trend.Positions)
+
+	technical := byName["agent/technical-agent"]
+	assert.InDelta(t, 125, technical.Used, 1e-9)
+	assert.Equal(t, 1.0, technical.Throttle)
+
+	// Without a budget the agent is reported but keeps its weight
+	unbudgeted := byName["agent/reversion-agent"]
+	assert.Zero(t, unbudgeted.Limit)
+	assert.InDelta(t, 300, unbudgeted.Used, 1e-9)
+	assert.Equal(t, 1.0, unbudgeted.Throttle)
+
+	reversion := byName["strategy/reversion"]
+	assert.InDelta(t, 0.6, reversion.Utilization, 1e-9)
+	assert.False(t, reversion.Exhausted())
+}
+
+func TestEvaluateRiskBudgets_GainsOffsetLosses(t *testing.T) {
+	budgets := []RiskBudget{{Scope: BudgetScopeAgent, Name: "trend-agent", Limit: 100}}
+	positions := []AttributedPosition{
+		{Symbol: "BTCUSDT", RealizedPnL: -300, Agents: map[string]float64{"trend-agent": 1}},
+		{Symbol: "ETHUSDT", RealizedPnL: 250, Agents: map[string]float64{"trend-agent": 1}},
+		{Symbol: "SOLUSDT", Value: 1000, CapitalAtRisk: 60, Agents: map[string]float64{"trend-agent": 1}},
+	}
+
+	usages, err := EvaluateRiskBudgets(budgets, positions, nil, BudgetOptions{})
+	require.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.InDelta(t, 110, usages[0].Used, 1e-9)
+	assert.True(t, usages[0].Exhausted())
+	assert.Equal(t, 0.0, usages[0].Throttle)
+}
+
+func TestEvaluateRiskBudgets_VaR(t *testing.T) {
+	returns := map[string][]float64{
+		"BTCUSDT": {0.02, -0.03, 0.01, -0.05, 0.04, -0.01, 0.02, -0.02, 0.03, -0.04},
+	}
+	positions := []AttributedPosition{
+		{Symbol: "BTCUSDT", Value: 10000, CapitalAtRisk: 10000, Agents: map[string]float64{"trend-agent": 0.5, "technical-agent": 0.5}},
+		// No return history: counts its capital at risk
+		{Symbol: "NEWUSDT", Value: 500, CapitalAtRisk: 200, Agents: map[string]float64{"trend-agent": 1}},
+	}
+
+	usages, err := EvaluateRiskBudgets(nil, positions, returns, BudgetOptions{Metric: BudgetMetricVaR, VaRMethod: VaRMethodParametric})
+	require.NoError(t, err)
+	require.Len(t, usages, 2)
+
+	engine, err := NewVaREngine(VaROptions{})
+	require.NoError(t, err)
+	estimate, err := engine.Estimate(&Portfolio{Positions: []PortfolioPosition{{Symbol: "BTCUSDT", Value: 5000, Returns: returns["BTCUSDT"]}}}, VaRMethodParametric)
+	require.NoError(t, err)
+
+	assert.Equal(t, "technical-agent", usages[0].Name)
+	assert.InDelta(t, estimate.VaRAmount, usages[0].OpenRisk, 1e-9)
+	assert.Equal(t, "trend-agent", usages[1].Name)
+	assert.InDelta(t, estimate.VaRAmount+200, usages[1].OpenRisk, 1e-9)
+	assert.Equal(t, BudgetMetricVaR, usages[1].Metric)
+}
@@ -481,6 +481,8 @@ func (c *Calculator) CalculateSharpeFromEquity(ctx context.Context, sessionID *s
 
 // DetectMarketRegime detects market regime using 30-day rolling volatility
 // Uses moving averages and volatility to determine bullish/bearish/sideways
+// The regime service (internal/regime) supersedes this heuristic with a hidden
+// Markov model; it remains the fallback when no recent estimate is stored.
 func (c *Calculator) DetectMarketRegime(ctx context.Context, symbol string, days int) (*MarketRegimeData, error) {
 	// Validate symbol to prevent SQL injection
 	if !isValidSymbol(symbol) {
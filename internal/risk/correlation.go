@@ -0,0 +1,331 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CorrelationMethod selects how return correlations are weighted
+type CorrelationMethod string
+
+const (
+	// CorrelationMethodRolling weights the most recent Window periods equally
+	CorrelationMethodRolling CorrelationMethod = "rolling"
+	// CorrelationMethodEWMA weights all periods with exponentially decaying weights
+	CorrelationMethodEWMA CorrelationMethod = "ewma"
+)
+
+// ParseCorrelationMethod converts a method name to a CorrelationMethod. An
+// empty name selects the rolling window.
+func ParseCorrelationMethod(name string) (CorrelationMethod, error) {
+	switch CorrelationMethod(name) {
+	case "", CorrelationMethodRolling:
+		return CorrelationMethodRolling, nil
+	case CorrelationMethodEWMA:
+		return CorrelationMethodEWMA, nil
+	default:
+		return "", fmt.Errorf("invalid correlation method %q (must be rolling or ewma)", name)
+	}
+}
+
+// CorrelationOptions configures a correlation matrix. Zero values select the defaults.
+type CorrelationOptions struct {
+	Method CorrelationMethod // rolling
+	Window int               // 30 periods for rolling correlations
+	Lambda float64           // 0.94 decay for EWMA correlations
+}
+
+// withDefaults fills unset options
+func (o CorrelationOptions) withDefaults() CorrelationOptions {
+	if o.Method == "" {
+		o.Method = CorrelationMethodRolling
+	}
+	if o.Window <= 0 {
+		o.Window = 30
+	}
+	if o.Lambda == 0 {
+		o.Lambda = 0.94
+	}
+	return o
+}
+
+// CorrelationMatrix holds pairwise return correlations of a set of symbols
+type CorrelationMatrix struct {
+	Symbols      []string          `json:"symbols"`
+	Matrix       [][]float64       `json:"matrix"`
+	Method       CorrelationMethod `json:"method"`
+	Window       int               `json:"window,omitempty"`
+	Lambda       float64           `json:"lambda,omitempty"`
+	Interval     string            `json:"interval,omitempty"`
+	Observations int               `json:"observations"`
+	ComputedAt   time.Time         `json:"computed_at"`
+}
+
+// NewCorrelationMatrix computes the correlation matrix of return series, one
+// per symbol, oldest first. Series are aligned on their most recent common periods.
+func NewCorrelationMatrix(symbols []string, returns [][]float64, options CorrelationOptions) (*CorrelationMatrix, error) {
+	if len(symbols) == 0 || len(symbols) != len(returns) {
+		return nil, fmt.Errorf("one return series is required per symbol")
+	}
+	options = options.withDefaults()
+	if options.Lambda <= 0 || options.Lambda >= 1 {
+		return nil, fmt.Errorf("EWMA lambda must be between 0 and 1")
+	}
+
+	periods := len(returns[0])
+	for _, series := range returns[1:] {
+		periods = min(periods, len(series))
+	}
+	if options.Method == CorrelationMethodRolling {
+		periods = min(periods, options.Window)
+	}
+	if periods < 2 {
+		return nil, fmt.Errorf("at least 2 return periods are required (got %d)", periods)
+	}
+
+	series := make([][]float64, len(returns))
+	for i, r := range returns {
+		series[i] = r[len(r)-periods:]
+	}
+
+	var cov [][]float64
+	switch options.Method {
+	case CorrelationMethodRolling:
+		means := make([]float64, len(series))
+		for i, r := range series {
+			means[i] = mean(r)
+		}
+		cov = covariance(series, means)
+	case CorrelationMethodEWMA:
+		cov = ewmaCovariance(series, options.Lambda)
+	default:
+		return nil, fmt.Errorf("unsupported correlation method: %s", options.Method)
+	}
+
+	n := len(series)
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n)
+		for j := range matrix[i] {
+			switch {
+			case i == j:
+				matrix[i][j] = 1
+			case cov[i][i] > 0 && cov[j][j] > 0:
+				// Clamp rounding error to the valid range
+				matrix[i][j] = math.Max(-1, math.Min(1, cov[i][j]/math.Sqrt(cov[i][i]*cov[j][j])))
+			}
+		}
+	}
+
+	result := &CorrelationMatrix{
+		Symbols:      symbols,
+		Matrix:       matrix,
+		Method:       options.Method,
+		Observations: periods,
+		ComputedAt:   time.Now(),
+	}
+	if options.Method == CorrelationMethodRolling {
+		result.Window = options.Window
+	} else {
+		result.Lambda = options.Lambda
+	}
+	return result, nil
+}
+
+// Correlation returns the correlation of two symbols, and whether both are in the matrix
+func (m *CorrelationMatrix) Correlation(a, b string) (float64, bool) {
+	i, j := m.index(a), m.index(b)
+	if i < 0 || j < 0 {
+		return 0, false
+	}
+	return m.Matrix[i][j], true
+}
+
+// index returns the position of a symbol in the matrix, or -1
+func (m *CorrelationMatrix) index(symbol string) int {
+	for i, s := range m.Symbols {
+		if strings.EqualFold(s, symbol) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ParseCorrelationMatrix reads a correlation matrix from MCP arguments
+// ({"symbols": [...], "matrix": [[...]]})
+func ParseCorrelationMatrix(raw map[string]interface{}) (*CorrelationMatrix, error) {
+	symbolsRaw, _ := raw["symbols"].([]interface{})
+	rowsRaw, _ := raw["matrix"].([]interface{})
+	if len(symbolsRaw) == 0 || len(rowsRaw) != len(symbolsRaw) {
+		return nil, fmt.Errorf("correlations must have symbols and a square matrix")
+	}
+
+	matrix := &CorrelationMatrix{Symbols: make([]string, len(symbolsRaw)), Matrix: make([][]float64, len(rowsRaw))}
+	for i, symbol := range symbolsRaw {
+		matrix.Symbols[i], _ = symbol.(string)
+	}
+	for i, rowRaw := range rowsRaw {
+		row, err := parseReturns(rowRaw)
+		if err != nil || len(row) != len(symbolsRaw) {
+			return nil, fmt.Errorf("correlations must have symbols and a square matrix")
+		}
+		matrix.Matrix[i] = row
+	}
+	return matrix, nil
+}
+
+// CorrelatedExposure sums the absolute exposure of the positions in symbol and
+// in symbols whose correlation with it is at least threshold
+func CorrelatedExposure(matrix *CorrelationMatrix, positions []Position, symbol string, threshold float64) float64 {
+	var exposure float64
+	for _, position := range positions {
+		if !strings.EqualFold(position.Symbol, symbol) {
+			if matrix == nil {
+				continue
+			}
+			corr, ok := matrix.Correlation(symbol, position.Symbol)
+			if !ok || corr < threshold {
+				continue
+			}
+		}
+		exposure += math.Abs(position.Size)
+	}
+	return exposure
+}
+
+// ewmaCovariance is the zero-mean exponentially weighted covariance matrix
+// (RiskMetrics) of equally long series
+func ewmaCovariance(series [][]float64, lambda float64) [][]float64 {
+	n := len(series)
+	periods := len(series[0])
+
+	weights := make([]float64, periods)
+	var total float64
+	for t := range weights {
+		weights[t] = math.Pow(lambda, float64(periods-1-t))
+		total += weights[t]
+	}
+
+	cov := make([][]float64, n)
+	for i := range cov {
+		cov[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			var sum float64
+			for t := 0; t < periods; t++ {
+				sum += weights[t] * series[i][t] * series[j][t]
+			}
+			cov[i][j] = sum / total
+			cov[j][i] = cov[i][j]
+		}
+	}
+	return cov
+}
+
+// CorrelationService computes correlation matrices from candlestick returns.
+// Matrices are cached for one candle interval.
+type CorrelationService struct {
+	calculator *Calculator
+
+	mu    sync.Mutex
+	cache map[string]*CorrelationMatrix
+	now   func() time.Time
+}
+
+// NewCorrelationService creates a correlation service backed by a calculator
+func NewCorrelationService(calculator *Calculator) *CorrelationService {
+	return &CorrelationService{
+		calculator: calculator,
+		cache:      make(map[string]*CorrelationMatrix),
+		now:        time.Now,
+	}
+}
+
+// Matrix returns the correlation matrix of symbols' returns over the last days
+// of interval candles
+func (s *CorrelationService) Matrix(ctx context.Context, symbols []string, interval string, days int, options CorrelationOptions) (*CorrelationMatrix, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("at least one symbol is required")
+	}
+	options = options.withDefaults()
+
+	key := correlationCacheKey(symbols, interval, days, options)
+	now := s.now()
+
+	s.mu.Lock()
+	cached, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && now.Sub(cached.ComputedAt) < intervalDuration(interval) {
+		return cached, nil
+	}
+
+	positions := make([]PortfolioPosition, len(symbols))
+	for i, symbol := range symbols {
+		positions[i] = PortfolioPosition{Symbol: symbol}
+	}
+	portfolio, err := s.calculator.LoadPortfolioReturns(ctx, positions, interval, days)
+	if err != nil {
+		return nil, err
+	}
+
+	returns := make([][]float64, len(portfolio.Positions))
+	for i, position := range portfolio.Positions {
+		returns[i] = position.Returns
+	}
+	matrix, err := NewCorrelationMatrix(symbols, returns, options)
+	if err != nil {
+		return nil, err
+	}
+	matrix.Interval = interval
+	matrix.ComputedAt = now
+
+	s.mu.Lock()
+	s.cache[key] = matrix
+	s.mu.Unlock()
+
+	log.Debug().
+		Strs("symbols", symbols).
+		Str("interval", interval).
+		Str("method", string(options.Method)).
+		Int("observations", matrix.Observations).
+		Msg("Correlation matrix calculated")
+
+	return matrix, nil
+}
+
+// correlationCacheKey identifies a matrix regardless of symbol order
+func correlationCacheKey(symbols []string, interval string, days int, options CorrelationOptions) string {
+	sorted := make([]string, len(symbols))
+	copy(sorted, symbols)
+	sort.Strings(sorted)
+	return fmt.Sprintf("%s|%d|%s|%d|%g|%s", interval, days, options.Method, options.Window, options.Lambda, strings.Join(sorted, ","))
+}
+
+// intervalDuration converts a candle interval such as "15m", "4h" or "1d" to a
+// duration. Unknown intervals are treated as one minute.
+func intervalDuration(interval string) time.Duration {
+	units := map[byte]time.Duration{
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+	if len(interval) < 2 {
+		return time.Minute
+	}
+	unit, ok := units[interval[len(interval)-1]]
+	count, err := strconv.Atoi(interval[:len(interval)-1])
+	if !ok || err != nil || count <= 0 {
+		return time.Minute
+	}
+	return time.Duration(count) * unit
+}
@@ -0,0 +1,130 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCorrelationMatrix(t *testing.T) {
+	btc := []float64{0.01, -0.02, 0.03, -0.01, 0.02, -0.03}
+	eth := []float64{0.02, -0.04, 0.06, -0.02, 0.04, -0.06}
+	hedge := []float64{-0.01, 0.02, -0.03, 0.01, -0.02, 0.03}
+
+	for _, method := range []CorrelationMethod{CorrelationMethodRolling, CorrelationMethodEWMA} {
+		matrix, err := NewCorrelationMatrix([]string{"BTCUSDT", "ETHUSDT", "HEDGE"}, [][]float64{btc, eth, hedge}, CorrelationOptions{Method: method})
+		require.NoError(t, err)
+
+		assert.Equal(t, 1.0, matrix.Matrix[0][0], method)
+		corr, ok := matrix.Correlation("BTCUSDT", "ethusdt")
+		require.True(t, ok)
+		assert.InDelta(t, 1.0, corr, 1e-9, method)
+		corr, _ = matrix.Correlation("HEDGE", "BTCUSDT")
+		assert.InDelta(t, -1.0, corr, 1e-9, method)
+
+		_, ok = matrix.Correlation("BTCUSDT", "SOLUSDT")
+		assert.False(t, ok)
+	}
+}
+
+func TestNewCorrelationMatrix_RollingWindow(t *testing.T) {
+	// Perfectly correlated until the last three periods, where they move apart
+	a := []float64{0.01, 0.02, 0.03, 0.04, 0.01, -0.01, 0.01}
+	b := []float64{0.01, 0.02, 0.03, 0.04, -0.01, 0.01, -0.01}
+
+	full, err := NewCorrelationMatrix([]string{"A", "B"}, [][]float64{a, b}, CorrelationOptions{})
+	require.NoError(t, err)
+	recent, err := NewCorrelationMatrix([]string{"A", "B"}, [][]float64{a, b}, CorrelationOptions{Window: 3})
+	require.NoError(t, err)
+
+	assert.Greater(t, full.Matrix[0][1], 0.0)
+	assert.InDelta(t, -1.0, recent.Matrix[0][1], 1e-9)
+	assert.Equal(t, 3, recent.Observations)
+}
+
+func TestNewCorrelationMatrix_ConstantSeries(t *testing.T) {
+	matrix, err := NewCorrelationMatrix([]string{"A", "STABLE"}, [][]float64{{0.01, -0.02, 0.03}, {0, 0, 0}}, CorrelationOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, matrix.Matrix[0][1])
+	assert.Equal(t, 1.0, matrix.Matrix[1][1])
+
+	_, err = NewCorrelationMatrix([]string{"A"}, [][]float64{{0.01}}, CorrelationOptions{})
+	assert.Error(t, err)
+}
+
+func TestCorrelatedExposure(t *testing.T) {
+	matrix := &CorrelationMatrix{
+		Symbols: []string{"BTCUSDT", "ETHUSDT", "PAXGUSDT"},
+		Matrix: [][]float64{
+			{1, 0.8, 0.1},
+			{0.8, 1, 0.0},
+			{0.1, 0.0, 1},
+		},
+	}
+	positions := []Position{
+		{Symbol: "BTCUSDT", Size: 5000},
+		{Symbol: "ETHUSDT", Size: -3000},
+		{Symbol: "PAXGUSDT", Size: 4000},
+		{Symbol: "SOLUSDT", Size: 2000}, // Not in the matrix
+	}
+
+	assert.Equal(t, 8000.0, CorrelatedExposure(matrix, positions, "BTCUSDT", 0.7))
+	assert.Equal(t, 4000.0, CorrelatedExposure(matrix, positions, "PAXGUSDT", 0.7))
+	assert.Equal(t, 2000.0, CorrelatedExposure(matrix, positions, "SOLUSDT", 0.7))
+	assert.Equal(t, 5000.0, CorrelatedExposure(nil, positions, "BTCUSDT", 0.7))
+}
+
+func TestCorrelationService_CachesPerInterval(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	service := NewCorrelationService(NewCalculator(mock))
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	expect := func() {
+		for _, symbol := range []string{"BTCUSDT", "ETHUSDT"} {
+			rows := pgxmock.NewRows([]string{"close", "open_time"})
+			for i, price := range []float64{100, 102, 99, 103, 101} {
+				rows.AddRow(price, now.Add(time.Duration(i-5)*time.Hour))
+			}
+			mock.ExpectQuery("SELECT close, open_time FROM candlesticks").
+				WithArgs(symbol, "1h", 7).
+				WillReturnRows(rows)
+		}
+	}
+	expect()
+
+	ctx := context.Background()
+	matrix, err := service.Matrix(ctx, []string{"BTCUSDT", "ETHUSDT"}, "1h", 7, CorrelationOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "1h", matrix.Interval)
+	assert.InDelta(t, 1.0, matrix.Matrix[0][1], 1e-9)
+
+	// Within the interval the cached matrix is reused, in any symbol order
+	now = now.Add(30 * time.Minute)
+	cached, err := service.Matrix(ctx, []string{"ETHUSDT", "BTCUSDT"}, "1h", 7, CorrelationOptions{})
+	require.NoError(t, err)
+	assert.Same(t, matrix, cached)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// A new candle recomputes it
+	now = now.Add(time.Hour)
+	expect()
+	_, err = service.Matrix(ctx, []string{"BTCUSDT", "ETHUSDT"}, "1h", 7, CorrelationOptions{})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIntervalDuration(t *testing.T) {
+	assert.Equal(t, 15*time.Minute, intervalDuration("15m"))
+	assert.Equal(t, 4*time.Hour, intervalDuration("4h"))
+	assert.Equal(t, 24*time.Hour, intervalDuration("1d"))
+	assert.Equal(t, 7*24*time.Hour, intervalDuration("1w"))
+	assert.Equal(t, time.Minute, intervalDuration("bogus"))
+}
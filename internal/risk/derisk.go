@@ -0,0 +1,457 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultDeRiskCooldown is the minimum time between two steps of a rule that
+// stays breached
+const DefaultDeRiskCooldown = 15 * time.Minute
+
+// deRiskMaxRounds bounds how many times a step may cut each position
+const deRiskMaxRounds = 32
+
+// DeRiskMetric is the risk measure a de-risking rule watches
+type DeRiskMetric string
+
+const (
+	DeRiskMetricDrawdown DeRiskMetric = "drawdown" // Fractional drawdown from the equity peak
+	DeRiskMetricExposure DeRiskMetric = "exposure" // Gross exposure as a fraction of the exposure limit
+	DeRiskMetricVaR      DeRiskMetric = "var"      // 95% VaR as a fraction of gross exposure
+)
+
+// DeRiskAction is what a breached rule does to open positions
+type DeRiskAction string
+
+const (
+	DeRiskActionReduce  DeRiskAction = "reduce"  // Cut positions by the rule's fraction
+	DeRiskActionFlatten DeRiskAction = "flatten" // Close every position
+)
+
+// DeRiskRanking orders the positions a reduction cuts first
+type DeRiskRanking string
+
+const (
+	DeRiskRankLargest  DeRiskRanking = "largest"  // Largest notional first
+	DeRiskRankRiskiest DeRiskRanking = "riskiest" // Largest notional times volatility first
+)
+
+// DeRiskRule reduces or flattens positions when a metric rises above a threshold
+type DeRiskRule struct {
+	Name           string       `json:"name"`
+	Metric         DeRiskMetric `json:"metric"`
+	Threshold      float64      `json:"threshold"`
+	Action         DeRiskAction `json:"action"`
+	ReduceFraction float64      `json:"reduce_fraction,omitempty"` // Share of a position cut per round
+}
+
+// DeRiskPolicy is a strategy's de-risking rules
+type DeRiskPolicy struct {
+	Enabled  bool
+	Ranking  DeRiskRanking
+	Cooldown time.Duration
+	Rules    []DeRiskRule
+}
+
+// Validate checks the policy's rules
+func (p DeRiskPolicy) Validate() error {
+	switch p.Ranking {
+	case "", DeRiskRankLargest, DeRiskRankRiskiest:
+	default:
+		return fmt.Errorf("invalid de-risking ranking %q (must be largest or riskiest)", p.Ranking)
+	}
+
+	names := make(map[string]bool, len(p.Rules))
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("de-risking rule %d has no name", i)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate de-risking rule %q", rule.Name)
+		}
+		names[rule.Name] = true
+
+		switch rule.Metric {
+		case DeRiskMetricDrawdown, DeRiskMetricExposure, DeRiskMetricVaR:
+		default:
+			return fmt.Errorf("de-risking rule %q: invalid metric %q (must be drawdown, exposure or var)", rule.Name, rule.Metric)
+		}
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("de-risking rule %q: threshold must be positive", rule.Name)
+		}
+		switch rule.Action {
+		case DeRiskActionFlatten:
+		case DeRiskActionReduce:
+			if rule.ReduceFraction <= 0 || rule.ReduceFraction > 1 {
+				return fmt.Errorf("de-risking rule %q: reduce fraction must be between 0 and 1", rule.Name)
+			}
+		default:
+			return fmt.Errorf("de-risking rule %q: invalid action %q (must be reduce or flatten)", rule.Name, rule.Action)
+		}
+	}
+	return nil
+}
+
+// DeRiskMetrics is a snapshot of the risk measures rules are checked against
+type DeRiskMetrics struct {
+	Drawdown            float64 `json:"drawdown"`
+	Exposure            float64 `json:"exposure"`
+	ExposureLimit       float64 `json:"exposure_limit"`
+	ExposureUtilization float64 `json:"exposure_utilization"`
+	VaR95               float64 `json:"var_95"`
+	LargestPosition     float64 `json:"largest_position"`
+	Positions           int     `json:"positions"`
+}
+
+// value returns the snapshot's value of a metric
+func (m DeRiskMetrics) value(metric DeRiskMetric) float64 {
+	switch metric {
+	case DeRiskMetricDrawdown:
+		return m.Drawdown
+	case DeRiskMetricExposure:
+		return m.ExposureUtilization
+	case DeRiskMetricVaR:
+		return m.VaR95
+	default:
+		return 0
+	}
+}
+
+// DeRiskPosition is an open position a plan may cut. Lot rules are zero when
+// the exchange's rules are unknown.
+type DeRiskPosition struct {
+	ID         string
+	Symbol     string
+	Side       string // LONG or SHORT
+	Quantity   float64
+	Price      float64
+	Volatility float64 // Annualized; zero when unknown
+
+	StepSize    float64
+	MinQty      float64
+	MinNotional float64
+}
+
+// Notional returns the position's value at its price
+func (p DeRiskPosition) Notional() float64 {
+	return p.Quantity * p.Price
+}
+
+// minQuantity is the smallest placeable order at the position's price
+func (p DeRiskPosition) minQuantity() float64 {
+	minimum := p.MinQty
+	if p.MinNotional > 0 && p.Price > 0 {
+		minimum = math.Max(minimum, p.MinNotional/p.Price)
+	}
+	if p.StepSize > 0 && minimum > 0 {
+		minimum = math.Ceil(minimum/p.StepSize-1e-9) * p.StepSize
+	}
+	return minimum
+}
+
+// placeable rounds a cut of quantity from a position remaining units large to a
+// tradable order. Cuts below the exchange minimum are raised to it, and cuts
+// that would leave an untradable remainder close the whole position.
+func (p DeRiskPosition) placeable(quantity, remaining float64) (float64, bool) {
+	if quantity >= remaining {
+		return remaining, true
+	}
+	if p.StepSize > 0 {
+		quantity = math.Floor(quantity/p.StepSize+1e-9) * p.StepSize
+	}
+	minimum := p.minQuantity()
+	if quantity < minimum || quantity <= 0 {
+		quantity = minimum
+	}
+	if quantity <= 0 || quantity >= remaining || remaining-quantity < minimum {
+		return remaining, true
+	}
+	return quantity, false
+}
+
+// DeRiskOrder cuts part or all of a position
+type DeRiskOrder struct {
+	PositionID string  `json:"position_id"`
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"` // Side of the closing order
+	Quantity   float64 `json:"quantity"`
+	Notional   float64 `json:"notional"`
+	Close      bool    `json:"close"` // The whole position is closed
+}
+
+// DeRiskPlan is one de-risking step
+type DeRiskPlan struct {
+	Rule    DeRiskRule    `json:"rule"`
+	Value   float64       `json:"value"` // Metric value that breached the rule
+	Before  DeRiskMetrics `json:"before"`
+	After   DeRiskMetrics `json:"after"` // Projected once the orders fill
+	Orders  []DeRiskOrder `json:"orders"`
+	Skipped []string      `json:"skipped,omitempty"` // Positions below exchange minimums
+}
+
+// Reason describes why the plan was made
+func (p *DeRiskPlan) Reason() string {
+	return fmt.Sprintf("%s %.4f above %.4f (rule %s: %s)", p.Rule.Metric, p.Value, p.Rule.Threshold, p.Rule.Name, p.Rule.Action)
+}
+
+// DeRisker turns breached de-risking rules into stepwise position cuts. A rule
+// that stays breached takes another step once its cooldown has passed.
+type DeRisker struct {
+	mu       sync.Mutex
+	policy   DeRiskPolicy
+	lastStep map[string]time.Time
+	metrics  *deRiskMetrics
+}
+
+// NewDeRisker creates a de-risker for a policy
+func NewDeRisker(policy DeRiskPolicy) *DeRisker {
+	return &DeRisker{
+		policy:   policy,
+		lastStep: make(map[string]time.Time),
+		metrics:  getDeRiskMetrics(),
+	}
+}
+
+// SetPolicy replaces the policy, e.g. when the active strategy changes
+func (d *DeRisker) SetPolicy(policy DeRiskPolicy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.policy = policy
+}
+
+// Policy returns the current policy
+func (d *DeRisker) Policy() DeRiskPolicy {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.policy
+}
+
+// Plan returns the next de-risking step, or nil when no rule is breached or
+// every breached rule is cooling down. A breached flatten rule wins; otherwise
+// the reduce rule that cuts the most is taken.
+func (d *DeRisker) Plan(state DeRiskMetrics, positions []DeRiskPosition, now time.Time) *DeRiskPlan {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.policy.Enabled || len(positions) == 0 {
+		return nil
+	}
+	cooldown := d.policy.Cooldown
+	if cooldown <= 0 {
+		cooldown = DefaultDeRiskCooldown
+	}
+
+	var chosen *DeRiskRule
+	chosenCut := 0.0
+	for i := range d.policy.Rules {
+		rule := &d.policy.Rules[i]
+		if state.value(rule.Metric) <= rule.Threshold {
+			delete(d.lastStep, rule.Name)
+			continue
+		}
+		if last, ok := d.lastStep[rule.Name]; ok && now.Sub(last) < cooldown {
+			continue
+		}
+
+		cut := deRiskTarget(*rule, state)
+		switch {
+		case chosen == nil:
+		case rule.Action == DeRiskActionFlatten && chosen.Action != DeRiskActionFlatten:
+		case rule.Action == chosen.Action && cut > chosenCut:
+		default:
+			continue
+		}
+		chosen, chosenCut = rule, cut
+	}
+	if chosen == nil {
+		return nil
+	}
+	d.lastStep[chosen.Name] = now
+
+	plan := &DeRiskPlan{
+		Rule:   *chosen,
+		Value:  state.value(chosen.Metric),
+		Before: state,
+	}
+	fraction := chosen.ReduceFraction
+	if chosen.Action == DeRiskActionFlatten {
+		fraction = 1
+	}
+	plan.Orders, plan.Skipped = cutPositions(rankPositions(positions, d.policy.Ranking), chosenCut, fraction)
+	plan.After = projectDeRisk(state, positions, plan.Orders)
+
+	if len(plan.Orders) > 0 {
+		cut := 0.0
+		for _, order := range plan.Orders {
+			cut += order.Notional
+		}
+		d.metrics.steps.WithLabelValues(chosen.Name, string(chosen.Action)).Inc()
+		d.metrics.notional.WithLabelValues(chosen.Name).Add(cut)
+	}
+	return plan
+}
+
+// deRiskTarget is the notional a rule's step cuts. Exposure rules cut back to
+// the threshold; drawdown and VaR do not fall as positions shrink, so each step
+// cuts the rule's fraction of gross exposure.
+func deRiskTarget(rule DeRiskRule, state DeRiskMetrics) float64 {
+	switch {
+	case rule.Action == DeRiskActionFlatten:
+		return math.Inf(1)
+	case rule.Metric == DeRiskMetricExposure && state.ExposureLimit > 0:
+		return math.Max(0, state.Exposure-rule.Threshold*state.ExposureLimit)
+	default:
+		return rule.ReduceFraction * state.Exposure
+	}
+}
+
+// rankPositions orders positions by the ranking, largest notional first by default
+func rankPositions(positions []DeRiskPosition, ranking DeRiskRanking) []DeRiskPosition {
+	ranked := append([]DeRiskPosition(nil), positions...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if ranking == DeRiskRankRiskiest && (a.Volatility > 0) != (b.Volatility > 0) {
+			return a.Volatility > 0 // Positions without a volatility estimate rank last
+		}
+		if ranking == DeRiskRankRiskiest && a.Volatility > 0 {
+			return a.Notional()*a.Volatility > b.Notional()*b.Volatility
+		}
+		return a.Notional() > b.Notional()
+	})
+	return ranked
+}
+
+// cutPositions cuts each ranked position in turn by fraction of what is left of
+// it, round after round, until target notional is cut. Positions too small to
+// trade are skipped.
+func cutPositions(ranked []DeRiskPosition, target, fraction float64) ([]DeRiskOrder, []string) {
+	remaining := make([]float64, len(ranked))
+	orders := make([]*DeRiskOrder, len(ranked))
+	var skipped []string
+	for i, position := range ranked {
+		remaining[i] = position.Quantity
+		if position.Quantity <= 0 || position.Price <= 0 || position.Quantity < position.minQuantity() {
+			remaining[i] = 0
+			skipped = append(skipped, position.ID)
+		}
+	}
+
+	cut := 0.0
+	for round := 0; round < deRiskMaxRounds && cut < target; round++ {
+		progress := false
+		for i, position := range ranked {
+			if cut >= target {
+				break
+			}
+			if remaining[i] <= 0 {
+				continue
+			}
+
+			want := remaining[i] * fraction
+			if left := (target - cut) / position.Price; left < want {
+				want = left
+			}
+			quantity, closed := position.placeable(want, remaining[i])
+
+			if orders[i] == nil {
+				side := "SELL"
+				if position.Side == "SHORT" {
+					side = "BUY"
+				}
+				orders[i] = &DeRiskOrder{PositionID: position.ID, Symbol: position.Symbol, Side: side}
+			}
+			orders[i].Quantity += quantity
+			orders[i].Notional += quantity * position.Price
+			orders[i].Close = closed
+			remaining[i] -= quantity
+			if closed {
+				remaining[i] = 0
+			}
+			cut += quantity * position.Price
+			progress = true
+		}
+		if !progress {
+			break
+		}
+	}
+
+	result := make([]DeRiskOrder, 0, len(orders))
+	for _, order := range orders {
+		if order != nil {
+			result = append(result, *order)
+		}
+	}
+	return result, skipped
+}
+
+// projectDeRisk estimates the metrics once the orders fill. Drawdown and VaR
+// are carried over unchanged.
+func projectDeRisk(state DeRiskMetrics, positions []DeRiskPosition, orders []DeRiskOrder) DeRiskMetrics {
+	cuts := make(map[string]DeRiskOrder, len(orders))
+	for _, order := range orders {
+		cuts[order.PositionID] = order
+	}
+
+	after := state
+	after.LargestPosition = 0
+	after.Positions = 0
+	exposure := 0.0
+	for _, position := range positions {
+		notional := position.Notional()
+		if order, ok := cuts[position.ID]; ok {
+			if order.Close {
+				continue
+			}
+			notional -= order.Notional
+		}
+		exposure += notional
+		after.LargestPosition = math.Max(after.LargestPosition, notional)
+		after.Positions++
+	}
+
+	// Keep exposure outside the listed positions (e.g. other venues) in the projection
+	before := 0.0
+	for _, position := range positions {
+		before += position.Notional()
+	}
+	after.Exposure = math.Max(0, state.Exposure-(before-exposure))
+	if after.ExposureLimit > 0 {
+		after.ExposureUtilization = after.Exposure / after.ExposureLimit
+	}
+	return after
+}
+
+// deRiskMetrics holds Prometheus metrics for de-risking
+type deRiskMetrics struct {
+	steps    *prometheus.CounterVec
+	notional *prometheus.CounterVec
+}
+
+var (
+	deRiskMetricsInstance *deRiskMetrics
+	deRiskMetricsOnce     sync.Once
+)
+
+// getDeRiskMetrics registers the de-risking metrics exactly once
+func getDeRiskMetrics() *deRiskMetrics {
+	deRiskMetricsOnce.Do(func() {
+		deRiskMetricsInstance = &deRiskMetrics{
+			steps: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "cryptofunk_derisk_steps_total",
+				Help: "De-risking steps planned by rule and action (reduce, flatten)",
+			}, []string{"rule", "action"}),
+			notional: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "cryptofunk_derisk_notional_total",
+				Help: "Notional cut by de-risking steps by rule (quote currency)",
+			}, []string{"rule"}),
+		}
+	})
+	return deRiskMetricsInstance
+}
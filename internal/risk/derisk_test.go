@@ -0,0 +1,170 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDeRiskPolicy() DeRiskPolicy {
+	return DeRiskPolicy{
+		Enabled:  true,
+		Ranking:  DeRiskRankLargest,
+		Cooldown: 10 * time.Minute,
+		Rules: []DeRiskRule{
+			{Name: "soft_drawdown", Metric: DeRiskMetricDrawdown, Threshold: 0.05, Action: DeRiskActionReduce, ReduceFraction: 0.25},
+			{Name: "hard_drawdown", Metric: DeRiskMetricDrawdown, Threshold: 0.10, Action: DeRiskActionFlatten},
+			{Name: "exposure", Metric: DeRiskMetricExposure, Threshold: 1.0, Action: DeRiskActionReduce, ReduceFraction: 0.5},
+		},
+	}
+}
+
+func testDeRiskPositions() []DeRiskPosition {
+	return []DeRiskPosition{
+		{ID: "eth", Symbol: "ETHUSDT", Side: "LONG", Quantity: 10, Price: 2000, StepSize: 0.01, MinNotional: 10},
+		{ID: "btc", Symbol: "BTCUSDT", Side: "SHORT", Quantity: 1, Price: 50000, StepSize: 0.001, MinNotional: 10},
+		{ID: "sol", Symbol: "SOLUSDT", Side: "LONG", Quantity: 100, Price: 100, StepSize: 0.1, MinNotional: 10},
+	}
+}
+
+func TestDeRiskPolicy_Validate(t *testing.T) {
+	require.NoError(t, testDeRiskPolicy().Validate())
+
+	invalid := []DeRiskRule{
+		{Name: "", Metric: DeRiskMetricDrawdown, Threshold: 0.1, Action: DeRiskActionFlatten},
+		{Name: "a", Metric: "sharpe", Threshold: 0.1, Action: DeRiskActionFlatten},
+		{Name: "a", Metric: DeRiskMetricDrawdown, Threshold: 0, Action: DeRiskActionFlatten},
+		{Name: "a", Metric: DeRiskMetricDrawdown, Threshold: 0.1, Action: DeRiskActionReduce},
+		{Name: "a", Metric: DeRiskMetricDrawdown, Threshold: 0.1, Action: "hedge"},
+	}
+	for _, rule := range invalid {
+		assert.Error(t, DeRiskPolicy{Rules: []DeRiskRule{rule}}.Validate(), "rule %+v", rule)
+	}
+	assert.Error(t, DeRiskPolicy{Ranking: "oldest"}.Validate())
+}
+
+func TestDeRisker_NoBreach(t *testing.T) {
+	d := NewDeRisker(testDeRiskPolicy())
+	state := DeRiskMetrics{Drawdown: 0.03, Exposure: 80000, ExposureLimit: 100000, ExposureUtilization: 0.8}
+	assert.Nil(t, d.Plan(state, testDeRiskPositions(), time.Now()))
+
+	disabled := testDeRiskPolicy()
+	disabled.Enabled = false
+	state.Drawdown = 0.5
+	assert.Nil(t, NewDeRisker(disabled).Plan(state, testDeRiskPositions(), time.Now()))
+}
+
+func TestDeRisker_SoftDrawdownReducesLargestFirst(t *testing.T) {
+	d := NewDeRisker(testDeRiskPolicy())
+	state := DeRiskMetrics{Drawdown: 0.07, Exposure: 80000, ExposureLimit: 100000, ExposureUtilization: 0.8, Positions: 3, LargestPosition: 50000}
+
+	plan := d.Plan(state, testDeRiskPositions(), time.Now())
+	require.NotNil(t, plan)
+	assert.Equal(t, "soft_drawdown", plan.Rule.Name)
+
+	// A quarter of $80k gross: 25% of BTC ($12.5k), 25% of ETH ($5k), then $2.5k of SOL
+	require.Len(t, plan.Orders, 3)
+	assert.Equal(t, "btc", plan.Orders[0].PositionID)
+	assert.Equal(t, "BUY", plan.Orders[0].Side)
+	assert.InDelta(t, 0.25, plan.Orders[0].Quantity, 1e-9)
+	assert.InDelta(t, 2.5, plan.Orders[1].Quantity, 1e-9)
+	assert.InDelta(t, 25, plan.Orders[2].Quantity, 1e-9)
+	for _, order := range plan.Orders {
+		assert.False(t, order.Close)
+	}
+
+	assert.InDelta(t, 60000, plan.After.Exposure, 1e-6)
+	assert.InDelta(t, 0.6, plan.After.ExposureUtilization, 1e-9)
+	assert.InDelta(t, 37500, plan.After.LargestPosition, 1e-6)
+	assert.Equal(t, 3, plan.After.Positions)
+	assert.Equal(t, 0.07, plan.After.Drawdown)
+	assert.Contains(t, plan.Reason(), "soft_drawdown")
+}
+
+func TestDeRisker_HardLimitFlattens(t *testing.T) {
+	d := NewDeRisker(testDeRiskPolicy())
+	state := DeRiskMetrics{Drawdown: 0.12, Exposure: 80000, ExposureLimit: 100000, ExposureUtilization: 0.8}
+
+	plan := d.Plan(state, testDeRiskPositions(), time.Now())
+	require.NotNil(t, plan)
+	assert.Equal(t, "hard_drawdown", plan.Rule.Name)
+	require.Len(t, plan.Orders, 3)
+	for _, order := range plan.Orders {
+		assert.True(t, order.Close)
+	}
+	assert.Equal(t, 0.0, plan.After.Exposure)
+	assert.Equal(t, 0, plan.After.Positions)
+}
+
+func TestDeRisker_ExposureCutsBackToLimit(t *testing.T) {
+	d := NewDeRisker(testDeRiskPolicy())
+	state := DeRiskMetrics{Exposure: 80000, ExposureLimit: 75000, ExposureUtilization: 80000.0 / 75000}
+
+	plan := d.Plan(state, testDeRiskPositions(), time.Now())
+	require.NotNil(t, plan)
+	assert.Equal(t, "exposure", plan.Rule.Name)
+	require.Len(t, plan.Orders, 1)
+	assert.Equal(t, "btc", plan.Orders[0].PositionID)
+	assert.InDelta(t, 5000, plan.Orders[0].Notional, 1e-6)
+	assert.InDelta(t, 1.0, plan.After.ExposureUtilization, 1e-9)
+}
+
+func TestDeRisker_RiskiestRanking(t *testing.T) {
+	policy := testDeRiskPolicy()
+	policy.Ranking = DeRiskRankRiskiest
+	positions := testDeRiskPositions()
+	positions[0].Volatility = 0.6  // ETH $20k -> 12,000
+	positions[1].Volatility = 0.2  // BTC $50k -> 10,000
+	positions[2].Volatility = 1.50 // SOL $10k -> 15,000
+
+	state := DeRiskMetrics{Exposure: 80000, ExposureLimit: 79000, ExposureUtilization: 80000.0 / 79000}
+	plan := NewDeRisker(policy).Plan(state, positions, time.Now())
+	require.NotNil(t, plan)
+	require.Len(t, plan.Orders, 1)
+	assert.Equal(t, "sol", plan.Orders[0].PositionID)
+}
+
+func TestDeRisker_CooldownAndReset(t *testing.T) {
+	d := NewDeRisker(testDeRiskPolicy())
+	breached := DeRiskMetrics{Drawdown: 0.07, Exposure: 80000, ExposureLimit: 100000, ExposureUtilization: 0.8}
+	now := time.Now()
+
+	require.NotNil(t, d.Plan(breached, testDeRiskPositions(), now))
+	assert.Nil(t, d.Plan(breached, testDeRiskPositions(), now.Add(5*time.Minute)), "still cooling down")
+	assert.NotNil(t, d.Plan(breached, testDeRiskPositions(), now.Add(11*time.Minute)), "next step after cooldown")
+
+	// Recovering clears the cooldown so a new breach acts at once
+	recovered := breached
+	recovered.Drawdown = 0.01
+	assert.Nil(t, d.Plan(recovered, testDeRiskPositions(), now.Add(12*time.Minute)))
+	assert.NotNil(t, d.Plan(breached, testDeRiskPositions(), now.Add(13*time.Minute)))
+}
+
+func TestDeRisker_RespectsMinimumOrderSize(t *testing.T) {
+	policy := DeRiskPolicy{
+		Enabled: true,
+		Rules:   []DeRiskRule{{Name: "soft", Metric: DeRiskMetricDrawdown, Threshold: 0.05, Action: DeRiskActionReduce, ReduceFraction: 0.25}},
+	}
+	positions := []DeRiskPosition{
+		// 25% would be $7.50, below the $10 minimum, so the cut is raised to it
+		{ID: "a", Symbol: "AUSDT", Side: "LONG", Quantity: 30, Price: 1, StepSize: 1, MinNotional: 10},
+		// Raising the cut to the $5 minimum would leave $3 of dust, so the whole position closes
+		{ID: "b", Symbol: "BUSDT", Side: "LONG", Quantity: 8, Price: 1, StepSize: 1, MinNotional: 5},
+		// Too small to trade at all
+		{ID: "c", Symbol: "CUSDT", Side: "LONG", Quantity: 4, Price: 1, StepSize: 1, MinNotional: 5},
+	}
+	state := DeRiskMetrics{Drawdown: 0.06, Exposure: 42}
+
+	plan := NewDeRisker(policy).Plan(state, positions, time.Now())
+	require.NotNil(t, plan)
+	assert.Equal(t, []string{"c"}, plan.Skipped)
+	require.Len(t, plan.Orders, 2)
+	assert.Equal(t, "a", plan.Orders[0].PositionID)
+	assert.InDelta(t, 10, plan.Orders[0].Quantity, 1e-9)
+	assert.False(t, plan.Orders[0].Close)
+	assert.Equal(t, "b", plan.Orders[1].PositionID)
+	assert.InDelta(t, 8, plan.Orders[1].Quantity, 1e-9)
+	assert.True(t, plan.Orders[1].Close)
+}
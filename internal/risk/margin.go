@@ -0,0 +1,623 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Margin monitoring defaults. Margin ratio is maintenance margin over margin
+// balance, so a position is liquidated when it reaches 1.
+const (
+	DefaultMarginWarnRatio      = 0.5
+	DefaultMarginActionRatio    = 0.8
+	DefaultMarginTargetRatio    = 0.4
+	DefaultMarginWarnDistance   = 0.10 // Adverse price move, as a fraction of the mark price
+	DefaultMarginActionDistance = 0.05
+	DefaultMarginReduceFraction = 0.25
+	DefaultMarginCooldown       = 5 * time.Minute
+)
+
+// MarginTier is one bracket of a maintenance margin schedule. Positions with
+// notional up to MaxNotional (zero = unbounded) pay MaintenanceRate of their
+// notional less MaintenanceAmount.
+type MarginTier struct {
+	MaxNotional       float64 `json:"max_notional"`
+	MaintenanceRate   float64 `json:"maintenance_rate"`
+	MaintenanceAmount float64 `json:"maintenance_amount"`
+}
+
+// DefaultMarginTiers is Binance's BTCUSDT perpetual maintenance schedule
+var DefaultMarginTiers = []MarginTier{
+	{MaxNotional: 50_000, MaintenanceRate: 0.004},
+	{MaxNotional: 500_000, MaintenanceRate: 0.005, MaintenanceAmount: 50},
+	{MaxNotional: 8_000_000, MaintenanceRate: 0.01, MaintenanceAmount: 2_550},
+	{MaxNotional: 50_000_000, MaintenanceRate: 0.025, MaintenanceAmount: 122_550},
+	{MaxNotional: 80_000_000, MaintenanceRate: 0.05, MaintenanceAmount: 1_372_550},
+	{MaxNotional: 100_000_000, MaintenanceRate: 0.1, MaintenanceAmount: 5_372_550},
+	{MaintenanceRate: 0.125, MaintenanceAmount: 7_872_550},
+}
+
+// MarginModel computes margin requirements and liquidation prices of leveraged
+// positions from a maintenance margin schedule
+type MarginModel struct {
+	Tiers []MarginTier // Ordered by MaxNotional; DefaultMarginTiers when empty
+}
+
+// tier returns the bracket a notional falls in
+func (m MarginModel) tier(notional float64) MarginTier {
+	tiers := m.Tiers
+	if len(tiers) == 0 {
+		tiers = DefaultMarginTiers
+	}
+	for _, t := range tiers {
+		if t.MaxNotional <= 0 || notional <= t.MaxNotional {
+			return t
+		}
+	}
+	return tiers[len(tiers)-1]
+}
+
+// MaintenanceMargin is the margin a position of this notional must keep
+func (m MarginModel) MaintenanceMargin(notional float64) float64 {
+	t := m.tier(notional)
+	return math.Max(0, notional*t.MaintenanceRate-t.MaintenanceAmount)
+}
+
+// MarginPosition is an open leveraged position
+type MarginPosition struct {
+	Symbol         string  `json:"symbol"`
+	Side           string  `json:"side"`     // LONG or SHORT
+	Quantity       float64 `json:"quantity"` // Absolute
+	EntryPrice     float64 `json:"entry_price"`
+	MarkPrice      float64 `json:"mark_price"`
+	Leverage       float64 `json:"leverage"`
+	Isolated       bool    `json:"isolated"`
+	IsolatedWallet float64 `json:"isolated_wallet"` // Margin assigned to an isolated position, excluding unrealized PnL
+}
+
+// direction is +1 for longs and -1 for shorts
+func (p MarginPosition) direction() float64 {
+	if p.Side == "SHORT" {
+		return -1
+	}
+	return 1
+}
+
+// Notional is the position's value at the mark price
+func (p MarginPosition) Notional() float64 {
+	return p.Quantity * p.MarkPrice
+}
+
+// UnrealizedPnL is the position's profit at the mark price
+func (p MarginPosition) UnrealizedPnL() float64 {
+	return p.direction() * p.Quantity * (p.MarkPrice - p.EntryPrice)
+}
+
+// key identifies the position in metrics and cooldowns
+func (p MarginPosition) key() string {
+	return p.Symbol + "/" + p.Side
+}
+
+// PositionMargin is the margin state of one position
+type PositionMargin struct {
+	Symbol              string  `json:"symbol"`
+	Side                string  `json:"side"`
+	Isolated            bool    `json:"isolated"`
+	Quantity            float64 `json:"quantity"`
+	MarkPrice           float64 `json:"mark_price"`
+	Notional            float64 `json:"notional"`
+	UnrealizedPnL       float64 `json:"unrealized_pnl"`
+	InitialMargin       float64 `json:"initial_margin"`
+	MaintenanceMargin   float64 `json:"maintenance_margin"`
+	MarginBalance       float64 `json:"margin_balance"` // Isolated wallet plus PnL, or the account's cross margin balance
+	MarginRatio         float64 `json:"margin_ratio"`   // Maintenance margin over margin balance; 1 liquidates
+	LiquidationPrice    float64 `json:"liquidation_price"`
+	LiquidationDistance float64 `json:"liquidation_distance"` // Adverse move to liquidation as a fraction of the mark price
+}
+
+// AccountMargin is the margin state of a futures account. The margin ratio
+// covers the cross margined positions, which share the cross wallet balance;
+// isolated positions are backed by their own wallets.
+type AccountMargin struct {
+	WalletBalance      float64          `json:"wallet_balance"`       // Including isolated wallets
+	CrossWalletBalance float64          `json:"cross_wallet_balance"` // Excluding isolated wallets
+	UnrealizedPnL      float64          `json:"unrealized_pnl"`
+	MarginBalance      float64          `json:"margin_balance"`       // Wallet balance plus unrealized PnL
+	CrossMarginBalance float64          `json:"cross_margin_balance"` // Cross wallet plus cross unrealized PnL
+	InitialMargin      float64          `json:"initial_margin"`       // Of every position
+	MaintenanceMargin  float64          `json:"maintenance_margin"`   // Of cross positions
+	MarginRatio        float64          `json:"margin_ratio"`         // Cross maintenance over cross margin balance
+	Utilization        float64          `json:"utilization"`          // Initial margin over margin balance
+	Available          float64          `json:"available"`            // Cross margin balance not used as initial margin
+	Positions          []PositionMargin `json:"positions"`
+}
+
+// Position returns the margin state of a symbol and side
+func (a *AccountMargin) Position(symbol, side string) (PositionMargin, bool) {
+	for _, p := range a.Positions {
+		if p.Symbol == symbol && p.Side == side {
+			return p, true
+		}
+	}
+	return PositionMargin{}, false
+}
+
+// Account computes the margin state of an account holding walletBalance,
+// isolated wallets included, and the given positions
+func (m MarginModel) Account(walletBalance float64, positions []MarginPosition) (*AccountMargin, error) {
+	account := &AccountMargin{
+		WalletBalance:      walletBalance,
+		CrossWalletBalance: walletBalance,
+		Positions:          make([]PositionMargin, 0, len(positions)),
+	}
+	crossPnL, crossInitial := 0.0, 0.0
+	for _, p := range positions {
+		if p.Quantity <= 0 || p.MarkPrice <= 0 {
+			return nil, fmt.Errorf("position %s has no quantity or mark price", p.key())
+		}
+		if p.Leverage <= 0 {
+			return nil, fmt.Errorf("position %s has no leverage", p.key())
+		}
+		account.UnrealizedPnL += p.UnrealizedPnL()
+		if p.Isolated {
+			account.CrossWalletBalance -= p.IsolatedWallet
+			account.InitialMargin += p.IsolatedWallet
+			continue
+		}
+		crossPnL += p.UnrealizedPnL()
+		crossInitial += p.Notional() / p.Leverage
+		account.MaintenanceMargin += m.MaintenanceMargin(p.Notional())
+	}
+	account.InitialMargin += crossInitial
+	account.MarginBalance = walletBalance + account.UnrealizedPnL
+	account.CrossMarginBalance = account.CrossWalletBalance + crossPnL
+	account.MarginRatio = marginRatio(account.MaintenanceMargin, account.CrossMarginBalance)
+	account.Available = account.CrossMarginBalance - crossInitial
+	if account.MarginBalance > 0 {
+		account.Utilization = account.InitialMargin / account.MarginBalance
+	}
+
+	for _, p := range positions {
+		account.Positions = append(account.Positions, m.position(p, account))
+	}
+	return account, nil
+}
+
+// position computes a position's margin state. A cross position's liquidation
+// price holds the other cross positions at their mark prices.
+func (m MarginModel) position(p MarginPosition, account *AccountMargin) PositionMargin {
+	notional := p.Notional()
+	t := m.tier(notional)
+	result := PositionMargin{
+		Symbol:            p.Symbol,
+		Side:              p.Side,
+		Isolated:          p.Isolated,
+		Quantity:          p.Quantity,
+		MarkPrice:         p.MarkPrice,
+		Notional:          notional,
+		UnrealizedPnL:     p.UnrealizedPnL(),
+		InitialMargin:     notional / p.Leverage,
+		MaintenanceMargin: m.MaintenanceMargin(notional),
+	}
+
+	// Collateral covering the position other than its own PnL and maintenance
+	wallet := p.IsolatedWallet
+	if p.Isolated {
+		result.MarginBalance = p.IsolatedWallet + result.UnrealizedPnL
+		result.MarginRatio = marginRatio(result.MaintenanceMargin, result.MarginBalance)
+	} else {
+		wallet = account.CrossMarginBalance - result.UnrealizedPnL - (account.MaintenanceMargin - result.MaintenanceMargin)
+		result.MarginBalance = account.CrossMarginBalance
+		result.MarginRatio = account.MarginRatio
+	}
+	result.LiquidationPrice = liquidationPrice(p, wallet, t)
+	result.LiquidationDistance = liquidationDistance(p, result.LiquidationPrice)
+	return result
+}
+
+// liquidationPrice solves wallet + PnL(price) = maintenance(price) for a
+// position in maintenance tier t. It is zero when a long cannot be liquidated.
+func liquidationPrice(p MarginPosition, wallet float64, t MarginTier) float64 {
+	side := p.direction()
+	denominator := p.Quantity * (t.MaintenanceRate - side)
+	if denominator == 0 {
+		return 0
+	}
+	price := (wallet + t.MaintenanceAmount - side*p.Quantity*p.EntryPrice) / denominator
+	return math.Max(0, price)
+}
+
+// liquidationDistance is the adverse price move, as a fraction of the mark
+// price, that liquidates a position. Positions past liquidation have zero.
+func liquidationDistance(p MarginPosition, liquidation float64) float64 {
+	if p.direction() > 0 {
+		return math.Max(0, (p.MarkPrice-liquidation)/p.MarkPrice)
+	}
+	if liquidation <= 0 {
+		return 0
+	}
+	return math.Max(0, (liquidation-p.MarkPrice)/p.MarkPrice)
+}
+
+// marginRatio is maintenance over balance; an exhausted balance is at 1 or more
+func marginRatio(maintenance, balance float64) float64 {
+	if maintenance <= 0 {
+		return 0
+	}
+	if balance <= 0 {
+		return math.Inf(1)
+	}
+	return maintenance / balance
+}
+
+// topUpFor returns the margin to add to an isolated position so its margin
+// ratio falls to targetRatio and its liquidation distance rises to
+// targetDistance
+func (m MarginModel) topUpFor(p MarginPosition, state PositionMargin, targetRatio, targetDistance float64) float64 {
+	needed := 0.0
+	if targetRatio > 0 {
+		needed = state.MaintenanceMargin/targetRatio - state.MarginBalance
+	}
+	if targetDistance > 0 {
+		// Invert liquidationPrice for the wallet that puts liquidation targetDistance away
+		side := p.direction()
+		t := m.tier(state.Notional)
+		target := p.MarkPrice * (1 - side*targetDistance)
+		wallet := target*p.Quantity*(t.MaintenanceRate-side) - t.MaintenanceAmount + side*p.Quantity*p.EntryPrice
+		needed = math.Max(needed, wallet-p.IsolatedWallet)
+	}
+	return math.Max(0, needed)
+}
+
+// MarginAlertLevel is the severity of a margin alert
+type MarginAlertLevel string
+
+const (
+	MarginAlertWarning  MarginAlertLevel = "warning"  // Past the warning thresholds
+	MarginAlertCritical MarginAlertLevel = "critical" // Past the action thresholds
+)
+
+// MarginActionType is what the monitor does about a position near liquidation
+type MarginActionType string
+
+const (
+	MarginActionTopUp  MarginActionType = "top_up" // Add margin to an isolated position
+	MarginActionReduce MarginActionType = "reduce" // Close part of a cross position
+)
+
+// MarginPolicy sets the thresholds of the margin monitor. Isolated positions
+// past the action thresholds are topped up; cross positions are reduced, since
+// cutting an isolated position releases its margin pro rata and leaves its
+// liquidation price where it was.
+type MarginPolicy struct {
+	Enabled        bool
+	WarnRatio      float64 // Alert at this margin ratio
+	ActionRatio    float64 // Act at this margin ratio
+	TargetRatio    float64 // Actions aim for this margin ratio
+	WarnDistance   float64 // Alert when liquidation is this close
+	ActionDistance float64 // Act when liquidation is this close
+	TopUp          bool    // Add margin to isolated positions
+	MaxTopUp       float64 // Largest single top-up in the quote currency (0 = available balance)
+	Reduce         bool    // Cut cross positions
+	ReduceFraction float64 // Smallest share of each cross position a reduction cuts
+	Cooldown       time.Duration
+}
+
+// withDefaults fills unset thresholds with the defaults
+func (p MarginPolicy) withDefaults() MarginPolicy {
+	if p.WarnRatio == 0 {
+		p.WarnRatio = DefaultMarginWarnRatio
+	}
+	if p.ActionRatio == 0 {
+		p.ActionRatio = DefaultMarginActionRatio
+	}
+	if p.TargetRatio == 0 {
+		p.TargetRatio = DefaultMarginTargetRatio
+	}
+	if p.WarnDistance == 0 {
+		p.WarnDistance = DefaultMarginWarnDistance
+	}
+	if p.ActionDistance == 0 {
+		p.ActionDistance = DefaultMarginActionDistance
+	}
+	if p.ReduceFraction == 0 {
+		p.ReduceFraction = DefaultMarginReduceFraction
+	}
+	if p.Cooldown == 0 {
+		p.Cooldown = DefaultMarginCooldown
+	}
+	return p
+}
+
+// Validate checks that the thresholds are ordered
+func (p MarginPolicy) Validate() error {
+	p = p.withDefaults()
+	if p.TargetRatio <= 0 || p.TargetRatio >= p.WarnRatio || p.WarnRatio > p.ActionRatio || p.ActionRatio >= 1 {
+		return fmt.Errorf("margin ratios must satisfy 0 < target < warn <= action < 1")
+	}
+	if p.ActionDistance <= 0 || p.ActionDistance > p.WarnDistance || p.WarnDistance >= 1 {
+		return fmt.Errorf("liquidation distances must satisfy 0 < action <= warn < 1")
+	}
+	if p.ReduceFraction <= 0 || p.ReduceFraction > 1 {
+		return fmt.Errorf("margin reduce fraction must be between 0 and 1")
+	}
+	if p.MaxTopUp < 0 || p.Cooldown < 0 {
+		return fmt.Errorf("margin max top-up and cooldown must not be negative")
+	}
+	return nil
+}
+
+// MarginAlert flags a position near liquidation
+type MarginAlert struct {
+	Symbol              string           `json:"symbol"`
+	Side                string           `json:"side"`
+	Level               MarginAlertLevel `json:"level"`
+	MarginRatio         float64          `json:"margin_ratio"`
+	LiquidationPrice    float64          `json:"liquidation_price"`
+	LiquidationDistance float64          `json:"liquidation_distance"`
+	Reason              string           `json:"reason"`
+}
+
+// MarginAction tops up or reduces a position
+type MarginAction struct {
+	Type     MarginActionType `json:"type"`
+	Symbol   string           `json:"symbol"`
+	Side     string           `json:"side"`
+	Amount   float64          `json:"amount,omitempty"`   // Margin to add
+	Quantity float64          `json:"quantity,omitempty"` // Quantity to close
+	Reason   string           `json:"reason"`
+}
+
+// MarginCheck is the outcome of one margin check
+type MarginCheck struct {
+	Account *AccountMargin `json:"account"`
+	Alerts  []MarginAlert  `json:"alerts,omitempty"`
+	Actions []MarginAction `json:"actions,omitempty"`
+}
+
+// MarginMonitor checks leveraged positions against a margin policy, exports
+// their margin state as gauges and plans top-ups and reductions. A position
+// acted on is left alone for the policy's cooldown.
+type MarginMonitor struct {
+	model MarginModel
+
+	mu       sync.Mutex
+	policy   MarginPolicy
+	lastStep map[string]time.Time
+	tracked  map[string][2]string // Position key -> symbol and side labels of exported gauges
+	metrics  *marginMetrics
+}
+
+// NewMarginMonitor creates a monitor for a policy
+func NewMarginMonitor(model MarginModel, policy MarginPolicy) *MarginMonitor {
+	return &MarginMonitor{
+		model:    model,
+		policy:   policy,
+		lastStep: make(map[string]time.Time),
+		tracked:  make(map[string][2]string),
+		metrics:  getMarginMetrics(),
+	}
+}
+
+// Policy returns the current policy
+func (m *MarginMonitor) Policy() MarginPolicy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.policy
+}
+
+// SetPolicy replaces the policy
+func (m *MarginMonitor) SetPolicy(policy MarginPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = policy
+}
+
+// Check computes the account's margin state, updates the gauges and returns
+// the alerts raised and, when the policy is enabled, the actions to take.
+// Top-ups together never exceed the account's available margin.
+func (m *MarginMonitor) Check(walletBalance float64, positions []MarginPosition, now time.Time) (*MarginCheck, error) {
+	account, err := m.model.Account(walletBalance, positions)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.export(account)
+
+	policy := m.policy.withDefaults()
+	check := &MarginCheck{Account: account}
+	available := math.Max(0, account.Available)
+	reduceCross := false
+	crossReason := ""
+
+	for i, p := range positions {
+		state := account.Positions[i]
+		level, reason := policy.level(state)
+		if level == "" {
+			delete(m.lastStep, p.key())
+			continue
+		}
+		check.Alerts = append(check.Alerts, MarginAlert{
+			Symbol:              state.Symbol,
+			Side:                state.Side,
+			Level:               level,
+			MarginRatio:         state.MarginRatio,
+			LiquidationPrice:    state.LiquidationPrice,
+			LiquidationDistance: state.LiquidationDistance,
+			Reason:              reason,
+		})
+		m.metrics.alerts.WithLabelValues(string(level)).Inc()
+
+		if level != MarginAlertCritical || !policy.Enabled {
+			continue
+		}
+		if last, ok := m.lastStep[p.key()]; ok && now.Sub(last) < policy.Cooldown {
+			continue
+		}
+
+		if !p.Isolated {
+			if policy.Reduce {
+				reduceCross = true
+				crossReason = reason
+			}
+			continue
+		}
+		if !policy.TopUp {
+			continue
+		}
+		amount := m.model.topUpFor(p, state, policy.TargetRatio, policy.WarnDistance)
+		if policy.MaxTopUp > 0 {
+			amount = math.Min(amount, policy.MaxTopUp)
+		}
+		amount = math.Min(amount, available)
+		if amount <= 0 {
+			continue
+		}
+		available -= amount
+		m.lastStep[p.key()] = now
+		check.Actions = append(check.Actions, MarginAction{
+			Type:   MarginActionTopUp,
+			Symbol: p.Symbol,
+			Side:   p.Side,
+			Amount: amount,
+			Reason: reason,
+		})
+	}
+
+	if reduceCross {
+		check.Actions = append(check.Actions, m.reduceCross(policy, account, positions, crossReason, now)...)
+	}
+	for _, action := range check.Actions {
+		m.metrics.actions.WithLabelValues(string(action.Type)).Inc()
+	}
+	return check, nil
+}
+
+// level returns the alert level of a position's margin state and why
+func (p MarginPolicy) level(state PositionMargin) (MarginAlertLevel, string) {
+	switch {
+	case state.MarginRatio >= p.ActionRatio:
+		return MarginAlertCritical, fmt.Sprintf("margin ratio %.2f at or above %.2f", state.MarginRatio, p.ActionRatio)
+	case state.LiquidationDistance <= p.ActionDistance:
+		return MarginAlertCritical, fmt.Sprintf("liquidation %.2f%% away, within %.2f%%", state.LiquidationDistance*100, p.ActionDistance*100)
+	case state.MarginRatio >= p.WarnRatio:
+		return MarginAlertWarning, fmt.Sprintf("margin ratio %.2f at or above %.2f", state.MarginRatio, p.WarnRatio)
+	case state.LiquidationDistance <= p.WarnDistance:
+		return MarginAlertWarning, fmt.Sprintf("liquidation %.2f%% away, within %.2f%%", state.LiquidationDistance*100, p.WarnDistance*100)
+	default:
+		return "", ""
+	}
+}
+
+// reduceCross cuts every cross position by the same fraction, enough to bring
+// the account's margin ratio down to the target and at least ReduceFraction.
+// Maintenance margin shrinks with the positions while the wallet stays.
+func (m *MarginMonitor) reduceCross(policy MarginPolicy, account *AccountMargin, positions []MarginPosition, reason string, now time.Time) []MarginAction {
+	fraction := policy.ReduceFraction
+	if account.MarginRatio > 0 {
+		fraction = math.Max(fraction, 1-policy.TargetRatio/account.MarginRatio)
+	}
+	fraction = math.Min(1, fraction)
+
+	var actions []MarginAction
+	for _, p := range positions {
+		if p.Isolated {
+			continue
+		}
+		m.lastStep[p.key()] = now
+		actions = append(actions, MarginAction{
+			Type:     MarginActionReduce,
+			Symbol:   p.Symbol,
+			Side:     p.Side,
+			Quantity: p.Quantity * fraction,
+			Reason:   reason,
+		})
+	}
+	return actions
+}
+
+// export sets the gauges of the account and its positions and drops those of
+// closed positions
+func (m *MarginMonitor) export(account *AccountMargin) {
+	m.metrics.accountRatio.Set(finiteOr(account.MarginRatio, 1))
+	m.metrics.utilization.Set(account.Utilization)
+
+	open := make(map[string][2]string, len(account.Positions))
+	for _, p := range account.Positions {
+		open[p.Symbol+"/"+p.Side] = [2]string{p.Symbol, p.Side}
+		m.metrics.distance.WithLabelValues(p.Symbol, p.Side).Set(p.LiquidationDistance)
+		m.metrics.ratio.WithLabelValues(p.Symbol, p.Side).Set(finiteOr(p.MarginRatio, 1))
+	}
+	for key, labels := range m.tracked {
+		if _, ok := open[key]; ok {
+			continue
+		}
+		m.metrics.distance.DeleteLabelValues(labels[0], labels[1])
+		m.metrics.ratio.DeleteLabelValues(labels[0], labels[1])
+		delete(m.lastStep, key)
+	}
+	m.tracked = open
+}
+
+// finiteOr replaces an infinite value, which gauges should not carry
+func finiteOr(value, fallback float64) float64 {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return fallback
+	}
+	return value
+}
+
+// marginMetrics holds Prometheus metrics for margin monitoring
+type marginMetrics struct {
+	distance     *prometheus.GaugeVec
+	ratio        *prometheus.GaugeVec
+	accountRatio prometheus.Gauge
+	utilization  prometheus.Gauge
+	alerts       *prometheus.CounterVec
+	actions      *prometheus.CounterVec
+}
+
+var (
+	marginMetricsInstance *marginMetrics
+	marginMetricsOnce     sync.Once
+)
+
+// getMarginMetrics registers the margin metrics exactly once
+func getMarginMetrics() *marginMetrics {
+	marginMetricsOnce.Do(func() {
+		marginMetricsInstance = &marginMetrics{
+			distance: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "cryptofunk_margin_liquidation_distance",
+				Help: "Adverse price move to liquidation as a fraction of the mark price, by position",
+			}, []string{"symbol", "side"}),
+			ratio: promauto.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "cryptofunk_margin_ratio",
+				Help: "Maintenance margin over margin balance by position (1 = liquidation)",
+			}, []string{"symbol", "side"}),
+			accountRatio: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "cryptofunk_margin_account_ratio",
+				Help: "Maintenance margin over margin balance of the cross margined positions",
+			}),
+			utilization: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "cryptofunk_margin_utilization",
+				Help: "Initial margin of all positions over the cross margin balance",
+			}),
+			alerts: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "cryptofunk_margin_alerts_total",
+				Help: "Margin checks that found a position past an alert threshold by level (warning, critical)",
+			}, []string{"level"}),
+			actions: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "cryptofunk_margin_actions_total",
+				Help: "Margin actions planned by type (top_up, reduce)",
+			}, []string{"action"}),
+		}
+	})
+	return marginMetricsInstance
+}
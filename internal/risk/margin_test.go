@@ -0,0 +1,153 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarginModel_MaintenanceMargin(t *testing.T) {
+	model := MarginModel{}
+	assert.InDelta(t, 40, model.MaintenanceMargin(10_000), 1e-9)
+	assert.InDelta(t, 100_000*0.005-50, model.MaintenanceMargin(100_000), 1e-9)
+	assert.InDelta(t, 200_000_000*0.125-7_872_550, model.MaintenanceMargin(200_000_000), 1e-9)
+
+	custom := MarginModel{Tiers: []MarginTier{{MaintenanceRate: 0.01}}}
+	assert.InDelta(t, 100, custom.MaintenanceMargin(10_000), 1e-9)
+}
+
+func TestMarginModel_Account(t *testing.T) {
+	model := MarginModel{}
+	account, err := model.Account(5000, []MarginPosition{
+		{Symbol: "BTCUSDT", Side: "LONG", Quantity: 1, EntryPrice: 10_000, MarkPrice: 10_000, Leverage: 10, Isolated: true, IsolatedWallet: 1000},
+		{Symbol: "ETHUSDT", Side: "SHORT", Quantity: 10, EntryPrice: 2000, MarkPrice: 2100, Leverage: 20},
+	})
+	require.NoError(t, err)
+
+	assert.InDelta(t, 4000, account.CrossWalletBalance, 1e-9)
+	assert.InDelta(t, -1000, account.UnrealizedPnL, 1e-9)
+	assert.InDelta(t, 3000, account.CrossMarginBalance, 1e-9)
+	assert.InDelta(t, 84, account.MaintenanceMargin, 1e-9)
+	assert.InDelta(t, 84.0/3000, account.MarginRatio, 1e-9)
+	assert.InDelta(t, 1000+21_000.0/20, account.InitialMargin, 1e-9)
+	assert.InDelta(t, 3000-21_000.0/20, account.Available, 1e-9)
+	assert.InDelta(t, account.InitialMargin/4000, account.Utilization, 1e-9)
+
+	// The isolated long is liquidated where its wallet plus PnL meets maintenance
+	long, ok := account.Position("BTCUSDT", "LONG")
+	require.True(t, ok)
+	assert.InDelta(t, 0.04, long.MarginRatio, 1e-9)
+	assert.InDelta(t, 9000/0.996, long.LiquidationPrice, 1e-6)
+	assert.InDelta(t, 1000+(long.LiquidationPrice-10_000), 0.004*long.LiquidationPrice, 1e-6)
+	assert.InDelta(t, (10_000-long.LiquidationPrice)/10_000, long.LiquidationDistance, 1e-9)
+
+	// The cross short shares the cross margin balance
+	short, ok := account.Position("ETHUSDT", "SHORT")
+	require.True(t, ok)
+	assert.Equal(t, account.MarginRatio, short.MarginRatio)
+	loss := 10 * (short.LiquidationPrice - 2000)
+	assert.InDelta(t, 4000-loss, 0.004*10*short.LiquidationPrice, 1e-6)
+	assert.Greater(t, short.LiquidationPrice, 2100.0)
+
+	_, err = model.Account(1000, []MarginPosition{{Symbol: "BTCUSDT", Side: "LONG", Quantity: 1, MarkPrice: 10_000}})
+	assert.Error(t, err, "leverage is required")
+}
+
+func TestMarginModel_UnleveragedLongCannotBeLiquidated(t *testing.T) {
+	account, err := MarginModel{}.Account(10_000, []MarginPosition{
+		{Symbol: "BTCUSDT", Side: "LONG", Quantity: 1, EntryPrice: 10_000, MarkPrice: 10_000, Leverage: 1, Isolated: true, IsolatedWallet: 10_000},
+	})
+	require.NoError(t, err)
+	assert.Zero(t, account.Positions[0].LiquidationPrice)
+	assert.Equal(t, 1.0, account.Positions[0].LiquidationDistance)
+}
+
+func TestMarginPolicy_Validate(t *testing.T) {
+	assert.NoError(t, MarginPolicy{}.Validate())
+	assert.Error(t, MarginPolicy{WarnRatio: 0.9, ActionRatio: 0.8}.Validate())
+	assert.Error(t, MarginPolicy{TargetRatio: 0.6}.Validate())
+	assert.Error(t, MarginPolicy{ActionRatio: 1}.Validate())
+	assert.Error(t, MarginPolicy{WarnDistance: 0.02}.Validate())
+	assert.Error(t, MarginPolicy{ReduceFraction: 1.5}.Validate())
+	assert.Error(t, MarginPolicy{MaxTopUp: -1}.Validate())
+}
+
+func TestMarginMonitor_TopsUpIsolatedPositions(t *testing.T) {
+	monitor := NewMarginMonitor(MarginModel{}, MarginPolicy{Enabled: true, TopUp: true})
+	now := time.Now()
+	position := MarginPosition{Symbol: "BTCUSDT", Side: "LONG", Quantity: 1, EntryPrice: 10_000, MarkPrice: 10_000, Leverage: 20, Isolated: true, IsolatedWallet: 600}
+
+	// Liquidation about 5.6% away only warns
+	check, err := monitor.Check(5000, []MarginPosition{position}, now)
+	require.NoError(t, err)
+	require.Len(t, check.Alerts, 1)
+	assert.Equal(t, MarginAlertWarning, check.Alerts[0].Level)
+	assert.Empty(t, check.Actions)
+	assert.InDelta(t, check.Account.Positions[0].LiquidationDistance,
+		testutil.ToFloat64(getMarginMetrics().distance.WithLabelValues("BTCUSDT", "LONG")), 1e-9)
+
+	// Within 5% the position is topped up until liquidation is 10% away
+	position.MarkPrice = 9900
+	check, err = monitor.Check(5000, []MarginPosition{position}, now)
+	require.NoError(t, err)
+	require.Len(t, check.Actions, 1)
+	action := check.Actions[0]
+	assert.Equal(t, MarginActionTopUp, action.Type)
+	assert.Equal(t, MarginAlertCritical, check.Alerts[0].Level)
+
+	position.IsolatedWallet += action.Amount
+	after, err := MarginModel{}.Account(5000+action.Amount, []MarginPosition{position})
+	require.NoError(t, err)
+	assert.InDelta(t, DefaultMarginWarnDistance, after.Positions[0].LiquidationDistance, 1e-9)
+
+	// The position is left alone during the cooldown
+	position.IsolatedWallet -= action.Amount
+	check, err = monitor.Check(5000, []MarginPosition{position}, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Len(t, check.Alerts, 1)
+	assert.Empty(t, check.Actions)
+
+	// Top-ups are capped
+	monitor.SetPolicy(MarginPolicy{Enabled: true, TopUp: true, MaxTopUp: 100})
+	check, err = monitor.Check(5000, []MarginPosition{position}, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, check.Actions, 1)
+	assert.InDelta(t, 100, check.Actions[0].Amount, 1e-9)
+
+	// Closed positions drop their gauges
+	_, err = monitor.Check(5000, nil, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, testutil.CollectAndCount(getMarginMetrics().distance))
+}
+
+func TestMarginMonitor_ReducesCrossPositions(t *testing.T) {
+	positions := []MarginPosition{
+		{Symbol: "BTCUSDT", Side: "LONG", Quantity: 2, EntryPrice: 10_000, MarkPrice: 9540, Leverage: 20},
+		{Symbol: "ETHUSDT", Side: "LONG", Quantity: 1, EntryPrice: 2000, MarkPrice: 2000, Leverage: 20, Isolated: true, IsolatedWallet: 500},
+	}
+
+	// Disabled policies alert without acting
+	monitor := NewMarginMonitor(MarginModel{}, MarginPolicy{Reduce: true})
+	check, err := monitor.Check(1500, positions, time.Now())
+	require.NoError(t, err)
+	require.Len(t, check.Alerts, 1)
+	assert.Equal(t, "BTCUSDT", check.Alerts[0].Symbol)
+	assert.Equal(t, MarginAlertCritical, check.Alerts[0].Level)
+	assert.Empty(t, check.Actions)
+
+	monitor.SetPolicy(MarginPolicy{Enabled: true, Reduce: true})
+	check, err = monitor.Check(1500, positions, time.Now())
+	require.NoError(t, err)
+	require.Len(t, check.Actions, 1)
+	action := check.Actions[0]
+	assert.Equal(t, MarginActionReduce, action.Type)
+	assert.Equal(t, "BTCUSDT", action.Symbol)
+
+	// The cut brings the cross margin ratio back to the target
+	ratio := check.Account.MarginRatio
+	require.Greater(t, ratio, DefaultMarginActionRatio)
+	assert.InDelta(t, 2*(1-DefaultMarginTargetRatio/ratio), action.Quantity, 1e-9)
+}
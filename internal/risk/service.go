@@ -1,6 +1,8 @@
 package risk
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 
 	"github.com/rs/zerolog/log"
@@ -126,6 +128,29 @@ func (s *Service) CalculateVaR(args map[string]interface{}) (interface{}, error)
 		return nil, fmt.Errorf("confidence_level must be between 0 and 1")
 	}
 
+	// Other methods go through the VaR engine; historical simulation is the default
+	method, err := ParseVaRMethod(stringArg(args, "method"))
+	if err != nil {
+		return nil, err
+	}
+	if method != VaRMethodHistorical {
+		engine, err := NewVaREngine(VaROptions{ConfidenceLevel: confidenceLevel})
+		if err != nil {
+			return nil, err
+		}
+		estimate, err := engine.Estimate(&Portfolio{Positions: []PortfolioPosition{{Value: 1, Returns: returns}}}, method)
+		if err != nil {
+			return nil, err
+		}
+		return &VaRResult{
+			VaR:             estimate.VaR,
+			CVaR:            estimate.ExpectedShortfall,
+			ConfidenceLevel: confidenceLevel * 100,
+			Method:          string(method),
+			Interpretation:  getVaRInterpretation(estimate.VaR),
+		}, nil
+	}
+
 	// Calculate VaR using historical simulation
 	// Sort returns in ascending order
 	sortedReturns := make([]float64, len(returns))
@@ -156,10 +181,229 @@ func (s *Service) CalculateVaR(args map[string]interface{}) (interface{}, error)
 		VaR:             varValue,
 		CVaR:            cvarValue,
 		ConfidenceLevel: confidenceLevel * 100,
+		Method:          string(VaRMethodHistorical),
 		Interpretation:  getVaRInterpretation(varValue),
 	}, nil
 }
 
+// CalculatePortfolioVaR estimates portfolio VaR and expected shortfall from
+// position values and return series. "method" selects one VaR method or "all".
+func (s *Service) CalculatePortfolioVaR(args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("CalculatePortfolioVaR called")
+
+	positionsRaw, ok := args["positions"].([]interface{})
+	if !ok || len(positionsRaw) == 0 {
+		return nil, fmt.Errorf("positions must be a non-empty array")
+	}
+
+	portfolio := &Portfolio{Positions: make([]PortfolioPosition, 0, len(positionsRaw))}
+	for i, p := range positionsRaw {
+		posMap, ok := p.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("positions[%d] must be an object", i)
+		}
+		value, ok := posMap["value"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("positions[%d].value must be a number", i)
+		}
+		returns, err := parseReturns(posMap["returns"])
+		if err != nil {
+			return nil, fmt.Errorf("positions[%d].returns: %w", i, err)
+		}
+		symbol, _ := posMap["symbol"].(string)
+		portfolio.Positions = append(portfolio.Positions, PortfolioPosition{Symbol: symbol, Value: value, Returns: returns})
+	}
+
+	options := VaROptions{}
+	if v, ok := args["confidence_level"].(float64); ok {
+		options.ConfidenceLevel = v
+	}
+	if v, ok := args["horizon_periods"].(float64); ok {
+		options.HorizonPeriods = int(v)
+	}
+	if v, ok := args["ewma_lambda"].(float64); ok {
+		options.EWMALambda = v
+	}
+	if v, ok := args["simulations"].(float64); ok {
+		options.Simulations = int(v)
+	}
+	if v, ok := args["seed"].(float64); ok {
+		options.Seed = int64(v)
+	}
+
+	engine, err := NewVaREngine(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var estimates []*VaREstimate
+	if methodName := stringArg(args, "method"); methodName == "all" {
+		estimates, err = engine.EstimateAll(portfolio)
+	} else {
+		var method VaRMethod
+		if method, err = ParseVaRMethod(methodName); err != nil {
+			return nil, err
+		}
+		var estimate *VaREstimate
+		if estimate, err = engine.Estimate(portfolio, method); err == nil {
+			estimates = []*VaREstimate{estimate}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	weights := portfolio.Weights()
+	positionWeights := make(map[string]float64, len(weights))
+	for i, position := range portfolio.Positions {
+		positionWeights[position.Symbol] += weights[i]
+	}
+
+	return &PortfolioVaRResult{
+		GrossValue:     portfolio.GrossValue(),
+		Weights:        positionWeights,
+		Estimates:      estimates,
+		Interpretation: getVaRInterpretation(estimates[0].VaR),
+	}, nil
+}
+
+// RunStressTest applies stress scenarios to positions. "scenarios" lists
+// built-in historical scenario names or scenario objects; without it every
+// built-in historical scenario is run.
+func (s *Service) RunStressTest(args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("RunStressTest called")
+
+	positionsRaw, ok := args["positions"].([]interface{})
+	if !ok || len(positionsRaw) == 0 {
+		return nil, fmt.Errorf("positions must be a non-empty array")
+	}
+	positions, err := ParseStressPositions(positionsRaw)
+	if err != nil {
+		return nil, err
+	}
+	scenarios, err := ParseStressScenarios(args["scenarios"])
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := NewStressTester(nil).RunAll(context.Background(), positions, scenarios)
+	if err != nil {
+		return nil, err
+	}
+	return NewStressTestResult(results), nil
+}
+
+// CalculateVolTargetSize sizes a position to a target annualized volatility.
+// The asset's volatility comes from "volatility" or from "returns" sampled
+// "periods_per_year" times a year. With "positions" (symbol, value, returns)
+// the whole portfolio is scaled to the target instead.
+func (s *Service) CalculateVolTargetSize(args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("CalculateVolTargetSize called")
+
+	capital, ok := args["capital"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("capital must be a number")
+	}
+	targetVol, ok := args["target_volatility"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("target_volatility must be a number")
+	}
+	maxLeverage, _ := args["max_leverage"].(float64)
+	periodsPerYear := float64(CryptoDaysPerYear)
+	if v, ok := args["periods_per_year"].(float64); ok {
+		periodsPerYear = v
+	}
+
+	if positionsRaw, ok := args["positions"].([]interface{}); ok {
+		portfolio := &Portfolio{Positions: make([]PortfolioPosition, 0, len(positionsRaw))}
+		for i, p := range positionsRaw {
+			posMap, ok := p.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("positions[%d] must be an object", i)
+			}
+			value, ok := posMap["value"].(float64)
+			if !ok {
+				return nil, fmt.Errorf("positions[%d].value must be a number", i)
+			}
+			returns, err := parseReturns(posMap["returns"])
+			if err != nil {
+				return nil, fmt.Errorf("positions[%d].returns: %w", i, err)
+			}
+			symbol, _ := posMap["symbol"].(string)
+			portfolio.Positions = append(portfolio.Positions, PortfolioPosition{Symbol: symbol, Value: value, Returns: returns})
+		}
+		return PortfolioVolTargetScale(portfolio, capital, targetVol, periodsPerYear, maxLeverage)
+	}
+
+	assetVol, ok := args["volatility"].(float64)
+	if !ok {
+		returns, err := parseReturns(args["returns"])
+		if err != nil {
+			return nil, fmt.Errorf("volatility or returns is required: %w", err)
+		}
+		if assetVol = AnnualizedVolatility(returns, periodsPerYear); assetVol == 0 {
+			return nil, fmt.Errorf("returns must have at least 2 values with non-zero volatility")
+		}
+	}
+
+	sizing, err := VolTargetSize(capital, targetVol, assetVol, maxLeverage)
+	if err != nil {
+		return nil, err
+	}
+	if price, ok := args["price"].(float64); ok && price > 0 {
+		sizing.Quantity = sizing.Notional / price
+	}
+	return sizing, nil
+}
+
+// CalculateATRSize sizes a position so that a stop "atr_multiplier" ATRs away
+// risks "risk_per_trade" of capital. The ATR comes from "atr" or is calculated
+// from "highs", "lows" and "closes" over "atr_period" candles.
+func (s *Service) CalculateATRSize(args map[string]interface{}) (interface{}, error) {
+	log.Debug().Interface("args", args).Msg("CalculateATRSize called")
+
+	capital, ok := args["capital"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("capital must be a number")
+	}
+	riskPerTrade, ok := args["risk_per_trade"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("risk_per_trade must be a number")
+	}
+	price, ok := args["price"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("price must be a number")
+	}
+	multiplier, _ := args["atr_multiplier"].(float64)
+	maxLeverage, _ := args["max_leverage"].(float64)
+	side := stringArg(args, "side")
+
+	atr, ok := args["atr"].(float64)
+	if !ok {
+		period := DefaultATRPeriod
+		if v, ok := args["atr_period"].(float64); ok {
+			period = int(v)
+		}
+		highs, err := parseFloats(args["highs"], "highs")
+		if err != nil {
+			return nil, fmt.Errorf("atr or candles are required: %w", err)
+		}
+		lows, err := parseFloats(args["lows"], "lows")
+		if err != nil {
+			return nil, err
+		}
+		closes, err := parseFloats(args["closes"], "closes")
+		if err != nil {
+			return nil, err
+		}
+		if atr, err = CalculateATR(highs, lows, closes, period); err != nil {
+			return nil, err
+		}
+	}
+
+	return ATRSize(capital, riskPerTrade, price, atr, multiplier, side, maxLeverage)
+}
+
 // CheckPortfolioLimits checks if a trade violates portfolio risk limits
 func (s *Service) CheckPortfolioLimits(args map[string]interface{}) (interface{}, error) {
 	log.Debug().Interface("args", args).Msg("CheckPortfolioLimits called")
@@ -225,6 +469,21 @@ func (s *Service) CheckPortfolioLimits(args map[string]interface{}) (interface{}
 			limits.MaxOpenPositions))
 	}
 
+	// Check exposure to symbols moving with the trade's symbol (optional correlations arg)
+	if correlationsRaw, ok := args["correlations"].(map[string]interface{}); ok {
+		correlations, err := ParseCorrelationMatrix(correlationsRaw)
+		if err != nil {
+			return nil, err
+		}
+		projected := append(append([]Position{}, positions...), Position{Symbol: newTrade.Symbol, Size: newTrade.Size})
+		correlatedExposure := CorrelatedExposure(correlations, projected, newTrade.Symbol, limits.MaxCorrelation)
+		maxCorrelatedExposure := limits.MaxTotalExposure * limits.MaxCorrelatedExposure
+		if correlatedExposure > maxCorrelatedExposure {
+			violations = append(violations, fmt.Sprintf("Exposure correlated above %.2f with %s %.2f would exceed limit %.2f",
+				limits.MaxCorrelation, newTrade.Symbol, correlatedExposure, maxCorrelatedExposure))
+		}
+	}
+
 	// Determine approval
 	approved := len(violations) == 0
 	reason := "Trade approved"
@@ -452,9 +711,37 @@ type VaRResult struct {
 	VaR             float64 `json:"var"`
 	CVaR            float64 `json:"cvar"`
 	ConfidenceLevel float64 `json:"confidence_level"`
+	Method          string  `json:"method"`
 	Interpretation  string  `json:"interpretation"`
 }
 
+// PortfolioVaRResult represents the result of portfolio VaR calculation
+type PortfolioVaRResult struct {
+	GrossValue     float64            `json:"gross_value"`
+	Weights        map[string]float64 `json:"weights"`
+	Estimates      []*VaREstimate     `json:"estimates"`
+	Interpretation string             `json:"interpretation"`
+}
+
+// StressTestResult represents the result of a stress test
+type StressTestResult struct {
+	Results       []*StressResult `json:"results"`
+	WorstScenario string          `json:"worst_scenario,omitempty"`
+	WorstPnL      float64         `json:"worst_pnl"`
+}
+
+// NewStressTestResult summarizes stress results by their worst scenario
+func NewStressTestResult(results []*StressResult) *StressTestResult {
+	summary := &StressTestResult{Results: results}
+	for _, result := range results {
+		if summary.WorstScenario == "" || result.PnL < summary.WorstPnL {
+			summary.WorstScenario = result.Scenario
+			summary.WorstPnL = result.PnL
+		}
+	}
+	return summary
+}
+
 // SharpeResult represents the result of Sharpe ratio calculation
 type SharpeResult struct {
 	SharpeRatio      float64 `json:"sharpe_ratio"`
@@ -605,6 +892,9 @@ type RiskLimits struct {
 	MaxTotalExposure float64
 	MaxConcentration float64 // As fraction of total exposure
 	MaxOpenPositions int
+
+	MaxCorrelation        float64 // Symbols correlated at or above this count as one exposure
+	MaxCorrelatedExposure float64 // As fraction of total exposure
 }
 
 // parseLimits extracts risk limits from raw data
@@ -614,6 +904,9 @@ func parseLimits(raw map[string]interface{}) RiskLimits {
 		MaxTotalExposure: 100000, // Default
 		MaxConcentration: 0.2,    // Default 20%
 		MaxOpenPositions: 10,     // Default
+
+		MaxCorrelation:        0.7, // Default
+		MaxCorrelatedExposure: 0.5, // Default 50%
 	}
 
 	if v, ok := raw["max_position_size"].(float64); ok {
@@ -628,6 +921,12 @@ func parseLimits(raw map[string]interface{}) RiskLimits {
 	if v, ok := raw["max_open_positions"].(float64); ok {
 		limits.MaxOpenPositions = int(v)
 	}
+	if v, ok := raw["max_correlation"].(float64); ok {
+		limits.MaxCorrelation = v
+	}
+	if v, ok := raw["max_correlated_exposure"].(float64); ok {
+		limits.MaxCorrelatedExposure = v
+	}
 
 	return limits
 }
@@ -663,6 +962,124 @@ func parseTrade(raw map[string]interface{}) Trade {
 	}
 }
 
+// stringArg returns a string argument, or "" when it is missing
+func stringArg(args map[string]interface{}, key string) string {
+	value, _ := args[key].(string)
+	return value
+}
+
+// parseReturns converts a JSON array of numbers to returns
+func parseReturns(raw interface{}) ([]float64, error) {
+	values, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("returns must be an array")
+	}
+	returns := make([]float64, len(values))
+	for i, v := range values {
+		r, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("all returns must be numbers")
+		}
+		returns[i] = r
+	}
+	return returns, nil
+}
+
+// parseFloats converts a JSON array of numbers to floats
+func parseFloats(raw interface{}, name string) ([]float64, error) {
+	values, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array", name)
+	}
+	floats := make([]float64, len(values))
+	for i, v := range values {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("all %s must be numbers", name)
+		}
+		floats[i] = f
+	}
+	return floats, nil
+}
+
+// ParseStressPositions converts JSON positions (symbol, side, quantity,
+// entry_price and optional price) to stress positions
+func ParseStressPositions(raw []interface{}) ([]StressPosition, error) {
+	positions := make([]StressPosition, 0, len(raw))
+	for i, p := range raw {
+		posMap, ok := p.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("positions[%d] must be an object", i)
+		}
+		symbol, _ := posMap["symbol"].(string)
+		if symbol == "" {
+			return nil, fmt.Errorf("positions[%d].symbol is required", i)
+		}
+		quantity, ok := posMap["quantity"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("positions[%d].quantity must be a number", i)
+		}
+		side, _ := posMap["side"].(string)
+		if side == "" {
+			side = "LONG"
+		}
+		entryPrice, _ := posMap["entry_price"].(float64)
+		price, _ := posMap["price"].(float64)
+		if entryPrice <= 0 && price <= 0 {
+			return nil, fmt.Errorf("positions[%d] needs a price or entry_price", i)
+		}
+
+		positions = append(positions, StressPosition{
+			Symbol:     symbol,
+			Side:       side,
+			Quantity:   quantity,
+			EntryPrice: entryPrice,
+			Price:      price,
+		})
+	}
+	return positions, nil
+}
+
+// ParseStressScenarios converts a JSON array of built-in historical scenario
+// names and scenario objects to scenarios. Nil means every historical scenario.
+func ParseStressScenarios(raw interface{}) ([]StressScenario, error) {
+	if raw == nil {
+		return HistoricalScenarios(), nil
+	}
+	values, ok := raw.([]interface{})
+	if !ok || len(values) == 0 {
+		return nil, fmt.Errorf("scenarios must be a non-empty array")
+	}
+
+	scenarios := make([]StressScenario, 0, len(values))
+	for i, v := range values {
+		switch value := v.(type) {
+		case string:
+			scenario, ok := HistoricalScenario(value)
+			if !ok {
+				return nil, fmt.Errorf("unknown historical scenario: %s", value)
+			}
+			scenarios = append(scenarios, scenario)
+		case map[string]interface{}:
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("scenarios[%d]: %w", i, err)
+			}
+			var scenario StressScenario
+			if err := json.Unmarshal(data, &scenario); err != nil {
+				return nil, fmt.Errorf("scenarios[%d]: %w", i, err)
+			}
+			if scenario.Name == "" {
+				return nil, fmt.Errorf("scenarios[%d].name is required", i)
+			}
+			scenarios = append(scenarios, scenario)
+		default:
+			return nil, fmt.Errorf("scenarios[%d] must be a name or an object", i)
+		}
+	}
+	return scenarios, nil
+}
+
 // calculateTotalExposure sums all position sizes
 func calculateTotalExposure(positions []Position) float64 {
 	total := 0.0
@@ -180,6 +180,30 @@ func TestCalculateVaR(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "Parametric method",
+			args: map[string]interface{}{
+				"returns": returns,
+				"method":  "parametric",
+			},
+			wantError: false,
+		},
+		{
+			name: "Filtered historical method",
+			args: map[string]interface{}{
+				"returns": returns,
+				"method":  "filtered_historical",
+			},
+			wantError: false,
+		},
+		{
+			name: "Unknown method",
+			args: map[string]interface{}{
+				"returns": returns,
+				"method":  "garch",
+			},
+			wantError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -266,6 +290,47 @@ func TestCheckPortfolioLimits(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "Trade pushes correlated exposure past limit",
+			args: map[string]interface{}{
+				"current_positions": []interface{}{
+					map[string]interface{}{"symbol": "BTC", "size": 9000.0},
+					map[string]interface{}{"symbol": "ETH", "size": 9000.0},
+				},
+				"new_trade": map[string]interface{}{"symbol": "SOL", "size": 9000.0},
+				"limits": map[string]interface{}{
+					"max_position_size":       10000.0,
+					"max_total_exposure":      50000.0,
+					"max_concentration":       0.3,
+					"max_open_positions":      float64(5),
+					"max_correlated_exposure": 0.5,
+				},
+				"correlations": map[string]interface{}{
+					"symbols": []interface{}{"BTC", "ETH", "SOL"},
+					"matrix": []interface{}{
+						[]interface{}{1.0, 0.9, 0.8},
+						[]interface{}{0.9, 1.0, 0.85},
+						[]interface{}{0.8, 0.85, 1.0},
+					},
+				},
+			},
+			wantError: false,
+			checkFunc: func(t *testing.T, result *PortfolioLimitsResult) {
+				if result.Approved {
+					t.Error("Expected trade to be rejected for correlated exposure")
+				}
+			},
+		},
+		{
+			name: "Malformed correlations",
+			args: map[string]interface{}{
+				"current_positions": currentPositions,
+				"new_trade":         map[string]interface{}{"symbol": "BTC", "size": 2000.0},
+				"limits":            limits,
+				"correlations":      map[string]interface{}{"symbols": []interface{}{"BTC"}},
+			},
+			wantError: true,
+		},
 		{
 			name: "Trade exceeds total exposure",
 			args: map[string]interface{}{
@@ -387,6 +452,95 @@ func TestCalculateSharpe(t *testing.T) {
 	}
 }
 
+func TestRunStressTest(t *testing.T) {
+	service := NewService()
+	positions := []interface{}{
+		map[string]interface{}{"symbol": "BTCUSDT", "side": "LONG", "quantity": 1.0, "entry_price": 40000.0, "price": 50000.0},
+		map[string]interface{}{"symbol": "ETHUSDT", "side": "SHORT", "quantity": 10.0, "entry_price": 3000.0},
+	}
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		wantError bool
+		wantRuns  int
+		wantWorst string
+	}{
+		{
+			name:      "All historical scenarios by default",
+			args:      map[string]interface{}{"positions": positions},
+			wantRuns:  3,
+			wantWorst: "covid_march_2020",
+		},
+		{
+			name: "Named and custom scenarios",
+			args: map[string]interface{}{
+				"positions": positions,
+				"scenarios": []interface{}{
+					"ftx_collapse_2022",
+					map[string]interface{}{
+						"name":          "btc_down_30_alts_down_45",
+						"shocks":        map[string]interface{}{"BTC": -0.30},
+						"default_shock": -0.45,
+					},
+				},
+			},
+			wantRuns:  2,
+			wantWorst: "ftx_collapse_2022",
+		},
+		{
+			name:      "Missing positions",
+			args:      map[string]interface{}{},
+			wantError: true,
+		},
+		{
+			name: "Position without a price",
+			args: map[string]interface{}{
+				"positions": []interface{}{map[string]interface{}{"symbol": "BTCUSDT", "quantity": 1.0}},
+			},
+			wantError: true,
+		},
+		{
+			name:      "Unknown historical scenario",
+			args:      map[string]interface{}{"positions": positions, "scenarios": []interface{}{"tulip_mania"}},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.RunStressTest(tt.args)
+
+			if tt.wantError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+
+			stressResult, ok := result.(*StressTestResult)
+			if !ok {
+				t.Fatal("Expected *StressTestResult type")
+			}
+
+			if len(stressResult.Results) != tt.wantRuns {
+				t.Errorf("Expected %d scenario results, got %d", tt.wantRuns, len(stressResult.Results))
+			}
+			if stressResult.WorstScenario != tt.wantWorst {
+				t.Errorf("Expected worst scenario %s, got %s", tt.wantWorst, stressResult.WorstScenario)
+			}
+			if stressResult.WorstPnL >= 0 {
+				t.Errorf("Expected a loss in the worst scenario, got %.2f", stressResult.WorstPnL)
+			}
+		})
+	}
+}
+
 func TestCalculateDrawdown(t *testing.T) {
 	service := NewService()
 
@@ -694,3 +848,86 @@ func abs(x float64) float64 {
 	}
 	return x
 }
+
+func TestCalculateVolTargetSize(t *testing.T) {
+	service := NewService()
+
+	result, err := service.CalculateVolTargetSize(map[string]interface{}{
+		"capital":           100000.0,
+		"target_volatility": 0.2,
+		"volatility":        0.8,
+		"price":             50000.0,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sizing := result.(*VolTargetSizing)
+	if sizing.Notional != 25000 || sizing.Quantity != 0.5 {
+		t.Errorf("Expected $25000 / 0.5 units, got $%.2f / %.4f", sizing.Notional, sizing.Quantity)
+	}
+
+	returns := []interface{}{0.02, -0.02, 0.02, -0.02}
+	result, err = service.CalculateVolTargetSize(map[string]interface{}{
+		"capital":           100000.0,
+		"target_volatility": 0.1,
+		"positions": []interface{}{
+			map[string]interface{}{"symbol": "BTCUSDT", "value": 50000.0, "returns": returns},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if target := result.(*PortfolioVolTarget); target.Scale <= 0 || target.Values["BTCUSDT"] <= 0 {
+		t.Errorf("Expected a positive portfolio scale, got %+v", target)
+	}
+
+	if _, err := service.CalculateVolTargetSize(map[string]interface{}{
+		"capital":           100000.0,
+		"target_volatility": 0.2,
+	}); err == nil {
+		t.Error("Expected error without volatility or returns")
+	}
+}
+
+func TestCalculateATRSize(t *testing.T) {
+	service := NewService()
+
+	result, err := service.CalculateATRSize(map[string]interface{}{
+		"capital":        100000.0,
+		"risk_per_trade": 0.01,
+		"price":          50000.0,
+		"atr":            500.0,
+		"atr_multiplier": 2.0,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sizing := result.(*ATRSizing); sizing.Quantity != 1 || sizing.StopLoss != 49000 {
+		t.Errorf("Expected 1 unit with a 49000 stop, got %+v", sizing)
+	}
+
+	result, err = service.CalculateATRSize(map[string]interface{}{
+		"capital":        10000.0,
+		"risk_per_trade": 0.01,
+		"price":          105.0,
+		"side":           "SELL",
+		"atr_period":     3.0,
+		"highs":          []interface{}{110.0, 110.0, 110.0, 110.0},
+		"lows":           []interface{}{100.0, 100.0, 100.0, 100.0},
+		"closes":         []interface{}{105.0, 105.0, 105.0, 105.0},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sizing := result.(*ATRSizing); sizing.ATR != 10 || sizing.StopLoss != 125 {
+		t.Errorf("Expected ATR 10 with a 125 stop, got %+v", sizing)
+	}
+
+	if _, err := service.CalculateATRSize(map[string]interface{}{
+		"capital":        10000.0,
+		"risk_per_trade": 0.01,
+		"price":          105.0,
+	}); err == nil {
+		t.Error("Expected error without ATR or candles")
+	}
+}
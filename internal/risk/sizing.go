@@ -0,0 +1,375 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// SizingMethod selects how a position's size is derived
+type SizingMethod string
+
+const (
+	SizingMethodKelly     SizingMethod = "kelly"
+	SizingMethodVolTarget SizingMethod = "vol_target"
+	SizingMethodATR       SizingMethod = "atr"
+)
+
+const (
+	// CryptoDaysPerYear annualizes volatility; crypto markets trade every day
+	CryptoDaysPerYear = 365
+	// DefaultATRPeriod is Wilder's original ATR period
+	DefaultATRPeriod = 14
+	// DefaultATRMultiplier places stops two ATRs from entry
+	DefaultATRMultiplier = 2.0
+)
+
+// ParseSizingMethod converts a method name to a SizingMethod; empty means Kelly
+func ParseSizingMethod(name string) (SizingMethod, error) {
+	switch SizingMethod(strings.ToLower(name)) {
+	case "", SizingMethodKelly:
+		return SizingMethodKelly, nil
+	case SizingMethodVolTarget:
+		return SizingMethodVolTarget, nil
+	case SizingMethodATR:
+		return SizingMethodATR, nil
+	default:
+		return "", fmt.Errorf("unknown sizing method: %s (use kelly, vol_target or atr)", name)
+	}
+}
+
+// PeriodsPerYear is the number of candles of an interval in a year
+func PeriodsPerYear(interval string) float64 {
+	return float64(CryptoDaysPerYear*24*time.Hour) / float64(intervalDuration(interval))
+}
+
+// AnnualizedVolatility scales the standard deviation of returns sampled
+// periodsPerYear times a year to an annual volatility
+func AnnualizedVolatility(returns []float64, periodsPerYear float64) float64 {
+	if len(returns) < 2 || periodsPerYear <= 0 {
+		return 0
+	}
+	return calculateStdDev(returns) * math.Sqrt(periodsPerYear)
+}
+
+// ============================================================================
+// VOLATILITY TARGETING
+// ============================================================================
+
+// VolTargetSizing is the notional that gives a position the target volatility
+type VolTargetSizing struct {
+	Notional         float64 `json:"notional"`
+	Quantity         float64 `json:"quantity,omitempty"` // Set when a price is known
+	Leverage         float64 `json:"leverage"`           // Notional as a multiple of capital
+	TargetVolatility float64 `json:"target_volatility"`
+	AssetVolatility  float64 `json:"asset_volatility"`
+	Capped           bool    `json:"capped"` // Notional was limited by max leverage
+}
+
+// VolTargetSize sizes a position so that its annualized volatility is
+// targetVol of capital: notional = capital × targetVol / assetVol. Notional
+// never exceeds capital × maxLeverage; maxLeverage defaults to 1 (no leverage).
+func VolTargetSize(capital, targetVol, assetVol, maxLeverage float64) (*VolTargetSizing, error) {
+	if capital <= 0 {
+		return nil, fmt.Errorf("capital must be positive")
+	}
+	if targetVol <= 0 {
+		return nil, fmt.Errorf("target volatility must be positive")
+	}
+	if assetVol <= 0 {
+		return nil, fmt.Errorf("asset volatility must be positive")
+	}
+	if maxLeverage <= 0 {
+		maxLeverage = 1
+	}
+
+	sizing := &VolTargetSizing{
+		Notional:         capital * targetVol / assetVol,
+		TargetVolatility: targetVol,
+		AssetVolatility:  assetVol,
+	}
+	if limit := capital * maxLeverage; sizing.Notional > limit {
+		sizing.Notional = limit
+		sizing.Capped = true
+	}
+	sizing.Leverage = sizing.Notional / capital
+	return sizing, nil
+}
+
+// PortfolioVolTarget scales a portfolio to a target annualized volatility
+type PortfolioVolTarget struct {
+	Volatility       float64            `json:"volatility"` // Current annualized volatility as a fraction of capital
+	TargetVolatility float64            `json:"target_volatility"`
+	Scale            float64            `json:"scale"`  // Multiplier applied to every position
+	Values           map[string]float64 `json:"values"` // Target value per symbol, negative for shorts
+	Capped           bool               `json:"capped"` // Scale was limited by max leverage
+}
+
+// PortfolioVolTargetScale finds the common multiplier that brings the
+// portfolio's annualized volatility, measured against capital, to targetVol.
+// Correlations are taken from the positions' shared return periods. The scaled
+// gross value never exceeds capital × maxLeverage (default 1).
+func PortfolioVolTargetScale(portfolio *Portfolio, capital, targetVol, periodsPerYear, maxLeverage float64) (*PortfolioVolTarget, error) {
+	if portfolio == nil || len(portfolio.Positions) == 0 {
+		return nil, fmt.Errorf("portfolio has no positions")
+	}
+	if capital <= 0 {
+		return nil, fmt.Errorf("capital must be positive")
+	}
+	if targetVol <= 0 {
+		return nil, fmt.Errorf("target volatility must be positive")
+	}
+	if portfolio.periods() < 2 {
+		return nil, fmt.Errorf("need at least 2 shared return periods")
+	}
+	if maxLeverage <= 0 {
+		maxLeverage = 1
+	}
+
+	gross := portfolio.GrossValue()
+	if gross == 0 {
+		return nil, fmt.Errorf("portfolio has no exposure")
+	}
+	volatility := AnnualizedVolatility(portfolio.Returns(), periodsPerYear) * gross / capital
+	if volatility == 0 {
+		return nil, fmt.Errorf("portfolio returns have no volatility")
+	}
+
+	target := &PortfolioVolTarget{
+		Volatility:       volatility,
+		TargetVolatility: targetVol,
+		Scale:            targetVol / volatility,
+		Values:           make(map[string]float64, len(portfolio.Positions)),
+	}
+	if limit := capital * maxLeverage / gross; target.Scale > limit {
+		target.Scale = limit
+		target.Capped = true
+	}
+	for _, position := range portfolio.Positions {
+		target.Values[position.Symbol] += position.Value * target.Scale
+	}
+	return target, nil
+}
+
+// MarginalVolTargetSize returns how much of symbol, up to maxValue, can be
+// added to the portfolio before its annualized volatility, measured against
+// capital, exceeds targetVol. The portfolio must include symbol's returns
+// (with a zero Value when it is not held). Additions that reduce volatility,
+// such as hedges, are not limited.
+func MarginalVolTargetSize(portfolio *Portfolio, symbol string, maxValue, capital, targetVol, periodsPerYear float64) (float64, error) {
+	if portfolio == nil || len(portfolio.Positions) == 0 {
+		return 0, fmt.Errorf("portfolio has no positions")
+	}
+	if capital <= 0 || targetVol <= 0 || periodsPerYear <= 0 {
+		return 0, fmt.Errorf("capital, target volatility and periods per year must be positive")
+	}
+	periods := portfolio.periods()
+	if periods < 2 {
+		return 0, fmt.Errorf("need at least 2 shared return periods")
+	}
+
+	// Quote-currency P&L of the current positions and returns of the addition
+	series := portfolio.assetReturns()
+	pnl := make([]float64, periods)
+	var asset []float64
+	for i, position := range portfolio.Positions {
+		for t, r := range series[i] {
+			pnl[t] += position.Value * r
+		}
+		if strings.EqualFold(position.Symbol, symbol) {
+			asset = series[i]
+		}
+	}
+	if asset == nil {
+		return 0, fmt.Errorf("portfolio has no returns for %s", symbol)
+	}
+
+	// Var(pnl + c·asset) = A + 2cB + c²C must stay within the target variance
+	cov := covariance([][]float64{pnl, asset}, []float64{mean(pnl), mean(asset)})
+	a, b, c := cov[0][0], cov[0][1], cov[1][1]
+	limit := math.Pow(targetVol*capital, 2) / periodsPerYear
+	if c == 0 {
+		if a <= limit {
+			return maxValue, nil
+		}
+		return 0, nil
+	}
+	discriminant := b*b - c*(a-limit)
+	if discriminant < 0 {
+		return 0, nil // Adding this asset can never bring the portfolio to target
+	}
+	upper := (-b + math.Sqrt(discriminant)) / c
+	return math.Max(math.Min(maxValue, upper), 0), nil
+}
+
+// ============================================================================
+// ATR SIZING
+// ============================================================================
+
+// CalculateATR returns the Average True Range of the candles using Wilder's
+// smoothing. The first candle only supplies the previous close, so at least
+// period+1 candles are needed; older candles warm up the average.
+func CalculateATR(highs, lows, closes []float64, period int) (float64, error) {
+	if period <= 0 {
+		return 0, fmt.Errorf("ATR period must be positive")
+	}
+	if len(highs) != len(lows) || len(highs) != len(closes) {
+		return 0, fmt.Errorf("highs, lows and closes must have the same length")
+	}
+	if len(closes) < period+1 {
+		return 0, fmt.Errorf("need at least %d candles for a %d-period ATR (got %d)", period+1, period, len(closes))
+	}
+
+	var atr float64
+	for i := 1; i < len(closes); i++ {
+		trueRange := math.Max(highs[i]-lows[i],
+			math.Max(math.Abs(highs[i]-closes[i-1]), math.Abs(lows[i]-closes[i-1])))
+		switch {
+		case i < period:
+			atr += trueRange
+		case i == period:
+			atr = (atr + trueRange) / float64(period)
+		default:
+			atr = (atr*float64(period-1) + trueRange) / float64(period)
+		}
+	}
+	return atr, nil
+}
+
+// ATRSizing is a position sized so that a stop multiplier ATRs away risks a
+// fixed share of capital
+type ATRSizing struct {
+	Quantity     float64 `json:"quantity"`
+	Notional     float64 `json:"notional"`
+	RiskAmount   float64 `json:"risk_amount"` // Loss if the stop is hit
+	ATR          float64 `json:"atr"`
+	StopDistance float64 `json:"stop_distance"`
+	StopLoss     float64 `json:"stop_loss"`
+	Capped       bool    `json:"capped"` // Notional was limited by max leverage
+}
+
+// ATRSize sizes a position so that hitting a stop multiplier×ATR from price
+// loses riskPerTrade of capital: quantity = capital × riskPerTrade / (N × ATR).
+// Notional never exceeds capital × maxLeverage (default 1).
+func ATRSize(capital, riskPerTrade, price, atr, multiplier float64, side string, maxLeverage float64) (*ATRSizing, error) {
+	if capital <= 0 {
+		return nil, fmt.Errorf("capital must be positive")
+	}
+	if riskPerTrade <= 0 || riskPerTrade > 1 {
+		return nil, fmt.Errorf("risk per trade must be between 0 and 1")
+	}
+	if price <= 0 {
+		return nil, fmt.Errorf("price must be positive")
+	}
+	if atr <= 0 {
+		return nil, fmt.Errorf("ATR must be positive")
+	}
+	if multiplier <= 0 {
+		multiplier = DefaultATRMultiplier
+	}
+	if maxLeverage <= 0 {
+		maxLeverage = 1
+	}
+
+	stopDistance := multiplier * atr
+	sizing := &ATRSizing{
+		Quantity:     capital * riskPerTrade / stopDistance,
+		ATR:          atr,
+		StopDistance: stopDistance,
+		StopLoss:     ATRStopLoss(price, atr, multiplier, side),
+	}
+	if limit := capital * maxLeverage / price; sizing.Quantity > limit {
+		sizing.Quantity = limit
+		sizing.Capped = true
+	}
+	sizing.Notional = sizing.Quantity * price
+	sizing.RiskAmount = sizing.Quantity * stopDistance
+	return sizing, nil
+}
+
+// ATRStopLoss places a stop multiplier ATRs below a long entry or above a
+// short one. Long stops never go below zero.
+func ATRStopLoss(price, atr, multiplier float64, side string) float64 {
+	distance := multiplier * atr
+	switch strings.ToUpper(side) {
+	case "SELL", "SHORT":
+		return price + distance
+	default:
+		return math.Max(price-distance, 0)
+	}
+}
+
+// ============================================================================
+// DATABASE-BACKED SIZING INPUTS
+// ============================================================================
+
+// LoadAnnualizedVolatility measures a symbol's annualized volatility from its
+// candle returns over the last days
+func (c *Calculator) LoadAnnualizedVolatility(ctx context.Context, symbol string, interval string, days int) (float64, error) {
+	histData, err := c.LoadHistoricalPrices(ctx, symbol, interval, days)
+	if err != nil {
+		return 0, err
+	}
+	volatility := AnnualizedVolatility(histData.Returns, PeriodsPerYear(interval))
+	if volatility == 0 {
+		return 0, fmt.Errorf("not enough price history to measure volatility of %s", symbol)
+	}
+	return volatility, nil
+}
+
+// LoadATR calculates a symbol's ATR from its most recent candles. Four times
+// the period is loaded so Wilder's smoothing has history to warm up on.
+func (c *Calculator) LoadATR(ctx context.Context, symbol string, interval string, period int) (float64, error) {
+	// Validate symbol to prevent SQL injection
+	if !isValidSymbol(symbol) {
+		return 0, fmt.Errorf("invalid symbol format: %s", symbol)
+	}
+	if period <= 0 {
+		period = DefaultATRPeriod
+	}
+
+	// Return error if no pool available
+	if c.pool == nil {
+		return 0, fmt.Errorf("no database pool available")
+	}
+
+	query := `
+		SELECT high, low, close
+		FROM candlesticks
+		WHERE symbol = $1
+			AND interval = $2
+		ORDER BY open_time DESC
+		LIMIT $3
+	`
+
+	rows, err := c.pool.Query(ctx, query, symbol, interval, 4*period+1)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query candles: %w", err)
+	}
+	defer rows.Close()
+
+	var highs, lows, closes []float64
+	for rows.Next() {
+		var high, low, closePrice float64
+		if err := rows.Scan(&high, &low, &closePrice); err != nil {
+			return 0, fmt.Errorf("failed to scan candle row: %w", err)
+		}
+		highs = append(highs, high)
+		lows = append(lows, low)
+		closes = append(closes, closePrice)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating candle rows: %w", err)
+	}
+
+	// Newest first from the query; ATR runs oldest first
+	for i, j := 0, len(closes)-1; i < j; i, j = i+1, j-1 {
+		highs[i], highs[j] = highs[j], highs[i]
+		lows[i], lows[j] = lows[j], lows[i]
+		closes[i], closes[j] = closes[j], closes[i]
+	}
+
+	return CalculateATR(highs, lows, closes, period)
+}
@@ -0,0 +1,222 @@
+package risk
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSizingMethod(t *testing.T) {
+	method, err := ParseSizingMethod("")
+	require.NoError(t, err)
+	assert.Equal(t, SizingMethodKelly, method)
+
+	method, err = ParseSizingMethod("VOL_TARGET")
+	require.NoError(t, err)
+	assert.Equal(t, SizingMethodVolTarget, method)
+
+	_, err = ParseSizingMethod("martingale")
+	assert.Error(t, err)
+}
+
+func TestPeriodsPerYear(t *testing.T) {
+	assert.InDelta(t, 365, PeriodsPerYear("1d"), 1e-9)
+	assert.InDelta(t, 365*24, PeriodsPerYear("1h"), 1e-9)
+	assert.InDelta(t, 365*6, PeriodsPerYear("4h"), 1e-9)
+}
+
+func TestVolTargetSize(t *testing.T) {
+	// 20% target on an 80% vol asset: a quarter of capital
+	sizing, err := VolTargetSize(100000, 0.20, 0.80, 0)
+	require.NoError(t, err)
+	assert.InDelta(t, 25000, sizing.Notional, 1e-9)
+	assert.InDelta(t, 0.25, sizing.Leverage, 1e-9)
+	assert.False(t, sizing.Capped)
+
+	// A 10% vol asset would need 2x leverage; spot caps it at 1x
+	sizing, err = VolTargetSize(100000, 0.20, 0.10, 0)
+	require.NoError(t, err)
+	assert.InDelta(t, 100000, sizing.Notional, 1e-9)
+	assert.True(t, sizing.Capped)
+
+	sizing, err = VolTargetSize(100000, 0.20, 0.10, 3)
+	require.NoError(t, err)
+	assert.InDelta(t, 200000, sizing.Notional, 1e-9)
+	assert.False(t, sizing.Capped)
+
+	_, err = VolTargetSize(100000, 0.20, 0, 0)
+	assert.Error(t, err)
+	_, err = VolTargetSize(0, 0.20, 0.5, 0)
+	assert.Error(t, err)
+}
+
+func TestPortfolioVolTargetScale(t *testing.T) {
+	returns := []float64{0.02, -0.02, 0.02, -0.02, 0.02, -0.02}
+	hedge := []float64{-0.02, 0.02, -0.02, 0.02, -0.02, 0.02}
+	dailyVol := AnnualizedVolatility(returns, CryptoDaysPerYear)
+
+	portfolio := &Portfolio{Positions: []PortfolioPosition{
+		{Symbol: "BTCUSDT", Value: 30000, Returns: returns},
+		{Symbol: "ETHUSDT", Value: 20000, Returns: returns},
+	}}
+	target, err := PortfolioVolTargetScale(portfolio, 100000, 0.10, CryptoDaysPerYear, 0)
+	require.NoError(t, err)
+
+	// Perfectly correlated: portfolio vol is half the asset vol on half the capital
+	assert.InDelta(t, dailyVol*0.5, target.Volatility, 1e-9)
+	assert.InDelta(t, 0.10/(dailyVol*0.5), target.Scale, 1e-9)
+	assert.InDelta(t, 30000*target.Scale, target.Values["BTCUSDT"], 1e-6)
+	assert.False(t, target.Capped)
+
+	// A perfect hedge leaves no volatility to scale
+	portfolio.Positions[1].Returns = hedge
+	portfolio.Positions[1].Value = 30000
+	_, err = PortfolioVolTargetScale(portfolio, 100000, 0.10, CryptoDaysPerYear, 0)
+	assert.Error(t, err)
+
+	// A very high target is capped at 1x gross
+	portfolio.Positions[1].Returns = returns
+	target, err = PortfolioVolTargetScale(portfolio, 100000, 50, CryptoDaysPerYear, 0)
+	require.NoError(t, err)
+	assert.True(t, target.Capped)
+	assert.InDelta(t, 100000.0/60000.0, target.Scale, 1e-9)
+}
+
+func TestMarginalVolTargetSize(t *testing.T) {
+	returns := []float64{0.02, -0.02, 0.02, -0.02, 0.02, -0.02}
+	hedge := []float64{-0.02, 0.02, -0.02, 0.02, -0.02, 0.02}
+	assetVol := AnnualizedVolatility(returns, CryptoDaysPerYear)
+
+	// Empty book: the addition alone may carry the target volatility
+	portfolio := &Portfolio{Positions: []PortfolioPosition{{Symbol: "BTCUSDT", Returns: returns}}}
+	size, err := MarginalVolTargetSize(portfolio, "BTCUSDT", 1e9, 100000, 0.10, CryptoDaysPerYear)
+	require.NoError(t, err)
+	assert.InDelta(t, 100000*0.10/assetVol, size, 1e-6)
+
+	// A correlated holding uses up part of the budget
+	portfolio.Positions = append(portfolio.Positions, PortfolioPosition{Symbol: "ETHUSDT", Value: 10000, Returns: returns})
+	size, err = MarginalVolTargetSize(portfolio, "BTCUSDT", 1e9, 100000, 0.10, CryptoDaysPerYear)
+	require.NoError(t, err)
+	assert.InDelta(t, 100000*0.10/assetVol-10000, size, 1e-6)
+
+	// Smaller requests pass through
+	size, err = MarginalVolTargetSize(portfolio, "BTCUSDT", 500, 100000, 0.10, CryptoDaysPerYear)
+	require.NoError(t, err)
+	assert.Equal(t, 500.0, size)
+
+	// Hedging an over-target book is not limited
+	portfolio.Positions[1].Value = 1e6
+	portfolio.Positions[0].Returns = hedge
+	size, err = MarginalVolTargetSize(portfolio, "BTCUSDT", 2e5, 100000, 0.10, CryptoDaysPerYear)
+	require.NoError(t, err)
+	assert.Equal(t, 2e5, size)
+
+	_, err = MarginalVolTargetSize(portfolio, "SOLUSDT", 1000, 100000, 0.10, CryptoDaysPerYear)
+	assert.Error(t, err)
+}
+
+func TestCalculateATR(t *testing.T) {
+	// Constant 10-point ranges with no gaps
+	highs := []float64{110, 110, 110, 110, 110}
+	lows := []float64{100, 100, 100, 100, 100}
+	closes := []float64{105, 105, 105, 105, 105}
+	atr, err := CalculateATR(highs, lows, closes, 3)
+	require.NoError(t, err)
+	assert.InDelta(t, 10, atr, 1e-9)
+
+	// A gap up counts from the previous close
+	highs = []float64{110, 130, 130, 130}
+	lows = []float64{100, 125, 125, 125}
+	closes = []float64{105, 128, 128, 128}
+	atr, err = CalculateATR(highs, lows, closes, 2)
+	require.NoError(t, err)
+	// TRs: 25, 5, 5 → seed (25+5)/2 = 15, then (15+5)/2 = 10
+	assert.InDelta(t, 10, atr, 1e-9)
+
+	_, err = CalculateATR(highs, lows, closes, 4)
+	assert.Error(t, err)
+	_, err = CalculateATR(highs, lows[:2], closes, 2)
+	assert.Error(t, err)
+}
+
+func TestATRSize(t *testing.T) {
+	// Risk 1% of $100k with a 2×$500 stop: 1 BTC
+	sizing, err := ATRSize(100000, 0.01, 50000, 500, 2, "BUY", 0)
+	require.NoError(t, err)
+	assert.InDelta(t, 1, sizing.Quantity, 1e-9)
+	assert.InDelta(t, 50000, sizing.Notional, 1e-9)
+	assert.InDelta(t, 1000, sizing.RiskAmount, 1e-9)
+	assert.InDelta(t, 49000, sizing.StopLoss, 1e-9)
+
+	sizing, err = ATRSize(100000, 0.01, 50000, 500, 2, "SHORT", 0)
+	require.NoError(t, err)
+	assert.InDelta(t, 51000, sizing.StopLoss, 1e-9)
+
+	// A tight stop would need more than the capital
+	sizing, err = ATRSize(100000, 0.02, 50000, 100, 2, "BUY", 0)
+	require.NoError(t, err)
+	assert.True(t, sizing.Capped)
+	assert.InDelta(t, 2, sizing.Quantity, 1e-9)
+	assert.InDelta(t, 400, sizing.RiskAmount, 1e-9)
+
+	_, err = ATRSize(100000, 0.01, 50000, 0, 2, "BUY", 0)
+	assert.Error(t, err)
+	_, err = ATRSize(100000, 1.5, 50000, 500, 2, "BUY", 0)
+	assert.Error(t, err)
+}
+
+func TestATRStopLoss(t *testing.T) {
+	assert.Equal(t, 90.0, ATRStopLoss(100, 5, 2, "LONG"))
+	assert.Equal(t, 110.0, ATRStopLoss(100, 5, 2, "sell"))
+	assert.Equal(t, 0.0, ATRStopLoss(10, 20, 1, "BUY"))
+}
+
+func TestLoadATR(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	// Newest candle first, as the query returns them
+	rows := pgxmock.NewRows([]string{"high", "low", "close"}).
+		AddRow(130.0, 125.0, 128.0).
+		AddRow(130.0, 125.0, 128.0).
+		AddRow(130.0, 125.0, 128.0).
+		AddRow(110.0, 100.0, 105.0)
+	mock.ExpectQuery("SELECT high, low, close FROM candlesticks").
+		WithArgs("BTCUSDT", "1h", 9).
+		WillReturnRows(rows)
+
+	atr, err := NewCalculator(mock).LoadATR(context.Background(), "BTCUSDT", "1h", 2)
+	require.NoError(t, err)
+	assert.InDelta(t, 10, atr, 1e-9)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = NewCalculator(mock).LoadATR(context.Background(), "BTC'; DROP", "1h", 2)
+	assert.Error(t, err)
+}
+
+func TestLoadAnnualizedVolatility(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := pgxmock.NewRows([]string{"close", "open_time"})
+	for i, price := range []float64{100, 102, 100, 102, 100} {
+		rows.AddRow(price, start.AddDate(0, 0, i))
+	}
+	mock.ExpectQuery("SELECT close, open_time FROM candlesticks").
+		WithArgs("ETHUSDT", "1d", 30).
+		WillReturnRows(rows)
+
+	volatility, err := NewCalculator(mock).LoadAnnualizedVolatility(context.Background(), "ETHUSDT", "1d", 30)
+	require.NoError(t, err)
+	returns := []float64{0.02, -2.0 / 102, 0.02, -2.0 / 102}
+	assert.InDelta(t, calculateStdDev(returns)*math.Sqrt(365), volatility, 1e-9)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,438 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ScenarioKind classifies stress scenarios
+type ScenarioKind string
+
+const (
+	// ScenarioKindShock applies user-defined price shocks
+	ScenarioKindShock ScenarioKind = "shock"
+	// ScenarioKindHistorical replays the price moves of a past market event
+	ScenarioKindHistorical ScenarioKind = "historical"
+	// ScenarioKindCorrelation shocks a driver asset and moves every other asset
+	// by its beta to the driver under stressed correlation
+	ScenarioKindCorrelation ScenarioKind = "correlation"
+)
+
+// quoteAssets are stripped from symbols to find the base asset, longest first
+var quoteAssets = []string{"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "USD", "EUR"}
+
+// StressScenario describes a market move. Shocks are fractional price changes
+// (-0.3 is a 30% drop) keyed by symbol ("BTCUSDT") or base asset ("BTC").
+type StressScenario struct {
+	Name         string             `json:"name"`
+	Description  string             `json:"description,omitempty"`
+	Kind         ScenarioKind       `json:"kind"`
+	Shocks       map[string]float64 `json:"shocks,omitempty"`
+	DefaultShock float64            `json:"default_shock"` // Applied to symbols without a shock
+
+	// Historical scenarios: when candlesticks cover the window, each symbol's
+	// worst close relative to the window's first close replaces its shock
+	Start time.Time `json:"start,omitempty"`
+	End   time.Time `json:"end,omitempty"`
+
+	// Correlation scenarios
+	Driver              string               `json:"driver,omitempty"`               // Driver symbol (default BTCUSDT)
+	DriverShock         float64              `json:"driver_shock,omitempty"`         // Shock to the driver
+	StressedCorrelation float64              `json:"stressed_correlation,omitempty"` // Floor for correlations with the driver (default 0.9)
+	Returns             map[string][]float64 `json:"returns,omitempty"`              // Aligned return series by symbol, instead of candlesticks
+}
+
+// historicalScenarios are approximate peak-to-trough moves of past crypto crashes
+var historicalScenarios = []StressScenario{
+	{
+		Name:         "covid_march_2020",
+		Description:  "COVID crash, 7-13 March 2020: BTC -50%, ETH -60%, alts -60%",
+		Kind:         ScenarioKindHistorical,
+		Shocks:       map[string]float64{"BTC": -0.50, "ETH": -0.60},
+		DefaultShock: -0.60,
+		Start:        time.Date(2020, 3, 7, 0, 0, 0, 0, time.UTC),
+		End:          time.Date(2020, 3, 13, 23, 59, 59, 0, time.UTC),
+	},
+	{
+		Name:         "may_2021_crash",
+		Description:  "China mining ban crash, 12-19 May 2021: BTC -45%, ETH -55%, alts -60%",
+		Kind:         ScenarioKindHistorical,
+		Shocks:       map[string]float64{"BTC": -0.45, "ETH": -0.55},
+		DefaultShock: -0.60,
+		Start:        time.Date(2021, 5, 12, 0, 0, 0, 0, time.UTC),
+		End:          time.Date(2021, 5, 19, 23, 59, 59, 0, time.UTC),
+	},
+	{
+		Name:         "ftx_collapse_2022",
+		Description:  "FTX collapse, 6-9 November 2022: BTC -26%, ETH -32%, SOL -65%, FTT -90%, alts -35%",
+		Kind:         ScenarioKindHistorical,
+		Shocks:       map[string]float64{"BTC": -0.26, "ETH": -0.32, "SOL": -0.65, "FTT": -0.90},
+		DefaultShock: -0.35,
+		Start:        time.Date(2022, 11, 6, 0, 0, 0, 0, time.UTC),
+		End:          time.Date(2022, 11, 9, 23, 59, 59, 0, time.UTC),
+	},
+}
+
+// HistoricalScenarios returns the built-in historical replay scenarios
+func HistoricalScenarios() []StressScenario {
+	scenarios := make([]StressScenario, len(historicalScenarios))
+	copy(scenarios, historicalScenarios)
+	return scenarios
+}
+
+// HistoricalScenario returns a built-in historical scenario by name
+func HistoricalScenario(name string) (StressScenario, bool) {
+	for _, scenario := range historicalScenarios {
+		if scenario.Name == name {
+			return scenario, true
+		}
+	}
+	return StressScenario{}, false
+}
+
+// StressPosition is an open position to stress. Price is the current mark;
+// EntryPrice is used when it is unknown.
+type StressPosition struct {
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"` // LONG or SHORT
+	Quantity   float64 `json:"quantity"`
+	EntryPrice float64 `json:"entry_price"`
+	Price      float64 `json:"price,omitempty"`
+}
+
+// markPrice is the current price, falling back to the entry price
+func (p StressPosition) markPrice() float64 {
+	if p.Price > 0 {
+		return p.Price
+	}
+	return p.EntryPrice
+}
+
+// isShort reports whether the position profits from falling prices
+func (p StressPosition) isShort() bool {
+	return strings.EqualFold(p.Side, "SHORT") || strings.EqualFold(p.Side, "SELL")
+}
+
+// PositionImpact is the stressed P&L of one position
+type PositionImpact struct {
+	Symbol        string  `json:"symbol"`
+	Side          string  `json:"side"`
+	Value         float64 `json:"value"`
+	Price         float64 `json:"price"`
+	Shock         float64 `json:"shock"`
+	StressedPrice float64 `json:"stressed_price"`
+	PnL           float64 `json:"pnl"`
+}
+
+// StressResult is the P&L impact of a scenario on a set of positions
+type StressResult struct {
+	Scenario       string           `json:"scenario"`
+	Kind           ScenarioKind     `json:"kind"`
+	Description    string           `json:"description,omitempty"`
+	PortfolioValue float64          `json:"portfolio_value"` // Gross value of the positions
+	PnL            float64          `json:"pnl"`
+	PnLPercent     float64          `json:"pnl_percent"` // Of portfolio value
+	WorstSymbol    string           `json:"worst_symbol,omitempty"`
+	Positions      []PositionImpact `json:"positions"`
+}
+
+// StressTester runs stress scenarios against positions. The calculator loads
+// candlesticks for historical replays and correlation scenarios; without a
+// database historical scenarios use their built-in shocks.
+type StressTester struct {
+	calculator *Calculator
+}
+
+// NewStressTester creates a stress tester; calculator may be nil
+func NewStressTester(calculator *Calculator) *StressTester {
+	return &StressTester{calculator: calculator}
+}
+
+// Run applies one scenario to positions
+func (t *StressTester) Run(ctx context.Context, positions []StressPosition, scenario StressScenario) (*StressResult, error) {
+	if scenario.Name == "" {
+		return nil, fmt.Errorf("scenario name is required")
+	}
+
+	shocks := scenario.Shocks
+	switch scenario.Kind {
+	case "", ScenarioKindShock:
+		scenario.Kind = ScenarioKindShock
+	case ScenarioKindHistorical:
+		shocks = t.replayShocks(ctx, positions, scenario)
+	case ScenarioKindCorrelation:
+		var err error
+		if shocks, err = t.correlationShocks(ctx, positions, scenario); err != nil {
+			return nil, fmt.Errorf("scenario %s: %w", scenario.Name, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported scenario kind: %s", scenario.Kind)
+	}
+
+	result := &StressResult{
+		Scenario:    scenario.Name,
+		Kind:        scenario.Kind,
+		Description: scenario.Description,
+		Positions:   make([]PositionImpact, 0, len(positions)),
+	}
+
+	worstPnL := 0.0
+	for _, position := range positions {
+		price := position.markPrice()
+		value := position.Quantity * price
+		shock := math.Max(shockFor(shocks, scenario.DefaultShock, position.Symbol), -1)
+
+		pnl := value * shock
+		if position.isShort() {
+			pnl = -pnl
+		}
+
+		result.Positions = append(result.Positions, PositionImpact{
+			Symbol:        position.Symbol,
+			Side:          position.Side,
+			Value:         value,
+			Price:         price,
+			Shock:         shock,
+			StressedPrice: price * (1 + shock),
+			PnL:           pnl,
+		})
+		result.PortfolioValue += math.Abs(value)
+		result.PnL += pnl
+		if pnl < worstPnL {
+			worstPnL = pnl
+			result.WorstSymbol = position.Symbol
+		}
+	}
+	if result.PortfolioValue > 0 {
+		result.PnLPercent = result.PnL / result.PortfolioValue * 100
+	}
+
+	log.Debug().
+		Str("scenario", scenario.Name).
+		Str("kind", string(scenario.Kind)).
+		Int("positions", len(positions)).
+		Float64("pnl", result.PnL).
+		Msg("Stress scenario applied")
+
+	return result, nil
+}
+
+// RunAll applies each scenario to positions
+func (t *StressTester) RunAll(ctx context.Context, positions []StressPosition, scenarios []StressScenario) ([]*StressResult, error) {
+	results := make([]*StressResult, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		result, err := t.Run(ctx, positions, scenario)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// replayShocks overrides a historical scenario's shocks with the moves found in
+// candlesticks, symbol by symbol
+func (t *StressTester) replayShocks(ctx context.Context, positions []StressPosition, scenario StressScenario) map[string]float64 {
+	shocks := make(map[string]float64, len(scenario.Shocks)+len(positions))
+	for key, shock := range scenario.Shocks {
+		shocks[key] = shock
+	}
+	if t.calculator == nil || scenario.Start.IsZero() || scenario.End.IsZero() {
+		return shocks
+	}
+
+	for _, position := range positions {
+		move, err := t.calculator.LoadWorstPriceMove(ctx, position.Symbol, "1h", scenario.Start, scenario.End)
+		if err != nil {
+			log.Debug().Err(err).Str("symbol", position.Symbol).Str("scenario", scenario.Name).
+				Msg("No candlesticks for historical scenario, using built-in shock")
+			continue
+		}
+		shocks[strings.ToUpper(position.Symbol)] = move
+	}
+	return shocks
+}
+
+// correlationShocks moves every symbol by its beta to the driver, with
+// correlations to the driver floored at the stressed correlation (hedges fail
+// and diversification disappears in a crash)
+func (t *StressTester) correlationShocks(ctx context.Context, positions []StressPosition, scenario StressScenario) (map[string]float64, error) {
+	driver := scenario.Driver
+	if driver == "" {
+		driver = "BTCUSDT"
+	}
+	stressed := scenario.StressedCorrelation
+	if stressed == 0 {
+		stressed = 0.9
+	}
+
+	// Reuse a held symbol's naming for the driver, e.g. BTC/USDT
+	symbols := []string{driver}
+	for _, position := range positions {
+		if baseAsset(position.Symbol) == baseAsset(driver) {
+			symbols[0] = position.Symbol
+			continue
+		}
+		if !containsFold(symbols, position.Symbol) {
+			symbols = append(symbols, position.Symbol)
+		}
+	}
+
+	if len(scenario.Returns) > 0 {
+		if _, ok := scenario.Returns[driver]; !ok {
+			return nil, fmt.Errorf("no returns for driver %s", driver)
+		}
+		return CorrelationStressShocks(driver, scenario.DriverShock, scenario.Returns, stressed)
+	}
+	if t.calculator == nil {
+		return nil, fmt.Errorf("correlation scenarios need returns or candlesticks")
+	}
+
+	returns := make(map[string][]float64, len(symbols))
+	if len(symbols) > 1 {
+		portfolioPositions := make([]PortfolioPosition, len(symbols))
+		for i, symbol := range symbols {
+			portfolioPositions[i] = PortfolioPosition{Symbol: symbol}
+		}
+		portfolio, err := t.calculator.LoadPortfolioReturns(ctx, portfolioPositions, "1d", 90)
+		if err != nil {
+			return nil, err
+		}
+		for _, position := range portfolio.Positions {
+			returns[position.Symbol] = position.Returns
+		}
+	}
+
+	return CorrelationStressShocks(symbols[0], scenario.DriverShock, returns, stressed)
+}
+
+// CorrelationStressShocks computes each symbol's shock as its beta to the driver
+// times the driver's shock, with the correlation to the driver floored at
+// stressedCorrelation. returns holds aligned return series by symbol.
+func CorrelationStressShocks(driver string, driverShock float64, returns map[string][]float64, stressedCorrelation float64) (map[string]float64, error) {
+	shocks := map[string]float64{strings.ToUpper(driver): driverShock}
+	if len(returns) < 2 {
+		return shocks, nil
+	}
+
+	driverReturns, ok := returns[driver]
+	if !ok {
+		return nil, fmt.Errorf("no returns for driver %s", driver)
+	}
+	driverVol := calculateStdDev(driverReturns)
+	if driverVol == 0 {
+		return nil, fmt.Errorf("driver %s has no volatility", driver)
+	}
+
+	for symbol, symbolReturns := range returns {
+		if symbol == driver {
+			continue
+		}
+		matrix, err := NewCorrelationMatrix([]string{driver, symbol}, [][]float64{driverReturns, symbolReturns},
+			CorrelationOptions{Window: len(driverReturns)})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", symbol, err)
+		}
+		correlation := math.Max(matrix.Matrix[0][1], stressedCorrelation)
+		beta := correlation * calculateStdDev(symbolReturns) / driverVol
+		shocks[strings.ToUpper(symbol)] = beta * driverShock
+	}
+	return shocks, nil
+}
+
+// shockFor finds the shock of a symbol: by symbol, then by base asset, then the default
+func shockFor(shocks map[string]float64, defaultShock float64, symbol string) float64 {
+	for key, shock := range shocks {
+		if strings.EqualFold(key, symbol) {
+			return shock
+		}
+	}
+	base := baseAsset(symbol)
+	for key, shock := range shocks {
+		if strings.EqualFold(key, base) {
+			return shock
+		}
+	}
+	return defaultShock
+}
+
+// baseAsset strips separators and the quote asset: BTC/USDT, BTC-USDT and BTCUSDT are BTC
+func baseAsset(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	if i := strings.IndexAny(symbol, "/-_"); i > 0 {
+		return symbol[:i]
+	}
+	for _, quote := range quoteAssets {
+		if base, ok := strings.CutSuffix(symbol, quote); ok && base != "" {
+			return base
+		}
+	}
+	return symbol
+}
+
+// containsFold reports whether symbols contains symbol, ignoring case
+func containsFold(symbols []string, symbol string) bool {
+	for _, s := range symbols {
+		if strings.EqualFold(s, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadWorstPriceMove returns the largest drop (or smallest gain) of a symbol's
+// close within a window, relative to the window's first close
+func (c *Calculator) LoadWorstPriceMove(ctx context.Context, symbol string, interval string, start, end time.Time) (float64, error) {
+	// Validate symbol to prevent SQL injection
+	if !isValidSymbol(symbol) {
+		return 0, fmt.Errorf("invalid symbol format: %s", symbol)
+	}
+
+	// Return error if no pool available
+	if c.pool == nil {
+		return 0, fmt.Errorf("no database pool available")
+	}
+
+	query := `
+		SELECT close
+		FROM candlesticks
+		WHERE symbol = $1
+			AND interval = $2
+			AND open_time >= $3
+			AND open_time <= $4
+		ORDER BY open_time ASC
+	`
+
+	rows, err := c.pool.Query(ctx, query, symbol, interval, start, end)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query prices: %w", err)
+	}
+	defer rows.Close()
+
+	var first, worst float64
+	count := 0
+	for rows.Next() {
+		var price float64
+		if err := rows.Scan(&price); err != nil {
+			return 0, fmt.Errorf("failed to scan price row: %w", err)
+		}
+		if count == 0 {
+			first, worst = price, price
+		}
+		worst = math.Min(worst, price)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating price rows: %w", err)
+	}
+
+	if count < 2 || first <= 0 {
+		return 0, fmt.Errorf("not enough prices for %s between %s and %s", symbol, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return worst/first - 1, nil
+}
@@ -0,0 +1,156 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStressPositions() []StressPosition {
+	return []StressPosition{
+		{Symbol: "BTCUSDT", Side: "LONG", Quantity: 0.5, EntryPrice: 40000, Price: 50000}, // $25,000
+		{Symbol: "ETH/USDT", Side: "LONG", Quantity: 5, EntryPrice: 2000},                 // $10,000
+		{Symbol: "SOLUSDT", Side: "SHORT", Quantity: 100, EntryPrice: 50},                 // $5,000
+	}
+}
+
+func TestStressTester_ShockScenario(t *testing.T) {
+	tester := NewStressTester(nil)
+	scenario := StressScenario{
+		Name:         "btc_crash",
+		Shocks:       map[string]float64{"BTC": -0.30},
+		DefaultShock: -0.45,
+	}
+
+	result, err := tester.Run(context.Background(), testStressPositions(), scenario)
+	require.NoError(t, err)
+
+	assert.Equal(t, ScenarioKindShock, result.Kind)
+	assert.InDelta(t, 40000, result.PortfolioValue, 1e-9)
+	require.Len(t, result.Positions, 3)
+	assert.InDelta(t, -7500, result.Positions[0].PnL, 1e-9)
+	assert.InDelta(t, 35000, result.Positions[0].StressedPrice, 1e-9)
+	assert.InDelta(t, -4500, result.Positions[1].PnL, 1e-9) // Alt shock, priced at entry
+	assert.InDelta(t, 2250, result.Positions[2].PnL, 1e-9)  // Shorts gain
+	assert.InDelta(t, -9750, result.PnL, 1e-9)
+	assert.InDelta(t, -24.375, result.PnLPercent, 1e-9)
+	assert.Equal(t, "BTCUSDT", result.WorstSymbol)
+}
+
+func TestStressTester_ShocksAreCappedAtTotalLoss(t *testing.T) {
+	result, err := NewStressTester(nil).Run(context.Background(), testStressPositions()[:1],
+		StressScenario{Name: "wipeout", DefaultShock: -1.5})
+	require.NoError(t, err)
+	assert.Equal(t, -1.0, result.Positions[0].Shock)
+	assert.InDelta(t, -25000, result.PnL, 1e-9)
+}
+
+func TestStressTester_HistoricalScenarios(t *testing.T) {
+	tester := NewStressTester(nil)
+	results, err := tester.RunAll(context.Background(), testStressPositions(), HistoricalScenarios())
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// Without candlesticks the built-in FTX moves apply: BTC -26%, ETH -32%, SOL -65%
+	ftx := results[2]
+	assert.Equal(t, "ftx_collapse_2022", ftx.Scenario)
+	assert.InDelta(t, -6500, ftx.Positions[0].PnL, 1e-9)
+	assert.InDelta(t, -3200, ftx.Positions[1].PnL, 1e-9)
+	assert.InDelta(t, 3250, ftx.Positions[2].PnL, 1e-9)
+
+	_, ok := HistoricalScenario("may_2021_crash")
+	assert.True(t, ok)
+	_, ok = HistoricalScenario("tulip_mania")
+	assert.False(t, ok)
+}
+
+func TestStressTester_HistoricalReplayFromCandlesticks(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	scenario, _ := HistoricalScenario("ftx_collapse_2022")
+	rows := pgxmock.NewRows([]string{"close"}).AddRow(20000.0).AddRow(18000.0).AddRow(15000.0).AddRow(16000.0)
+	mock.ExpectQuery("SELECT close FROM candlesticks").
+		WithArgs("BTCUSDT", "1h", scenario.Start, scenario.End).
+		WillReturnRows(rows)
+
+	tester := NewStressTester(NewCalculator(mock))
+	result, err := tester.Run(context.Background(), testStressPositions()[:1], scenario)
+	require.NoError(t, err)
+
+	// Realized worst move of -25% replaces the built-in -26%
+	assert.InDelta(t, -0.25, result.Positions[0].Shock, 1e-9)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStressTester_CorrelationScenario(t *testing.T) {
+	btc := []float64{0.01, -0.02, 0.03, -0.01, 0.02, -0.03}
+	eth := []float64{0.02, -0.04, 0.06, -0.02, 0.04, -0.06} // Twice BTC's moves
+	hedge := []float64{-0.01, 0.02, -0.03, 0.01, -0.02, 0.03}
+
+	scenario := StressScenario{
+		Name:        "btc_led_selloff",
+		Kind:        ScenarioKindCorrelation,
+		DriverShock: -0.20,
+		Returns:     map[string][]float64{"BTCUSDT": btc, "ETHUSDT": eth, "HEDGEUSDT": hedge},
+	}
+	positions := []StressPosition{
+		{Symbol: "BTCUSDT", Quantity: 1, Price: 1000},
+		{Symbol: "ETHUSDT", Quantity: 1, Price: 1000},
+		{Symbol: "HEDGEUSDT", Quantity: 1, Price: 1000},
+	}
+
+	result, err := NewStressTester(nil).Run(context.Background(), positions, scenario)
+	require.NoError(t, err)
+
+	assert.InDelta(t, -0.20, result.Positions[0].Shock, 1e-9)
+	assert.InDelta(t, -0.40, result.Positions[1].Shock, 1e-9)
+	// The hedge's -1 correlation is floored at the stressed 0.9
+	assert.InDelta(t, -0.18, result.Positions[2].Shock, 1e-9)
+
+	// Correlation scenarios need returns from somewhere
+	scenario.Returns = nil
+	_, err = NewStressTester(nil).Run(context.Background(), positions, scenario)
+	assert.Error(t, err)
+}
+
+func TestStressTester_InvalidScenario(t *testing.T) {
+	tester := NewStressTester(nil)
+	_, err := tester.Run(context.Background(), testStressPositions(), StressScenario{})
+	assert.Error(t, err)
+	_, err = tester.Run(context.Background(), testStressPositions(), StressScenario{Name: "x", Kind: "bogus"})
+	assert.Error(t, err)
+}
+
+func TestLoadWorstPriceMove(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	start := time.Date(2021, 5, 12, 0, 0, 0, 0, time.UTC)
+	end := start.Add(7 * 24 * time.Hour)
+	mock.ExpectQuery("SELECT close FROM candlesticks").
+		WithArgs("ETHUSDT", "1h", start, end).
+		WillReturnRows(pgxmock.NewRows([]string{"close"}).AddRow(4000.0))
+
+	calculator := NewCalculator(mock)
+	_, err = calculator.LoadWorstPriceMove(context.Background(), "ETHUSDT", "1h", start, end)
+	assert.Error(t, err, "one candle is not a move")
+
+	_, err = calculator.LoadWorstPriceMove(context.Background(), "ETH'; DROP", "1h", start, end)
+	assert.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBaseAsset(t *testing.T) {
+	assert.Equal(t, "BTC", baseAsset("BTCUSDT"))
+	assert.Equal(t, "BTC", baseAsset("btc/usdt"))
+	assert.Equal(t, "ETH", baseAsset("ETH-USD"))
+	assert.Equal(t, "SOL", baseAsset("SOLFDUSD"))
+	assert.Equal(t, "USDT", baseAsset("USDT"))
+}
@@ -0,0 +1,277 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// DefaultTradingBreakerCooldown is how long trade-rate and volatility trips last
+// before the breaker may reset on its own
+const DefaultTradingBreakerCooldown = time.Hour
+
+// TripReason identifies the threshold that tripped the trading breaker
+type TripReason string
+
+const (
+	TripReasonTradeRate   TripReason = "trade_rate"
+	TripReasonDailyLosses TripReason = "daily_losses"
+	TripReasonVolatility  TripReason = "volatility"
+	TripReasonDrawdown    TripReason = "drawdown"
+)
+
+// TradingBreakerLimits are the thresholds of a strategy's circuit breakers.
+// Zero disables a threshold.
+type TradingBreakerLimits struct {
+	Enabled             bool
+	MaxTradesPerHour    int
+	MaxLossesPerDay     int
+	VolatilityThreshold float64 // Realized daily volatility of any traded symbol
+	DrawdownHalt        float64 // Fractional drawdown from the session equity peak
+}
+
+// TradingActivity is a snapshot of recent trading
+type TradingActivity struct {
+	TradesLastHour int
+	LossesToday    int     // Losing trades closed since midnight UTC
+	Volatility     float64 // Highest realized daily volatility of the traded symbols
+	Equity         float64 // Equity of the active sessions
+}
+
+// TradingBreakerTrip describes why the breaker tripped and when it may reset
+type TradingBreakerTrip struct {
+	Reason    TripReason `json:"reason"`
+	Value     float64    `json:"value"`
+	Limit     float64    `json:"limit"`
+	Message   string     `json:"message"`
+	TrippedAt time.Time  `json:"tripped_at"`
+	ResetAt   *time.Time `json:"reset_at,omitempty"` // Nil when only a manual reset releases the breaker
+}
+
+// TradingBreaker halts trading when a strategy's circuit breaker thresholds are
+// crossed. Trade-rate and volatility trips reset after a cooldown once the
+// condition has cleared, daily-loss trips reset at midnight UTC, and drawdown
+// trips need a manual reset.
+type TradingBreaker struct {
+	mu            sync.Mutex
+	cooldown      time.Duration
+	trip          *TradingBreakerTrip
+	peakEquity    float64
+	suppressUntil time.Time // After a manual reset only drawdown may trip until then
+	metrics       *tradingBreakerMetrics
+}
+
+// NewTradingBreaker creates a trading breaker; cooldown defaults to an hour
+func NewTradingBreaker(cooldown time.Duration) *TradingBreaker {
+	if cooldown <= 0 {
+		cooldown = DefaultTradingBreakerCooldown
+	}
+	return &TradingBreaker{
+		cooldown: cooldown,
+		metrics:  getTradingBreakerMetrics(),
+	}
+}
+
+// Evaluate checks activity against limits. It returns the trip when the breaker
+// trips and the released trip when it resets; both are nil otherwise.
+func (b *TradingBreaker) Evaluate(now time.Time, limits TradingBreakerLimits, activity TradingActivity) (tripped, reset *TradingBreakerTrip) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if activity.Equity > b.peakEquity {
+		b.peakEquity = activity.Equity
+	}
+	drawdown := 0.0
+	if b.peakEquity > 0 && activity.Equity > 0 {
+		drawdown = (b.peakEquity - activity.Equity) / b.peakEquity
+	}
+	b.metrics.record(activity, drawdown)
+
+	if !limits.Enabled {
+		// Disabling the breakers releases a tripped breaker
+		return nil, b.releaseLocked()
+	}
+
+	if b.trip != nil {
+		if b.canResetLocked(now, limits, activity) {
+			return nil, b.releaseLocked()
+		}
+		return nil, nil
+	}
+
+	if now.Before(b.suppressUntil) {
+		limits = TradingBreakerLimits{Enabled: true, DrawdownHalt: limits.DrawdownHalt}
+	}
+	if trip := b.checkLocked(now, limits, activity, drawdown); trip != nil {
+		b.trip = trip
+		b.metrics.tripped.Set(1)
+		b.metrics.trips.WithLabelValues(string(trip.Reason)).Inc()
+		return trip, nil
+	}
+	return nil, nil
+}
+
+// Reset releases the breaker, e.g. when an operator resumes trading. The
+// drawdown peak restarts from the next equity observation, and the other
+// thresholds cannot trip again for one cooldown.
+func (b *TradingBreaker) Reset(now time.Time) *TradingBreakerTrip {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.peakEquity = 0
+	b.suppressUntil = now.Add(b.cooldown)
+	return b.releaseLocked()
+}
+
+// Trip returns the active trip, or nil when trading is allowed
+func (b *TradingBreaker) Trip() *TradingBreakerTrip {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.trip == nil {
+		return nil
+	}
+	trip := *b.trip
+	return &trip
+}
+
+// checkLocked returns the first crossed threshold, most severe first
+func (b *TradingBreaker) checkLocked(now time.Time, limits TradingBreakerLimits, activity TradingActivity, drawdown float64) *TradingBreakerTrip {
+	switch {
+	case limits.DrawdownHalt > 0 && drawdown >= limits.DrawdownHalt:
+		return &TradingBreakerTrip{
+			Reason:    TripReasonDrawdown,
+			Value:     drawdown,
+			Limit:     limits.DrawdownHalt,
+			Message:   fmt.Sprintf("drawdown %.2f%% reached halt level %.2f%%", drawdown*100, limits.DrawdownHalt*100),
+			TrippedAt: now,
+		}
+	case limits.MaxLossesPerDay > 0 && activity.LossesToday >= limits.MaxLossesPerDay:
+		resetAt := nextUTCMidnight(now)
+		return &TradingBreakerTrip{
+			Reason:    TripReasonDailyLosses,
+			Value:     float64(activity.LossesToday),
+			Limit:     float64(limits.MaxLossesPerDay),
+			Message:   fmt.Sprintf("%d losing trades today (max %d)", activity.LossesToday, limits.MaxLossesPerDay),
+			TrippedAt: now,
+			ResetAt:   &resetAt,
+		}
+	case limits.MaxTradesPerHour > 0 && activity.TradesLastHour >= limits.MaxTradesPerHour:
+		resetAt := now.Add(b.cooldown)
+		return &TradingBreakerTrip{
+			Reason:    TripReasonTradeRate,
+			Value:     float64(activity.TradesLastHour),
+			Limit:     float64(limits.MaxTradesPerHour),
+			Message:   fmt.Sprintf("%d trades in the last hour (max %d)", activity.TradesLastHour, limits.MaxTradesPerHour),
+			TrippedAt: now,
+			ResetAt:   &resetAt,
+		}
+	case limits.VolatilityThreshold > 0 && activity.Volatility > limits.VolatilityThreshold:
+		resetAt := now.Add(b.cooldown)
+		return &TradingBreakerTrip{
+			Reason:    TripReasonVolatility,
+			Value:     activity.Volatility,
+			Limit:     limits.VolatilityThreshold,
+			Message:   fmt.Sprintf("realized volatility %.2f%% above threshold %.2f%%", activity.Volatility*100, limits.VolatilityThreshold*100),
+			TrippedAt: now,
+			ResetAt:   &resetAt,
+		}
+	}
+	return nil
+}
+
+// canResetLocked applies the auto-reset policy of the active trip
+func (b *TradingBreaker) canResetLocked(now time.Time, limits TradingBreakerLimits, activity TradingActivity) bool {
+	if b.trip.ResetAt == nil || now.Before(*b.trip.ResetAt) {
+		return false
+	}
+	switch b.trip.Reason {
+	case TripReasonTradeRate:
+		return limits.MaxTradesPerHour <= 0 || activity.TradesLastHour < limits.MaxTradesPerHour
+	case TripReasonVolatility:
+		return limits.VolatilityThreshold <= 0 || activity.Volatility <= limits.VolatilityThreshold
+	default:
+		return true
+	}
+}
+
+// releaseLocked clears the active trip and returns it
+func (b *TradingBreaker) releaseLocked() *TradingBreakerTrip {
+	trip := b.trip
+	b.trip = nil
+	b.metrics.tripped.Set(0)
+	return trip
+}
+
+// nextUTCMidnight returns the start of the next UTC day
+func nextUTCMidnight(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+// RealizedDailyVolatility scales the standard deviation of returns sampled
+// periodsPerDay times a day to a daily volatility
+func RealizedDailyVolatility(returns []float64, periodsPerDay float64) float64 {
+	if len(returns) < 2 || periodsPerDay <= 0 {
+		return 0
+	}
+	return calculateStdDev(returns) * math.Sqrt(periodsPerDay)
+}
+
+// tradingBreakerMetrics holds Prometheus metrics for the trading breaker
+type tradingBreakerMetrics struct {
+	tripped        prometheus.Gauge
+	trips          *prometheus.CounterVec
+	tradesLastHour prometheus.Gauge
+	lossesToday    prometheus.Gauge
+	volatility     prometheus.Gauge
+	drawdown       prometheus.Gauge
+}
+
+var (
+	tradingBreakerMetricsInstance *tradingBreakerMetrics
+	tradingBreakerMetricsOnce     sync.Once
+)
+
+// getTradingBreakerMetrics registers the trading breaker metrics exactly once
+func getTradingBreakerMetrics() *tradingBreakerMetrics {
+	tradingBreakerMetricsOnce.Do(func() {
+		tradingBreakerMetricsInstance = &tradingBreakerMetrics{
+			tripped: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "trading_circuit_breaker_tripped",
+				Help: "Whether the trading circuit breaker has halted trading (0=no, 1=yes)",
+			}),
+			trips: promauto.NewCounterVec(prometheus.CounterOpts{
+				Name: "trading_circuit_breaker_trips_total",
+				Help: "Total trading circuit breaker trips by reason",
+			}, []string{"reason"}),
+			tradesLastHour: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "trading_circuit_breaker_trades_last_hour",
+				Help: "Trades executed in the last hour",
+			}),
+			lossesToday: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "trading_circuit_breaker_losses_today",
+				Help: "Losing trades closed since midnight UTC",
+			}),
+			volatility: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "trading_circuit_breaker_volatility",
+				Help: "Highest realized daily volatility of the traded symbols",
+			}),
+			drawdown: promauto.NewGauge(prometheus.GaugeOpts{
+				Name: "trading_circuit_breaker_drawdown",
+				Help: "Drawdown of the active sessions from their equity peak",
+			}),
+		}
+	})
+	return tradingBreakerMetricsInstance
+}
+
+// record publishes the observed activity
+func (m *tradingBreakerMetrics) record(activity TradingActivity, drawdown float64) {
+	m.tradesLastHour.Set(float64(activity.TradesLastHour))
+	m.lossesToday.Set(float64(activity.LossesToday))
+	m.volatility.Set(activity.Volatility)
+	m.drawdown.Set(drawdown)
+}
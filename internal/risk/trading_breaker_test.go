@@ -0,0 +1,134 @@
+package risk
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBreakerLimits() TradingBreakerLimits {
+	return TradingBreakerLimits{
+		Enabled:             true,
+		MaxTradesPerHour:    10,
+		MaxLossesPerDay:     3,
+		VolatilityThreshold: 0.05,
+		DrawdownHalt:        0.08,
+	}
+}
+
+func TestTradingBreaker_TradeRateTripsAndResetsAfterCooldown(t *testing.T) {
+	breaker := NewTradingBreaker(time.Hour)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tripped, reset := breaker.Evaluate(now, testBreakerLimits(), TradingActivity{TradesLastHour: 9, Equity: 10000})
+	assert.Nil(t, tripped)
+	assert.Nil(t, reset)
+
+	tripped, _ = breaker.Evaluate(now, testBreakerLimits(), TradingActivity{TradesLastHour: 10, Equity: 10000})
+	require.NotNil(t, tripped)
+	assert.Equal(t, TripReasonTradeRate, tripped.Reason)
+	require.NotNil(t, tripped.ResetAt)
+	assert.Equal(t, now.Add(time.Hour), *tripped.ResetAt)
+	assert.NotNil(t, breaker.Trip())
+
+	// Still within the cooldown
+	_, reset = breaker.Evaluate(now.Add(30*time.Minute), testBreakerLimits(), TradingActivity{Equity: 10000})
+	assert.Nil(t, reset)
+
+	// Cooldown over but still trading too fast
+	_, reset = breaker.Evaluate(now.Add(time.Hour), testBreakerLimits(), TradingActivity{TradesLastHour: 12, Equity: 10000})
+	assert.Nil(t, reset)
+
+	_, reset = breaker.Evaluate(now.Add(61*time.Minute), testBreakerLimits(), TradingActivity{TradesLastHour: 2, Equity: 10000})
+	require.NotNil(t, reset)
+	assert.Equal(t, TripReasonTradeRate, reset.Reason)
+	assert.Nil(t, breaker.Trip())
+}
+
+func TestTradingBreaker_DailyLossesResetAtMidnight(t *testing.T) {
+	breaker := NewTradingBreaker(time.Hour)
+	now := time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC)
+
+	tripped, _ := breaker.Evaluate(now, testBreakerLimits(), TradingActivity{LossesToday: 3})
+	require.NotNil(t, tripped)
+	assert.Equal(t, TripReasonDailyLosses, tripped.Reason)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), *tripped.ResetAt)
+
+	_, reset := breaker.Evaluate(now.Add(8*time.Hour), testBreakerLimits(), TradingActivity{LossesToday: 3})
+	assert.Nil(t, reset)
+	_, reset = breaker.Evaluate(now.Add(9*time.Hour), testBreakerLimits(), TradingActivity{})
+	assert.NotNil(t, reset)
+}
+
+func TestTradingBreaker_DrawdownNeedsManualReset(t *testing.T) {
+	breaker := NewTradingBreaker(time.Hour)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	breaker.Evaluate(now, testBreakerLimits(), TradingActivity{Equity: 10000})
+	tripped, _ := breaker.Evaluate(now.Add(time.Minute), testBreakerLimits(), TradingActivity{Equity: 10500})
+	assert.Nil(t, tripped)
+
+	// 8% below the 10,500 peak
+	tripped, _ = breaker.Evaluate(now.Add(2*time.Minute), testBreakerLimits(), TradingActivity{Equity: 9660})
+	require.NotNil(t, tripped)
+	assert.Equal(t, TripReasonDrawdown, tripped.Reason)
+	assert.InDelta(t, 0.08, tripped.Value, 1e-9)
+	assert.Nil(t, tripped.ResetAt)
+
+	// Recovery does not release a drawdown halt
+	_, reset := breaker.Evaluate(now.Add(48*time.Hour), testBreakerLimits(), TradingActivity{Equity: 11000})
+	assert.Nil(t, reset)
+
+	released := breaker.Reset(now.Add(48 * time.Hour))
+	require.NotNil(t, released)
+	assert.Equal(t, TripReasonDrawdown, released.Reason)
+
+	// The peak restarts from the next observation
+	tripped, _ = breaker.Evaluate(now.Add(49*time.Hour), testBreakerLimits(), TradingActivity{Equity: 9000})
+	assert.Nil(t, tripped)
+}
+
+func TestTradingBreaker_ManualResetSuppressesRetrips(t *testing.T) {
+	breaker := NewTradingBreaker(time.Hour)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tripped, _ := breaker.Evaluate(now, testBreakerLimits(), TradingActivity{LossesToday: 4, Equity: 10000})
+	require.NotNil(t, tripped)
+	breaker.Reset(now)
+
+	// Losses still count today, but the operator's resume holds for the cooldown
+	tripped, _ = breaker.Evaluate(now.Add(time.Minute), testBreakerLimits(), TradingActivity{LossesToday: 4, Equity: 10000})
+	assert.Nil(t, tripped)
+
+	// Drawdown is still enforced
+	tripped, _ = breaker.Evaluate(now.Add(2*time.Minute), testBreakerLimits(), TradingActivity{LossesToday: 4, Equity: 9000})
+	require.NotNil(t, tripped)
+	assert.Equal(t, TripReasonDrawdown, tripped.Reason)
+}
+
+func TestTradingBreaker_VolatilityAndDisabledLimits(t *testing.T) {
+	breaker := NewTradingBreaker(0)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tripped, _ := breaker.Evaluate(now, testBreakerLimits(), TradingActivity{Volatility: 0.08})
+	require.NotNil(t, tripped)
+	assert.Equal(t, TripReasonVolatility, tripped.Reason)
+	assert.Equal(t, now.Add(DefaultTradingBreakerCooldown), *tripped.ResetAt)
+
+	// Disabling the breakers releases the trip and stops new ones
+	limits := testBreakerLimits()
+	limits.Enabled = false
+	_, reset := breaker.Evaluate(now.Add(time.Minute), limits, TradingActivity{Volatility: 0.08})
+	assert.NotNil(t, reset)
+	tripped, _ = breaker.Evaluate(now.Add(2*time.Minute), limits, TradingActivity{Volatility: 0.08, TradesLastHour: 50})
+	assert.Nil(t, tripped)
+}
+
+func TestRealizedDailyVolatility(t *testing.T) {
+	returns := []float64{0.01, -0.01, 0.01, -0.01}
+	assert.InDelta(t, calculateStdDev(returns)*math.Sqrt(24), RealizedDailyVolatility(returns, 24), 1e-12)
+	assert.Equal(t, 0.0, RealizedDailyVolatility([]float64{0.01}, 24))
+}
@@ -0,0 +1,500 @@
+package risk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// VaRMethod selects how Value at Risk is estimated
+type VaRMethod string
+
+const (
+	// VaRMethodParametric assumes normally distributed portfolio returns
+	VaRMethodParametric VaRMethod = "parametric"
+	// VaRMethodHistorical replays the observed portfolio returns
+	VaRMethodHistorical VaRMethod = "historical"
+	// VaRMethodFilteredHistorical replays returns rescaled from their EWMA
+	// volatility at the time to the current EWMA volatility
+	VaRMethodFilteredHistorical VaRMethod = "filtered_historical"
+	// VaRMethodMonteCarlo simulates correlated normal asset returns
+	VaRMethodMonteCarlo VaRMethod = "monte_carlo"
+)
+
+// VaRMethods lists every supported method
+var VaRMethods = []VaRMethod{
+	VaRMethodParametric,
+	VaRMethodHistorical,
+	VaRMethodFilteredHistorical,
+	VaRMethodMonteCarlo,
+}
+
+// ParseVaRMethod converts a method name to a VaRMethod. An empty name selects
+// historical simulation.
+func ParseVaRMethod(name string) (VaRMethod, error) {
+	if name == "" {
+		return VaRMethodHistorical, nil
+	}
+	for _, method := range VaRMethods {
+		if string(method) == name {
+			return method, nil
+		}
+	}
+	return "", fmt.Errorf("invalid VaR method %q (must be parametric, historical, filtered_historical or monte_carlo)", name)
+}
+
+// VaROptions configures the VaR engine. Zero values select the defaults.
+type VaROptions struct {
+	ConfidenceLevel float64 // 0.95
+	HorizonPeriods  int     // 1; longer horizons scale by the square root of time
+	EWMALambda      float64 // 0.94 (RiskMetrics decay for filtered historical VaR)
+	Simulations     int     // 10000 Monte Carlo scenarios
+	Seed            int64   // Monte Carlo seed; zero seeds from the clock
+}
+
+// withDefaults fills unset options
+func (o VaROptions) withDefaults() VaROptions {
+	if o.ConfidenceLevel == 0 {
+		o.ConfidenceLevel = 0.95
+	}
+	if o.HorizonPeriods <= 0 {
+		o.HorizonPeriods = 1
+	}
+	if o.EWMALambda == 0 {
+		o.EWMALambda = 0.94
+	}
+	if o.Simulations <= 0 {
+		o.Simulations = 10000
+	}
+	return o
+}
+
+// validate checks option ranges
+func (o VaROptions) validate() error {
+	if o.ConfidenceLevel <= 0 || o.ConfidenceLevel >= 1 {
+		return fmt.Errorf("confidence level must be between 0 and 1")
+	}
+	if o.EWMALambda <= 0 || o.EWMALambda >= 1 {
+		return fmt.Errorf("EWMA lambda must be between 0 and 1")
+	}
+	return nil
+}
+
+// PortfolioPosition is one position of a portfolio. Value is the position's
+// market value in the quote currency, negative for shorts; Returns are its
+// period returns, oldest first.
+type PortfolioPosition struct {
+	Symbol  string    `json:"symbol"`
+	Value   float64   `json:"value"`
+	Returns []float64 `json:"returns,omitempty"`
+}
+
+// Portfolio is a set of positions whose return series cover the same periods
+type Portfolio struct {
+	Positions []PortfolioPosition
+}
+
+// GrossValue is the sum of absolute position values
+func (p *Portfolio) GrossValue() float64 {
+	var gross float64
+	for _, position := range p.Positions {
+		gross += math.Abs(position.Value)
+	}
+	return gross
+}
+
+// Weights returns each position's signed share of the gross value
+func (p *Portfolio) Weights() []float64 {
+	gross := p.GrossValue()
+	weights := make([]float64, len(p.Positions))
+	if gross == 0 {
+		return weights
+	}
+	for i, position := range p.Positions {
+		weights[i] = position.Value / gross
+	}
+	return weights
+}
+
+// periods is the number of return periods all positions share
+func (p *Portfolio) periods() int {
+	if len(p.Positions) == 0 {
+		return 0
+	}
+	periods := len(p.Positions[0].Returns)
+	for _, position := range p.Positions[1:] {
+		periods = min(periods, len(position.Returns))
+	}
+	return periods
+}
+
+// assetReturns returns the most recent shared periods of each position's returns
+func (p *Portfolio) assetReturns() [][]float64 {
+	periods := p.periods()
+	series := make([][]float64, len(p.Positions))
+	for i, position := range p.Positions {
+		series[i] = position.Returns[len(position.Returns)-periods:]
+	}
+	return series
+}
+
+// Returns is the weighted portfolio return of each shared period
+func (p *Portfolio) Returns() []float64 {
+	weights := p.Weights()
+	series := p.assetReturns()
+	returns := make([]float64, p.periods())
+	for i, assetReturns := range series {
+		for t, r := range assetReturns {
+			returns[t] += weights[i] * r
+		}
+	}
+	return returns
+}
+
+// VaREstimate is a Value at Risk and expected shortfall (CVaR) estimate.
+// VaR and ExpectedShortfall are losses as a fraction of the gross portfolio
+// value; the amounts are in the quote currency.
+type VaREstimate struct {
+	Method                  VaRMethod `json:"method"`
+	ConfidenceLevel         float64   `json:"confidence_level"`
+	HorizonPeriods          int       `json:"horizon_periods"`
+	VaR                     float64   `json:"var"`
+	ExpectedShortfall       float64   `json:"expected_shortfall"`
+	VaRAmount               float64   `json:"var_amount"`
+	ExpectedShortfallAmount float64   `json:"expected_shortfall_amount"`
+	Observations            int       `json:"observations"`
+	Simulations             int       `json:"simulations,omitempty"`
+}
+
+// VaREngine estimates portfolio VaR and expected shortfall
+type VaREngine struct {
+	options VaROptions
+	rng     *rand.Rand
+}
+
+// NewVaREngine creates a VaR engine with the given options
+func NewVaREngine(options VaROptions) (*VaREngine, error) {
+	options = options.withDefaults()
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &VaREngine{
+		options: options,
+		rng:     rand.New(rand.NewSource(seed)), // #nosec G404 -- simulation, not security
+	}, nil
+}
+
+// Estimate computes VaR and expected shortfall of a portfolio with one method
+func (e *VaREngine) Estimate(portfolio *Portfolio, method VaRMethod) (*VaREstimate, error) {
+	if len(portfolio.Positions) == 0 {
+		return nil, fmt.Errorf("portfolio has no positions")
+	}
+	if portfolio.GrossValue() == 0 {
+		return nil, fmt.Errorf("portfolio has no exposure")
+	}
+	observations := portfolio.periods()
+	if observations < 2 {
+		return nil, fmt.Errorf("at least 2 return periods are required (got %d)", observations)
+	}
+
+	alpha := 1 - e.options.ConfidenceLevel
+	estimate := &VaREstimate{
+		Method:          method,
+		ConfidenceLevel: e.options.ConfidenceLevel,
+		HorizonPeriods:  e.options.HorizonPeriods,
+		Observations:    observations,
+	}
+
+	switch method {
+	case VaRMethodParametric:
+		estimate.VaR, estimate.ExpectedShortfall = parametricVaR(portfolio.Returns(), alpha)
+	case VaRMethodHistorical:
+		estimate.VaR, estimate.ExpectedShortfall = empiricalVaR(portfolio.Returns(), alpha)
+	case VaRMethodFilteredHistorical:
+		estimate.VaR, estimate.ExpectedShortfall = empiricalVaR(filteredReturns(portfolio.Returns(), e.options.EWMALambda), alpha)
+	case VaRMethodMonteCarlo:
+		scenarios, err := e.simulate(portfolio)
+		if err != nil {
+			return nil, err
+		}
+		estimate.VaR, estimate.ExpectedShortfall = empiricalVaR(scenarios, alpha)
+		estimate.Simulations = len(scenarios)
+	default:
+		return nil, fmt.Errorf("unsupported VaR method: %s", method)
+	}
+
+	// Square-root-of-time scaling to the horizon
+	scale := math.Sqrt(float64(e.options.HorizonPeriods))
+	estimate.VaR *= scale
+	estimate.ExpectedShortfall *= scale
+
+	gross := portfolio.GrossValue()
+	estimate.VaRAmount = estimate.VaR * gross
+	estimate.ExpectedShortfallAmount = estimate.ExpectedShortfall * gross
+
+	log.Debug().
+		Str("method", string(method)).
+		Int("positions", len(portfolio.Positions)).
+		Int("observations", observations).
+		Float64("var", estimate.VaR).
+		Float64("expected_shortfall", estimate.ExpectedShortfall).
+		Msg("Portfolio VaR estimated")
+
+	return estimate, nil
+}
+
+// EstimateAll computes VaR and expected shortfall with every method
+func (e *VaREngine) EstimateAll(portfolio *Portfolio) ([]*VaREstimate, error) {
+	estimates := make([]*VaREstimate, 0, len(VaRMethods))
+	for _, method := range VaRMethods {
+		estimate, err := e.Estimate(portfolio, method)
+		if err != nil {
+			return nil, fmt.Errorf("%s VaR: %w", method, err)
+		}
+		estimates = append(estimates, estimate)
+	}
+	return estimates, nil
+}
+
+// simulate draws portfolio returns from a multivariate normal distribution
+// with the assets' sample means and covariance
+func (e *VaREngine) simulate(portfolio *Portfolio) ([]float64, error) {
+	series := portfolio.assetReturns()
+	weights := portfolio.Weights()
+
+	means := make([]float64, len(series))
+	for i, returns := range series {
+		means[i] = mean(returns)
+	}
+	chol, err := cholesky(covariance(series, means))
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(series)
+	shocks := make([]float64, n)
+	scenarios := make([]float64, e.options.Simulations)
+	for s := range scenarios {
+		for i := range shocks {
+			shocks[i] = e.rng.NormFloat64()
+		}
+		var portfolioReturn float64
+		for i := 0; i < n; i++ {
+			assetReturn := means[i]
+			for j := 0; j <= i; j++ {
+				assetReturn += chol[i][j] * shocks[j]
+			}
+			portfolioReturn += weights[i] * assetReturn
+		}
+		scenarios[s] = portfolioReturn
+	}
+	return scenarios, nil
+}
+
+// parametricVaR is the normal VaR and expected shortfall of a return series
+func parametricVaR(returns []float64, alpha float64) (float64, float64) {
+	mu := mean(returns)
+	sigma := calculateStdDev(returns)
+	z := normalQuantile(alpha)
+	density := math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+
+	varValue := -(mu + z*sigma)
+	esValue := -(mu - sigma*density/alpha)
+	return varValue, esValue
+}
+
+// empiricalVaR is the VaR and expected shortfall of a sample of returns, using
+// the same percentile convention as CalculateVaR
+func empiricalVaR(sample []float64, alpha float64) (float64, float64) {
+	sorted := make([]float64, len(sample))
+	copy(sorted, sample)
+	sortReturns(sorted)
+
+	index := int(float64(len(sorted)) * alpha)
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+
+	var tail float64
+	for i := 0; i <= index; i++ {
+		tail += sorted[i]
+	}
+	return -sorted[index], -tail / float64(index+1)
+}
+
+// filteredReturns rescales each return from the EWMA volatility when it occurred
+// to the current EWMA volatility (filtered historical simulation)
+func filteredReturns(returns []float64, lambda float64) []float64 {
+	variance := calculateStdDev(returns)
+	variance *= variance
+
+	volatilities := make([]float64, len(returns))
+	for t, r := range returns {
+		volatilities[t] = math.Sqrt(variance)
+		variance = lambda*variance + (1-lambda)*r*r
+	}
+	current := math.Sqrt(variance)
+
+	filtered := make([]float64, len(returns))
+	for t, r := range returns {
+		if volatilities[t] > 0 {
+			filtered[t] = r / volatilities[t] * current
+		}
+	}
+	return filtered
+}
+
+// normalQuantile is the inverse of the standard normal CDF
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// mean is the arithmetic mean of a slice
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// covariance is the sample covariance matrix of equally long series
+func covariance(series [][]float64, means []float64) [][]float64 {
+	n := len(series)
+	periods := len(series[0])
+	cov := make([][]float64, n)
+	for i := range cov {
+		cov[i] = make([]float64, n)
+	}
+	if periods < 2 {
+		return cov
+	}
+
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			var sum float64
+			for t := 0; t < periods; t++ {
+				sum += (series[i][t] - means[i]) * (series[j][t] - means[j])
+			}
+			cov[i][j] = sum / float64(periods-1)
+			cov[j][i] = cov[i][j]
+		}
+	}
+	return cov
+}
+
+// cholesky returns the lower triangular L with L*Lᵀ = matrix. Perfectly
+// correlated or constant assets make the matrix singular; their pivots are
+// treated as zero.
+func cholesky(matrix [][]float64) ([][]float64, error) {
+	n := len(matrix)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+	}
+
+	const tolerance = 1e-12
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			sum := matrix[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum < -tolerance {
+					return nil, fmt.Errorf("covariance matrix is not positive semi-definite")
+				}
+				l[i][i] = math.Sqrt(math.Max(sum, 0))
+				continue
+			}
+			if l[j][j] > tolerance {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l, nil
+}
+
+// LoadPortfolioReturns fills in the return series of each position from
+// candlesticks. Returns are aligned on the candle times all symbols share.
+func (c *Calculator) LoadPortfolioReturns(ctx context.Context, positions []PortfolioPosition, interval string, days int) (*Portfolio, error) {
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("portfolio has no positions")
+	}
+
+	// Close prices by candle time, per symbol
+	closes := make([]map[time.Time]float64, len(positions))
+	var times []time.Time
+	for i, position := range positions {
+		histData, err := c.LoadHistoricalPrices(ctx, position.Symbol, interval, days)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load prices for %s: %w", position.Symbol, err)
+		}
+		closes[i] = make(map[time.Time]float64, len(histData.Prices))
+		for j, price := range histData.Prices {
+			closes[i][histData.Times[j]] = price
+		}
+		if i == 0 {
+			times = histData.Times
+		}
+	}
+
+	// Candle times every symbol has a price for
+	shared := make([]time.Time, 0, len(times))
+	for _, openTime := range times {
+		inAll := true
+		for _, symbolCloses := range closes {
+			if _, ok := symbolCloses[openTime]; !ok {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			shared = append(shared, openTime)
+		}
+	}
+	if len(shared) < 3 {
+		return nil, fmt.Errorf("not enough overlapping price history (%d candles)", len(shared))
+	}
+
+	portfolio := &Portfolio{Positions: make([]PortfolioPosition, len(positions))}
+	for i, position := range positions {
+		returns := make([]float64, 0, len(shared)-1)
+		for t := 1; t < len(shared); t++ {
+			previous := closes[i][shared[t-1]]
+			if previous <= 0 {
+				returns = append(returns, 0)
+				continue
+			}
+			returns = append(returns, (closes[i][shared[t]]-previous)/previous)
+		}
+		portfolio.Positions[i] = PortfolioPosition{Symbol: position.Symbol, Value: position.Value, Returns: returns}
+	}
+	return portfolio, nil
+}
+
+// CalculatePortfolioVaR estimates VaR and expected shortfall of positions from
+// their candlestick history
+func (c *Calculator) CalculatePortfolioVaR(ctx context.Context, positions []PortfolioPosition, interval string, days int, method VaRMethod, options VaROptions) (*VaREstimate, error) {
+	engine, err := NewVaREngine(options)
+	if err != nil {
+		return nil, err
+	}
+	portfolio, err := c.LoadPortfolioReturns(ctx, positions, interval, days)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Estimate(portfolio, method)
+}
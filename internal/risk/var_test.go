@@ -0,0 +1,208 @@
+package risk
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// normalReturns draws n returns from N(mu, sigma²) with a fixed seed
+func normalReturns(n int, mu, sigma float64, seed int64) []float64 {
+	engine, _ := NewVaREngine(VaROptions{Seed: seed})
+	returns := make([]float64, n)
+	for i := range returns {
+		returns[i] = mu + sigma*engine.rng.NormFloat64()
+	}
+	return returns
+}
+
+// TestVaREngine_MethodsAgreeOnNormalReturns checks every method recovers the
+// analytic VaR of normally distributed returns
+func TestVaREngine_MethodsAgreeOnNormalReturns(t *testing.T) {
+	returns := normalReturns(5000, 0, 0.02, 1)
+	portfolio := &Portfolio{Positions: []PortfolioPosition{{Symbol: "BTCUSDT", Value: 10000, Returns: returns}}}
+
+	engine, err := NewVaREngine(VaROptions{Seed: 42})
+	require.NoError(t, err)
+
+	estimates, err := engine.EstimateAll(portfolio)
+	require.NoError(t, err)
+	require.Len(t, estimates, len(VaRMethods))
+
+	// 95% VaR of N(0, 0.02²) is 1.645σ; expected shortfall is φ(1.645)/0.05·σ
+	for _, estimate := range estimates {
+		assert.InDelta(t, 0.0329, estimate.VaR, 0.003, estimate.Method)
+		assert.InDelta(t, 0.0413, estimate.ExpectedShortfall, 0.004, estimate.Method)
+		assert.InDelta(t, estimate.VaR*10000, estimate.VaRAmount, 1e-9, estimate.Method)
+		assert.Equal(t, 5000, estimate.Observations)
+	}
+}
+
+func TestVaREngine_Parametric(t *testing.T) {
+	returns := []float64{0.01, -0.01, 0.01, -0.01}
+	portfolio := &Portfolio{Positions: []PortfolioPosition{{Value: 1, Returns: returns}}}
+
+	engine, err := NewVaREngine(VaROptions{ConfidenceLevel: 0.99, HorizonPeriods: 4})
+	require.NoError(t, err)
+
+	estimate, err := engine.Estimate(portfolio, VaRMethodParametric)
+	require.NoError(t, err)
+
+	sigma := calculateStdDev(returns)
+	assert.InDelta(t, 2.326*sigma*2, estimate.VaR, 1e-3) // sqrt(4) horizon scaling
+	assert.Greater(t, estimate.ExpectedShortfall, estimate.VaR)
+}
+
+func TestVaREngine_HistoricalMatchesCalculateVaR(t *testing.T) {
+	returns := []float64{-0.05, 0.02, -0.03, 0.01, 0.04, -0.01, 0.03, -0.02, 0.01, 0.00}
+	portfolio := &Portfolio{Positions: []PortfolioPosition{{Value: 1, Returns: returns}}}
+
+	engine, err := NewVaREngine(VaROptions{ConfidenceLevel: 0.9})
+	require.NoError(t, err)
+
+	estimate, err := engine.Estimate(portfolio, VaRMethodHistorical)
+	require.NoError(t, err)
+
+	varValue, cvarValue, err := NewCalculator(nil).CalculateVaR(returns, 0.9)
+	require.NoError(t, err)
+	assert.InDelta(t, varValue, estimate.VaR, 1e-12)
+	assert.InDelta(t, cvarValue, estimate.ExpectedShortfall, 1e-12)
+}
+
+// TestVaREngine_FilteredHistoricalReactsToVolatility checks filtered VaR rises
+// above plain historical VaR when recent volatility exceeds the sample average
+func TestVaREngine_FilteredHistoricalReactsToVolatility(t *testing.T) {
+	returns := append(normalReturns(500, 0, 0.01, 2), normalReturns(50, 0, 0.04, 3)...)
+	portfolio := &Portfolio{Positions: []PortfolioPosition{{Value: 1, Returns: returns}}}
+
+	engine, err := NewVaREngine(VaROptions{})
+	require.NoError(t, err)
+
+	historical, err := engine.Estimate(portfolio, VaRMethodHistorical)
+	require.NoError(t, err)
+	filtered, err := engine.Estimate(portfolio, VaRMethodFilteredHistorical)
+	require.NoError(t, err)
+
+	assert.Greater(t, filtered.VaR, 2*historical.VaR)
+}
+
+func TestVaREngine_Diversification(t *testing.T) {
+	a := normalReturns(2000, 0, 0.02, 4)
+	b := normalReturns(2000, 0, 0.02, 5)
+	negated := make([]float64, len(a))
+	for i, r := range a {
+		negated[i] = -r
+	}
+
+	engine, err := NewVaREngine(VaROptions{Seed: 7})
+	require.NoError(t, err)
+
+	single, err := engine.Estimate(&Portfolio{Positions: []PortfolioPosition{{Value: 2000, Returns: a}}}, VaRMethodMonteCarlo)
+	require.NoError(t, err)
+
+	// Uncorrelated assets diversify by about 1/sqrt(2)
+	diversified, err := engine.Estimate(&Portfolio{Positions: []PortfolioPosition{
+		{Symbol: "A", Value: 1000, Returns: a},
+		{Symbol: "B", Value: 1000, Returns: b},
+	}}, VaRMethodMonteCarlo)
+	require.NoError(t, err)
+	assert.InDelta(t, single.VaR/math.Sqrt2, diversified.VaR, 0.004)
+
+	// A long and a short in the same asset hedge each other completely,
+	// including in the singular covariance Monte Carlo has to factor
+	for _, method := range VaRMethods {
+		hedged, err := engine.Estimate(&Portfolio{Positions: []PortfolioPosition{
+			{Symbol: "A", Value: 1000, Returns: a},
+			{Symbol: "A-short", Value: -1000, Returns: a},
+		}}, method)
+		require.NoError(t, err)
+		assert.InDelta(t, 0, hedged.VaR, 1e-9, method)
+	}
+
+	// Holding the negated series long is the same hedge
+	hedged, err := engine.Estimate(&Portfolio{Positions: []PortfolioPosition{
+		{Value: 1000, Returns: a},
+		{Value: 1000, Returns: negated},
+	}}, VaRMethodMonteCarlo)
+	require.NoError(t, err)
+	assert.InDelta(t, 0, hedged.VaR, 1e-9)
+}
+
+func TestPortfolio_AlignsRecentReturns(t *testing.T) {
+	portfolio := &Portfolio{Positions: []PortfolioPosition{
+		{Symbol: "BTCUSDT", Value: 3000, Returns: []float64{0.5, 0.01, 0.02}},
+		{Symbol: "ETHUSDT", Value: -1000, Returns: []float64{0.04, 0.08}},
+	}}
+
+	assert.Equal(t, 4000.0, portfolio.GrossValue())
+	assert.Equal(t, []float64{0.75, -0.25}, portfolio.Weights())
+
+	returns := portfolio.Returns()
+	require.Len(t, returns, 2)
+	assert.InDelta(t, 0.75*0.01-0.25*0.04, returns[0], 1e-12)
+	assert.InDelta(t, 0.75*0.02-0.25*0.08, returns[1], 1e-12)
+}
+
+func TestVaREngine_InvalidInput(t *testing.T) {
+	_, err := NewVaREngine(VaROptions{ConfidenceLevel: 1.5})
+	assert.Error(t, err)
+
+	_, err = ParseVaRMethod("garch")
+	assert.Error(t, err)
+
+	engine, err := NewVaREngine(VaROptions{})
+	require.NoError(t, err)
+
+	_, err = engine.Estimate(&Portfolio{}, VaRMethodHistorical)
+	assert.Error(t, err)
+
+	_, err = engine.Estimate(&Portfolio{Positions: []PortfolioPosition{{Value: 1000, Returns: []float64{0.01}}}}, VaRMethodHistorical)
+	assert.Error(t, err)
+
+	_, err = engine.Estimate(&Portfolio{Positions: []PortfolioPosition{{Value: 0, Returns: []float64{0.01, 0.02}}}}, VaRMethodHistorical)
+	assert.Error(t, err)
+}
+
+// TestLoadPortfolioReturns tests aligning returns of several symbols on shared candle times
+func TestLoadPortfolioReturns(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	require.NoError(t, err)
+	defer mock.Close()
+
+	calculator := NewCalculator(mock)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return start.Add(time.Duration(n) * 24 * time.Hour) }
+
+	mock.ExpectQuery("SELECT close, open_time FROM candlesticks").
+		WithArgs("BTCUSDT", "1d", 30).
+		WillReturnRows(pgxmock.NewRows([]string{"close", "open_time"}).
+			AddRow(100.0, day(0)).
+			AddRow(110.0, day(1)).
+			AddRow(99.0, day(2)).
+			AddRow(108.9, day(3)))
+
+	// ETH is missing day 1
+	mock.ExpectQuery("SELECT close, open_time FROM candlesticks").
+		WithArgs("ETHUSDT", "1d", 30).
+		WillReturnRows(pgxmock.NewRows([]string{"close", "open_time"}).
+			AddRow(10.0, day(0)).
+			AddRow(12.0, day(2)).
+			AddRow(9.0, day(3)))
+
+	portfolio, err := calculator.LoadPortfolioReturns(context.Background(), []PortfolioPosition{
+		{Symbol: "BTCUSDT", Value: 5000},
+		{Symbol: "ETHUSDT", Value: 5000},
+	}, "1d", 30)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, portfolio.Positions, 2)
+	assert.InDeltaSlice(t, []float64{-0.01, 0.1}, portfolio.Positions[0].Returns, 1e-9)
+	assert.InDeltaSlice(t, []float64{0.2, -0.25}, portfolio.Positions[1].Returns, 1e-9)
+	assert.Equal(t, 5000.0, portfolio.Positions[1].Value)
+}
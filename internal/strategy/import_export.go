@@ -464,6 +464,10 @@ func mergeRiskSettings(base, override *RiskSettings) {
 		override.CircuitBreakers.DrawdownHalt > 0 {
 		base.CircuitBreakers = override.CircuitBreakers
 	}
+	// De-risking - replace as a whole if enabled or any policy is set
+	if override.DeRisking.Enabled || len(override.DeRisking.Policies) > 0 {
+		base.DeRisking = override.DeRisking
+	}
 }
 
 func mergeOrchestration(base, override *OrchestrationSettings) {
@@ -455,6 +455,9 @@ func (s *StrategyConfig) validateRisk() ValidationErrors {
 		})
 	}
 
+	// De-risking policies - validate even if not enabled, as they may be enabled later
+	errs = append(errs, s.validateDeRisking()...)
+
 	// Position sizing
 	if s.Risk.KellyFraction < 0 || s.Risk.KellyFraction > 1 {
 		errs = append(errs, ValidationError{
@@ -512,11 +515,69 @@ func (s *StrategyConfig) validateRisk() ValidationErrors {
 	return errs
 }
 
+func (s *StrategyConfig) validateDeRisking() ValidationErrors {
+	var errs ValidationErrors
+	deRisking := s.Risk.DeRisking
+
+	if deRisking.Ranking != "" && deRisking.Ranking != "largest" && deRisking.Ranking != "riskiest" {
+		errs = append(errs, ValidationError{
+			Field:   "risk.de_risking.ranking",
+			Message: fmt.Sprintf("invalid ranking '%s', must be 'largest' or 'riskiest'", deRisking.Ranking),
+		})
+	}
+	if deRisking.Cooldown != "" {
+		if _, err := time.ParseDuration(deRisking.Cooldown); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   "risk.de_risking.cooldown",
+				Message: fmt.Sprintf("invalid duration format: %v", err),
+			})
+		}
+	}
+
+	names := make(map[string]bool, len(deRisking.Policies))
+	for i, policy := range deRisking.Policies {
+		field := fmt.Sprintf("risk.de_risking.policies[%d]", i)
+		if policy.Name == "" {
+			errs = append(errs, ValidationError{Field: field + ".name", Message: "policy name is required"})
+		} else if names[policy.Name] {
+			errs = append(errs, ValidationError{Field: field + ".name", Message: fmt.Sprintf("duplicate policy name '%s'", policy.Name)})
+		}
+		names[policy.Name] = true
+
+		if policy.Metric != "drawdown" && policy.Metric != "exposure" && policy.Metric != "var" {
+			errs = append(errs, ValidationError{
+				Field:   field + ".metric",
+				Message: fmt.Sprintf("invalid metric '%s', must be 'drawdown', 'exposure' or 'var'", policy.Metric),
+			})
+		}
+		if policy.Threshold <= 0 {
+			errs = append(errs, ValidationError{Field: field + ".threshold", Message: "threshold must be positive"})
+		}
+		switch policy.Action {
+		case "flatten":
+		case "reduce":
+			if policy.ReduceFraction <= 0 || policy.ReduceFraction > 1 {
+				errs = append(errs, ValidationError{
+					Field:   field + ".reduce_fraction",
+					Message: "reduce fraction must be between 0 and 1",
+				})
+			}
+		default:
+			errs = append(errs, ValidationError{
+				Field:   field + ".action",
+				Message: fmt.Sprintf("invalid action '%s', must be 'reduce' or 'flatten'", policy.Action),
+			})
+		}
+	}
+
+	return errs
+}
+
 func (s *StrategyConfig) validateOrchestration() ValidationErrors {
 	var errs ValidationErrors
 
 	// Voting method
-	validMethods := []string{"weighted_consensus", "majority"}
+	validMethods := []string{"weighted_consensus", "majority", "borda", "bayesian"}
 	if s.Orchestration.VotingMethod != "" {
 		valid := false
 		for _, m := range validMethods {
@@ -549,6 +610,16 @@ func (s *StrategyConfig) validateOrchestration() ValidationErrors {
 		})
 	}
 
+	// Veto agents
+	for i, agentType := range s.Orchestration.VetoAgents {
+		if agentType == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("orchestration.veto_agents[%d]", i),
+				Message: "veto agent type must not be empty",
+			})
+		}
+	}
+
 	// Validate duration strings
 	if s.Orchestration.StepInterval != "" {
 		if _, err := time.ParseDuration(s.Orchestration.StepInterval); err != nil {
@@ -224,6 +224,9 @@ type RiskSettings struct {
 	// Circuit breakers
 	CircuitBreakers CircuitBreakerSettings `yaml:"circuit_breakers,omitempty" json:"circuit_breakers,omitempty"`
 
+	// Automatic de-risking
+	DeRisking DeRiskingSettings `yaml:"de_risking,omitempty" json:"de_risking,omitempty"`
+
 	// Position sizing
 	KellyFraction  float64 `yaml:"kelly_fraction,omitempty" json:"kelly_fraction,omitempty"`
 	MinPositionUSD float64 `yaml:"min_position_usd,omitempty" json:"min_position_usd,omitempty"`
@@ -243,13 +246,31 @@ type CircuitBreakerSettings struct {
 	DrawdownHalt        float64 `yaml:"drawdown_halt,omitempty" json:"drawdown_halt,omitempty"`
 }
 
+// DeRiskingSettings defines how open positions are cut when risk limits are breached
+type DeRiskingSettings struct {
+	Enabled  bool              `yaml:"enabled" json:"enabled"`
+	Ranking  string            `yaml:"ranking,omitempty" json:"ranking,omitempty"`   // "largest" or "riskiest"
+	Cooldown string            `yaml:"cooldown,omitempty" json:"cooldown,omitempty"` // Minimum time between steps of a policy
+	Policies []DeRiskingPolicy `yaml:"policies,omitempty" json:"policies,omitempty"`
+}
+
+// DeRiskingPolicy reduces or flattens positions when a risk metric exceeds a threshold
+type DeRiskingPolicy struct {
+	Name           string  `yaml:"name" json:"name"`
+	Metric         string  `yaml:"metric" json:"metric"` // "drawdown", "exposure" or "var"
+	Threshold      float64 `yaml:"threshold" json:"threshold"`
+	Action         string  `yaml:"action" json:"action"` // "reduce" or "flatten"
+	ReduceFraction float64 `yaml:"reduce_fraction,omitempty" json:"reduce_fraction,omitempty"`
+}
+
 // OrchestrationSettings contains decision-making settings
 type OrchestrationSettings struct {
 	// Voting
-	VotingEnabled bool    `yaml:"voting_enabled" json:"voting_enabled"`
-	VotingMethod  string  `yaml:"voting_method" json:"voting_method"` // "weighted_consensus" or "majority"
-	MinVotes      int     `yaml:"min_votes" json:"min_votes"`
-	Quorum        float64 `yaml:"quorum" json:"quorum"`
+	VotingEnabled bool     `yaml:"voting_enabled" json:"voting_enabled"`
+	VotingMethod  string   `yaml:"voting_method" json:"voting_method"` // "weighted_consensus", "majority", "borda" or "bayesian"
+	MinVotes      int      `yaml:"min_votes" json:"min_votes"`
+	Quorum        float64  `yaml:"quorum" json:"quorum"`
+	VetoAgents    []string `yaml:"veto_agents,omitempty" json:"veto_agents,omitempty"` // Agent types that can block BUY and SELL
 
 	// Decision timing
 	StepInterval  string  `yaml:"step_interval" json:"step_interval"`
@@ -345,6 +366,14 @@ func NewDefaultStrategy(name string) *StrategyConfig {
 				MaxLossesPerDay:  3,
 				DrawdownHalt:     0.08,
 			},
+			DeRisking: DeRiskingSettings{
+				Ranking:  "largest",
+				Cooldown: "15m",
+				Policies: []DeRiskingPolicy{
+					{Name: "soft_drawdown", Metric: "drawdown", Threshold: 0.05, Action: "reduce", ReduceFraction: 0.25},
+					{Name: "hard_drawdown", Metric: "drawdown", Threshold: 0.1, Action: "flatten"},
+				},
+			},
 			KellyFraction:  0.25,
 			MinPositionUSD: 10.0,
 			MaxPositionUSD: 1000.0,
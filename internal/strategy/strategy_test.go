@@ -154,6 +154,34 @@ func TestStrategyConfig_Validate_InvalidRiskSettings(t *testing.T) {
 			},
 			errMsg: "min_consensus_votes",
 		},
+		{
+			name: "de_risking metric unknown",
+			modify: func(s *StrategyConfig) {
+				s.Risk.DeRisking.Policies[0].Metric = "sharpe"
+			},
+			errMsg: "de_risking.policies[0].metric",
+		},
+		{
+			name: "de_risking reduce without fraction",
+			modify: func(s *StrategyConfig) {
+				s.Risk.DeRisking.Policies[0].ReduceFraction = 0
+			},
+			errMsg: "de_risking.policies[0].reduce_fraction",
+		},
+		{
+			name: "de_risking duplicate policy",
+			modify: func(s *StrategyConfig) {
+				s.Risk.DeRisking.Policies[1].Name = s.Risk.DeRisking.Policies[0].Name
+			},
+			errMsg: "duplicate policy name",
+		},
+		{
+			name: "de_risking invalid cooldown",
+			modify: func(s *StrategyConfig) {
+				s.Risk.DeRisking.Cooldown = "soon"
+			},
+			errMsg: "de_risking.cooldown",
+		},
 	}
 
 	for _, tt := range tests {
@@ -225,6 +253,23 @@ func TestStrategyConfig_Validate_InvalidIndicators(t *testing.T) {
 	}
 }
 
+func TestStrategyConfig_Validate_Voting(t *testing.T) {
+	for _, method := range []string{"weighted_consensus", "majority", "borda", "bayesian"} {
+		s := NewDefaultStrategy("Test")
+		s.Orchestration.VotingMethod = method
+		s.Orchestration.VetoAgents = []string{"risk"}
+		assert.NoError(t, s.Validate(), method)
+	}
+
+	s := NewDefaultStrategy("Test")
+	s.Orchestration.VotingMethod = "plurality"
+	s.Orchestration.VetoAgents = []string{""}
+	err := s.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "orchestration.voting_method")
+	assert.Contains(t, err.Error(), "orchestration.veto_agents[0]")
+}
+
 func TestStrategyConfig_ValidateQuick(t *testing.T) {
 	s := NewDefaultStrategy("Test")
 	err := s.ValidateQuick()
@@ -32,6 +32,8 @@ type Config struct {
 	PollingTimeout  int
 	Debug           bool
 	OrchestratorURL string // URL for querying orchestrator
+	APIURL          string // URL of the API server (kill switch)
+	APIKey          string // API key for trading control endpoints (optional)
 }
 
 // CommandHandler is a function that handles a bot command
@@ -80,6 +82,7 @@ func (b *Bot) registerDefaultHandlers() {
 	b.RegisterHandler("pl", handlePL)
 	b.RegisterHandler("pause", handlePause)
 	b.RegisterHandler("resume", handleResume)
+	b.RegisterHandler("flatten", handleFlatten)
 	b.RegisterHandler("decisions", handleDecisions)
 	b.RegisterHandler("verify", handleVerify)
 	b.RegisterHandler("settings", handleSettings)
@@ -321,6 +321,8 @@ func TestBotConfig(t *testing.T) {
 		PollingTimeout:  60,
 		Debug:           true,
 		OrchestratorURL: "http://localhost:8081",
+		APIURL:          "http://localhost:8080",
+		APIKey:          "test_key",
 	}
 
 	assert.Equal(t, "test_token", config.BotToken)
@@ -328,4 +330,6 @@ func TestBotConfig(t *testing.T) {
 	assert.Equal(t, 60, config.PollingTimeout)
 	assert.True(t, config.Debug)
 	assert.Equal(t, "http://localhost:8081", config.OrchestratorURL)
+	assert.Equal(t, "http://localhost:8080", config.APIURL)
+	assert.Equal(t, "test_key", config.APIKey)
 }
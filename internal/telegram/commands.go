@@ -1,6 +1,7 @@
 package telegram
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -25,6 +26,7 @@ I'm your AI-powered trading assistant. I can help you monitor your trading sessi
 /decisions - Show recent agent decisions (last 5)
 /pause - Emergency pause trading
 /resume - Resume trading after pause
+/flatten - Kill switch: cancel all orders and close all positions
 /settings - Manage notification preferences
 /help - Show this help message
 
@@ -56,6 +58,8 @@ func handleHelp(ctx context.Context, bot *Bot, message *tgbotapi.Message) error
 *Control Commands:*
 /pause - Emergency pause all trading (positions remain open)
 /resume - Resume trading after pause
+/flatten [reason] - Kill switch: cancel all orders and close all positions
+/flatten confirm <token> - Confirm a kill switch request
 
 *Settings Commands:*
 /settings - View and manage notification preferences
@@ -255,6 +259,80 @@ Use /positions to check your open positions.`
 	return err
 }
 
+// handleFlatten handles the /flatten command. Without arguments it arms the
+// kill switch and returns a confirmation token; "/flatten confirm <token>" executes it.
+func handleFlatten(ctx context.Context, bot *Bot, message *tgbotapi.Message) error {
+	// Check if user is verified
+	verified, err := isUserVerified(ctx, bot, message.From.ID)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return sendVerificationRequired(bot, message.Chat.ID)
+	}
+
+	args := strings.Fields(message.Text)
+	requestedBy := fmt.Sprintf("telegram:%d", message.From.ID)
+
+	var responseText string
+	if len(args) >= 2 && strings.EqualFold(args[1], "confirm") {
+		if len(args) < 3 {
+			msg := tgbotapi.NewMessage(message.Chat.ID, "Please provide the confirmation token: /flatten confirm <token>")
+			_, err := bot.api.Send(msg)
+			return err
+		}
+
+		report, err := confirmFlatten(ctx, bot, args[2])
+		if err != nil {
+			return fmt.Errorf("failed to execute kill switch: %w", err)
+		}
+
+		status := "✅ All orders cancelled and positions closed."
+		if report.HaltOnly {
+			status = "✅ Trading halted. Paper orders and positions were not flattened."
+		}
+		if !report.Success {
+			status = fmt.Sprintf("⚠️ Completed with %d error(s). Check /positions.", len(report.Errors))
+		}
+		responseText = fmt.Sprintf(`🛑 *Kill Switch Executed*
+
+Cancelled orders: %d
+Closed positions: %d
+
+%s
+Trading is paused. Use /resume when ready.`, len(report.CancelledOrders), len(report.ClosedPositions), status)
+	} else {
+		reason := "Telegram kill switch"
+		if len(args) > 1 {
+			reason = strings.Join(args[1:], " ")
+		}
+
+		ticket, err := armFlatten(ctx, bot, requestedBy, reason)
+		if err != nil {
+			return fmt.Errorf("failed to arm kill switch: %w", err)
+		}
+
+		action := fmt.Sprintf("This will cancel %d open order(s) and close %d open position(s) at market.",
+			ticket.OpenOrders, ticket.OpenPositions)
+		if ticket.HaltOnly {
+			action = "Paper trading: this will pause all trading sessions but not flatten orders or positions."
+		}
+		responseText = fmt.Sprintf(`⚠️ *Kill Switch Armed*
+
+%s
+
+To confirm, send within %s:
+/flatten confirm %s`, action,
+			time.Until(ticket.ExpiresAt).Round(time.Second), ticket.Token)
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, responseText)
+	msg.ParseMode = ParseModeMarkdown
+
+	_, err = bot.api.Send(msg)
+	return err
+}
+
 // handleResume handles the /resume command
 func handleResume(ctx context.Context, bot *Bot, message *tgbotapi.Message) error {
 	// Check if user is verified
@@ -489,6 +567,22 @@ type Decision struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+type FlattenTicket struct {
+	Token         string    `json:"token"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	OpenOrders    int       `json:"open_orders"`
+	OpenPositions int       `json:"open_positions"`
+	HaltOnly      bool      `json:"halt_only"`
+}
+
+type FlattenReport struct {
+	CancelledOrders []string          `json:"cancelled_orders"`
+	ClosedPositions []json.RawMessage `json:"closed_positions"`
+	Errors          []string          `json:"errors"`
+	Success         bool              `json:"success"`
+	HaltOnly        bool              `json:"halt_only"`
+}
+
 type UserSettings struct {
 	ReceiveAlerts             bool `json:"receive_alerts"`
 	ReceiveTradeNotifications bool `json:"receive_trade_notifications"`
@@ -547,3 +641,62 @@ func sendOrchestratorCommand(ctx context.Context, bot *Bot, command string) erro
 
 	return nil
 }
+
+// armFlatten asks the API server to arm the kill switch
+func armFlatten(ctx context.Context, bot *Bot, requestedBy, reason string) (*FlattenTicket, error) {
+	var ticket FlattenTicket
+	err := postAPI(ctx, bot, "/api/v1/trade/flatten", map[string]string{
+		"source":       "telegram",
+		"requested_by": requestedBy,
+		"reason":       reason,
+	}, &ticket)
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// confirmFlatten executes the kill switch with a confirmation token
+func confirmFlatten(ctx context.Context, bot *Bot, token string) (*FlattenReport, error) {
+	var response struct {
+		Report FlattenReport `json:"report"`
+	}
+	if err := postAPI(ctx, bot, "/api/v1/trade/flatten/confirm", map[string]string{"token": token}, &response); err != nil {
+		return nil, err
+	}
+	return &response.Report, nil
+}
+
+// postAPI sends a JSON request to the API server and decodes the response
+func postAPI(ctx context.Context, bot *Bot, path string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s%s", bot.config.APIURL, path)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if bot.config.APIKey != "" {
+		req.Header.Set("X-API-Key", bot.config.APIKey)
+	}
+
+	// Flattening can take a while when limit orders are chased
+	client := &http.Client{Timeout: 3 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 207 means the kill switch ran but some steps failed; the report says which
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusMultiStatus {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, respBody)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
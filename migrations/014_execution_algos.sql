@@ -0,0 +1,20 @@
+-- Migration: Execution Algorithms
+-- Description: Adds parent/child order tracking for TWAP, VWAP, POV and iceberg execution
+-- Version: 014
+
+-- Child orders reference the parent (algo) order they were sliced from
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS parent_order_id UUID REFERENCES orders(id) ON DELETE CASCADE;
+
+-- Execution algorithm used for parent orders (twap, vwap, pov, iceberg)
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS algo_type VARCHAR(20);
+
+-- Index for looking up the children of a parent order
+CREATE INDEX IF NOT EXISTS idx_orders_parent ON orders (parent_order_id, placed_at ASC) WHERE parent_order_id IS NOT NULL;
+
+-- Index for listing algo (parent) orders
+CREATE INDEX IF NOT EXISTS idx_orders_algo_type ON orders (algo_type, placed_at DESC) WHERE algo_type IS NOT NULL;
+
+COMMENT ON COLUMN orders.parent_order_id IS 'Parent algo order this child order was sliced from';
+COMMENT ON COLUMN orders.algo_type IS 'Execution algorithm for parent orders: twap, vwap, pov, iceberg';
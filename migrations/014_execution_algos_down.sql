@@ -0,0 +1,9 @@
+-- Migration Down: Execution Algorithms
+-- Description: Removes parent/child order tracking columns
+-- Version: 014
+
+DROP INDEX IF EXISTS idx_orders_algo_type;
+DROP INDEX IF EXISTS idx_orders_parent;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS algo_type;
+ALTER TABLE orders DROP COLUMN IF EXISTS parent_order_id;
@@ -0,0 +1,28 @@
+-- Migration: Instrument Metadata
+-- Description: Per-exchange trading rules (tick size, lot size, min notional, precision)
+-- Version: 015
+
+CREATE TABLE IF NOT EXISTS instruments (
+    exchange VARCHAR(50) NOT NULL,
+    symbol VARCHAR(20) NOT NULL,
+    base_asset VARCHAR(20),
+    quote_asset VARCHAR(20),
+    tick_size DECIMAL(20, 10) NOT NULL DEFAULT 0,
+    step_size DECIMAL(20, 10) NOT NULL DEFAULT 0,
+    min_qty DECIMAL(30, 10) NOT NULL DEFAULT 0,
+    max_qty DECIMAL(30, 10) NOT NULL DEFAULT 0,
+    min_price DECIMAL(30, 10) NOT NULL DEFAULT 0,
+    max_price DECIMAL(30, 10) NOT NULL DEFAULT 0,
+    min_notional DECIMAL(30, 10) NOT NULL DEFAULT 0,
+    price_precision INTEGER NOT NULL DEFAULT 8,
+    quantity_precision INTEGER NOT NULL DEFAULT 8,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (exchange, symbol)
+);
+
+CREATE INDEX IF NOT EXISTS idx_instruments_symbol ON instruments(symbol);
+
+COMMENT ON TABLE instruments IS 'Exchange trading rules used to round and validate orders and to size positions';
+COMMENT ON COLUMN instruments.tick_size IS 'Minimum price increment';
+COMMENT ON COLUMN instruments.step_size IS 'Minimum quantity increment (lot size)';
+COMMENT ON COLUMN instruments.min_notional IS 'Minimum order value (quantity * price) in quote currency';
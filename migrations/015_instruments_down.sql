@@ -0,0 +1,6 @@
+-- Migration Down: Instrument Metadata
+-- Description: Removes the instruments table
+-- Version: 015
+
+DROP INDEX IF EXISTS idx_instruments_symbol;
+DROP TABLE IF EXISTS instruments;
@@ -0,0 +1,16 @@
+-- Migration: Account Balances
+-- Description: Latest asset inventory snapshot per exchange account
+-- Version: 016
+
+CREATE TABLE IF NOT EXISTS account_balances (
+    exchange VARCHAR(50) NOT NULL,
+    asset VARCHAR(20) NOT NULL,
+    free DECIMAL(30, 10) NOT NULL DEFAULT 0,
+    locked DECIMAL(30, 10) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (exchange, asset)
+);
+
+COMMENT ON TABLE account_balances IS 'Exchange account balances published by the order executor';
+COMMENT ON COLUMN account_balances.free IS 'Balance available for trading';
+COMMENT ON COLUMN account_balances.locked IS 'Balance reserved by open orders';
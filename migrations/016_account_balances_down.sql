@@ -0,0 +1,5 @@
+-- Migration Down: Account Balances
+-- Description: Removes the account_balances table
+-- Version: 016
+
+DROP TABLE IF EXISTS account_balances;
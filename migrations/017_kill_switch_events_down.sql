@@ -0,0 +1,30 @@
+-- Migration: Kill Switch Audit Events (rollback)
+-- Description: Restore the audit_logs event type constraint from migration 009
+-- Version: 017
+
+DROP INDEX IF EXISTS idx_audit_logs_kill_switch_events;
+
+DELETE FROM audit_logs
+WHERE event_type IN ('KILL_SWITCH_ARMED', 'KILL_SWITCH_TRIGGERED', 'KILL_SWITCH_COMPLETED', 'POSITION_CLOSED');
+
+DO $$
+BEGIN
+    ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_event_type_check;
+EXCEPTION
+    WHEN undefined_object THEN
+        NULL; -- Constraint doesn't exist, that's fine
+END $$;
+
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_event_type_check CHECK (
+    event_type IN (
+        'LOGIN', 'LOGOUT', 'LOGIN_FAILED', 'PASSWORD_CHANGE',
+        'TRADING_START', 'TRADING_STOP', 'TRADING_PAUSE', 'TRADING_RESUME',
+        'ORDER_PLACED', 'ORDER_CANCELED', 'ORDER_FILLED',
+        'CONFIG_UPDATED', 'CONFIG_VIEWED',
+        'STRATEGY_UPDATED', 'STRATEGY_IMPORTED', 'STRATEGY_EXPORTED', 'STRATEGY_CLONED', 'STRATEGY_MERGED',
+        'AGENT_STARTED', 'AGENT_STOPPED', 'AGENT_FAILED',
+        'RATE_LIMIT_EXCEEDED', 'UNAUTHORIZED_ACCESS', 'INVALID_INPUT',
+        'DATA_EXPORT', 'DATA_DELETE',
+        'DECISION_LIST_ACCESSED', 'DECISION_VIEWED', 'DECISION_SEARCHED', 'DECISION_STATS_ACCESSED', 'DECISION_SIMILAR_ACCESSED'
+    )
+);
@@ -0,0 +1,46 @@
+-- Migration: Tax Lots
+-- Description: Tax-lot ledger and realized gains for per-session gain reporting
+-- Version: 018
+
+CREATE TABLE IF NOT EXISTS tax_lots (
+    id UUID PRIMARY KEY,
+    session_id UUID REFERENCES trading_sessions(id) ON DELETE CASCADE,
+    symbol VARCHAR(20) NOT NULL,
+    side VARCHAR(10) NOT NULL CHECK (side IN ('LONG', 'SHORT')),
+    quantity DECIMAL(30, 10) NOT NULL,
+    remaining_quantity DECIMAL(30, 10) NOT NULL,
+    price DECIMAL(30, 10) NOT NULL,
+    fees DECIMAL(30, 10) NOT NULL DEFAULT 0,
+    order_id VARCHAR(100),
+    acquired_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_lots_open ON tax_lots(session_id, symbol) WHERE closed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS realized_gains (
+    id UUID PRIMARY KEY,
+    session_id UUID REFERENCES trading_sessions(id) ON DELETE CASCADE,
+    lot_id UUID NOT NULL REFERENCES tax_lots(id) ON DELETE CASCADE,
+    symbol VARCHAR(20) NOT NULL,
+    side VARCHAR(10) NOT NULL CHECK (side IN ('LONG', 'SHORT')),
+    method VARCHAR(20) NOT NULL,
+    quantity DECIMAL(30, 10) NOT NULL,
+    cost_basis DECIMAL(30, 10) NOT NULL,
+    proceeds DECIMAL(30, 10) NOT NULL,
+    gain DECIMAL(30, 10) NOT NULL,
+    order_id VARCHAR(100),
+    acquired_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    disposed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_realized_gains_session ON realized_gains(session_id, disposed_at);
+CREATE INDEX IF NOT EXISTS idx_realized_gains_disposed_at ON realized_gains(disposed_at);
+
+COMMENT ON TABLE tax_lots IS 'Quantity acquired by a single fill, consumed by later closing fills';
+COMMENT ON COLUMN tax_lots.fees IS 'Acquisition fees of the remaining quantity, included in its cost basis';
+COMMENT ON TABLE realized_gains IS 'Disposal of (part of) a tax lot';
+COMMENT ON COLUMN realized_gains.cost_basis IS 'Acquisition cost including allocated acquisition fees';
+COMMENT ON COLUMN realized_gains.proceeds IS 'Disposal value net of allocated disposal fees';
@@ -0,0 +1,6 @@
+-- Migration Down: Tax Lots
+-- Description: Removes the tax_lots and realized_gains tables
+-- Version: 018
+
+DROP TABLE IF EXISTS realized_gains;
+DROP TABLE IF EXISTS tax_lots;
@@ -0,0 +1,9 @@
+-- Migration: Order Amendments
+-- Description: Keeps the price/quantity amendment history of resting orders
+-- Version: 019
+
+-- Each amendment (in place or cancel-replace) is appended as a JSON object
+ALTER TABLE orders
+ADD COLUMN IF NOT EXISTS amendments JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+COMMENT ON COLUMN orders.amendments IS 'Price/quantity amendments in order: previous and new values, exchange order IDs and time';
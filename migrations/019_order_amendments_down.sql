@@ -0,0 +1,5 @@
+-- Migration Down: Order Amendments
+-- Description: Removes the orders.amendments column
+-- Version: 019
+
+ALTER TABLE orders DROP COLUMN IF EXISTS amendments;
@@ -0,0 +1,9 @@
+-- Migration: Session Controls
+-- Description: Per-session pause flag so concurrent trading sessions can be controlled independently
+-- Version: 020
+
+ALTER TABLE trading_sessions ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_trading_sessions_active ON trading_sessions(started_at DESC) WHERE stopped_at IS NULL;
+
+COMMENT ON COLUMN trading_sessions.paused IS 'Paused sessions reject new orders until resumed';
@@ -0,0 +1,6 @@
+-- Migration Down: Session Controls
+-- Description: Removes the trading_sessions.paused column
+-- Version: 020
+
+DROP INDEX IF EXISTS idx_trading_sessions_active;
+ALTER TABLE trading_sessions DROP COLUMN IF EXISTS paused;
@@ -0,0 +1,19 @@
+-- Migration: Decision Attributions
+-- Description: Agents and strategies behind each orchestrator BUY/SELL decision, used to attribute position P&L and risk to risk budgets
+-- Version: 021
+
+CREATE TABLE IF NOT EXISTS decision_attributions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    symbol VARCHAR(20) NOT NULL,
+    action order_side NOT NULL,
+    agents JSONB NOT NULL DEFAULT '{}',
+    strategies JSONB NOT NULL DEFAULT '{}',
+    confidence DECIMAL(10, 4),
+    decided_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_decision_attributions_symbol ON decision_attributions(symbol, action, decided_at DESC);
+
+COMMENT ON TABLE decision_attributions IS 'Share of each orchestrator decision owed to its agents and strategies; positions are attributed to the latest matching decision before their entry';
+COMMENT ON COLUMN decision_attributions.agents IS 'Agent name -> share of the winning weighted vote (shares sum to 1)';
+COMMENT ON COLUMN decision_attributions.strategies IS 'Agent type -> share of the winning weighted vote (shares sum to 1)';
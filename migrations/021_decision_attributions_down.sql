@@ -0,0 +1,6 @@
+-- Migration Down: Decision Attributions
+-- Description: Removes the decision_attributions table
+-- Version: 021
+
+DROP INDEX IF EXISTS idx_decision_attributions_symbol;
+DROP TABLE IF EXISTS decision_attributions;
@@ -0,0 +1,31 @@
+-- Migration: De-risking Audit Events (rollback)
+-- Description: Restore the audit_logs event type constraint from migration 017
+-- Version: 022
+
+DROP INDEX IF EXISTS idx_audit_logs_derisk_events;
+
+DELETE FROM audit_logs
+WHERE event_type IN ('DERISK_EXECUTED', 'POSITION_REDUCED');
+
+DO $$
+BEGIN
+    ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_event_type_check;
+EXCEPTION
+    WHEN undefined_object THEN
+        NULL; -- Constraint doesn't exist, that's fine
+END $$;
+
+ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_event_type_check CHECK (
+    event_type IN (
+        'LOGIN', 'LOGOUT', 'LOGIN_FAILED', 'PASSWORD_CHANGE',
+        'TRADING_START', 'TRADING_STOP', 'TRADING_PAUSE', 'TRADING_RESUME',
+        'ORDER_PLACED', 'ORDER_CANCELED', 'ORDER_FILLED',
+        'CONFIG_UPDATED', 'CONFIG_VIEWED',
+        'STRATEGY_UPDATED', 'STRATEGY_IMPORTED', 'STRATEGY_EXPORTED', 'STRATEGY_CLONED', 'STRATEGY_MERGED',
+        'AGENT_STARTED', 'AGENT_STOPPED', 'AGENT_FAILED',
+        'RATE_LIMIT_EXCEEDED', 'UNAUTHORIZED_ACCESS', 'INVALID_INPUT',
+        'DATA_EXPORT', 'DATA_DELETE',
+        'DECISION_LIST_ACCESSED', 'DECISION_VIEWED', 'DECISION_SEARCHED', 'DECISION_STATS_ACCESSED', 'DECISION_SIMILAR_ACCESSED',
+        'KILL_SWITCH_ARMED', 'KILL_SWITCH_TRIGGERED', 'KILL_SWITCH_COMPLETED', 'POSITION_CLOSED'
+    )
+);
@@ -0,0 +1,22 @@
+-- Migration: Market Regimes
+-- Description: Regime probabilities per symbol from the hidden Markov model regime service
+-- Version: 023
+
+CREATE TABLE IF NOT EXISTS market_regimes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    symbol VARCHAR(20) NOT NULL,
+    interval VARCHAR(10) NOT NULL,
+    regime VARCHAR(20) NOT NULL,
+    confidence DECIMAL(10, 6) NOT NULL,
+    probabilities JSONB NOT NULL DEFAULT '{}',
+    states JSONB NOT NULL DEFAULT '[]',
+    log_likelihood DOUBLE PRECISION,
+    observations INTEGER NOT NULL,
+    detected_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_market_regimes_symbol ON market_regimes(symbol, interval, detected_at DESC);
+
+COMMENT ON TABLE market_regimes IS 'Regime of each symbol at its latest candle, from a Gaussian HMM on returns and volatility';
+COMMENT ON COLUMN market_regimes.probabilities IS 'Regime -> probability (bullish, bearish, sideways, volatile; sums to 1)';
+COMMENT ON COLUMN market_regimes.states IS 'Fitted hidden states: regime label, probability, mean return, volatility, persistence';
@@ -0,0 +1,6 @@
+-- Migration Down: Market Regimes
+-- Description: Removes the market_regimes table
+-- Version: 023
+
+DROP INDEX IF EXISTS idx_market_regimes_symbol;
+DROP TABLE IF EXISTS market_regimes;
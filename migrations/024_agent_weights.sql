@@ -0,0 +1,44 @@
+-- Migration: Agent Weights
+-- Description: Voting weights learned online from the outcomes of agent signals, with an audit trail of every change
+-- Version: 024
+
+CREATE TABLE IF NOT EXISTS agent_weights (
+    agent_name VARCHAR(100) PRIMARY KEY,
+    agent_type VARCHAR(50) NOT NULL,
+    weight DOUBLE PRECISION NOT NULL,
+    learned_weight DOUBLE PRECISION NOT NULL,
+    base_weight DOUBLE PRECISION NOT NULL,
+    hit_rate DOUBLE PRECISION NOT NULL,
+    brier_score DOUBLE PRECISION NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    frozen BOOLEAN NOT NULL DEFAULT FALSE,
+    override_weight DOUBLE PRECISION,
+    scored_through TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS agent_weight_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    agent_name VARCHAR(100) NOT NULL,
+    reason VARCHAR(20) NOT NULL,
+    previous_weight DOUBLE PRECISION NOT NULL,
+    weight DOUBLE PRECISION NOT NULL,
+    hit_rate DOUBLE PRECISION NOT NULL,
+    brier_score DOUBLE PRECISION NOT NULL,
+    samples INTEGER NOT NULL DEFAULT 0,
+    actor VARCHAR(100),
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_weight_events_agent ON agent_weight_events(agent_name, created_at DESC);
+
+-- Signals are scored in time order once their return horizon has passed
+CREATE INDEX IF NOT EXISTS idx_agent_signals_created ON agent_signals(created_at);
+
+COMMENT ON TABLE agent_weights IS 'Voting weight of each agent: learned from its signals against subsequent returns unless frozen or overridden';
+COMMENT ON COLUMN agent_weights.weight IS 'Weight the orchestrator votes with: the override, the weight when frozen, or the learned weight';
+COMMENT ON COLUMN agent_weights.hit_rate IS 'Exponentially weighted share of signals whose direction the subsequent return confirmed';
+COMMENT ON COLUMN agent_weights.brier_score IS 'Exponentially weighted squared error of signal confidence against the hit (0.25 is a coin flip)';
+COMMENT ON COLUMN agent_weights.scored_through IS 'Signals created up to this time have been scored';
+COMMENT ON TABLE agent_weight_events IS 'Audit trail of agent weight changes: learned, freeze, unfreeze, override, clear_override';
@@ -0,0 +1,8 @@
+-- Migration Down: Agent Weights
+-- Description: Removes the agent_weights and agent_weight_events tables
+-- Version: 024
+
+DROP INDEX IF EXISTS idx_agent_signals_created;
+DROP INDEX IF EXISTS idx_agent_weight_events_agent;
+DROP TABLE IF EXISTS agent_weight_events;
+DROP TABLE IF EXISTS agent_weights;
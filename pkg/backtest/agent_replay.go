@@ -18,6 +18,7 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/ajitpratap0/cryptofunk/internal/db"
+	"github.com/ajitpratap0/cryptofunk/internal/regime"
 )
 
 // ============================================================================
@@ -32,6 +33,10 @@ type AgentReplayAdapter struct {
 	agentMetrics map[string]*AgentPerformance // agent name -> performance metrics
 	consensus    ConsensusStrategy            // How to combine signals from multiple agents
 	context      map[string]interface{}       // Shared context for agents
+
+	// Regime model refitted on every candle when set
+	regimeDetector *regime.Detector
+	regimeLookback int
 }
 
 // Agent represents a trading agent that can generate signals
@@ -55,6 +60,7 @@ type MarketData struct {
 	History      []*Candlestick         `json:"historical_candles"`
 	Indicators   map[string]float64     `json:"indicators,omitempty"`
 	Context      map[string]interface{} `json:"context,omitempty"`
+	Regime       *regime.Estimate       `json:"regime,omitempty"` // Set when a regime detector is configured
 }
 
 // AgentPerformance tracks individual agent performance during backtest
@@ -117,6 +123,14 @@ func (a *AgentReplayAdapter) AddAgent(agent Agent) error {
 	return nil
 }
 
+// SetRegimeDetector gives agents the market regime at each candle, fitted on
+// at most lookback candles up to it (0 = all so far). Signals are tagged
+// with the regime in their metadata.
+func (a *AgentReplayAdapter) SetRegimeDetector(detector *regime.Detector, lookback int) {
+	a.regimeDetector = detector
+	a.regimeLookback = lookback
+}
+
 // SetContext sets shared context data for all agents
 func (a *AgentReplayAdapter) SetContext(key string, value interface{}) {
 	a.context[key] = value
@@ -176,6 +190,16 @@ func (a *AgentReplayAdapter) GenerateSignals(engine *Engine) ([]*Signal, error)
 			Context:      a.context,
 		}
 
+		// Regime as the live regime service would have seen it
+		if a.regimeDetector != nil {
+			estimate, err := engine.DetectRegime(symbol, a.regimeDetector, a.regimeLookback)
+			if err != nil {
+				log.Debug().Err(err).Str("symbol", symbol).Msg("No market regime for candle")
+			} else {
+				marketData.Regime = estimate
+			}
+		}
+
 		// Calculate basic indicators (agents can compute their own too)
 		if len(historicalCandles) >= 20 {
 			marketData.Indicators["sma_20"] = calculateSMA(append(historicalCandles, currentCandle), 20)
@@ -201,6 +225,12 @@ func (a *AgentReplayAdapter) GenerateSignals(engine *Engine) ([]*Signal, error)
 				signal.Symbol = symbol
 				signal.Timestamp = currentCandle.Timestamp
 				signal.Agent = name
+				if marketData.Regime != nil {
+					if signal.Metadata == nil {
+						signal.Metadata = make(map[string]interface{})
+					}
+					signal.Metadata["regime"] = string(marketData.Regime.Regime)
+				}
 
 				// Track signal
 				agentSignals = append(agentSignals, signal)
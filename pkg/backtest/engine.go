@@ -59,6 +59,7 @@ type Position struct {
 	CurrentPrice float64   `json:"current_price"`
 	UnrealizedPL float64   `json:"unrealized_pl"`
 	Commission   float64   `json:"commission"`
+	StopLoss     float64   `json:"stop_loss,omitempty"` // Exit price set by ATR sizing (0 = none)
 }
 
 // ClosedPosition represents a closed position with P&L
@@ -93,10 +94,16 @@ type Engine struct {
 	// Configuration
 	InitialCapital float64 `json:"initial_capital"`
 	CommissionRate float64 `json:"commission_rate"` // e.g., 0.001 for 0.1%
-	PositionSizing string  `json:"position_sizing"` // "fixed", "percent", "kelly"
+	PositionSizing string  `json:"position_sizing"` // "fixed", "percent", "kelly", "vol_target", "atr"
 	PositionSize   float64 `json:"position_size"`   // Amount per trade
 	MaxPositions   int     `json:"max_positions"`   // Maximum concurrent positions
 
+	// Volatility-target and ATR sizing
+	VolatilityLookback        int     `json:"volatility_lookback"`         // Candles of returns for vol_target sizing
+	PortfolioTargetVolatility float64 `json:"portfolio_target_volatility"` // Cap on annualized portfolio volatility in vol_target mode (0 = none)
+	ATRPeriod                 int     `json:"atr_period"`                  // Candles in the ATR
+	ATRMultiplier             float64 `json:"atr_multiplier"`              // ATR stop distance in ATRs
+
 	// State
 	Cash            float64              `json:"cash"`
 	Positions       map[string]*Position `json:"positions"` // symbol -> position
@@ -135,6 +142,11 @@ func NewEngine(config BacktestConfig) *Engine {
 		Data:            make(map[string][]*Candlestick),
 		CurrentIndex:    make(map[string]int),
 		PeakEquity:      config.InitialCapital,
+
+		VolatilityLookback:        config.VolatilityLookback,
+		PortfolioTargetVolatility: config.PortfolioTargetVolatility,
+		ATRPeriod:                 config.ATRPeriod,
+		ATRMultiplier:             config.ATRMultiplier,
 	}
 }
 
@@ -142,12 +154,20 @@ func NewEngine(config BacktestConfig) *Engine {
 type BacktestConfig struct {
 	InitialCapital float64
 	CommissionRate float64
-	PositionSizing string // "fixed", "percent", "kelly"
+	PositionSizing string // "fixed", "percent", "kelly", "vol_target", "atr"
 	PositionSize   float64
 	MaxPositions   int
 	StartDate      time.Time
 	EndDate        time.Time
 	Symbols        []string
+
+	// vol_target: PositionSize is the annualized volatility of each position
+	// as a fraction of equity. atr: PositionSize is the share of equity lost
+	// when the N×ATR stop is hit. Zero values use the defaults.
+	VolatilityLookback        int
+	PortfolioTargetVolatility float64
+	ATRPeriod                 int
+	ATRMultiplier             float64
 }
 
 // ============================================================================
@@ -244,10 +264,15 @@ func (e *Engine) Step(ctx context.Context) (bool, error) {
 		}
 	}
 
-	// Update current prices for all positions
+	// Update current prices for all positions, exiting those whose stop was hit
 	for symbol, position := range e.Positions {
 		candle, err := e.GetCurrentCandle(symbol)
 		if err == nil {
+			// The entry candle closed at the entry price, so stops apply from the next one
+			if position.StopLoss > 0 && candle.Timestamp.After(position.EntryTime) && candle.Low <= position.StopLoss {
+				e.executeStopLoss(position, candle)
+				continue
+			}
 			position.CurrentPrice = candle.Close
 			position.UnrealizedPL = e.calculateUnrealizedPL(position)
 		}
@@ -311,7 +336,11 @@ func (e *Engine) executeBuy(signal *Signal, price float64, timestamp time.Time)
 	}
 
 	// Calculate position size
-	quantity := e.calculatePositionSize(price)
+	quantity, stopLoss, err := e.sizePosition(signal.Symbol, price)
+	if err != nil {
+		log.Debug().Err(err).Str("symbol", signal.Symbol).Msg("Cannot size position, skipping buy")
+		return nil
+	}
 	if quantity <= 0 {
 		return fmt.Errorf("invalid quantity: %f", quantity)
 	}
@@ -352,6 +381,7 @@ func (e *Engine) executeBuy(signal *Signal, price float64, timestamp time.Time)
 		CurrentPrice: price,
 		UnrealizedPL: 0,
 		Commission:   commission,
+		StopLoss:     stopLoss,
 	}
 
 	// Update state
@@ -0,0 +1,38 @@
+package backtest
+
+import (
+	"fmt"
+
+	"github.com/ajitpratap0/cryptofunk/internal/regime"
+)
+
+// DetectRegime fits the regime model to a symbol's candles up to and
+// including the current one, so a backtest sees the same regime the live
+// regime service would have seen at that time. Lookback caps the candles in
+// the fit (0 = all so far).
+func (e *Engine) DetectRegime(symbol string, detector *regime.Detector, lookback int) (*regime.Estimate, error) {
+	candles, exists := e.Data[symbol]
+	if !exists {
+		return nil, fmt.Errorf("no data loaded for symbol %s", symbol)
+	}
+	end := e.CurrentIndex[symbol]
+	if end >= len(candles) {
+		end = len(candles) - 1
+	}
+	start := 0
+	if lookback > 0 && end+1-lookback > 0 {
+		start = end + 1 - lookback
+	}
+
+	prices := make([]float64, 0, end+1-start)
+	for _, candle := range candles[start : end+1] {
+		prices = append(prices, candle.Close)
+	}
+	estimate, err := detector.Detect(prices)
+	if err != nil {
+		return nil, err
+	}
+	estimate.Symbol = symbol
+	estimate.DetectedAt = candles[end].Timestamp
+	return estimate, nil
+}
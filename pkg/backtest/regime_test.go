@@ -0,0 +1,102 @@
+package backtest
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/regime"
+)
+
+// regimeTestCandles is a calm market followed by a turbulent one
+func regimeTestCandles(symbol string) []*Candlestick {
+	rng := rand.New(rand.NewSource(11))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	price := 100.0
+	candles := make([]*Candlestick, 0, 400)
+	for i := 0; i < 400; i++ {
+		vol := 0.004
+		if i >= 250 {
+			vol = 0.03
+		}
+		price *= math.Exp(vol * rng.NormFloat64())
+		candles = append(candles, &Candlestick{
+			Symbol:    symbol,
+			Timestamp: start.Add(time.Duration(i) * time.Hour),
+			Open:      price, High: price, Low: price, Close: price,
+		})
+	}
+	return candles
+}
+
+// regimeRecorder records the regime it is given and holds
+type regimeRecorder struct {
+	regimes []*regime.Estimate
+}
+
+func (r *regimeRecorder) GetName() string { return "regime-recorder" }
+func (r *regimeRecorder) Reset() error    { return nil }
+func (r *regimeRecorder) Analyze(_ context.Context, data *MarketData) (*Signal, error) {
+	r.regimes = append(r.regimes, data.Regime)
+	return &Signal{Side: "HOLD", Confidence: 0.5}, nil
+}
+
+func TestEngine_DetectRegimeUsesNoFutureCandles(t *testing.T) {
+	engine := NewEngine(BacktestConfig{InitialCapital: 10000})
+	candles := regimeTestCandles("BTC/USDT")
+	require.NoError(t, engine.LoadHistoricalData("BTC/USDT", candles))
+	detector := regime.NewDetector(regime.Config{})
+
+	// Too little history at the start
+	_, err := engine.DetectRegime("BTC/USDT", detector, 0)
+	assert.ErrorIs(t, err, regime.ErrTooFewObservations)
+
+	// Still calm at candle 240, however turbulent the candles after it are
+	engine.CurrentIndex["BTC/USDT"] = 240
+	estimate, err := engine.DetectRegime("BTC/USDT", detector, 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, regime.RegimeVolatile, estimate.Regime)
+	assert.Equal(t, candles[240].Timestamp, estimate.DetectedAt)
+
+	closes := make([]float64, 241)
+	for i := range closes {
+		closes[i] = candles[i].Close
+	}
+	direct, err := detector.Detect(closes)
+	require.NoError(t, err)
+	assert.Equal(t, direct.Probabilities, estimate.Probabilities)
+
+	engine.CurrentIndex["BTC/USDT"] = 399
+	estimate, err = engine.DetectRegime("BTC/USDT", detector, 300)
+	require.NoError(t, err)
+	assert.Equal(t, regime.RegimeVolatile, estimate.Regime)
+	assert.Equal(t, 300-1-regime.DefaultVolatilityWindow+1, estimate.Observations)
+
+	_, err = engine.DetectRegime("ETH/USDT", detector, 0)
+	assert.Error(t, err)
+}
+
+func TestAgentReplay_PassesRegimeToAgents(t *testing.T) {
+	engine := NewEngine(BacktestConfig{InitialCapital: 10000})
+	require.NoError(t, engine.LoadHistoricalData("BTC/USDT", regimeTestCandles("BTC/USDT")))
+	engine.CurrentIndex["BTC/USDT"] = 399
+
+	recorder := &regimeRecorder{}
+	adapter := NewAgentReplayAdapter(ConsensusAll)
+	require.NoError(t, adapter.AddAgent(recorder))
+	adapter.SetRegimeDetector(regime.NewDetector(regime.Config{}), 300)
+	require.NoError(t, adapter.Initialize(engine))
+
+	signals, err := adapter.GenerateSignals(engine)
+	require.NoError(t, err)
+	require.Len(t, recorder.regimes, 1)
+	require.NotNil(t, recorder.regimes[0])
+	assert.Equal(t, regime.RegimeVolatile, recorder.regimes[0].Regime)
+	require.Len(t, signals, 1)
+	assert.Equal(t, "volatile", signals[0].Metadata["regime"])
+}
@@ -0,0 +1,167 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// Defaults for volatility-target and ATR sizing
+const (
+	defaultVolatilityLookback = 30
+	defaultTargetVolatility   = 0.20
+	defaultRiskPerTrade       = 0.01
+)
+
+// sizePosition returns the quantity to buy and, for ATR sizing, the stop-loss
+// price. Volatility-target and ATR sizing need enough candles before the
+// current one; until then they return an error.
+func (e *Engine) sizePosition(symbol string, price float64) (quantity, stopLoss float64, err error) {
+	switch e.PositionSizing {
+	case string(risk.SizingMethodVolTarget):
+		quantity, err = e.volTargetQuantity(symbol, price)
+		return quantity, 0, err
+	case string(risk.SizingMethodATR):
+		return e.atrQuantity(symbol, price)
+	default:
+		return e.calculatePositionSize(price), 0, nil
+	}
+}
+
+// volTargetQuantity sizes a position so its annualized volatility is
+// PositionSize of equity, capped so the portfolio stays within
+// PortfolioTargetVolatility
+func (e *Engine) volTargetQuantity(symbol string, price float64) (float64, error) {
+	targetVol := e.PositionSize
+	if targetVol <= 0 {
+		targetVol = defaultTargetVolatility
+	}
+
+	returns, periodsPerYear, err := e.recentReturns(symbol)
+	if err != nil {
+		return 0, err
+	}
+	equity := e.GetCurrentEquity()
+	sizing, err := risk.VolTargetSize(equity, targetVol, risk.AnnualizedVolatility(returns, periodsPerYear), 1)
+	if err != nil {
+		return 0, err
+	}
+
+	notional := sizing.Notional
+	if e.PortfolioTargetVolatility > 0 && len(e.Positions) > 0 {
+		portfolio := &risk.Portfolio{Positions: []risk.PortfolioPosition{{Symbol: symbol, Returns: returns}}}
+		for held, position := range e.Positions {
+			heldReturns, _, err := e.recentReturns(held)
+			if err != nil {
+				continue // Too little history to count against the target
+			}
+			portfolio.Positions = append(portfolio.Positions, risk.PortfolioPosition{
+				Symbol:  held,
+				Value:   position.CurrentPrice * position.Quantity,
+				Returns: heldReturns,
+			})
+		}
+		if notional, err = risk.MarginalVolTargetSize(portfolio, symbol, notional, equity, e.PortfolioTargetVolatility, periodsPerYear); err != nil {
+			return 0, err
+		}
+	}
+
+	return notional / price, nil
+}
+
+// atrQuantity sizes a position so that hitting a stop ATRMultiplier ATRs
+// below price loses PositionSize of equity, and returns that stop
+func (e *Engine) atrQuantity(symbol string, price float64) (float64, float64, error) {
+	riskPerTrade := e.PositionSize
+	if riskPerTrade <= 0 || riskPerTrade > 1 {
+		riskPerTrade = defaultRiskPerTrade
+	}
+	period := e.ATRPeriod
+	if period <= 0 {
+		period = risk.DefaultATRPeriod
+	}
+	multiplier := e.ATRMultiplier
+	if multiplier <= 0 {
+		multiplier = risk.DefaultATRMultiplier
+	}
+
+	candles, err := e.GetHistoricalCandles(symbol, 4*period+1)
+	if err != nil {
+		return 0, 0, err
+	}
+	highs := make([]float64, len(candles))
+	lows := make([]float64, len(candles))
+	closes := make([]float64, len(candles))
+	for i, candle := range candles {
+		highs[i], lows[i], closes[i] = candle.High, candle.Low, candle.Close
+	}
+	atr, err := risk.CalculateATR(highs, lows, closes, period)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sizing, err := risk.ATRSize(e.GetCurrentEquity(), riskPerTrade, price, atr, multiplier, "BUY", 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return sizing.Quantity, sizing.StopLoss, nil
+}
+
+// recentReturns returns the close-to-close returns of the VolatilityLookback
+// candles before the current one, and how many such periods fit in a year
+func (e *Engine) recentReturns(symbol string) ([]float64, float64, error) {
+	lookback := e.VolatilityLookback
+	if lookback <= 0 {
+		lookback = defaultVolatilityLookback
+	}
+
+	candles, err := e.GetHistoricalCandles(symbol, lookback+1)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(candles) < 3 {
+		return nil, 0, fmt.Errorf("need at least 3 candles of %s to measure volatility (got %d)", symbol, len(candles))
+	}
+
+	returns := make([]float64, 0, len(candles)-1)
+	for i := 1; i < len(candles); i++ {
+		if candles[i-1].Close > 0 {
+			returns = append(returns, (candles[i].Close-candles[i-1].Close)/candles[i-1].Close)
+		}
+	}
+
+	spacing := candles[len(candles)-1].Timestamp.Sub(candles[0].Timestamp) / time.Duration(len(candles)-1)
+	if spacing <= 0 {
+		return nil, 0, fmt.Errorf("candles of %s have no time spacing", symbol)
+	}
+	periodsPerYear := float64(risk.CryptoDaysPerYear*24*time.Hour) / float64(spacing)
+	return returns, periodsPerYear, nil
+}
+
+// executeStopLoss closes a position whose stop was hit during the candle. A
+// gap through the stop fills at the open.
+func (e *Engine) executeStopLoss(position *Position, candle *Candlestick) {
+	exitPrice := position.StopLoss
+	if candle.Open > 0 {
+		exitPrice = math.Min(exitPrice, candle.Open)
+	}
+	signal := &Signal{
+		Timestamp:  candle.Timestamp,
+		Symbol:     position.Symbol,
+		Side:       "SELL",
+		Confidence: 1.0,
+		Reasoning:  fmt.Sprintf("Stop-loss hit at %.8f", position.StopLoss),
+		Agent:      "backtest_engine",
+	}
+
+	if err := e.executeSell(signal, exitPrice, candle.Timestamp); err != nil {
+		log.Warn().
+			Err(err).
+			Str("symbol", position.Symbol).
+			Msg("Failed to execute stop-loss")
+	}
+}
@@ -0,0 +1,142 @@
+package backtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ajitpratap0/cryptofunk/internal/risk"
+)
+
+// swingCandles returns daily candles closing alternately at 100 and 105 with a
+// true range of 10, followed by a crash candle that gaps down to 90
+func swingCandles(symbol string, count int) []*Candlestick {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	candles := make([]*Candlestick, 0, count+1)
+	for i := 0; i < count; i++ {
+		closePrice := 100.0
+		if i%2 == 1 {
+			closePrice = 105.0
+		}
+		candles = append(candles, &Candlestick{
+			Symbol:    symbol,
+			Timestamp: start.AddDate(0, 0, i),
+			Open:      closePrice,
+			High:      closePrice + 5,
+			Low:       closePrice - 5,
+			Close:     closePrice,
+		})
+	}
+	return append(candles, &Candlestick{
+		Symbol:    symbol,
+		Timestamp: start.AddDate(0, 0, count),
+		Open:      90,
+		High:      90,
+		Low:       70,
+		Close:     75,
+	})
+}
+
+// swingVolatility is the annualized volatility of the swing candles' returns
+func swingVolatility(returns int) float64 {
+	series := make([]float64, returns)
+	for i := range series {
+		if i%2 == 0 {
+			series[i] = 0.05
+		} else {
+			series[i] = -5.0 / 105
+		}
+	}
+	return risk.AnnualizedVolatility(series, risk.CryptoDaysPerYear)
+}
+
+func TestVolTargetSizing(t *testing.T) {
+	engine := NewEngine(BacktestConfig{
+		InitialCapital: 10000,
+		PositionSizing: "vol_target",
+		PositionSize:   0.20,
+		MaxPositions:   3,
+	})
+	require.NoError(t, engine.LoadHistoricalData("BTC", swingCandles("BTC", 10)))
+	engine.CurrentIndex["BTC"] = 8
+
+	quantity, stopLoss, err := engine.sizePosition("BTC", 100)
+	require.NoError(t, err)
+	assert.InDelta(t, 10000*0.20/swingVolatility(7)/100, quantity, 1e-9)
+	assert.Zero(t, stopLoss)
+}
+
+func TestVolTargetSizing_PortfolioCap(t *testing.T) {
+	engine := NewEngine(BacktestConfig{
+		InitialCapital:            10000,
+		PositionSizing:            "vol_target",
+		PositionSize:              1.0,
+		MaxPositions:              3,
+		PortfolioTargetVolatility: 0.30,
+	})
+	require.NoError(t, engine.LoadHistoricalData("BTC", swingCandles("BTC", 10)))
+	require.NoError(t, engine.LoadHistoricalData("ETH", swingCandles("ETH", 10)))
+	engine.CurrentIndex["BTC"] = 8
+	engine.CurrentIndex["ETH"] = 8
+	engine.Positions["ETH"] = &Position{Symbol: "ETH", Side: "LONG", Quantity: 10, EntryPrice: 100, CurrentPrice: 100}
+
+	// ETH moves in step with BTC, so its $1,000 uses up part of the budget
+	quantity, _, err := engine.sizePosition("BTC", 100)
+	require.NoError(t, err)
+	budget := 11000 * 0.30 / swingVolatility(7)
+	assert.InDelta(t, (budget-1000)/100, quantity, 1e-6)
+}
+
+func TestATRSizing_StopsOut(t *testing.T) {
+	engine := NewEngine(BacktestConfig{
+		InitialCapital: 10000,
+		PositionSizing: "atr",
+		PositionSize:   0.01,
+		MaxPositions:   3,
+		ATRPeriod:      3,
+		ATRMultiplier:  2,
+	})
+	require.NoError(t, engine.LoadHistoricalData("BTC", swingCandles("BTC", 10)))
+	engine.CurrentIndex["BTC"] = 8
+
+	// $100 at risk over a 2×10 stop: 5 units at $100, stopped at $80
+	require.NoError(t, engine.ExecuteSignal(&Signal{Symbol: "BTC", Side: "BUY", Agent: "test"}))
+	position := engine.Positions["BTC"]
+	require.NotNil(t, position)
+	assert.InDelta(t, 5, position.Quantity, 1e-9)
+	assert.InDelta(t, 80, position.StopLoss, 1e-9)
+
+	// The entry candle and the next one stay above the stop
+	for i := 0; i < 2; i++ {
+		_, err := engine.Step(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Contains(t, engine.Positions, "BTC")
+
+	// The crash gaps to 90 and trades through the stop
+	_, err := engine.Step(context.Background())
+	require.NoError(t, err)
+	assert.NotContains(t, engine.Positions, "BTC")
+	require.Len(t, engine.ClosedPositions, 1)
+	assert.InDelta(t, 80, engine.ClosedPositions[0].ExitPrice, 1e-9)
+	assert.InDelta(t, -100, engine.ClosedPositions[0].RealizedPL, 1e-9)
+}
+
+func TestVolatilitySizing_SkipsWithoutHistory(t *testing.T) {
+	for _, sizing := range []string{"vol_target", "atr"} {
+		engine := NewEngine(BacktestConfig{
+			InitialCapital: 10000,
+			PositionSizing: sizing,
+			PositionSize:   0.01,
+			MaxPositions:   3,
+		})
+		require.NoError(t, engine.LoadHistoricalData("BTC", swingCandles("BTC", 10)))
+		engine.CurrentIndex["BTC"] = 1
+
+		require.NoError(t, engine.ExecuteSignal(&Signal{Symbol: "BTC", Side: "BUY", Agent: "test"}), sizing)
+		assert.Empty(t, engine.Positions, sizing)
+	}
+}